@@ -1,14 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
-	_ "net/http/pprof" // 导入 pprof 包
+	"net/http/pprof"
 	"os"
 	"os/signal"
 	"runtime"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -21,6 +28,7 @@ import (
 	"github.com/penwyp/mini-gateway/internal/core/observability"
 	"github.com/penwyp/mini-gateway/internal/core/routing"
 	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	internalrouter "github.com/penwyp/mini-gateway/internal/core/routing/router"
 	"github.com/penwyp/mini-gateway/internal/core/security"
 	"github.com/penwyp/mini-gateway/internal/core/traffic"
 	"github.com/penwyp/mini-gateway/internal/middleware"
@@ -28,7 +36,6 @@ import (
 	"github.com/penwyp/mini-gateway/pkg/cache"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/penwyp/mini-gateway/plugins"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -43,6 +50,16 @@ var (
 )
 
 func main() {
+	validateOnly := flag.Bool("validate-config", false, "仅校验配置并输出报告，不启动服务")
+	hashPassword := flag.Bool("hash-password", false, "从标准输入读取密码并输出 bcrypt 哈希，用于填写 security.users")
+	flag.Parse()
+	if *validateOnly {
+		os.Exit(runValidateConfig(os.Stdout))
+	}
+	if *hashPassword {
+		os.Exit(runHashPassword())
+	}
+
 	configMgr := config.InitConfig() // 初始化配置管理器
 	server = initServer(configMgr)   // 初始化服务
 
@@ -50,13 +67,65 @@ func main() {
 	server.start()                      // 启动服务
 }
 
+// runHashPassword 从标准输入读取一行密码，输出其 bcrypt 哈希，返回进程退出码
+func runHashPassword() int {
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		fmt.Fprintf(os.Stderr, "读取密码失败: %v\n", err)
+		return 1
+	}
+	hash, err := security.HashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成密码哈希失败: %v\n", err)
+		return 1
+	}
+	fmt.Println(hash)
+	return 0
+}
+
+// runValidateConfig 加载并校验配置，将校验报告写入 w，返回进程退出码
+func runValidateConfig(w io.Writer) int {
+	cfg, files, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(w, "配置加载失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(w, "配置文件: %v\n", files)
+
+	for _, warning := range config.ValidationWarnings(cfg) {
+		fmt.Fprintf(w, "警告: %s\n", warning)
+	}
+	errs := config.ValidationErrors(cfg)
+	if len(errs) == 0 {
+		fmt.Fprintln(w, "配置校验通过")
+		return 0
+	}
+	fmt.Fprintf(w, "配置校验失败，共 %d 个问题:\n", len(errs))
+	for _, err := range errs {
+		fmt.Fprintf(w, "  - %v\n", err)
+	}
+	return 1
+}
+
 // Server 结构体封装服务相关组件
 type Server struct {
 	Router         *gin.Engine                 // Gin 路由引擎
 	ConfigMgr      *config.ConfigManager       // 配置管理器
 	TracingCleanup func(context.Context) error // 分布式追踪清理函数
+	MetricsCleanup func(context.Context) error // OTLP 指标导出清理函数
 	LoadBalancer   loadbalancer.LoadBalancer   // 负载均衡器
 	HTTPProxy      *proxy.HTTPProxy            // HTTP 代理
+	AdminServer    *http.Server                // 管理 API 服务，未启用时为 nil
+	PprofServer    *http.Server                // 性能剖析服务，未启用时为 nil
+	Readiness      *health.Readiness           // 就绪状态，启动宽限期内保持未就绪
+
+	RateLimitCleanup func()                     // 停止当前限流中间件的限流器，未启用限流时为 nil
+	active           atomic.Pointer[gin.Engine] // 正在处理请求的路由引擎，配置热更新重建完成后切换
+}
+
+// ServeHTTP 将请求交给当前生效的路由引擎处理
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.active.Load().ServeHTTP(w, r)
 }
 
 // initServer 初始化服务实例
@@ -72,46 +141,74 @@ func initServer(configMgr *config.ConfigManager) *Server {
 		Compress:   cfg.Logger.Compress,
 	})
 
-	validateConfig(cfg)           // 验证配置有效性
 	cache.Init(cfg)               // 初始化缓存
 	observability.InitMetrics()   // 初始化监控指标
 	health.InitHealthChecker(cfg) // 初始化健康检查
+	if cfg.Routing.StartupProbe.Enabled {
+		runStartupProbe(cfg) // 启动前探测所有后端目标
+	}
 
 	s := &Server{
-		Router:    setupGinRouter(cfg), // 设置 Gin 路由器
-		ConfigMgr: configMgr,
+		Router:         setupGinRouter(cfg), // 设置 Gin 路由器
+		ConfigMgr:      configMgr,
+		MetricsCleanup: observability.InitOTLPMetrics(cfg), // 初始化 OTLP 指标导出
+		Readiness:      health.NewReadiness(cfg.Server.StartupGracePeriod),
 	}
 
 	// 如果启用了 RBAC 认证，则初始化 RBAC
 	if cfg.Security.AuthMode == "rbac" && cfg.Security.RBAC.Enabled {
 		security.InitRBAC(cfg)
 	}
+	// 初始化登录凭证校验器
+	security.InitLogin(cfg)
+
 	s.setupMiddleware(cfg) // 配置中间件
 	s.setupHTTPProxy(cfg)  // 配置 HTTP 代理
 	s.setupRoutes(cfg)     // 配置路由
+	s.active.Store(s.Router)
 
 	return s
 }
 
+// runStartupProbe 同步探测所有后端目标一次并记录结果，不可达目标占比达到 failThreshold 时退出
+func runStartupProbe(cfg *config.Config) {
+	summary := health.GetGlobalHealthChecker().ProbeAll()
+	ratio := summary.UnreachableRatio()
+	fields := []zap.Field{
+		zap.Int("reachable", len(summary.Reachable)),
+		zap.Int("unreachable", len(summary.Unreachable)),
+		zap.Strings("unreachableTargets", summary.Unreachable),
+	}
+	if len(summary.Unreachable) == 0 {
+		logger.Info("启动探测完成，所有后端目标可达", fields...)
+		return
+	}
+	logger.Warn("启动探测完成，部分后端目标不可达", fields...)
+
+	threshold := cfg.Routing.StartupProbe.FailThreshold
+	if threshold > 0 && ratio >= threshold {
+		logger.Error("不可达后端目标占比达到阈值，停止启动",
+			zap.Float64("unreachableRatio", ratio),
+			zap.Float64("failThreshold", threshold))
+		os.Exit(1)
+	}
+}
+
 // setupRoutes 配置所有路由，简洁调用独立处理函数
 func (s *Server) setupRoutes(cfg *config.Config) {
 	// 基本路由
 	s.Router.GET("/health", s.handleHealth) // 健康检查路由
+	s.Router.GET("/readyz", s.handleReadyz) // 就绪检查路由
 	s.Router.GET("/status", s.handleStatus) // 状态检查路由
+	s.Router.GET("/version", handleVersion) // 构建信息路由
 	s.Router.POST("/login", s.handleLogin)  // 登录路由
 
-	// 添加 pprof 调试路由
-	if cfg.Server.PprofEnabled { // 假设在 config 中添加了 PprofEnabled 字段
-		s.Router.GET("/debug/pprof/*profile", gin.WrapH(http.DefaultServeMux))
-		logger.Info("pprof endpoints enabled at /debug/pprof")
-	}
-
 	// 添加关闭熔断器的 API
 	s.Router.POST("/breaker/disable", traffic.DisableBreakerHandler)
 
 	// Prometheus 监控路由
 	if cfg.Observability.Prometheus.Enabled {
-		s.Router.GET(cfg.Observability.Prometheus.Path, gin.WrapH(promhttp.Handler()))
+		s.Router.GET(cfg.Observability.Prometheus.Path, gin.WrapH(observability.MetricsHandler()))
 	}
 
 	// 文件服务路由
@@ -146,6 +243,23 @@ func (s *Server) handleHealth(c *gin.Context) {
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
+// handleVersion 返回网关的构建信息，不做额外计算也不记录日志，供部署校验和监控面板频繁调用
+func handleVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, currentBuildInfo())
+}
+
+// handleReadyz 处理就绪检查请求，启动宽限期内返回 503
+func (s *Server) handleReadyz(c *gin.Context) {
+	if ready, remaining := s.Readiness.Ready(); !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "warming up",
+			"remaining": remaining.Round(time.Second).String(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
 // handleStatus 处理状态检查请求
 func (s *Server) handleStatus(c *gin.Context) {
 	logger.Info("收到状态检查请求", zap.String("clientIP", c.ClientIP()))
@@ -172,13 +286,13 @@ func (s *Server) handleStatus(c *gin.Context) {
 
 	backendStats := health.GetGlobalHealthChecker().GetAllStats()
 	cachedStats := s.getCachedPathStats(backendStats)
-	pluginStatus := getPluginStatus()
-
 	cfg := s.ConfigMgr.GetConfig()
+	pluginStatus := getPluginStatus(cfg.Plugin)
 	configSummary := ConfigSummary{
 		Server: ServerConfigSummary{
-			Port:    cfg.Server.Port,
-			GinMode: cfg.Server.GinMode,
+			Port:            cfg.Server.Port,
+			GinMode:         cfg.Server.EffectiveGinMode(),
+			HealthCheckOnly: cfg.Server.HealthCheckOnly,
 		},
 		Logger: LoggerConfigSummary{
 			Level: cfg.Logger.Level,
@@ -202,7 +316,8 @@ func (s *Server) handleStatus(c *gin.Context) {
 			RBACEnabled: cfg.Security.RBAC.Enabled,
 		},
 		Cache: CacheConfigSummary{
-			Addr:           cfg.Cache.Addr,
+			Mode:           cfg.Cache.Mode,
+			Addr:           cfg.Cache.Endpoint(),
 			EnabledCaching: cfg.Caching.Enabled,
 		},
 		Traffic: TrafficConfigSummary{
@@ -245,9 +360,14 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
-	if creds.Username != "admin" || creds.Password != "password" {
-		logger.Warn("登录失败", zap.String("username", creds.Username))
-		c.JSON(401, gin.H{"error": "Invalid credentials"})
+	if err := security.VerifyCredentials(c.Request.Context(), creds.Username, creds.Password); err != nil {
+		if errors.Is(err, security.ErrInvalidCredentials) {
+			logger.Warn("登录失败", zap.String("username", creds.Username))
+			c.JSON(401, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		logger.Error("登录凭证校验失败", zap.String("username", creds.Username), zap.Error(err))
+		c.JSON(503, gin.H{"error": "Credential verification unavailable"})
 		return
 	}
 
@@ -274,6 +394,24 @@ func (s *Server) handleLogin(c *gin.Context) {
 	}
 }
 
+// 路由管理 API 的错误
+var (
+	errRouteExists   = errors.New("Route already exists")
+	errRouteNotFound = errors.New("Route not found")
+)
+
+// writeRouteError 根据路由修改失败的原因返回对应的状态码，其余错误为配置校验失败
+func writeRouteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errRouteExists):
+		c.JSON(409, gin.H{"error": err.Error()})
+	case errors.Is(err, errRouteNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	default:
+		c.JSON(400, gin.H{"error": err.Error()})
+	}
+}
+
 // handleAddRoute 处理添加路由请求
 func (s *Server) handleAddRoute(c *gin.Context) {
 	var route struct {
@@ -285,18 +423,17 @@ func (s *Server) handleAddRoute(c *gin.Context) {
 		return
 	}
 
-	cfg := s.ConfigMgr.GetConfig()
-	if cfg.Routing.Rules == nil {
-		cfg.Routing.Rules = make(map[string]config.RoutingRules)
-	}
-
-	if _, exists := cfg.Routing.Rules[route.Path]; exists {
-		c.JSON(409, gin.H{"error": "Route already exists"})
+	err := s.ConfigMgr.Modify(func(cfg *config.Config) error {
+		if _, exists := cfg.Routing.Rules[route.Path]; exists {
+			return errRouteExists
+		}
+		cfg.Routing.Rules[route.Path] = route.Rules
+		return nil
+	})
+	if err != nil {
+		writeRouteError(c, err)
 		return
 	}
-
-	cfg.Routing.Rules[route.Path] = route.Rules
-	s.ConfigMgr.UpdateConfig(cfg)
 	logger.Info("路由已添加", zap.String("path", route.Path), zap.Any("rules", route.Rules))
 	c.JSON(200, gin.H{"message": "Route added successfully"})
 }
@@ -314,14 +451,17 @@ func (s *Server) handleUpdateRoute(c *gin.Context) {
 
 	path, rules := route.Path, route.Rules
 
-	cfg := s.ConfigMgr.GetConfig()
-	if _, exists := cfg.Routing.Rules[path]; !exists {
-		c.JSON(404, gin.H{"error": "Route not found"})
+	err := s.ConfigMgr.Modify(func(cfg *config.Config) error {
+		if _, exists := cfg.Routing.Rules[path]; !exists {
+			return errRouteNotFound
+		}
+		cfg.Routing.Rules[path] = rules
+		return nil
+	})
+	if err != nil {
+		writeRouteError(c, err)
 		return
 	}
-
-	cfg.Routing.Rules[path] = rules
-	s.ConfigMgr.UpdateConfig(cfg)
 	logger.Info("路由已更新", zap.String("path", path), zap.Any("rules", rules))
 	c.JSON(200, gin.H{"message": "Route updated successfully"})
 }
@@ -338,15 +478,17 @@ func (s *Server) handleDeleteRoute(c *gin.Context) {
 	}
 
 	path := route.Path
-	cfg := s.ConfigMgr.GetConfig()
-
-	if _, exists := cfg.Routing.Rules[path]; !exists {
-		c.JSON(404, gin.H{"error": "Route not found"})
+	err := s.ConfigMgr.Modify(func(cfg *config.Config) error {
+		if _, exists := cfg.Routing.Rules[path]; !exists {
+			return errRouteNotFound
+		}
+		delete(cfg.Routing.Rules, path)
+		return nil
+	})
+	if err != nil {
+		writeRouteError(c, err)
 		return
 	}
-
-	delete(cfg.Routing.Rules, path)
-	s.ConfigMgr.UpdateConfig(cfg)
 	logger.Info("路由已删除", zap.String("path", path))
 	c.JSON(200, gin.H{"message": "Route deleted successfully"})
 }
@@ -374,33 +516,55 @@ func (s *Server) handleSaveConfig(c *gin.Context) {
 func (s *Server) setupMiddleware(cfg *config.Config) {
 	s.Router = setupGinRouter(cfg)
 
+	s.Router.Use(middleware.RequestID()) // 请求 ID
+	if cfg.Logging.Access.Enabled {
+		s.Router.Use(middleware.AccessLog(cfg)) // 访问日志
+	}
+	if cfg.Server.Debug.Enabled {
+		s.Router.Use(middleware.DebugHeaders(cfg)) // 调试响应头
+	}
+	if cfg.Traffic.MaxConcurrent.Enabled() {
+		s.Router.Use(middleware.ConcurrencyLimit(cfg)) // 并发请求数限制
+	}
 	s.Router.Use(middleware.CacheMiddleware()) // 启用缓存中间件
 
 	plugins.LoadPlugins(s.Router, cfg) // 加载自定义插件
+	plugins.LoadWasmPlugins(cfg)       // 加载 WASM 请求过滤插件
 
 	if cfg.Middleware.IPAcl {
 		security.InitIPRules(cfg)
 		s.Router.Use(security.IPAcl()) // IP 访问控制
 	}
+	security.InitAutoBan(cfg) // 频繁违规的 IP 自动封禁，封禁由 IP 访问控制生效
 	if cfg.Middleware.AntiInjection {
 		s.Router.Use(security.AntiInjection()) // 防注入攻击
 	}
+	if len(cfg.Routing.Scripts) > 0 {
+		s.Router.Use(middleware.Script(cfg)) // 路由脚本
+	}
 
+	s.RateLimitCleanup = nil
 	if cfg.Middleware.RateLimit {
-		switch cfg.Traffic.RateLimit.Algorithm {
-		case "token_bucket":
-			s.Router.Use(traffic.TokenBucketRateLimit()) // 令牌桶限流
-		case "leaky_bucket":
-			s.Router.Use(traffic.LeakyBucketRateLimit()) // 漏桶限流
-		default:
+		// 按 algorithm 选择令牌桶、漏桶或滑动窗口限流
+		rateLimit, cleanup, err := traffic.RateLimit(cfg)
+		if err != nil {
 			logger.Error("未知的限流算法", zap.String("algorithm", cfg.Traffic.RateLimit.Algorithm))
 			os.Exit(1)
 		}
+		s.Router.Use(rateLimit)
+		s.RateLimitCleanup = cleanup
 	}
 	if cfg.Middleware.Breaker {
 		s.Router.Use(traffic.Breaker()) // 熔断器
 	}
 
+	// 配置热更新时先关闭旧的追踪提供者，以便无需修改代码即可开启或关闭追踪
+	if s.TracingCleanup != nil {
+		if err := s.TracingCleanup(context.Background()); err != nil {
+			logger.Error("关闭追踪提供者失败", zap.Error(err))
+		}
+		s.TracingCleanup = nil
+	}
 	if cfg.Middleware.Tracing {
 		cleanup := observability.InitTracing(cfg)
 		s.TracingCleanup = cleanup
@@ -418,9 +582,18 @@ func (s *Server) setupHTTPProxy(cfg *config.Config) {
 func refreshConfig(server *Server, configMgr *config.ConfigManager) {
 	for newCfg := range configMgr.ConfigChan {
 		logger.Info("正在刷新服务配置")
+		previousRateLimitCleanup := server.RateLimitCleanup
 		server.setupMiddleware(newCfg)
 		server.setupRoutes(newCfg)
+		server.active.Store(server.Router)
+		// 新路由引擎接管请求后再停止旧的限流器，避免旧引擎上仍在处理的请求受影响
+		if previousRateLimitCleanup != nil {
+			previousRateLimitCleanup()
+		}
 		server.HTTPProxy.RefreshLoadBalancer(newCfg)
+		server.HTTPProxy.RefreshSettings(newCfg)
+		server.HTTPProxy.RefreshRetryPolicy(newCfg)
+		security.InitLogin(newCfg)
 		health.GetGlobalHealthChecker().RefreshTargets(newCfg)
 		logger.Info("服务配置刷新成功")
 	}
@@ -434,16 +607,146 @@ func (s *Server) start() {
 	listenAddr := ":" + cfg.Server.Port
 	logger.Info("服务开始监听", zap.String("address", listenAddr))
 	go func() {
-		if err := s.Router.Run(listenAddr); err != nil {
+		if err := http.ListenAndServe(listenAddr, s); err != nil {
 			logger.Error("启动服务失败", zap.Error(err))
 			os.Exit(1)
 		}
 	}()
+	s.startAdminServer(cfg)
+	s.startPprofServer(cfg)
 	go StartMemoryMonitoring()
 
 	s.gracefulShutdown()
 }
 
+// startAdminServer 在独立端口上启动管理 API，监听端口和令牌在启动时确定，不随配置热更新变化
+func (s *Server) startAdminServer(cfg *config.Config) {
+	admin := cfg.Server.Admin
+	if !admin.Enabled {
+		return
+	}
+
+	s.AdminServer = &http.Server{Addr: ":" + admin.Port, Handler: s.newAdminHandler(admin.Token)}
+	logger.Info("管理 API 开始监听", zap.String("address", s.AdminServer.Addr))
+	go func() {
+		if err := s.AdminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("启动管理 API 服务失败", zap.Error(err))
+		}
+	}()
+}
+
+// newAdminHandler 创建管理 API 路由，所有接口都需携带 Bearer Token
+func (s *Server) newAdminHandler(token string) http.Handler {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	adminGroup := r.Group("/admin", adminAuth(token))
+	{
+		adminGroup.GET("/routes", s.handleAdminRoutes)  // 当前路由规则
+		adminGroup.GET("/config", s.handleAdminConfig)  // 脱敏后的当前配置
+		adminGroup.POST("/reload", s.handleAdminReload) // 重新加载配置文件
+
+		adminGroup.POST("/targets/drain", handleAdminDrainTarget)   // 管理下线目标
+		adminGroup.POST("/targets/enable", handleAdminEnableTarget) // 恢复目标
+
+		adminGroup.POST("/ip/ban", security.BanIPHandler) // 临时封禁 IP，需启用 IP 访问控制中间件才会生效
+	}
+	return r
+}
+
+// startPprofServer 在独立地址上启动 /debug/pprof 性能剖析端点
+// pprof 端点没有认证，会暴露 goroutine 调用栈、堆内存内容摘要和进程命令行参数，CPU 剖析和 trace 在采样期间会增加开销，
+// 因此不挂载在对外服务的路由上，并使用独立的 ServeMux，避免注册到 http.DefaultServeMux 的处理器被意外暴露
+func (s *Server) startPprofServer(cfg *config.Config) {
+	pprofCfg := cfg.Server.Pprof
+	if !pprofCfg.Enabled {
+		return
+	}
+
+	mux := http.NewServeMux()
+	// Index 同时提供 goroutine、heap、allocs、block、mutex、threadcreate 等命名剖析，如 /debug/pprof/heap
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile) // CPU 剖析，通过 seconds 参数指定采样时长
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	s.PprofServer = &http.Server{Addr: pprofCfg.Addr, Handler: mux}
+	logger.Info("pprof 性能剖析端点开始监听", zap.String("address", s.PprofServer.Addr))
+	go func() {
+		if err := s.PprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("启动 pprof 服务失败", zap.Error(err))
+		}
+	}()
+}
+
+// adminAuth 校验管理 API 请求携带的 Bearer Token，未配置令牌时拒绝所有请求
+func adminAuth(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleAdminRoutes 返回当前生效的路由规则
+func (s *Server) handleAdminRoutes(c *gin.Context) {
+	cfg := s.ConfigMgr.GetConfig()
+	c.JSON(http.StatusOK, gin.H{"routes": cfg.Routing.Rules})
+}
+
+// handleAdminConfig 返回隐藏敏感字段后的当前配置
+func (s *Server) handleAdminConfig(c *gin.Context) {
+	cfg := s.ConfigMgr.GetConfig()
+	c.JSON(http.StatusOK, gin.H{"config": cfg.Sanitized()})
+}
+
+// handleAdminReload 重新读取配置文件，校验通过后通过 ConfigChan 触发服务刷新
+func (s *Server) handleAdminReload(c *gin.Context) {
+	if err := s.ConfigMgr.Reload(); err != nil {
+		logger.Error("手动重新加载配置失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.Info("手动重新加载配置成功")
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration reloaded successfully"})
+}
+
+// adminTargetRequest 目标管理请求
+type adminTargetRequest struct {
+	Target string `json:"target" binding:"required"`
+}
+
+// handleAdminDrainTarget 将目标标记为管理下线，正在处理的请求继续完成，新请求不再路由到该目标
+func handleAdminDrainTarget(c *gin.Context) {
+	handleAdminTargetChange(c, health.GetGlobalHealthChecker().Drain, "drained")
+}
+
+// handleAdminEnableTarget 取消目标的管理下线状态
+func handleAdminEnableTarget(c *gin.Context) {
+	handleAdminTargetChange(c, health.GetGlobalHealthChecker().Enable, "enabled")
+}
+
+// handleAdminTargetChange 解析目标管理请求并执行状态变更
+func handleAdminTargetChange(c *gin.Context, change func(target string) error, action string) {
+	var request adminTargetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: target is required"})
+		return
+	}
+	if err := change(request.Target); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	logger.Info("目标状态已变更", zap.String("target", request.Target), zap.String("action", action))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Target " + action + ": " + request.Target,
+		"drained": health.GetGlobalHealthChecker().DrainedTargets(),
+	})
+}
+
 // logStartupInfo 记录服务启动信息
 func logStartupInfo(cfg *config.Config) {
 	logger.Info("启动 mini-gateway",
@@ -455,6 +758,7 @@ func logStartupInfo(cfg *config.Config) {
 		zap.Any("routingRules", cfg.Routing.Rules),
 		zap.String("authMode", cfg.Security.AuthMode),
 		zap.Bool("rbacEnabled", cfg.Security.RBAC.Enabled),
+		zap.Bool("healthCheckOnly", cfg.Server.HealthCheckOnly),
 	)
 
 	logger.Info("中间件状态",
@@ -473,30 +777,60 @@ func (s *Server) gracefulShutdown() {
 	<-quit
 	logger.Info("正在关闭服务...")
 
+	if s.AdminServer != nil {
+		if err := s.AdminServer.Shutdown(context.Background()); err != nil {
+			logger.Error("关闭管理 API 服务失败", zap.Error(err))
+		}
+	}
+	if s.PprofServer != nil {
+		if err := s.PprofServer.Shutdown(context.Background()); err != nil {
+			logger.Error("关闭 pprof 服务失败", zap.Error(err))
+		}
+	}
+
 	if s.TracingCleanup != nil {
 		if err := s.TracingCleanup(context.Background()); err != nil {
 			logger.Error("关闭追踪提供者失败", zap.Error(err))
 		}
 	}
+	if s.MetricsCleanup != nil {
+		if err := s.MetricsCleanup(context.Background()); err != nil {
+			logger.Error("关闭指标导出失败", zap.Error(err))
+		}
+	}
+	if s.RateLimitCleanup != nil {
+		s.RateLimitCleanup()
+	}
+	s.HTTPProxy.Close()
 	health.GetGlobalHealthChecker().Close()
 }
 
 // setupGinRouter 初始化 Gin 路由器
 func setupGinRouter(cfg *config.Config) *gin.Engine {
-	gin.SetMode(cfg.Server.GinMode)
+	gin.SetMode(cfg.Server.EffectiveGinMode())
 	r := gin.New()
+	internalrouter.ConfigureTrailingSlash(r, cfg) // 尾部斜杠策略对所有路由引擎一致
 	r.Use(gin.Recovery())
 	r.Use(requestMetricsMiddleware())
 	r.LoadHTMLGlob("templates/*") // 加载 templates 目录下的所有模板
 	return r
 }
 
-// validateConfig 验证配置
-func validateConfig(cfg *config.Config) {
-	if cfg.Routing.LoadBalancer != "consul" && (cfg.Routing.Rules == nil || len(cfg.Routing.Rules) == 0) {
-		logger.Error("路由规则为空或未定义")
-		os.Exit(1)
+// BuildInfo 网关的构建信息，由构建时通过 -ldflags 注入
+type BuildInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// currentBuildInfo 返回构建信息，未注入 Go 版本时使用运行时的版本
+func currentBuildInfo() BuildInfo {
+	info := BuildInfo{Version: Version, BuildTime: BuildTime, GitCommit: GitCommit, GoVersion: GoVersion}
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
 	}
+	return info
 }
 
 // GatewayStatus 网关自身状态
@@ -512,7 +846,7 @@ func (s *Server) getUnhealthyTargets() []string {
 	var unhealthy []string
 	stats := health.GetGlobalHealthChecker().GetAllStats()
 	for _, stat := range stats {
-		if stat.ProbeFailureCount > stat.ProbeSuccessCount {
+		if stat.Ejected || stat.ProbeFailureCount > stat.ProbeSuccessCount {
 			unhealthy = append(unhealthy, stat.URL)
 		}
 	}
@@ -568,21 +902,29 @@ type PluginStatus struct {
 	Enabled     bool   `json:"enabled"`
 }
 
-// getPluginStatus 获取插件状态
-func getPluginStatus() []PluginStatus {
+// getPluginStatus 获取插件状态，已加载的插件按加载顺序排列，配置中禁用的插件排在最后
+func getPluginStatus(pluginCfg config.Plugin) []PluginStatus {
 	var status []PluginStatus
 	loadedPlugins := plugins.GetLoadedPlugins()
 	for _, p := range loadedPlugins {
+		info := p.PluginInfo()
+		var pluginVersion string
+		if info.Version != nil { // WASM 插件没有版本信息
+			pluginVersion = info.Version.String()
+		}
 		status = append(status, PluginStatus{
-			Name:        p.PluginInfo().Name,
-			Description: p.PluginInfo().Description,
-			Version:     p.PluginInfo().Version.String(),
+			Name:        info.Name,
+			Description: info.Description,
+			Version:     pluginVersion,
 			Enabled:     true,
 		})
 	}
-	sort.Slice(status, func(i, j int) bool {
-		return status[i].Name < status[j].Name
-	})
+	// 禁用的插件未加载，只能报告配置中的名称
+	for _, entry := range pluginCfg.Ordered() {
+		if !entry.Enabled {
+			status = append(status, PluginStatus{Name: entry.Name, Enabled: false})
+		}
+	}
 	return status
 }
 
@@ -598,7 +940,7 @@ func requestMetricsMiddleware() gin.HandlerFunc {
 		status := fmt.Sprintf("%d", c.Writer.Status())
 		observability.RequestsTotal.WithLabelValues(method, path, status).Inc()
 		duration := time.Since(start).Seconds()
-		observability.RequestDuration.WithLabelValues(method, path).Observe(duration)
+		observability.ObserveRequestDuration(c.Request.Context(), method, path, duration)
 	}
 }
 
@@ -614,8 +956,9 @@ type ConfigSummary struct {
 }
 
 type ServerConfigSummary struct {
-	Port    string `json:"port"`
-	GinMode string `json:"gin_mode"`
+	Port            string `json:"port"`
+	GinMode         string `json:"gin_mode"`
+	HealthCheckOnly bool   `json:"health_check_only"`
 }
 
 type LoggerConfigSummary struct {
@@ -644,6 +987,7 @@ type SecurityConfigSummary struct {
 }
 
 type CacheConfigSummary struct {
+	Mode           string `json:"mode"`
 	Addr           string `json:"addr"`
 	EnabledCaching bool   `json:"enabled_caching"`
 }
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/health"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig 能通过校验的最小配置
+const validConfig = `
+grpc:
+  enabled: false
+websocket:
+  enabled: false
+routing:
+  loadBalancer: round_robin
+  rules:
+    /api/v1/user:
+      - target: http://127.0.0.1:8381
+`
+
+// writeTestConfig 写入临时配置文件并通过 GATEWAY_CONFIG_PATH 指向它
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	t.Setenv("GATEWAY_CONFIG_PATH", file)
+	return file
+}
+
+func TestRunValidateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantCode int
+		want     []string
+	}{
+		{
+			name:     "valid",
+			content:  validConfig,
+			wantCode: 0,
+			want:     []string{"配置文件: [", "配置校验通过"},
+		},
+		{
+			name: "warnings only",
+			content: validConfig + `
+      - target: http://127.0.0.1:8382
+        fallback:
+          strategy: cached
+`,
+			wantCode: 0,
+			want:     []string{"警告: route /api/v1/user uses the cached fallback strategy", "配置校验通过"},
+		},
+		{
+			name: "invalid",
+			content: `
+grpc:
+  enabled: false
+websocket:
+  enabled: false
+routing:
+  loadBalancer: bogus
+  retry:
+    backoff: -1s
+  rules:
+    /api/v1/user:
+      - target: http://127.0.0.1:8381
+`,
+			wantCode: 1,
+			want:     []string{"配置校验失败，共 2 个问题:", "  - ", "bogus"},
+		},
+		{
+			name:     "unparsable",
+			content:  "routing: [",
+			wantCode: 1,
+			want:     []string{"配置加载失败: "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestConfig(t, tt.content)
+
+			var out bytes.Buffer
+			assert.Equal(t, tt.wantCode, runValidateConfig(&out))
+			for _, want := range tt.want {
+				assert.Contains(t, out.String(), want)
+			}
+			if tt.wantCode == 0 {
+				assert.Contains(t, out.String(), file)
+				assert.NotContains(t, out.String(), "配置校验失败")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("GATEWAY_CONFIG_PATH", filepath.Join(t.TempDir(), "missing.yaml"))
+		var out bytes.Buffer
+		assert.Equal(t, 1, runValidateConfig(&out))
+		assert.Contains(t, out.String(), "配置加载失败")
+	})
+}
+
+// TestValidateConfigFlag 以子进程运行 gateway --validate-config，检查进程退出码且不启动服务
+func TestValidateConfigFlag(t *testing.T) {
+	if os.Getenv("GATEWAY_TEST_MAIN") == "1" {
+		os.Args = []string{"gateway", "--validate-config"}
+		main()
+		return
+	}
+
+	for _, tc := range []struct {
+		name     string
+		content  string
+		wantCode int
+		want     string
+	}{
+		{"valid", validConfig, 0, "配置校验通过"},
+		{"invalid", "routing:\n  loadBalancer: bogus\n", 1, "配置校验失败"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeTestConfig(t, tc.content)
+			cmd := exec.Command(os.Args[0], "-test.run=^TestValidateConfigFlag$")
+			cmd.Env = append(os.Environ(), "GATEWAY_TEST_MAIN=1", "GATEWAY_CONFIG_PATH="+file)
+			out, err := cmd.Output()
+
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantCode, code)
+			assert.Contains(t, string(out), tc.want)
+		})
+	}
+}
+
+// newAdminTestServer 基于临时配置文件创建 Server 及其管理 API 路由
+func newAdminTestServer(t *testing.T, content string) (*Server, http.Handler, string) {
+	t.Helper()
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	file := writeTestConfig(t, content)
+	cm, err := config.NewConfigManager()
+	require.NoError(t, err)
+	s := &Server{ConfigMgr: cm}
+	return s, s.newAdminHandler(adminTestToken), file
+}
+
+const adminTestToken = "admin-token"
+
+// serveAdmin 以给定的 Authorization 请求头调用管理 API
+func serveAdmin(h http.Handler, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAuth(t *testing.T) {
+	_, h, _ := newAdminTestServer(t, validConfig)
+
+	for _, tc := range []struct {
+		name          string
+		authorization string
+		wantCode      int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong-token", http.StatusUnauthorized},
+		{"token prefix", "Bearer admin", http.StatusUnauthorized},
+		{"missing scheme", adminTestToken, http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + adminTestToken, http.StatusUnauthorized},
+		{"valid token", "Bearer " + adminTestToken, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, route := range []struct{ method, path string }{
+				{"GET", "/admin/routes"},
+				{"GET", "/admin/config"},
+				{"POST", "/admin/reload"},
+			} {
+				w := serveAdmin(h, route.method, route.path, tc.authorization)
+				assert.Equal(t, tc.wantCode, w.Code, "%s %s", route.method, route.path)
+			}
+		})
+	}
+
+	// 未配置令牌时 "Bearer " 也不能通过校验
+	r := gin.New()
+	r.GET("/admin/routes", adminAuth(""), func(c *gin.Context) { c.Status(http.StatusOK) })
+	assert.Equal(t, http.StatusUnauthorized, serveAdmin(r, "GET", "/admin/routes", "Bearer ").Code)
+}
+
+func TestAdminConfig_RedactsSecrets(t *testing.T) {
+	_, h, _ := newAdminTestServer(t, validConfig+`
+server:
+  admin:
+    enabled: true
+    port: "8091"
+    token: admin-secret-token
+  debug:
+    token: debug-secret-token
+security:
+  jwt:
+    secret: jwt-secret-value
+  apiKey:
+    keys:
+      - key: api-secret-key
+        client: mobile
+  introspection:
+    clientSecret: introspection-secret
+  users:
+    - username: admin
+      passwordHash: $2a$04$271rrzTJa9nal4FXANQHQeHoPwsBgGH/iUP3MgsUCMDniUt4pfZGi
+cache:
+  password: redis-secret-password
+`)
+
+	w := serveAdmin(h, "GET", "/admin/config", "Bearer "+adminTestToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	for _, secret := range []string{
+		"admin-secret-token", "debug-secret-token", "jwt-secret-value", "api-secret-key",
+		"introspection-secret", "271rrzTJa9nal4FXANQHQe", "redis-secret-password",
+	} {
+		assert.NotContains(t, body, secret)
+	}
+	assert.Contains(t, body, "mobile", "non-secret fields are kept")
+	assert.Contains(t, body, "/api/v1/user")
+}
+
+func TestAdminReload(t *testing.T) {
+	s, h, file := newAdminTestServer(t, validConfig)
+
+	// 配置文件新增路由后重新加载，新配置生效并通知刷新
+	require.NoError(t, os.WriteFile(file, []byte(validConfig+`
+    /api/v1/order:
+      - target: http://127.0.0.1:8382
+`), 0644))
+	w := serveAdmin(h, "POST", "/admin/reload", "Bearer "+adminTestToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, s.ConfigMgr.GetConfig().Routing.Rules, "/api/v1/order")
+	select {
+	case cfg := <-s.ConfigMgr.ConfigChan:
+		assert.Contains(t, cfg.Routing.Rules, "/api/v1/order")
+	default:
+		t.Fatal("reload did not notify the configuration listeners")
+	}
+
+	w = serveAdmin(h, "GET", "/admin/routes", "Bearer "+adminTestToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/api/v1/order")
+
+	// 校验失败时返回 500 并保留当前配置
+	require.NoError(t, os.WriteFile(file, []byte("routing:\n  loadBalancer: bogus\n"), 0644))
+	w = serveAdmin(h, "POST", "/admin/reload", "Bearer "+adminTestToken)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "bogus")
+	assert.Equal(t, "round_robin", s.ConfigMgr.GetConfig().Routing.LoadBalancer)
+	assert.Contains(t, s.ConfigMgr.GetConfig().Routing.Rules, "/api/v1/order")
+}
+
+// serveAdminJSON 以有效令牌调用管理 API 并携带 JSON 请求体
+func serveAdminJSON(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminTestToken)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminDrainAndEnableTarget(t *testing.T) {
+	s, h, _ := newAdminTestServer(t, validConfig)
+	cache.InitTestStore()
+	checker := health.InitHealthChecker(s.ConfigMgr.GetConfig())
+	defer checker.Close()
+	require.Same(t, checker, health.GetGlobalHealthChecker(), "handlers use the global health checker")
+
+	var resp struct {
+		Message string   `json:"message"`
+		Drained []string `json:"drained"`
+	}
+
+	w := serveAdminJSON(h, "POST", "/admin/targets/drain", `{"target": "http://127.0.0.1:8381"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Target drained: http://127.0.0.1:8381", resp.Message)
+	assert.Equal(t, []string{"127.0.0.1:8381"}, resp.Drained)
+	assert.True(t, checker.IsDrained("http://127.0.0.1:8381"))
+
+	w = serveAdminJSON(h, "POST", "/admin/targets/enable", `{"target": "http://127.0.0.1:8381"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Target enabled: http://127.0.0.1:8381", resp.Message)
+	assert.Empty(t, resp.Drained)
+	assert.False(t, checker.IsDrained("http://127.0.0.1:8381"))
+
+	for _, path := range []string{"/admin/targets/drain", "/admin/targets/enable"} {
+		w = serveAdminJSON(h, "POST", path, `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "%s without target", path)
+
+		w = serveAdminJSON(h, "POST", path, `{"target": "http://127.0.0.1:9999"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code, "%s with unknown target", path)
+		assert.Contains(t, w.Body.String(), "unknown target")
+
+		w = serveAdmin(h, "POST", path, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "%s without token", path)
+	}
+	assert.Empty(t, checker.DrainedTargets())
+}
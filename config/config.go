@@ -1,19 +1,32 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"text/template"
+	"text/template/parse"
 	"time"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/mitchellh/mapstructure"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/penwyp/mini-gateway/pkg/signing"
 	"github.com/spf13/viper"
 )
 
@@ -21,8 +34,11 @@ var configMgr *ConfigManager
 
 // ConfigManager 管理配置及其变更通知
 type ConfigManager struct {
-	config *Config
-	mutex  sync.RWMutex
+	config   *Config
+	mutex    sync.RWMutex
+	path     string     // 配置路径（文件、目录或逗号分隔的列表），重新加载时重新解析，使目录中新增或删除的文件生效
+	files    []string   // 实际加载的配置文件，重新加载时按相同顺序合并
+	reloadMu sync.Mutex // 串行化配置重新加载
 
 	ConfigChan chan *Config // 用于通知配置变更
 }
@@ -36,6 +52,7 @@ type Config struct {
 	Observability Observability `mapstructure:"observability"`
 	Plugin        Plugin        `mapstructure:"plugin"`
 	Logger        Logger        `mapstructure:"logger"`
+	Logging       Logging       `mapstructure:"logging"`
 	Cache         Cache         `mapstructure:"cache"`
 	Caching       Caching       `mapstructure:"caching"`
 	Consul        Consul        `mapstructure:"consul"`
@@ -46,86 +63,229 @@ type Config struct {
 	Performance   Performance   `mapstructure:"performance"`
 }
 
-// InitConfig 初始化配置并返回 ConfigManager
-func InitConfig() *ConfigManager {
-	cfg := &Config{}
-
-	v := viper.New()
-	v.SetConfigFile("config/config.yaml")
-	v.SetConfigType("yaml")
-	setDefaultValues(v)
+const (
+	configPathEnv     = "GATEWAY_CONFIG_PATH" // 配置路径环境变量，支持单个文件、目录或逗号分隔的文件列表
+	defaultConfigPath = "config/config.yaml"  // 默认配置文件路径
+	envPrefix         = "MG"                  // 覆盖配置的环境变量前缀
+)
 
-	if err := v.ReadInConfig(); err != nil {
-		logger.Error("Failed to read configuration file", zap.Error(err))
+// InitConfig 初始化配置并返回 ConfigManager，配置无法加载或校验失败时退出进程
+func InitConfig() *ConfigManager {
+	cm, err := NewConfigManager()
+	if err != nil {
+		logger.Error("Failed to initialize configuration", zap.String("path", configPath()), zap.Error(err))
 		os.Exit(1)
 	}
-	if err := v.Unmarshal(cfg); err != nil {
-		logger.Error("Failed to unmarshal configuration", zap.Error(err))
-		os.Exit(1)
+	configMgr = cm
+
+	// 监听配置文件及配置目录的变化以实现热更新，任一文件变化时重新合并全部文件，监听持续到进程退出
+	if _, err := configMgr.watch(); err != nil {
+		logger.Error("Failed to watch configuration files", zap.String("path", configMgr.path), zap.Error(err))
 	}
 
-	if err := validateGRPCConfig(cfg); err != nil {
-		logger.Error("gRPC configuration validation failed", zap.Error(err))
-		os.Exit(1)
+	return configMgr
+}
+
+// NewConfigManager 按 GATEWAY_CONFIG_PATH 加载并校验配置，创建不监听文件变化的 ConfigManager
+func NewConfigManager() (*ConfigManager, error) {
+	path := configPath()
+	files, err := resolveConfigFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve configuration files: %w", err)
 	}
-	if err := validateWebSocketConfig(cfg); err != nil {
-		logger.Error("WebSocket configuration validation failed", zap.Error(err))
-		os.Exit(1)
+	cfg, err := loadConfigFiles(files)
+	if err != nil {
+		return nil, fmt.Errorf("load configuration: %w", err)
+	}
+	if err := Validate(cfg); err != nil {
+		return nil, fmt.Errorf("validate configuration: %w", err)
 	}
 
-	configMgr = &ConfigManager{
+	return &ConfigManager{
 		config:     cfg,
+		path:       path,
+		files:      files,
 		ConfigChan: make(chan *Config, 1), // 缓冲通道，避免阻塞
-		mutex:      sync.RWMutex{},
+	}, nil
+}
+
+// LoadConfig 按 GATEWAY_CONFIG_PATH 加载并合并配置，不做校验也不监听变化，返回配置及实际加载的文件
+func LoadConfig() (*Config, []string, error) {
+	files, err := resolveConfigFiles(configPath())
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := loadConfigFiles(files)
+	if err != nil {
+		return nil, files, err
 	}
+	return cfg, files, nil
+}
 
-	// 监听配置文件变化以实现热更新
-	v.WatchConfig()
-	v.OnConfigChange(func(e fsnotify.Event) {
-		logger.Info("Configuration file changed", zap.String("file", e.Name))
-		newCfg := &Config{}
+// configPath 返回配置路径，未设置环境变量时使用默认配置文件
+func configPath() string {
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path
+	}
+	return defaultConfigPath
+}
 
-		newV := viper.New()
-		newV.SetConfigFile(e.Name)
-		newV.SetConfigType("yaml")
-		setDefaultValues(newV)
+// resolveConfigFiles 将配置路径解析为按合并顺序排列的文件列表
+// 目录按文件名排序加载其中的 .yaml/.yml 文件，逗号分隔的列表按书写顺序加载
+func resolveConfigFiles(configPath string) ([]string, error) {
+	var files []string
+	for _, path := range strings.Split(configPath, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
 
-		if err := newV.ReadInConfig(); err != nil {
-			logger.Error("Failed to read configuration file", zap.Error(err))
-			os.Exit(1)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
 		}
-		if err := newV.Unmarshal(newCfg); err != nil {
-			logger.Error("Failed to unmarshal configuration", zap.Error(err))
-			os.Exit(1)
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
 		}
 
-		if err := viper.Unmarshal(newCfg); err != nil {
-			logger.Error("Failed to reload configuration", zap.Error(err))
-			return
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
 		}
-		if err := validateGRPCConfig(newCfg); err != nil {
-			logger.Error("gRPC configuration validation failed on reload", zap.Error(err))
-			return
+		var dirFiles []string
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				dirFiles = append(dirFiles, filepath.Join(path, entry.Name()))
+			}
 		}
-		if err := validateWebSocketConfig(newCfg); err != nil {
-			logger.Error("WebSocket configuration validation failed on reload", zap.Error(err))
-			return
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no configuration files found in %s", configPath)
+	}
+	return files, nil
+}
+
+// watch 监听配置路径的变化，文件变化、目录中新增或删除配置文件时重新加载配置
+// 单个文件通过其所在目录监听，以便编辑器先删除再重建文件时也能收到通知
+// 返回的 stop 关闭监听并等待监听协程退出，可重复调用
+func (cm *ConfigManager) watch() (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]struct{}) // 以目录方式配置的路径，其中任意配置文件的变化都会触发重新加载
+	files := make(map[string]struct{})
+	for _, path := range strings.Split(cm.path, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
 		}
+		path = filepath.Clean(path)
+		watchDir := filepath.Dir(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs[path] = struct{}{}
+			watchDir = path
+		} else {
+			files[path] = struct{}{}
+		}
+		if err := watcher.Add(watchDir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("watch %s: %w", watchDir, err)
+		}
+	}
 
-		configMgr.mutex.Lock()
-		configMgr.config = newCfg
-		configMgr.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isConfigChange(event, dirs, files) {
+					continue
+				}
+				logger.Info("Configuration file changed",
+					zap.String("file", event.Name),
+					zap.String("op", event.Op.String()))
+				if err := cm.Reload(); err != nil {
+					logger.Error("Configuration reload failed, keeping current configuration", zap.Error(err))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Configuration watcher error", zap.Error(err))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// 关闭监听会关闭事件通道，监听协程处理完当前事件后退出
+			watcher.Close()
+			<-done
+		})
+	}, nil
+}
 
-		// 通知配置变更
-		select {
-		case configMgr.ConfigChan <- newCfg:
-			logger.Info("Configuration reload notification sent")
-		default:
-			logger.Warn("Config channel full, skipping notification")
+// isConfigChange 判断文件事件是否影响已加载的配置：配置目录中的 .yaml/.yml 文件或单独指定的配置文件
+func isConfigChange(event fsnotify.Event, dirs, files map[string]struct{}) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) &&
+		!event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	if _, ok := files[name]; ok {
+		return true
+	}
+	if _, ok := dirs[filepath.Dir(name)]; ok {
+		ext := filepath.Ext(name)
+		return ext == ".yaml" || ext == ".yml"
+	}
+	return false
+}
+
+// loadConfigFiles 依次读取并合并配置文件，后面的文件覆盖前面的同名配置
+// 路由规则按路径合并：同一路径以后面文件中的规则为准，其余路径保留
+// 环境变量优先级最高，见 bindEnv
+func loadConfigFiles(files []string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	setDefaultValues(v)
+	bindEnv(v)
+
+	for i, file := range files {
+		v.SetConfigFile(file)
+		var err error
+		if i == 0 {
+			err = v.ReadInConfig()
+		} else {
+			err = v.MergeInConfig()
 		}
-	})
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", file, err)
+		}
+	}
 
-	return configMgr
+	cfg := &Config{}
+	decodeHook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		pluginEntryDecodeHook,
+	))
+	if err := v.Unmarshal(cfg, decodeHook); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 // Grayscale 灰度发布配置
@@ -134,32 +294,301 @@ type Grayscale struct {
 	WeightedRandom bool   `mapstructure:"weightedRandom"` // 是否在灰度发布中使用权重随机路由
 	DefaultEnv     string `mapstructure:"defaultEnv"`     // 默认环境（如 "stable"）
 	CanaryEnv      string `mapstructure:"canaryEnv"`      // 灰度环境（如 "canary"）
+	SessionCookie  string `mapstructure:"sessionCookie"`  // 按比例灰度时用于固定分流结果的会话 Cookie，缺失时使用客户端 IP
+}
+
+// FanOut 扇出配置，请求并行发往多个目标，达到法定数量的一致响应后返回
+// 扇出会把同一请求发往多个后端，默认只对 GET、HEAD、OPTIONS 生效，其他方法需在 methods 中显式列出
+type FanOut struct {
+	Targets int      `mapstructure:"targets"` // 并行请求的目标数 N，按权重从高到低选取
+	Quorum  int      `mapstructure:"quorum"`  // 需要一致的成功响应数 M
+	Methods []string `mapstructure:"methods"` // 允许扇出的请求方法，为空时只扇出安全方法，其他方法按普通请求转发到单个目标
+}
+
+// AllowsMethod 判断该请求方法是否扇出
+func (f FanOut) AllowsMethod(method string) bool {
+	if len(f.Methods) == 0 {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return true
+		}
+		return false
+	}
+	for _, m := range f.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Coalesce 请求合并配置，时间窗口内相同的 GET 请求只向后端发送一次，所有请求共享同一响应
+type Coalesce struct {
+	Window time.Duration `mapstructure:"window"` // 合并窗口，自首个请求起该时间内到达的相同请求共享其响应
+}
+
+// Retry 上游请求失败时的重试策略，只对幂等方法生效，重试发往同一目标
+// 默认只在连接建立失败时重试，此时请求一定未到达后端；按状态码重试时后端可能已部分处理请求，需确认接口可以安全重放
+// 每次重试前按指数退避等待：第 n 次重试等待 backoff×2^(n-1)，不超过 maxBackoff，并在其一半到全部之间随机取值，避免大量请求同时重试
+type Retry struct {
+	Attempts    int           `mapstructure:"attempts"`    // 失败后的最大重试次数，0 表示不重试
+	On          string        `mapstructure:"on"`          // 重试条件：connection 仅连接错误，status 连接错误及 statusCodes 中的状态码
+	StatusCodes []int         `mapstructure:"statusCodes"` // on 为 status 时触发重试的上游状态码，如 502、503
+	Backoff     time.Duration `mapstructure:"backoff"`     // 首次重试前的等待时间，0 表示不等待
+	MaxBackoff  time.Duration `mapstructure:"maxBackoff"`  // 重试等待时间的上限
+}
+
+// DefaultKetamaVirtualNodes ketama 每个目标默认的虚拟节点数
+const DefaultKetamaVirtualNodes = 160
+
+// Ketama 一致性哈希环配置
+// 虚拟节点越多，各目标分到的流量越均匀，哈希环占用的内存和重建耗时也越多，100-160 通常足够
+type Ketama struct {
+	VirtualNodes int `mapstructure:"virtualNodes"` // 每个目标在哈希环上的虚拟节点数，须为正数
+}
+
+// EWMA 按后端近期响应时间选择目标的负载均衡配置
+type EWMA struct {
+	Decay     time.Duration `mapstructure:"decay"`     // 响应时间平均值的衰减时间常数，越小越快反映后端变化，须为正数
+	ProbeRate float64       `mapstructure:"probeRate"` // 随机选择目标的请求比例（0-1），使较慢的目标恢复后能被重新发现
+}
+
+// HealthCheckDefaults 各协议的默认健康检查目标，路由规则未设置 healthCheckPath 时使用
+type HealthCheckDefaults struct {
+	HTTPPath      string `mapstructure:"httpPath"`      // HTTP 目标的探测路径
+	GRPCService   string `mapstructure:"grpcService"`   // gRPC 健康检查协议中的服务名，为空表示检查整个服务器
+	WebSocketPath string `mapstructure:"websocketPath"` // WebSocket 目标握手的路径
+
+	Environments map[string]ProbeSettings `mapstructure:"environments"` // 按目标环境设置探测间隔与超时，如对灰度目标探测更频繁
+}
+
+// ProbeSettings 主动健康检查的探测间隔与超时，为 0 的字段沿用 heartbeatInterval 与默认超时
+type ProbeSettings struct {
+	Interval time.Duration `mapstructure:"interval"` // 探测间隔
+	Timeout  time.Duration `mapstructure:"timeout"`  // 探测超时
+}
+
+// ForEnv 返回指定环境的探测设置，环境名不区分大小写，未配置时返回零值
+func (d HealthCheckDefaults) ForEnv(env string) ProbeSettings {
+	return d.Environments[strings.ToLower(env)]
+}
+
+// ForProtocol 返回协议的默认健康检查目标：HTTP 与 WebSocket 为路径，gRPC 为服务名
+func (d HealthCheckDefaults) ForProtocol(protocol string) string {
+	switch protocol {
+	case "grpc":
+		return d.GRPCService
+	case "websocket":
+		return d.WebSocketPath
+	}
+	return d.HTTPPath
+}
+
+// StartupProbe 启动时同步探测所有目标一次并记录可达情况，不必等待第一次心跳
+type StartupProbe struct {
+	Enabled       bool    `mapstructure:"enabled"`       // 是否在启动时探测
+	FailThreshold float64 `mapstructure:"failThreshold"` // 不可达目标占比达到该值时启动失败，取值 0-1，0 表示只记录结果不影响启动
+}
+
+// RequestSigning 转发请求的 HMAC-SHA256 签名，后端使用共享密钥校验签名（见 pkg/signing），只信任来自网关的请求
+type RequestSigning struct {
+	Enabled   bool          `mapstructure:"enabled"`   // 是否为转发的请求签名
+	Secret    string        `mapstructure:"secret"`    // 与后端共享的签名密钥
+	Header    string        `mapstructure:"header"`    // 签名所在的请求头，时间戳写入 X-Gateway-Timestamp
+	Fields    []string      `mapstructure:"fields"`    // 按顺序参与签名的字段：method、path、query、timestamp，必须包含 timestamp
+	Tolerance time.Duration `mapstructure:"tolerance"` // 后端校验时允许的时间戳偏差，超出视为重放，后端应与此处保持一致
+}
+
+// ResponseHeaderLimit 上游响应头大小限制，避免超大的响应头（如过长的 Set-Cookie）被转发后导致客户端无法解析响应
+type ResponseHeaderLimit struct {
+	MaxSize int    `mapstructure:"maxSize"` // 单个响应头名称与值的最大字节数之和，0 表示不限制
+	Action  string `mapstructure:"action"`  // 超出时的处理方式：strip 删除该响应头，truncate 将值截断到上限
+}
+
+// ErrorPassthrough 上游错误响应透传配置，启用后网关无法给出正常响应时返回上游的 5xx 响应而非通用错误
+type ErrorPassthrough struct {
+	MaxBodySize int `mapstructure:"maxBodySize"` // 透传响应体的最大字节数，超出部分截断
+}
+
+// RouteScript 路由脚本配置，脚本可读取、修改请求头或直接返回响应
+type RouteScript struct {
+	Source  string        `mapstructure:"source"`  // 内联 Lua 脚本
+	File    string        `mapstructure:"file"`    // Lua 脚本文件路径，与 source 二选一
+	Timeout time.Duration `mapstructure:"timeout"` // 单次执行超时时间
+}
+
+// OutlierDetection 被动健康检查配置，根据真实请求结果剔除异常目标
+type OutlierDetection struct {
+	Enabled             bool                        `mapstructure:"enabled"`             // 是否启用被动健康检查
+	ConsecutiveFailures int                         `mapstructure:"consecutiveFailures"` // 连续失败次数达到该值时剔除目标
+	EjectionDuration    time.Duration               `mapstructure:"ejectionDuration"`    // 目标被剔除的冷却时间
+	Environments        map[string]OutlierThreshold `mapstructure:"environments"`        // 按环境覆盖剔除阈值，如对灰度目标更严格
+}
+
+// OutlierThreshold 单个环境的剔除阈值，零值字段沿用全局配置
+type OutlierThreshold struct {
+	ConsecutiveFailures int           `mapstructure:"consecutiveFailures"` // 连续失败次数达到该值时剔除目标
+	EjectionDuration    time.Duration `mapstructure:"ejectionDuration"`    // 目标被剔除的冷却时间
+}
+
+// ForEnv 返回指定环境生效的剔除阈值，环境名不区分大小写
+func (o OutlierDetection) ForEnv(env string) OutlierThreshold {
+	threshold := OutlierThreshold{
+		ConsecutiveFailures: o.ConsecutiveFailures,
+		EjectionDuration:    o.EjectionDuration,
+	}
+	override, ok := o.Environments[strings.ToLower(env)]
+	if !ok {
+		return threshold
+	}
+	if override.ConsecutiveFailures > 0 {
+		threshold.ConsecutiveFailures = override.ConsecutiveFailures
+	}
+	if override.EjectionDuration > 0 {
+		threshold.EjectionDuration = override.EjectionDuration
+	}
+	return threshold
 }
 
 // Plugin 插件配置
 type Plugin struct {
-	Dir     string   `mapstructure:"dir"`     // 插件目录
-	Plugins []string `mapstructure:"plugins"` // 插件列表
+	Dir     string        `mapstructure:"dir"`     // 插件目录
+	Plugins []PluginEntry `mapstructure:"plugins"` // 插件列表，为空时加载插件目录中的全部插件
+	Wasm    WasmPlugins   `mapstructure:"wasm"`    // WASM 请求过滤插件
+}
+
+// WasmPlugins WASM 请求过滤插件配置
+type WasmPlugins struct {
+	Timeout          time.Duration `mapstructure:"timeout"`          // 单个模块单次执行超时时间
+	MemoryLimitPages uint32        `mapstructure:"memoryLimitPages"` // 单个模块实例可用的最大内存页数（每页 64KiB），0 表示默认的 256 页（16MiB）
+	Modules          []WasmModule  `mapstructure:"modules"`          // 按列表顺序在 .so 插件之后执行
+}
+
+// WasmModule 单个 WASM 模块配置
+type WasmModule struct {
+	Name string `mapstructure:"name"` // 模块名，用于日志和插件状态
+	Path string `mapstructure:"path"` // .wasm 文件路径
+}
+
+// PluginEntry 单个插件的配置，兼容只写插件名的旧格式
+type PluginEntry struct {
+	Name    string `mapstructure:"name"`    // 插件名，对应插件目录中的 <name>.so
+	Enabled bool   `mapstructure:"enabled"` // 是否启用，未设置时为 true
+	Order   int    `mapstructure:"order"`   // 加载顺序，值小的先加载并先注册中间件和拦截器，相同时按配置顺序
+}
+
+// Ordered 按加载顺序返回插件配置
+func (p Plugin) Ordered() []PluginEntry {
+	entries := append([]PluginEntry(nil), p.Plugins...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Order < entries[j].Order
+	})
+	return entries
+}
+
+// pluginEntryDecodeHook 将只写插件名的旧配置转为 PluginEntry，并将未设置 enabled 的插件视为启用
+func pluginEntryDecodeHook(from, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(PluginEntry{}) {
+		return data, nil
+	}
+	switch entry := data.(type) {
+	case string:
+		return map[string]any{"name": entry, "enabled": true}, nil
+	case map[string]any:
+		if _, ok := entry["enabled"]; !ok {
+			withDefault := make(map[string]any, len(entry)+1)
+			for k, v := range entry {
+				withDefault[k] = v
+			}
+			withDefault["enabled"] = true
+			return withDefault, nil
+		}
+	}
+	return data, nil
+}
+
+// validatePlugins 校验插件名非空且不重复
+func validatePlugins(entries []PluginEntry) error {
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if entry.Name == "" {
+			return fmt.Errorf("plugin %d has no name", i)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("plugin %q is configured more than once", entry.Name)
+		}
+		seen[entry.Name] = true
+	}
+	return nil
+}
+
+// MaxWasmMemoryPages WASM 32 位内存最多可用的页数（4GiB）
+const MaxWasmMemoryPages = 65536
+
+// validateWasmPlugins 校验 WASM 模块名非空且不重复，并且配置了文件路径，内存上限不超过 WASM 允许的最大页数
+func validateWasmPlugins(wasm WasmPlugins) error {
+	if wasm.Timeout < 0 {
+		return fmt.Errorf("timeout %s must not be negative", wasm.Timeout)
+	}
+	if wasm.MemoryLimitPages > MaxWasmMemoryPages {
+		return fmt.Errorf("memoryLimitPages %d exceeds the WASM maximum of %d pages", wasm.MemoryLimitPages, MaxWasmMemoryPages)
+	}
+	seen := make(map[string]bool, len(wasm.Modules))
+	for i, module := range wasm.Modules {
+		if module.Name == "" {
+			return fmt.Errorf("module %d has no name", i)
+		}
+		if seen[module.Name] {
+			return fmt.Errorf("module %q is configured more than once", module.Name)
+		}
+		seen[module.Name] = true
+		if module.Path == "" {
+			return fmt.Errorf("module %q has no path", module.Name)
+		}
+	}
+	return nil
 }
 
 // FileServer 文件服务器配置
 type FileServer struct {
-	StaticFilePath  string `mapstructure:"staticFilePath"`  // 静态文件路径
+	StaticFilePath  string `mapstructure:"staticFilePath"`  // 静态文件路径，挂载在 /static 下
 	EnabledFastHttp bool   `mapstructure:"enabledFastHttp"` // 是否启用 fasthttp
+	Listing         bool   `mapstructure:"listing"`         // 请求目录且目录下没有 index.html 时是否列出目录内容
+	// 额外的路径前缀到本地目录的映射，如 /docs: ./site/docs，前缀需以 / 开头且不能为 /
+	Mounts map[string]string `mapstructure:"mounts"`
+}
+
+// validateFileServer 校验文件服务的挂载前缀与目录
+func validateFileServer(fs FileServer) []error {
+	var errs []error
+	for prefix, dir := range fs.Mounts {
+		if !strings.HasPrefix(prefix, "/") || strings.TrimRight(prefix, "/") == "" {
+			errs = append(errs, fmt.Errorf("fileServer mount %q must start with / and must not be /", prefix))
+		}
+		if strings.TrimRight(prefix, "/") == "/static" && fs.StaticFilePath != "" {
+			errs = append(errs, fmt.Errorf("fileServer mount %q conflicts with staticFilePath", prefix))
+		}
+		if dir == "" {
+			errs = append(errs, fmt.Errorf("fileServer mount %q has no directory", prefix))
+		}
+	}
+	return errs
 }
 
 // Performance 性能相关配置
 type Performance struct {
-	MemoryPool      MemoryPool `mapstructure:"memoryPool"`
+	MemoryPool      MemoryPool `mapstructure:"memoryPool"`      // 路由规则与目标列表的对象池
 	MaxConnsPerHost int        `mapstructure:"maxConnsPerHost"` // 每个目标的最大连接数
 	HttpPoolEnabled bool       `mapstructure:"httpPoolEnabled"` // 是否启用 HTTP 连接池
+	// 目标被标记为不可用时是否关闭并移除其连接池客户端，恢复后重新建立连接
+	EvictUnhealthyClients bool `mapstructure:"evictUnhealthyClients"`
 }
 
 // MemoryPool 内存池配置
 type MemoryPool struct {
-	Enabled         bool `mapstructure:"enabled"`
-	TargetsCapacity int  `mapstructure:"targetsCapacity"`
-	RulesCapacity   int  `mapstructure:"rulesCapacity"`
+	Enabled         bool `mapstructure:"enabled"`         // 是否复用选择目标时分配的切片
+	TargetsCapacity int  `mapstructure:"targetsCapacity"` // 目标切片的初始容量
+	RulesCapacity   int  `mapstructure:"rulesCapacity"`   // 规则切片的初始容量
 }
 
 // Consul Consul 服务发现配置
@@ -200,50 +629,461 @@ type GRPCConfig struct {
 	HealthCheckPath string   `mapstructure:"healthCheckPath"`
 	Reflection      bool     `mapstructure:"reflection"`
 	AllowedOrigins  []string `mapstructure:"allowedOrigins"`
+	MountPattern    string   `mapstructure:"mountPattern"` // 路由挂载模式，{path} 替换为规则路径，如 "{path}/*any" 可匹配所有子路径
+	Web             bool     `mapstructure:"web"`          // 是否接受浏览器的 gRPC-Web 请求，跨域来源由 allowedOrigins 控制
 }
 
 // Middleware 中间件开关配置
 type Middleware struct {
-	RateLimit     bool `mapstructure:"rateLimit"`
-	IPAcl         bool `mapstructure:"ipAcl"`
-	AntiInjection bool `mapstructure:"antiInjection"`
-	Auth          bool `mapstructure:"auth"`
-	Breaker       bool `mapstructure:"breaker"`
-	Tracing       bool `mapstructure:"tracing"`
+	RateLimit     bool `mapstructure:"rateLimit"`     // 限流，算法与阈值见 traffic.rateLimit
+	IPAcl         bool `mapstructure:"ipAcl"`         // IP 黑白名单，名单见 security.ipBlacklist/ipWhitelist
+	AntiInjection bool `mapstructure:"antiInjection"` // 请求参数注入检测
+	Auth          bool `mapstructure:"auth"`          // 认证，方式见 security.authMode
+	Breaker       bool `mapstructure:"breaker"`       // 熔断，还需开启 traffic.breaker.enabled
+	Tracing       bool `mapstructure:"tracing"`       // 分布式追踪，导出配置见 observability.jaeger
 }
 
 // Caching 业务缓存策略配置
 type Caching struct {
-	Enabled bool          `mapstructure:"enabled"`
-	Rules   []CachingRule `mapstructure:"rules"`
+	Enabled       bool          `mapstructure:"enabled"`       // 是否缓存后端响应
+	Rules         []CachingRule `mapstructure:"rules"`         // 按路径匹配的缓存规则，未匹配的路由不缓存
+	FailurePolicy string        `mapstructure:"failurePolicy"` // 读取缓存时 Redis 失败的处理方式，默认 open 按未命中转发
 }
 
+// Redis 操作失败时依赖 Redis 的中间件的处理方式
+const (
+	FailurePolicyOpen   = "open"   // 跳过该中间件的检查，放行请求
+	FailurePolicyClosed = "closed" // 返回 503 拒绝请求
+)
+
 // CachingRule 定义单个缓存规则
 type CachingRule struct {
 	Path      string        `mapstructure:"path"`
 	Method    string        `mapstructure:"method"`
 	Threshold int           `mapstructure:"threshold"`
 	TTL       time.Duration `mapstructure:"ttl"`
+	Compress  bool          `mapstructure:"compress"` // 是否以 gzip 压缩形式缓存响应，支持 gzip 的客户端直接获得压缩内容
+	// 是否缓存携带 Authorization 的请求，开启后缓存按身份隔离；默认不缓存，避免响应在用户之间泄露
+	Authenticated bool `mapstructure:"authenticated"`
+	// 最近一次成功响应的保留时间，供降级策略 cached 在熔断或无可用目标时返回，为 0 时不保留
+	StaleTTL time.Duration `mapstructure:"staleTTL"`
 }
 
 // Cache 缓存配置
 type Cache struct {
-	Addr     string `mapstructure:"addr"`
-	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
+	Addr     string `mapstructure:"addr"`     // standalone 模式的 Redis 地址
+	Password string `mapstructure:"password"` // Redis 密码
+	DB       int    `mapstructure:"db"`       // 数据库编号，cluster 模式不支持
+
+	ReconnectInterval time.Duration `mapstructure:"reconnectInterval"` // Redis 不可用时后台检查连接的间隔，连接恢复前依赖 Redis 的功能按各自的 failurePolicy 处理
+
+	Mode         string        `mapstructure:"mode"`         // 部署模式：standalone（默认）、cluster、sentinel
+	Addrs        []string      `mapstructure:"addrs"`        // cluster 模式的节点地址或 sentinel 模式的哨兵地址
+	MasterName   string        `mapstructure:"masterName"`   // sentinel 模式的主节点名称
+	PoolSize     int           `mapstructure:"poolSize"`     // 每个节点的最大连接数，为 0 时使用客户端默认值（每个 CPU 10 个）
+	MinIdleConns int           `mapstructure:"minIdleConns"` // 每个节点保持的最少空闲连接数
+	DialTimeout  time.Duration `mapstructure:"dialTimeout"`  // 建立连接的超时时间
+	ReadTimeout  time.Duration `mapstructure:"readTimeout"`  // 读取命令结果的超时时间
+	WriteTimeout time.Duration `mapstructure:"writeTimeout"` // 写入命令的超时时间
+
+	Backend    string `mapstructure:"backend"`    // 缓存后端：redis（默认）或 memory（进程内缓存，适合单实例部署）
+	MaxEntries int    `mapstructure:"maxEntries"` // memory 后端最多保存的键数量，超过时淘汰最久未访问的键
+}
+
+// 缓存后端
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+)
+
+// Redis 部署模式
+const (
+	CacheModeStandalone = "standalone"
+	CacheModeCluster    = "cluster"
+	CacheModeSentinel   = "sentinel"
+)
+
+// Endpoint 返回用于日志和状态展示的 Redis 地址
+func (c Cache) Endpoint() string {
+	if c.Mode == CacheModeCluster || c.Mode == CacheModeSentinel {
+		return strings.Join(c.Addrs, ",")
+	}
+	return c.Addr
+}
+
+// validateCache 校验缓存后端，以及 Redis 部署模式与连接池、超时设置
+func validateCache(c Cache) []error {
+	var errs []error
+	switch c.Backend {
+	case "", CacheBackendRedis:
+	case CacheBackendMemory:
+		if c.MaxEntries < 0 {
+			errs = append(errs, fmt.Errorf("maxEntries %d must not be negative", c.MaxEntries))
+		}
+		return errs
+	default:
+		return append(errs, fmt.Errorf("unknown backend: %q", c.Backend))
+	}
+
+	switch c.Mode {
+	case "", CacheModeStandalone:
+	case CacheModeCluster:
+		if len(c.Addrs) == 0 {
+			errs = append(errs, fmt.Errorf("addrs is required in cluster mode"))
+		}
+		if c.DB != 0 {
+			errs = append(errs, fmt.Errorf("db %d is not supported in cluster mode", c.DB))
+		}
+	case CacheModeSentinel:
+		if len(c.Addrs) == 0 {
+			errs = append(errs, fmt.Errorf("addrs is required in sentinel mode"))
+		}
+		if c.MasterName == "" {
+			errs = append(errs, fmt.Errorf("masterName is required in sentinel mode"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown mode: %q", c.Mode))
+	}
+
+	if c.PoolSize < 0 {
+		errs = append(errs, fmt.Errorf("poolSize %d must not be negative", c.PoolSize))
+	}
+	if c.MinIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("minIdleConns %d must not be negative", c.MinIdleConns))
+	}
+	if c.PoolSize > 0 && c.MinIdleConns > c.PoolSize {
+		errs = append(errs, fmt.Errorf("minIdleConns %d must not exceed poolSize %d", c.MinIdleConns, c.PoolSize))
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"dialTimeout", c.DialTimeout},
+		{"readTimeout", c.ReadTimeout},
+		{"writeTimeout", c.WriteTimeout},
+		{"reconnectInterval", c.ReconnectInterval},
+	} {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%s %s must not be negative", timeout.name, timeout.value))
+		}
+	}
+	return errs
 }
 
 // RoutingRule 路由规则定义
 type RoutingRule struct {
-	Target          string `mapstructure:"target"`
-	Weight          int    `mapstructure:"weight"`
-	Env             string `mapstructure:"env"`
-	Protocol        string `mapstructure:"protocol"`
-	HealthCheckPath string `mapstructure:"healthCheckPath"`
+	Target              string        `mapstructure:"target"`
+	Weight              int           `mapstructure:"weight"`
+	Env                 string        `mapstructure:"env"`                 // 目标所属环境，如 stable、canary，灰度发布按环境选择目标
+	CanaryWeight        int           `mapstructure:"canaryWeight"`        // 灰度目标自动承接的流量百分比（0-100），仅对 env 为灰度环境的规则生效
+	Protocol            string        `mapstructure:"protocol"`            // 后端协议：http（默认）、grpc 或 websocket
+	HealthCheckPath     string        `mapstructure:"healthCheckPath"`     // 健康检查路径（gRPC 为服务名），为空时使用 routing.healthCheck 中对应协议的默认值
+	HealthCheckInterval time.Duration `mapstructure:"healthCheckInterval"` // 探测间隔，为 0 时使用 routing.healthCheck 中所属环境的设置或 routing.heartbeatInterval
+	HealthCheckTimeout  time.Duration `mapstructure:"healthCheckTimeout"`  // 探测超时，为 0 时使用 routing.healthCheck 中所属环境的设置，默认 5 秒
+	ReadinessCheckPath  string        `mapstructure:"readinessCheckPath"`  // 就绪探测路径（gRPC 为服务名），设置后目标首次就绪探测成功前不接收流量
+	Mirror              Mirror        `mapstructure:"mirror"`              // 流量镜像，转发到该目标的请求按比例复制到镜像目标
+	Fallback            Fallback      `mapstructure:"fallback"`            // 路由无可用目标或熔断时的降级响应
+	Methods             []string      `mapstructure:"methods"`             // 允许的请求方法，为空时不限制，允许 GET 时同时允许 HEAD
+	MinShare            int           `mapstructure:"minShare"`            // 加权轮询时保证的最低流量百分比（0-100），用于新实例预热，权重再低也至少分到该比例
+	Priority            int           `mapstructure:"priority"`            // 路由优先级，多个路由都能匹配请求时数值大的优先，同一路由取各规则中的最大值
+	Host                string        `mapstructure:"host"`                // 只处理该 Host 的请求，支持 *.example.com 通配子域名，为空时处理所有 Host
+
+	// 转发前改写 JSON 请求体，如包装请求或为旧版后端重命名字段
+	RequestTransform RequestTransform `mapstructure:"requestTransform"`
+	// 返回客户端前从 JSON 响应中删除的字段路径，如 user.password；路径经过数组时对每个元素生效
+	ResponseFilter []string `mapstructure:"responseFilter"`
+	// 转发到该目标时补充的默认请求头，覆盖 routing.defaultHeaders 中的同名项，值为空表示不补充该请求头
+	DefaultHeaders map[string]string `mapstructure:"defaultHeaders"`
+	// 将 gRPC 服务端流式方法以 Server-Sent Events 形式提供给 HTTP 客户端，仅对 gRPC 规则生效
+	SSE SSEBridge `mapstructure:"sse"`
+}
+
+// SSEBridge gRPC 服务端流式方法到 SSE 的桥接，网关打开 gRPC 流并将每条消息作为一个 SSE 事件发送
+type SSEBridge struct {
+	Method string `mapstructure:"method"` // 服务端流式方法全名，如 /grpc.health.v1.Health/Watch，为空时不启用
+}
+
+// sseMethodPattern 合法的 gRPC 方法全名：/包名.服务名/方法名
+var sseMethodPattern = regexp.MustCompile(`^/[A-Za-z_][A-Za-z0-9_.]*/[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateSSEBridge 校验 SSE 桥接只配置在 gRPC 规则上且方法名格式正确
+func validateSSEBridge(protocol string, bridge SSEBridge) error {
+	if bridge.Method == "" {
+		return nil
+	}
+	if protocol != "grpc" {
+		return fmt.Errorf("requires protocol grpc")
+	}
+	if !sseMethodPattern.MatchString(bridge.Method) {
+		return fmt.Errorf("method %q must have the form /package.Service/Method", bridge.Method)
+	}
+	return nil
+}
+
+// RequestTransform JSON 请求体的改写规则，先按 Rename 重命名顶层字段，再按 Template 生成新的请求体
+// 只处理 JSON 类型的请求体，其他请求体和格式错误的 JSON 原样转发
+type RequestTransform struct {
+	Rename   []FieldRename `mapstructure:"rename"`   // 顶层字段重命名
+	Template string        `mapstructure:"template"` // Go text/template 模板，数据为解析后的 JSON，可用 json 函数输出 JSON 值，结果须为合法 JSON
+}
+
+// FieldRename 字段重命名，用列表而不是映射配置，避免字段名被转为小写
+type FieldRename struct {
+	From string `mapstructure:"from"`
+	To   string `mapstructure:"to"`
+}
+
+// Enabled 检查是否配置了请求体改写
+func (t RequestTransform) Enabled() bool {
+	return len(t.Rename) > 0 || t.Template != ""
+}
+
+// templateFuncs 请求体模板可用的函数
+var templateFuncs = template.FuncMap{
+	// json 将值编码为 JSON，如 {"data": {{json .}}}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// escapeJSONString 按 JSON 字符串的规则转义值，不含两侧引号，由 escapeTemplateActions 自动追加到输出动作
+	"escapeJSONString": func(v any) string {
+		b, _ := json.Marshal(fmt.Sprint(v))
+		return string(b[1 : len(b)-1])
+	},
+}
+
+// ParseTemplate 解析请求体模板，未配置模板时返回 nil
+// 模板中直接输出的值都会按 JSON 字符串转义，见 escapeTemplateActions
+func (t RequestTransform) ParseTemplate() (*template.Template, error) {
+	if t.Template == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("requestTransform").Funcs(templateFuncs).Parse(t.Template)
+	if err != nil {
+		return nil, err
+	}
+	for _, defined := range tmpl.Templates() {
+		escapeTemplateActions(defined.Tree.Root)
+	}
+	return tmpl, nil
+}
+
+// escapeTemplateActions 在模板每个输出动作的管道末尾追加 escapeJSONString，
+// 使请求中的值即使包含引号也只能作为字符串内容输出，如 "{{.name}}"，无法改变生成的 JSON 结构；
+// 以 json 函数结尾的动作已输出合法的 JSON 值，保持不变
+func escapeTemplateActions(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeTemplateActions(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || endsWithFunc(n.Pipe, "json") {
+			return
+		}
+		escape := parse.NewIdentifier("escapeJSONString").SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{escape}})
+	case *parse.IfNode:
+		escapeTemplateActions(n.List)
+		escapeTemplateActions(n.ElseList)
+	case *parse.RangeNode:
+		escapeTemplateActions(n.List)
+		escapeTemplateActions(n.ElseList)
+	case *parse.WithNode:
+		escapeTemplateActions(n.List)
+		escapeTemplateActions(n.ElseList)
+	}
+}
+
+// endsWithFunc 判断管道的最后一个命令是否调用了指定的函数
+func endsWithFunc(pipe *parse.PipeNode, name string) bool {
+	if len(pipe.Cmds) == 0 {
+		return false
+	}
+	ident, ok := pipe.Cmds[len(pipe.Cmds)-1].Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == name
+}
+
+// 降级策略
+const (
+	FallbackStrategyError  = "error"  // 返回网关默认的 503 错误
+	FallbackStrategyStatic = "static" // 返回配置的响应体或重定向
+	FallbackStrategyCached = "cached" // 返回响应缓存中保留的最近一次成功响应，没有时按 static 处理
+)
+
+// Fallback 路由不可用时的降级响应，配置 RedirectURL 时重定向，否则返回固定响应体
+type Fallback struct {
+	Status      int    `mapstructure:"status"`      // 响应状态码，为 0 时默认 503，重定向时默认 302
+	Body        string `mapstructure:"body"`        // 响应体，如维护公告
+	ContentType string `mapstructure:"contentType"` // 响应体类型，为空时默认 application/json
+	RedirectURL string `mapstructure:"redirectURL"` // 重定向地址，设置后忽略 Body
+	Strategy    string `mapstructure:"strategy"`    // 降级策略：error、static 或 cached，为空时按 static 处理
+}
+
+// Enabled 检查是否配置了降级响应
+func (f Fallback) Enabled() bool {
+	return f.Status != 0 || f.Body != "" || f.RedirectURL != "" || f.Strategy != ""
+}
+
+// HasStatic 检查是否配置了固定的降级响应
+func (f Fallback) HasStatic() bool {
+	return f.Status != 0 || f.Body != "" || f.RedirectURL != ""
+}
+
+// Mirror 流量镜像配置，镜像请求异步发送且响应被丢弃，不影响客户端响应
+type Mirror struct {
+	Target     string `mapstructure:"target"`     // 镜像目标地址，为空时不镜像
+	Percentage int    `mapstructure:"percentage"` // 镜像的请求百分比（0-100）
 }
 
 type RoutingRules []RoutingRule
 
+// Fallback 返回路由的降级响应配置，多条规则配置时使用第一条
+func (i RoutingRules) Fallback() (Fallback, bool) {
+	for _, rule := range i {
+		if rule.Fallback.Enabled() {
+			return rule.Fallback, true
+		}
+	}
+	return Fallback{}, false
+}
+
+// Priority 返回路由的优先级，为各规则 Priority 的最大值
+func (i RoutingRules) Priority() int {
+	priority := 0
+	for j, rule := range i {
+		if j == 0 || rule.Priority > priority {
+			priority = rule.Priority
+		}
+	}
+	return priority
+}
+
+// ForHost 返回处理该 Host 请求的规则：优先精确匹配 Host 的规则，其次通配匹配的规则，最后是未配置 Host 的规则
+// 返回空时表示该路由不处理此 Host
+func (i RoutingRules) ForHost(host string) RoutingRules {
+	if !i.hasHosts() {
+		return i
+	}
+	host = normalizeHost(host)
+	var exact, wildcard, unscoped RoutingRules
+	for _, rule := range i {
+		pattern := strings.ToLower(rule.Host)
+		switch {
+		case pattern == "":
+			unscoped = append(unscoped, rule)
+		case pattern == host:
+			exact = append(exact, rule)
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			wildcard = append(wildcard, rule)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	if len(wildcard) > 0 {
+		return wildcard
+	}
+	return unscoped
+}
+
+// hasHosts 检查路由是否有规则限定了 Host
+func (i RoutingRules) hasHosts() bool {
+	for _, rule := range i {
+		if rule.Host != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost 去掉请求 Host 中的端口和末尾的点并转为小写
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// validHost 校验路由规则的 Host：只能是域名或 IP，通配符只能作为最左侧的整段标签
+func validHost(host string) bool {
+	name := strings.TrimPrefix(host, "*.")
+	if name == "" || strings.ContainsAny(name, "*/:") {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsMethod 检查路由是否允许该请求方法，任一规则未限制方法时允许所有方法
+func (i RoutingRules) AllowsMethod(method string) bool {
+	return len(i) == 0 || len(i.ForMethod(method)) > 0
+}
+
+// ForMethod 返回允许该请求方法的规则，未限制方法的规则允许所有方法；只能从这些规则中选择目标，
+// 否则同一路径下限定了方法的规则可能被选中处理其不允许的请求
+func (i RoutingRules) ForMethod(method string) RoutingRules {
+	var allowed RoutingRules
+	for n, rule := range i {
+		if rule.allowsMethod(method) {
+			if allowed != nil {
+				allowed = append(allowed, rule)
+			}
+			continue
+		}
+		// 遇到第一条不允许的规则时才复制，规则都允许时不分配内存
+		if allowed == nil {
+			allowed = append(make(RoutingRules, 0, len(i)), i[:n]...)
+		}
+	}
+	if allowed == nil {
+		return i
+	}
+	return allowed
+}
+
+// allowsMethod 检查单条规则是否允许该请求方法，允许 GET 时同时允许 HEAD
+func (r RoutingRule) allowsMethod(method string) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if strings.EqualFold(m, method) || (method == http.MethodHead && strings.EqualFold(m, http.MethodGet)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedMethods 返回路由允许的请求方法（大写、去重），用于 405 响应的 Allow 头
+func (i RoutingRules) AllowedMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	add := func(m string) {
+		if !seen[m] {
+			seen[m] = true
+			methods = append(methods, m)
+		}
+	}
+	for _, rule := range i {
+		for _, m := range rule.Methods {
+			m = strings.ToUpper(m)
+			add(m)
+			if m == http.MethodGet {
+				add(http.MethodHead)
+			}
+		}
+	}
+	return methods
+}
+
 // HasGrpcRule 检查是否存在 gRPC 规则
 func (i RoutingRules) HasGrpcRule() bool {
 	for _, rule := range i {
@@ -266,11 +1106,91 @@ func (i RoutingRules) HasWebsocketRule() bool {
 
 // Routing 路由配置
 type Routing struct {
-	Rules             map[string]RoutingRules `mapstructure:"rules"`
-	Engine            string                  `mapstructure:"engine"`
-	LoadBalancer      string                  `mapstructure:"loadBalancer"`
-	HeartbeatInterval int                     `mapstructure:"heartbeatInterval"`
-	Grayscale         Grayscale               `mapstructure:"grayscale"`
+	Rules             map[string]RoutingRules     `mapstructure:"rules"`
+	Engine            string                      `mapstructure:"engine"`
+	LoadBalancer      string                      `mapstructure:"loadBalancer"`
+	StickyTTL         time.Duration               `mapstructure:"stickyTTL"`         // ketama 客户端亲和性有效期，命中时刷新，0 表示仅按哈希选择
+	HashKey           string                      `mapstructure:"hashKey"`           // ketama 哈希键的来源：remote、forwarded、header:<名称> 或 cookie:<名称>，来源缺失时使用对端 IP
+	Ketama            Ketama                      `mapstructure:"ketama"`            // ketama 哈希环配置
+	EWMA              EWMA                        `mapstructure:"ewma"`              // ewma 负载均衡配置
+	HeartbeatInterval int                         `mapstructure:"heartbeatInterval"` // 健康检查的默认间隔（秒），规则未设置 healthCheckInterval 时使用
+	Grayscale         Grayscale                   `mapstructure:"grayscale"`
+	OutlierDetection  OutlierDetection            `mapstructure:"outlierDetection"`
+	PreserveRawPath   bool                        `mapstructure:"preserveRawPath"`  // 是否按原始编码转发请求路径（如保留 %2F）
+	TrustForwarded    bool                        `mapstructure:"trustForwarded"`   // 是否信任客户端请求自带的 X-Forwarded-* 与 X-Real-IP，网关位于可信负载均衡器之后时开启
+	DefaultHeaders    map[string]string           `mapstructure:"defaultHeaders"`   // 所有转发请求补充的默认请求头（如 User-Agent、网关标识），客户端已携带时不覆盖
+	FanOut            map[string]FanOut           `mapstructure:"fanOut"`           // 按路由路径配置的扇出请求
+	Scripts           map[string]RouteScript      `mapstructure:"scripts"`          // 按路由路径配置的 Lua 请求处理脚本
+	ErrorPassthrough  map[string]ErrorPassthrough `mapstructure:"errorPassthrough"` // 按路由路径配置的上游错误响应透传
+	Coalesce          map[string]Coalesce         `mapstructure:"coalesce"`         // 按路由路径配置的相同 GET 请求合并
+	ProtocolMismatch  string                      `mapstructure:"protocolMismatch"` // HTTP 路由的上游返回 gRPC 响应时的处理方式：reject 返回 502，passthrough 原样转发
+	TrailingSlash     string                      `mapstructure:"trailingSlash"`    // 尾部斜杠策略：strict 严格匹配，redirect 重定向到已配置的路径，ignore 忽略尾部斜杠
+	Retry             Retry                       `mapstructure:"retry"`            // 上游请求失败时的重试策略
+	ResponseHeaders   ResponseHeaderLimit         `mapstructure:"responseHeaders"`  // 上游响应头大小限制
+	SharedCounter     bool                        `mapstructure:"sharedCounter"`    // 轮询类负载均衡器是否使用 Redis 中多副本共享的选择计数器
+	HealthCheck       HealthCheckDefaults         `mapstructure:"healthCheck"`      // 各协议的默认健康检查目标
+	SlowStart         time.Duration               `mapstructure:"slowStart"`        // 目标通过就绪探测后流量从 0 线性增加到完整份额所需的时间，0 表示立即承接全部流量
+	StartupProbe      StartupProbe                `mapstructure:"startupProbe"`     // 启动时对所有目标的一次性探测
+	Signing           RequestSigning              `mapstructure:"signing"`          // 转发请求的 HMAC 签名
+
+	Limits RouteLimits `mapstructure:"limits"` // 路由规则与目标数量上限
+}
+
+// ketama 哈希键的来源
+const (
+	HashKeyRemote    = "remote"    // 与网关直接相连的对端 IP
+	HashKeyForwarded = "forwarded" // X-Forwarded-For 中最左侧的客户端 IP，网关位于负载均衡器之后时使用
+	HashKeyHeader    = "header"    // 指定请求头的值，如 X-Session-ID
+	HashKeyCookie    = "cookie"    // 指定 Cookie 的值
+)
+
+// ParseHashKey 解析 routing.hashKey，返回来源以及请求头或 Cookie 的名称，为空时使用 remote
+func ParseHashKey(key string) (source, name string, err error) {
+	source, name, _ = strings.Cut(key, ":")
+	switch source {
+	case "":
+		return HashKeyRemote, "", nil
+	case HashKeyRemote, HashKeyForwarded:
+		if name != "" {
+			return "", "", fmt.Errorf("hash key source %q does not take a name", source)
+		}
+		return source, "", nil
+	case HashKeyHeader, HashKeyCookie:
+		if name == "" {
+			return "", "", fmt.Errorf("hash key source %q requires a name, e.g. %s:X-Session-ID", source, source)
+		}
+		return source, name, nil
+	default:
+		return "", "", fmt.Errorf("unknown hash key source: %q", source)
+	}
+}
+
+// RouteLimits 路由规则与目标数量上限，规则过多会占用大量内存（前缀树节点、正则编译结果）
+// 超过软上限时记录警告，超过硬上限时配置校验失败，0 表示不限制
+type RouteLimits struct {
+	MaxRules    int `mapstructure:"maxRules"`    // 路由路径数的硬上限
+	MaxTargets  int `mapstructure:"maxTargets"`  // 所有路由的目标总数的硬上限
+	WarnRules   int `mapstructure:"warnRules"`   // 路由路径数的软上限
+	WarnTargets int `mapstructure:"warnTargets"` // 所有路由的目标总数的软上限
+}
+
+// Counts 返回路由路径数及所有路由的目标总数
+func (i Routing) Counts() (rules, targets int) {
+	for _, r := range i.Rules {
+		targets += len(r)
+	}
+	return len(i.Rules), targets
+}
+
+// CanaryPercentage 返回路由中灰度目标自动承接的流量百分比，为各灰度规则 CanaryWeight 之和，最大 100
+func (i RoutingRules) CanaryPercentage(canaryEnv string) int {
+	total := 0
+	for _, rule := range i {
+		if rule.Env == canaryEnv {
+			total += rule.CanaryWeight
+		}
+	}
+	return min(total, 100)
 }
 
 // GetGrpcRules 获取 gRPC 路由规则
@@ -295,11 +1215,87 @@ func (i Routing) GetHTTPRules() map[string]RoutingRules {
 	return httpRules
 }
 
+// RoutingRulesDiff 两组路由规则之间的差异，各列表按路径排序
+type RoutingRulesDiff struct {
+	Added   []string // 新增的路由路径
+	Removed []string // 删除的路由路径
+	Changed []string // 规则发生变化的路由路径
+}
+
+// Empty 判断两组路由规则是否完全相同
+func (d RoutingRulesDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffRoutingRules 比较新旧路由规则，供热更新时只重建变化的路由
+func DiffRoutingRules(old, new map[string]RoutingRules) RoutingRulesDiff {
+	var diff RoutingRulesDiff
+	for path, rules := range new {
+		oldRules, ok := old[path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, path)
+		case !reflect.DeepEqual(oldRules, rules):
+			diff.Changed = append(diff.Changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := new[path]; !ok {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
 // Server 服务器配置
 type Server struct {
-	Port         string `mapstructure:"port"`
-	GinMode      string `mapstructure:"ginMode"`
-	PprofEnabled bool   `mapstructure:"pprofenabled"`
+	Port               string        `mapstructure:"port"`
+	GinMode            string        `mapstructure:"ginMode"`            // Gin 运行模式：debug、release 或 test，默认 release
+	Pprof              Pprof         `mapstructure:"pprof"`              // 性能剖析端点
+	HealthCheckOnly    bool          `mapstructure:"healthCheckOnly"`    // 仅运行健康检查，代理路由统一返回 503，用于上线前验证后端
+	Admin              Admin         `mapstructure:"admin"`              // 管理 API
+	StartupGracePeriod time.Duration `mapstructure:"startupGracePeriod"` // 启动宽限期，期间 /readyz 保持未就绪，便于滚动发布时预热缓存与连接
+	Debug              Debug         `mapstructure:"debug"`              // 调试响应头
+}
+
+// Gin 运行模式
+const (
+	GinModeDebug   = "debug"
+	GinModeRelease = "release"
+	GinModeTest    = "test"
+)
+
+// EffectiveGinMode 返回实际使用的 Gin 运行模式，未设置或取值无效时回退到 release，避免 gin.SetMode 因未知模式 panic
+func (s Server) EffectiveGinMode() string {
+	switch s.GinMode {
+	case GinModeDebug, GinModeRelease, GinModeTest:
+		return s.GinMode
+	}
+	return GinModeRelease
+}
+
+// Debug 调试响应头配置，请求携带正确令牌的 X-Gateway-Debug 头时，响应中附带选中的目标、负载均衡器、环境和缓存命中情况
+type Debug struct {
+	Enabled bool   `mapstructure:"enabled"` // 是否启用调试响应头
+	Token   string `mapstructure:"token"`   // 调试令牌，请求头 X-Gateway-Debug 需与之一致
+}
+
+// Pprof 性能剖析端点配置，/debug/pprof 在独立的监听地址上提供，不挂载在对外服务的端口上
+// pprof 会暴露 goroutine 调用栈、内存分配情况和进程命令行参数，CPU 剖析与 trace 还会在采样期间明显增加开销，
+// 且这些端点没有认证，因此默认只监听本机地址，需要远程访问时应通过 SSH 隧道或限制来源的内网地址开放，不能暴露到公网
+type Pprof struct {
+	Enabled bool   `mapstructure:"enabled"` // 是否启用性能剖析端点
+	Addr    string `mapstructure:"addr"`    // 监听地址，默认 127.0.0.1:6060，需与 server.port 和管理 API 端口不同
+}
+
+// Admin 管理 API 配置，管理 API 在独立端口上监听，请求需携带 Bearer Token
+type Admin struct {
+	Enabled bool   `mapstructure:"enabled"` // 是否启用管理 API
+	Port    string `mapstructure:"port"`    // 监听端口，需与 server.port 不同
+	Token   string `mapstructure:"token"`   // 访问令牌
 }
 
 // JWT JWT 认证配置
@@ -316,7 +1312,64 @@ type Security struct {
 	RBAC         RBAC     `mapstructure:"rbac"`
 	IPBlacklist  []string `mapstructure:"ipBlacklist"`
 	IPWhitelist  []string `mapstructure:"ipWhitelist"`
-	IPUpdateMode string   `mapstructure:"ipUpdateMode"`
+	IPUpdateMode string   `mapstructure:"ipUpdateMode"` // 加载 IP 黑白名单的方式：override 覆盖缓存中的名单，append 追加
+	AutoBan      AutoBan  `mapstructure:"autoBan"`
+	Login        Login    `mapstructure:"login"`
+	Users        []User   `mapstructure:"users"`  // static 登录校验使用的用户列表
+	APIKey       APIKey   `mapstructure:"apiKey"` // authMode 为 apikey 时的 API Key 认证配置
+	// authMode 为 oauth2-introspection 时的令牌自省配置
+	Introspection OAuth2Introspection `mapstructure:"introspection"`
+
+	// IP 黑白名单检查时 Redis 失败的处理方式，默认 closed 拒绝请求，避免黑名单在 Redis 故障时失效
+	IPAclFailurePolicy string `mapstructure:"ipAclFailurePolicy"`
+}
+
+// APIKey API Key 认证配置，Key 可来自配置或 Redis，同一客户端可同时持有多个有效 Key 以便轮换
+type APIKey struct {
+	Header string        `mapstructure:"header"` // 携带 API Key 的请求头，默认 X-API-Key
+	Keys   []APIKeyEntry `mapstructure:"keys"`   // 配置中的 API Key
+	Redis  bool          `mapstructure:"redis"`  // 是否同时在 Redis 中查找 API Key
+}
+
+// OAuth2Introspection 按 RFC 7662 向授权服务器自省不透明访问令牌，结果按令牌的 SHA-256 摘要缓存
+type OAuth2Introspection struct {
+	URL          string        `mapstructure:"url"`          // 授权服务器的自省端点
+	ClientID     string        `mapstructure:"clientId"`     // 调用自省端点的客户端凭证，以 HTTP Basic 认证发送
+	ClientSecret string        `mapstructure:"clientSecret"` // 客户端密钥
+	Timeout      time.Duration `mapstructure:"timeout"`      // 调用自省端点的超时时间
+	CacheTTL     time.Duration `mapstructure:"cacheTTL"`     // 自省结果的缓存时间，有效令牌不超过其剩余有效期，0 表示不缓存
+}
+
+// APIKeyEntry API Key 与其对应的客户端标识
+type APIKeyEntry struct {
+	Key    string `mapstructure:"key"`
+	Client string `mapstructure:"client"`
+}
+
+// Login 登录凭证校验配置
+type Login struct {
+	Verifier string    `mapstructure:"verifier"` // 凭证校验方式：static 使用 security.users，http 调用外部校验接口
+	HTTP     LoginHTTP `mapstructure:"http"`
+}
+
+// LoginHTTP 外部凭证校验接口配置，接口返回 2xx 表示校验通过，401/403 表示凭证错误
+type LoginHTTP struct {
+	URL     string        `mapstructure:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// User 登录用户，密码以 bcrypt 哈希保存
+type User struct {
+	Username     string `mapstructure:"username"`
+	PasswordHash string `mapstructure:"passwordHash"`
+}
+
+// AutoBan 自动封禁配置，IP 在时间窗口内触发防注入拦截或限流拒绝的次数达到阈值后被临时封禁
+type AutoBan struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Threshold   int           `mapstructure:"threshold"`   // 时间窗口内的违规次数阈值
+	Window      time.Duration `mapstructure:"window"`      // 违规计数的时间窗口
+	BanDuration time.Duration `mapstructure:"banDuration"` // 封禁时长
 }
 
 // RBAC RBAC 权限配置
@@ -332,6 +1385,7 @@ type TrafficRateLimit struct {
 	QPS         int                         `mapstructure:"qps"`
 	Burst       int                         `mapstructure:"burst"`
 	Algorithm   string                      `mapstructure:"algorithm"`
+	Window      time.Duration               `mapstructure:"window"`       // sliding_window 算法的统计窗口，窗口内最多允许 qps×窗口秒数 个请求
 	IPLimits    map[string]TrafficRateLimit `mapstructure:"ip_limits"`    // IP维度限流
 	RouteLimits map[string]TrafficRateLimit `mapstructure:"route_limits"` // 路由维度限流
 }
@@ -339,19 +1393,39 @@ type TrafficRateLimit struct {
 // TrafficBreaker 熔断器配置
 type TrafficBreaker struct {
 	Enabled        bool    `mapstructure:"enabled"`
-	ErrorRate      float64 `mapstructure:"errorRate"`
-	Timeout        int     `mapstructure:"timeout"`
-	MinRequests    int     `mapstructure:"minRequests"`
-	SleepWindow    int     `mapstructure:"sleepWindow"`
-	MaxConcurrent  int     `mapstructure:"maxConcurrent"`
-	WindowSize     int     `mapstructure:"windowSize"`
-	WindowDuration int     `mapstructure:"windowDuration"`
+	ErrorRate      float64 `mapstructure:"errorRate"`      // 触发熔断的错误率（0-1）
+	Timeout        int     `mapstructure:"timeout"`        // 单个请求的超时时间（毫秒）
+	MinRequests    int     `mapstructure:"minRequests"`    // 统计窗口内计算错误率所需的最少请求数
+	SleepWindow    int     `mapstructure:"sleepWindow"`    // 熔断打开后尝试恢复前的等待时间（毫秒）
+	MaxConcurrent  int     `mapstructure:"maxConcurrent"`  // 每个目标同时处理的请求数上限
+	WindowSize     int     `mapstructure:"windowSize"`     // 保留字段，统计窗口目前只按 windowDuration 划分
+	WindowDuration int     `mapstructure:"windowDuration"` // 错误率统计窗口长度（秒）
 }
 
 // Traffic 流量控制配置
 type Traffic struct {
-	RateLimit TrafficRateLimit `mapstructure:"rateLimit"`
-	Breaker   TrafficBreaker   `mapstructure:"breaker"`
+	RateLimit     TrafficRateLimit   `mapstructure:"rateLimit"`
+	Breaker       TrafficBreaker     `mapstructure:"breaker"`
+	MaxConcurrent TrafficConcurrency `mapstructure:"maxConcurrent"` // 同时处理的请求数上限，与按 QPS 限流相互独立
+}
+
+// 并发请求数达到上限时的处理方式
+const (
+	ConcurrencyOnLimitReject = "reject" // 立即返回 503
+	ConcurrencyOnLimitQueue  = "queue"  // 排队等待空闲名额，超过 queueTimeout 返回 503
+)
+
+// TrafficConcurrency 并发请求数限制，用于限制网关同时处理的请求数以保护内存
+type TrafficConcurrency struct {
+	Global       int64            `mapstructure:"global"`       // 全局并发上限，0 表示不限制
+	Routes       map[string]int64 `mapstructure:"routes"`       // 按路由的并发上限，与全局上限同时生效
+	OnLimit      string           `mapstructure:"onLimit"`      // 达到上限时的处理方式：reject 或 queue，默认 reject
+	QueueTimeout time.Duration    `mapstructure:"queueTimeout"` // queue 模式下的最长等待时间
+}
+
+// Enabled 检查是否配置了并发上限
+func (t TrafficConcurrency) Enabled() bool {
+	return t.Global > 0 || len(t.Routes) > 0
 }
 
 // Observability 可观测性配置
@@ -359,6 +1433,19 @@ type Observability struct {
 	Prometheus Prometheus `mapstructure:"prometheus"`
 	Grafana    Grafana    `mapstructure:"grafana"`
 	Jaeger     Jaeger     `mapstructure:"jaeger"`
+	OTLP       OTLP       `mapstructure:"otlp"`
+}
+
+// OTLP 导出配置
+type OTLP struct {
+	Metrics OTLPMetrics `mapstructure:"metrics"`
+}
+
+// OTLPMetrics OTLP 指标导出配置
+type OTLPMetrics struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Endpoint string        `mapstructure:"endpoint"` // OTLP HTTP 端点，支持 host:port 或完整 URL
+	Interval time.Duration `mapstructure:"interval"` // 导出周期
 }
 
 // Grafana 配置
@@ -375,11 +1462,11 @@ type Prometheus struct {
 
 // Jaeger 追踪配置
 type Jaeger struct {
-	Enabled      bool    `mapstructure:"enabled"`
-	Endpoint     string  `mapstructure:"endpoint"`
-	HttpEndpoint string  `mapstructure:"httpEndpoint"`
-	Sampler      string  `mapstructure:"sampler"`
-	SampleRatio  float64 `mapstructure:"sampleRatio"`
+	Enabled      bool    `mapstructure:"enabled"`      // 是否启用追踪数据导出
+	Endpoint     string  `mapstructure:"endpoint"`     // OTLP HTTP 接收地址，host:port 或完整 URL
+	HttpEndpoint string  `mapstructure:"httpEndpoint"` // Jaeger UI 地址，仅用于状态页展示
+	Sampler      string  `mapstructure:"sampler"`      // 采样器类型：ratio、always、never
+	SampleRatio  float64 `mapstructure:"sampleRatio"`  // 按比例采样时的采样率（0.0-1.0）
 }
 
 // Logger 日志配置
@@ -392,6 +1479,18 @@ type Logger struct {
 	Compress   bool   `mapstructure:"compress"`
 }
 
+// Logging 日志输出配置
+type Logging struct {
+	Access AccessLog `mapstructure:"access"`
+}
+
+// AccessLog 访问日志配置
+type AccessLog struct {
+	Enabled      bool     `mapstructure:"enabled"`      // 是否启用访问日志
+	SampleRate   float64  `mapstructure:"sampleRate"`   // 采样率（0-1），高负载时只记录部分请求
+	ExcludePaths []string `mapstructure:"excludePaths"` // 不记录访问日志的路径，如健康检查和指标路径
+}
+
 // GetConfig 获取当前配置（线程安全）
 func (cm *ConfigManager) GetConfig() *Config {
 	cm.mutex.RLock()
@@ -439,11 +1538,65 @@ func InitTestConfigManager() {
 	}
 }
 
+// bindEnv 启用环境变量覆盖配置
+// 环境变量名为 MG_ 前缀加上大写的配置路径，层级之间的 "." 替换为 "_"，例如：
+//
+//	server.port            -> MG_SERVER_PORT
+//	traffic.rateLimit.qps  -> MG_TRAFFIC_RATELIMIT_QPS
+//	observability.jaeger.enabled -> MG_OBSERVABILITY_JAEGER_ENABLED
+//
+// 切片类型的值以逗号分隔（如 MG_SECURITY_IPBLACKLIST="1.1.1.1,2.2.2.2"）；
+// 映射类型（如以路径为 key 的 routing.rules）不绑定环境变量，同名环境变量会被忽略
+// 热更新同样经过 loadConfigFiles，因此环境变量覆盖在重新加载后仍然生效
+func bindEnv(v *viper.Viper) {
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	// 只显式绑定结构体中的非映射字段，而不使用 AutomaticEnv：后者会让 MG_ROUTING_RULES 之类的变量把整个映射替换为字符串
+	bindStructEnv(v, reflect.TypeOf(Config{}), "")
+}
+
+// bindStructEnv 递归绑定结构体中每个 mapstructure 字段对应的环境变量
+func bindStructEnv(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Struct {
+			bindStructEnv(v, fieldType, key)
+			continue
+		}
+		if fieldType.Kind() == reflect.Map {
+			continue
+		}
+		_ = v.BindEnv(key)
+	}
+}
+
 // setDefaultValues 设置默认配置值
 func setDefaultValues(v *viper.Viper) {
 	v.SetDefault("server.port", "8080")
-	v.SetDefault("server.ginMode", "release")
-	v.SetDefault("server.pprofenabled", false)
+	v.SetDefault("server.ginMode", GinModeRelease)
+	v.SetDefault("server.pprof.enabled", false)
+	v.SetDefault("server.pprof.addr", "127.0.0.1:6060")
+	v.SetDefault("server.healthCheckOnly", false)
+	v.SetDefault("server.startupGracePeriod", 0)
+	v.SetDefault("server.debug.enabled", false)
+	v.SetDefault("server.admin.enabled", false)
+	v.SetDefault("server.admin.port", "8388")
+
+	v.SetDefault("performance.evictUnhealthyClients", true)
+
+	v.SetDefault("logging.access.enabled", false)
+	v.SetDefault("logging.access.sampleRate", 1.0)
+	v.SetDefault("logging.access.excludePaths", []string{"/health", "/metrics"})
 
 	v.SetDefault("plugin.dir", "bin/plugins")
 	v.SetDefault("plugin.plugins", []string{"log"})
@@ -451,12 +1604,46 @@ func setDefaultValues(v *viper.Viper) {
 	v.SetDefault("routing.engine", "gin")
 	v.SetDefault("routing.loadBalancer", "round-robin")
 	v.SetDefault("routing.heartbeatInterval", 30)
+	v.SetDefault("routing.preserveRawPath", false)
+	v.SetDefault("routing.trustForwarded", false)
+	v.SetDefault("routing.protocolMismatch", "reject")
+	v.SetDefault("routing.trailingSlash", "redirect")
+	v.SetDefault("routing.retry.attempts", 0)
+	v.SetDefault("routing.retry.on", "connection")
+	v.SetDefault("routing.retry.backoff", 25*time.Millisecond)
+	v.SetDefault("routing.retry.maxBackoff", time.Second)
+	v.SetDefault("routing.responseHeaders.maxSize", 0)
+	v.SetDefault("routing.responseHeaders.action", "strip")
+	v.SetDefault("routing.stickyTTL", 0)
+	v.SetDefault("routing.hashKey", HashKeyRemote)
+	v.SetDefault("routing.ketama.virtualNodes", DefaultKetamaVirtualNodes)
+	v.SetDefault("routing.ewma.decay", 10*time.Second)
+	v.SetDefault("routing.ewma.probeRate", 0.05)
+	v.SetDefault("routing.sharedCounter", false)
+	v.SetDefault("routing.slowStart", 0)
+	v.SetDefault("routing.startupProbe.enabled", false)
+	v.SetDefault("routing.startupProbe.failThreshold", 0)
+	v.SetDefault("routing.signing.enabled", false)
+	v.SetDefault("routing.signing.header", signing.DefaultHeader)
+	v.SetDefault("routing.signing.fields", signing.DefaultFields)
+	v.SetDefault("routing.signing.tolerance", signing.DefaultTolerance)
+	v.SetDefault("routing.limits.maxRules", 10000)
+	v.SetDefault("routing.limits.maxTargets", 50000)
+	v.SetDefault("routing.limits.warnRules", 1000)
+	v.SetDefault("routing.limits.warnTargets", 5000)
+	v.SetDefault("routing.healthCheck.httpPath", "/health")
+	v.SetDefault("routing.healthCheck.grpcService", "")
+	v.SetDefault("routing.healthCheck.websocketPath", "/health")
+	v.SetDefault("routing.outlierDetection.enabled", false)
+	v.SetDefault("routing.outlierDetection.consecutiveFailures", 5)
+	v.SetDefault("routing.outlierDetection.ejectionDuration", 30*time.Second)
 
 	v.SetDefault("middleware.rateLimit", true)
 	v.SetDefault("middleware.ipAcl", true)
 	v.SetDefault("middleware.antiInjection", true)
 	v.SetDefault("middleware.auth", true)
 	v.SetDefault("middleware.breaker", true)
+	v.SetDefault("middleware.tracing", false)
 
 	v.SetDefault("redis.addr", "localhost:6379")
 	v.SetDefault("redis.password", "")
@@ -472,11 +1659,33 @@ func setDefaultValues(v *viper.Viper) {
 	v.SetDefault("security.rbac.modelPath", "config/data/rbac_model.conf")
 	v.SetDefault("security.rbac.policyPath", "config/data/rbac_policy.csv")
 	v.SetDefault("security.ipUpdateMode", "override")
+	v.SetDefault("security.ipAclFailurePolicy", FailurePolicyClosed)
+	v.SetDefault("caching.enabled", false)
+	v.SetDefault("caching.failurePolicy", FailurePolicyOpen)
+	v.SetDefault("cache.backend", CacheBackendRedis)
+	v.SetDefault("cache.addr", "127.0.0.1:6379")
+	v.SetDefault("cache.db", 0)
+	v.SetDefault("cache.maxEntries", 10000)
+	v.SetDefault("cache.mode", CacheModeStandalone)
+	v.SetDefault("cache.dialTimeout", 5*time.Second)
+	v.SetDefault("cache.readTimeout", 3*time.Second)
+	v.SetDefault("cache.writeTimeout", 3*time.Second)
+	v.SetDefault("cache.reconnectInterval", 5*time.Second)
+	v.SetDefault("security.autoBan.enabled", false)
+	v.SetDefault("security.autoBan.threshold", 10)
+	v.SetDefault("security.autoBan.window", time.Minute)
+	v.SetDefault("security.autoBan.banDuration", 10*time.Minute)
+	v.SetDefault("security.apiKey.header", "X-API-Key")
+	v.SetDefault("security.introspection.timeout", 5*time.Second)
+	v.SetDefault("security.introspection.cacheTTL", 30*time.Second)
+	v.SetDefault("security.login.verifier", "static")
+	v.SetDefault("security.login.http.timeout", 5*time.Second)
 
 	v.SetDefault("traffic.rateLimit.enabled", true)
 	v.SetDefault("traffic.rateLimit.qps", 1000)
 	v.SetDefault("traffic.rateLimit.burst", 2000)
 	v.SetDefault("traffic.rateLimit.algorithm", "token_bucket")
+	v.SetDefault("traffic.rateLimit.window", time.Second)
 	v.SetDefault("traffic.breaker.enabled", true)
 	v.SetDefault("traffic.breaker.errorRate", 0.5)
 	v.SetDefault("traffic.breaker.timeout", 1000)
@@ -485,13 +1694,18 @@ func setDefaultValues(v *viper.Viper) {
 	v.SetDefault("traffic.breaker.maxConcurrent", 100)
 	v.SetDefault("traffic.breaker.windowSize", 100)
 	v.SetDefault("traffic.breaker.windowDuration", 10)
+	v.SetDefault("traffic.maxConcurrent.onLimit", ConcurrencyOnLimitReject)
+	v.SetDefault("traffic.maxConcurrent.queueTimeout", time.Second)
 
 	v.SetDefault("observability.prometheus.enabled", true)
 	v.SetDefault("observability.prometheus.path", "/metrics")
 	v.SetDefault("observability.jaeger.enabled", false)
-	v.SetDefault("observability.jaeger.endpoint", "http://localhost:14268/api/traces")
-	v.SetDefault("observability.jaeger.sampler", "always")
-	v.SetDefault("observability.jaeger.sampleRatio", 1.0)
+	v.SetDefault("observability.jaeger.endpoint", "localhost:4318")
+	v.SetDefault("observability.jaeger.sampler", "ratio")
+	v.SetDefault("observability.jaeger.sampleRatio", 0.01)
+	v.SetDefault("observability.otlp.metrics.enabled", false)
+	v.SetDefault("observability.otlp.metrics.endpoint", "localhost:4318")
+	v.SetDefault("observability.otlp.metrics.interval", 15*time.Second)
 
 	v.SetDefault("logger.level", "info")
 	v.SetDefault("logger.filePath", "logs/gateway.log")
@@ -505,6 +1719,8 @@ func setDefaultValues(v *viper.Viper) {
 	v.SetDefault("grpc.reflection", false)
 	v.SetDefault("grpc.allowedOrigins", []string{"*"})
 	v.SetDefault("grpc.prefix", "/grpc")
+	v.SetDefault("grpc.mountPattern", "{path}")
+	v.SetDefault("grpc.web", false)
 
 	v.SetDefault("websocket.enabled", true)
 	v.SetDefault("websocket.maxIdleConns", 100)
@@ -519,6 +1735,570 @@ func setDefaultValues(v *viper.Viper) {
 
 	v.SetDefault("fileServer.staticFilePath", "./data")
 	v.SetDefault("fileServer.enabledFastHttp", true)
+	v.SetDefault("fileServer.listing", false)
+}
+
+// Validate 校验配置的完整性及路由规则与引擎的兼容性，返回发现的全部错误，不影响生效的问题记录为警告
+func Validate(cfg *Config) error {
+	for _, warning := range ValidationWarnings(cfg) {
+		logger.Warn("Configuration validation warning", zap.String("warning", warning))
+	}
+	return errors.Join(ValidationErrors(cfg)...)
+}
+
+// ValidationWarnings 返回不影响配置生效但需要注意的问题，如路由规则或目标数量超过软上限、取值无效时回退到默认值的配置
+func ValidationWarnings(cfg *Config) []string {
+	var warnings []string
+	if mode := cfg.Server.GinMode; mode != "" && mode != cfg.Server.EffectiveGinMode() {
+		warnings = append(warnings, fmt.Sprintf("server ginMode %q is not one of debug, release, test; falling back to %s", mode, cfg.Server.EffectiveGinMode()))
+	}
+	limits := cfg.Routing.Limits
+	rules, targets := cfg.Routing.Counts()
+	if limits.WarnRules > 0 && rules > limits.WarnRules {
+		warnings = append(warnings, fmt.Sprintf("routing has %d rules, exceeding the soft limit %d", rules, limits.WarnRules))
+	}
+	if limits.WarnTargets > 0 && targets > limits.WarnTargets {
+		warnings = append(warnings, fmt.Sprintf("routing has %d targets, exceeding the soft limit %d", targets, limits.WarnTargets))
+	}
+	var cachedRoutes []string
+	for path, rules := range cfg.Routing.Rules {
+		if fb, ok := rules.Fallback(); ok && fb.Strategy == FallbackStrategyCached {
+			cachedRoutes = append(cachedRoutes, path)
+		}
+	}
+	sort.Strings(cachedRoutes)
+	for _, path := range cachedRoutes {
+		if rule := cfg.GetCacheRuleByPath(path); !cfg.Caching.Enabled || rule == nil || rule.StaleTTL <= 0 {
+			warnings = append(warnings, fmt.Sprintf("route %s uses the cached fallback strategy but no caching rule keeps its responses (staleTTL)", path))
+		}
+	}
+	// 以下功能直接依赖 Redis，memory 后端下不可用
+	if cfg.Cache.Backend == CacheBackendMemory {
+		if cfg.Routing.SharedCounter {
+			warnings = append(warnings, "routing sharedCounter requires the redis cache backend and falls back to local counters")
+		}
+		if cfg.Security.APIKey.Redis {
+			warnings = append(warnings, "security apiKey redis requires the redis cache backend and only keys from the configuration are used")
+		}
+	}
+	return warnings
+}
+
+// ValidationErrors 逐项校验配置并返回全部问题，便于一次性输出校验报告
+func ValidationErrors(cfg *Config) []error {
+	var errs []error
+	if cfg.Routing.LoadBalancer != "consul" && len(cfg.Routing.Rules) == 0 {
+		errs = append(errs, fmt.Errorf("routing rules are empty or undefined"))
+	}
+
+	switch cfg.Routing.LoadBalancer {
+	case "round-robin", "round_robin", "weighted-round-robin", "weighted_round_robin", "ketama", "consul", "ewma":
+	default:
+		errs = append(errs, fmt.Errorf("unknown load balancer: %q", cfg.Routing.LoadBalancer))
+	}
+	switch cfg.Routing.ProtocolMismatch {
+	case "", "reject", "passthrough":
+	default:
+		errs = append(errs, fmt.Errorf("unknown protocol mismatch behavior: %q", cfg.Routing.ProtocolMismatch))
+	}
+	if cfg.Routing.SlowStart < 0 {
+		errs = append(errs, fmt.Errorf("routing slowStart %s must not be negative", cfg.Routing.SlowStart))
+	}
+	if t := cfg.Routing.StartupProbe.FailThreshold; t < 0 || t > 1 {
+		errs = append(errs, fmt.Errorf("routing startupProbe failThreshold %v must be between 0 and 1", t))
+	}
+	if err := validateRequestSigning(cfg.Routing.Signing); err != nil {
+		errs = append(errs, fmt.Errorf("routing signing: %w", err))
+	}
+	for path, coalesce := range cfg.Routing.Coalesce {
+		if coalesce.Window < 0 {
+			errs = append(errs, fmt.Errorf("route %s: coalesce window %s must not be negative", path, coalesce.Window))
+		}
+	}
+	switch cfg.Routing.TrailingSlash {
+	case "", "strict", "redirect", "ignore":
+	default:
+		errs = append(errs, fmt.Errorf("unknown trailing slash policy: %q", cfg.Routing.TrailingSlash))
+	}
+	if err := validateRetry(cfg.Routing.Retry); err != nil {
+		errs = append(errs, fmt.Errorf("routing retry: %w", err))
+	}
+	for _, protocol := range []string{"http", "grpc", "websocket"} {
+		if err := validateHealthCheckPath(protocol, cfg.Routing.HealthCheck.ForProtocol(protocol)); err != nil {
+			errs = append(errs, fmt.Errorf("routing healthCheck: %w", err))
+		}
+	}
+	for env, probe := range cfg.Routing.HealthCheck.Environments {
+		if probe.Interval < 0 || probe.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("routing healthCheck environment %s: interval and timeout must not be negative", env))
+		}
+	}
+	errs = append(errs, validateRouteLimits(cfg.Routing)...)
+	errs = append(errs, validateFileServer(cfg.FileServer)...)
+	if _, _, err := ParseHashKey(cfg.Routing.HashKey); err != nil {
+		errs = append(errs, fmt.Errorf("routing hashKey: %w", err))
+	}
+	if cfg.Routing.LoadBalancer == "ketama" && cfg.Routing.Ketama.VirtualNodes <= 0 {
+		errs = append(errs, fmt.Errorf("routing ketama virtualNodes %d must be positive", cfg.Routing.Ketama.VirtualNodes))
+	}
+	if ewma := cfg.Routing.EWMA; cfg.Routing.LoadBalancer == "ewma" {
+		if ewma.Decay <= 0 {
+			errs = append(errs, fmt.Errorf("routing ewma decay must be positive, got %s", ewma.Decay))
+		}
+		if ewma.ProbeRate < 0 || ewma.ProbeRate > 1 {
+			errs = append(errs, fmt.Errorf("routing ewma probeRate %v must be between 0 and 1", ewma.ProbeRate))
+		}
+	}
+	if err := validateResponseHeaderLimit(cfg.Routing.ResponseHeaders); err != nil {
+		errs = append(errs, fmt.Errorf("routing responseHeaders: %w", err))
+	}
+	if err := validateDefaultHeaders(cfg.Routing.DefaultHeaders); err != nil {
+		errs = append(errs, fmt.Errorf("routing defaultHeaders: %w", err))
+	}
+	if err := validatePlugins(cfg.Plugin.Plugins); err != nil {
+		errs = append(errs, fmt.Errorf("plugin: %w", err))
+	}
+	if err := validateWasmPlugins(cfg.Plugin.Wasm); err != nil {
+		errs = append(errs, fmt.Errorf("plugin wasm: %w", err))
+	}
+	for _, err := range validateCache(cfg.Cache) {
+		errs = append(errs, fmt.Errorf("cache: %w", err))
+	}
+	if err := validateFailurePolicy(cfg.Security.IPAclFailurePolicy); err != nil {
+		errs = append(errs, fmt.Errorf("security ipAclFailurePolicy: %w", err))
+	}
+	if err := validateFailurePolicy(cfg.Caching.FailurePolicy); err != nil {
+		errs = append(errs, fmt.Errorf("caching failurePolicy: %w", err))
+	}
+	if cfg.Middleware.RateLimit {
+		switch cfg.Traffic.RateLimit.Algorithm {
+		case "token_bucket", "leaky_bucket", "sliding_window":
+		default:
+			errs = append(errs, fmt.Errorf("unknown rate limit algorithm: %q", cfg.Traffic.RateLimit.Algorithm))
+		}
+		if cfg.Traffic.RateLimit.Enabled {
+			if cfg.Traffic.RateLimit.QPS <= 0 {
+				errs = append(errs, fmt.Errorf("traffic rateLimit qps must be positive, got %d", cfg.Traffic.RateLimit.QPS))
+			}
+			// 滑动窗口只按窗口计数，不使用 burst
+			if cfg.Traffic.RateLimit.Algorithm == "sliding_window" {
+				if cfg.Traffic.RateLimit.Window <= 0 {
+					errs = append(errs, fmt.Errorf("traffic rateLimit window must be positive, got %s", cfg.Traffic.RateLimit.Window))
+				}
+			} else if cfg.Traffic.RateLimit.Burst <= 0 {
+				errs = append(errs, fmt.Errorf("traffic rateLimit burst must be positive, got %d", cfg.Traffic.RateLimit.Burst))
+			}
+		}
+	}
+
+	errs = append(errs, validateConcurrency(cfg.Traffic.MaxConcurrent)...)
+
+	if err := ValidateRoutingRules(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateGRPCConfig(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("gRPC configuration: %w", err))
+	}
+	if err := validateWebSocketConfig(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("WebSocket configuration: %w", err))
+	}
+
+	if cfg.Server.StartupGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("server startupGracePeriod %s must not be negative", cfg.Server.StartupGracePeriod))
+	}
+	if cfg.Server.Debug.Enabled && cfg.Server.Debug.Token == "" {
+		errs = append(errs, fmt.Errorf("debug headers require a token"))
+	}
+	if pprof := cfg.Server.Pprof; pprof.Enabled {
+		if _, port, err := net.SplitHostPort(pprof.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("pprof addr %q must be host:port", pprof.Addr))
+		} else if port == cfg.Server.Port || (cfg.Server.Admin.Enabled && port == cfg.Server.Admin.Port) {
+			errs = append(errs, fmt.Errorf("pprof addr %q must not share a port with the server or admin API", pprof.Addr))
+		}
+	}
+	if admin := cfg.Server.Admin; admin.Enabled {
+		if admin.Token == "" {
+			errs = append(errs, fmt.Errorf("admin API requires a token"))
+		}
+		if admin.Port == "" || admin.Port == cfg.Server.Port {
+			errs = append(errs, fmt.Errorf("admin API port %q must be set and differ from server port", admin.Port))
+		}
+	}
+
+	switch login := cfg.Security.Login; login.Verifier {
+	case "", "static":
+	case "http":
+		if u, err := url.Parse(login.HTTP.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("login verifier http requires an absolute http(s) url, got %q", login.HTTP.URL))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown login verifier: %q", login.Verifier))
+	}
+
+	errs = append(errs, validateUsers(cfg.Security.Users)...)
+	if cfg.Security.AuthMode == "apikey" {
+		errs = append(errs, validateAPIKey(cfg.Security.APIKey)...)
+	}
+	if cfg.Security.AuthMode == "oauth2-introspection" {
+		errs = append(errs, validateIntrospection(cfg.Security.Introspection)...)
+	}
+
+	// RBAC 启用时模型与策略文件必须存在
+	if cfg.Security.AuthMode == "rbac" && cfg.Security.RBAC.Enabled {
+		for _, file := range []string{cfg.Security.RBAC.ModelPath, cfg.Security.RBAC.PolicyPath} {
+			if _, err := os.Stat(file); err != nil {
+				errs = append(errs, fmt.Errorf("RBAC file %s is not accessible: %w", file, err))
+			}
+		}
+	}
+	return errs
+}
+
+// engineHonorsPriority 判断路由引擎是否按 priority 选择路由，gin 引擎的匹配顺序由 Gin 决定
+func engineHonorsPriority(engine string) bool {
+	switch engine {
+	case "trie", "trie-regexp", "trie_regexp", "regexp":
+		return true
+	}
+	return false
+}
+
+// ValidateRoutingRules 验证路由规则与配置的引擎兼容性（正则表达式路径与 priority）、正则表达式的有效性，以及请求方法、灰度比例、最低流量占比、降级响应、SSE 桥接和流量镜像配置
+func ValidateRoutingRules(cfg *Config) error {
+	var errs []error
+	engine := cfg.Routing.Engine
+	for path, rules := range cfg.Routing.Rules {
+		// gRPC 路由由 gRPC 代理单独处理，跳过进一步验证
+		if rules.HasGrpcRule() || !IsRegexPattern(path) {
+			continue
+		}
+		// 仅 trie-regexp 和 regexp 引擎支持正则表达式路径
+		switch engine {
+		case "trie-regexp", "trie_regexp", "regexp":
+		default:
+			errs = append(errs, fmt.Errorf("routing engine %q does not support regular expression path %s, use 'trie-regexp' or 'regexp' engine instead", engine, path))
+			continue
+		}
+		if _, err := regexp.Compile(path); err != nil {
+			errs = append(errs, fmt.Errorf("route %s is not a valid regular expression: %w", path, err))
+		}
+	}
+	for path, rules := range cfg.Routing.Rules {
+		totalMinShare := 0
+		for _, rule := range rules {
+			if rule.CanaryWeight < 0 || rule.CanaryWeight > 100 {
+				errs = append(errs, fmt.Errorf("route %s target %s: canaryWeight %d must be between 0 and 100", path, rule.Target, rule.CanaryWeight))
+			}
+			if rule.MinShare < 0 || rule.MinShare > 100 {
+				errs = append(errs, fmt.Errorf("route %s target %s: minShare %d must be between 0 and 100", path, rule.Target, rule.MinShare))
+			}
+			totalMinShare += rule.MinShare
+			for _, method := range rule.Methods {
+				if !validMethods[strings.ToUpper(method)] {
+					errs = append(errs, fmt.Errorf("route %s target %s: unknown HTTP method %q", path, rule.Target, method))
+				}
+			}
+			if rule.Priority != 0 && !engineHonorsPriority(engine) {
+				errs = append(errs, fmt.Errorf("route %s target %s: priority is ignored by routing engine %q, use the 'trie', 'trie-regexp' or 'regexp' engine or remove it", path, rule.Target, engine))
+			}
+			if rule.Host != "" && !validHost(rule.Host) {
+				errs = append(errs, fmt.Errorf("route %s target %s: invalid host %q, expected a hostname such as api.example.com or *.example.com", path, rule.Target, rule.Host))
+			}
+			if err := validateHealthCheckPath(rule.Protocol, rule.HealthCheckPath); err != nil {
+				errs = append(errs, fmt.Errorf("route %s target %s: %w", path, rule.Target, err))
+			}
+			if err := validateHealthCheckPath(rule.Protocol, rule.ReadinessCheckPath); err != nil {
+				errs = append(errs, fmt.Errorf("route %s target %s: readiness %w", path, rule.Target, err))
+			}
+			if err := validateFallback(rule.Fallback); err != nil {
+				errs = append(errs, fmt.Errorf("route %s target %s: fallback %w", path, rule.Target, err))
+			}
+			if err := validateRequestTransform(rule.RequestTransform); err != nil {
+				errs = append(errs, fmt.Errorf("route %s target %s: requestTransform %w", path, rule.Target, err))
+			}
+			if err := validateDefaultHeaders(rule.DefaultHeaders); err != nil {
+				errs = append(errs, fmt.Errorf("route %s target %s: defaultHeaders %w", path, rule.Target, err))
+			}
+			if err := validateSSEBridge(rule.Protocol, rule.SSE); err != nil {
+				errs = append(errs, fmt.Errorf("route %s target %s: sse %w", path, rule.Target, err))
+			}
+			for _, field := range rule.ResponseFilter {
+				if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+					errs = append(errs, fmt.Errorf("route %s target %s: responseFilter path %q has an empty segment", path, rule.Target, field))
+				}
+			}
+			if rule.Mirror.Target == "" {
+				continue
+			}
+			if u, err := url.Parse(rule.Mirror.Target); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("route %s target %s: mirror target %q must be an absolute URL", path, rule.Target, rule.Mirror.Target))
+			}
+			if rule.Mirror.Percentage < 0 || rule.Mirror.Percentage > 100 {
+				errs = append(errs, fmt.Errorf("route %s target %s: mirror percentage %d must be between 0 and 100", path, rule.Target, rule.Mirror.Percentage))
+			}
+		}
+		if totalMinShare > 100 {
+			errs = append(errs, fmt.Errorf("route %s: total minShare %d exceeds 100", path, totalMinShare))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validMethods 路由规则 methods 可使用的请求方法
+var validMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// validateRetry 校验重试次数、退避时间、重试条件及触发重试的状态码
+func validateRetry(retry Retry) error {
+	if retry.Attempts < 0 {
+		return fmt.Errorf("attempts %d must not be negative", retry.Attempts)
+	}
+	if retry.Backoff < 0 || retry.MaxBackoff < 0 {
+		return fmt.Errorf("backoff %s and maxBackoff %s must not be negative", retry.Backoff, retry.MaxBackoff)
+	}
+	if retry.Backoff > 0 && retry.MaxBackoff < retry.Backoff {
+		return fmt.Errorf("maxBackoff %s must not be less than backoff %s", retry.MaxBackoff, retry.Backoff)
+	}
+	switch retry.On {
+	case "", "connection":
+		if len(retry.StatusCodes) > 0 {
+			return fmt.Errorf("statusCodes only apply when on is status")
+		}
+	case "status":
+		if len(retry.StatusCodes) == 0 {
+			return fmt.Errorf("on status requires statusCodes")
+		}
+		for _, code := range retry.StatusCodes {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("status %d is not a valid HTTP status", code)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown retry condition: %q", retry.On)
+	}
+	return nil
+}
+
+// validateHealthCheckPath 校验健康检查目标的格式：gRPC 为服务名，HTTP 与 WebSocket 为以 / 开头的路径，为空时使用默认值
+func validateHealthCheckPath(protocol, target string) error {
+	if target == "" {
+		return nil
+	}
+	if protocol == "grpc" {
+		if strings.HasPrefix(target, "/") {
+			return fmt.Errorf("gRPC health check %q must be a service name such as hello.Health, not a path; leave it empty to check the whole server", target)
+		}
+		return nil
+	}
+	if !strings.HasPrefix(target, "/") {
+		return fmt.Errorf("health check path %q must start with /", target)
+	}
+	return nil
+}
+
+// validateRouteLimits 校验数量上限的取值，以及路由规则和目标数量是否超过硬上限
+func validateRouteLimits(routing Routing) []error {
+	var errs []error
+	limits := routing.Limits
+	names := []string{"maxRules", "maxTargets", "warnRules", "warnTargets"}
+	for i, limit := range []int{limits.MaxRules, limits.MaxTargets, limits.WarnRules, limits.WarnTargets} {
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("routing limits %s %d must not be negative", names[i], limit))
+		}
+	}
+	if limits.MaxRules > 0 && limits.WarnRules > limits.MaxRules {
+		errs = append(errs, fmt.Errorf("routing limits warnRules %d exceeds maxRules %d", limits.WarnRules, limits.MaxRules))
+	}
+	if limits.MaxTargets > 0 && limits.WarnTargets > limits.MaxTargets {
+		errs = append(errs, fmt.Errorf("routing limits warnTargets %d exceeds maxTargets %d", limits.WarnTargets, limits.MaxTargets))
+	}
+
+	rules, targets := routing.Counts()
+	if limits.MaxRules > 0 && rules > limits.MaxRules {
+		errs = append(errs, fmt.Errorf("routing has %d rules, exceeding the limit %d", rules, limits.MaxRules))
+	}
+	if limits.MaxTargets > 0 && targets > limits.MaxTargets {
+		errs = append(errs, fmt.Errorf("routing has %d targets, exceeding the limit %d", targets, limits.MaxTargets))
+	}
+	return errs
+}
+
+// validateResponseHeaderLimit 校验响应头大小上限及超出时的处理方式
+func validateResponseHeaderLimit(limit ResponseHeaderLimit) error {
+	if limit.MaxSize < 0 {
+		return fmt.Errorf("maxSize %d must not be negative", limit.MaxSize)
+	}
+	switch limit.Action {
+	case "", "strip", "truncate":
+	default:
+		return fmt.Errorf("unknown oversized header action: %q", limit.Action)
+	}
+	return nil
+}
+
+// validateFailurePolicy 校验 Redis 失败处理方式，为空时使用默认值
+func validateFailurePolicy(policy string) error {
+	switch policy {
+	case "", FailurePolicyOpen, FailurePolicyClosed:
+		return nil
+	}
+	return fmt.Errorf("unknown failure policy: %q", policy)
+}
+
+// validateAPIKey 校验 API Key 认证配置：至少有一个 Key 来源，配置的 Key 非空、不重复且有客户端标识
+func validateAPIKey(apiKey APIKey) []error {
+	var errs []error
+	if len(apiKey.Keys) == 0 && !apiKey.Redis {
+		errs = append(errs, fmt.Errorf("apikey auth mode requires security.apiKey.keys or security.apiKey.redis"))
+	}
+	seen := make(map[string]bool, len(apiKey.Keys))
+	for i, entry := range apiKey.Keys {
+		switch {
+		case entry.Key == "":
+			errs = append(errs, fmt.Errorf("API key #%d: key is empty", i+1))
+		case entry.Client == "":
+			errs = append(errs, fmt.Errorf("API key #%d: client is empty", i+1))
+		case seen[entry.Key]:
+			errs = append(errs, fmt.Errorf("API key #%d: duplicate key for client %s", i+1, entry.Client))
+		}
+		seen[entry.Key] = true
+	}
+	return errs
+}
+
+// validateIntrospection 校验令牌自省配置：端点为绝对 http(s) 地址且提供客户端凭证
+func validateIntrospection(in OAuth2Introspection) []error {
+	var errs []error
+	if u, err := url.Parse(in.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("oauth2-introspection auth mode requires an absolute http(s) introspection url, got %q", in.URL))
+	}
+	if in.ClientID == "" || in.ClientSecret == "" {
+		errs = append(errs, fmt.Errorf("oauth2-introspection auth mode requires security.introspection.clientId and clientSecret"))
+	}
+	if in.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("introspection cacheTTL %s must not be negative", in.CacheTTL))
+	}
+	return errs
+}
+
+// validateUsers 校验登录用户列表：用户名非空且不重复，密码必须是 bcrypt 哈希而不是明文
+func validateUsers(users []User) []error {
+	var errs []error
+	seen := make(map[string]bool, len(users))
+	for i, user := range users {
+		if user.Username == "" {
+			errs = append(errs, fmt.Errorf("security user #%d: username is empty", i+1))
+			continue
+		}
+		if seen[user.Username] {
+			errs = append(errs, fmt.Errorf("security user %s: duplicate username", user.Username))
+		}
+		seen[user.Username] = true
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			errs = append(errs, fmt.Errorf("security user %s: passwordHash is not a bcrypt hash, generate one with --hash-password", user.Username))
+		}
+	}
+	return errs
+}
+
+// validateConcurrency 校验并发请求数限制
+func validateConcurrency(mc TrafficConcurrency) []error {
+	var errs []error
+	if mc.Global < 0 {
+		errs = append(errs, fmt.Errorf("traffic maxConcurrent global %d must not be negative", mc.Global))
+	}
+	for path, limit := range mc.Routes {
+		if limit <= 0 {
+			errs = append(errs, fmt.Errorf("traffic maxConcurrent route %s: limit must be positive, got %d", path, limit))
+		}
+	}
+	switch mc.OnLimit {
+	case "", ConcurrencyOnLimitReject, ConcurrencyOnLimitQueue:
+	default:
+		errs = append(errs, fmt.Errorf("traffic maxConcurrent onLimit %q is invalid, must be %s or %s",
+			mc.OnLimit, ConcurrencyOnLimitReject, ConcurrencyOnLimitQueue))
+	}
+	if mc.OnLimit == ConcurrencyOnLimitQueue && mc.QueueTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("traffic maxConcurrent queueTimeout must be positive when onLimit is %s, got %s",
+			ConcurrencyOnLimitQueue, mc.QueueTimeout))
+	}
+	return errs
+}
+
+// validateRequestTransform 校验请求体改写规则的字段名与模板
+func validateRequestTransform(t RequestTransform) error {
+	for _, rename := range t.Rename {
+		if rename.From == "" || rename.To == "" {
+			return fmt.Errorf("rename requires both from and to, got %q -> %q", rename.From, rename.To)
+		}
+	}
+	if _, err := t.ParseTemplate(); err != nil {
+		return fmt.Errorf("template is invalid: %w", err)
+	}
+	return nil
+}
+
+// validateRequestSigning 校验请求签名配置，启用时必须提供密钥
+func validateRequestSigning(sg RequestSigning) error {
+	if !sg.Enabled {
+		return nil
+	}
+	if sg.Secret == "" {
+		return fmt.Errorf("secret is required when signing is enabled")
+	}
+	if strings.ContainsAny(sg.Header, " \t\r\n:") {
+		return fmt.Errorf("header name %q is invalid", sg.Header)
+	}
+	if sg.Tolerance < 0 {
+		return fmt.Errorf("tolerance %s must not be negative", sg.Tolerance)
+	}
+	return signing.ValidateFields(sg.Fields)
+}
+
+// validateDefaultHeaders 校验默认请求头的名称与值，值中不能含有换行，避免拼接出额外的请求头
+func validateDefaultHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("header name %q is invalid", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s value must not contain line breaks", name)
+		}
+	}
+	return nil
+}
+
+// validateFallback 校验降级响应的策略、状态码与重定向地址
+func validateFallback(fb Fallback) error {
+	switch fb.Strategy {
+	case "", FallbackStrategyError, FallbackStrategyStatic, FallbackStrategyCached:
+	default:
+		return fmt.Errorf("strategy %q is invalid, must be one of %s, %s, %s",
+			fb.Strategy, FallbackStrategyError, FallbackStrategyStatic, FallbackStrategyCached)
+	}
+	if fb.Status != 0 && (fb.Status < 100 || fb.Status > 599) {
+		return fmt.Errorf("status %d is not a valid HTTP status", fb.Status)
+	}
+	if fb.RedirectURL == "" {
+		return nil
+	}
+	if fb.Status != 0 && (fb.Status < 300 || fb.Status > 399) {
+		return fmt.Errorf("status %d must be a 3xx code when redirectURL is set", fb.Status)
+	}
+	if _, err := url.Parse(fb.RedirectURL); err != nil {
+		return fmt.Errorf("redirectURL %q is invalid: %w", fb.RedirectURL, err)
+	}
+	return nil
+}
+
+// wildcardSuffix 匹配路由末尾的 *name 通配段，与 gin 写法一致，不视为正则表达式
+var wildcardSuffix = regexp.MustCompile(`/\*[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsRegexPattern 检查路径是否包含正则表达式字符，末尾的 *name 通配段除外
+func IsRegexPattern(path string) bool {
+	return strings.ContainsAny(wildcardSuffix.ReplaceAllString(path, ""), ".*+?()|[]^$\\")
 }
 
 // validateWebSocketConfig 验证 WebSocket 配置
@@ -593,6 +2373,34 @@ func (c *Config) GetCacheRuleByPath(path string) *CachingRule {
 	return nil
 }
 
+// redactedValue 脱敏后敏感字段的取值
+const redactedValue = "******"
+
+// Sanitized 返回隐藏了密钥、密码等敏感字段的配置副本，用于对外展示
+func (c *Config) Sanitized() *Config {
+	sanitized := *c
+	redact := func(value *string) {
+		if *value != "" {
+			*value = redactedValue
+		}
+	}
+	redact(&sanitized.Server.Admin.Token)
+	redact(&sanitized.Server.Debug.Token)
+	redact(&sanitized.Security.JWT.Secret)
+	redact(&sanitized.Cache.Password)
+	redact(&sanitized.Security.Introspection.ClientSecret)
+	redact(&sanitized.Routing.Signing.Secret)
+	sanitized.Security.APIKey.Keys = append([]APIKeyEntry(nil), c.Security.APIKey.Keys...)
+	for i := range sanitized.Security.APIKey.Keys {
+		redact(&sanitized.Security.APIKey.Keys[i].Key)
+	}
+	sanitized.Security.Users = append([]User(nil), c.Security.Users...)
+	for i := range sanitized.Security.Users {
+		redact(&sanitized.Security.Users[i].PasswordHash)
+	}
+	return &sanitized
+}
+
 // SaveConfigToFile 将配置保存到文件并保证字段顺序
 func (cm *ConfigManager) SaveConfigToFile(cfg *Config, filePath string) error {
 	cm.mutex.Lock()
@@ -632,10 +2440,87 @@ func (cm *ConfigManager) SaveConfigToFile(cfg *Config, filePath string) error {
 	return nil
 }
 
-// UpdateConfig 更新配置并通知监听者
-func (cm *ConfigManager) UpdateConfig(cfg *Config) {
+// Reload 重新解析配置路径并合并配置文件，校验通过后替换当前配置并通知监听者
+// 读取或校验失败时保留旧配置，避免错误配置导致进程退出或在请求时才暴露问题
+func (cm *ConfigManager) Reload() error {
+	cm.reloadMu.Lock()
+	defer cm.reloadMu.Unlock()
+
+	if cm.path != "" {
+		files, err := resolveConfigFiles(cm.path)
+		if err != nil {
+			return fmt.Errorf("resolve configuration files: %w", err)
+		}
+		cm.files = files
+	}
+	newCfg, err := loadConfigFiles(cm.files)
+	if err != nil {
+		return fmt.Errorf("load configuration: %w", err)
+	}
+	if err := Validate(newCfg); err != nil {
+		return fmt.Errorf("validate configuration: %w", err)
+	}
+	cm.apply(newCfg)
+	return nil
+}
+
+// UpdateConfig 校验通过后更新配置并通知监听者
+func (cm *ConfigManager) UpdateConfig(cfg *Config) error {
+	cm.reloadMu.Lock()
+	defer cm.reloadMu.Unlock()
+
+	if err := Validate(cfg); err != nil {
+		return err
+	}
+	cm.apply(cfg)
+	return nil
+}
+
+// Modify 在当前配置的副本上执行修改，校验通过后替换当前配置并通知监听者
+// 与 Reload、UpdateConfig 串行执行，避免并发的读取-修改-写入相互覆盖，修改函数返回错误时当前配置保持不变
+func (cm *ConfigManager) Modify(modify func(cfg *Config) error) error {
+	cm.reloadMu.Lock()
+	defer cm.reloadMu.Unlock()
+
+	newCfg := cm.GetConfig().clone()
+	if err := modify(newCfg); err != nil {
+		return err
+	}
+	if err := Validate(newCfg); err != nil {
+		return err
+	}
+	cm.apply(newCfg)
+	return nil
+}
+
+// apply 替换当前配置并通知监听者，调用方需持有 reloadMu
+func (cm *ConfigManager) apply(cfg *Config) {
 	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
 	cm.config = cfg
-	cm.ConfigChan <- cfg
+	cm.mutex.Unlock()
+
+	// 通道中尚有未处理的旧配置时丢弃旧配置，合并为一次刷新，保证监听者最终收到的是最新配置
+	for {
+		select {
+		case cm.ConfigChan <- cfg:
+			logger.Info("Configuration change notification sent")
+			return
+		default:
+		}
+		select {
+		case <-cm.ConfigChan:
+			logger.Info("Coalesced pending configuration change notification")
+		default:
+		}
+	}
+}
+
+// clone 复制配置，供修改后整体替换；路由规则表会被修改，因此单独复制
+func (c *Config) clone() *Config {
+	cloned := *c
+	cloned.Routing.Rules = make(map[string]RoutingRules, len(c.Routing.Rules))
+	for path, rules := range c.Routing.Rules {
+		cloned.Routing.Rules[path] = append(RoutingRules(nil), rules...)
+	}
+	return &cloned
 }
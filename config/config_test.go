@@ -0,0 +1,954 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager 基于临时配置文件创建 ConfigManager，配置中只包含 /file 一条路由
+func newTestManager(t *testing.T) *ConfigManager {
+	logger.InitTestLogger()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+grpc:
+  enabled: false
+websocket:
+  enabled: false
+routing:
+  loadBalancer: round_robin
+  rules:
+    /file:
+      - target: http://127.0.0.1:8381
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+
+	cfg, err := loadConfigFiles([]string{file})
+	require.NoError(t, err)
+	require.NoError(t, Validate(cfg))
+	return &ConfigManager{
+		config:     cfg,
+		files:      []string{file},
+		ConfigChan: make(chan *Config, 1),
+	}
+}
+
+func TestConfigManager_ConcurrentReloads(t *testing.T) {
+	cm := newTestManager(t)
+
+	// 模拟服务刷新协程，记录最后一次收到的配置
+	var last *Config
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		for cfg := range cm.ConfigChan {
+			last = cfg
+		}
+	}()
+
+	const workers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, cm.Reload())
+		}()
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/modify/%d", i)
+			assert.NoError(t, cm.Modify(func(cfg *Config) error {
+				cfg.Routing.Rules[path] = RoutingRules{{Target: "http://127.0.0.1:8382"}}
+				return nil
+			}))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_ = cm.GetConfig().Routing.Rules["/file"]
+		}()
+	}
+	wg.Wait()
+	close(cm.ConfigChan)
+	<-consumed
+
+	final := cm.GetConfig()
+	assert.Same(t, final, last, "the last notified configuration should be the current one")
+	assert.Contains(t, final.Routing.Rules, "/file")
+}
+
+func TestConfigManager_ModifyIsAtomic(t *testing.T) {
+	cm := newTestManager(t)
+
+	const workers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/route/%d", i)
+			assert.NoError(t, cm.Modify(func(cfg *Config) error {
+				cfg.Routing.Rules[path] = RoutingRules{{Target: "http://127.0.0.1:8382"}}
+				return nil
+			}))
+			// 监听者可能来不及处理，丢弃通知以免阻塞后续修改
+			select {
+			case <-cm.ConfigChan:
+			default:
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 每次修改都基于最新配置，任何一次修改都不应被覆盖
+	assert.Len(t, cm.GetConfig().Routing.Rules, workers+1)
+}
+
+func TestConfigManager_ModifyErrorKeepsConfig(t *testing.T) {
+	cm := newTestManager(t)
+	before := cm.GetConfig()
+
+	err := cm.Modify(func(cfg *Config) error {
+		delete(cfg.Routing.Rules, "/file")
+		return nil
+	})
+	assert.Error(t, err, "empty routing rules should fail validation")
+	assert.Same(t, before, cm.GetConfig())
+	assert.Contains(t, before.Routing.Rules, "/file", "the current configuration must not be modified in place")
+	assert.Empty(t, cm.ConfigChan)
+}
+
+func TestValidateRoutingRules_Fallback(t *testing.T) {
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/ok":           {{Target: "http://a", Fallback: Fallback{RedirectURL: "https://status.example.com", Status: 307}}},
+		"/bad-code":     {{Target: "http://b", Fallback: Fallback{Status: 999}}},
+		"/bad-redirect": {{Target: "http://c", Fallback: Fallback{RedirectURL: "https://status.example.com", Status: 503}}},
+		"/cached":       {{Target: "http://d", Fallback: Fallback{Strategy: FallbackStrategyCached}}},
+		"/bad-strategy": {{Target: "http://e", Fallback: Fallback{Strategy: "retry"}}},
+	}}}
+
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "/ok")
+	assert.NotContains(t, err.Error(), "/cached")
+	assert.Contains(t, err.Error(), "route /bad-code target http://b: fallback status 999")
+	assert.Contains(t, err.Error(), "route /bad-redirect target http://c: fallback status 503 must be a 3xx code")
+	assert.Contains(t, err.Error(), `route /bad-strategy target http://e: fallback strategy "retry" is invalid`)
+}
+
+func TestValidateRoutingRules_PriorityRequiresTrieOrRegexpEngine(t *testing.T) {
+	for _, engine := range []string{"trie", "trie-regexp", "trie_regexp", "regexp"} {
+		cfg := &Config{Routing: Routing{Engine: engine, Rules: map[string]RoutingRules{"/users/:id": {{Target: "http://a", Priority: 10}}}}}
+		assert.NoError(t, ValidateRoutingRules(cfg), engine)
+	}
+
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/users/:id": {{Target: "http://a", Priority: 10}},
+		"/orders":    {{Target: "http://b"}},
+	}}}
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `route /users/:id target http://a: priority is ignored by routing engine "gin"`)
+	assert.NotContains(t, err.Error(), "/orders")
+}
+
+func TestValidationWarnings_CachedFallbackWithoutStaleCache(t *testing.T) {
+	cfg := &Config{Routing: Routing{Rules: map[string]RoutingRules{
+		"/user":  {{Target: "http://a", Fallback: Fallback{Strategy: FallbackStrategyCached}}},
+		"/order": {{Target: "http://b", Fallback: Fallback{Strategy: FallbackStrategyStatic, Body: "down"}}},
+	}}}
+	assert.Equal(t, []string{
+		"route /user uses the cached fallback strategy but no caching rule keeps its responses (staleTTL)",
+	}, ValidationWarnings(cfg))
+
+	cfg.Caching = Caching{Enabled: true, Rules: []CachingRule{{Path: "/user", Method: "GET", StaleTTL: time.Hour}}}
+	assert.Empty(t, ValidationWarnings(cfg))
+}
+
+func TestRoutingRules_Methods(t *testing.T) {
+	readOnly := RoutingRules{{Target: "http://a", Methods: []string{"get", "OPTIONS"}}}
+	assert.True(t, readOnly.AllowsMethod("GET"))
+	assert.True(t, readOnly.AllowsMethod("HEAD"), "HEAD is implied by GET")
+	assert.False(t, readOnly.AllowsMethod("DELETE"))
+	assert.Equal(t, []string{"GET", "HEAD", "OPTIONS"}, readOnly.AllowedMethods())
+
+	mixed := append(readOnly, RoutingRule{Target: "http://b"})
+	assert.True(t, mixed.AllowsMethod("DELETE"), "a rule without methods accepts every method")
+	assert.Equal(t, RoutingRules{{Target: "http://b"}}, mixed.ForMethod("DELETE"), "rules that do not allow the method are not selectable")
+	assert.Equal(t, mixed, mixed.ForMethod("GET"))
+	assert.Empty(t, readOnly.ForMethod("DELETE"))
+
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/ok":  readOnly,
+		"/bad": {{Target: "http://c", Methods: []string{"FETCH"}}},
+	}}}
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "/ok")
+	assert.Contains(t, err.Error(), `route /bad target http://c: unknown HTTP method "FETCH"`)
+}
+
+func TestIsRegexPattern(t *testing.T) {
+	assert.True(t, IsRegexPattern("/api/v2/.*"))
+	assert.True(t, IsRegexPattern("/api/*"))
+	assert.False(t, IsRegexPattern("/users/:id"))
+	assert.False(t, IsRegexPattern("/static/*filepath"), "a trailing *name wildcard segment is supported by the trie engines")
+}
+
+func TestValidateRoutingRules_MinShare(t *testing.T) {
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/ok":        {{Target: "http://a", Weight: 10}, {Target: "http://b", Weight: 1, MinShare: 20}},
+		"/bad-range": {{Target: "http://c", MinShare: 120}},
+		"/bad-total": {{Target: "http://d", MinShare: 60}, {Target: "http://e", MinShare: 50}},
+	}}}
+
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "/ok")
+	assert.Contains(t, err.Error(), "route /bad-range target http://c: minShare 120 must be between 0 and 100")
+	assert.Contains(t, err.Error(), "route /bad-total: total minShare 110 exceeds 100")
+}
+
+func TestRoutingRules_Priority(t *testing.T) {
+	assert.Equal(t, 0, RoutingRules{}.Priority())
+	assert.Equal(t, 0, RoutingRules{{Target: "http://a"}}.Priority())
+	assert.Equal(t, 5, RoutingRules{{Target: "http://a", Priority: 5}, {Target: "http://b"}}.Priority())
+	assert.Equal(t, -1, RoutingRules{{Target: "http://a", Priority: -1}}.Priority())
+}
+
+func TestRoutingRules_ForHost(t *testing.T) {
+	rules := RoutingRules{
+		{Target: "http://a", Host: "api.example.com"},
+		{Target: "http://b", Host: "*.example.com"},
+		{Target: "http://c"},
+	}
+	assert.Equal(t, "http://a", rules.ForHost("api.example.com:8380")[0].Target)
+	assert.Equal(t, "http://b", rules.ForHost("a.b.example.com")[0].Target)
+	assert.Equal(t, "http://c", rules.ForHost("example.com")[0].Target, "a wildcard does not match the apex domain")
+	assert.Len(t, rules[:2].ForHost("other.org"), 0)
+
+	unscoped := RoutingRules{{Target: "http://a"}}
+	assert.Equal(t, unscoped, unscoped.ForHost("any.host"))
+
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/ok":  rules,
+		"/bad": {{Target: "http://d", Host: "api.*.com"}, {Target: "http://e", Host: "https://x.com"}},
+	}}}
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "/ok")
+	assert.Contains(t, err.Error(), `route /bad target http://d: invalid host "api.*.com"`)
+	assert.Contains(t, err.Error(), `route /bad target http://e: invalid host "https://x.com"`)
+}
+
+func TestValidationErrors_Login(t *testing.T) {
+	cfg := &Config{Security: Security{Login: Login{Verifier: "http"}}}
+	assert.Contains(t, Validate(cfg).Error(), `login verifier http requires an absolute http(s) url, got ""`)
+
+	cfg.Security.Login.Verifier = "ldap"
+	assert.Contains(t, Validate(cfg).Error(), `unknown login verifier: "ldap"`)
+}
+
+func TestSanitized_RedactsPasswordHashes(t *testing.T) {
+	cfg := &Config{Security: Security{Users: []User{{Username: "admin", PasswordHash: "$2a$10$hash"}}}}
+	sanitized := cfg.Sanitized()
+	assert.Equal(t, "admin", sanitized.Security.Users[0].Username)
+	assert.Equal(t, redactedValue, sanitized.Security.Users[0].PasswordHash)
+	assert.Equal(t, "$2a$10$hash", cfg.Security.Users[0].PasswordHash, "the original config must not be modified")
+}
+
+// secretFields 配置中保存密钥、令牌或密码的字段名，新增此类字段时需同时在 Sanitized 中脱敏
+var secretFields = map[string]bool{
+	"Token": true, "Secret": true, "ClientSecret": true, "Password": true, "PasswordHash": true, "Key": true,
+}
+
+// fillSecrets 递归地为结构体中的密钥字段写入可识别的值，切片字段追加一个元素，返回写入的值
+func fillSecrets(v reflect.Value, path string) []string {
+	var filled []string
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := path + "." + field.Name
+			if field.Type.Kind() == reflect.String && secretFields[field.Name] {
+				value := "secret" + strings.ReplaceAll(fieldPath, ".", "-")
+				v.Field(i).SetString(value)
+				filled = append(filled, value)
+				continue
+			}
+			filled = append(filled, fillSecrets(v.Field(i), fieldPath)...)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			filled = append(filled, fillSecrets(elem, path+"[0]")...)
+			v.Set(reflect.Append(v, elem))
+		}
+	}
+	return filled
+}
+
+func TestSanitized_RedactsEverySecret(t *testing.T) {
+	cfg := &Config{}
+	secrets := fillSecrets(reflect.ValueOf(cfg).Elem(), "")
+	require.NotEmpty(t, secrets)
+
+	sanitized, err := json.Marshal(cfg.Sanitized())
+	require.NoError(t, err)
+	for _, secret := range secrets {
+		assert.NotContains(t, string(sanitized), secret, "secret field %s is not redacted", secret)
+	}
+
+	// 脱敏不修改原配置
+	original, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	for _, secret := range secrets {
+		assert.Contains(t, string(original), secret)
+	}
+
+	// 空值保持为空，便于区分未配置与已脱敏
+	empty := (&Config{}).Sanitized()
+	assert.Empty(t, empty.Server.Admin.Token)
+	assert.Empty(t, empty.Security.JWT.Secret)
+}
+
+func TestValidationErrors_Users(t *testing.T) {
+	cfg := &Config{Security: Security{Users: []User{
+		{Username: "admin", PasswordHash: "$2a$10$T5Ip3q/xg1cMIG.sUiHoAukST5qnw3AtqX2xQaOBBT/OIwjKe4XJK"},
+		{Username: "admin", PasswordHash: "$2a$10$T5Ip3q/xg1cMIG.sUiHoAukST5qnw3AtqX2xQaOBBT/OIwjKe4XJK"},
+		{Username: "plain", PasswordHash: "password"},
+		{PasswordHash: "x"},
+	}}}
+	err := Validate(cfg).Error()
+	assert.Contains(t, err, "security user admin: duplicate username")
+	assert.Contains(t, err, "security user plain: passwordHash is not a bcrypt hash")
+	assert.Contains(t, err, "security user #4: username is empty")
+	assert.NotContains(t, err, "security user admin: passwordHash")
+}
+
+func TestValidateRetry(t *testing.T) {
+	assert.NoError(t, validateRetry(Retry{}))
+	assert.NoError(t, validateRetry(Retry{Attempts: 2, On: "connection"}))
+	assert.NoError(t, validateRetry(Retry{Attempts: 2, On: "status", StatusCodes: []int{502, 503}}))
+	assert.NoError(t, validateRetry(Retry{Attempts: 2, Backoff: 25 * time.Millisecond, MaxBackoff: time.Second}))
+
+	assert.EqualError(t, validateRetry(Retry{Attempts: -1}), "attempts -1 must not be negative")
+	assert.EqualError(t, validateRetry(Retry{On: "status"}), "on status requires statusCodes")
+	assert.EqualError(t, validateRetry(Retry{StatusCodes: []int{502}}), "statusCodes only apply when on is status")
+	assert.EqualError(t, validateRetry(Retry{On: "status", StatusCodes: []int{999}}), "status 999 is not a valid HTTP status")
+	assert.EqualError(t, validateRetry(Retry{On: "always"}), `unknown retry condition: "always"`)
+	assert.EqualError(t, validateRetry(Retry{Backoff: -time.Millisecond}), "backoff -1ms and maxBackoff 0s must not be negative")
+	assert.EqualError(t, validateRetry(Retry{Backoff: time.Second, MaxBackoff: time.Millisecond}), "maxBackoff 1ms must not be less than backoff 1s")
+}
+
+func TestHealthCheckDefaults_ForProtocol(t *testing.T) {
+	defaults := HealthCheckDefaults{HTTPPath: "/healthz", GRPCService: "hello.Health", WebSocketPath: "/ws/ping"}
+	assert.Equal(t, "/healthz", defaults.ForProtocol("http"))
+	assert.Equal(t, "/healthz", defaults.ForProtocol(""))
+	assert.Equal(t, "hello.Health", defaults.ForProtocol("grpc"))
+	assert.Equal(t, "/ws/ping", defaults.ForProtocol("websocket"))
+}
+
+func TestHealthCheckDefaults_ForEnv(t *testing.T) {
+	defaults := HealthCheckDefaults{Environments: map[string]ProbeSettings{
+		"canary": {Interval: 5 * time.Second, Timeout: time.Second},
+	}}
+	assert.Equal(t, ProbeSettings{Interval: 5 * time.Second, Timeout: time.Second}, defaults.ForEnv("Canary"))
+	assert.Equal(t, ProbeSettings{}, defaults.ForEnv("stable"))
+	assert.Equal(t, ProbeSettings{}, defaults.ForEnv(""))
+
+	cfg := &Config{Routing: Routing{HealthCheck: HealthCheckDefaults{Environments: map[string]ProbeSettings{
+		"canary": {Interval: -time.Second},
+	}}}}
+	assert.Contains(t, fmt.Sprint(ValidationErrors(cfg)), "routing healthCheck environment canary: interval and timeout must not be negative")
+}
+
+func TestValidateHealthCheckPath(t *testing.T) {
+	assert.NoError(t, validateHealthCheckPath("http", ""))
+	assert.NoError(t, validateHealthCheckPath("http", "/status"))
+	assert.NoError(t, validateHealthCheckPath("websocket", "/health"))
+	assert.NoError(t, validateHealthCheckPath("grpc", ""))
+	assert.NoError(t, validateHealthCheckPath("grpc", "hello.Health"))
+
+	assert.EqualError(t, validateHealthCheckPath("", "status"), `health check path "status" must start with /`)
+	assert.ErrorContains(t, validateHealthCheckPath("grpc", "/health"), "must be a service name")
+}
+
+func TestValidateResponseHeaderLimit(t *testing.T) {
+	assert.NoError(t, validateResponseHeaderLimit(ResponseHeaderLimit{}))
+	assert.NoError(t, validateResponseHeaderLimit(ResponseHeaderLimit{MaxSize: 4096, Action: "truncate"}))
+
+	assert.EqualError(t, validateResponseHeaderLimit(ResponseHeaderLimit{MaxSize: -1}), "maxSize -1 must not be negative")
+	assert.EqualError(t, validateResponseHeaderLimit(ResponseHeaderLimit{Action: "drop"}), `unknown oversized header action: "drop"`)
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	assert.Empty(t, validateAPIKey(APIKey{Redis: true}))
+	assert.Empty(t, validateAPIKey(APIKey{Keys: []APIKeyEntry{{Key: "a", Client: "billing"}, {Key: "b", Client: "billing"}}}))
+
+	errs := validateAPIKey(APIKey{})
+	assert.Len(t, errs, 1)
+
+	err := errors.Join(validateAPIKey(APIKey{Keys: []APIKeyEntry{
+		{Key: "a", Client: "billing"},
+		{Key: "a", Client: "ops"},
+		{Key: "", Client: "ops"},
+		{Key: "c"},
+	}})...).Error()
+	assert.Contains(t, err, "API key #2: duplicate key for client ops")
+	assert.Contains(t, err, "API key #3: key is empty")
+	assert.Contains(t, err, "API key #4: client is empty")
+
+	cfg := &Config{Security: Security{APIKey: APIKey{Keys: []APIKeyEntry{{Key: "secret", Client: "billing"}}}}}
+	assert.Equal(t, redactedValue, cfg.Sanitized().Security.APIKey.Keys[0].Key)
+	assert.Equal(t, "secret", cfg.Security.APIKey.Keys[0].Key)
+}
+
+func TestValidationErrors_RateLimit(t *testing.T) {
+	cfg := &Config{
+		Middleware: Middleware{RateLimit: true},
+		Traffic:    Traffic{RateLimit: TrafficRateLimit{Enabled: true, Algorithm: "leaky_bucket"}},
+	}
+	err := Validate(cfg).Error()
+	assert.Contains(t, err, "traffic rateLimit qps must be positive, got 0")
+	assert.Contains(t, err, "traffic rateLimit burst must be positive, got 0")
+
+	cfg.Traffic.RateLimit.QPS, cfg.Traffic.RateLimit.Burst = 10, 20
+	if err := Validate(cfg); err != nil {
+		assert.NotContains(t, err.Error(), "traffic rateLimit")
+	}
+
+	// 滑动窗口不使用 burst，但需要正的窗口长度
+	cfg.Traffic.RateLimit = TrafficRateLimit{Enabled: true, QPS: 10, Algorithm: "sliding_window"}
+	err = Validate(cfg).Error()
+	assert.Contains(t, err, "traffic rateLimit window must be positive, got 0s")
+	assert.NotContains(t, err, "burst")
+}
+
+func TestValidationErrors_EWMA(t *testing.T) {
+	cfg := &Config{Routing: Routing{LoadBalancer: "ewma", EWMA: EWMA{ProbeRate: 1.5}}}
+	err := Validate(cfg).Error()
+	assert.NotContains(t, err, "unknown load balancer")
+	assert.Contains(t, err, "routing ewma decay must be positive, got 0s")
+	assert.Contains(t, err, "routing ewma probeRate 1.5 must be between 0 and 1")
+
+	cfg.Routing.EWMA = EWMA{Decay: 10 * time.Second, ProbeRate: 0.05}
+	assert.NotContains(t, Validate(cfg).Error(), "ewma")
+}
+
+func TestServer_EffectiveGinMode(t *testing.T) {
+	assert.Equal(t, GinModeRelease, Server{}.EffectiveGinMode())
+	assert.Equal(t, GinModeDebug, Server{GinMode: "debug"}.EffectiveGinMode())
+	assert.Equal(t, GinModeTest, Server{GinMode: "test"}.EffectiveGinMode())
+	assert.Equal(t, GinModeRelease, Server{GinMode: "production"}.EffectiveGinMode())
+
+	cfg := &Config{Server: Server{GinMode: "production"}}
+	assert.Contains(t, ValidationWarnings(cfg), `server ginMode "production" is not one of debug, release, test; falling back to release`)
+	cfg.Server.GinMode = GinModeDebug
+	assert.Empty(t, ValidationWarnings(cfg))
+}
+
+func TestValidationErrors_Pprof(t *testing.T) {
+	cfg := &Config{Server: Server{Port: "8380", Pprof: Pprof{Enabled: true, Addr: "6060"}}}
+	assert.Contains(t, Validate(cfg).Error(), `pprof addr "6060" must be host:port`)
+
+	cfg.Server.Pprof.Addr = "127.0.0.1:8380"
+	assert.Contains(t, Validate(cfg).Error(), "must not share a port with the server or admin API")
+
+	cfg.Server.Pprof.Addr = "127.0.0.1:6060"
+	if err := Validate(cfg); err != nil {
+		assert.NotContains(t, err.Error(), "pprof")
+	}
+}
+
+func TestValidateFailurePolicy(t *testing.T) {
+	assert.NoError(t, validateFailurePolicy(""))
+	assert.NoError(t, validateFailurePolicy(FailurePolicyOpen))
+	assert.NoError(t, validateFailurePolicy(FailurePolicyClosed))
+	assert.EqualError(t, validateFailurePolicy("ignore"), `unknown failure policy: "ignore"`)
+}
+
+func TestValidationErrors_RouteLimits(t *testing.T) {
+	cfg := &Config{Routing: Routing{
+		Rules: map[string]RoutingRules{
+			"/a": {{Target: "http://a1"}, {Target: "http://a2"}},
+			"/b": {{Target: "http://b1"}},
+			"/c": {{Target: "http://c1"}},
+		},
+		Limits: RouteLimits{MaxRules: 3, MaxTargets: 4, WarnRules: 2, WarnTargets: 3},
+	}}
+
+	// 3 个路由、4 个目标：未超过硬上限，但超过软上限
+	for _, err := range ValidationErrors(cfg) {
+		assert.NotContains(t, err.Error(), "exceeding the limit")
+	}
+	assert.Equal(t, []string{
+		"routing has 3 rules, exceeding the soft limit 2",
+		"routing has 4 targets, exceeding the soft limit 3",
+	}, ValidationWarnings(cfg))
+
+	cfg.Routing.Rules["/d"] = RoutingRules{{Target: "http://d1"}}
+	err := Validate(cfg).Error()
+	assert.Contains(t, err, "routing has 4 rules, exceeding the limit 3")
+	assert.Contains(t, err, "routing has 5 targets, exceeding the limit 4")
+
+	// 0 表示不限制
+	cfg.Routing.Limits = RouteLimits{}
+	assert.Empty(t, ValidationWarnings(cfg))
+	for _, err := range ValidationErrors(cfg) {
+		assert.NotContains(t, err.Error(), "exceeding")
+	}
+}
+
+func TestValidateRouteLimits_Settings(t *testing.T) {
+	errs := validateRouteLimits(Routing{Limits: RouteLimits{MaxRules: -1, MaxTargets: 10, WarnTargets: 20}})
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "routing limits maxRules -1 must not be negative")
+	assert.EqualError(t, errs[1], "routing limits warnTargets 20 exceeds maxTargets 10")
+}
+
+func TestDiffRoutingRules(t *testing.T) {
+	old := map[string]RoutingRules{
+		"/a": {{Target: "http://a1"}},
+		"/b": {{Target: "http://b1"}},
+		"/c": {{Target: "http://c1"}},
+	}
+	diff := DiffRoutingRules(old, map[string]RoutingRules{
+		"/a": {{Target: "http://a1"}},
+		"/b": {{Target: "http://b1", Weight: 2}},
+		"/d": {{Target: "http://d1"}},
+	})
+	assert.Equal(t, RoutingRulesDiff{Added: []string{"/d"}, Removed: []string{"/c"}, Changed: []string{"/b"}}, diff)
+	assert.False(t, diff.Empty())
+	assert.True(t, DiffRoutingRules(old, old).Empty())
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	assert.Empty(t, validateConcurrency(TrafficConcurrency{}))
+	assert.Empty(t, validateConcurrency(TrafficConcurrency{Global: 100, OnLimit: ConcurrencyOnLimitQueue, QueueTimeout: time.Second}))
+
+	errs := validateConcurrency(TrafficConcurrency{
+		Global:  -1,
+		Routes:  map[string]int64{"/a": 0},
+		OnLimit: ConcurrencyOnLimitQueue,
+	})
+	assert.Len(t, errs, 3)
+	assert.EqualError(t, errs[0], "traffic maxConcurrent global -1 must not be negative")
+	assert.EqualError(t, errs[1], "traffic maxConcurrent route /a: limit must be positive, got 0")
+	assert.EqualError(t, errs[2], "traffic maxConcurrent queueTimeout must be positive when onLimit is queue, got 0s")
+
+	assert.Len(t, validateConcurrency(TrafficConcurrency{OnLimit: "drop"}), 1)
+}
+
+func TestValidateRequestTransform(t *testing.T) {
+	assert.NoError(t, validateRequestTransform(RequestTransform{
+		Rename:   []FieldRename{{From: "userId", To: "user_id"}},
+		Template: `{"data": {{json .}}}`,
+	}))
+	assert.EqualError(t, validateRequestTransform(RequestTransform{Rename: []FieldRename{{From: "userId"}}}),
+		`rename requires both from and to, got "userId" -> ""`)
+	assert.ErrorContains(t, validateRequestTransform(RequestTransform{Template: `{"data": {{json .}`}), "template is invalid")
+}
+
+func TestRequestTransform_ParseTemplateEscapesActions(t *testing.T) {
+	tmpl, err := RequestTransform{Template: `{{$n := .name}}{"a": "{{$n}}", "b": {{json .name}}, "c": [{{range .tags}}"{{.}}",{{end}}""], "d": "{{if .name}}{{.name | printf "%s!"}}{{end}}"}`}.ParseTemplate()
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, tmpl.Execute(&out, map[string]any{"name": `a"b`, "tags": []any{`</x>`, `\`}}))
+	assert.Equal(t, `{"a": "a\"b", "b": "a\"b", "c": ["\u003c/x\u003e","\\",""], "d": "a\"b!"}`, out.String())
+	assert.True(t, json.Valid([]byte(out.String())))
+}
+
+func TestParseHashKey(t *testing.T) {
+	for _, tc := range []struct {
+		key          string
+		source, name string
+		err          string
+	}{
+		{key: "", source: HashKeyRemote},
+		{key: "remote", source: HashKeyRemote},
+		{key: "forwarded", source: HashKeyForwarded},
+		{key: "header:X-Session-ID", source: HashKeyHeader, name: "X-Session-ID"},
+		{key: "cookie:session", source: HashKeyCookie, name: "session"},
+		{key: "header", err: `hash key source "header" requires a name, e.g. header:X-Session-ID`},
+		{key: "remote:x", err: `hash key source "remote" does not take a name`},
+		{key: "query:id", err: `unknown hash key source: "query"`},
+	} {
+		source, name, err := ParseHashKey(tc.key)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, tc.key)
+			continue
+		}
+		require.NoError(t, err, tc.key)
+		assert.Equal(t, tc.source, source, tc.key)
+		assert.Equal(t, tc.name, name, tc.key)
+	}
+}
+
+func TestValidationErrors_KetamaVirtualNodes(t *testing.T) {
+	cfg := &Config{Routing: Routing{LoadBalancer: "ketama", Ketama: Ketama{VirtualNodes: 0}}}
+	assert.Contains(t, Validate(cfg).Error(), "routing ketama virtualNodes 0 must be positive")
+
+	cfg.Routing.Ketama.VirtualNodes = 100
+	assert.NotContains(t, Validate(cfg).Error(), "virtualNodes")
+}
+
+func TestValidateDefaultHeaders(t *testing.T) {
+	assert.NoError(t, validateDefaultHeaders(map[string]string{"user-agent": "mini-gateway/1.0", "x-trace-source": ""}))
+	assert.EqualError(t, validateDefaultHeaders(map[string]string{"x gateway": "gw-1"}), `header name "x gateway" is invalid`)
+	assert.EqualError(t, validateDefaultHeaders(map[string]string{"x-gateway": "gw-1\r\nX-Admin: true"}),
+		"header x-gateway value must not contain line breaks")
+}
+
+func TestValidateRoutingRules_ResponseFilter(t *testing.T) {
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/ok":  {{Target: "http://a", ResponseFilter: []string{"password", "orders.card.number"}}},
+		"/bad": {{Target: "http://b", ResponseFilter: []string{"user..password"}}},
+	}}}
+
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "/ok")
+	assert.Contains(t, err.Error(), `route /bad target http://b: responseFilter path "user..password" has an empty segment`)
+}
+
+func TestValidateRoutingRules_SSE(t *testing.T) {
+	cfg := &Config{Routing: Routing{Engine: "gin", Rules: map[string]RoutingRules{
+		"/ok":     {{Target: "127.0.0.1:50051", Protocol: "grpc", SSE: SSEBridge{Method: "/grpc.health.v1.Health/Watch"}}},
+		"/http":   {{Target: "http://a", SSE: SSEBridge{Method: "/grpc.health.v1.Health/Watch"}}},
+		"/format": {{Target: "127.0.0.1:50052", Protocol: "grpc", SSE: SSEBridge{Method: "grpc.health.v1.Health.Watch"}}},
+	}}}
+
+	err := ValidateRoutingRules(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "/ok")
+	assert.Contains(t, err.Error(), "route /http target http://a: sse requires protocol grpc")
+	assert.Contains(t, err.Error(), `route /format target 127.0.0.1:50052: sse method "grpc.health.v1.Health.Watch" must have the form /package.Service/Method`)
+}
+
+func TestValidateFileServer(t *testing.T) {
+	assert.Empty(t, validateFileServer(FileServer{StaticFilePath: "./data", Mounts: map[string]string{"/docs": "./docs"}}))
+	assert.Empty(t, validateFileServer(FileServer{Mounts: map[string]string{"/static": "./assets"}}))
+
+	errs := validateFileServer(FileServer{StaticFilePath: "./data", Mounts: map[string]string{"/": "./a", "/static/": "./b", "/empty": ""}})
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	assert.ElementsMatch(t, []string{
+		`fileServer mount "/" must start with / and must not be /`,
+		`fileServer mount "/static/" conflicts with staticFilePath`,
+		`fileServer mount "/empty" has no directory`,
+	}, messages)
+}
+
+func TestValidateRequestSigning(t *testing.T) {
+	assert.NoError(t, validateRequestSigning(RequestSigning{}))
+	assert.NoError(t, validateRequestSigning(RequestSigning{Enabled: true, Secret: "s", Fields: []string{"method", "timestamp"}}))
+	assert.EqualError(t, validateRequestSigning(RequestSigning{Enabled: true}), "secret is required when signing is enabled")
+	assert.EqualError(t, validateRequestSigning(RequestSigning{Enabled: true, Secret: "s", Header: "X Sig"}), `header name "X Sig" is invalid`)
+	assert.EqualError(t, validateRequestSigning(RequestSigning{Enabled: true, Secret: "s", Fields: []string{"method"}}), `signing fields must include "timestamp"`)
+}
+
+func TestValidateIntrospection(t *testing.T) {
+	assert.Empty(t, validateIntrospection(OAuth2Introspection{URL: "https://auth.example.com/introspect", ClientID: "gw", ClientSecret: "s"}))
+	assert.Len(t, validateIntrospection(OAuth2Introspection{URL: "auth.example.com", ClientID: "gw", ClientSecret: "s"}), 1)
+	assert.Len(t, validateIntrospection(OAuth2Introspection{URL: "https://auth.example.com/introspect", ClientID: "gw", CacheTTL: -time.Second}), 2)
+}
+
+func TestLoadConfigFiles_Plugins(t *testing.T) {
+	logger.InitTestLogger()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+plugin:
+  dir: bin/plugins
+  plugins:
+  - name: auth
+    order: 20
+  - name: log
+    enabled: false
+    order: 10
+  - legacy
+  - name: ping
+    enabled: true
+    order: 10
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+
+	cfg, err := loadConfigFiles([]string{file})
+	require.NoError(t, err)
+	assert.Equal(t, []PluginEntry{
+		{Name: "legacy", Enabled: true},
+		{Name: "log", Enabled: false, Order: 10},
+		{Name: "ping", Enabled: true, Order: 10},
+		{Name: "auth", Enabled: true, Order: 20},
+	}, cfg.Plugin.Ordered())
+}
+
+func TestValidatePlugins(t *testing.T) {
+	assert.NoError(t, validatePlugins([]PluginEntry{{Name: "log"}, {Name: "ping"}}))
+	assert.EqualError(t, validatePlugins([]PluginEntry{{Name: "log"}, {}}), "plugin 1 has no name")
+	assert.EqualError(t, validatePlugins([]PluginEntry{{Name: "log"}, {Name: "log", Order: 1}}), `plugin "log" is configured more than once`)
+}
+
+func TestValidateWasmPlugins(t *testing.T) {
+	assert.NoError(t, validateWasmPlugins(WasmPlugins{Modules: []WasmModule{{Name: "deny-bots", Path: "bin/plugins/deny_bots.wasm"}}}))
+	assert.EqualError(t, validateWasmPlugins(WasmPlugins{Timeout: -time.Second}), "timeout -1s must not be negative")
+	assert.EqualError(t, validateWasmPlugins(WasmPlugins{MemoryLimitPages: MaxWasmMemoryPages + 1}), "memoryLimitPages 65537 exceeds the WASM maximum of 65536 pages")
+	assert.EqualError(t, validateWasmPlugins(WasmPlugins{Modules: []WasmModule{{Path: "a.wasm"}}}), "module 0 has no name")
+	assert.EqualError(t, validateWasmPlugins(WasmPlugins{Modules: []WasmModule{{Name: "a"}}}), `module "a" has no path`)
+	assert.EqualError(t, validateWasmPlugins(WasmPlugins{Modules: []WasmModule{{Name: "a", Path: "a.wasm"}, {Name: "a", Path: "b.wasm"}}}),
+		`module "a" is configured more than once`)
+}
+
+func TestValidateCache(t *testing.T) {
+	assert.Empty(t, validateCache(Cache{Addr: "127.0.0.1:6379", PoolSize: 20, MinIdleConns: 5, DialTimeout: time.Second}))
+	assert.Empty(t, validateCache(Cache{Mode: CacheModeCluster, Addrs: []string{"10.0.0.1:6379", "10.0.0.2:6379"}}))
+	assert.Empty(t, validateCache(Cache{Mode: CacheModeSentinel, Addrs: []string{"10.0.0.1:26379"}, MasterName: "mymaster", DB: 1}))
+
+	tests := []struct {
+		cache Cache
+		err   string
+	}{
+		{Cache{Mode: "replica"}, `unknown mode: "replica"`},
+		{Cache{Mode: CacheModeCluster}, "addrs is required in cluster mode"},
+		{Cache{Mode: CacheModeCluster, Addrs: []string{"10.0.0.1:6379"}, DB: 2}, "db 2 is not supported in cluster mode"},
+		{Cache{Mode: CacheModeSentinel, Addrs: []string{"10.0.0.1:26379"}}, "masterName is required in sentinel mode"},
+		{Cache{PoolSize: -1}, "poolSize -1 must not be negative"},
+		{Cache{PoolSize: 5, MinIdleConns: 10}, "minIdleConns 10 must not exceed poolSize 5"},
+		{Cache{ReadTimeout: -time.Second}, "readTimeout -1s must not be negative"},
+	}
+	for _, tt := range tests {
+		errs := validateCache(tt.cache)
+		if assert.Len(t, errs, 1, tt.err) {
+			assert.EqualError(t, errs[0], tt.err)
+		}
+	}
+}
+
+func TestValidateCache_Backend(t *testing.T) {
+	assert.Empty(t, validateCache(Cache{Backend: CacheBackendMemory, MaxEntries: 1000}))
+	assert.Empty(t, validateCache(Cache{Backend: CacheBackendMemory, Mode: "ignored"}), "redis settings are not validated for the memory backend")
+	assert.Equal(t, []error{fmt.Errorf(`unknown backend: "memcached"`)}, validateCache(Cache{Backend: "memcached"}))
+	assert.Equal(t, []error{fmt.Errorf("maxEntries -1 must not be negative")}, validateCache(Cache{Backend: CacheBackendMemory, MaxEntries: -1}))
+
+	cfg := &Config{Cache: Cache{Backend: CacheBackendMemory}, Routing: Routing{SharedCounter: true}}
+	assert.Contains(t, ValidationWarnings(cfg), "routing sharedCounter requires the redis cache backend and falls back to local counters")
+}
+
+// writeConfigFile 在目录中写入配置文件并返回其路径
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+const (
+	baseConfig = `
+grpc:
+  enabled: false
+websocket:
+  enabled: false
+server:
+  port: "8380"
+routing:
+  loadBalancer: round_robin
+  rules:
+    /base:
+      - target: http://127.0.0.1:8381
+    /shared:
+      - target: http://127.0.0.1:8381
+      - target: http://127.0.0.1:8382
+`
+	prodConfig = `
+server:
+  port: "9000"
+routing:
+  rules:
+    /shared:
+      - target: http://127.0.0.1:9001
+    /prod:
+      - target: http://127.0.0.1:9002
+`
+)
+
+func TestResolveConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "20-prod.yml", prodConfig)
+	writeConfigFile(t, dir, "10-base.yaml", baseConfig)
+	writeConfigFile(t, dir, "README.md", "not a config file")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested.yaml"), 0755))
+
+	// 目录按文件名排序，只包含 .yaml/.yml 文件
+	files, err := resolveConfigFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "10-base.yaml"), filepath.Join(dir, "20-prod.yml")}, files)
+
+	// 逗号分隔的列表按书写顺序，可与目录混用
+	other := writeConfigFile(t, t.TempDir(), "override.yaml", "")
+	files, err = resolveConfigFiles(other + ", " + dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{other, filepath.Join(dir, "10-base.yaml"), filepath.Join(dir, "20-prod.yml")}, files)
+
+	_, err = resolveConfigFiles(t.TempDir())
+	assert.ErrorContains(t, err, "no configuration files found")
+	_, err = resolveConfigFiles(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoadConfigFiles_MergeOrder(t *testing.T) {
+	logger.InitTestLogger()
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "base.yaml", baseConfig)
+	prod := writeConfigFile(t, dir, "prod.yaml", prodConfig)
+
+	cfg, err := loadConfigFiles([]string{base, prod})
+	require.NoError(t, err)
+	assert.Equal(t, "9000", cfg.Server.Port, "later files override scalar values")
+	assert.Equal(t, "round_robin", cfg.Routing.LoadBalancer, "values missing from later files are kept")
+	// 路由规则按路径合并：同一路径以后面的文件为准，其余路径保留
+	assert.Equal(t, RoutingRules{{Target: "http://127.0.0.1:8381"}}, cfg.Routing.Rules["/base"])
+	assert.Equal(t, RoutingRules{{Target: "http://127.0.0.1:9001"}}, cfg.Routing.Rules["/shared"])
+	assert.Equal(t, RoutingRules{{Target: "http://127.0.0.1:9002"}}, cfg.Routing.Rules["/prod"])
+
+	// 顺序相反时先加载的 prod 被 base 覆盖
+	cfg, err = loadConfigFiles([]string{prod, base})
+	require.NoError(t, err)
+	assert.Equal(t, "8380", cfg.Server.Port)
+	assert.Len(t, cfg.Routing.Rules["/shared"], 2)
+	assert.Contains(t, cfg.Routing.Rules, "/prod")
+}
+
+func TestConfigManager_ReloadResolvesDirectory(t *testing.T) {
+	logger.InitTestLogger()
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "10-base.yaml", baseConfig)
+	files, err := resolveConfigFiles(dir)
+	require.NoError(t, err)
+	cfg, err := loadConfigFiles(files)
+	require.NoError(t, err)
+	cm := &ConfigManager{config: cfg, path: dir, files: files, ConfigChan: make(chan *Config, 1)}
+
+	// 目录中新增的文件在重新加载时生效
+	writeConfigFile(t, dir, "20-prod.yaml", prodConfig)
+	require.NoError(t, cm.Reload())
+	assert.Equal(t, "9000", cm.GetConfig().Server.Port)
+	assert.Contains(t, cm.GetConfig().Routing.Rules, "/prod")
+
+	// 删除的文件不再参与合并
+	require.NoError(t, os.Remove(filepath.Join(dir, "20-prod.yaml")))
+	require.NoError(t, cm.Reload())
+	assert.Equal(t, "8380", cm.GetConfig().Server.Port)
+	assert.NotContains(t, cm.GetConfig().Routing.Rules, "/prod")
+}
+
+func TestConfigManager_WatchDirectory(t *testing.T) {
+	logger.InitTestLogger()
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "10-base.yaml", baseConfig)
+	files, err := resolveConfigFiles(dir)
+	require.NoError(t, err)
+	cfg, err := loadConfigFiles(files)
+	require.NoError(t, err)
+	cm := &ConfigManager{config: cfg, path: dir, files: files, ConfigChan: make(chan *Config, 1)}
+	stop, err := cm.watch()
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	// 启动后才加入目录的文件同样会触发重新加载
+	writeConfigFile(t, dir, "20-prod.yaml", prodConfig)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case cfg := <-cm.ConfigChan:
+			if _, ok := cfg.Routing.Rules["/prod"]; ok {
+				assert.Equal(t, "9000", cfg.Server.Port)
+				return
+			}
+		case <-deadline:
+			t.Fatal("adding a file to the configuration directory did not trigger a reload")
+		}
+	}
+}
+
+func TestConfigManager_WatchStop(t *testing.T) {
+	logger.InitTestLogger()
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "10-base.yaml", baseConfig)
+	files, err := resolveConfigFiles(dir)
+	require.NoError(t, err)
+	cfg, err := loadConfigFiles(files)
+	require.NoError(t, err)
+	cm := &ConfigManager{config: cfg, path: dir, files: files, ConfigChan: make(chan *Config, 1)}
+	stop, err := cm.watch()
+	require.NoError(t, err)
+
+	stop()
+	stop() // 重复调用不会阻塞或 panic
+
+	writeConfigFile(t, dir, "20-prod.yaml", prodConfig)
+	select {
+	case <-cm.ConfigChan:
+		t.Fatal("a stopped watcher must not reload the configuration")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestIsConfigChange(t *testing.T) {
+	dirs := map[string]struct{}{"/etc/gateway/conf.d": {}}
+	files := map[string]struct{}{"/etc/gateway/config.yaml": {}}
+
+	assert.True(t, isConfigChange(fsnotify.Event{Name: "/etc/gateway/config.yaml", Op: fsnotify.Write}, dirs, files))
+	assert.True(t, isConfigChange(fsnotify.Event{Name: "/etc/gateway/conf.d/prod.yml", Op: fsnotify.Create}, dirs, files))
+	assert.True(t, isConfigChange(fsnotify.Event{Name: "/etc/gateway/conf.d/prod.yaml", Op: fsnotify.Remove}, dirs, files))
+	assert.False(t, isConfigChange(fsnotify.Event{Name: "/etc/gateway/conf.d/notes.txt", Op: fsnotify.Write}, dirs, files))
+	assert.False(t, isConfigChange(fsnotify.Event{Name: "/etc/gateway/other.yaml", Op: fsnotify.Write}, dirs, files), "siblings of a single file are ignored")
+	assert.False(t, isConfigChange(fsnotify.Event{Name: "/etc/gateway/config.yaml", Op: fsnotify.Chmod}, dirs, files))
+}
+
+func TestLoadConfigFiles_EnvOverrides(t *testing.T) {
+	logger.InitTestLogger()
+	file := writeConfigFile(t, t.TempDir(), "config.yaml", baseConfig+`
+traffic:
+  rateLimit:
+    qps: 100
+`)
+	t.Setenv("MG_SERVER_PORT", "9090")
+	t.Setenv("MG_TRAFFIC_RATELIMIT_QPS", "500")                     // 嵌套字段，覆盖文件中的值
+	t.Setenv("MG_ROUTING_OUTLIERDETECTION_EJECTIONDURATION", "45s") // 时长，文件中未出现
+	t.Setenv("MG_ROUTING_RETRY_MAXBACKOFF", "2s")                   // 时长，覆盖默认值
+	t.Setenv("MG_OBSERVABILITY_JAEGER_SAMPLERATIO", "0.25")         // 浮点数
+	t.Setenv("MG_SECURITY_IPBLACKLIST", "192.168.1.1,10.0.0.0/8")   // 切片以逗号分隔
+	t.Setenv("MG_ROUTING_RULES", "ignored")                         // 映射不绑定环境变量
+
+	cfg, err := loadConfigFiles([]string{file})
+	require.NoError(t, err)
+	assert.Equal(t, "9090", cfg.Server.Port)
+	assert.Equal(t, 500, cfg.Traffic.RateLimit.QPS)
+	assert.Equal(t, 45*time.Second, cfg.Routing.OutlierDetection.EjectionDuration)
+	assert.Equal(t, 2*time.Second, cfg.Routing.Retry.MaxBackoff)
+	assert.Equal(t, 0.25, cfg.Observability.Jaeger.SampleRatio)
+	assert.Equal(t, []string{"192.168.1.1", "10.0.0.0/8"}, cfg.Security.IPBlacklist)
+	assert.Contains(t, cfg.Routing.Rules, "/base", "maps are not overridden by environment variables")
+
+	// 重新加载后环境变量覆盖仍然生效
+	cm := &ConfigManager{config: cfg, files: []string{file}, ConfigChan: make(chan *Config, 1)}
+	require.NoError(t, cm.Reload())
+	assert.Equal(t, 500, cm.GetConfig().Traffic.RateLimit.QPS)
+	assert.Equal(t, 45*time.Second, cm.GetConfig().Routing.OutlierDetection.EjectionDuration)
+}
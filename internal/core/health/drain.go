@@ -0,0 +1,87 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Drain 将目标标记为管理下线，负载均衡不再为其分配新请求，已在处理中的请求不受影响
+func (h *HealthChecker) Drain(target string) error {
+	host, err := h.knownTarget(target)
+	if err != nil {
+		return err
+	}
+
+	h.drainMu.Lock()
+	h.drained[host] = struct{}{}
+	h.drainMu.Unlock()
+	logger.Warn("Target drained by administrator", zap.String("target", host))
+	return nil
+}
+
+// Enable 取消目标的管理下线状态，目标重新参与负载均衡
+func (h *HealthChecker) Enable(target string) error {
+	host, err := h.knownTarget(target)
+	if err != nil {
+		return err
+	}
+
+	h.drainMu.Lock()
+	delete(h.drained, host)
+	h.drainMu.Unlock()
+	logger.Info("Target enabled by administrator", zap.String("target", host))
+	return nil
+}
+
+// IsDrained 判断目标当前是否被管理下线
+func (h *HealthChecker) IsDrained(target string) bool {
+	if h == nil {
+		return false
+	}
+	host := outlierKey(target)
+
+	h.drainMu.RLock()
+	defer h.drainMu.RUnlock()
+	_, ok := h.drained[host]
+	return ok
+}
+
+// DrainedTargets 返回当前被管理下线的全部目标
+func (h *HealthChecker) DrainedTargets() []string {
+	h.drainMu.RLock()
+	defer h.drainMu.RUnlock()
+
+	targets := make([]string, 0, len(h.drained))
+	for host := range h.drained {
+		targets = append(targets, host)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// knownTarget 返回目标规范化后的键，目标不在当前配置中时返回错误
+func (h *HealthChecker) knownTarget(target string) (string, error) {
+	host := outlierKey(target)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, ok := h.probes[host]; !ok {
+		return "", fmt.Errorf("unknown target: %s", target)
+	}
+	return host, nil
+}
+
+// pruneDrained 清理已不在配置中的目标的管理下线状态
+func (h *HealthChecker) pruneDrained(hosts map[string]*targetProbe) {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+
+	for host := range h.drained {
+		if _, ok := hosts[host]; !ok {
+			delete(h.drained, host)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain_DrainAndEnable(t *testing.T) {
+	h := newOutlierTestChecker(config.OutlierDetection{}, map[string]string{
+		"127.0.0.1:8381": "stable",
+		"127.0.0.1:8382": "stable",
+	})
+
+	require.NoError(t, h.Drain("http://127.0.0.1:8382"))
+	assert.True(t, h.IsDrained("http://127.0.0.1:8382"))
+	assert.True(t, h.IsDrained("127.0.0.1:8382"), "targets are matched by host")
+	assert.False(t, h.IsDrained("http://127.0.0.1:8381"))
+
+	require.NoError(t, h.Drain("http://127.0.0.1:8381"))
+	require.NoError(t, h.Drain("http://127.0.0.1:8381"), "draining twice is a no-op")
+	assert.Equal(t, []string{"127.0.0.1:8381", "127.0.0.1:8382"}, h.DrainedTargets())
+
+	require.NoError(t, h.Enable("http://127.0.0.1:8382"))
+	assert.False(t, h.IsDrained("http://127.0.0.1:8382"))
+	assert.Equal(t, []string{"127.0.0.1:8381"}, h.DrainedTargets())
+	require.NoError(t, h.Enable("http://127.0.0.1:8382"), "enabling an active target is a no-op")
+}
+
+func TestDrain_UnknownTarget(t *testing.T) {
+	h := newOutlierTestChecker(config.OutlierDetection{}, map[string]string{"127.0.0.1:8381": "stable"})
+
+	assert.EqualError(t, h.Drain("http://127.0.0.1:9999"), "unknown target: http://127.0.0.1:9999")
+	assert.EqualError(t, h.Enable("http://127.0.0.1:9999"), "unknown target: http://127.0.0.1:9999")
+	assert.Empty(t, h.DrainedTargets())
+
+	var nilChecker *HealthChecker
+	assert.False(t, nilChecker.IsDrained("http://127.0.0.1:8381"))
+}
+
+func TestDrain_PruneRemovedTargets(t *testing.T) {
+	h := newOutlierTestChecker(config.OutlierDetection{}, map[string]string{
+		"127.0.0.1:8381": "stable",
+		"127.0.0.1:8382": "stable",
+	})
+	require.NoError(t, h.Drain("http://127.0.0.1:8381"))
+	require.NoError(t, h.Drain("http://127.0.0.1:8382"))
+
+	h.pruneDrained(map[string]*targetProbe{"127.0.0.1:8381": {}})
+	assert.Equal(t, []string{"127.0.0.1:8381"}, h.DrainedTargets(), "drain state of removed targets should be dropped")
+}
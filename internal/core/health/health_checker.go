@@ -2,14 +2,13 @@ package health
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/redis/go-redis/v9"
-
 	"net/url"
 
 	"github.com/gorilla/websocket"
@@ -36,17 +35,67 @@ type TargetStatus struct {
 	ProbeFailureCount int64     `json:"probe_failure_count"`
 	LastProbeTime     time.Time `json:"last_probe_time"`
 	LastRequestTime   time.Time `json:"last_request_time"`
+	Ejected           bool      `json:"ejected"`
+	Drained           bool      `json:"drained"`
 }
 
 // HealthChecker 健康检查服务
 type HealthChecker struct {
-	healthPaths map[string]string
-	mu          sync.RWMutex
-	cfg         *config.Config
-	cleanupCh   chan struct{}
-	ctx         context.Context
+	probes    map[string]*targetProbe
+	mu        sync.RWMutex
+	cfg       *config.Config
+	cleanupCh chan struct{}
+	ctx       context.Context
+
+	outliers  map[string]*outlierState // 被动健康检查状态
+	outlierMu sync.Mutex
+
+	drained map[string]struct{} // 被管理下线的目标
+	drainMu sync.RWMutex
+
+	readySince map[string]time.Time // 目标首次通过就绪探测的时间
+	warmupMu   sync.RWMutex
+
+	listeners      []statusSubscription // 健康状态变化回调，按注册顺序调用
+	nextListenerID uint64               // 下一个回调的编号，用于取消注册
+	listenerMu     sync.RWMutex
+}
+
+// StatusListener 目标健康状态变化回调，healthy 为 false 表示目标被标记为不可用
+type StatusListener func(target string, healthy bool)
+
+// statusSubscription 已注册的健康状态变化回调
+type statusSubscription struct {
+	id       uint64
+	listener StatusListener
+}
+
+// targetProbe 单个目标的探测配置，每个目标由独立的协程按自身间隔探测
+type targetProbe struct {
+	target        string
+	url           string
+	protocol      string
+	healthPath    string // HTTP 与 WebSocket 为探测路径，gRPC 为健康检查的服务名
+	readinessPath string // 就绪探测路径，格式同 healthPath，为空时不做就绪门控
+	interval      time.Duration
+	timeout       time.Duration
+	env           string // 目标所属环境，决定被动健康检查的剔除阈值
+	stopCh        chan struct{}
+	down          bool // 最近一次探测是否失败，仅由探测协程读写
 }
 
+// sameSpec 判断两个探测配置是否一致，一致时无需重启探测协程
+func (p *targetProbe) sameSpec(o *targetProbe) bool {
+	return p.url == o.url && p.protocol == o.protocol && p.healthPath == o.healthPath &&
+		p.readinessPath == o.readinessPath && p.interval == o.interval && p.timeout == o.timeout
+}
+
+// 默认探测参数
+const (
+	defaultHeartbeatInterval = 30 * time.Second
+	defaultProbeTimeout      = 5 * time.Second
+)
+
 // Redis key 前缀
 const (
 	healthStatsPrefix = "mg:health:stats:"    // 健康检查状态
@@ -54,6 +103,9 @@ const (
 	reqCountPrefix    = "mg:cache:req_count:" // 请求计数
 )
 
+// errCacheNotInitialized 缓存存储未初始化时返回的错误
+var errCacheNotInitialized = errors.New("cache store not initialized")
+
 // GetHealthStatsKey 生成目标的健康状态 Redis 键
 func GetHealthStatsKey(target string) string {
 	return healthStatsPrefix + target
@@ -83,10 +135,13 @@ func GetGlobalHealthChecker() *HealthChecker {
 func InitHealthChecker(cfg *config.Config) *HealthChecker {
 	logger.Info("Initializing health checker service")
 	checker := &HealthChecker{
-		healthPaths: make(map[string]string),
-		cfg:         cfg,
-		cleanupCh:   make(chan struct{}),
-		ctx:         context.Background(),
+		probes:     make(map[string]*targetProbe),
+		cfg:        cfg,
+		cleanupCh:  make(chan struct{}),
+		ctx:        context.Background(),
+		outliers:   make(map[string]*outlierState),
+		drained:    make(map[string]struct{}),
+		readySince: make(map[string]time.Time),
 	}
 
 	// 清空 Redis 中所有健康检查和缓存相关键
@@ -98,7 +153,6 @@ func InitHealthChecker(cfg *config.Config) *HealthChecker {
 	}
 
 	checker.RefreshTargets(cfg)
-	go checker.startHeartbeat()
 
 	once.Do(func() {
 		globalHealthChecker = checker
@@ -106,40 +160,40 @@ func InitHealthChecker(cfg *config.Config) *HealthChecker {
 	return checker
 }
 
-// clearRedisKeys 清空 Redis 中所有相关键
+// clearRedisKeys 清空缓存存储中所有相关键
 func (h *HealthChecker) clearRedisKeys() error {
-	patterns := []string{
-		healthStatsPrefix + "*",
-		cachePrefix + "*",
-		reqCountPrefix + "*",
+	if cache.Default == nil {
+		return errCacheNotInitialized
 	}
-	for _, pattern := range patterns {
-		keys, err := cache.Client.Keys(h.ctx, pattern).Result()
-		if err != nil {
+	for _, prefix := range []string{healthStatsPrefix, cachePrefix, reqCountPrefix} {
+		if err := cache.Default.DelPrefix(h.ctx, prefix); err != nil {
 			return err
 		}
-		if len(keys) > 0 {
-			pipe := cache.Client.Pipeline()
-			for _, key := range keys {
-				pipe.Del(h.ctx, key)
-			}
-			_, err = pipe.Exec(h.ctx)
-			if err != nil {
-				return err
-			}
-		}
 	}
 	return nil
 }
 
-// RefreshTargets 刷新目标健康检查路径并初始化 Redis 数据
+// healthTarget 返回规则的健康检查目标，规则未设置 healthCheckPath 时使用其协议的默认值
+func healthTarget(rule config.RoutingRule, defaults config.HealthCheckDefaults) string {
+	if rule.HealthCheckPath != "" {
+		return rule.HealthCheckPath
+	}
+	return defaults.ForProtocol(rule.Protocol)
+}
+
+// RefreshTargets 刷新目标探测配置并初始化 Redis 数据
+// 配置未变化的目标保留原有探测协程，变化或已移除的目标会停止旧协程
 func (h *HealthChecker) RefreshTargets(cfg *config.Config) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	h.cfg = cfg
-	h.healthPaths = make(map[string]string)
+	defaultInterval := defaultHeartbeatInterval
+	if cfg.Routing.HeartbeatInterval > 0 {
+		defaultInterval = time.Duration(cfg.Routing.HeartbeatInterval) * time.Second
+	}
 
+	desired := make(map[string]*targetProbe)
 	for ruleName, rules := range cfg.Routing.Rules {
 		for _, rule := range rules {
 			host, err := NormalizeTarget(rule)
@@ -150,11 +204,48 @@ func (h *HealthChecker) RefreshTargets(cfg *config.Config) {
 				continue
 			}
 
-			if rule.HealthCheckPath != "" {
-				h.healthPaths[host] = rule.HealthCheckPath
-			} else {
-				h.healthPaths[host] = "/health"
+			probe := &targetProbe{
+				target:        host,
+				url:           rule.Target,
+				protocol:      rule.Protocol,
+				healthPath:    healthTarget(rule, cfg.Routing.HealthCheck),
+				readinessPath: rule.ReadinessCheckPath,
+				interval:      rule.HealthCheckInterval,
+				timeout:       rule.HealthCheckTimeout,
+				env:           rule.Env,
+			}
+			// 规则未设置时依次使用所属环境的设置和全局默认值
+			envProbe := cfg.Routing.HealthCheck.ForEnv(rule.Env)
+			if probe.interval <= 0 {
+				probe.interval = envProbe.Interval
 			}
+			if probe.interval <= 0 {
+				probe.interval = defaultInterval
+			}
+			if probe.timeout <= 0 {
+				probe.timeout = envProbe.Timeout
+			}
+			if probe.timeout <= 0 {
+				probe.timeout = defaultProbeTimeout
+			}
+			// 同一目标出现在多条规则中时，取最短间隔与最长超时
+			if existing, ok := desired[host]; ok {
+				if existing.interval < probe.interval {
+					probe.interval = existing.interval
+				}
+				if existing.timeout > probe.timeout {
+					probe.timeout = existing.timeout
+				}
+				if probe.readinessPath == "" {
+					probe.readinessPath = existing.readinessPath
+				}
+				// 目标属于多个环境时使用更严格的剔除阈值
+				od := cfg.Routing.OutlierDetection
+				if od.ForEnv(existing.env).ConsecutiveFailures < od.ForEnv(probe.env).ConsecutiveFailures {
+					probe.env = existing.env
+				}
+			}
+			desired[host] = probe
 
 			stat := TargetStatus{
 				Rule:              ruleName,
@@ -176,38 +267,74 @@ func (h *HealthChecker) RefreshTargets(cfg *config.Config) {
 				logger.Info("Initialized new health check target in Redis",
 					zap.String("target", host),
 					zap.String("protocol", rule.Protocol),
-					zap.String("healthCheckPath", h.healthPaths[host]))
+					zap.String("healthCheckPath", probe.healthPath))
 			}
 		}
 	}
+
+	h.pruneOutliers(desired)
+	h.pruneDrained(desired)
+	h.pruneWarmups(desired)
+
+	// 停止已移除或配置变化的探测协程
+	for host, old := range h.probes {
+		if probe, ok := desired[host]; ok && old.sameSpec(probe) {
+			old.env = probe.env // 探测协程不读取环境，可直接更新
+			continue
+		}
+		close(old.stopCh)
+		delete(h.probes, host)
+		logger.Info("Stopped health probe", zap.String("target", host))
+	}
+
+	// 为新增或配置变化的目标启动探测协程
+	for host, probe := range desired {
+		if _, ok := h.probes[host]; ok {
+			continue
+		}
+		probe.stopCh = make(chan struct{})
+		h.probes[host] = probe
+		go h.runProbe(probe)
+		logger.Info("Started health probe",
+			zap.String("target", host),
+			zap.Duration("interval", probe.interval),
+			zap.Duration("timeout", probe.timeout))
+	}
+
 	logger.Info("Health checker targets refreshed",
-		zap.Int("totalTargets", len(h.healthPaths)))
+		zap.Int("totalTargets", len(h.probes)))
 }
 
 // saveToRedis 保存目标状态到 Redis
 func (h *HealthChecker) saveToRedis(target string, stat *TargetStatus) error {
 	key := GetHealthStatsKey(target)
-	data := map[string]interface{}{
+	data := map[string]string{
 		"rule":                stat.Rule,
 		"url":                 stat.URL,
 		"protocol":            stat.Protocol,
-		"request_count":       stat.RequestCount,
-		"success_count":       stat.SuccessCount,
-		"cache_hit_count":     stat.CacheHitCount,
-		"failure_count":       stat.FailureCount,
-		"probe_request_count": stat.ProbeRequestCount,
-		"probe_success_count": stat.ProbeSuccessCount,
-		"probe_failure_count": stat.ProbeFailureCount,
-		"last_probe_time":     stat.LastProbeTime.Unix(),
-		"last_request_time":   stat.LastRequestTime.Unix(),
-	}
-	return cache.Client.HMSet(h.ctx, key, data).Err()
+		"request_count":       strconv.FormatInt(stat.RequestCount, 10),
+		"success_count":       strconv.FormatInt(stat.SuccessCount, 10),
+		"cache_hit_count":     strconv.FormatInt(stat.CacheHitCount, 10),
+		"failure_count":       strconv.FormatInt(stat.FailureCount, 10),
+		"probe_request_count": strconv.FormatInt(stat.ProbeRequestCount, 10),
+		"probe_success_count": strconv.FormatInt(stat.ProbeSuccessCount, 10),
+		"probe_failure_count": strconv.FormatInt(stat.ProbeFailureCount, 10),
+		"last_probe_time":     strconv.FormatInt(stat.LastProbeTime.Unix(), 10),
+		"last_request_time":   strconv.FormatInt(stat.LastRequestTime.Unix(), 10),
+	}
+	if cache.Default == nil {
+		return errCacheNotInitialized
+	}
+	return cache.Default.HSet(h.ctx, key, data)
 }
 
 // loadFromRedis 从 Redis 加载目标状态
 func (h *HealthChecker) loadFromRedis(target string) (*TargetStatus, error) {
 	key := GetHealthStatsKey(target)
-	data, err := cache.Client.HGetAll(h.ctx, key).Result()
+	if cache.Default == nil {
+		return nil, errCacheNotInitialized
+	}
+	data, err := cache.Default.HGetAll(h.ctx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -251,20 +378,19 @@ func (h *HealthChecker) loadFromRedis(target string) (*TargetStatus, error) {
 }
 
 // CheckCache 检查缓存是否存在并返回内容，同时更新缓存命中计数
-func (h *HealthChecker) CheckCache(ctx context.Context, method, path, target string) (string, bool) {
-	if cache.Client == nil {
-		logger.Warn("Redis client not initialized, skipping cache check")
-		return "", false
+func (h *HealthChecker) CheckCache(ctx context.Context, method, path, target string) (string, bool, error) {
+	if cache.Default == nil {
+		logger.Warn("Cache store not initialized, skipping cache check")
+		return "", false, nil
 	}
 
 	key := GetCacheKey(method, path)
-	content, err := cache.Client.Get(ctx, key).Result()
-	if err == redis.Nil {
+	content, err := cache.Default.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
 		logger.Debug("Cache miss", zap.String("key", key))
-		return "", false
+		return "", false, nil
 	} else if err != nil {
-		logger.Error("Failed to check cache", zap.Error(err), zap.String("key", key))
-		return "", false
+		return "", false, fmt.Errorf("get cache %s: %w", key, err)
 	}
 
 	// 更新缓存命中计数
@@ -276,18 +402,18 @@ func (h *HealthChecker) CheckCache(ctx context.Context, method, path, target str
 	}
 
 	logger.Debug("Cache hit", zap.String("key", key))
-	return content, true
+	return content, true, nil
 }
 
 // SetCache 设置缓存内容并指定过期时间
 func (h *HealthChecker) SetCache(ctx context.Context, method, path string, content string, ttl time.Duration) error {
-	if cache.Client == nil {
-		logger.Warn("Redis client not initialized, skipping cache set")
-		return fmt.Errorf("redis client not initialized")
+	if cache.Default == nil {
+		logger.Warn("Cache store not initialized, skipping cache set")
+		return errCacheNotInitialized
 	}
 
 	key := GetCacheKey(method, path)
-	err := cache.Client.Set(ctx, key, content, ttl).Err()
+	err := cache.Default.Set(ctx, key, content, ttl)
 	if err != nil {
 		logger.Error("Failed to set cache", zap.Error(err), zap.String("key", key), zap.Duration("ttl", ttl))
 		return err
@@ -298,21 +424,17 @@ func (h *HealthChecker) SetCache(ctx context.Context, method, path string, conte
 }
 
 // IncrementRequestCount 增加指定路径的请求计数，返回当前计数
-func (h *HealthChecker) IncrementRequestCount(ctx context.Context, path string, ttl time.Duration) int64 {
+func (h *HealthChecker) IncrementRequestCount(ctx context.Context, path string, ttl time.Duration) (int64, error) {
+	if cache.Default == nil {
+		return 0, errCacheNotInitialized
+	}
 	key := GetPathReqCountKey(path)
-	script := redis.NewScript(`
-		local key = KEYS[1]
-		local ttl = ARGV[1]
-		local count = redis.call('INCR', key)
-		redis.call('EXPIRE', key, ttl)
-		return count
-	`)
-	count, err := script.Run(ctx, cache.Client, []string{key}, ttl.Seconds()).Int64()
+	// 每次请求都刷新过期时间，持续 ttl 没有请求后计数清零
+	count, err := cache.Default.IncrExpire(ctx, key, ttl)
 	if err != nil {
-		logger.Error("Failed to increment request count with Lua", zap.Error(err), zap.String("key", key))
-		return 0
+		return 0, fmt.Errorf("increment request count %s: %w", key, err)
 	}
-	return count
+	return count, nil
 }
 
 // NormalizeTarget 规范化目标地址
@@ -336,75 +458,92 @@ func NormalizeTargetHost(target string) (string, error) {
 	return u.Host, nil
 }
 
-// startHeartbeat 开始周期性心跳检测
-func (h *HealthChecker) startHeartbeat() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// runProbe 按目标自身的间隔周期性探测，直到目标被移除或服务关闭
+func (h *HealthChecker) runProbe(p *targetProbe) {
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-h.cleanupCh:
-			logger.Info("Stopping heartbeat checks")
 			return
-		case <-ticker.C:
-			h.mu.RLock()
-			heartbeatInterval := 30 * time.Second
-			if h.cfg.Routing.HeartbeatInterval > 0 {
-				heartbeatInterval = time.Duration(h.cfg.Routing.HeartbeatInterval) * time.Second
-			}
-			h.mu.RUnlock()
-
-			h.performHeartbeatCheck()
-			ticker.Reset(heartbeatInterval)
+		case <-p.stopCh:
+			return
+		case <-timer.C:
+			h.probeOnce(p)
+			timer.Reset(p.interval)
 		}
 	}
 }
 
-// performHeartbeatCheck 执行一次心跳检测
-func (h *HealthChecker) performHeartbeatCheck() {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// probeOnce 执行一次探测并将结果写入 Redis，目标尚未就绪时先进行就绪探测
+func (h *HealthChecker) probeOnce(p *targetProbe) {
+	if p.readinessPath != "" && !h.passedReadiness(p.target) {
+		h.probeReadiness(p)
+	}
 
-	logger.Info("Starting heartbeat check",
-		zap.Int("targetCount", len(h.healthPaths)),
-		zap.String("timestamp", time.Now().Format("2006-01-02 15:04:05")))
+	healthy, supported := h.check(p, p.healthPath)
+	if !supported {
+		logger.Warn("Unsupported protocol, skipping health check",
+			zap.String("protocol", p.protocol),
+			zap.String("target", p.target))
+		return
+	}
 
-	for target, healthPath := range h.healthPaths {
-		stat, err := h.loadFromRedis(target)
-		if err != nil || stat == nil {
-			logger.Warn("Failed to load target stats from Redis",
-				zap.String("target", target), zap.Error(err))
-			continue
-		}
+	// 健康状态发生变化时通知回调
+	if healthy == p.down {
+		p.down = !healthy
+		h.notifyStatusChange(p.target, healthy)
+	}
 
-		now := time.Now()
-		stat.LastProbeTime = now
-		stat.ProbeRequestCount++
-
-		switch stat.Protocol {
-		case "http", "":
-			h.checkHTTP(target, healthPath, stat)
-		case "grpc":
-			h.checkGRPC(target, stat)
-		case "websocket":
-			h.checkWebSocket(stat.URL, healthPath, stat)
-		default:
-			logger.Warn("Unsupported protocol, skipping health check",
-				zap.String("protocol", stat.Protocol),
-				zap.String("target", target))
-		}
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-		// 保存更新后的状态到 Redis
-		err = h.saveToRedis(target, stat)
-		if err != nil {
-			logger.Error("Failed to save target stats to Redis",
-				zap.String("target", target), zap.Error(err))
-		}
+	// 探测期间目标已被移除或重启，丢弃本次结果
+	select {
+	case <-p.stopCh:
+		return
+	default:
 	}
+
+	stat, err := h.loadFromRedis(p.target)
+	if err != nil || stat == nil {
+		logger.Warn("Failed to load target stats from Redis",
+			zap.String("target", p.target), zap.Error(err))
+		return
+	}
+
+	stat.LastProbeTime = time.Now()
+	stat.ProbeRequestCount++
+	if healthy {
+		stat.ProbeSuccessCount++
+	} else {
+		stat.ProbeFailureCount++
+	}
+
+	// 保存更新后的状态到 Redis
+	err = h.saveToRedis(p.target, stat)
+	if err != nil {
+		logger.Error("Failed to save target stats to Redis",
+			zap.String("target", p.target), zap.Error(err))
+	}
+}
+
+// check 按目标协议探测给定路径（gRPC 为服务名），协议不支持探测时 supported 为 false
+func (h *HealthChecker) check(p *targetProbe, path string) (healthy, supported bool) {
+	switch p.protocol {
+	case "http", "":
+		return h.checkHTTP(p.target, path, p.timeout), true
+	case "grpc":
+		return h.checkGRPC(p.target, path, p.timeout), true
+	case "websocket":
+		return h.checkWebSocket(p.url, path, p.timeout), true
+	}
+	return false, false
 }
 
 // checkHTTP 检查 HTTP 目标健康状态
-func (h *HealthChecker) checkHTTP(target, healthPath string, stat *TargetStatus) {
+func (h *HealthChecker) checkHTTP(target, healthPath string, timeout time.Duration) bool {
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseRequest(req)
@@ -414,54 +553,41 @@ func (h *HealthChecker) checkHTTP(target, healthPath string, stat *TargetStatus)
 	req.Header.SetMethod("HEAD")
 
 	client := &fasthttp.Client{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
 	}
-	err := client.DoTimeout(req, resp, 5*time.Second)
+	err := client.DoTimeout(req, resp, timeout)
 	if err != nil || resp.StatusCode() >= 400 {
-		stat.ProbeFailureCount++
 		logger.Warn("HTTP heartbeat check failed",
 			zap.String("target", target),
 			zap.String("healthPath", healthPath),
 			zap.Error(err),
 			zap.Int("statusCode", resp.StatusCode()))
-		return
+		return false
 	}
-	stat.ProbeSuccessCount++
 	logger.Info("HTTP heartbeat check succeeded",
 		zap.String("target", target),
 		zap.String("healthPath", healthPath))
+	return true
 }
 
 // checkGRPC 检查 gRPC 目标健康状态
-func (h *HealthChecker) checkGRPC(target string, stat *TargetStatus) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (h *HealthChecker) checkGRPC(target, serviceName string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, target, grpc.WithInsecure(), grpc.WithBlock())
 	if err != nil {
-		stat.ProbeFailureCount++
 		logger.Warn("gRPC dial failed",
 			zap.String("target", target),
 			zap.Error(err))
-		return
+		return false
 	}
 	defer conn.Close()
 
-	healthPath, ok := h.healthPaths[target]
-	if !ok {
-		healthPath = "" // 默认检查整个服务器
-	}
-
 	client := grpc_health_v1.NewHealthClient(conn)
-	serviceName := healthPath
-	if serviceName == "/health" {
-		serviceName = "" // 检查整个服务器
-	}
-
 	resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: serviceName})
 	if err != nil || (resp != nil && resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING) {
-		stat.ProbeFailureCount++
 		var statusStr string
 		if resp != nil {
 			statusStr = resp.GetStatus().String()
@@ -473,39 +599,48 @@ func (h *HealthChecker) checkGRPC(target string, stat *TargetStatus) {
 			zap.String("service", serviceName),
 			zap.Error(err),
 			zap.String("status", statusStr))
-		return
+		return false
 	}
 
-	stat.ProbeSuccessCount++
 	logger.Info("gRPC health check succeeded",
 		zap.String("target", target),
 		zap.String("service", serviceName))
+	return true
 }
 
 // checkWebSocket 检查 WebSocket 目标健康状态
-func (h *HealthChecker) checkWebSocket(target, healthPath string, stat *TargetStatus) {
-	dialer := websocket.DefaultDialer
+func (h *HealthChecker) checkWebSocket(target, healthPath string, timeout time.Duration) bool {
+	dialer := &websocket.Dialer{
+		Proxy:            websocket.DefaultDialer.Proxy,
+		HandshakeTimeout: timeout,
+	}
 	fullURL := target + healthPath
 	conn, _, err := dialer.Dial(fullURL, nil)
 	if err != nil {
-		stat.ProbeFailureCount++
 		logger.Warn("WebSocket heartbeat check failed",
 			zap.String("target", target),
 			zap.String("healthPath", healthPath),
 			zap.String("fullURL", fullURL),
 			zap.Error(err))
-		return
+		return false
 	}
 	defer conn.Close()
-	stat.ProbeSuccessCount++
 	logger.Info("WebSocket heartbeat check succeeded",
 		zap.String("target", target),
 		zap.String("healthPath", healthPath),
 		zap.String("fullURL", fullURL))
+	return true
 }
 
 // UpdateRequestCount 更新业务请求计数
 func (h *HealthChecker) UpdateRequestCount(target string, success bool) {
+	if h == nil {
+		return
+	}
+	if key := outlierKey(target); h.recordOutcome(key, success) {
+		h.notifyStatusChange(key, false)
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -537,7 +672,7 @@ func (h *HealthChecker) ResetAllStats() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	for target := range h.healthPaths {
+	for target := range h.probes {
 		stat, err := h.loadFromRedis(target)
 		if err != nil || stat == nil {
 			continue
@@ -564,7 +699,7 @@ func (h *HealthChecker) GetAllStats() []TargetStatus {
 	defer h.mu.RUnlock()
 
 	var stats []TargetStatus
-	for target := range h.healthPaths {
+	for target := range h.probes {
 		stat, err := h.loadFromRedis(target)
 		if err != nil {
 			logger.Error("Failed to load target stats from Redis",
@@ -572,6 +707,8 @@ func (h *HealthChecker) GetAllStats() []TargetStatus {
 			continue
 		}
 		if stat != nil {
+			stat.Ejected = h.IsEjected(target)
+			stat.Drained = h.IsDrained(target)
 			stats = append(stats, *stat)
 		}
 	}
@@ -588,8 +725,49 @@ func (h *HealthChecker) GetAllStats() []TargetStatus {
 	return stats
 }
 
-// Close 关闭健康检查服务
+// OnStatusChange 注册目标健康状态变化回调，主动探测结果翻转或被动检查剔除目标时触发
+// 返回的函数用于取消注册，回调所属的组件关闭时应调用，可重复调用
+func (h *HealthChecker) OnStatusChange(listener StatusListener) (unsubscribe func()) {
+	h.listenerMu.Lock()
+	defer h.listenerMu.Unlock()
+	id := h.nextListenerID
+	h.nextListenerID++
+	h.listeners = append(h.listeners, statusSubscription{id: id, listener: listener})
+
+	return func() {
+		h.listenerMu.Lock()
+		defer h.listenerMu.Unlock()
+		for i, sub := range h.listeners {
+			if sub.id == id {
+				h.listeners = append(h.listeners[:i:i], h.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notifyStatusChange 通知所有回调目标健康状态发生变化
+func (h *HealthChecker) notifyStatusChange(target string, healthy bool) {
+	h.listenerMu.RLock()
+	listeners := make([]StatusListener, len(h.listeners))
+	for i, sub := range h.listeners {
+		listeners[i] = sub.listener
+	}
+	h.listenerMu.RUnlock()
+
+	logger.Info("Target health status changed",
+		zap.String("target", target),
+		zap.Bool("healthy", healthy))
+	for _, listener := range listeners {
+		listener(target, healthy)
+	}
+}
+
+// Close 关闭健康检查服务，并停止所有探测协程
 func (h *HealthChecker) Close() {
+	h.mu.Lock()
+	h.probes = make(map[string]*targetProbe)
+	h.mu.Unlock()
 	close(h.cleanupCh)
 	logger.Info("Health checker service closed")
 }
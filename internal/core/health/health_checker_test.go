@@ -0,0 +1,195 @@
+package health
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthTarget_ProtocolDefaults(t *testing.T) {
+	defaults := config.HealthCheckDefaults{HTTPPath: "/health", GRPCService: "", WebSocketPath: "/ws/health"}
+
+	assert.Equal(t, "/health", healthTarget(config.RoutingRule{Protocol: "http"}, defaults))
+	assert.Equal(t, "/health", healthTarget(config.RoutingRule{}, defaults))
+	assert.Equal(t, "", healthTarget(config.RoutingRule{Protocol: "grpc"}, defaults), "gRPC defaults to checking the whole server")
+	assert.Equal(t, "/ws/health", healthTarget(config.RoutingRule{Protocol: "websocket"}, defaults))
+}
+
+func TestHealthTarget_RuleOverride(t *testing.T) {
+	defaults := config.HealthCheckDefaults{HTTPPath: "/health", GRPCService: "", WebSocketPath: "/health"}
+
+	assert.Equal(t, "/status", healthTarget(config.RoutingRule{Protocol: "http", HealthCheckPath: "/status"}, defaults))
+	assert.Equal(t, "hello.Health", healthTarget(config.RoutingRule{Protocol: "grpc", HealthCheckPath: "hello.Health"}, defaults))
+	assert.Equal(t, "/ping", healthTarget(config.RoutingRule{Protocol: "websocket", HealthCheckPath: "/ping"}, defaults))
+}
+
+func TestCheckGRPC_UsesServiceNameVerbatim(t *testing.T) {
+	logger.InitTestLogger()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	hs := grpchealth.NewServer()
+	hs.SetServingStatus("hello.Health", grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	h := &HealthChecker{}
+	target := lis.Addr().String()
+	assert.True(t, h.checkGRPC(target, "", time.Second), "empty service checks the whole server")
+	assert.True(t, h.checkGRPC(target, "hello.Health", time.Second))
+	// /health 不再被视为整个服务器，而是按服务名查询
+	assert.False(t, h.checkGRPC(target, "/health", time.Second))
+}
+
+// probeLifecycleConfig 返回单个 HTTP 目标的配置，探测间隔与超时按参数设置
+func probeLifecycleConfig(target string, interval, timeout time.Duration) *config.Config {
+	return &config.Config{Routing: config.Routing{Rules: map[string]config.RoutingRules{
+		"/api/v1/user": {{Target: target, Protocol: "http", HealthCheckPath: "/health", HealthCheckInterval: interval, HealthCheckTimeout: timeout}},
+	}}}
+}
+
+// stopped 判断探测协程的停止信号是否已发出
+func stopped(p *targetProbe) bool {
+	select {
+	case <-p.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestRefreshTargets_ProbeLifecycle(t *testing.T) {
+	logger.InitTestLogger()
+	cache.InitTestStore()
+	var probes atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	host, err := NormalizeTargetHost(backend.URL)
+	require.NoError(t, err)
+
+	h := InitHealthChecker(probeLifecycleConfig(backend.URL, 50*time.Millisecond, time.Second))
+	defer h.Close()
+
+	// 启动：每个目标一个探测协程，按自身间隔持续探测
+	require.Contains(t, h.probes, host)
+	first := h.probes[host]
+	assert.Equal(t, 50*time.Millisecond, first.interval)
+	assert.Equal(t, time.Second, first.timeout)
+	assert.Eventually(t, func() bool { return probes.Load() >= 3 }, 3*time.Second, 10*time.Millisecond,
+		"target should be probed repeatedly on its own interval")
+
+	// 配置未变化时保留原探测协程
+	h.RefreshTargets(probeLifecycleConfig(backend.URL, 50*time.Millisecond, time.Second))
+	assert.Same(t, first, h.probes[host])
+	assert.False(t, stopped(first))
+
+	// 超时变化时停止旧协程并启动新协程
+	h.RefreshTargets(probeLifecycleConfig(backend.URL, 50*time.Millisecond, 2*time.Second))
+	second := h.probes[host]
+	assert.NotSame(t, first, second)
+	assert.True(t, stopped(first), "old probe should be stopped after its spec changed")
+	assert.False(t, stopped(second))
+	assert.Equal(t, 2*time.Second, second.timeout)
+
+	// 目标移除后停止探测，不再访问后端
+	h.RefreshTargets(&config.Config{})
+	assert.Empty(t, h.probes)
+	assert.True(t, stopped(second), "probe of removed target should be stopped")
+	time.Sleep(100 * time.Millisecond) // 等待进行中的探测结束
+	before := probes.Load()
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, before, probes.Load(), "removed target should not be probed any more")
+}
+
+func TestClose_StopsAllProbes(t *testing.T) {
+	logger.InitTestLogger()
+	cache.InitTestStore()
+	var probes atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	h := InitHealthChecker(probeLifecycleConfig(backend.URL, 50*time.Millisecond, time.Second))
+	assert.Eventually(t, func() bool { return probes.Load() >= 1 }, 3*time.Second, 10*time.Millisecond)
+
+	h.Close()
+	time.Sleep(100 * time.Millisecond)
+	before := probes.Load()
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, before, probes.Load(), "no probe should run after Close")
+}
+
+func TestOnStatusChange_Unsubscribe(t *testing.T) {
+	logger.InitTestLogger()
+	h := &HealthChecker{}
+	var calls []string
+	record := func(name string) StatusListener {
+		return func(target string, healthy bool) { calls = append(calls, name) }
+	}
+
+	unsubscribeFirst := h.OnStatusChange(record("first"))
+	h.OnStatusChange(record("second"))
+	unsubscribeThird := h.OnStatusChange(record("third"))
+
+	h.notifyStatusChange("127.0.0.1:8381", false)
+	assert.Equal(t, []string{"first", "second", "third"}, calls, "listeners are called in registration order")
+
+	calls = nil
+	unsubscribeFirst()
+	unsubscribeFirst() // 重复取消不影响其他回调
+	h.notifyStatusChange("127.0.0.1:8381", true)
+	assert.Equal(t, []string{"second", "third"}, calls)
+
+	calls = nil
+	unsubscribeThird()
+	h.notifyStatusChange("127.0.0.1:8381", false)
+	assert.Equal(t, []string{"second"}, calls)
+}
+
+func TestRefreshTargets_ProbeSettingsPerEnvironment(t *testing.T) {
+	logger.InitTestLogger()
+	cache.InitTestStore()
+	cfg := &config.Config{Routing: config.Routing{
+		HeartbeatInterval: 30,
+		HealthCheck: config.HealthCheckDefaults{Environments: map[string]config.ProbeSettings{
+			"canary": {Interval: 5 * time.Second, Timeout: time.Second},
+		}},
+		Rules: map[string]config.RoutingRules{
+			"/api/v1/user": {
+				{Target: "http://127.0.0.1:8381", Env: "stable"},
+				{Target: "http://127.0.0.1:8382", Env: "canary"},
+				// 规则上的设置优先于环境的设置
+				{Target: "http://127.0.0.1:8383", Env: "canary", HealthCheckInterval: 2 * time.Second},
+			},
+		},
+	}}
+	h := InitHealthChecker(cfg)
+	defer h.Close()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	assert.Equal(t, 30*time.Second, h.probes["127.0.0.1:8381"].interval, "stable target uses heartbeatInterval")
+	assert.Equal(t, defaultProbeTimeout, h.probes["127.0.0.1:8381"].timeout)
+	assert.Equal(t, 5*time.Second, h.probes["127.0.0.1:8382"].interval, "canary target is probed more often")
+	assert.Equal(t, time.Second, h.probes["127.0.0.1:8382"].timeout)
+	assert.Equal(t, 2*time.Second, h.probes["127.0.0.1:8383"].interval)
+	assert.Equal(t, time.Second, h.probes["127.0.0.1:8383"].timeout)
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"time"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// outlierState 记录单个目标的被动健康状态
+type outlierState struct {
+	consecutiveFailures int       // 当前连续失败次数
+	ejectedUntil        time.Time // 剔除截止时间，零值表示未被剔除
+}
+
+// recordOutcome 根据真实请求结果更新被动健康状态，连续失败达到目标所属环境的阈值时剔除目标，返回本次是否触发剔除
+func (h *HealthChecker) recordOutcome(host string, success bool) bool {
+	h.mu.RLock()
+	od := h.cfg.Routing.OutlierDetection
+	var env string
+	if probe, ok := h.probes[host]; ok {
+		env = probe.env
+	}
+	h.mu.RUnlock()
+	threshold := od.ForEnv(env)
+	if !od.Enabled || threshold.ConsecutiveFailures <= 0 {
+		return false
+	}
+
+	h.outlierMu.Lock()
+	defer h.outlierMu.Unlock()
+
+	state, ok := h.outliers[host]
+	if !ok {
+		state = &outlierState{}
+		h.outliers[host] = state
+	}
+	if success {
+		state.consecutiveFailures = 0
+		return false
+	}
+
+	state.consecutiveFailures++
+	now := time.Now()
+	if state.consecutiveFailures >= threshold.ConsecutiveFailures && !now.Before(state.ejectedUntil) {
+		state.ejectedUntil = now.Add(threshold.EjectionDuration)
+		state.consecutiveFailures = 0
+		logger.Warn("Target ejected by outlier detection",
+			zap.String("target", host),
+			zap.String("env", env),
+			zap.Int("consecutiveFailures", threshold.ConsecutiveFailures),
+			zap.Duration("ejectionDuration", threshold.EjectionDuration))
+		return true
+	}
+	return false
+}
+
+// IsEjected 判断目标当前是否因被动健康检查被剔除
+func (h *HealthChecker) IsEjected(target string) bool {
+	if h == nil {
+		return false
+	}
+	host := outlierKey(target)
+
+	h.outlierMu.Lock()
+	defer h.outlierMu.Unlock()
+
+	state, ok := h.outliers[host]
+	return ok && time.Now().Before(state.ejectedUntil)
+}
+
+// outlierKey 将目标地址规范化为被动健康状态的键，与探测目标的键保持一致
+func outlierKey(target string) string {
+	if host, err := NormalizeTargetHost(target); err == nil && host != "" {
+		return host
+	}
+	return target
+}
+
+// pruneOutliers 清理已不在配置中的目标的被动健康状态
+func (h *HealthChecker) pruneOutliers(hosts map[string]*targetProbe) {
+	h.outlierMu.Lock()
+	defer h.outlierMu.Unlock()
+
+	for host := range h.outliers {
+		if _, ok := hosts[host]; !ok {
+			delete(h.outliers, host)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// newOutlierTestChecker 创建不启动探测协程的健康检查实例，目标按 host 到环境的映射注册
+func newOutlierTestChecker(od config.OutlierDetection, envs map[string]string) *HealthChecker {
+	logger.InitTestLogger()
+	h := &HealthChecker{
+		probes:   make(map[string]*targetProbe),
+		cfg:      &config.Config{Routing: config.Routing{OutlierDetection: od}},
+		outliers: make(map[string]*outlierState),
+		drained:  make(map[string]struct{}),
+	}
+	for host, env := range envs {
+		h.probes[host] = &targetProbe{target: host, env: env}
+	}
+	return h
+}
+
+func TestOutlierDetection_CanaryEjectedSooner(t *testing.T) {
+	od := config.OutlierDetection{
+		Enabled:             true,
+		ConsecutiveFailures: 5,
+		EjectionDuration:    30 * time.Second,
+		Environments: map[string]config.OutlierThreshold{
+			"canary": {ConsecutiveFailures: 2},
+		},
+	}
+	h := newOutlierTestChecker(od, map[string]string{
+		"127.0.0.1:8381": "stable",
+		"127.0.0.1:8382": "canary",
+	})
+
+	// 两个目标都连续失败 3 次
+	for i := 0; i < 3; i++ {
+		h.recordOutcome("127.0.0.1:8381", false)
+		h.recordOutcome("127.0.0.1:8382", false)
+	}
+
+	assert.True(t, h.IsEjected("http://127.0.0.1:8382"), "canary target should be ejected")
+	assert.False(t, h.IsEjected("http://127.0.0.1:8381"), "stable target should stay in rotation")
+}
+
+func TestOutlierDetection_EnvironmentInheritsGlobalValues(t *testing.T) {
+	od := config.OutlierDetection{
+		Enabled:             true,
+		ConsecutiveFailures: 5,
+		EjectionDuration:    30 * time.Second,
+		Environments: map[string]config.OutlierThreshold{
+			"canary": {EjectionDuration: time.Minute},
+		},
+	}
+
+	assert.Equal(t, config.OutlierThreshold{ConsecutiveFailures: 5, EjectionDuration: time.Minute}, od.ForEnv("Canary"))
+	assert.Equal(t, config.OutlierThreshold{ConsecutiveFailures: 5, EjectionDuration: 30 * time.Second}, od.ForEnv("stable"))
+	assert.Equal(t, config.OutlierThreshold{ConsecutiveFailures: 5, EjectionDuration: 30 * time.Second}, od.ForEnv(""))
+}
+
+func TestOutlierDetection_EjectsAfterConsecutiveFailures(t *testing.T) {
+	od := config.OutlierDetection{Enabled: true, ConsecutiveFailures: 3, EjectionDuration: 30 * time.Second}
+	h := newOutlierTestChecker(od, map[string]string{"127.0.0.1:8381": "stable"})
+
+	h.UpdateRequestCount("http://127.0.0.1:8381", false)
+	h.UpdateRequestCount("http://127.0.0.1:8381", false)
+	assert.False(t, h.IsEjected("http://127.0.0.1:8381"), "target should stay below the threshold")
+
+	h.UpdateRequestCount("http://127.0.0.1:8381", false)
+	assert.True(t, h.IsEjected("http://127.0.0.1:8381"), "target should be ejected after 3 consecutive failures")
+}
+
+func TestOutlierDetection_SuccessResetsFailures(t *testing.T) {
+	od := config.OutlierDetection{Enabled: true, ConsecutiveFailures: 3, EjectionDuration: 30 * time.Second}
+	h := newOutlierTestChecker(od, map[string]string{"127.0.0.1:8381": "stable"})
+
+	// 失败之间夹杂成功请求时不算连续失败
+	for i := 0; i < 5; i++ {
+		h.recordOutcome("127.0.0.1:8381", false)
+		h.recordOutcome("127.0.0.1:8381", false)
+		h.recordOutcome("127.0.0.1:8381", true)
+	}
+	assert.False(t, h.IsEjected("http://127.0.0.1:8381"))
+}
+
+func TestOutlierDetection_EjectionExpires(t *testing.T) {
+	od := config.OutlierDetection{Enabled: true, ConsecutiveFailures: 1, EjectionDuration: 30 * time.Second}
+	h := newOutlierTestChecker(od, map[string]string{"127.0.0.1:8381": "stable"})
+
+	assert.True(t, h.recordOutcome("127.0.0.1:8381", false), "first failure should eject the target")
+	assert.True(t, h.IsEjected("http://127.0.0.1:8381"))
+	// 冷却期内的失败不会重复剔除
+	assert.False(t, h.recordOutcome("127.0.0.1:8381", false))
+
+	h.outliers["127.0.0.1:8381"].ejectedUntil = time.Now().Add(-time.Second)
+	assert.False(t, h.IsEjected("http://127.0.0.1:8381"), "target should return after the ejection duration")
+}
+
+func TestOutlierDetection_Disabled(t *testing.T) {
+	od := config.OutlierDetection{Enabled: false, ConsecutiveFailures: 1, EjectionDuration: 30 * time.Second}
+	h := newOutlierTestChecker(od, map[string]string{"127.0.0.1:8381": "stable"})
+
+	for i := 0; i < 5; i++ {
+		h.recordOutcome("127.0.0.1:8381", false)
+	}
+	assert.False(t, h.IsEjected("http://127.0.0.1:8381"))
+
+	var nilChecker *HealthChecker
+	assert.False(t, nilChecker.IsEjected("http://127.0.0.1:8381"))
+}
+
+func TestOutlierDetection_PruneRemovedTargets(t *testing.T) {
+	od := config.OutlierDetection{Enabled: true, ConsecutiveFailures: 1, EjectionDuration: 30 * time.Second}
+	h := newOutlierTestChecker(od, map[string]string{"127.0.0.1:8381": "stable", "127.0.0.1:8382": "stable"})
+	h.recordOutcome("127.0.0.1:8381", false)
+	h.recordOutcome("127.0.0.1:8382", false)
+
+	h.pruneOutliers(map[string]*targetProbe{"127.0.0.1:8381": {}})
+	assert.True(t, h.IsEjected("http://127.0.0.1:8381"))
+	assert.False(t, h.IsEjected("http://127.0.0.1:8382"), "state of removed target should be dropped")
+}
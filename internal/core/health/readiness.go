@@ -0,0 +1,29 @@
+package health
+
+import "time"
+
+// Readiness 判断网关是否可以接收流量，启动宽限期内即使其他检查通过也保持未就绪，
+// 滚动发布时新实例可先预热缓存与连接，再由负载均衡器引入流量
+type Readiness struct {
+	startedAt   time.Time        // 启动时间
+	gracePeriod time.Duration    // 启动宽限期
+	now         func() time.Time // 当前时间，便于测试替换
+}
+
+// NewReadiness 创建就绪状态，宽限期从调用时开始计算
+func NewReadiness(gracePeriod time.Duration) *Readiness {
+	return &Readiness{
+		startedAt:   time.Now(),
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+	}
+}
+
+// Ready 返回网关是否就绪，未就绪时同时返回宽限期的剩余时间
+func (r *Readiness) Ready() (bool, time.Duration) {
+	remaining := r.gracePeriod - r.now().Sub(r.startedAt)
+	if remaining > 0 {
+		return false, remaining
+	}
+	return true, 0
+}
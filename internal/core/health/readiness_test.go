@@ -0,0 +1,35 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness_HeldDuringGracePeriod(t *testing.T) {
+	start := time.Now()
+	now := start
+	r := NewReadiness(15 * time.Second)
+	r.startedAt = start
+	r.now = func() time.Time { return now }
+
+	ready, remaining := r.Ready()
+	assert.False(t, ready, "should not be ready right after startup")
+	assert.Equal(t, 15*time.Second, remaining)
+
+	now = start.Add(10 * time.Second)
+	ready, remaining = r.Ready()
+	assert.False(t, ready, "should stay not-ready within the grace period")
+	assert.Equal(t, 5*time.Second, remaining)
+
+	now = start.Add(15 * time.Second)
+	ready, remaining = r.Ready()
+	assert.True(t, ready, "should flip to ready once the grace period elapses")
+	assert.Zero(t, remaining)
+}
+
+func TestReadiness_NoGracePeriod(t *testing.T) {
+	ready, _ := NewReadiness(0).Ready()
+	assert.True(t, ready)
+}
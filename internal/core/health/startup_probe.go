@@ -0,0 +1,66 @@
+package health
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ProbeSummary 一次性探测所有目标的结果，协议不支持探测的目标不计入
+type ProbeSummary struct {
+	Reachable   []string `json:"reachable"`
+	Unreachable []string `json:"unreachable"`
+}
+
+// UnreachableRatio 返回不可达目标的占比，没有探测任何目标时为 0
+func (s ProbeSummary) UnreachableRatio() float64 {
+	total := len(s.Reachable) + len(s.Unreachable)
+	if total == 0 {
+		return 0
+	}
+	return float64(len(s.Unreachable)) / float64(total)
+}
+
+// ProbeAll 并发探测所有目标一次并等待全部完成，用于启动时确认后端是否可达
+// 探测结果不写入统计，也不触发健康状态变化回调，周期探测照常进行
+func (h *HealthChecker) ProbeAll() ProbeSummary {
+	h.mu.RLock()
+	probes := make([]*targetProbe, 0, len(h.probes))
+	for _, p := range h.probes {
+		probes = append(probes, p)
+	}
+	h.mu.RUnlock()
+
+	var (
+		summary ProbeSummary
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+	for _, p := range probes {
+		wg.Add(1)
+		go func(p *targetProbe) {
+			defer wg.Done()
+			healthy, supported := h.check(p, p.healthPath)
+			if !supported {
+				logger.Warn("Unsupported protocol, skipping startup probe",
+					zap.String("protocol", p.protocol),
+					zap.String("target", p.target))
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if healthy {
+				summary.Reachable = append(summary.Reachable, p.target)
+			} else {
+				summary.Unreachable = append(summary.Unreachable, p.target)
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	sort.Strings(summary.Reachable)
+	sort.Strings(summary.Unreachable)
+	return summary
+}
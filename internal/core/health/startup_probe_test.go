@@ -0,0 +1,38 @@
+package health
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeAll(t *testing.T) {
+	logger.InitTestLogger()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	up := backend.Listener.Addr().String()
+	h := &HealthChecker{probes: map[string]*targetProbe{
+		up:          {target: up, protocol: "http", healthPath: "/health", timeout: time.Second},
+		down:        {target: down, protocol: "http", healthPath: "/health", timeout: time.Second},
+		"127.0.0.1": {target: "127.0.0.1", protocol: "tcp", timeout: time.Second},
+	}}
+
+	summary := h.ProbeAll()
+	assert.Equal(t, []string{up}, summary.Reachable)
+	assert.Equal(t, []string{down}, summary.Unreachable)
+	assert.Equal(t, 0.5, summary.UnreachableRatio(), "targets with unsupported protocols are not counted")
+	assert.Zero(t, ProbeSummary{}.UnreachableRatio())
+}
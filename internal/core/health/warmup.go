@@ -0,0 +1,80 @@
+package health
+
+import (
+	"time"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// minWarmupFactor 慢启动期间的最小流量比例，保证刚通过就绪探测的目标也能分到少量流量
+const minWarmupFactor = 0.01
+
+// WarmupFactor 返回目标当前可承接的流量比例：配置了就绪探测但尚未通过时为 0，
+// 通过后在 routing.slowStart 内从 0 线性增加到 1，未配置就绪探测或不在配置中的目标为 1
+func (h *HealthChecker) WarmupFactor(target string) float64 {
+	if h == nil {
+		return 1
+	}
+	host := outlierKey(target)
+
+	h.mu.RLock()
+	probe, ok := h.probes[host]
+	slowStart := h.cfg.Routing.SlowStart
+	h.mu.RUnlock()
+	if !ok || probe.readinessPath == "" {
+		return 1
+	}
+
+	h.warmupMu.RLock()
+	since, ready := h.readySince[host]
+	h.warmupMu.RUnlock()
+	if !ready {
+		return 0
+	}
+	elapsed := time.Since(since)
+	if slowStart <= 0 || elapsed >= slowStart {
+		return 1
+	}
+	return max(float64(elapsed)/float64(slowStart), minWarmupFactor)
+}
+
+// passedReadiness 判断目标是否已通过就绪探测
+func (h *HealthChecker) passedReadiness(host string) bool {
+	h.warmupMu.RLock()
+	defer h.warmupMu.RUnlock()
+	_, ok := h.readySince[host]
+	return ok
+}
+
+// probeReadiness 执行一次就绪探测，首次成功后记录就绪时间，此后只做存活探测
+func (h *HealthChecker) probeReadiness(p *targetProbe) {
+	ready, supported := h.check(p, p.readinessPath)
+	if !supported || !ready {
+		logger.Debug("Target not ready yet",
+			zap.String("target", p.target),
+			zap.String("readinessPath", p.readinessPath))
+		return
+	}
+
+	h.warmupMu.Lock()
+	if _, ok := h.readySince[p.target]; !ok {
+		h.readySince[p.target] = time.Now()
+	}
+	h.warmupMu.Unlock()
+	logger.Info("Target passed readiness probe, admitting traffic",
+		zap.String("target", p.target),
+		zap.String("readinessPath", p.readinessPath))
+}
+
+// pruneWarmups 清理已不在配置中的目标的就绪状态，目标重新加入时需再次通过就绪探测
+func (h *HealthChecker) pruneWarmups(hosts map[string]*targetProbe) {
+	h.warmupMu.Lock()
+	defer h.warmupMu.Unlock()
+
+	for host := range h.readySince {
+		if _, ok := hosts[host]; !ok {
+			delete(h.readySince, host)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWarmupChecker 创建只包含单个就绪门控目标的健康检查器
+func newWarmupChecker(host string, slowStart time.Duration) *HealthChecker {
+	return &HealthChecker{
+		probes: map[string]*targetProbe{
+			host: {target: host, protocol: "http", readinessPath: "/ready", timeout: time.Second},
+		},
+		cfg:        &config.Config{Routing: config.Routing{SlowStart: slowStart}},
+		readySince: make(map[string]time.Time),
+	}
+}
+
+func TestWarmupFactor_GatedUntilReadinessPasses(t *testing.T) {
+	logger.InitTestLogger()
+	var ready atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ready" && ready.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	host := backend.Listener.Addr().String()
+	h := newWarmupChecker(host, 0)
+
+	h.probeReadiness(h.probes[host])
+	assert.Equal(t, 0.0, h.WarmupFactor(backend.URL), "target must not receive traffic before readiness passes")
+
+	ready.Store(true)
+	h.probeReadiness(h.probes[host])
+	assert.Equal(t, 1.0, h.WarmupFactor(backend.URL), "without slow start the target is fully admitted")
+}
+
+func TestWarmupFactor_SlowStartRamp(t *testing.T) {
+	h := newWarmupChecker("127.0.0.1:8381", 10*time.Second)
+
+	h.readySince["127.0.0.1:8381"] = time.Now().Add(-5 * time.Second)
+	assert.InDelta(t, 0.5, h.WarmupFactor("http://127.0.0.1:8381"), 0.05)
+
+	h.readySince["127.0.0.1:8381"] = time.Now()
+	assert.Equal(t, minWarmupFactor, h.WarmupFactor("http://127.0.0.1:8381"), "just-ready targets get a minimal share")
+
+	h.readySince["127.0.0.1:8381"] = time.Now().Add(-time.Minute)
+	assert.Equal(t, 1.0, h.WarmupFactor("http://127.0.0.1:8381"))
+}
+
+func TestWarmupFactor_UngatedTargets(t *testing.T) {
+	var nilChecker *HealthChecker
+	assert.Equal(t, 1.0, nilChecker.WarmupFactor("http://127.0.0.1:8381"))
+
+	h := newWarmupChecker("127.0.0.1:8381", 0)
+	assert.Equal(t, 1.0, h.WarmupFactor("http://127.0.0.1:9999"), "targets unknown to the checker are not gated")
+}
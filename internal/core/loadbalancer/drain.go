@@ -0,0 +1,127 @@
+package loadbalancer
+
+import (
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// DrainChecker 判断目标是否被管理下线
+type DrainChecker func(target string) bool
+
+// WarmupChecker 返回目标当前可承接的流量比例：0 表示尚未通过就绪探测，0 到 1 之间表示处于慢启动，1 表示完全可用
+type WarmupChecker func(target string) float64
+
+// DrainAware 包装负载均衡器，选择目标时跳过被管理下线的目标，设置预热检查后同时跳过尚未就绪的目标
+type DrainAware struct {
+	LoadBalancer
+	isDrained DrainChecker
+	warmup    WarmupChecker // 为 nil 时不考虑目标的就绪与慢启动
+}
+
+// NewDrainAware 创建跳过被管理下线目标的负载均衡器
+func NewDrainAware(lb LoadBalancer, isDrained DrainChecker) *DrainAware {
+	return &DrainAware{LoadBalancer: lb, isDrained: isDrained}
+}
+
+// WithWarmup 设置目标预热检查，返回自身便于链式调用
+func (d *DrainAware) WithWarmup(warmup WarmupChecker) *DrainAware {
+	d.warmup = warmup
+	return d
+}
+
+// SelectTarget 从未下线且已就绪的目标中选择，没有可用目标时返回空字符串
+// Consul 和加权轮询可能从自身维护的列表中选出已下线或未就绪的目标，此时按轮次重新选择
+func (d *DrainAware) SelectTarget(targets []string, r *http.Request) string {
+	available := d.availableTargets(targets)
+	if len(available) == 0 {
+		logger.Warn("All targets are drained", zap.Strings("targets", targets))
+		return ""
+	}
+	available = d.warmTargets(available)
+	if len(available) == 0 {
+		logger.Warn("No target has passed its readiness probe yet", zap.Strings("targets", targets))
+		return ""
+	}
+
+	for range targets {
+		target := d.LoadBalancer.SelectTarget(available, r)
+		if target == "" || d.admits(target, available) {
+			return target
+		}
+	}
+	return available[0]
+}
+
+// admits 判断负载均衡器选出的目标能否使用：未被下线，且已完全预热或在本次参与选择的目标中
+func (d *DrainAware) admits(target string, candidates []string) bool {
+	if d.isDrained(target) {
+		return false
+	}
+	if d.warmup == nil || d.warmup(target) >= 1 {
+		return true
+	}
+	return slices.Contains(candidates, target)
+}
+
+// warmTargets 排除尚未就绪的目标，慢启动中的目标按已预热比例随机参与本次选择，
+// 随机排除后没有目标时保留慢启动中的目标，避免刚就绪的目标因慢启动而拒绝请求
+func (d *DrainAware) warmTargets(targets []string) []string {
+	if d.warmup == nil {
+		return targets
+	}
+	factors := make([]float64, len(targets))
+	allWarm := true
+	for i, target := range targets {
+		factors[i] = d.warmup(target)
+		allWarm = allWarm && factors[i] >= 1
+	}
+	if allWarm {
+		return targets
+	}
+
+	var selected, ramping []string
+	for i, target := range targets {
+		switch factor := factors[i]; {
+		case factor >= 1:
+			selected = append(selected, target)
+		case factor > 0:
+			ramping = append(ramping, target)
+			if rand.Float64() < factor {
+				selected = append(selected, target)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return ramping
+	}
+	return selected
+}
+
+// ActiveTargets 透传被包装负载均衡器的活跃目标
+func (d *DrainAware) ActiveTargets() []string {
+	if reporter, ok := d.LoadBalancer.(ActiveTargetsReporter); ok {
+		return reporter.ActiveTargets()
+	}
+	return nil
+}
+
+// availableTargets 过滤被管理下线的目标，没有目标下线时直接返回原切片
+func (d *DrainAware) availableTargets(targets []string) []string {
+	for i, target := range targets {
+		if !d.isDrained(target) {
+			continue
+		}
+		available := append(make([]string, 0, len(targets)), targets[:i]...)
+		for _, t := range targets[i+1:] {
+			if !d.isDrained(t) {
+				available = append(available, t)
+			}
+		}
+		return available
+	}
+	return targets
+}
@@ -0,0 +1,135 @@
+package loadbalancer
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// drainedSet 返回判断目标是否在给定集合中的 DrainChecker
+func drainedSet(targets ...string) DrainChecker {
+	set := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		set[target] = true
+	}
+	return func(target string) bool { return set[target] }
+}
+
+func TestDrainAware_SkipsDrainedTargets(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382", "http://localhost:8383"}
+	lb := NewDrainAware(NewRoundRobin(), drainedSet("http://localhost:8382"))
+	req := httptest.NewRequest("GET", "/", nil)
+
+	for i := 0; i < 6; i++ {
+		if got := lb.SelectTarget(targets, req); got == "http://localhost:8382" {
+			t.Fatalf("SelectTarget() returned drained target on attempt %d", i)
+		}
+	}
+	if lb.Type() != "round-robin" {
+		t.Errorf("Type() = %v, want round-robin", lb.Type())
+	}
+}
+
+func TestDrainAware_AllDrained(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	lb := NewDrainAware(NewRoundRobin(), drainedSet(targets...))
+
+	if got := lb.SelectTarget(targets, httptest.NewRequest("GET", "/", nil)); got != "" {
+		t.Errorf("SelectTarget() = %v, want empty", got)
+	}
+}
+
+func TestDrainAware_WeightedRulesSkipDrained(t *testing.T) {
+	// 加权轮询按路径使用自身规则选择，可能选出已下线目标，需要重新选择
+	wrr := NewWeightedRoundRobin(map[string][]TargetWeight{
+		"/api": {
+			{Target: "http://localhost:8381", Weight: 5},
+			{Target: "http://localhost:8382", Weight: 1},
+			{Target: "http://localhost:8383", Weight: 1},
+		},
+	})
+	lb := NewDrainAware(wrr, drainedSet("http://localhost:8381"))
+	targets := []string{"http://localhost:8381", "http://localhost:8382", "http://localhost:8383"}
+
+	for i := 0; i < 14; i++ {
+		got := lb.SelectTarget(targets, httptest.NewRequest("GET", "/api", nil))
+		if got != "http://localhost:8382" && got != "http://localhost:8383" {
+			t.Fatalf("SelectTarget() = %v on attempt %d, want an enabled target", got, i)
+		}
+	}
+}
+
+func TestDrainAware_SkipsUnreadyTargets(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	factors := map[string]float64{"http://localhost:8382": 0}
+	lb := NewDrainAware(NewRoundRobin(), drainedSet()).WithWarmup(func(target string) float64 {
+		if factor, ok := factors[target]; ok {
+			return factor
+		}
+		return 1
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+
+	for i := 0; i < 6; i++ {
+		if got := lb.SelectTarget(targets, req); got != "http://localhost:8381" {
+			t.Fatalf("SelectTarget() = %v on attempt %d, want the ready target", got, i)
+		}
+	}
+
+	// 通过就绪探测并完成慢启动后参与轮询
+	factors["http://localhost:8382"] = 1
+	selected := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		selected[lb.SelectTarget(targets, req)] = true
+	}
+	if !selected["http://localhost:8382"] {
+		t.Errorf("SelectTarget() never returned the warmed-up target: %v", selected)
+	}
+}
+
+func TestDrainAware_NoReadyTargets(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	lb := NewDrainAware(NewRoundRobin(), drainedSet()).WithWarmup(func(string) float64 { return 0 })
+
+	if got := lb.SelectTarget(targets, httptest.NewRequest("GET", "/", nil)); got != "" {
+		t.Errorf("SelectTarget() = %v, want empty", got)
+	}
+}
+
+func TestDrainAware_SlowStartReceivesPartialTraffic(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	lb := NewDrainAware(NewRoundRobin(), drainedSet()).WithWarmup(func(target string) float64 {
+		if target == "http://localhost:8382" {
+			return 0.2
+		}
+		return 1
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		counts[lb.SelectTarget(targets, req)]++
+	}
+	// 完全预热时约占一半流量，慢启动 20% 时约占 10%
+	if ramping := counts["http://localhost:8382"]; ramping == 0 || ramping > 400 {
+		t.Errorf("slow-start target got %d of 2000 selections, want roughly 200", ramping)
+	}
+}
+
+// activeTargetsLB 报告固定活跃目标的负载均衡器
+type activeTargetsLB struct {
+	LoadBalancer
+	active []string
+}
+
+func (lb activeTargetsLB) ActiveTargets() []string { return lb.active }
+
+func TestDrainAware_ActiveTargets(t *testing.T) {
+	lb := NewDrainAware(activeTargetsLB{LoadBalancer: NewRoundRobin(), active: []string{"http://localhost:8381"}}, drainedSet())
+	if got := lb.ActiveTargets(); len(got) != 1 || got[0] != "http://localhost:8381" {
+		t.Errorf("ActiveTargets() = %v, want the wrapped balancer's targets", got)
+	}
+
+	if got := NewDrainAware(NewRoundRobin(), drainedSet()).ActiveTargets(); got != nil {
+		t.Errorf("ActiveTargets() = %v, want nil for balancers without ActiveTargets", got)
+	}
+}
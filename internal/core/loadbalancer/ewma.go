@@ -0,0 +1,159 @@
+package loadbalancer
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ewmaTracer 为 EWMA 负载均衡模块初始化追踪器
+var ewmaTracer = otel.Tracer("loadbalancer:ewma")
+
+// ewmaFailureLatency 请求失败时计入的最小响应时间，避免快速失败（如连接被拒绝）的目标因响应时间短而被优先选择
+const ewmaFailureLatency = time.Second
+
+// EWMABalancer 按各目标近期响应时间的指数加权移动平均选择目标
+// 每次随机取两个目标并选择平均响应时间较低的一个，既偏向较快的目标，又不会把全部流量集中到同一目标；
+// 另有 probeRate 比例的请求随机选择目标，使变慢后恢复的目标能被重新发现
+type EWMABalancer struct {
+	decay     time.Duration // 衰减时间常数
+	probeRate float64       // 随机探测的请求比例
+
+	mu    sync.Mutex              // 保护 stats
+	stats map[string]*ewmaLatency // 目标到响应时间统计的映射
+	now   func() time.Time        // 当前时间，便于测试替换
+}
+
+// ewmaLatency 单个目标的响应时间统计
+type ewmaLatency struct {
+	value   float64   // 平均响应时间（纳秒）
+	updated time.Time // 最近一次更新时间
+}
+
+// NewEWMABalancer 创建 EWMA 负载均衡器，decay 越小越快反映后端的变化
+func NewEWMABalancer(decay time.Duration, probeRate float64) *EWMABalancer {
+	b := &EWMABalancer{
+		decay:     decay,
+		probeRate: probeRate,
+		stats:     make(map[string]*ewmaLatency),
+		now:       time.Now,
+	}
+	logger.Info("EWMA load balancer initialized",
+		zap.Duration("decay", decay),
+		zap.Float64("probeRate", probeRate))
+	return b
+}
+
+func (b *EWMABalancer) Type() string {
+	return "ewma"
+}
+
+// SelectTarget 选择平均响应时间较低的目标，尚无统计的目标视为最快，以便尽快获得样本
+func (b *EWMABalancer) SelectTarget(targets []string, r *http.Request) string {
+	_, span := ewmaTracer.Start(r.Context(), "LoadBalancer.Select",
+		trace.WithAttributes(attribute.String("type", b.Type())),
+		trace.WithAttributes(attribute.Int("target_count", len(targets))))
+	defer span.End()
+
+	if len(targets) == 0 {
+		logger.Warn("No targets available for EWMA selection")
+		span.SetAttributes(attribute.String("result", "no targets"))
+		return ""
+	}
+	if len(targets) == 1 {
+		span.SetAttributes(attribute.String("selected_target", targets[0]))
+		return targets[0]
+	}
+
+	if rand.Float64() < b.probeRate {
+		target := targets[rand.Intn(len(targets))]
+		span.SetAttributes(attribute.String("selected_target", target), attribute.Bool("probe", true))
+		logger.Debug("Selected probe target using EWMA", zap.String("target", target))
+		return target
+	}
+
+	// 随机取两个不同的目标比较
+	i := rand.Intn(len(targets))
+	j := rand.Intn(len(targets) - 1)
+	if j >= i {
+		j++
+	}
+	b.mu.Lock()
+	first, second := b.latency(targets[i]), b.latency(targets[j])
+	b.mu.Unlock()
+	target, latency := targets[i], first
+	if second < first {
+		target, latency = targets[j], second
+	}
+
+	span.SetAttributes(attribute.String("selected_target", target))
+	logger.Debug("Selected target using EWMA",
+		zap.String("target", target),
+		zap.Duration("latency", time.Duration(latency)))
+	return target
+}
+
+// latency 返回目标的平均响应时间，没有统计时返回 0，调用方需持有 mu
+func (b *EWMABalancer) latency(target string) float64 {
+	if stat, ok := b.stats[target]; ok {
+		return stat.value
+	}
+	return 0
+}
+
+// ObserveLatency 记录一次请求的响应时间
+// 样本高于当前平均值时直接取样本值，使变慢的目标立即被避开；低于平均值时按距上次更新的时间衰减，逐步回落。
+// 失败的请求至少按 ewmaFailureLatency 与当前平均值的两倍计入
+func (b *EWMABalancer) ObserveLatency(target string, d time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	sample := float64(d)
+	stat, ok := b.stats[target]
+	if failed {
+		sample = math.Max(sample, float64(ewmaFailureLatency))
+		if ok {
+			sample = math.Max(sample, 2*stat.value)
+		}
+	}
+	if !ok {
+		b.stats[target] = &ewmaLatency{value: sample, updated: now}
+		return
+	}
+
+	if sample > stat.value {
+		stat.value = sample
+	} else {
+		w := math.Exp(-float64(now.Sub(stat.updated)) / float64(b.decay))
+		stat.value = stat.value*w + sample*(1-w)
+	}
+	stat.updated = now
+}
+
+// UpdateTargets 清理已不在配置中的目标的统计，保留的目标沿用原有统计
+func (b *EWMABalancer) UpdateTargets(cfg *config.Config) {
+	targets := make(map[string]struct{})
+	for _, rules := range cfg.Routing.Rules {
+		for _, rule := range rules {
+			targets[rule.Target] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for target := range b.stats {
+		if _, ok := targets[target]; !ok {
+			delete(b.stats, target)
+		}
+	}
+}
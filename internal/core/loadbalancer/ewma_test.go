@@ -0,0 +1,107 @@
+package loadbalancer
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+)
+
+func TestEWMABalancer_PrefersFasterTarget(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	lb := NewEWMABalancer(10*time.Second, 0)
+	lb.ObserveLatency(targets[0], 200*time.Millisecond, false)
+	lb.ObserveLatency(targets[1], 20*time.Millisecond, false)
+	req := httptest.NewRequest("GET", "/", nil)
+
+	for i := 0; i < 20; i++ {
+		if got := lb.SelectTarget(targets, req); got != targets[1] {
+			t.Fatalf("SelectTarget() = %v on attempt %d, want %v", got, i, targets[1])
+		}
+	}
+	if lb.Type() != "ewma" {
+		t.Errorf("Type() = %v, want ewma", lb.Type())
+	}
+}
+
+func TestEWMABalancer_UntriedTargetSelectedFirst(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	lb := NewEWMABalancer(10*time.Second, 0)
+	lb.ObserveLatency(targets[0], 5*time.Millisecond, false)
+
+	if got := lb.SelectTarget(targets, httptest.NewRequest("GET", "/", nil)); got != targets[1] {
+		t.Errorf("SelectTarget() = %v, want untried target %v", got, targets[1])
+	}
+}
+
+func TestEWMABalancer_ProbesSlowerTargets(t *testing.T) {
+	targets := []string{"http://localhost:8381", "http://localhost:8382"}
+	lb := NewEWMABalancer(10*time.Second, 1)
+	lb.ObserveLatency(targets[0], time.Second, false)
+	lb.ObserveLatency(targets[1], time.Millisecond, false)
+	req := httptest.NewRequest("GET", "/", nil)
+
+	counts := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		counts[lb.SelectTarget(targets, req)]++
+	}
+	if counts[targets[0]] == 0 {
+		t.Errorf("slower target was never probed: %v", counts)
+	}
+}
+
+func TestEWMABalancer_ObserveLatency(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	lb := NewEWMABalancer(10*time.Second, 0)
+	lb.now = func() time.Time { return now }
+	target := "http://localhost:8381"
+
+	lb.ObserveLatency(target, 100*time.Millisecond, false)
+	// 更慢的样本立即生效
+	lb.ObserveLatency(target, 300*time.Millisecond, false)
+	if got := time.Duration(lb.latency(target)); got != 300*time.Millisecond {
+		t.Errorf("latency after slower sample = %v, want 300ms", got)
+	}
+
+	// 更快的样本按经过的时间衰减：经过一个时间常数后约保留 1/e 的旧值
+	now = now.Add(10 * time.Second)
+	lb.ObserveLatency(target, 0, false)
+	if got := time.Duration(lb.latency(target)); got < 100*time.Millisecond || got > 120*time.Millisecond {
+		t.Errorf("latency after decay = %v, want about 110ms", got)
+	}
+
+	// 快速失败仍按较高的响应时间计入
+	failing := "http://localhost:8382"
+	lb.ObserveLatency(failing, time.Millisecond, true)
+	if got := time.Duration(lb.latency(failing)); got != ewmaFailureLatency {
+		t.Errorf("latency after failure = %v, want %v", got, ewmaFailureLatency)
+	}
+}
+
+func TestEWMABalancer_ObserveThroughDrainAware(t *testing.T) {
+	inner := NewEWMABalancer(10*time.Second, 0)
+	ObserveLatency(NewDrainAware(inner, drainedSet()), "http://localhost:8381", 50*time.Millisecond, false)
+	if got := time.Duration(inner.latency("http://localhost:8381")); got != 50*time.Millisecond {
+		t.Errorf("latency = %v, want 50ms", got)
+	}
+	// 不关心响应时间的负载均衡器直接忽略
+	ObserveLatency(NewRoundRobin(), "http://localhost:8381", time.Millisecond, false)
+}
+
+func TestEWMABalancer_UpdateTargetsPrunesRemoved(t *testing.T) {
+	lb := NewEWMABalancer(10*time.Second, 0)
+	lb.ObserveLatency("http://localhost:8381", 10*time.Millisecond, false)
+	lb.ObserveLatency("http://localhost:8382", 10*time.Millisecond, false)
+
+	cfg := &config.Config{Routing: config.Routing{Rules: map[string]config.RoutingRules{
+		"/api": {{Target: "http://localhost:8381"}},
+	}}}
+	lb.UpdateTargets(cfg)
+	if _, ok := lb.stats["http://localhost:8382"]; ok {
+		t.Error("stats of removed target were kept")
+	}
+	if _, ok := lb.stats["http://localhost:8381"]; !ok {
+		t.Error("stats of remaining target were dropped")
+	}
+}
@@ -9,14 +9,32 @@ import (
 func NewLoadBalancer(algorithm string, cfg *config.Config) (LoadBalancer, error) {
 	switch algorithm {
 	case "round-robin", "round_robin":
+		if cfg.Routing.SharedCounter {
+			return NewSharedRoundRobin(NewSharedCounter()), nil
+		}
 		return NewRoundRobin(), nil
 	case "ketama":
-		return NewKetama(160), nil
+		virtualNodes := cfg.Routing.Ketama.VirtualNodes
+		if virtualNodes <= 0 {
+			virtualNodes = config.DefaultKetamaVirtualNodes
+		}
+		k := NewKetamaWithAffinity(virtualNodes, cfg.Routing.StickyTTL)
+		source, name, err := config.ParseHashKey(cfg.Routing.HashKey)
+		if err != nil {
+			return nil, err
+		}
+		k.SetHashKey(source, name)
+		return k, nil
 	case "consul":
 		return NewConsulBalancer(cfg.Consul.Addr)
 	case "weighted-round-robin", "weighted_round_robin":
 		rules := buildWeightedRoundRobinRules(cfg)
+		if cfg.Routing.SharedCounter {
+			return NewSharedWeightedRoundRobin(rules, NewSharedCounter()), nil
+		}
 		return NewWeightedRoundRobin(rules), nil
+	case "ewma":
+		return NewEWMABalancer(cfg.Routing.EWMA.Decay, cfg.Routing.EWMA.ProbeRate), nil
 	default:
 		return nil, fmt.Errorf("unknown load balancer algorithm: %s", algorithm)
 	}
@@ -28,8 +46,9 @@ func buildWeightedRoundRobinRules(cfg *config.Config) map[string][]TargetWeight
 		rules[path] = make([]TargetWeight, len(targetRules))
 		for i, rule := range targetRules {
 			rules[path][i] = TargetWeight{
-				Target: rule.Target,
-				Weight: rule.Weight,
+				Target:   rule.Target,
+				Weight:   rule.Weight,
+				MinShare: rule.MinShare,
 			}
 		}
 	}
@@ -1,9 +1,52 @@
 package loadbalancer
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+)
 
 // LoadBalancer 定义负载均衡接口
 type LoadBalancer interface {
 	SelectTarget(targets []string, r *http.Request) string
 	Type() string
 }
+
+// ActiveTargetsReporter 可选接口，由能够报告当前活跃目标的负载均衡器实现
+type ActiveTargetsReporter interface {
+	ActiveTargets() []string
+}
+
+// TargetUpdater 可选接口，由能够在配置热更新时就地更新目标的负载均衡器实现，未变化路由的选择状态得以保留
+type TargetUpdater interface {
+	UpdateTargets(cfg *config.Config)
+}
+
+// LatencyObserver 可选接口，由根据请求响应时间调整选择的负载均衡器实现
+type LatencyObserver interface {
+	ObserveLatency(target string, d time.Duration, failed bool)
+}
+
+// ObserveLatency 将一次请求的响应时间交给负载均衡器，负载均衡器不关心响应时间时忽略
+func ObserveLatency(lb LoadBalancer, target string, d time.Duration, failed bool) {
+	if drain, ok := lb.(*DrainAware); ok {
+		lb = drain.LoadBalancer
+	}
+	if observer, ok := lb.(LatencyObserver); ok {
+		observer.ObserveLatency(target, d, failed)
+	}
+}
+
+// UpdateTargets 就地更新负载均衡器的目标，负载均衡器不支持就地更新时返回 false，调用方需重新创建
+func UpdateTargets(lb LoadBalancer, cfg *config.Config) bool {
+	if d, ok := lb.(*DrainAware); ok {
+		lb = d.LoadBalancer
+	}
+	updater, ok := lb.(TargetUpdater)
+	if !ok {
+		return false
+	}
+	updater.UpdateTargets(cfg)
+	return true
+}
@@ -3,11 +3,15 @@ package loadbalancer
 import (
 	"crypto/md5"
 	"encoding/binary"
+	"net"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -25,8 +29,25 @@ type Ketama struct {
 	hashMap  map[uint32]string // 哈希值到节点的映射
 	replicas int               // 每个物理节点的虚拟节点数
 	mu       sync.RWMutex      // 保护哈希环的并发访问
+
+	affinityTTL time.Duration             // 客户端亲和性有效期，0 表示仅依赖哈希环
+	affinity    map[string]*affinityEntry // 哈希键到已选目标的亲和性记录
+	affinityMu  sync.Mutex                // 保护亲和性记录
+	nextSweep   int                       // 亲和性记录数达到该值时清理过期记录
+	now         func() time.Time          // 当前时间，便于测试替换
+
+	keySource hashKeySource // 哈希键的来源
+}
+
+// affinityEntry 客户端亲和性记录，每次命中都会刷新过期时间
+type affinityEntry struct {
+	target    string
+	expiresAt time.Time
 }
 
+// minAffinitySweep 触发亲和性记录清理的最小记录数
+const minAffinitySweep = 1024
+
 // NewKetama 创建并初始化 Ketama 负载均衡器
 func NewKetama(replicas int) *Ketama {
 	k := &Ketama{
@@ -38,11 +59,38 @@ func NewKetama(replicas int) *Ketama {
 	return k
 }
 
+// SetHashKey 设置哈希键的来源，source 与 name 由 config.ParseHashKey 解析得到
+func (k *Ketama) SetHashKey(source, name string) {
+	k.keySource = hashKeySource{source: source, name: name}
+	logger.Info("Ketama hash key configured",
+		zap.String("source", source),
+		zap.String("name", name))
+}
+
+// NewKetamaWithAffinity 创建带客户端亲和性的 Ketama 负载均衡器
+// 客户端在 ttl 内持续访问时固定到首次选中的目标，即使哈希环因扩容发生变化；
+// 空闲超过 ttl 后重新按当前哈希环选择，从而逐步分摊到新增节点
+func NewKetamaWithAffinity(replicas int, ttl time.Duration) *Ketama {
+	k := NewKetama(replicas)
+	if ttl > 0 {
+		k.affinityTTL = ttl
+		k.affinity = make(map[string]*affinityEntry)
+		k.nextSweep = minAffinitySweep
+		k.now = time.Now
+		logger.Info("Ketama client affinity enabled", zap.Duration("ttl", ttl))
+	}
+	return k
+}
+
+// UpdateTargets 对于 Ketama 是空操作，哈希环随每次请求的目标列表重建，保留亲和性记录使客户端在热更新后仍访问原目标
+func (k *Ketama) UpdateTargets(cfg *config.Config) {
+}
+
 func (cb *Ketama) Type() string {
 	return "ketama"
 }
 
-// SelectTarget 根据客户端 IP 使用一致性哈希选择目标节点
+// SelectTarget 根据哈希键使用一致性哈希选择目标节点，默认以客户端 IP 为键
 func (k *Ketama) SelectTarget(targets []string, req *http.Request) string {
 	// 开始追踪负载均衡选择过程
 	_, span := kTracer.Start(req.Context(), "LoadBalancer.Select",
@@ -82,17 +130,113 @@ func (k *Ketama) SelectTarget(targets []string, req *http.Request) string {
 		return target
 	}
 
-	// 使用客户端 IP 作为哈希键进行一致性选择
-	key := k.hashKey(req.RemoteAddr)
+	clientKey := k.keySource.key(req)
+	if target, ok := k.pinnedTarget(clientKey, targets); ok {
+		span.SetAttributes(attribute.String("selected_target", target), attribute.Bool("affinity", true))
+		logger.Debug("Selected target using Ketama client affinity",
+			zap.String("clientKey", clientKey),
+			zap.String("target", target))
+		return target
+	}
+
+	// 按哈希键进行一致性选择
+	key := k.hashKey(clientKey)
 	index := k.findNearest(key)
 	target := k.hashMap[k.hashRing[index]]
+	k.pin(clientKey, target)
 	span.SetAttributes(attribute.String("selected_target", target))
 	logger.Debug("Selected target using Ketama consistent hashing",
-		zap.String("clientIP", req.RemoteAddr),
+		zap.String("clientKey", clientKey),
 		zap.String("target", target))
 	return target
 }
 
+// hashKeySource 哈希键的来源，零值表示使用对端 IP
+type hashKeySource struct {
+	source string // config.HashKeyRemote 等
+	name   string // 请求头或 Cookie 的名称
+}
+
+// key 从请求中提取哈希键，配置的来源缺失时回退到对端 IP
+func (s hashKeySource) key(req *http.Request) string {
+	switch s.source {
+	case config.HashKeyHeader:
+		if value := req.Header.Get(s.name); value != "" {
+			return value
+		}
+	case config.HashKeyCookie:
+		if cookie, err := req.Cookie(s.name); err == nil && cookie.Value != "" {
+			return cookie.Value
+		}
+	case config.HashKeyForwarded:
+		// 最左侧的地址是最初的客户端
+		first, _, _ := strings.Cut(req.Header.Get("X-Forwarded-For"), ",")
+		if first = strings.TrimSpace(first); first != "" {
+			return first
+		}
+	}
+	return clientIPFromAddr(req.RemoteAddr)
+}
+
+// pinnedTarget 返回哈希键在有效期内固定的目标并刷新过期时间，目标已下线或记录过期时返回 false
+func (k *Ketama) pinnedTarget(clientKey string, targets []string) (string, bool) {
+	if k.affinityTTL <= 0 {
+		return "", false
+	}
+	k.affinityMu.Lock()
+	defer k.affinityMu.Unlock()
+
+	entry, ok := k.affinity[clientKey]
+	if !ok {
+		return "", false
+	}
+	now := k.now()
+	if now.After(entry.expiresAt) || !containsTarget(targets, entry.target) {
+		delete(k.affinity, clientKey)
+		return "", false
+	}
+	entry.expiresAt = now.Add(k.affinityTTL)
+	return entry.target, true
+}
+
+// pin 记录哈希键选中的目标
+func (k *Ketama) pin(clientKey, target string) {
+	if k.affinityTTL <= 0 {
+		return
+	}
+	k.affinityMu.Lock()
+	defer k.affinityMu.Unlock()
+
+	now := k.now()
+	k.affinity[clientKey] = &affinityEntry{target: target, expiresAt: now.Add(k.affinityTTL)}
+	if len(k.affinity) >= k.nextSweep {
+		for key, entry := range k.affinity {
+			if now.After(entry.expiresAt) {
+				delete(k.affinity, key)
+			}
+		}
+		k.nextSweep = max(minAffinitySweep, 2*len(k.affinity))
+	}
+}
+
+// clientIPFromAddr 从 RemoteAddr 中提取客户端 IP，去掉端口以免同一客户端的不同连接被分散
+func clientIPFromAddr(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// containsTarget 判断目标是否在列表中
+func containsTarget(targets []string, target string) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
 // buildRing 根据目标列表构建 Ketama 哈希环
 func (k *Ketama) buildRing(targets []string) {
 	k.nodes = targets
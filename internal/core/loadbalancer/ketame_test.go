@@ -1,9 +1,14 @@
 package loadbalancer
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
 )
 
 func TestKetama_SelectTarget(t *testing.T) {
@@ -144,3 +149,158 @@ func TestKetama_ZeroReplicas(t *testing.T) {
 		t.Errorf("Expected first target %v with zero replicas, got %v", targets[0], got)
 	}
 }
+
+// newAffinityTestKetama 创建使用可控时钟的带亲和性 Ketama
+func newAffinityTestKetama(ttl time.Duration) (*Ketama, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	k := NewKetamaWithAffinity(160, ttl)
+	k.now = func() time.Time { return now }
+	return k, &now
+}
+
+// findMovingClient 找到一个在扩容后哈希结果会变化的客户端地址
+func findMovingClient(t *testing.T, before, after []string) string {
+	for i := 1; i < 255; i++ {
+		addr := fmt.Sprintf("10.0.0.%d:40000", i)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		if NewKetama(160).SelectTarget(before, req) != NewKetama(160).SelectTarget(after, req) {
+			return addr
+		}
+	}
+	t.Fatal("no client found whose target changes after scaling out")
+	return ""
+}
+
+func TestKetama_AffinityPinnedWithinTTL(t *testing.T) {
+	before := []string{"http://localhost:8081", "http://localhost:8082"}
+	after := append(append([]string{}, before...), "http://localhost:8083")
+	addr := findMovingClient(t, before, after)
+
+	k, now := newAffinityTestKetama(time.Minute)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = addr
+	pinned := k.SelectTarget(before, req)
+
+	// 扩容后持续访问，每次访问都刷新有效期，客户端保持固定
+	for i := 0; i < 5; i++ {
+		*now = now.Add(50 * time.Second)
+		if got := k.SelectTarget(after, req); got != pinned {
+			t.Fatalf("client moved within TTL: got %v, want %v", got, pinned)
+		}
+	}
+}
+
+func TestKetama_AffinityExpiresAfterIdle(t *testing.T) {
+	before := []string{"http://localhost:8081", "http://localhost:8082"}
+	after := append(append([]string{}, before...), "http://localhost:8083")
+	addr := findMovingClient(t, before, after)
+
+	k, now := newAffinityTestKetama(time.Minute)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = addr
+	pinned := k.SelectTarget(before, req)
+
+	// 空闲超过有效期后，按扩容后的哈希环重新选择
+	*now = now.Add(2 * time.Minute)
+	want := NewKetama(160).SelectTarget(after, req)
+	if got := k.SelectTarget(after, req); got != want || got == pinned {
+		t.Fatalf("client not rebalanced after TTL: got %v, want %v (pinned %v)", got, want, pinned)
+	}
+}
+
+func TestKetama_AffinityDropsRemovedTarget(t *testing.T) {
+	targets := []string{"http://localhost:8081", "http://localhost:8082"}
+	k, _ := newAffinityTestKetama(time.Minute)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:40000"
+	pinned := k.SelectTarget(targets, req)
+
+	var remaining []string
+	for _, target := range targets {
+		if target != pinned {
+			remaining = append(remaining, target)
+		}
+	}
+	if got := k.SelectTarget(remaining, req); got != remaining[0] {
+		t.Fatalf("client still pinned to removed target: got %v, want %v", got, remaining[0])
+	}
+}
+
+func TestKetama_HashKeySources(t *testing.T) {
+	logger.InitTestLogger()
+	targets := []string{"http://localhost:8081", "http://localhost:8082", "http://localhost:8083"}
+	// 按对端地址选择的结果，用于对照
+	byAddr := func(addr string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		return NewKetama(160).SelectTarget(targets, req)
+	}
+
+	tests := []struct {
+		name    string
+		hashKey string
+		setKey  func(req *http.Request, key string)
+	}{
+		{name: "header", hashKey: "header:X-Session-ID", setKey: func(req *http.Request, key string) { req.Header.Set("X-Session-ID", key) }},
+		{name: "cookie", hashKey: "cookie:session", setKey: func(req *http.Request, key string) { req.AddCookie(&http.Cookie{Name: "session", Value: key}) }},
+		{name: "forwarded", hashKey: "forwarded", setKey: func(req *http.Request, key string) { req.Header.Set("X-Forwarded-For", key+", 10.0.0.1") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, name, err := config.ParseHashKey(tt.hashKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k := NewKetama(160)
+			k.SetHashKey(source, name)
+
+			// 所有请求都来自同一个负载均衡器，按配置的键仍能分散到不同目标
+			seen := make(map[string]bool)
+			for i := 1; i <= 50; i++ {
+				key := fmt.Sprintf("192.0.2.%d", i)
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "10.0.0.1:40000"
+				tt.setKey(req, key)
+				got := k.SelectTarget(targets, req)
+				if want := byAddr(key); got != want {
+					t.Fatalf("key %s selected %s, want %s", key, got, want)
+				}
+				seen[got] = true
+			}
+			if len(seen) < 2 {
+				t.Errorf("all clients behind one load balancer collapsed to %v", seen)
+			}
+
+			// 配置的来源缺失时回退到对端地址
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "198.51.100.7:40000"
+			if got, want := k.SelectTarget(targets, req), byAddr("198.51.100.7:1"); got != want {
+				t.Errorf("fallback selected %s, want %s (RemoteAddr)", got, want)
+			}
+		})
+	}
+}
+
+func TestNewLoadBalancer_KetamaVirtualNodes(t *testing.T) {
+	logger.InitTestLogger()
+	targets := []string{"http://localhost:8081", "http://localhost:8082"}
+	for _, tc := range []struct {
+		virtualNodes int
+		want         int
+	}{
+		{virtualNodes: 50, want: 50},
+		{virtualNodes: 0, want: config.DefaultKetamaVirtualNodes}, // 未经配置加载时使用默认值
+	} {
+		cfg := &config.Config{Routing: config.Routing{Ketama: config.Ketama{VirtualNodes: tc.virtualNodes}}}
+		lb, err := NewLoadBalancer("ketama", cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := lb.(*Ketama)
+		k.SelectTarget(targets, httptest.NewRequest("GET", "/", nil))
+		if len(k.hashRing) != tc.want*len(targets) {
+			t.Errorf("virtualNodes %d: ring has %d slots, want %d", tc.virtualNodes, len(k.hashRing), tc.want*len(targets))
+		}
+	}
+}
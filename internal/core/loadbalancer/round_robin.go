@@ -1,6 +1,7 @@
 package loadbalancer
 
 import (
+	"context"
 	"net/http"
 	"sync"
 
@@ -17,8 +18,9 @@ var rrTracer = otel.Tracer("loadbalancer:round-robin")
 
 // RoundRobin 实现简单的轮询负载均衡算法
 type RoundRobin struct {
-	next uint32     // 跟踪下一个目标索引
-	mu   sync.Mutex // 确保索引更新的线程安全
+	next   uint32         // 跟踪下一个目标索引
+	mu     sync.Mutex     // 确保索引更新的线程安全
+	shared *SharedCounter // 多副本共享的计数器，为 nil 时只使用本地计数
 }
 
 // NewRoundRobin 创建并初始化 RoundRobin 负载均衡器实例
@@ -28,6 +30,13 @@ func NewRoundRobin() LoadBalancer {
 	return rr
 }
 
+// NewSharedRoundRobin 创建使用多副本共享计数器的 RoundRobin 负载均衡器，共享计数器不可用时回退到本地计数
+func NewSharedRoundRobin(shared *SharedCounter) LoadBalancer {
+	rr := &RoundRobin{shared: shared}
+	logger.Info("RoundRobin load balancer initialized with shared counter")
+	return rr
+}
+
 func (rr *RoundRobin) Type() string {
 	return "round-robin"
 }
@@ -46,13 +55,9 @@ func (rr *RoundRobin) SelectTarget(targets []string, r *http.Request) string {
 		return ""
 	}
 
-	rr.mu.Lock()
-	defer rr.mu.Unlock()
-
 	// 选择下一个目标并递增计数器
-	index := rr.next % uint32(len(targets))
+	index := rr.nextIndex(r.Context(), len(targets))
 	target := targets[index]
-	rr.next++
 
 	// 在追踪和日志中记录所选目标
 	span.SetAttributes(attribute.String("selected_target", target))
@@ -62,6 +67,18 @@ func (rr *RoundRobin) SelectTarget(targets []string, r *http.Request) string {
 	return target
 }
 
+// nextIndex 返回本次选择的目标索引，启用共享计数器时优先使用共享计数
+func (rr *RoundRobin) nextIndex(ctx context.Context, n int) uint32 {
+	if count, ok := rr.shared.next(ctx, rrCounterKey); ok {
+		return uint32((count - 1) % uint64(n))
+	}
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	index := rr.next % uint32(n)
+	rr.next++
+	return index
+}
+
 // UpdateTargets 对于 RoundRobin 是空操作，因其依赖运行时目标列表
 func (rr *RoundRobin) UpdateTargets(cfg *config.Config) {
 	// 无需配置更新，目标由每次请求提供
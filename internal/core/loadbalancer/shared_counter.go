@@ -0,0 +1,60 @@
+package loadbalancer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	sharedCounterPrefix  = "mg:lb:counter:"      // Redis 中共享选择计数器的键前缀
+	sharedCounterTimeout = 50 * time.Millisecond // 单次读取共享计数器的超时，避免 Redis 变慢拖慢路由
+	sharedCounterBackoff = 5 * time.Second       // 共享计数器失败后改用本地计数的时长
+	rrCounterKey         = sharedCounterPrefix + "round-robin"
+	wrrCounterKeyPrefix  = sharedCounterPrefix + "weighted-round-robin:"
+)
+
+// SharedCounter 多个网关副本通过缓存存储共享的选择计数器，使各副本的轮询在整体上接近全局均衡
+// Redis 不可用时返回失败，由调用方回退到本地计数，并在 sharedCounterBackoff 内不再访问 Redis
+type SharedCounter struct {
+	mu         sync.Mutex
+	retryAfter time.Time        // 在此之前不访问 Redis
+	now        func() time.Time // 当前时间，测试时可替换
+}
+
+// NewSharedCounter 创建使用全局缓存存储的共享计数器，只有 redis 后端能在副本间共享计数
+func NewSharedCounter() *SharedCounter {
+	return &SharedCounter{now: time.Now}
+}
+
+// next 递增 key 对应的共享计数并返回递增后的值（从 1 开始），计数器为 nil 或 Redis 不可用时 ok 为 false
+func (s *SharedCounter) next(ctx context.Context, key string) (count uint64, ok bool) {
+	if s == nil || cache.Default == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	skip := s.now().Before(s.retryAfter)
+	s.mu.Unlock()
+	if skip {
+		return 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sharedCounterTimeout)
+	defer cancel()
+	value, err := cache.Default.Incr(ctx, key)
+	if err != nil {
+		s.mu.Lock()
+		s.retryAfter = s.now().Add(sharedCounterBackoff)
+		s.mu.Unlock()
+		logger.Warn("Shared load balancer counter unavailable, falling back to local counter",
+			zap.String("key", key),
+			zap.Duration("backoff", sharedCounterBackoff),
+			zap.Error(err))
+		return 0, false
+	}
+	return uint64(value), true
+}
@@ -0,0 +1,89 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+)
+
+// useTestStore 将全局缓存存储替换为内存存储，同一进程内的多个计数器共享它，测试结束后恢复
+func useTestStore(t *testing.T) cache.Store {
+	logger.InitTestLogger()
+	store := cache.InitTestStore()
+	t.Cleanup(func() { cache.Default = nil })
+	return store
+}
+
+func TestSharedRoundRobin_TwoReplicasShareCounter(t *testing.T) {
+	useTestStore(t)
+	targets := []string{"http://a", "http://b"}
+	replicas := []LoadBalancer{NewSharedRoundRobin(NewSharedCounter()), NewSharedRoundRobin(NewSharedCounter())}
+
+	// 两个副本交替接收请求：各自独立轮询时合并结果为 a a b b，共享计数时为 a b a b
+	var got []string
+	for i := 1; i <= 8; i++ {
+		got = append(got, replicas[(i-1)%2].SelectTarget(targets, httptest.NewRequest("GET", "/", nil)))
+	}
+	for i, target := range got {
+		if want := targets[i%2]; target != want {
+			t.Fatalf("selection %d = %s, want %s (all: %v)", i, target, want, got)
+		}
+	}
+}
+
+func TestSharedWeightedRoundRobin_TwoReplicasShareCounter(t *testing.T) {
+	useTestStore(t)
+	rules := map[string][]TargetWeight{
+		"/test": {
+			{Target: "http://a", Weight: 1},
+			{Target: "http://b", Weight: 3},
+		},
+	}
+	targets := []string{"http://a", "http://b"}
+	replicas := []*WeightedRoundRobin{
+		NewSharedWeightedRoundRobin(rules, NewSharedCounter()),
+		NewSharedWeightedRoundRobin(rules, NewSharedCounter()),
+	}
+
+	counts := make(map[string]int)
+	for i := 1; i <= 40; i++ {
+		counts[replicas[(i-1)%2].SelectTarget(targets, httptest.NewRequest("GET", "/test", nil))]++
+	}
+	if counts["http://a"] != 10 || counts["http://b"] != 30 {
+		t.Errorf("combined distribution = %v, want a:10 b:30", counts)
+	}
+}
+
+func TestSharedCounter_FallsBackToLocalWhenRedisDown(t *testing.T) {
+	store := useTestStore(t)
+	cache.Default = cache.NewErrorStore(errors.New("connection refused"))
+	now := time.Now()
+	counter := NewSharedCounter()
+	counter.now = func() time.Time { return now }
+	rr := NewSharedRoundRobin(counter)
+	targets := []string{"http://a", "http://b"}
+	req := httptest.NewRequest("GET", "/", nil)
+
+	if got := rr.SelectTarget(targets, req); got != "http://a" {
+		t.Errorf("first local selection = %s, want http://a", got)
+	}
+	// 退避期内不访问 Redis，继续本地轮询
+	if got := rr.SelectTarget(targets, req); got != "http://b" {
+		t.Errorf("second local selection = %s, want http://b", got)
+	}
+
+	// 退避期结束后重新使用共享计数，其他副本已递增过一次
+	now = now.Add(sharedCounterBackoff)
+	cache.Default = store
+	if _, err := store.Incr(context.Background(), rrCounterKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := rr.SelectTarget(targets, req); got != "http://b" {
+		t.Errorf("shared selection after backoff = %s, want http://b", got)
+	}
+}
@@ -1,9 +1,13 @@
 package loadbalancer
 
 import (
+	"context"
 	"net/http"
+	"slices"
 	"sync"
 
+	"github.com/penwyp/mini-gateway/config"
+
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -16,8 +20,9 @@ var wrrTracer = otel.Tracer("loadbalancer:weighted-round-robin")
 
 // TargetWeight 定义目标及其关联权重
 type TargetWeight struct {
-	Target string //目标地址
-	Weight int    //权重值
+	Target   string //目标地址
+	Weight   int    //权重值
+	MinShare int    // 最低流量百分比（0-100），按权重计算的占比低于该值时按该比例分配
 }
 
 func (cb *WeightedRoundRobin) Type() string {
@@ -26,9 +31,13 @@ func (cb *WeightedRoundRobin) Type() string {
 
 // WeightedRoundRobin 实现加权轮询负载均衡算法
 type WeightedRoundRobin struct {
-	rules  map[string][]TargetWeight // 预定义的路径到加权目标的映射规则
-	states map[string]*wrrState      // 每个路径的运行时状态
-	mu     sync.Mutex                // 确保状态更新的线程安全
+	rules   map[string][]TargetWeight // 预定义的路径到加权目标的映射规则
+	states  map[string]*wrrState      // 每个路径的运行时状态
+	rulesMu sync.RWMutex              // 保护 rules 和 states，配置热更新时替换
+	mu      sync.Mutex                // 确保状态更新的线程安全
+	shared  *SharedCounter            // 多副本共享的计数器，为 nil 时只使用本地计数
+
+	fallbackCount uint64 // 回退到简单轮询时的计数器，与各路径的加权计数分开，由 mu 保护
 }
 
 // wrrState 保存加权轮询选择的状态
@@ -48,31 +57,157 @@ func NewWeightedRoundRobin(rules map[string][]TargetWeight) *WeightedRoundRobin
 
 	// 根据预定义规则初始化状态
 	for path, targetRules := range rules {
-		targets := make([]string, len(targetRules))
-		weights := make([]int, len(targetRules))
-		totalWeight := 0
-		for i, rule := range targetRules {
-			targets[i] = rule.Target
-			weights[i] = rule.Weight
-			totalWeight += rule.Weight
-		}
-		wrr.states[path] = &wrrState{
-			targets:      targets,
-			weights:      weights,
-			totalWeight:  totalWeight,
-			currentCount: -1, // 从 -1 开始，第一次递增后选择索引 0
-		}
+		wrr.states[path] = newWRRState(targetRules)
 	}
 	logger.Info("WeightedRoundRobin load balancer initialized",
 		zap.Int("ruleCount", len(rules)))
 	return wrr
 }
 
-// SelectTarget 根据加权轮询选择目标，或回退到简单轮询
-func (wrr *WeightedRoundRobin) SelectTarget(targets []string, req *http.Request) string {
+// newWRRState 根据路径的加权目标创建初始状态
+func newWRRState(targetRules []TargetWeight) *wrrState {
+	targets := make([]string, len(targetRules))
+	for i, rule := range targetRules {
+		targets[i] = rule.Target
+	}
+	weights := effectiveWeights(targetRules)
+	totalWeight := 0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	return &wrrState{
+		targets:      targets,
+		weights:      weights,
+		totalWeight:  totalWeight,
+		currentCount: -1, // 从 -1 开始，第一次递增后选择索引 0
+	}
+}
+
+// UpdateTargets 按新配置更新加权规则：规则未变化的路径保留原有状态和计数，新增或变化的路径重新开始计数，已删除的路径被清理
+func (wrr *WeightedRoundRobin) UpdateTargets(cfg *config.Config) {
+	rules := buildWeightedRoundRobinRules(cfg)
+
+	wrr.rulesMu.Lock()
+	defer wrr.rulesMu.Unlock()
+	states := make(map[string]*wrrState, len(rules))
+	kept := 0
+	for path, targetRules := range rules {
+		if state, ok := wrr.states[path]; ok && slices.Equal(wrr.rules[path], targetRules) {
+			states[path] = state
+			kept++
+			continue
+		}
+		states[path] = newWRRState(targetRules)
+	}
+	wrr.rules, wrr.states = rules, states
+	logger.Info("WeightedRoundRobin rules updated",
+		zap.Int("ruleCount", len(rules)),
+		zap.Int("unchanged", kept))
+}
+
+// state 返回路径的运行时状态，没有预定义规则时返回 nil
+func (wrr *WeightedRoundRobin) state(path string) (*wrrState, bool) {
+	wrr.rulesMu.RLock()
+	defer wrr.rulesMu.RUnlock()
+	state, ok := wrr.states[path]
+	return state, ok
+}
+
+// NewSharedWeightedRoundRobin 创建使用多副本共享计数器的 WeightedRoundRobin 实例，共享计数器不可用时回退到本地计数
+func NewSharedWeightedRoundRobin(rules map[string][]TargetWeight, shared *SharedCounter) *WeightedRoundRobin {
+	wrr := NewWeightedRoundRobin(rules)
+	wrr.shared = shared
+	return wrr
+}
+
+// effectiveWeights 根据权重和最低流量占比计算实际用于轮询的权重
+// 按权重计算的占比低于 MinShare 的目标预留 MinShare 的比例，其余比例由剩余目标按权重分配；
+// 预留会压低剩余目标的占比，因此反复检查直到没有新的目标需要预留
+func effectiveWeights(rules []TargetWeight) []int {
+	reserved := make([]bool, len(rules))
+	reservedShare := 0 // 已预留的百分比之和
+	for {
+		freeWeight := 0 // 未预留目标的权重之和
+		for i, rule := range rules {
+			if !reserved[i] {
+				freeWeight += rule.Weight
+			}
+		}
+
+		changed := false
+		for i, rule := range rules {
+			// 占比 Weight*(100-reservedShare)/freeWeight 低于 MinShare 时预留
+			if !reserved[i] && rule.MinShare > 0 && rule.Weight*(100-reservedShare) < rule.MinShare*freeWeight {
+				reserved[i] = true
+				reservedShare += rule.MinShare
+				changed = true
+			}
+		}
+		if changed {
+			continue
+		}
+
+		// 预留目标权重为 MinShare*freeWeight，其余目标为 Weight*(100-reservedShare)，
+		// 总权重为 100*freeWeight，从而预留目标恰好获得 MinShare% 的选择
+		weights := make([]int, len(rules))
+		divisor := 0
+		for i, rule := range rules {
+			switch {
+			case reserved[i] && freeWeight == 0:
+				weights[i] = rule.MinShare // 所有目标均预留时按 MinShare 的比例分配
+			case reserved[i]:
+				weights[i] = rule.MinShare * freeWeight
+			default:
+				weights[i] = rule.Weight * (100 - reservedShare)
+			}
+			divisor = gcd(divisor, weights[i])
+		}
+		if divisor > 1 {
+			for i := range weights {
+				weights[i] /= divisor
+			}
+		}
+		return weights
+	}
+}
+
+// gcd 返回两个非负整数的最大公约数
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// nextCount 递增并返回路径的选择计数，启用共享计数器时优先使用共享计数
+func (wrr *WeightedRoundRobin) nextCount(ctx context.Context, path string, state *wrrState) int {
+	if count, ok := wrr.shared.next(ctx, wrrCounterKeyPrefix+path); ok {
+		return int((count - 1) % uint64(state.totalWeight))
+	}
 	wrr.mu.Lock()
 	defer wrr.mu.Unlock()
+	state.currentCount++
+	return state.currentCount
+}
 
+// selectFallback 对传入的目标简单轮询，按传入目标的数量取模，索引不会越界
+func (wrr *WeightedRoundRobin) selectFallback(span trace.Span, path string, targets []string) string {
+	wrr.mu.Lock()
+	index := wrr.fallbackCount % uint64(len(targets))
+	wrr.fallbackCount++
+	wrr.mu.Unlock()
+
+	target := targets[index]
+	span.SetAttributes(attribute.String("selected_target", target))
+	logger.Debug("Selected target using simple round-robin fallback",
+		zap.String("path", path),
+		zap.String("target", target))
+	return target
+}
+
+// SelectTarget 根据加权轮询选择目标，或回退到简单轮询
+// 规则只在配置热更新时整体替换，计数器单独加锁，共享计数器的 Redis 请求不在锁内进行
+func (wrr *WeightedRoundRobin) SelectTarget(targets []string, req *http.Request) string {
 	// 开始追踪负载均衡选择过程
 	_, span := wrrTracer.Start(req.Context(), "LoadBalancer.Select",
 		trace.WithAttributes(attribute.String("type", wrr.Type())),
@@ -95,20 +230,10 @@ func (wrr *WeightedRoundRobin) SelectTarget(targets []string, req *http.Request)
 
 	// 尝试使用预定义的加权规则
 	path := req.URL.Path
-	state, ok := wrr.states[path]
+	state, ok := wrr.state(path)
 	if !ok || len(state.targets) == 0 {
 		// 如果没有预定义规则，回退到简单轮询
-		count := 0
-		if state != nil {
-			count = state.currentCount
-			state.currentCount = (state.currentCount + 1) % len(targets)
-		}
-		target := targets[count%len(targets)]
-		span.SetAttributes(attribute.String("selected_target", target))
-		logger.Debug("Selected target using simple round-robin fallback",
-			zap.String("path", path),
-			zap.String("target", target))
-		return target
+		return wrr.selectFallback(span, path, targets)
 	}
 
 	// 加权轮询选择
@@ -119,8 +244,7 @@ func (wrr *WeightedRoundRobin) SelectTarget(targets []string, req *http.Request)
 	}
 
 	// 递增计数器并计算在总权重中的位置
-	state.currentCount++
-	current := state.currentCount % state.totalWeight
+	current := wrr.nextCount(req.Context(), path, state) % state.totalWeight
 	cumulativeWeight := 0
 
 	// 根据累计权重选择目标
@@ -128,6 +252,10 @@ func (wrr *WeightedRoundRobin) SelectTarget(targets []string, req *http.Request)
 		cumulativeWeight += weight
 		if current < cumulativeWeight {
 			target := state.targets[i]
+			if !slices.Contains(targets, target) {
+				// 选中的目标不在本次传入的目标中（如已被摘除），回退到对传入目标的简单轮询
+				return wrr.selectFallback(span, path, targets)
+			}
 			span.SetAttributes(attribute.String("selected_target", target))
 			logger.Debug("Selected target using weighted round-robin",
 				zap.String("path", path),
@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
 )
 
 func TestWeightedRoundRobin_SelectTarget(t *testing.T) {
@@ -76,3 +79,149 @@ func TestWeightedRoundRobin_Distribution(t *testing.T) {
 		t.Errorf("Expected ~20 calls to 8082, got %d", counts["http://localhost:8082"])
 	}
 }
+
+func TestWeightedRoundRobin_MinShare(t *testing.T) {
+	rules := map[string][]TargetWeight{
+		"/test": {
+			{Target: "http://localhost:8081", Weight: 100},
+			{Target: "http://localhost:8082", Weight: 100},
+			{Target: "http://localhost:8083", Weight: 1, MinShare: 10}, // 新实例预热
+		},
+	}
+	wrr := NewWeightedRoundRobin(rules)
+	req := httptest.NewRequest("GET", "/test", nil)
+	targets := []string{"http://localhost:8081", "http://localhost:8082", "http://localhost:8083"}
+
+	const total = 1000
+	counts := make(map[string]int)
+	for i := 0; i < total; i++ {
+		counts[wrr.SelectTarget(targets, req)]++
+	}
+
+	// 仅按权重只能分到约 0.5%，minShare 保证至少 10%
+	if counts["http://localhost:8083"] < total/10 {
+		t.Errorf("Expected at least %d calls to 8083, got %d", total/10, counts["http://localhost:8083"])
+	}
+	// 其余流量仍按 1:1 的权重分配
+	if diff := counts["http://localhost:8081"] - counts["http://localhost:8082"]; diff < -10 || diff > 10 {
+		t.Errorf("Expected 8081 and 8082 to share the rest evenly, got %d and %d", counts["http://localhost:8081"], counts["http://localhost:8082"])
+	}
+}
+
+func TestEffectiveWeights(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []TargetWeight
+		want  []int
+	}{
+		{
+			name:  "No minShare keeps weights",
+			rules: []TargetWeight{{Weight: 1}, {Weight: 2}},
+			want:  []int{1, 2},
+		},
+		{
+			name:  "minShare already satisfied by weight",
+			rules: []TargetWeight{{Weight: 1}, {Weight: 1, MinShare: 20}},
+			want:  []int{1, 1},
+		},
+		{
+			name:  "Low weight target reserves its minShare",
+			rules: []TargetWeight{{Weight: 9}, {Weight: 1, MinShare: 25}},
+			want:  []int{3, 1}, // 75% : 25%
+		},
+		{
+			name:  "Zero weight target still receives its minShare",
+			rules: []TargetWeight{{Weight: 5}, {Weight: 5}, {Weight: 0, MinShare: 20}},
+			want:  []int{2, 2, 1}, // 40% : 40% : 20%
+		},
+		{
+			name: "Reserving one target pushes another below its minShare",
+			// 仅预留第三个目标后第二个目标只剩 (100-30)*10/(90+10) = 7% < 10%，需一并预留
+			rules: []TargetWeight{{Weight: 90}, {Weight: 10, MinShare: 10}, {Weight: 0, MinShare: 30}},
+			want:  []int{6, 1, 3}, // 60% : 10% : 30%
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := effectiveWeights(tt.rules)
+			if len(got) != len(tt.want) {
+				t.Fatalf("effectiveWeights() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("effectiveWeights() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedRoundRobin_UpdateTargetsKeepsUnchangedPaths(t *testing.T) {
+	logger.InitTestLogger()
+	cfg := &config.Config{Routing: config.Routing{Rules: map[string]config.RoutingRules{
+		"/a": {{Target: "http://a1", Weight: 1}, {Target: "http://a2", Weight: 1}},
+		"/b": {{Target: "http://b1", Weight: 1}, {Target: "http://b2", Weight: 1}},
+		"/c": {{Target: "http://c1", Weight: 1}},
+	}}}
+	wrr := NewWeightedRoundRobin(buildWeightedRoundRobinRules(cfg))
+	selectPath := func(path string, targets ...string) string {
+		return wrr.SelectTarget(targets, httptest.NewRequest("GET", path, nil))
+	}
+	selectPath("/a", "http://a1", "http://a2")
+	selectPath("/b", "http://b1", "http://b2")
+
+	// 只修改 /b 的权重并删除 /c
+	cfg.Routing.Rules = map[string]config.RoutingRules{
+		"/a": cfg.Routing.Rules["/a"],
+		"/b": {{Target: "http://b1", Weight: 1}, {Target: "http://b2", Weight: 3}},
+	}
+	wrr.UpdateTargets(cfg)
+
+	if got := selectPath("/a", "http://a1", "http://a2"); got != "http://a2" {
+		t.Errorf("unchanged path selection = %s, want http://a2", got)
+	}
+	if got := selectPath("/b", "http://b1", "http://b2"); got != "http://b1" {
+		t.Errorf("changed path selection = %s, want http://b1 (counter restarts)", got)
+	}
+	if _, ok := wrr.state("/c"); ok {
+		t.Error("state of removed path should be dropped")
+	}
+}
+
+func TestWeightedRoundRobin_FallbackWithMismatchedTargets(t *testing.T) {
+	logger.InitTestLogger()
+	wrr := NewWeightedRoundRobin(map[string][]TargetWeight{
+		"/weighted": {
+			{Target: "http://a", Weight: 1},
+			{Target: "http://b", Weight: 1},
+			{Target: "http://c", Weight: 1},
+		},
+	})
+	// 规则中的路径没有目标时也回退到简单轮询
+	wrr.states["/empty"] = newWRRState(nil)
+
+	for _, path := range []string{"/unknown", "/empty"} {
+		targets := []string{"http://x", "http://y"}
+		seen := make(map[string]int)
+		for i := 0; i < 6; i++ {
+			got := wrr.SelectTarget(targets, httptest.NewRequest("GET", path, nil))
+			if got != "http://x" && got != "http://y" {
+				t.Fatalf("%s selection %d = %q, want one of %v", path, i, got, targets)
+			}
+			seen[got]++
+		}
+		if seen["http://x"] != 3 || seen["http://y"] != 3 {
+			t.Errorf("%s fallback distribution = %v, want x:3 y:3", path, seen)
+		}
+	}
+
+	// 传入的目标少于规则中的目标（如 http://c 已被摘除）时只在传入目标中选择
+	targets := []string{"http://a", "http://b"}
+	for i := 0; i < 9; i++ {
+		if got := wrr.SelectTarget(targets, httptest.NewRequest("GET", "/weighted", nil)); got != "http://a" && got != "http://b" {
+			t.Fatalf("selection %d = %q, want one of %v", i, got, targets)
+		}
+	}
+}
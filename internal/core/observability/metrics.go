@@ -1,8 +1,13 @@
 package observability
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // 定义全局 Prometheus 指标用于可观测性
@@ -26,6 +31,16 @@ var (
 		[]string{"method", "path"},
 	)
 
+	// UpstreamDuration 测量转发到后端的往返延迟分布（单位：秒），按目标分类，不含网关自身开销
+	UpstreamDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gateway_upstream_duration_seconds",
+			Help:    "Upstream round-trip latency in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"target"},
+	)
+
 	// RateLimitRejections 统计因限流拒绝的请求数，按路径分类
 	RateLimitRejections = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -35,6 +50,15 @@ var (
 		[]string{"path"},
 	)
 
+	// ConcurrencyLimitRejections 统计因并发请求数达到上限被拒绝的请求数，按路径和维度（global、route）分类
+	ConcurrencyLimitRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_concurrency_limit_rejections_total",
+			Help: "Total number of requests rejected because the concurrency limit was reached",
+		},
+		[]string{"path", "scope"},
+	)
+
 	// BreakerTrips 统计熔断器触发的次数，按路径分类
 	BreakerTrips = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -44,6 +68,24 @@ var (
 		[]string{"path"},
 	)
 
+	// BreakerState 熔断器当前状态：0 关闭、1 打开、2 半开，按路由和目标分类
+	BreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_breaker_state",
+			Help: "Circuit breaker state per route and target (0=closed, 1=open, 2=half-open)",
+		},
+		[]string{"path", "target"},
+	)
+
+	// BreakerProbes 统计熔断器半开时的探测请求数，按路由和结果（success、failure）分类
+	BreakerProbes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_breaker_half_open_probes_total",
+			Help: "Total number of requests let through a half-open circuit breaker to probe the upstream",
+		},
+		[]string{"path", "result"},
+	)
+
 	// ActiveWebSocketConnections 跟踪当前活跃的 WebSocket 连接数
 	ActiveWebSocketConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -52,6 +94,22 @@ var (
 		},
 	)
 
+	// RoutingRules 当前配置中的路由路径数
+	RoutingRules = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gateway_routing_rules",
+			Help: "Number of configured routing rules",
+		},
+	)
+
+	// RoutingTargets 当前配置中所有路由的目标总数
+	RoutingTargets = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gateway_routing_targets",
+			Help: "Number of configured routing targets across all rules",
+		},
+	)
+
 	// JwtAuthFailures 统计 JWT 认证失败的次数，按路径分类
 	JwtAuthFailures = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -61,6 +119,24 @@ var (
 		[]string{"path"},
 	)
 
+	// OAuth2AuthFailures 统计 OAuth2 令牌自省认证失败的次数，按路径分类
+	OAuth2AuthFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_oauth2_auth_failures_total",
+			Help: "Total number of OAuth2 token introspection authentication failures",
+		},
+		[]string{"path"},
+	)
+
+	// APIKeyAuthFailures 统计 API Key 认证失败的次数，按路径分类
+	APIKeyAuthFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_apikey_auth_failures_total",
+			Help: "Total number of API key authentication failures",
+		},
+		[]string{"path"},
+	)
+
 	// IPAclRejections 统计因 IP 访问控制列表拒绝的请求数，按路径和 IP 分类
 	IPAclRejections = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -70,6 +146,15 @@ var (
 		[]string{"path", "ip"},
 	)
 
+	// IPAutoBans 统计因频繁违规被自动封禁的 IP 次数，按触发原因分类
+	IPAutoBans = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_ip_auto_bans_total",
+			Help: "Total number of IPs automatically banned after repeated violations",
+		},
+		[]string{"reason"},
+	)
+
 	// AntiInjectionBlocks 统计因检测到注入行为而阻止的请求数，按路径分类
 	AntiInjectionBlocks = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -106,6 +191,51 @@ var (
 		[]string{"path", "status"},
 	)
 
+	// ProtocolMismatches 跟踪上游协议与路由协议不匹配的次数，按目标和上游实际协议分类
+	ProtocolMismatches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_protocol_mismatches_total",
+			Help: "Total number of upstream responses whose protocol does not match the route",
+		},
+		[]string{"target", "protocol"},
+	)
+
+	// CoalescedRequests 跟踪被合并、共享其他请求响应而未访问后端的请求数，按路由路径分类
+	CoalescedRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_coalesced_requests_total",
+			Help: "Total number of requests served from a coalesced backend request",
+		},
+		[]string{"path"},
+	)
+
+	// OversizedResponseHeaders 统计超出大小上限而被删除或截断的上游响应头，按目标、响应头名称和处理方式分类
+	OversizedResponseHeaders = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_oversized_response_headers_total",
+			Help: "Total number of upstream response headers stripped or truncated for exceeding the size limit",
+		},
+		[]string{"target", "header", "action"},
+	)
+
+	// RedisFailures 统计依赖 Redis 的中间件遇到 Redis 失败的次数，按中间件和生效的失败处理方式（open 或 closed）分类
+	RedisFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_redis_failures_total",
+			Help: "Total number of Redis failures seen by Redis-dependent middlewares",
+		},
+		[]string{"middleware", "policy"},
+	)
+
+	// UpstreamRetries 跟踪向上游重试的次数，按目标和触发原因（connection 或状态码）分类
+	UpstreamRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_upstream_retries_total",
+			Help: "Total number of retried upstream requests",
+		},
+		[]string{"target", "reason"},
+	)
+
 	// MemoryAllocations 跟踪网关内存分配情况，按类型分类
 	MemoryAllocations = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -170,13 +300,45 @@ func ResetMetrics() {
 	RequestsTotal.Reset()
 	RequestDuration.Reset()
 	RateLimitRejections.Reset()
+	ConcurrencyLimitRejections.Reset()
 	BreakerTrips.Reset()
+	BreakerState.Reset()
+	BreakerProbes.Reset()
 	ActiveWebSocketConnections.Set(0)
+	RoutingRules.Set(0)
+	RoutingTargets.Set(0)
 	JwtAuthFailures.Reset()
+	APIKeyAuthFailures.Reset()
+	OAuth2AuthFailures.Reset()
 	IPAclRejections.Reset()
 	AntiInjectionBlocks.Reset()
 	CacheHits.Reset()
 	CacheMisses.Reset()
 	GRPCCallsTotal.Reset()
+	ProtocolMismatches.Reset()
+	CoalescedRequests.Reset()
+	UpstreamRetries.Reset()
+	OversizedResponseHeaders.Reset()
+	RedisFailures.Reset()
 	MemoryAllocations.Reset() // 重置内存分配指标
 }
+
+// ObserveRequestDuration 记录请求延迟，上下文中有采样的追踪时附带 trace_id 样本（exemplar），便于从指标跳转到追踪
+func ObserveRequestDuration(ctx context.Context, method, path string, seconds float64) {
+	observer := RequestDuration.WithLabelValues(method, path)
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() && sc.IsSampled() {
+		if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(seconds, prometheus.Labels{"trace_id": sc.TraceID().String()})
+			return
+		}
+	}
+	observer.Observe(seconds)
+}
+
+// MetricsHandler 返回 Prometheus 指标处理器，启用 OpenMetrics 格式以便抓取方获取 exemplar
+func MetricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
@@ -0,0 +1,70 @@
+package observability
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// requestDurationExemplars 返回指定方法和路径的请求延迟直方图上所有桶的 exemplar
+func requestDurationExemplars(t *testing.T, method, path string) []*dto.Exemplar {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	var exemplars []*dto.Exemplar
+	for _, family := range families {
+		if family.GetName() != "gateway_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["method"] != method || labels["path"] != path {
+				continue
+			}
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				if bucket.GetExemplar() != nil {
+					exemplars = append(exemplars, bucket.GetExemplar())
+				}
+			}
+		}
+	}
+	return exemplars
+}
+
+func TestObserveRequestDuration_WithTraceExemplar(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	defer tp.Shutdown(context.Background())
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	ObserveRequestDuration(ctx, "GET", "/exemplar", 0.042)
+
+	exemplars := requestDurationExemplars(t, "GET", "/exemplar")
+	require.Len(t, exemplars, 1)
+	assert.Equal(t, 0.042, exemplars[0].GetValue())
+	require.Len(t, exemplars[0].GetLabel(), 1)
+	assert.Equal(t, "trace_id", exemplars[0].GetLabel()[0].GetName())
+	assert.Equal(t, span.SpanContext().TraceID().String(), exemplars[0].GetLabel()[0].GetValue())
+}
+
+func TestObserveRequestDuration_WithoutSpan(t *testing.T) {
+	ObserveRequestDuration(context.Background(), "GET", "/no-exemplar", 0.042)
+	assert.Empty(t, requestDurationExemplars(t, "GET", "/no-exemplar"))
+
+	// 未采样的追踪不附带 exemplar
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+	defer tp.Shutdown(context.Background())
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	ObserveRequestDuration(ctx, "GET", "/unsampled", 0.042)
+	assert.Empty(t, requestDurationExemplars(t, "GET", "/unsampled"))
+}
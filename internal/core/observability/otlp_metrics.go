@@ -0,0 +1,191 @@
+package observability
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.uber.org/zap"
+)
+
+// gatewayMetricPrefix 仅导出网关自身的指标，忽略 Go 运行时等默认采集器
+const gatewayMetricPrefix = "gateway_"
+
+// InitOTLPMetrics 初始化 OTLP 指标导出，根据配置决定是否启用
+// 指标直接读取 Prometheus 注册表中的同一份数据，两种导出方式同时开启时不会重复计数
+// 返回一个清理资源的关闭函数
+func InitOTLPMetrics(cfg *config.Config) func(context.Context) error {
+	otlpCfg := cfg.Observability.OTLP.Metrics
+	if !otlpCfg.Enabled {
+		logger.Info("OTLP metrics export is disabled in configuration")
+		return func(ctx context.Context) error { return nil }
+	}
+
+	exporter, err := otlpmetrichttp.New(context.Background(), metricExporterOptions(otlpCfg.Endpoint)...)
+	if err != nil {
+		logger.Error("Failed to initialize OTLP metric exporter",
+			zap.String("endpoint", otlpCfg.Endpoint),
+			zap.Error(err))
+		return func(ctx context.Context) error { return nil }
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("mini-gateway"),
+			semconv.ServiceVersionKey.String("0.1.0"),
+		),
+	)
+	if err != nil {
+		logger.Error("Failed to create metrics resource",
+			zap.Error(err))
+		return func(ctx context.Context) error { return nil }
+	}
+
+	readerOpts := []sdkmetric.PeriodicReaderOption{
+		sdkmetric.WithProducer(newPrometheusProducer(prometheus.DefaultGatherer)),
+	}
+	if otlpCfg.Interval > 0 {
+		readerOpts = append(readerOpts, sdkmetric.WithInterval(otlpCfg.Interval))
+	}
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, readerOpts...)),
+		sdkmetric.WithResource(res),
+	)
+
+	logger.Info("OTLP metrics export initialized successfully",
+		zap.String("endpoint", otlpCfg.Endpoint),
+		zap.Duration("interval", otlpCfg.Interval))
+
+	return mp.Shutdown
+}
+
+// metricExporterOptions 根据 endpoint 生成指标导出器选项，规则与追踪导出器一致
+func metricExporterOptions(endpoint string) []otlpmetrichttp.Option {
+	if strings.Contains(endpoint, "://") {
+		return []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(endpoint)}
+	}
+	return []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(endpoint),
+		otlpmetrichttp.WithURLPath("/v1/metrics"),
+		otlpmetrichttp.WithInsecure(), // 本地测试禁用 TLS，生产环境需配置
+	}
+}
+
+// prometheusProducer 将 Prometheus 注册表中的指标转换为 OTel 指标数据
+type prometheusProducer struct {
+	gatherer  prometheus.Gatherer
+	startTime time.Time // 累积指标的起始时间
+}
+
+// newPrometheusProducer 创建基于 Prometheus 注册表的指标生产者
+func newPrometheusProducer(gatherer prometheus.Gatherer) *prometheusProducer {
+	return &prometheusProducer{gatherer: gatherer, startTime: time.Now()}
+}
+
+// Produce 实现 sdkmetric.Producer，每次导出时采集一次 Prometheus 指标
+func (p *prometheusProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	metrics := make([]metricdata.Metrics, 0, len(families))
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), gatewayMetricPrefix) {
+			continue
+		}
+		if m, ok := p.convertFamily(family, now); ok {
+			metrics = append(metrics, m)
+		}
+	}
+
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: "github.com/penwyp/mini-gateway/internal/core/observability"},
+		Metrics: metrics,
+	}}, nil
+}
+
+// convertFamily 按指标类型转换单个 Prometheus 指标族
+func (p *prometheusProducer) convertFamily(family *dto.MetricFamily, now time.Time) (metricdata.Metrics, bool) {
+	m := metricdata.Metrics{Name: family.GetName(), Description: family.GetHelp()}
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, metric := range family.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: labelsToAttributes(metric.GetLabel()),
+				StartTime:  p.startTime,
+				Time:       now,
+				Value:      metric.GetCounter().GetValue(),
+			})
+		}
+		m.Data = sum
+	case dto.MetricType_GAUGE:
+		gauge := metricdata.Gauge[float64]{}
+		for _, metric := range family.GetMetric() {
+			gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: labelsToAttributes(metric.GetLabel()),
+				Time:       now,
+				Value:      metric.GetGauge().GetValue(),
+			})
+		}
+		m.Data = gauge
+	case dto.MetricType_HISTOGRAM:
+		histogram := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, metric := range family.GetMetric() {
+			histogram.DataPoints = append(histogram.DataPoints, p.convertHistogram(metric, now))
+		}
+		m.Data = histogram
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+// convertHistogram 将 Prometheus 的累积桶计数转换为 OTel 的分桶计数
+func (p *prometheusProducer) convertHistogram(metric *dto.Metric, now time.Time) metricdata.HistogramDataPoint[float64] {
+	h := metric.GetHistogram()
+	dp := metricdata.HistogramDataPoint[float64]{
+		Attributes: labelsToAttributes(metric.GetLabel()),
+		StartTime:  p.startTime,
+		Time:       now,
+		Count:      h.GetSampleCount(),
+		Sum:        h.GetSampleSum(),
+	}
+
+	var cumulative uint64
+	for _, bucket := range h.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		dp.Bounds = append(dp.Bounds, bucket.GetUpperBound())
+		dp.BucketCounts = append(dp.BucketCounts, bucket.GetCumulativeCount()-cumulative)
+		cumulative = bucket.GetCumulativeCount()
+	}
+	// 最后一个桶为 (最大边界, +Inf]
+	dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-cumulative)
+	return dp
+}
+
+// labelsToAttributes 将 Prometheus 标签转换为 OTel 属性集合
+func labelsToAttributes(labels []*dto.LabelPair) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(labels))
+	for _, label := range labels {
+		kvs = append(kvs, attribute.String(label.GetName(), label.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
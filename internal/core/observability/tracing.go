@@ -2,6 +2,7 @@ package observability
 
 import (
 	"context"
+	"strings"
 
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/pkg/logger"
@@ -24,11 +25,7 @@ func InitTracing(cfg *config.Config) func(context.Context) error {
 	logger.Info("Jaeger tracing is enabled in configuration")
 
 	// 创建 OTLP HTTP 导出器，用于将追踪数据发送到 Jaeger
-	exporter, err := otlptracehttp.New(context.Background(),
-		otlptracehttp.WithEndpoint(cfg.Observability.Jaeger.Endpoint),
-		otlptracehttp.WithURLPath("/v1/traces"),
-		otlptracehttp.WithInsecure(), // 本地测试禁用 TLS，生产环境需配置
-	)
+	exporter, err := otlptracehttp.New(context.Background(), exporterOptions(cfg.Observability.Jaeger.Endpoint)...)
 	if err != nil {
 		logger.Error("Failed to initialize OTLP exporter",
 			zap.String("endpoint", cfg.Observability.Jaeger.Endpoint),
@@ -36,18 +33,7 @@ func InitTracing(cfg *config.Config) func(context.Context) error {
 		panic(err) // 致命错误，生产环境建议优雅处理
 	}
 
-	// 根据配置选择采样器
-	var sampler sdktrace.Sampler
-	switch cfg.Observability.Jaeger.Sampler {
-	case "always":
-		sampler = sdktrace.AlwaysSample()
-	case "ratio":
-		sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Observability.Jaeger.SampleRatio))
-	default:
-		sampler = sdktrace.AlwaysSample()
-		logger.Warn("Unknown sampler type detected, defaulting to 'always'",
-			zap.String("sampler", cfg.Observability.Jaeger.Sampler))
-	}
+	sampler := newSampler(cfg.Observability.Jaeger)
 
 	// 定义服务资源信息
 	res, err := resource.New(context.Background(),
@@ -83,3 +69,40 @@ func InitTracing(cfg *config.Config) func(context.Context) error {
 
 	return tp.Shutdown // 返回清理函数以释放资源
 }
+
+// exporterOptions 根据 endpoint 生成导出器选项
+// 带协议的完整 URL（如 https://collector:4318/v1/traces）按原样使用，否则视为 host:port 并使用默认路径与明文传输
+func exporterOptions(endpoint string) []otlptracehttp.Option {
+	if strings.Contains(endpoint, "://") {
+		return []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+	}
+	return []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(endpoint),
+		otlptracehttp.WithURLPath("/v1/traces"),
+		otlptracehttp.WithInsecure(), // 本地测试禁用 TLS，生产环境需配置
+	}
+}
+
+// newSampler 根据配置创建采样器，均基于父 Span 的采样决策，以尊重上游发起的追踪
+func newSampler(jaeger config.Jaeger) sdktrace.Sampler {
+	ratio := jaeger.SampleRatio
+	if ratio < 0 {
+		ratio = 0
+	} else if ratio > 1 {
+		ratio = 1
+	}
+
+	switch jaeger.Sampler {
+	case "always":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case "never":
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	case "ratio", "":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	default:
+		logger.Warn("Unknown sampler type detected, defaulting to 'ratio'",
+			zap.String("sampler", jaeger.Sampler),
+			zap.Float64("sampleRatio", ratio))
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	}
+}
@@ -1,96 +1,285 @@
 package routing
 
 import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
 )
 
+// indexFile 请求目录时优先返回的文件
+const indexFile = "index.html"
+
+// errOutsideRoot 请求路径解析后位于挂载目录之外
+var errOutsideRoot = errors.New("path escapes the mounted directory")
+
+// fileMount 一个路径前缀到本地目录的映射
+type fileMount struct {
+	prefix string // 路由前缀，如 /static
+	root   string // 本地目录
+}
+
 // FileServerRouter 处理静态文件服务，可使用 fasthttp 或 Gin 默认实现
 type FileServerRouter struct {
-	filePath string // 静态文件根目录
-	enabled  bool   // 是否启用 fasthttp 实现零拷贝文件服务
+	mounts  []fileMount // 按前缀排序的挂载列表
+	enabled bool        // 是否启用 fasthttp 实现零拷贝文件服务
+	listing bool        // 目录没有 index.html 时是否列出目录内容
 }
 
 // NewFileServerRouter 根据配置创建 FileServerRouter 实例
 func NewFileServerRouter(cfg *config.Config) *FileServerRouter {
-	return &FileServerRouter{
-		filePath: cfg.FileServer.StaticFilePath,
-		enabled:  cfg.FileServer.EnabledFastHttp,
+	fr := &FileServerRouter{
+		enabled: cfg.FileServer.EnabledFastHttp,
+		listing: cfg.FileServer.Listing,
+	}
+	if cfg.FileServer.StaticFilePath != "" {
+		fr.mounts = append(fr.mounts, fileMount{prefix: "/static", root: cfg.FileServer.StaticFilePath})
 	}
+	for prefix, root := range cfg.FileServer.Mounts {
+		fr.mounts = append(fr.mounts, fileMount{prefix: strings.TrimRight(prefix, "/"), root: root})
+	}
+	sort.Slice(fr.mounts, func(i, j int) bool { return fr.mounts[i].prefix < fr.mounts[j].prefix })
+	return fr
 }
 
 // Setup 在 Gin 路由器中配置静态文件服务路由
 func (fr *FileServerRouter) Setup(r gin.IRouter, cfg *config.Config) {
-	if fr.filePath == "" {
+	if len(fr.mounts) == 0 {
 		logger.Warn("Static file serving disabled due to empty file path")
 		return
 	}
 
 	// 注册静态文件服务路由
-	r.GET("/static/*filepath", fr.serveStaticFile)
-	if fr.enabled {
-		logger.Info("FastHTTP static file serving enabled",
-			zap.String("rootPath", fr.filePath))
-	} else {
-		logger.Info("Gin default static file serving enabled",
-			zap.String("rootPath", fr.filePath))
+	for _, m := range fr.mounts {
+		handler := fr.serveStaticFile(m)
+		r.GET(m.prefix+"/*filepath", handler)
+		r.HEAD(m.prefix+"/*filepath", handler)
+		if fr.enabled {
+			logger.Info("FastHTTP static file serving enabled",
+				zap.String("prefix", m.prefix),
+				zap.String("rootPath", m.root),
+				zap.Bool("listing", fr.listing))
+		} else {
+			logger.Info("Gin default static file serving enabled",
+				zap.String("prefix", m.prefix),
+				zap.String("rootPath", m.root),
+				zap.Bool("listing", fr.listing))
+		}
 	}
 }
 
-// serveStaticFile 处理静态文件请求，根据配置选择 fasthttp 或 Gin 实现
-func (fr *FileServerRouter) serveStaticFile(c *gin.Context) {
-	filepath := c.Param("filepath")
-	if filepath == "" {
-		logger.Warn("Invalid static file request: empty file path")
-		c.JSON(400, gin.H{"error": "File path cannot be empty"})
-		return
-	}
+// serveStaticFile 处理静态文件请求，目录请求返回 index.html 或目录列表，文件根据配置选择 fasthttp 或 Gin 实现，范围请求始终使用 Gin 实现
+func (fr *FileServerRouter) serveStaticFile(m fileMount) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("filepath")
+		if name == "" {
+			logger.Warn("Invalid static file request: empty file path")
+			proxy.WriteError(c, http.StatusBadRequest, proxy.ErrCodeBadRequest, "File path cannot be empty")
+			return
+		}
 
-	// 构建完整文件路径
-	fullPath := fr.filePath + filepath
-	logger.Debug("Handling static file request",
-		zap.String("requestPath", c.Request.URL.Path),
-		zap.String("fullPath", fullPath))
+		fullPath, err := resolveFilePath(m.root, name)
+		if errors.Is(err, errOutsideRoot) {
+			logger.Warn("Rejected static file request outside the mounted directory",
+				zap.String("requestPath", c.Request.URL.Path),
+				zap.String("rootPath", m.root))
+			proxy.WriteError(c, http.StatusForbidden, proxy.ErrCodeForbidden, "Access denied")
+			return
+		}
+		info, statErr := os.Stat(fullPath)
+		if err != nil || statErr != nil {
+			logger.Debug("Static file not found",
+				zap.String("requestPath", c.Request.URL.Path),
+				zap.String("fullPath", fullPath))
+			proxy.WriteError(c, http.StatusNotFound, proxy.ErrCodeFileNotFound, "File not found")
+			return
+		}
+		logger.Debug("Handling static file request",
+			zap.String("requestPath", c.Request.URL.Path),
+			zap.String("fullPath", fullPath))
 
-	if fr.enabled {
-		// 使用 fasthttp 实现零拷贝文件服务
-		fctx := &fasthttp.RequestCtx{
-			Request:  fasthttp.Request{},
-			Response: fasthttp.Response{},
+		if info.IsDir() {
+			// 目录地址以 / 结尾，index.html 和目录列表中的相对链接才能正确解析
+			if !strings.HasSuffix(c.Request.URL.Path, "/") {
+				target := c.Request.URL.Path + "/"
+				if c.Request.URL.RawQuery != "" {
+					target += "?" + c.Request.URL.RawQuery
+				}
+				c.Redirect(http.StatusMovedPermanently, target)
+				return
+			}
+			index := filepath.Join(fullPath, indexFile)
+			if indexInfo, err := os.Stat(index); err == nil && !indexInfo.IsDir() {
+				fullPath, info = index, indexInfo
+			} else if fr.listing {
+				fr.serveDirectory(c, fullPath, name != "/")
+				return
+			} else {
+				proxy.WriteError(c, http.StatusForbidden, proxy.ErrCodeForbidden, "Directory listing is disabled")
+				return
+			}
 		}
-		fasthttp.ServeFile(fctx, fullPath)
 
-		if fctx.Response.StatusCode() >= 400 {
-			logger.Warn("FastHTTP failed to serve static file",
-				zap.String("filePath", fullPath),
-				zap.Int("statusCode", fctx.Response.StatusCode()))
-			c.Data(fctx.Response.StatusCode(), string(fctx.Response.Header.ContentType()), fctx.Response.Body())
-			return
+		// 范围请求统一交给 http.ServeContent，由其处理 Range 与 If-Range 并返回 206 或 416
+		if fr.enabled && c.GetHeader("Range") == "" {
+			fr.serveWithFastHTTP(c, fullPath, info)
+		} else {
+			fr.serveWithGin(c, fullPath, info)
 		}
+	}
+}
 
-		// 直接写入 Gin 的响应流，避免数据拷贝
-		c.Writer.WriteHeader(fctx.Response.StatusCode())
-		fctx.Response.Header.VisitAll(func(key, value []byte) {
-			c.Writer.Header().Set(string(key), string(value))
-		})
-		fctx.Response.BodyWriteTo(c.Writer)
-		logger.Info("FastHTTP successfully served static file",
+// resolveFilePath 将请求路径映射为挂载目录下的本地路径
+// 请求路径以 / 为根 Clean 后不会包含 ..；解析符号链接后仍需位于挂载目录内，防止通过链接访问目录外的文件
+func resolveFilePath(root, name string) (string, error) {
+	fullPath := filepath.Join(root, filepath.FromSlash(path.Clean("/"+name)))
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fullPath, err
+	}
+	realPath, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return fullPath, err
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fullPath, errOutsideRoot
+	}
+	return fullPath, nil
+}
+
+// fileETag 根据修改时间和大小生成强校验 ETag，与 Nginx 的格式相同
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size())
+}
+
+// serveWithGin 使用 http.ServeContent 返回文件，由其设置 Content-Type、Last-Modified 并处理条件请求和范围请求
+func (fr *FileServerRouter) serveWithGin(c *gin.Context, fullPath string, info os.FileInfo) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		logger.Warn("Gin failed to serve static file",
+			zap.String("filePath", fullPath),
+			zap.Error(err))
+		proxy.WriteError(c, http.StatusNotFound, proxy.ErrCodeFileNotFound, "File not found")
+		return
+	}
+	defer f.Close()
+
+	c.Header("ETag", fileETag(info))
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
+	logger.Info("Gin successfully served static file",
+		zap.String("filePath", fullPath),
+		zap.Int("statusCode", c.Writer.Status()))
+}
+
+// serveWithFastHTTP 使用 fasthttp 实现零拷贝文件服务，fasthttp 不处理 ETag，由此处判断 If-None-Match
+func (fr *FileServerRouter) serveWithFastHTTP(c *gin.Context, fullPath string, info os.FileInfo) {
+	etag := fileETag(info)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	fctx := &fasthttp.RequestCtx{
+		Request:  fasthttp.Request{},
+		Response: fasthttp.Response{},
+	}
+	// 只传递条件请求头；不传递 Accept-Encoding，避免 fasthttp 在静态目录中生成压缩缓存文件
+	fctx.Request.Header.SetMethod(c.Request.Method)
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		fctx.Request.Header.Set("If-Modified-Since", since)
+	}
+	fasthttp.ServeFile(fctx, fullPath)
+
+	if fctx.Response.StatusCode() >= 400 {
+		logger.Warn("FastHTTP failed to serve static file",
 			zap.String("filePath", fullPath),
 			zap.Int("statusCode", fctx.Response.StatusCode()))
-	} else {
-		// 使用 Gin 默认文件服务（非零拷贝）
-		c.File(fullPath)
-		if c.Writer.Status() >= 400 {
-			logger.Warn("Gin failed to serve static file",
-				zap.String("filePath", fullPath),
-				zap.Int("statusCode", c.Writer.Status()))
-			return
+		c.Data(fctx.Response.StatusCode(), string(fctx.Response.Header.ContentType()), fctx.Response.Body())
+		return
+	}
+
+	// 先复制响应头再写状态码，之后直接写入 Gin 的响应流，避免数据拷贝
+	fctx.Response.Header.VisitAll(func(key, value []byte) {
+		c.Writer.Header().Set(string(key), string(value))
+	})
+	c.Writer.WriteHeader(fctx.Response.StatusCode())
+	if c.Request.Method != http.MethodHead {
+		fctx.Response.BodyWriteTo(c.Writer)
+	}
+	logger.Info("FastHTTP successfully served static file",
+		zap.String("filePath", fullPath),
+		zap.Int("statusCode", fctx.Response.StatusCode()))
+}
+
+// etagMatches 判断 If-None-Match 是否包含指定 ETag，比较时忽略弱校验前缀
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
 		}
-		logger.Info("Gin successfully served static file",
-			zap.String("filePath", fullPath),
-			zap.Int("statusCode", c.Writer.Status()))
 	}
+	return false
+}
+
+// serveDirectory 以 HTML 列出目录内容，子目录排在文件之前，同类按名称排序
+func (fr *FileServerRouter) serveDirectory(c *gin.Context, dir string, hasParent bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Warn("Failed to read static directory",
+			zap.String("dirPath", dir),
+			zap.Error(err))
+		proxy.WriteError(c, http.StatusInternalServerError, proxy.ErrCodeInternal, "Failed to read directory")
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	title := html.EscapeString(c.Request.URL.Path)
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>Index of " + title + "</title></head>\n<body>\n")
+	b.WriteString("<h1>Index of " + title + "</h1>\n<table>\n")
+	if hasParent {
+		b.WriteString("<tr><td><a href=\"../\">../</a></td><td></td><td></td></tr>\n")
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name, size := entry.Name(), fmt.Sprintf("%d", info.Size())
+		if entry.IsDir() {
+			name, size = name+"/", "-"
+		}
+		href := (&url.URL{Path: name}).String()
+		fmt.Fprintf(&b, "<tr><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(href), html.EscapeString(name), size, info.ModTime().UTC().Format(time.RFC3339))
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(b.String()))
 }
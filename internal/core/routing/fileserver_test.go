@@ -0,0 +1,185 @@
+package routing
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFileServerEngine 创建挂载了文件服务的 Gin 引擎，静态目录包含 app.css、video.txt、site/index.html 和 files 目录
+func newFileServerEngine(t *testing.T, fastHTTP, listing bool) (*gin.Engine, string) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.css"), []byte("body{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "video.txt"), []byte("0123456789"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "site"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site", "index.html"), []byte("<h1>site</h1>"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "files", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "files", "a <b>.txt"), []byte("a"), 0644))
+
+	docs := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "readme.txt"), []byte("docs"), 0644))
+
+	cfg := &config.Config{FileServer: config.FileServer{
+		StaticFilePath:  root,
+		EnabledFastHttp: fastHTTP,
+		Listing:         listing,
+		Mounts:          map[string]string{"/docs/": docs},
+	}}
+	r := gin.New()
+	NewFileServerRouter(cfg).Setup(r, cfg)
+	return r, root
+}
+
+func serveFile(r *gin.Engine, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestFileServer_ServesFilesWithCachingHeaders(t *testing.T) {
+	for name, fastHTTP := range map[string]bool{"gin": false, "fasthttp": true} {
+		t.Run(name, func(t *testing.T) {
+			r, _ := newFileServerEngine(t, fastHTTP, false)
+
+			w := serveFile(r, "/static/app.css", nil)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "body{}", w.Body.String())
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+			assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+			etag := w.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+
+			w = serveFile(r, "/static/app.css", http.Header{"If-None-Match": {etag}})
+			assert.Equal(t, http.StatusNotModified, w.Code)
+			assert.Empty(t, w.Body.String())
+
+			w = serveFile(r, "/docs/readme.txt", nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "docs", w.Body.String())
+		})
+	}
+}
+
+func TestFileServer_Directories(t *testing.T) {
+	r, _ := newFileServerEngine(t, false, false)
+
+	w := serveFile(r, "/static/site?v=1", nil)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/static/site/?v=1", w.Header().Get("Location"))
+
+	w = serveFile(r, "/static/site/", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>site</h1>", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+
+	w = serveFile(r, "/static/files/", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r, _ = newFileServerEngine(t, false, true)
+	w = serveFile(r, "/static/files/", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `<a href="../">../</a>`)
+	assert.Contains(t, body, `<a href="sub/">sub/</a>`)
+	assert.Contains(t, body, `<a href="a%20%3Cb%3E.txt">a &lt;b&gt;.txt</a>`)
+	assert.Less(t, strings.Index(body, "sub/"), strings.Index(body, "a%20"), "directories are listed first")
+
+	w = serveFile(r, "/static/", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `href="../"`)
+}
+
+func TestFileServer_PathTraversal(t *testing.T) {
+	r, root := newFileServerEngine(t, false, false)
+	secret := filepath.Join(filepath.Dir(root), filepath.Base(root)+"-secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0644))
+	t.Cleanup(func() { os.Remove(secret) })
+	require.NoError(t, os.Symlink(secret, filepath.Join(root, "link.txt")))
+
+	for _, path := range []string{
+		"/static/../" + filepath.Base(secret),
+		"/static/%2e%2e/" + filepath.Base(secret),
+		"/static/files/..%2f..%2f" + filepath.Base(secret),
+	} {
+		w := serveFile(r, path, nil)
+		assert.NotEqual(t, http.StatusOK, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "secret", path)
+	}
+
+	w := serveFile(r, "/static/link.txt", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	w = serveFile(r, "/static/missing.txt", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFileServer_RangeRequests(t *testing.T) {
+	for name, fastHTTP := range map[string]bool{"gin": false, "fasthttp": true} {
+		t.Run(name, func(t *testing.T) {
+			r, _ := newFileServerEngine(t, fastHTTP, false)
+
+			w := serveFile(r, "/static/video.txt", http.Header{"Range": {"bytes=2-5"}})
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+			assert.Equal(t, "2345", w.Body.String())
+
+			w = serveFile(r, "/static/video.txt", http.Header{"Range": {"bytes=-3"}})
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			assert.Equal(t, "bytes 7-9/10", w.Header().Get("Content-Range"))
+			assert.Equal(t, "789", w.Body.String())
+
+			// 多个范围以 multipart/byteranges 返回
+			w = serveFile(r, "/static/video.txt", http.Header{"Range": {"bytes=0-1,8-9"}})
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			_, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			require.NoError(t, err)
+			reader := multipart.NewReader(w.Body, params["boundary"])
+			var parts []string
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				data, err := io.ReadAll(part)
+				require.NoError(t, err)
+				parts = append(parts, part.Header.Get("Content-Range")+" "+string(data))
+			}
+			assert.Equal(t, []string{"bytes 0-1/10 01", "bytes 8-9/10 89"}, parts)
+
+			w = serveFile(r, "/static/video.txt", http.Header{"Range": {"bytes=20-30"}})
+			assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+			assert.Equal(t, "bytes */10", w.Header().Get("Content-Range"))
+
+			// If-Range 与当前 ETag 不一致时忽略 Range，返回完整文件
+			w = serveFile(r, "/static/video.txt", http.Header{"Range": {"bytes=2-5"}, "If-Range": {`"stale"`}})
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "0123456789", w.Body.String())
+			etag := w.Header().Get("ETag")
+
+			w = serveFile(r, "/static/video.txt", http.Header{"Range": {"bytes=2-5"}, "If-Range": {etag}})
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			assert.Equal(t, "2345", w.Body.String())
+		})
+	}
+}
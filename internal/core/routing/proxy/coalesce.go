@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// maxCoalescedBodySize 可共享的最大响应体，超过时不共享，等待中的请求各自访问后端
+const maxCoalescedBodySize = 4 << 20
+
+// coalesceKeyHeaders 参与合并键计算的请求头，携带不同凭证、协商不同内容或访问不同环境的请求不会共享响应
+var coalesceKeyHeaders = []string{"Authorization", "Cookie", "Accept", "Accept-Encoding", "Accept-Language", "X-Env"}
+
+// requestCoalescer 全局的请求合并器
+var requestCoalescer = &coalescer{groups: make(map[string]*coalesceGroup)}
+
+// coalescer 按合并键管理正在进行或仍在窗口期内的请求组
+type coalescer struct {
+	mu     sync.Mutex
+	groups map[string]*coalesceGroup
+}
+
+// coalesceGroup 一组被合并的相同请求，由首个请求访问后端，其余请求等待并共享其响应
+type coalesceGroup struct {
+	done     chan struct{} // 首个请求完成后关闭
+	status   int
+	header   http.Header
+	body     []byte
+	shared   bool // 响应是否可共享，首个请求被取消、响应体过大或响应只属于首个客户端时为 false
+	finished bool // 首个请求是否已完成
+	expired  bool // 合并窗口是否已结束
+}
+
+// getCoalesceWindow 获取当前路由的请求合并窗口
+func getCoalesceWindow(c *gin.Context) (time.Duration, bool) {
+	coalesces := config.GetConfig().Routing.Coalesce
+	if len(coalesces) == 0 {
+		return 0, false
+	}
+	rule, ok := coalesces[c.FullPath()]
+	if !ok {
+		rule, ok = coalesces[c.Request.URL.Path]
+	}
+	return rule.Window, ok && rule.Window > 0
+}
+
+// coalesceKey 返回请求的合并键，由请求 URI 和影响响应内容的请求头组成
+func coalesceKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.RequestURI())
+	for _, name := range coalesceKeyHeaders {
+		b.WriteByte('\n')
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// coalesceRequest 合并窗口内相同的 GET 请求：首个请求调用 forward 访问后端并记录响应，
+// 窗口内（或首个请求仍未完成时）到达的相同请求等待并共享该响应，不再访问后端
+func coalesceRequest(c *gin.Context, window time.Duration, forward func()) {
+	if c.Request.Method != http.MethodGet || c.Request.ContentLength > 0 {
+		forward()
+		return
+	}
+
+	key := coalesceKey(c.Request)
+	requestCoalescer.mu.Lock()
+	if group, ok := requestCoalescer.groups[key]; ok {
+		requestCoalescer.mu.Unlock()
+		waitCoalesced(c, group, forward)
+		return
+	}
+	group := &coalesceGroup{done: make(chan struct{})}
+	requestCoalescer.groups[key] = group
+	requestCoalescer.mu.Unlock()
+
+	time.AfterFunc(window, func() {
+		requestCoalescer.mu.Lock()
+		defer requestCoalescer.mu.Unlock()
+		group.expired = true
+		if group.finished {
+			requestCoalescer.remove(key, group)
+		}
+	})
+
+	writer := &coalesceWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	forward()
+	c.Writer = writer.ResponseWriter
+
+	requestCoalescer.mu.Lock()
+	group.status = writer.Status()
+	group.header = writer.Header().Clone()
+	group.body = writer.body.Bytes()
+	group.shared = !writer.overflow && c.Request.Context().Err() == nil && !privateResponse(group.header)
+	group.finished = true
+	if group.expired {
+		requestCoalescer.remove(key, group)
+	}
+	requestCoalescer.mu.Unlock()
+	close(group.done)
+}
+
+// privateResponse 判断响应是否只属于发起请求的客户端：设置了 Cookie（如为匿名请求创建会话），
+// 或 Cache-Control 包含 private、no-store；这类响应只返回给首个请求，等待中的请求各自访问后端
+func privateResponse(header http.Header) bool {
+	if len(header.Values("Set-Cookie")) > 0 {
+		return true
+	}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(name, "private") || strings.EqualFold(name, "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// remove 移除请求组，调用方需持有锁
+func (co *coalescer) remove(key string, group *coalesceGroup) {
+	if co.groups[key] == group {
+		delete(co.groups, key)
+	}
+}
+
+// waitCoalesced 等待首个请求完成并写入共享的响应，响应不可共享时自行访问后端
+func waitCoalesced(c *gin.Context, group *coalesceGroup, forward func()) {
+	select {
+	case <-group.done:
+	case <-c.Request.Context().Done():
+		return
+	}
+	if !group.shared {
+		forward()
+		return
+	}
+
+	observability.CoalescedRequests.WithLabelValues(c.Request.URL.Path).Inc()
+	logger.Debug("Serving coalesced response",
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("status", group.status))
+	header := c.Writer.Header()
+	for name, values := range group.header {
+		// 保留网关为当前请求写入的响应头，如 X-Request-ID
+		if _, ok := header[name]; !ok {
+			header[name] = append([]string(nil), values...)
+		}
+	}
+	c.Writer.WriteHeader(group.status)
+	c.Writer.Write(group.body)
+}
+
+// coalesceWriter 在写出响应的同时记录响应体，供合并的请求共享
+type coalesceWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool // 响应体超过 maxCoalescedBodySize
+}
+
+func (w *coalesceWriter) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *coalesceWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// record 记录响应体，超过上限后不再记录
+func (w *coalesceWriter) record(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > maxCoalescedBodySize {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCoalesceRouter 创建为 /items 配置了请求合并窗口的路由，backendHits 统计后端收到的请求数
+func newCoalesceRouter(t *testing.T, window, backendDelay time.Duration) (*gin.Engine, *atomic.Int32) {
+	var backendHits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := backendHits.Add(1)
+		time.Sleep(backendDelay)
+		w.Header().Set("X-Backend-Hit", strconv.Itoa(int(n)))
+		if r.URL.Query().Has("session") {
+			w.Header().Set("Set-Cookie", "session="+strconv.Itoa(int(n)))
+		}
+		if cc := r.URL.Query().Get("cache-control"); cc != "" {
+			w.Header().Set("Cache-Control", cc)
+		}
+		w.Write([]byte("items for " + r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(backend.Close)
+
+	cfg := &config.Config{Routing: config.Routing{
+		LoadBalancer: "round_robin",
+		Coalesce:     map[string]config.Coalesce{"/items": {Window: window}},
+	}}
+
+	hp := newTestProxy(t, cfg)
+	router := gin.New()
+	router.Any("/items", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+	resetCoalescer()
+	return router, &backendHits
+}
+
+// resetCoalescer 清空上一个测试遗留在窗口期内的请求组
+func resetCoalescer() {
+	requestCoalescer.mu.Lock()
+	defer requestCoalescer.mu.Unlock()
+	clear(requestCoalescer.groups)
+}
+
+// getItems 发送一次 GET /items 请求
+func getItems(router *gin.Engine, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/items?page=1", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCoalesce_IdenticalGetsShareOneBackendCall(t *testing.T) {
+	router, backendHits := newCoalesceRouter(t, 200*time.Millisecond, 50*time.Millisecond)
+	before := testutil.ToFloat64(observability.CoalescedRequests.WithLabelValues("/items"))
+
+	const n = 10
+	responses := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = getItems(router, "Bearer alice")
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), backendHits.Load())
+	for _, w := range responses {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "items for Bearer alice", w.Body.String())
+		assert.Equal(t, "1", w.Header().Get("X-Backend-Hit"))
+	}
+	assert.Equal(t, before+n-1, testutil.ToFloat64(observability.CoalescedRequests.WithLabelValues("/items")))
+}
+
+func TestCoalesce_WindowOutlivesBackendCall(t *testing.T) {
+	router, backendHits := newCoalesceRouter(t, 200*time.Millisecond, 0)
+
+	// 首个请求已完成，但仍在窗口内，后续相同请求直接共享其响应
+	assert.Equal(t, http.StatusOK, getItems(router, "").Code)
+	assert.Equal(t, http.StatusOK, getItems(router, "").Code)
+	assert.Equal(t, int32(1), backendHits.Load())
+
+	// 窗口结束后重新访问后端
+	assert.Eventually(t, func() bool {
+		getItems(router, "")
+		return backendHits.Load() == 2
+	}, time.Second, 50*time.Millisecond)
+}
+
+func TestCoalesce_DifferentCredentialsAreNotShared(t *testing.T) {
+	router, backendHits := newCoalesceRouter(t, time.Second, 0)
+
+	assert.Equal(t, "items for Bearer alice", getItems(router, "Bearer alice").Body.String())
+	assert.Equal(t, "items for Bearer bob", getItems(router, "Bearer bob").Body.String())
+	assert.Equal(t, int32(2), backendHits.Load())
+}
+
+func TestCoalesce_NonGetRequestsAreNotCoalesced(t *testing.T) {
+	router, backendHits := newCoalesceRouter(t, time.Second, 0)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", "/items?page=1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, int32(3), backendHits.Load())
+}
+
+func TestCoalesce_PrivateResponsesAreNotShared(t *testing.T) {
+	router, backendHits := newCoalesceRouter(t, time.Second, 50*time.Millisecond)
+
+	// 后端为每个匿名请求创建会话，等待中的请求各自访问后端，不会拿到首个请求的 Cookie
+	const n = 5
+	cookies := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/items?session", nil))
+			cookies[i] = w.Header().Get("Set-Cookie")
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(n), backendHits.Load())
+	assert.Len(t, lo.Uniq(cookies), n)
+
+	for _, cc := range []string{"private", "no-store", "max-age=0, Private"} {
+		backendHits.Store(0)
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/items?cache-control="+url.QueryEscape(cc), nil))
+			assert.Equal(t, cc, w.Header().Get("Cache-Control"))
+		}
+		assert.Equal(t, int32(2), backendHits.Load(), cc)
+	}
+}
+
+func TestCoalesce_DifferentEnvsAreNotShared(t *testing.T) {
+	router, backendHits := newCoalesceRouter(t, time.Second, 0)
+
+	for _, env := range []string{"", "canary", ""} {
+		req := httptest.NewRequest("GET", "/items?page=1", nil)
+		if env != "" {
+			req.Header.Set("X-Env", env)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, int32(2), backendHits.Load())
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+)
+
+// defaultHeadersKey 上下文中保存本次转发要补充的默认请求头的键
+const defaultHeadersKey = "default_headers"
+
+// canonicalHeaders 将请求头名称转为规范形式，配置中的名称经 viper 解析后均为小写
+func canonicalHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	canonical := make(map[string]string, len(headers))
+	for name, value := range headers {
+		canonical[http.CanonicalHeaderKey(name)] = value
+	}
+	return canonical
+}
+
+// findDefaultHeaders 返回转发到 target 时要补充的默认请求头
+func findDefaultHeaders(global map[string]string, rules config.RoutingRules, target string) map[string]string {
+	for _, rule := range rules {
+		if rule.Target == target && len(rule.DefaultHeaders) > 0 {
+			return mergeDefaultHeaders(global, rule.DefaultHeaders)
+		}
+	}
+	return global
+}
+
+// mergeDefaultHeaders 合并全局默认请求头与规则配置的默认请求头，规则中的同名项优先
+func mergeDefaultHeaders(global, route map[string]string) map[string]string {
+	if len(route) == 0 {
+		return global
+	}
+	merged := make(map[string]string, len(global)+len(route))
+	for name, value := range global {
+		merged[name] = value
+	}
+	for name, value := range route {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	return merged
+}
+
+// setDefaultHeaders 记录本次转发要补充的默认请求头
+func setDefaultHeaders(c *gin.Context, headers map[string]string) {
+	c.Set(defaultHeadersKey, headers)
+}
+
+// defaultHeaders 返回本次转发要补充的默认请求头
+func defaultHeaders(c *gin.Context) map[string]string {
+	headers, _ := c.Value(defaultHeadersKey).(map[string]string)
+	return headers
+}
+
+// missingDefaultHeaders 对客户端请求未携带的每个默认请求头调用 set，值为空的项表示规则关闭了该请求头
+func missingDefaultHeaders(c *gin.Context, set func(name, value string)) {
+	for name, value := range defaultHeaders(c) {
+		if value != "" && c.Request.Header.Get(name) == "" {
+			set(name, value)
+		}
+	}
+}
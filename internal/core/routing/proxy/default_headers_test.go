@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveWithDefaultHeaders 按给定的默认请求头代理一次请求，返回后端收到的请求头
+func serveWithDefaultHeaders(t *testing.T, usePool bool, global, route map[string]string, clientHeaders http.Header) http.Header {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin", DefaultHeaders: global}}
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http", DefaultHeaders: route}}))
+
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	for name, values := range clientHeaders {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return <-received
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	// 配置经 viper 解析后名称均为小写
+	global := map[string]string{"user-agent": "mini-gateway/1.0", "x-gateway": "gw-1", "x-trace-source": "gateway"}
+
+	for _, mode := range []struct {
+		name    string
+		usePool bool
+	}{
+		{name: "direct"},
+		{name: "pool", usePool: true},
+	} {
+		t.Run(mode.name+" fills missing headers", func(t *testing.T) {
+			h := serveWithDefaultHeaders(t, mode.usePool, global, nil, nil)
+			assert.Equal(t, "mini-gateway/1.0", h.Get("User-Agent"))
+			assert.Equal(t, "gw-1", h.Get("X-Gateway"))
+		})
+		t.Run(mode.name+" keeps client headers", func(t *testing.T) {
+			h := serveWithDefaultHeaders(t, mode.usePool, global, nil, http.Header{"User-Agent": {"curl/8.0"}})
+			assert.Equal(t, "curl/8.0", h.Get("User-Agent"))
+			assert.Equal(t, "gw-1", h.Get("X-Gateway"))
+		})
+		t.Run(mode.name+" route overrides global", func(t *testing.T) {
+			route := map[string]string{"X-Gateway": "gw-orders", "x-trace-source": ""}
+			h := serveWithDefaultHeaders(t, mode.usePool, global, route, nil)
+			assert.Equal(t, "gw-orders", h.Get("X-Gateway"))
+			assert.Empty(t, h.Get("X-Trace-Source"), "an empty route value disables the global default")
+			assert.Equal(t, "mini-gateway/1.0", h.Get("User-Agent"))
+		})
+	}
+}
+
+func TestHTTPProxy_RefreshSettings_DefaultHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin", DefaultHeaders: map[string]string{"x-gateway": "gw-1"}}}
+	hp := newTestProxy(t, cfg)
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+	serve := func() http.Header {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/user", nil))
+		return <-received
+	}
+
+	assert.Equal(t, "gw-1", serve().Get("X-Gateway"))
+
+	hp.RefreshSettings(&config.Config{Routing: config.Routing{DefaultHeaders: map[string]string{"x-gateway": "gw-2"}}})
+	assert.Equal(t, "gw-2", serve().Get("X-Gateway"), "reloaded default headers apply to later requests")
+
+	hp.RefreshSettings(&config.Config{})
+	assert.Empty(t, serve().Get("X-Gateway"), "removed default headers are no longer added")
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 错误响应中的错误码
+const (
+	ErrCodeBadRequest          = "BAD_REQUEST"           // 请求不合法
+	ErrCodeRouteNotFound       = "ROUTE_NOT_FOUND"       // 未匹配到路由
+	ErrCodeFileNotFound        = "FILE_NOT_FOUND"        // 静态文件不存在
+	ErrCodeForbidden           = "FORBIDDEN"             // 禁止访问该资源
+	ErrCodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"    // 路由不允许该请求方法
+	ErrCodeNoTarget            = "NO_AVAILABLE_TARGET"   // 无可用目标
+	ErrCodeUnavailable         = "SERVICE_UNAVAILABLE"   // 网关暂不提供服务
+	ErrCodeBadGateway          = "BAD_GATEWAY"           // 上游请求失败
+	ErrCodeProtocolMismatch    = "PROTOCOL_MISMATCH"     // 上游协议与路由不匹配
+	ErrCodeResponseNotFiltered = "RESPONSE_NOT_FILTERED" // 配置了响应字段过滤但上游响应无法过滤
+	ErrCodeQuorumNotReached    = "QUORUM_NOT_REACHED"    // 扇出请求未达到法定数量
+	ErrCodeCircuitOpen         = "CIRCUIT_OPEN"          // 目标的熔断器打开或上游超时，且路由未配置降级响应
+	ErrCodeInternal            = "INTERNAL_ERROR"        // 网关内部错误
+)
+
+// requestIDHeader 请求 ID 的 HTTP 头名称，由请求 ID 中间件写入请求头
+const requestIDHeader = "X-Request-ID"
+
+// ErrorResponse 网关统一的错误响应格式
+type ErrorResponse struct {
+	Code      string `json:"code"`                // 错误码，如 NO_AVAILABLE_TARGET
+	Message   string `json:"message"`             // 错误说明
+	RequestID string `json:"requestId,omitempty"` // 请求 ID，便于与日志和追踪关联
+	Timestamp string `json:"timestamp"`           // 错误发生时间，RFC3339 格式
+}
+
+// NewErrorResponse 创建错误响应
+func NewErrorResponse(code, message, requestID string) ErrorResponse {
+	return ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// WriteError 以统一格式写入错误响应
+func WriteError(c *gin.Context, status int, code, message string) {
+	requestID := c.GetString("request_id")
+	if requestID == "" {
+		requestID = c.GetHeader(requestIDHeader)
+	}
+	c.JSON(status, NewErrorResponse(code, message, requestID))
+}
+
+// AbortWithError 以统一格式写入错误响应并终止后续处理
+func AbortWithError(c *gin.Context, status int, code, message string) {
+	WriteError(c, status, code, message)
+	c.Abort()
+}
+
+// writeHTTPError 在无法获取 gin 上下文的场景（如 ReverseProxy、gRPC 网关的错误处理）以统一格式写入错误响应
+func writeHTTPError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(NewErrorResponse(code, message, r.Header.Get(requestIDHeader)))
+}
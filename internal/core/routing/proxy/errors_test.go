@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeErrorResponse 解析统一格式的错误响应
+func decodeErrorResponse(t *testing.T, w *httptest.ResponseRecorder) ErrorResponse {
+	require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	_, err := time.Parse(time.RFC3339, resp.Timestamp)
+	assert.NoError(t, err, "timestamp should be RFC3339")
+	return resp
+}
+
+func TestCreateErrorHandler_WritesJSON(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+
+	// serveHTTPRoute 不经过请求 ID 中间件，通过直接代理访问已关闭的后端
+	w := serveHTTPRoute(t, backend.URL, false, false)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	resp := decodeErrorResponse(t, w)
+	assert.Equal(t, ErrCodeBadGateway, resp.Code)
+	assert.Equal(t, "Backend service unavailable", resp.Message)
+}
+
+func TestWriteHTTPError_IncludesRequestID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api", nil)
+	r.Header.Set(requestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+
+	writeHTTPError(w, r, http.StatusBadGateway, ErrCodeProtocolMismatch, "mismatch")
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	resp := decodeErrorResponse(t, w)
+	assert.Equal(t, ErrorResponse{Code: ErrCodeProtocolMismatch, Message: "mismatch", RequestID: "req-123", Timestamp: resp.Timestamp}, resp)
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// defaultFallbackContentType 降级响应未指定类型时使用的 Content-Type
+const defaultFallbackContentType = "application/json; charset=utf-8"
+
+// cachedFallbackKey 上下文中保存返回最近一次成功响应的函数的键
+const cachedFallbackKey = "cached_fallback"
+
+// CachedFallback 写入请求最近一次成功的响应，没有可用的响应时返回 false
+type CachedFallback func(c *gin.Context) bool
+
+// SetCachedFallback 设置请求的 CachedFallback，由缓存中间件在转发前设置，供降级策略 cached 使用
+func SetCachedFallback(c *gin.Context, fallback CachedFallback) {
+	c.Set(cachedFallbackKey, fallback)
+}
+
+// writeCachedFallback 写入缓存中保留的最近一次成功响应，没有时返回 false
+func writeCachedFallback(c *gin.Context) bool {
+	value, ok := c.Get(cachedFallbackKey)
+	if !ok {
+		return false
+	}
+	fallback, ok := value.(CachedFallback)
+	return ok && fallback(c)
+}
+
+// WriteFallback 按路由配置的降级策略写入降级响应，路由未配置降级响应或策略为 error 时返回 false，
+// 由调用方返回默认错误；策略为 cached 但没有可用的缓存响应时按 static 处理
+func WriteFallback(c *gin.Context, rules config.RoutingRules) bool {
+	fb, ok := rules.Fallback()
+	if !ok {
+		return false
+	}
+
+	switch fb.Strategy {
+	case config.FallbackStrategyError:
+		return false
+	case config.FallbackStrategyCached:
+		if writeCachedFallback(c) {
+			logger.Info("Serving cached fallback response",
+				zap.String("path", c.Request.URL.Path))
+			return true
+		}
+		if !fb.HasStatic() {
+			return false
+		}
+	}
+
+	if fb.RedirectURL != "" {
+		status := fb.Status
+		if status == 0 {
+			status = http.StatusFound
+		}
+		c.Redirect(status, fb.RedirectURL)
+	} else {
+		status := fb.Status
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		contentType := fb.ContentType
+		if contentType == "" {
+			contentType = defaultFallbackContentType
+		}
+		c.Data(status, contentType, []byte(fb.Body))
+	}
+	logger.Info("Serving route fallback response",
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("status", c.Writer.Status()),
+		zap.String("redirectURL", fb.RedirectURL))
+	return true
+}
+
+// FindRouteRules 返回请求所属路由的规则，依次按路由引擎记录的路由模式、gin 注册的路由模式与请求路径匹配
+func FindRouteRules(c *gin.Context, rules map[string]config.RoutingRules) (config.RoutingRules, bool) {
+	if pattern, ok := MatchedRoute(c); ok {
+		if route, exists := rules[pattern]; exists {
+			return route, true
+		}
+	}
+	if route, ok := rules[c.FullPath()]; ok {
+		return route, true
+	}
+	route, ok := rules[c.Request.URL.Path]
+	return route, ok
+}
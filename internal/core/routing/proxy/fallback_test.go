@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveNoTarget 在无可用目标的情况下处理一次请求
+func serveNoTarget(t *testing.T, rules config.RoutingRules) *httptest.ResponseRecorder {
+	hp := newTestProxy(t, nil, withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		return "", ""
+	}))
+	router := gin.New()
+	router.GET("/api/v1/order", hp.CreateHTTPHandler(rules))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/order", nil))
+	return w
+}
+
+func TestHandleNoTarget_StaticFallback(t *testing.T) {
+	rules := config.RoutingRules{
+		{Target: "http://a"},
+		{Target: "http://b", Fallback: config.Fallback{Body: `{"message":"under maintenance"}`}},
+	}
+	w := serveNoTarget(t, rules)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"message":"under maintenance"}`, w.Body.String())
+}
+
+func TestHandleNoTarget_CustomStatusAndContentType(t *testing.T) {
+	rules := config.RoutingRules{{
+		Target:   "http://a",
+		Fallback: config.Fallback{Status: http.StatusOK, Body: "<h1>maintenance</h1>", ContentType: "text/html"},
+	}}
+	w := serveNoTarget(t, rules)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<h1>maintenance</h1>", w.Body.String())
+}
+
+func TestHandleNoTarget_RedirectFallback(t *testing.T) {
+	rules := config.RoutingRules{{
+		Target:   "http://a",
+		Fallback: config.Fallback{RedirectURL: "https://status.example.com"},
+	}}
+	w := serveNoTarget(t, rules)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://status.example.com", w.Header().Get("Location"))
+}
+
+func TestHandleNoTarget_WithoutFallback(t *testing.T) {
+	w := serveNoTarget(t, config.RoutingRules{{Target: "http://a"}})
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), ErrCodeNoTarget)
+}
@@ -0,0 +1,243 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// errTargetRejected 目标的熔断器未放行请求
+var errTargetRejected = errors.New("target rejected by circuit breaker")
+
+// fanOutResult 单个目标的扇出响应
+type fanOutResult struct {
+	target  string
+	status  int
+	headers [][2]string
+	body    []byte
+	err     error
+}
+
+// key 返回用于判断响应是否一致的键
+func (r *fanOutResult) key() string {
+	return strconv.Itoa(r.status) + "\n" + string(r.body)
+}
+
+// getFanOutRule 获取当前路由的扇出配置，请求方法不在扇出范围内时按普通请求转发
+func getFanOutRule(c *gin.Context) (config.FanOut, bool) {
+	fanOuts := config.GetConfig().Routing.FanOut
+	if len(fanOuts) == 0 {
+		return config.FanOut{}, false
+	}
+	rule, ok := fanOuts[c.FullPath()]
+	if !ok {
+		rule, ok = fanOuts[c.Request.URL.Path]
+	}
+	return rule, ok && rule.Targets > 0 && rule.Quorum > 0 && rule.AllowsMethod(c.Request.Method)
+}
+
+// defaultErrorPassthroughBodySize 未配置时透传错误响应体的最大字节数
+const defaultErrorPassthroughBodySize = 4096
+
+// getErrorPassthroughRule 获取当前路由的错误响应透传配置
+func getErrorPassthroughRule(c *gin.Context) (config.ErrorPassthrough, bool) {
+	passthroughs := config.GetConfig().Routing.ErrorPassthrough
+	if len(passthroughs) == 0 {
+		return config.ErrorPassthrough{}, false
+	}
+	rule, ok := passthroughs[c.FullPath()]
+	if !ok {
+		rule, ok = passthroughs[c.Request.URL.Path]
+	}
+	if rule.MaxBodySize <= 0 {
+		rule.MaxBodySize = defaultErrorPassthroughBodySize
+	}
+	return rule, ok
+}
+
+// writeUpstreamError 透传上游错误响应，响应体超过上限时截断
+func writeUpstreamError(c *gin.Context, result *fanOutResult, maxBodySize int) {
+	body := result.body
+	if len(body) > maxBodySize {
+		body = body[:maxBodySize]
+	}
+	for _, header := range result.headers {
+		// 响应体可能被截断，长度由 gin 重新计算
+		if http.CanonicalHeaderKey(header[0]) == "Content-Length" {
+			continue
+		}
+		c.Header(header[0], header[1])
+	}
+	c.Status(result.status)
+	c.Writer.Write(body)
+}
+
+// selectFanOutTargets 按权重从高到低选取 n 个目标
+func selectFanOutTargets(rules config.RoutingRules, n int) config.RoutingRules {
+	selected := append(config.RoutingRules(nil), rules...)
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Weight > selected[j].Weight
+	})
+	if n < len(selected) {
+		selected = selected[:n]
+	}
+	return selected
+}
+
+// proxyFanOut 将请求并行转发到多个目标，返回首个达到法定数量的一致成功响应
+func (hp *HTTPProxy) proxyFanOut(c *gin.Context, rules config.RoutingRules, fanOut config.FanOut) {
+	_, span := httpTracer.Start(c.Request.Context(), "HTTPProxy.Handle.FanOut",
+		trace.WithAttributes(
+			attribute.String("http.method", c.Request.Method),
+			attribute.String("http.path", c.Request.URL.Path),
+			attribute.Int("fanout.targets", fanOut.Targets),
+			attribute.Int("fanout.quorum", fanOut.Quorum),
+		))
+	defer span.End()
+
+	targets := selectFanOutTargets(excludeDrainedRules(excludeEjectedRules(rules)), fanOut.Targets)
+	if len(targets) < fanOut.Quorum {
+		logger.Warn("Not enough targets for fan-out quorum",
+			zap.Int("available", len(targets)),
+			zap.Int("quorum", fanOut.Quorum))
+		handleNoTarget(c, span, rules, getEnvFromHeader(c))
+		return
+	}
+
+	// 请求体只能读取一次，先缓存后为每个目标重建
+	body, _ := c.GetRawData()
+	defaultHeaders := hp.proxySettings().defaultHeaders
+	results := make(chan *fanOutResult, len(targets))
+	for _, rule := range targets {
+		host, err := normalizeTarget(rule.Target)
+		if err != nil {
+			results <- &fanOutResult{target: rule.Target, err: err}
+			continue
+		}
+		client, err := hp.httpPool.GetClient(rule.Target)
+		if err != nil {
+			results <- &fanOutResult{target: rule.Target, err: err}
+			continue
+		}
+
+		// 各目标的请求并行发出，熔断器逐个判断目标是否允许转发
+		done, ok := AdmitTarget(c, rule.Target)
+		if !ok {
+			results <- &fanOutResult{target: rule.Target, err: errTargetRejected}
+			continue
+		}
+
+		req := fasthttp.AcquireRequest()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		setRequestTransform(c, rule.RequestTransform)
+		setDefaultHeaders(c, mergeDefaultHeaders(defaultHeaders, rule.DefaultHeaders))
+		hp.prepareFastHTTPRequest(c, req, host, rule.Env)
+		go doFanOutRequest(client, req, rule.Target, done, results)
+	}
+
+	counts := make(map[string]int, len(targets))
+	var upstreamError *fanOutResult // 最近一次上游返回的 5xx 响应，用于错误透传
+	for i := 0; i < len(targets); i++ {
+		result := <-results
+		success := recordFanOutResult(result)
+		if !success {
+			if result.err == nil {
+				upstreamError = result
+			}
+			logger.Warn("Fan-out request failed",
+				zap.String("target", result.target),
+				zap.Int("status", result.status),
+				zap.Error(result.err))
+			continue
+		}
+
+		key := result.key()
+		counts[key]++
+		if counts[key] >= fanOut.Quorum {
+			for _, header := range result.headers {
+				c.Header(header[0], header[1])
+			}
+			c.Status(result.status)
+			c.Writer.Write(result.body)
+			// 其余目标的响应不再影响结果，但仍需计入健康统计，否则较慢的故障目标永远不会被剔除
+			hp.drains.Add(1)
+			go func(remaining int) {
+				defer hp.drains.Done()
+				drainFanOutResults(results, remaining)
+			}(len(targets) - i - 1)
+			span.SetAttributes(attribute.String("proxy.target", result.target))
+			c.Set("proxy_target", result.target)
+			span.SetStatus(codes.Ok, "Fan-out quorum reached")
+			logger.Info("Fan-out quorum reached",
+				zap.String("path", c.Request.URL.Path),
+				zap.Int("quorum", fanOut.Quorum),
+				zap.Int("targets", len(targets)))
+			return
+		}
+	}
+
+	span.SetStatus(codes.Error, "Fan-out quorum not reached")
+	logger.Error("Fan-out quorum not reached",
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("quorum", fanOut.Quorum),
+		zap.Int("targets", len(targets)))
+	if passthrough, ok := getErrorPassthroughRule(c); ok && upstreamError != nil {
+		writeUpstreamError(c, upstreamError, passthrough.MaxBodySize)
+		return
+	}
+	WriteError(c, http.StatusBadGateway, ErrCodeQuorumNotReached, "Quorum not reached")
+}
+
+// recordFanOutResult 将单个目标的扇出结果计入健康统计，返回该结果是否成功
+func recordFanOutResult(result *fanOutResult) bool {
+	success := result.err == nil && result.status < http.StatusInternalServerError
+	updateRequestCount(result.target, success)
+	return success
+}
+
+// drainFanOutResults 在返回响应后接收剩余 n 个目标的结果并计入健康统计
+func drainFanOutResults(results <-chan *fanOutResult, n int) {
+	for i := 0; i < n; i++ {
+		recordFanOutResult(<-results)
+	}
+}
+
+// doFanOutRequest 向单个目标发送请求，向熔断器报告上游状态码（done），并将复制后的响应写入结果通道
+func doFanOutRequest(client *fasthttp.HostClient, req *fasthttp.Request, target string, done func(status int), results chan<- *fanOutResult) {
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	result := &fanOutResult{target: target}
+	start := time.Now()
+	err := client.Do(req, resp)
+	observability.UpstreamDuration.WithLabelValues(target).Observe(time.Since(start).Seconds())
+	if err != nil {
+		done(0)
+		result.err = err
+		results <- result
+		return
+	}
+
+	result.status = resp.StatusCode()
+	done(result.status)
+	result.body = append([]byte(nil), resp.Body()...)
+	resp.Header.VisitAll(func(key, value []byte) {
+		result.headers = append(result.headers, [2]string{string(key), string(value)})
+	})
+	results <- result
+}
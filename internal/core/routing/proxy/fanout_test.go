@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFanOutBackend 创建返回固定响应体的模拟后端
+func newFanOutBackend(t *testing.T, status int, body string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// serveFanOut 使用给定规则与扇出配置处理一次请求
+func serveFanOut(t *testing.T, rules config.RoutingRules, fanOut config.FanOut) *httptest.ResponseRecorder {
+	return serveFanOutWithPassthrough(t, rules, fanOut, nil)
+}
+
+// serveFanOutWithPassthrough 使用给定规则、扇出与错误透传配置处理一次请求
+func serveFanOutWithPassthrough(t *testing.T, rules config.RoutingRules, fanOut config.FanOut, passthrough map[string]config.ErrorPassthrough) *httptest.ResponseRecorder {
+	cfg := &config.Config{
+		Routing: config.Routing{
+			LoadBalancer:     "round_robin",
+			FanOut:           map[string]config.FanOut{"/fanout": fanOut},
+			ErrorPassthrough: passthrough,
+		},
+	}
+
+	hp := newTestProxy(t, cfg)
+	router := gin.New()
+	router.GET("/fanout", hp.CreateHTTPHandler(rules))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fanout", nil))
+	return w
+}
+
+func TestProxyFanOut_QuorumReached(t *testing.T) {
+	a := newFanOutBackend(t, http.StatusOK, "v1")
+	b := newFanOutBackend(t, http.StatusOK, "v2")
+	c := newFanOutBackend(t, http.StatusOK, "v1")
+
+	rules := config.RoutingRules{
+		{Target: a.URL, Weight: 10},
+		{Target: b.URL, Weight: 10},
+		{Target: c.URL, Weight: 10},
+	}
+	w := serveFanOut(t, rules, config.FanOut{Targets: 3, Quorum: 2})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v1", w.Body.String())
+}
+
+func TestProxyFanOut_QuorumNotReached(t *testing.T) {
+	a := newFanOutBackend(t, http.StatusOK, "v1")
+	b := newFanOutBackend(t, http.StatusOK, "v2")
+	c := newFanOutBackend(t, http.StatusInternalServerError, "v1")
+
+	rules := config.RoutingRules{
+		{Target: a.URL, Weight: 10},
+		{Target: b.URL, Weight: 10},
+		{Target: c.URL, Weight: 10},
+	}
+	w := serveFanOut(t, rules, config.FanOut{Targets: 3, Quorum: 2})
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "Quorum not reached")
+}
+
+func TestProxyFanOut_ErrorPassthrough(t *testing.T) {
+	a := newFanOutBackend(t, http.StatusServiceUnavailable, `{"error":"database is down"}`)
+	b := newFanOutBackend(t, http.StatusServiceUnavailable, `{"error":"database is down"}`)
+
+	rules := config.RoutingRules{
+		{Target: a.URL, Weight: 10},
+		{Target: b.URL, Weight: 10},
+	}
+	w := serveFanOutWithPassthrough(t, rules, config.FanOut{Targets: 2, Quorum: 2},
+		map[string]config.ErrorPassthrough{"/fanout": {MaxBodySize: 1024}})
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, `{"error":"database is down"}`, w.Body.String())
+}
+
+func TestProxyFanOut_ErrorPassthroughTruncated(t *testing.T) {
+	a := newFanOutBackend(t, http.StatusInternalServerError, "stack trace: something went wrong")
+
+	rules := config.RoutingRules{{Target: a.URL, Weight: 10}}
+	w := serveFanOutWithPassthrough(t, rules, config.FanOut{Targets: 1, Quorum: 1},
+		map[string]config.ErrorPassthrough{"/fanout": {MaxBodySize: 11}})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "stack trace", w.Body.String())
+}
+
+func TestProxyFanOut_NotEnoughTargets(t *testing.T) {
+	a := newFanOutBackend(t, http.StatusOK, "v1")
+
+	rules := config.RoutingRules{{Target: a.URL, Weight: 10}}
+	w := serveFanOut(t, rules, config.FanOut{Targets: 3, Quorum: 2})
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// TestProxyFanOut_AdmitsEachTarget 验证扇出在发出每个目标的请求前经由目标准入函数判断，并报告各目标的上游状态码
+func TestProxyFanOut_AdmitsEachTarget(t *testing.T) {
+	a := newFanOutBackend(t, http.StatusOK, "v1")
+	b := newFanOutBackend(t, http.StatusOK, "v1")
+	c := newFanOutBackend(t, http.StatusInternalServerError, "down")
+	cfg := &config.Config{Routing: config.Routing{
+		LoadBalancer: "round_robin",
+		FanOut:       map[string]config.FanOut{"/fanout": {Targets: 3, Quorum: 2}},
+	}}
+	hp := newTestProxy(t, cfg)
+
+	var mu sync.Mutex
+	reported := make(map[string]int)
+	router := gin.New()
+	// 模拟熔断器：拒绝 b，记录其他目标报告的状态码
+	router.Use(func(c *gin.Context) {
+		SetTargetAdmission(c, func(target string) (func(int), bool) {
+			if target == b.URL {
+				return nil, false
+			}
+			return func(status int) {
+				mu.Lock()
+				defer mu.Unlock()
+				reported[target] = status
+			}, true
+		})
+	})
+	router.GET("/fanout", hp.CreateHTTPHandler(config.RoutingRules{
+		{Target: a.URL, Weight: 10},
+		{Target: b.URL, Weight: 10},
+		{Target: c.URL, Weight: 10},
+	}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fanout", nil))
+
+	assert.Equal(t, http.StatusBadGateway, w.Code, "被拒绝的目标不计入法定数量")
+	assert.Contains(t, w.Body.String(), ErrCodeQuorumNotReached)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reported) == 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{a.URL: http.StatusOK, c.URL: http.StatusInternalServerError}, reported)
+}
+
+// TestProxyFanOut_UnsafeMethodNotFannedOut 验证未在 methods 中列出的非安全方法只转发到单个目标
+func TestProxyFanOut_UnsafeMethodNotFannedOut(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	backend := func() *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits++
+			mu.Unlock()
+			w.Write([]byte("ok"))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	rules := config.RoutingRules{
+		{Target: backend().URL, Weight: 10},
+		{Target: backend().URL, Weight: 10},
+	}
+
+	tests := []struct {
+		name     string
+		methods  []string
+		wantHits int
+	}{
+		{"default safe methods only", nil, 1},
+		{"explicitly allowed", []string{"post"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			hits = 0
+			mu.Unlock()
+			cfg := &config.Config{Routing: config.Routing{
+				LoadBalancer: "round_robin",
+				FanOut:       map[string]config.FanOut{"/fanout": {Targets: 2, Quorum: 2, Methods: tt.methods}},
+			}}
+			hp := newTestProxy(t, cfg)
+			router := gin.New()
+			router.POST("/fanout", hp.CreateHTTPHandler(rules))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/fanout", nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantHits, hits)
+		})
+	}
+}
+
+// TestProxyFanOut_DrainsRemainingResults 验证达到法定数量后，较慢目标的结果仍计入健康统计
+func TestProxyFanOut_DrainsRemainingResults(t *testing.T) {
+	fast := newFanOutBackend(t, http.StatusOK, "v1")
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(slow.Close)
+
+	var mu sync.Mutex
+	recorded := make(map[string]bool)
+	original := updateRequestCount
+	updateRequestCount = func(target string, success bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		recorded[target] = success
+	}
+	t.Cleanup(func() { updateRequestCount = original })
+
+	rules := config.RoutingRules{
+		{Target: fast.URL, Weight: 20},
+		{Target: slow.URL, Weight: 10},
+	}
+	w := serveFanOut(t, rules, config.FanOut{Targets: 2, Quorum: 1})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v1", w.Body.String())
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(recorded) == 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]bool{fast.URL: true, slow.URL: false}, recorded)
+}
+
+func TestSelectFanOutTargets(t *testing.T) {
+	rules := config.RoutingRules{
+		{Target: "a", Weight: 10},
+		{Target: "b", Weight: 50},
+		{Target: "c", Weight: 30},
+	}
+	selected := selectFanOutTargets(rules, 2)
+	assert.Len(t, selected, 2)
+	assert.Equal(t, "b", selected[0].Target)
+	assert.Equal(t, "c", selected[1].Target)
+	assert.Equal(t, "a", rules[0].Target, "original rules should not be reordered")
+}
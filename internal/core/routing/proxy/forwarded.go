@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 转发给上游的标准代理请求头
+const (
+	headerForwardedFor   = "X-Forwarded-For"
+	headerForwardedProto = "X-Forwarded-Proto"
+	headerForwardedHost  = "X-Forwarded-Host"
+	headerRealIP         = "X-Real-IP"
+)
+
+// forwardedHeaders 根据客户端请求计算出的代理请求头
+type forwardedHeaders struct {
+	priorFor string // 请求自带的 X-Forwarded-For 链，不信任时为空
+	peer     string // 与网关直接相连的对端地址
+	proto    string
+	host     string
+	realIP   string
+}
+
+// newForwardedHeaders 计算转发给上游的代理请求头，需在改写请求的 Host 之前调用
+// trust 为 true 时沿用请求自带的 X-Forwarded-For 链、X-Forwarded-Proto、X-Forwarded-Host 与 X-Real-IP；
+// 否则丢弃这些可被客户端伪造的值，以网关看到的连接信息为准
+func newForwardedHeaders(r *http.Request, trust bool) forwardedHeaders {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		peer = host
+	}
+	f := forwardedHeaders{peer: peer, proto: "http", host: r.Host, realIP: peer}
+	if r.TLS != nil {
+		f.proto = "https"
+	}
+	if !trust {
+		return f
+	}
+
+	if prior := r.Header.Values(headerForwardedFor); len(prior) > 0 {
+		f.priorFor = strings.Join(prior, ", ")
+		// 链中最左侧的地址是最初的客户端
+		if first, _, _ := strings.Cut(f.priorFor, ","); strings.TrimSpace(first) != "" {
+			f.realIP = strings.TrimSpace(first)
+		}
+	}
+	if proto := r.Header.Get(headerForwardedProto); proto != "" {
+		f.proto = proto
+	}
+	if host := r.Header.Get(headerForwardedHost); host != "" {
+		f.host = host
+	}
+	if realIP := r.Header.Get(headerRealIP); realIP != "" {
+		f.realIP = realIP
+	}
+	return f
+}
+
+// forwardedFor 返回在已有链之后追加对端地址的 X-Forwarded-For
+func (f forwardedHeaders) forwardedFor() string {
+	if f.priorFor == "" {
+		return f.peer
+	}
+	return f.priorFor + ", " + f.peer
+}
+
+// applyToHTTP 写入直接代理模式的请求头
+// httputil.ReverseProxy 会自行在 X-Forwarded-For 之后追加对端地址，这里只保留或清除已有的链
+func (f forwardedHeaders) applyToHTTP(h http.Header) {
+	if f.priorFor == "" {
+		h.Del(headerForwardedFor)
+	} else {
+		h.Set(headerForwardedFor, f.priorFor)
+	}
+	h.Set(headerForwardedProto, f.proto)
+	h.Set(headerForwardedHost, f.host)
+	h.Set(headerRealIP, f.realIP)
+}
+
+// applyToFastHTTP 写入连接池模式的请求头，覆盖从客户端请求复制过来的值
+func (f forwardedHeaders) applyToFastHTTP(h *fasthttp.RequestHeader) {
+	h.Set(headerForwardedFor, f.forwardedFor())
+	h.Set(headerForwardedProto, f.proto)
+	h.Set(headerForwardedHost, f.host)
+	h.Set(headerRealIP, f.realIP)
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveForwarded 代理一次带有伪造转发头的请求，返回后端收到的请求头
+func serveForwarded(t *testing.T, usePool, trust bool) http.Header {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin", TrustForwarded: trust}}
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+
+	req := httptest.NewRequest("GET", "http://client.example.com/api/v1/user", nil)
+	req.RemoteAddr = "10.0.0.2:5678"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "public.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return <-received
+}
+
+func TestForwardedHeaders(t *testing.T) {
+	for _, mode := range []struct {
+		name    string
+		usePool bool
+	}{
+		{name: "direct"},
+		{name: "pool", usePool: true},
+	} {
+		t.Run(mode.name+" trusted", func(t *testing.T) {
+			h := serveForwarded(t, mode.usePool, true)
+			assert.Equal(t, "203.0.113.7, 10.0.0.1, 10.0.0.2", h.Get("X-Forwarded-For"), "peer is appended to the existing chain")
+			assert.Equal(t, "https", h.Get("X-Forwarded-Proto"))
+			assert.Equal(t, "public.example.com", h.Get("X-Forwarded-Host"))
+			assert.Equal(t, "203.0.113.7", h.Get("X-Real-IP"), "the left-most address is the original client")
+		})
+		t.Run(mode.name+" untrusted", func(t *testing.T) {
+			h := serveForwarded(t, mode.usePool, false)
+			assert.Equal(t, "10.0.0.2", h.Get("X-Forwarded-For"), "spoofed chain is dropped")
+			assert.Equal(t, "http", h.Get("X-Forwarded-Proto"))
+			assert.Equal(t, "client.example.com", h.Get("X-Forwarded-Host"))
+			assert.Equal(t, "10.0.0.2", h.Get("X-Real-IP"))
+		})
+	}
+}
+
+func TestHTTPProxy_RefreshSettings_TrustForwarded(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	hp := newTestProxy(t, &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}})
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+	serve := func() http.Header {
+		req := httptest.NewRequest("GET", "/api/v1/user", nil)
+		req.RemoteAddr = "10.0.0.2:5678"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return <-received
+	}
+
+	assert.Equal(t, "10.0.0.2", serve().Get("X-Forwarded-For"), "untrusted initially")
+
+	hp.RefreshSettings(&config.Config{Routing: config.Routing{TrustForwarded: true}})
+	assert.Equal(t, "203.0.113.7, 10.0.0.2", serve().Get("X-Forwarded-For"), "reloaded setting trusts the client chain")
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/penwyp/mini-gateway/config"
+)
+
+// defaultGRPCMountPattern 默认按规则路径原样挂载 gRPC 路由
+const defaultGRPCMountPattern = "{path}"
+
+// grpcMountPath 根据配置的挂载模式计算 gRPC 路由在分组内的挂载路径
+func grpcMountPath(cfg *config.Config, route string) string {
+	pattern := cfg.GRPC.MountPattern
+	if pattern == "" {
+		pattern = defaultGRPCMountPattern
+	}
+	mounted := strings.ReplaceAll(pattern, "{path}", strings.TrimSuffix(route, "/"))
+	if mounted == "" {
+		return "/"
+	}
+	return mounted
+}
+
+// FindGRPCRouteConflicts 检测 gRPC 路由（含前缀）与 HTTP 路由的重叠，返回冲突描述，key 为 gRPC 规则路径
+func FindGRPCRouteConflicts(cfg *config.Config) map[string]string {
+	conflicts := make(map[string]string)
+	httpRules := cfg.Routing.GetHTTPRules()
+	for route := range cfg.Routing.GetGrpcRules() {
+		grpcPath := SingleJoiningSlash(cfg.GRPC.Prefix, grpcMountPath(cfg, route))
+		for httpPath := range httpRules {
+			if routePatternsOverlap(splitRoutePattern(grpcPath), splitRoutePattern(httpPath)) {
+				conflicts[route] = fmt.Sprintf("gRPC route %q (mounted at %q) overlaps HTTP route %q", route, grpcPath, httpPath)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// splitRoutePattern 将路由模式拆分为路径段
+func splitRoutePattern(pattern string) []string {
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
+
+// routePatternsOverlap 判断两个 gin 风格路由模式是否可能匹配同一请求路径
+// :param 匹配任意单个路径段，*catchAll 匹配剩余的任意路径段
+func routePatternsOverlap(a, b []string) bool {
+	for i := 0; ; i++ {
+		if i == len(a) || i == len(b) {
+			return len(a) == len(b) ||
+				(i < len(a) && strings.HasPrefix(a[i], "*")) ||
+				(i < len(b) && strings.HasPrefix(b[i], "*"))
+		}
+		if strings.HasPrefix(a[i], "*") || strings.HasPrefix(b[i], "*") {
+			return true
+		}
+		if strings.HasPrefix(a[i], ":") || strings.HasPrefix(b[i], ":") || a[i] == b[i] {
+			continue
+		}
+		return false
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -37,7 +38,7 @@ var newGRPCClient = func(target string, dialOpts ...grpc.DialOption) (*grpc.Clie
 
 var registerHelloServiceHandlerFunc = proto.RegisterHelloServiceHandler
 
-// SetupGRPCProxy 配置 HTTP 到 gRPC 的反向代理
+// SetupGRPCProxy 配置 HTTP 到 gRPC 的反向代理，路由挂载在传入的路由器上
 func SetupGRPCProxy(cfg *config.Config, r gin.IRouter) {
 	mux := runtime.NewServeMux(
 		runtime.WithErrorHandler(httpErrorHandler()),
@@ -47,10 +48,24 @@ func SetupGRPCProxy(cfg *config.Config, r gin.IRouter) {
 	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()), // 本地测试用，生产环境需启用 TLS
 		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
 	}
 
+	// 每个目标的连接，gRPC-Web 请求直接通过连接转发给后端
+	conns := make(map[string]grpc.ClientConnInterface)
+
+	// 检测与 HTTP 路由重叠的 gRPC 路由，冲突的路由不予挂载
+	conflicts := FindGRPCRouteConflicts(cfg)
+
 	// 遍历 gRPC 路由规则
 	for route, rules := range cfg.Routing.GetGrpcRules() {
+		if conflict, ok := conflicts[route]; ok {
+			logger.Error("Skipping gRPC route that conflicts with HTTP route",
+				zap.String("path", route),
+				zap.String("conflict", conflict))
+			continue
+		}
+
 		for _, rule := range rules {
 			if rule.Protocol != "grpc" {
 				continue
@@ -72,13 +87,21 @@ func SetupGRPCProxy(cfg *config.Config, r gin.IRouter) {
 				conn.Close()
 				continue
 			}
+			conns[rule.Target] = conn
 			logger.Info("Successfully registered gRPC service handler",
 				zap.String("path", route),
 				zap.String("target", rule.Target))
 		}
 
+		// 配置了 SSE 桥接的路由直接转发到服务端流式方法，不经过 grpc-gateway
+		if rule, ok := firstGRPCRule(rules); ok && rule.SSE.Method != "" {
+			mountSSEBridge(r, cfg, route, rule, conns[rule.Target])
+			continue
+		}
+
 		// 处理带有上下文传播的传入请求
-		r.Any(route, func(c *gin.Context) {
+		mountPath := grpcMountPath(cfg, route)
+		r.Any(mountPath, func(c *gin.Context) {
 			ctx, span := grpcTracer.Start(c.Request.Context(), "GRPCProxy.Handle",
 				trace.WithAttributes(
 					attribute.String("http.method", c.Request.Method),
@@ -88,6 +111,7 @@ func SetupGRPCProxy(cfg *config.Config, r gin.IRouter) {
 			defer span.End()
 
 			span.SetAttributes(attribute.String("grpc.routing.path", c.Request.URL.Path))
+			SetMatchedRoute(c, route)
 
 			req := c.Request
 			// 规范化 URL，避免不必要的重定向
@@ -110,14 +134,6 @@ func SetupGRPCProxy(cfg *config.Config, r gin.IRouter) {
 				req.URL.Path = adjustedPath
 			}
 
-			// 将元数据传播到请求上下文中
-			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("request-id", c.GetHeader("X-Request-ID")))
-			req = req.WithContext(ctx)
-
-			start := time.Now()
-			recorder := &statusRecorder{ResponseWriter: c.Writer, Status: http.StatusOK}
-			mux.ServeHTTP(recorder, req)
-
 			// 从路由规则中识别目标
 			target := ""
 			for _, rule := range cfg.Routing.GetGrpcRules()[route] {
@@ -126,18 +142,66 @@ func SetupGRPCProxy(cfg *config.Config, r gin.IRouter) {
 					break
 				}
 			}
-			health.GetGlobalHealthChecker().UpdateRequestCount(target, recorder.Status < http.StatusBadRequest)
+
+			// 将元数据传播到请求上下文中
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("request-id", c.GetHeader("X-Request-ID")))
+			ctx = context.WithValue(ctx, grpcTargetKey{}, target)
+			req = req.WithContext(ctx)
+
+			// gRPC-Web 请求来自浏览器，先按 allowedOrigins 处理跨域
+			grpcWeb := cfg.GRPC.Web && isGRPCWebRequest(req)
+			if cfg.GRPC.Web && isGRPCWebPreflight(req) {
+				writeGRPCWebPreflight(c.Writer, req, cfg.GRPC.AllowedOrigins)
+				c.Abort()
+				return
+			}
+			if grpcWeb && !setGRPCWebCORS(c.Writer, req, cfg.GRPC.AllowedOrigins) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+				c.Abort()
+				return
+			}
+
+			start := time.Now()
+			c.Set("proxy_target", target)
+			GuardTarget(c, target, func() {
+				if grpcWeb {
+					// 收到响应头即记录上游已响应，服务端流式调用的后续消息不受熔断超时限制
+					code := serveGRPCWeb(c.Writer, req, conns[target], func() { SetUpstreamStatus(c, http.StatusOK) })
+					if code != grpccodes.OK {
+						observability.GRPCCallsTotal.WithLabelValues(req.URL.Path, fmt.Sprintf("%d", code)).Inc()
+					}
+					httpStatus := runtime.HTTPStatusFromCode(code)
+					SetUpstreamStatus(c, httpStatus)
+					health.GetGlobalHealthChecker().UpdateRequestCount(target, httpStatus < http.StatusBadRequest)
+					return
+				}
+				recorder := &statusRecorder{ResponseWriter: c.Writer, Status: http.StatusOK}
+				mux.ServeHTTP(recorder, req)
+				SetUpstreamStatus(c, recorder.Status)
+				health.GetGlobalHealthChecker().UpdateRequestCount(target, recorder.Status < http.StatusBadRequest)
+			})
 
 			// 记录请求延迟
 			duration := time.Since(start).Seconds()
-			observability.RequestDuration.WithLabelValues(c.Request.Method, c.Request.URL.Path).Observe(duration)
+			observability.ObserveRequestDuration(ctx, c.Request.Method, c.Request.URL.Path, duration)
 			span.SetStatus(codes.Ok, "gRPC proxy completed successfully")
 		})
 		logger.Info("gRPC proxy route configured successfully",
-			zap.String("path", route))
+			zap.String("path", route),
+			zap.String("mountPath", mountPath))
 	}
 }
 
+// firstGRPCRule 返回路由的第一条 gRPC 规则，与请求转发时选取目标的方式一致
+func firstGRPCRule(rules config.RoutingRules) (config.RoutingRule, bool) {
+	for _, rule := range rules {
+		if rule.Protocol == "grpc" {
+			return rule, true
+		}
+	}
+	return config.RoutingRule{}, false
+}
+
 // statusRecorder 捕获 HTTP 响应状态码
 type statusRecorder struct {
 	gin.ResponseWriter
@@ -150,7 +214,10 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
-// httpErrorHandler 自定义 gRPC 请求的错误处理
+// grpcTargetKey 在请求上下文中保存 gRPC 路由目标的键
+type grpcTargetKey struct{}
+
+// httpErrorHandler 自定义 gRPC 请求的错误处理，上游不支持 gRPC 时返回带说明的 502
 func httpErrorHandler() runtime.ErrorHandlerFunc {
 	return func(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
 		st, _ := status.FromError(err)
@@ -158,6 +225,17 @@ func httpErrorHandler() runtime.ErrorHandlerFunc {
 		path := r.URL.Path
 
 		observability.GRPCCallsTotal.WithLabelValues(path, statusCode).Inc()
+		if isHTTP1Upstream(st) {
+			target, _ := ctx.Value(grpcTargetKey{}).(string)
+			observability.ProtocolMismatches.WithLabelValues(target, protocolHTTP1).Inc()
+			logger.Error("Upstream protocol does not match route",
+				zap.String("path", path),
+				zap.String("target", target),
+				zap.String("upstreamProtocol", protocolHTTP1),
+				zap.String("error", st.Message()))
+			writeHTTPError(w, r, http.StatusBadGateway, ErrCodeProtocolMismatch, protocolMismatchMessage(protocolHTTP1))
+			return
+		}
 		logger.Error("gRPC request processing failed",
 			zap.String("path", path),
 			zap.String("statusCode", statusCode),
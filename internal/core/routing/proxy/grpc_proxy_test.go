@@ -119,3 +119,53 @@ func TestSetupGRPCProxy_Response(t *testing.T) {
 	_ = observability.RequestDuration
 
 }
+
+// TestFindGRPCRouteConflicts 测试 gRPC 路由与 HTTP 路由前缀重叠时能被检测到
+func TestFindGRPCRouteConflicts(t *testing.T) {
+	tests := []struct {
+		name         string
+		mountPattern string
+		httpPath     string
+		grpcPath     string
+		wantConflict bool
+	}{
+		{"same path", "{path}", "/grpc/hello", "/hello", true},
+		{"catch-all overlaps nested http route", "{path}/*any", "/grpc/hello/world", "/hello", true},
+		{"http param overlaps grpc route", "{path}", "/grpc/:name", "/hello", true},
+		{"http catch-all overlaps grpc route", "{path}", "/grpc/*any", "/hello", true},
+		{"distinct prefixes", "{path}/*any", "/api/v1/user", "/hello", false},
+		{"nested http route without catch-all", "{path}", "/grpc/hello/world", "/hello", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				GRPC: config.GRPCConfig{Prefix: "/grpc", MountPattern: tt.mountPattern},
+				Routing: config.Routing{
+					Rules: map[string]config.RoutingRules{
+						tt.httpPath: {{Target: "http://localhost:8381", Protocol: "http"}},
+						tt.grpcPath: {{Target: "localhost:8391", Protocol: "grpc"}},
+					},
+				},
+			}
+
+			conflicts := FindGRPCRouteConflicts(cfg)
+			_, ok := conflicts[tt.grpcPath]
+			if ok != tt.wantConflict {
+				t.Errorf("conflict detected = %v, want %v (conflicts: %v)", ok, tt.wantConflict, conflicts)
+			}
+		})
+	}
+}
+
+// TestGRPCMountPath 测试挂载模式替换
+func TestGRPCMountPath(t *testing.T) {
+	cfg := &config.Config{}
+	if got := grpcMountPath(cfg, "/hello"); got != "/hello" {
+		t.Errorf("default mount path = %q, want %q", got, "/hello")
+	}
+	cfg.GRPC.MountPattern = "{path}/*any"
+	if got := grpcMountPath(cfg, "/hello/"); got != "/hello/*any" {
+		t.Errorf("catch-all mount path = %q, want %q", got, "/hello/*any")
+	}
+}
@@ -0,0 +1,315 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/health"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// sseMarshalOptions 事件数据使用不带换行的 JSON，每个事件只占一行 data
+var sseMarshalOptions = protojson.MarshalOptions{}
+
+// resolveStreamingMethod 在网关编译时包含的 proto 定义中查找服务端流式方法
+func resolveStreamingMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid method name %q", fullMethod)
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s is not known to the gateway: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, method)
+	}
+	if !md.IsStreamingServer() || md.IsStreamingClient() {
+		return nil, fmt.Errorf("%s is not a server-streaming method", fullMethod)
+	}
+	return md, nil
+}
+
+// mountSSEBridge 将路由挂载为 SSE 桥接端点，方法无法解析或目标没有可用连接时不挂载该路由
+func mountSSEBridge(r gin.IRouter, cfg *config.Config, route string, rule config.RoutingRule, conn grpc.ClientConnInterface) {
+	md, err := resolveStreamingMethod(rule.SSE.Method)
+	if err != nil {
+		logger.Error("Skipping gRPC SSE route with unusable method",
+			zap.String("path", route),
+			zap.String("method", rule.SSE.Method),
+			zap.Error(err))
+		return
+	}
+	if conn == nil {
+		logger.Error("Skipping gRPC SSE route without backend connection",
+			zap.String("path", route),
+			zap.String("target", rule.Target))
+		return
+	}
+
+	mountPath := grpcMountPath(cfg, route)
+	bridge := newSSEBridgeHandler(md, rule.Target, conn)
+	handler := func(c *gin.Context) {
+		SetMatchedRoute(c, route)
+		bridge(c)
+	}
+	r.GET(mountPath, handler)
+	r.POST(mountPath, handler)
+	logger.Info("gRPC SSE bridge route configured successfully",
+		zap.String("path", route),
+		zap.String("mountPath", mountPath),
+		zap.String("method", rule.SSE.Method))
+}
+
+// newSSEBridgeHandler 创建将 gRPC 服务端流式方法转为 SSE 的处理函数
+// 请求消息取自 JSON 请求体（POST）或查询参数（GET，浏览器的 EventSource 只能发送 GET 请求）
+func newSSEBridgeHandler(md protoreflect.MethodDescriptor, target string, conn grpc.ClientConnInterface) gin.HandlerFunc {
+	fullMethod := "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+	return func(c *gin.Context) {
+		ctx, span := grpcTracer.Start(c.Request.Context(), "GRPCProxy.SSEBridge",
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.path", c.Request.URL.Path),
+				attribute.String("grpc.method", fullMethod),
+			))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		req := dynamicpb.NewMessage(md.Input())
+		if err := decodeSSERequest(c.Writer, c.Request, req); err != nil {
+			httpStatus := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				httpStatus = http.StatusRequestEntityTooLarge
+			}
+			writeHTTPError(c.Writer, c.Request, httpStatus, ErrCodeBadRequest, err.Error())
+			c.Abort()
+			return
+		}
+
+		c.Set("proxy_target", target)
+		GuardTarget(c, target, func() {
+			code := serveSSEBridge(c, md, fullMethod, conn, req)
+			httpStatus := runtime.HTTPStatusFromCode(code)
+			SetUpstreamStatus(c, httpStatus)
+			health.GetGlobalHealthChecker().UpdateRequestCount(target, httpStatus < http.StatusBadRequest)
+		})
+	}
+}
+
+// serveSSEBridge 打开 gRPC 流并将每条消息作为 SSE 事件发送，返回流的最终状态码
+// 客户端断开时请求上下文被取消，gRPC 流随之取消
+func serveSSEBridge(c *gin.Context, md protoreflect.MethodDescriptor, fullMethod string, conn grpc.ClientConnInterface, req *dynamicpb.Message) codes.Code {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, grpcWebMetadata(c.Request.Header))
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err == nil {
+		if err = stream.SendMsg(req); errors.Is(err, io.EOF) {
+			err = nil // 发送失败时真实错误由 Header 或 RecvMsg 返回
+		}
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	// Header 返回空值说明后端未发送响应头即结束了流（如方法未实现），最终状态需从 RecvMsg 获取
+	ended := false
+	if err == nil {
+		var header metadata.MD
+		if header, err = stream.Header(); err == nil && header == nil {
+			if err = stream.RecvMsg(dynamicpb.NewMessage(md.Output())); errors.Is(err, io.EOF) {
+				err, ended = nil, true
+			}
+		}
+	}
+	// 流建立前失败时返回普通的 HTTP 错误响应
+	if err != nil {
+		st := status.Convert(err)
+		logger.Error("Failed to open gRPC stream for SSE bridge",
+			zap.String("method", fullMethod),
+			zap.String("code", st.Code().String()),
+			zap.String("error", st.Message()))
+		writeHTTPError(c.Writer, c.Request, runtime.HTTPStatusFromCode(st.Code()), ErrCodeBadGateway, st.Message())
+		return st.Code()
+	}
+
+	// 流已建立，记录上游已响应，此后持续推送的事件不受熔断超时限制
+	SetUpstreamStatus(c, http.StatusOK)
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // 避免 Nginx 等代理缓冲事件
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+	if ended {
+		writeSSEEvent(c.Writer, "end", "", "{}")
+		return codes.OK
+	}
+
+	for id := 1; ; id++ {
+		resp := dynamicpb.NewMessage(md.Output())
+		err := stream.RecvMsg(resp)
+		if errors.Is(err, io.EOF) {
+			writeSSEEvent(c.Writer, "end", "", "{}")
+			return codes.OK
+		}
+		if err != nil {
+			st := status.Convert(err)
+			if ctx.Err() != nil {
+				logger.Info("SSE client disconnected, gRPC stream cancelled", zap.String("method", fullMethod))
+				return codes.Canceled
+			}
+			logger.Warn("gRPC stream for SSE bridge failed",
+				zap.String("method", fullMethod),
+				zap.String("code", st.Code().String()),
+				zap.String("error", st.Message()))
+			data, _ := sseMarshalOptions.Marshal(st.Proto())
+			writeSSEEvent(c.Writer, "error", "", string(data))
+			return st.Code()
+		}
+		data, err := sseMarshalOptions.Marshal(resp)
+		if err != nil {
+			logger.Error("Failed to encode gRPC message for SSE bridge",
+				zap.String("method", fullMethod),
+				zap.Error(err))
+			return codes.Internal
+		}
+		if err := writeSSEEvent(c.Writer, "", strconv.Itoa(id), string(data)); err != nil {
+			return codes.Canceled
+		}
+	}
+}
+
+// writeSSEEvent 写出一个 SSE 事件并立即刷新，event 为空时使用默认的 message 事件
+func writeSSEEvent(w gin.ResponseWriter, event, id, data string) error {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	if _, err := w.WriteString(b.String()); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// decodeSSERequest 从 JSON 请求体或查询参数构造请求消息，请求体与 gRPC-Web 请求消息的大小上限一致
+func decodeSSERequest(w http.ResponseWriter, r *http.Request, msg *dynamicpb.Message) error {
+	if r.Method == http.MethodPost && r.ContentLength != 0 {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, grpcWebMaxMessageSize))
+		if err != nil {
+			return err
+		}
+		if err := protojson.Unmarshal(body, msg); err != nil {
+			return fmt.Errorf("invalid request body: %w", err)
+		}
+		return nil
+	}
+	return setQueryFields(msg, r.URL.Query())
+}
+
+// setQueryFields 按查询参数设置消息的顶层字段，参数名可为 JSON 字段名或 proto 字段名，重复参数对应重复字段
+func setQueryFields(msg *dynamicpb.Message, query url.Values) error {
+	fields := msg.Descriptor().Fields()
+	for name, values := range query {
+		fd := fields.ByJSONName(name)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(name))
+		}
+		if fd == nil {
+			return fmt.Errorf("unknown query parameter %q", name)
+		}
+		if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			return fmt.Errorf("query parameter %q does not name a scalar field", name)
+		}
+		if !fd.IsList() && len(values) > 1 {
+			return fmt.Errorf("query parameter %q is repeated", name)
+		}
+		for _, value := range values {
+			v, err := parseScalar(fd, value)
+			if err != nil {
+				return fmt.Errorf("query parameter %q: %w", name, err)
+			}
+			if fd.IsList() {
+				msg.Mutable(fd).List().Append(v)
+			} else {
+				msg.Set(fd, v)
+			}
+		}
+	}
+	return nil
+}
+
+// parseScalar 按字段类型解析查询参数的值
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(value)
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// startSSEBridge 启动健康检查后端以及挂载 Health/Watch 桥接的网关，done 在处理函数返回后收到信号
+// middleware 在桥接处理函数之前执行
+func startSSEBridge(t *testing.T, withHealth bool, middleware ...gin.HandlerFunc) (gateway *httptest.Server, hs *health.Server, done chan struct{}) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	hs = health.NewServer()
+	if withHealth {
+		grpc_health_v1.RegisterHealthServer(server, hs)
+	}
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	md, err := resolveStreamingMethod("/grpc.health.v1.Health/Watch")
+	require.NoError(t, err)
+	handler := newSSEBridgeHandler(md, ln.Addr().String(), conn)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	done = make(chan struct{}, 1)
+	r.GET("/events", append(middleware, func(c *gin.Context) {
+		handler(c)
+		done <- struct{}{}
+	})...)
+	gateway = httptest.NewServer(r)
+	t.Cleanup(gateway.Close)
+	return gateway, hs, done
+}
+
+// readSSEEvent 读取一个 SSE 事件的全部字段行
+func readSSEEvent(t *testing.T, r *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSEBridge_StreamsMessagesAndCancelsOnDisconnect(t *testing.T) {
+	logger.InitTestLogger()
+	gateway, hs, done := startSSEBridge(t, true)
+	hs.SetServingStatus("orders", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	resp, err := http.Get(gateway.URL + "/events?service=orders")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	body := bufio.NewReader(resp.Body)
+	event := readSSEEvent(t, body)
+	require.Len(t, event, 2)
+	assert.Equal(t, "id: 1", event[0])
+	assert.JSONEq(t, `{"status":"NOT_SERVING"}`, strings.TrimPrefix(event[1], "data: "))
+	hs.SetServingStatus("orders", grpc_health_v1.HealthCheckResponse_SERVING)
+	event = readSSEEvent(t, body)
+	require.Len(t, event, 2)
+	assert.Equal(t, "id: 2", event[0])
+	assert.JSONEq(t, `{"status":"SERVING"}`, strings.TrimPrefix(event[1], "data: "))
+
+	// 客户端断开后 gRPC 流被取消，处理函数返回
+	require.NoError(t, resp.Body.Close())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SSE handler did not return after client disconnected")
+	}
+}
+
+func TestSSEBridge_Errors(t *testing.T) {
+	logger.InitTestLogger()
+	gateway, _, done := startSSEBridge(t, false)
+
+	resp, err := http.Get(gateway.URL + "/events?unknown=1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	<-done
+
+	// 后端未实现该方法时在流建立前返回 HTTP 错误
+	resp, err = http.Get(gateway.URL + "/events?service=orders")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	<-done
+}
+
+// TestSSEBridge_RecordsUpstreamResponseOnOpen 验证流建立后即记录上游已响应，熔断器不必等到流结束
+func TestSSEBridge_RecordsUpstreamResponseOnOpen(t *testing.T) {
+	logger.InitTestLogger()
+	opened := make(chan int, 1)
+	gateway, hs, done := startSSEBridge(t, true, func(c *gin.Context) {
+		OnUpstreamResponse(c, func() {
+			status, _ := UpstreamStatus(c)
+			select {
+			case opened <- status:
+			default:
+			}
+		})
+	})
+	hs.SetServingStatus("orders", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	resp, err := http.Get(gateway.URL + "/events?service=orders")
+	require.NoError(t, err)
+	readSSEEvent(t, bufio.NewReader(resp.Body))
+	select {
+	case status := <-opened:
+		assert.Equal(t, http.StatusOK, status)
+	default:
+		t.Fatal("upstream response was not recorded while the stream is open")
+	}
+	require.NoError(t, resp.Body.Close())
+	<-done
+}
+
+func TestDecodeSSERequest_LimitsBodySize(t *testing.T) {
+	md, err := resolveStreamingMethod("/grpc.health.v1.Health/Watch")
+	require.NoError(t, err)
+
+	body := `{"service":"` + strings.Repeat("x", grpcWebMaxMessageSize) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+	err = decodeSSERequest(httptest.NewRecorder(), req, dynamicpb.NewMessage(md.Input()))
+	var tooLarge *http.MaxBytesError
+	assert.ErrorAs(t, err, &tooLarge)
+
+	req = httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"service":"orders"}`))
+	msg := dynamicpb.NewMessage(md.Input())
+	require.NoError(t, decodeSSERequest(httptest.NewRecorder(), req, msg))
+	assert.Equal(t, "orders", msg.Get(md.Input().Fields().ByName("service")).String())
+}
+
+func TestResolveStreamingMethod(t *testing.T) {
+	md, err := resolveStreamingMethod("/grpc.health.v1.Health/Watch")
+	require.NoError(t, err)
+	assert.Equal(t, "grpc.health.v1.HealthCheckRequest", string(md.Input().FullName()))
+
+	for _, method := range []string{"/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Missing", "/unknown.Service/Watch", "Watch"} {
+		_, err := resolveStreamingMethod(method)
+		assert.Error(t, err, method)
+	}
+}
+
+func TestSetQueryFields(t *testing.T) {
+	md, err := resolveStreamingMethod("/grpc.health.v1.Health/Watch")
+	require.NoError(t, err)
+
+	msg := dynamicpb.NewMessage(md.Input())
+	require.NoError(t, setQueryFields(msg, url.Values{"service": {"orders"}}))
+	assert.Equal(t, "orders", msg.Get(md.Input().Fields().ByName("service")).String())
+
+	assert.Error(t, setQueryFields(dynamicpb.NewMessage(md.Input()), url.Values{"service": {"a", "b"}}))
+	assert.Error(t, setQueryFields(dynamicpb.NewMessage(md.Input()), url.Values{"unknown": {"1"}}))
+}
@@ -0,0 +1,336 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC-Web 请求的 Content-Type 前缀，-text 变体的请求体与响应体经过 base64 编码
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+)
+
+const (
+	grpcWebTrailerFlag     = 0x80            // 响应中 trailer 帧的标志位
+	grpcWebCompressedFlag  = 0x01            // 消息经过压缩的标志位，网关不支持压缩的请求消息
+	grpcWebMaxMessageSize  = 4 * 1024 * 1024 // 请求消息的最大字节数，与 gRPC 默认的接收上限一致
+	grpcWebPreflightMaxAge = "600"           // 预检结果的缓存时间（秒）
+)
+
+// grpcWebSkipHeaders 不作为 gRPC 元数据转发给后端的请求头，grpc- 开头的请求头由 gRPC 协议本身处理
+var grpcWebSkipHeaders = map[string]bool{
+	"accept-encoding":   true,
+	"connection":        true,
+	"content-length":    true,
+	"content-type":      true,
+	"host":              true,
+	"keep-alive":        true,
+	"te":                true,
+	"trailer":           true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"user-agent":        true,
+	"x-grpc-web":        true,
+}
+
+// rawCodec 原样传递消息字节，网关无需了解后端服务的 protobuf 定义即可转发
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// isGRPCWebRequest 判断请求是否使用 gRPC-Web 协议（含 -text 变体）
+func isGRPCWebRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), grpcWebContentType)
+}
+
+// isGRPCWebPreflight 判断请求是否为浏览器发出的跨域预检请求
+func isGRPCWebPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// setGRPCWebCORS 按 grpc.allowedOrigins 为跨域请求设置 CORS 响应头，来源不在允许列表中时返回 false
+// 请求未携带 Origin 时视为同源或非浏览器请求，直接放行
+func setGRPCWebCORS(w http.ResponseWriter, r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || allowed == origin {
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Expose-Headers", "grpc-status, grpc-message")
+			return true
+		}
+	}
+	return false
+}
+
+// writeGRPCWebPreflight 响应跨域预检请求，来源不允许时返回 403
+func writeGRPCWebPreflight(w http.ResponseWriter, r *http.Request, allowedOrigins []string) {
+	if !setGRPCWebCORS(w, r, allowedOrigins) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	h := w.Header()
+	h.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+	h.Set("Access-Control-Max-Age", grpcWebPreflightMaxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveGRPCWeb 将 gRPC-Web 请求转换为对后端的 gRPC 调用，支持一元调用和服务端流式调用
+// 方法名取自请求路径的最后两段（/包名.服务名/方法名），返回调用的 gRPC 状态码
+// 收到后端的响应头时调用 onHeader（可为 nil），此后的消息可能持续推送很久，熔断器只对此前的阶段计时
+func serveGRPCWeb(w http.ResponseWriter, r *http.Request, conn grpc.ClientConnInterface, onHeader func()) codes.Code {
+	text := strings.HasPrefix(r.Header.Get("Content-Type"), grpcWebTextContentType)
+	if text {
+		w.Header().Set("Content-Type", grpcWebTextContentType+"+proto")
+	} else {
+		w.Header().Set("Content-Type", grpcWebContentType+"+proto")
+	}
+
+	st := callGRPCWeb(w, r, conn, text, onHeader)
+	if st.Code() != codes.OK {
+		logger.Warn("gRPC-Web call failed",
+			zap.String("path", r.URL.Path),
+			zap.String("code", st.Code().String()),
+			zap.String("error", st.Message()))
+	}
+	return st.Code()
+}
+
+// callGRPCWeb 转发一次调用并写出响应消息与 trailer 帧，返回调用的最终状态
+func callGRPCWeb(w http.ResponseWriter, r *http.Request, conn grpc.ClientConnInterface, text bool, onHeader func()) *status.Status {
+	var trailer metadata.MD
+	st := func() *status.Status {
+		method, ok := grpcWebMethod(r.URL.Path)
+		if !ok {
+			return status.Newf(codes.Unimplemented, "path %s does not name a gRPC method", r.URL.Path)
+		}
+		if conn == nil {
+			return status.New(codes.Unavailable, "no connection to upstream")
+		}
+		var body io.Reader = r.Body
+		if text {
+			body = base64.NewDecoder(base64.StdEncoding, r.Body)
+		}
+		msg, err := readGRPCWebMessage(body)
+		if err != nil {
+			return status.Convert(err)
+		}
+
+		ctx := metadata.NewOutgoingContext(r.Context(), grpcWebMetadata(r.Header))
+		if timeout, ok := parseGRPCTimeout(r.Header.Get("Grpc-Timeout")); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, method, grpc.ForceCodec(rawCodec{}))
+		if err != nil {
+			return status.Convert(err)
+		}
+		// 发送失败时 SendMsg 返回 io.EOF，真实错误由 RecvMsg 返回
+		if err := stream.SendMsg(&msg); err != nil && !errors.Is(err, io.EOF) {
+			return status.Convert(err)
+		}
+		if err := stream.CloseSend(); err != nil {
+			return status.Convert(err)
+		}
+
+		// 后端未发送响应头即结束（如失败时只返回 trailer）时 header 为 nil，最终状态由 RecvMsg 返回
+		if header, err := stream.Header(); err == nil && header != nil {
+			copyGRPCWebMetadata(w.Header(), header)
+			if onHeader != nil {
+				onHeader()
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		for {
+			var resp []byte
+			err := stream.RecvMsg(&resp)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				trailer = stream.Trailer()
+				return status.Convert(err)
+			}
+			if err := writeGRPCWebFrame(w, text, 0, resp); err != nil {
+				return status.New(codes.Canceled, err.Error()) // 客户端已断开
+			}
+		}
+		trailer = stream.Trailer()
+		return status.New(codes.OK, "")
+	}()
+
+	writeGRPCWebFrame(w, text, grpcWebTrailerFlag, grpcWebTrailer(st, trailer))
+	return st
+}
+
+// grpcWebMethod 从请求路径的最后两段解析 gRPC 方法全名
+func grpcWebMethod(path string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 {
+		return "", false
+	}
+	service, method := parts[len(parts)-2], parts[len(parts)-1]
+	if service == "" || method == "" {
+		return "", false
+	}
+	return "/" + service + "/" + method, true
+}
+
+// readGRPCWebMessage 读取请求体中的唯一一条消息，一元调用和服务端流式调用的请求都只包含一条消息
+func readGRPCWebMessage(body io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(body, header[:]); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "read message header: %v", err)
+	}
+	if header[0]&grpcWebCompressedFlag != 0 {
+		return nil, status.Error(codes.Unimplemented, "compressed request messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > grpcWebMaxMessageSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "request message larger than %d bytes", grpcWebMaxMessageSize)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "read message: %v", err)
+	}
+	if n, _ := body.Read(header[:1]); n > 0 {
+		return nil, status.Error(codes.Unimplemented, "client streaming is not supported over gRPC-Web")
+	}
+	return msg, nil
+}
+
+// writeGRPCWebFrame 写出一帧并立即刷新，使服务端流式调用的消息及时到达浏览器
+func writeGRPCWebFrame(w http.ResponseWriter, text bool, flag byte, data []byte) error {
+	frame := make([]byte, 5+len(data))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+	if text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	if _, err := w.Write(frame); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// grpcWebTrailer 生成 trailer 帧的内容，格式与 HTTP/1 头部相同，键为小写
+func grpcWebTrailer(st *status.Status, md metadata.MD) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if msg := st.Message(); msg != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeGRPCMessage(msg))
+	}
+	for key, values := range md {
+		for _, value := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, grpcWebHeaderValue(key, value))
+		}
+	}
+	return b.Bytes()
+}
+
+// grpcWebMetadata 将请求头转换为转发给后端的 gRPC 元数据
+func grpcWebMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for name, values := range header {
+		key := strings.ToLower(name)
+		if grpcWebSkipHeaders[key] || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		md.Append(key, values...)
+	}
+	return md
+}
+
+// copyGRPCWebMetadata 将后端返回的响应头元数据写入 HTTP 响应头
+func copyGRPCWebMetadata(dst http.Header, md metadata.MD) {
+	for key, values := range md {
+		if key == "content-type" {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, grpcWebHeaderValue(key, value))
+		}
+	}
+}
+
+// grpcWebHeaderValue 二进制元数据（键以 -bin 结尾）按 gRPC 约定以 base64 编码
+func grpcWebHeaderValue(key, value string) string {
+	if strings.HasSuffix(key, "-bin") {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	return value
+}
+
+// encodeGRPCMessage 按 gRPC 协议对 grpc-message 进行百分号编码
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// parseGRPCTimeout 解析 grpc-timeout 请求头，格式为最多 8 位数字加单位（H、M、S、m、u、n）
+func parseGRPCTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
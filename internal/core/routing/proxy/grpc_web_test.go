@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startGRPCWebBackend 启动包含健康检查服务（一元调用）和 test.Echo/Repeat（服务端流式调用）的 gRPC 后端
+func startGRPCWebBackend(t *testing.T) *grpc.ClientConn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	// Repeat 将请求中的字符串原样返回 3 次
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Repeat",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				var req wrapperspb.StringValue
+				if err := stream.RecvMsg(&req); err != nil {
+					return err
+				}
+				for i := 0; i < 3; i++ {
+					if err := stream.SendMsg(&req); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// grpcWebRequest 构造携带单条消息的 gRPC-Web 请求
+func grpcWebRequest(t *testing.T, path string, msg gproto.Message, text bool) *http.Request {
+	data, err := gproto.Marshal(msg)
+	require.NoError(t, err)
+	frame := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+
+	contentType := grpcWebContentType + "+proto"
+	if text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+		contentType = grpcWebTextContentType + "+proto"
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(frame))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+// parseGRPCWebFrames 解析响应体，返回消息帧与 trailer 帧的内容
+func parseGRPCWebFrames(t *testing.T, body []byte, text bool) (messages [][]byte, trailer string) {
+	if text {
+		var decoded []byte
+		// 每一帧单独进行 base64 编码，逐段解码
+		for len(body) > 0 {
+			n := strings.Index(string(body), "=")
+			end := len(body)
+			if n >= 0 {
+				end = n
+				for end < len(body) && body[end] == '=' {
+					end++
+				}
+			}
+			chunk, err := base64.StdEncoding.DecodeString(string(body[:end]))
+			require.NoError(t, err)
+			decoded = append(decoded, chunk...)
+			body = body[end:]
+		}
+		body = decoded
+	}
+	for len(body) > 0 {
+		require.GreaterOrEqual(t, len(body), 5)
+		length := binary.BigEndian.Uint32(body[1:5])
+		data := body[5 : 5+length]
+		if body[0]&grpcWebTrailerFlag != 0 {
+			trailer = string(data)
+		} else {
+			messages = append(messages, data)
+		}
+		body = body[5+length:]
+	}
+	return messages, trailer
+}
+
+func TestServeGRPCWeb_Unary(t *testing.T) {
+	logger.InitTestLogger()
+	conn := startGRPCWebBackend(t)
+
+	w := httptest.NewRecorder()
+	req := grpcWebRequest(t, "/grpc/api/hello/grpc.health.v1.Health/Check", &grpc_health_v1.HealthCheckRequest{}, false)
+	code := serveGRPCWeb(w, req, conn, nil)
+
+	assert.Equal(t, codes.OK, code)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/grpc-web+proto", w.Header().Get("Content-Type"))
+	messages, trailer := parseGRPCWebFrames(t, w.Body.Bytes(), false)
+	require.Len(t, messages, 1)
+	var resp grpc_health_v1.HealthCheckResponse
+	require.NoError(t, gproto.Unmarshal(messages[0], &resp))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.GetStatus())
+	assert.Contains(t, trailer, "grpc-status: 0\r\n")
+}
+
+func TestServeGRPCWeb_ServerStreamingText(t *testing.T) {
+	logger.InitTestLogger()
+	conn := startGRPCWebBackend(t)
+
+	w := httptest.NewRecorder()
+	code := serveGRPCWeb(w, grpcWebRequest(t, "/test.Echo/Repeat", wrapperspb.String("hi"), true), conn, nil)
+
+	assert.Equal(t, codes.OK, code)
+	assert.Equal(t, "application/grpc-web-text+proto", w.Header().Get("Content-Type"))
+	messages, trailer := parseGRPCWebFrames(t, w.Body.Bytes(), true)
+	require.Len(t, messages, 3)
+	for _, data := range messages {
+		var msg wrapperspb.StringValue
+		require.NoError(t, gproto.Unmarshal(data, &msg))
+		assert.Equal(t, "hi", msg.GetValue())
+	}
+	assert.Contains(t, trailer, "grpc-status: 0\r\n")
+}
+
+func TestServeGRPCWeb_Errors(t *testing.T) {
+	logger.InitTestLogger()
+	conn := startGRPCWebBackend(t)
+
+	w := httptest.NewRecorder()
+	code := serveGRPCWeb(w, grpcWebRequest(t, "/test.Echo/Missing", wrapperspb.String("hi"), false), conn, nil)
+	assert.Equal(t, codes.Unimplemented, code)
+	_, trailer := parseGRPCWebFrames(t, w.Body.Bytes(), false)
+	assert.Contains(t, trailer, "grpc-status: 12\r\n")
+	assert.Contains(t, trailer, "grpc-message: ")
+
+	// 请求体不是完整的帧
+	req := httptest.NewRequest(http.MethodPost, "/test.Echo/Repeat", strings.NewReader("\x00\x00"))
+	req.Header.Set("Content-Type", grpcWebContentType)
+	w = httptest.NewRecorder()
+	assert.Equal(t, codes.InvalidArgument, serveGRPCWeb(w, req, conn, nil))
+
+	w = httptest.NewRecorder()
+	assert.Equal(t, codes.Unavailable, serveGRPCWeb(w, grpcWebRequest(t, "/test.Echo/Repeat", wrapperspb.String("hi"), false), nil, nil))
+}
+
+// TestServeGRPCWeb_OnHeader 验证收到后端响应头时即回调，早于消息写出；后端未发送响应头即失败时不回调
+func TestServeGRPCWeb_OnHeader(t *testing.T) {
+	logger.InitTestLogger()
+	conn := startGRPCWebBackend(t)
+
+	w := httptest.NewRecorder()
+	headerCalls, bodyAtHeader := 0, -1
+	code := serveGRPCWeb(w, grpcWebRequest(t, "/test.Echo/Repeat", wrapperspb.String("hi"), false), conn, func() {
+		headerCalls++
+		bodyAtHeader = w.Body.Len()
+	})
+	assert.Equal(t, codes.OK, code)
+	assert.Equal(t, 1, headerCalls)
+	assert.Equal(t, 0, bodyAtHeader, "响应头回调应早于消息写出")
+
+	headerCalls = 0
+	code = serveGRPCWeb(httptest.NewRecorder(), grpcWebRequest(t, "/test.Echo/Missing", wrapperspb.String("hi"), false), conn, func() { headerCalls++ })
+	assert.Equal(t, codes.Unimplemented, code)
+	assert.Zero(t, headerCalls, "只返回 trailer 的失败调用不应视为已收到响应头")
+}
+
+func TestGRPCWebCORS(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/grpc/api/hello/test.Echo/Repeat", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	require.True(t, isGRPCWebPreflight(req))
+
+	w := httptest.NewRecorder()
+	writeGRPCWebPreflight(w, req, []string{"https://app.example.com"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "content-type,x-grpc-web", w.Header().Get("Access-Control-Allow-Headers"))
+
+	w = httptest.NewRecorder()
+	writeGRPCWebPreflight(w, req, []string{"https://other.example.com"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestParseGRPCTimeout(t *testing.T) {
+	d, ok := parseGRPCTimeout("250m")
+	assert.True(t, ok)
+	assert.Equal(t, "250ms", d.String())
+	for _, value := range []string{"", "5", "5x", "-1S", "1234567890S"} {
+		_, ok := parseGRPCTimeout(value)
+		assert.False(t, ok, value)
+	}
+}
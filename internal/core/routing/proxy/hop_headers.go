@@ -0,0 +1,34 @@
+package proxy
+
+import "strings"
+
+// hopHeaders RFC 7230 第 6.1 节定义的逐跳头，只对单个连接有意义，代理不应转发
+// 直接代理模式由 httputil.ReverseProxy 处理，连接池模式在复制请求头和响应头时跳过
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection", // 非标准，但部分客户端仍会发送
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// isHopHeader 判断 name 是否为逐跳头，connection 为 Connection 头的值，其中列出的头同样只对当前连接有效
+func isHopHeader(name, connection string) bool {
+	for _, h := range hopHeaders {
+		if strings.EqualFold(name, h) {
+			return true
+		}
+	}
+	for connection != "" {
+		var token string
+		token, connection, _ = strings.Cut(connection, ",")
+		if strings.EqualFold(strings.TrimSpace(token), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsHopHeader(t *testing.T) {
+	assert.True(t, isHopHeader("Connection", ""))
+	assert.True(t, isHopHeader("keep-alive", ""), "header names are case-insensitive")
+	assert.True(t, isHopHeader("X-Session-Hop", "keep-alive, x-session-hop"), "headers listed in Connection are hop-by-hop")
+	assert.False(t, isHopHeader("X-Request-ID", "keep-alive"))
+	assert.False(t, isHopHeader("Authorization", ""))
+}
+
+func TestProxy_StripsHopByHopHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		usePool bool
+	}{
+		{name: "direct"},
+		{name: "pool", usePool: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			received := make(chan http.Header, 1)
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received <- r.Header.Clone()
+				w.Header().Set("Connection", "X-Upstream-Hop")
+				w.Header().Set("X-Upstream-Hop", "1")
+				w.Header().Set("Keep-Alive", "timeout=5")
+				w.Header().Set("X-Upstream", "1")
+			}))
+			defer backend.Close()
+
+			cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+			hp := newTestProxy(t, cfg, withPool(tc.usePool))
+			router := gin.New()
+			router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+
+			req := httptest.NewRequest("GET", "/api/v1/user", nil)
+			req.Header.Set("Connection", "close, X-Client-Hop")
+			req.Header.Set("X-Client-Hop", "1")
+			req.Header.Set("Keep-Alive", "timeout=5")
+			req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+			req.Header.Set("X-Client", "1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			got := <-received
+			assert.NotContains(t, got.Values("Connection"), "close", "client Connection header is not forwarded")
+			assert.Empty(t, got.Get("X-Client-Hop"))
+			assert.Empty(t, got.Get("Keep-Alive"))
+			assert.Empty(t, got.Get("Proxy-Authorization"))
+			assert.Equal(t, "1", got.Get("X-Client"))
+
+			assert.Empty(t, w.Header().Get("Connection"))
+			assert.Empty(t, w.Header().Get("X-Upstream-Hop"))
+			assert.Empty(t, w.Header().Get("Keep-Alive"))
+			assert.Equal(t, "1", w.Header().Get("X-Upstream"))
+		})
+	}
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/penwyp/mini-gateway/config"
@@ -15,6 +16,7 @@ const (
 	defaultMaxIdleConnDuration = 30 * time.Second // 默认最大空闲连接持续时间
 	defaultReadTimeout         = 5 * time.Second  // 默认读取超时
 	defaultWriteTimeout        = 5 * time.Second  // 默认写入超时
+	defaultReadBufferSize      = 16 << 10         // 每个连接的读缓冲区大小，同时是可读取的上游响应头总大小上限
 )
 
 // HTTPConnectionPool 管理 TCP 连接池
@@ -22,6 +24,10 @@ type HTTPConnectionPool struct {
 	clients   sync.Map // map[string]*fasthttp.HostClient，使用 sync.Map 提升并发性能
 	cfg       *config.Config
 	cleanupCh chan struct{} // 清理信号通道
+
+	preserveRawPath atomic.Bool // 新建的 HostClient 是否保留请求路径的原始编码
+
+	unsubscribe func() // 取消健康状态变化回调，未注册时为 nil
 }
 
 // NewHTTPConnectionPool 创建并初始化连接池实例
@@ -30,6 +36,7 @@ func NewHTTPConnectionPool(cfg *config.Config) *HTTPConnectionPool {
 		cfg:       cfg,
 		cleanupCh: make(chan struct{}),
 	}
+	pool.preserveRawPath.Store(cfg.Routing.PreserveRawPath)
 
 	if cfg.Performance.HttpPoolEnabled {
 		pool.initializePool(cfg)
@@ -94,8 +101,46 @@ func normalizeTarget(target string) (string, error) {
 	return u.Host, nil
 }
 
-// Close 关闭连接池
+// Evict 关闭并移除指定目标的 HostClient，下次请求时重新建立连接
+func (p *HTTPConnectionPool) Evict(target string) {
+	host, err := normalizeTarget(target)
+	if err != nil {
+		return
+	}
+	if client, ok := p.clients.LoadAndDelete(host); ok {
+		client.(*fasthttp.HostClient).CloseIdleConnections()
+		logger.Info("Evicted HostClient for unhealthy target",
+			zap.String("host", host))
+	}
+}
+
+// setPreserveRawPath 更新是否保留请求路径的原始编码
+// HostClient 创建后无法修改该设置，变化时移除已有的 HostClient，后续请求按新设置重新创建
+func (p *HTTPConnectionPool) setPreserveRawPath(preserve bool) {
+	if p.preserveRawPath.Swap(preserve) == preserve {
+		return
+	}
+	p.clients.Range(func(key, value interface{}) bool {
+		p.clients.Delete(key)
+		value.(*fasthttp.HostClient).CloseIdleConnections()
+		return true
+	})
+	logger.Info("Recreating HostClients after raw path setting changed",
+		zap.Bool("preserveRawPath", preserve))
+}
+
+// handleTargetStatus 处理健康检查状态变化，目标不可用时按配置移除其 HostClient
+func (p *HTTPConnectionPool) handleTargetStatus(target string, healthy bool) {
+	if !healthy && p.cfg.Performance.EvictUnhealthyClients {
+		p.Evict(target)
+	}
+}
+
+// Close 关闭连接池，并取消健康状态变化回调
 func (p *HTTPConnectionPool) Close() {
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+	}
 	close(p.cleanupCh)
 	p.clients.Range(func(key, value interface{}) bool {
 		p.clients.Delete(key)
@@ -108,9 +153,13 @@ func (p *HTTPConnectionPool) Close() {
 func (p *HTTPConnectionPool) newHostClient(addr string) *fasthttp.HostClient {
 	return &fasthttp.HostClient{
 		Addr:                addr,
+		Dial:                dialFastHTTPUpstream,
 		MaxConns:            p.cfg.Performance.MaxConnsPerHost,
 		MaxIdleConnDuration: defaultMaxIdleConnDuration,
 		ReadTimeout:         defaultReadTimeout,
 		WriteTimeout:        defaultWriteTimeout,
+		ReadBufferSize:      defaultReadBufferSize,
+		// 保留原始编码路径时禁止 fasthttp 规范化路径，避免 %2F 等编码被解码
+		DisablePathNormalizing: p.preserveRawPath.Load(),
 	}
 }
@@ -154,11 +154,14 @@ func TestClose(t *testing.T) {
 		},
 	}
 	pool := NewHTTPConnectionPool(cfg)
+	unsubscribed := 0
+	pool.unsubscribe = func() { unsubscribed++ }
 
 	// 添加一个测试客户端
 	pool.GetClient("http://test.com")
 
 	pool.Close()
+	assert.Equal(t, 1, unsubscribed, "Close should unsubscribe from health status changes")
 
 	// 验证 cleanupCh 已关闭
 	select {
@@ -176,3 +179,46 @@ func TestClose(t *testing.T) {
 	})
 	assert.Equal(t, 0, count)
 }
+
+func TestHandleTargetStatus_EvictUnhealthyClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		evict       bool
+		wantNewConn bool
+	}{
+		{name: "Eviction enabled", evict: true, wantNewConn: true},
+		{name: "Eviction disabled", evict: false, wantNewConn: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewHTTPConnectionPool(&config.Config{
+				Performance: config.Performance{
+					HttpPoolEnabled:       true,
+					MaxConnsPerHost:       100,
+					EvictUnhealthyClients: tt.evict,
+				},
+			})
+			target := "http://localhost:8080"
+
+			before, err := pool.GetClient(target)
+			assert.NoError(t, err)
+
+			// 目标被标记为不可用后恢复
+			pool.handleTargetStatus("localhost:8080", false)
+			pool.handleTargetStatus("localhost:8080", true)
+
+			after, err := pool.GetClient(target)
+			assert.NoError(t, err)
+			if tt.wantNewConn {
+				assert.NotSame(t, before, after, "client should be recreated after target recovers")
+			} else {
+				assert.Same(t, before, after, "client should be reused when eviction is disabled")
+			}
+
+			again, err := pool.GetClient(target)
+			assert.NoError(t, err)
+			assert.Same(t, after, again)
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package proxy
 
 import (
+	"errors"
+	"hash/fnv"
 	"math/rand"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/penwyp/mini-gateway/pkg/util"
@@ -14,7 +18,10 @@ import (
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/health"
 	"github.com/penwyp/mini-gateway/internal/core/loadbalancer"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
 	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/penwyp/mini-gateway/pkg/signing"
+	"github.com/penwyp/mini-gateway/plugins"
 	"github.com/valyala/fasthttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -33,10 +40,14 @@ var httpTracer = otel.Tracer("proxy:http")
 
 // HTTPProxy 管理 HTTP 代理功能
 type HTTPProxy struct {
-	httpPool        *HTTPConnectionPool       // HTTP 连接池
-	loadBalancer    loadbalancer.LoadBalancer // 负载均衡器
-	objectPool      *util.ObjectPoolManager   // 对象池管理器
-	httpPoolEnabled bool                      // 是否启用 HTTP 连接池
+	httpPool        *HTTPConnectionPool           // HTTP 连接池
+	loadBalancer    loadbalancer.LoadBalancer     // 负载均衡器
+	objectPool      *util.ObjectPoolManager       // 对象池管理器
+	httpPoolEnabled bool                          // 是否启用 HTTP 连接池
+	settings        atomic.Pointer[proxySettings] // 随配置热更新整体替换的转发设置
+	retry           atomic.Pointer[retryPolicy]   // 上游请求失败时的重试策略，配置热更新时替换
+	lbSettings      loadBalancerSettings          // 创建当前负载均衡器所用的配置
+	drains          sync.WaitGroup                // 扇出达到法定数量后仍在统计剩余结果的协程，关闭代理时等待其退出
 
 	selectTargetFunc  func(c *gin.Context, rules config.RoutingRules) (string, string)
 	proxyWithPoolFunc func(c *gin.Context, target, env string)
@@ -48,12 +59,86 @@ func NewHTTPProxy(cfg *config.Config) *HTTPProxy {
 	logPoolStatus(cfg.Performance.HttpPoolEnabled)
 	logGrayscaleStatus(cfg.Routing.Grayscale)
 
-	return &HTTPProxy{
-		httpPool:        NewHTTPConnectionPool(cfg),
+	httpPool := NewHTTPConnectionPool(cfg)
+	if checker := health.GetGlobalHealthChecker(); checker != nil {
+		httpPool.unsubscribe = checker.OnStatusChange(httpPool.handleTargetStatus)
+	}
+
+	hp := &HTTPProxy{
+		httpPool:        httpPool,
 		loadBalancer:    lb,
 		objectPool:      util.NewPoolManager(cfg),
 		httpPoolEnabled: cfg.Performance.HttpPoolEnabled,
+		lbSettings:      newLoadBalancerSettings(cfg),
+	}
+	hp.RefreshSettings(cfg)
+	hp.RefreshRetryPolicy(cfg)
+	return hp
+}
+
+// proxySettings 按配置生成的转发设置，配置热更新时整体替换
+type proxySettings struct {
+	signer          *signing.Signer   // 转发请求的签名器，未启用签名时为 nil
+	defaultHeaders  map[string]string // 所有转发请求补充的默认请求头，名称为规范形式
+	trustForwarded  bool              // 是否信任客户端请求自带的 X-Forwarded-* 与 X-Real-IP
+	headerLimit     headerLimit       // 上游响应头大小限制
+	preserveRawPath bool              // 是否保留请求路径的原始编码
+	passthroughGRPC bool              // 为 true 时不拦截 HTTP 路由上游返回的 gRPC 响应
+}
+
+// newProxySettings 按配置生成转发设置
+func newProxySettings(cfg *config.Config) *proxySettings {
+	return &proxySettings{
+		signer:          newRequestSigner(cfg.Routing.Signing),
+		defaultHeaders:  canonicalHeaders(cfg.Routing.DefaultHeaders),
+		trustForwarded:  cfg.Routing.TrustForwarded,
+		headerLimit:     newHeaderLimit(cfg.Routing.ResponseHeaders),
+		preserveRawPath: cfg.Routing.PreserveRawPath,
+		passthroughGRPC: cfg.Routing.ProtocolMismatch == "passthrough",
+	}
+}
+
+// RefreshSettings 按配置更新转发设置，已在转发中的请求沿用原设置
+func (hp *HTTPProxy) RefreshSettings(cfg *config.Config) {
+	settings := newProxySettings(cfg)
+	hp.settings.Store(settings)
+	if hp.httpPool != nil {
+		hp.httpPool.setPreserveRawPath(settings.preserveRawPath)
+	}
+}
+
+// proxySettings 返回当前的转发设置，未设置时返回零值
+func (hp *HTTPProxy) proxySettings() *proxySettings {
+	if settings := hp.settings.Load(); settings != nil {
+		return settings
 	}
+	return &proxySettings{}
+}
+
+// Close 等待扇出剩余结果统计完成，关闭连接池并取消其健康状态变化回调
+func (hp *HTTPProxy) Close() {
+	if hp == nil {
+		return
+	}
+	hp.drains.Wait()
+	if hp.httpPool == nil {
+		return
+	}
+	hp.httpPool.Close()
+}
+
+// RefreshRetryPolicy 按配置更新上游重试策略，已在重试中的请求沿用原策略
+func (hp *HTTPProxy) RefreshRetryPolicy(cfg *config.Config) {
+	policy := newRetryPolicy(cfg.Routing.Retry)
+	hp.retry.Store(&policy)
+}
+
+// retryPolicy 返回当前的重试策略，未设置时不重试
+func (hp *HTTPProxy) retryPolicy() retryPolicy {
+	if policy := hp.retry.Load(); policy != nil {
+		return *policy
+	}
+	return retryPolicy{}
 }
 
 // logGrayscaleStatus 记录灰度发布配置状态
@@ -72,13 +157,55 @@ func (hp *HTTPProxy) GetLoadBalancerType() string {
 	return hp.loadBalancer.Type()
 }
 
+// GetLoadBalancerActiveTargets 获取负载均衡器当前的活跃目标，不支持时返回 nil
+func (hp *HTTPProxy) GetLoadBalancerActiveTargets() []string {
+	if hp == nil || hp.loadBalancer == nil {
+		return nil
+	}
+	if reporter, ok := hp.loadBalancer.(loadbalancer.ActiveTargetsReporter); ok {
+		return reporter.ActiveTargets()
+	}
+	return nil
+}
+
 // RefreshLoadBalancer 刷新负载均衡器
+// 负载均衡算法及其参数未变化时就地更新目标，未变化路由的选择状态（如加权轮询计数）得以保留，否则重新创建
 func (hp *HTTPProxy) RefreshLoadBalancer(cfg *config.Config) {
+	settings := newLoadBalancerSettings(cfg)
+	if settings == hp.lbSettings && loadbalancer.UpdateTargets(hp.loadBalancer, cfg) {
+		logger.Info("HTTPProxy load balancer targets updated in place",
+			zap.String("loadBalancerType", cfg.Routing.LoadBalancer))
+		return
+	}
 	hp.loadBalancer = initializeLoadBalancer(cfg)
+	hp.lbSettings = settings
 	logger.Info("HTTPProxy load balancer refreshed",
 		zap.String("loadBalancerType", cfg.Routing.LoadBalancer))
 }
 
+// loadBalancerSettings 决定负载均衡器类型及构造参数的配置，变化时需要重新创建负载均衡器
+type loadBalancerSettings struct {
+	algorithm     string
+	sharedCounter bool
+	stickyTTL     time.Duration
+	hashKey       string
+	virtualNodes  int
+	consulAddr    string
+	ewma          config.EWMA
+}
+
+func newLoadBalancerSettings(cfg *config.Config) loadBalancerSettings {
+	return loadBalancerSettings{
+		algorithm:     cfg.Routing.LoadBalancer,
+		sharedCounter: cfg.Routing.SharedCounter,
+		stickyTTL:     cfg.Routing.StickyTTL,
+		hashKey:       cfg.Routing.HashKey,
+		virtualNodes:  cfg.Routing.Ketama.VirtualNodes,
+		consulAddr:    cfg.Consul.Addr,
+		ewma:          cfg.Routing.EWMA,
+	}
+}
+
 // SetupHTTPProxy 配置 HTTP 代理路由
 func (hp *HTTPProxy) SetupHTTPProxy(r gin.IRouter, cfg *config.Config) {
 	rules := cfg.Routing.GetHTTPRules()
@@ -106,19 +233,65 @@ func (hp *HTTPProxy) CreateHTTPHandler(rules config.RoutingRules) gin.HandlerFun
 		defer span.End()
 
 		c.Request = c.Request.WithContext(ctx)
-		target, selectedEnv := hp.getSelectTarget(c, rules)
-		if target == "" {
-			handleNoTarget(c, span, c.Request.URL.Path, getEnvFromHeader(c))
+		hostRules := rules.ForHost(c.Request.Host)
+		if len(hostRules) == 0 && len(rules) > 0 {
+			handleHostNotFound(c, span)
+			return
+		}
+		// 只从允许该方法的规则中选择目标，同一路径下限定了方法的规则不会被选中处理其他方法的请求
+		methodRules := hostRules.ForMethod(c.Request.Method)
+		if len(methodRules) == 0 && len(hostRules) > 0 {
+			handleMethodNotAllowed(c, span, hostRules)
+			return
+		}
+		hostRules = methodRules
+		// 插件可在转发前检查或修改请求，中止时不再转发
+		if plugins.InterceptRequest(c); c.IsAborted() {
+			return
+		}
+		if fanOut, ok := getFanOutRule(c); ok {
+			hp.proxyFanOut(c, hostRules, fanOut)
 			return
 		}
+		if window, ok := getCoalesceWindow(c); ok {
+			coalesceRequest(c, window, func() { hp.forward(c, span, hostRules) })
+			return
+		}
+		hp.forward(c, span, hostRules)
+	}
+}
 
-		span.SetAttributes(attribute.String("proxy.target", target))
-		if hp.httpPoolEnabled {
+// forward 选择目标并将请求转发到该目标
+func (hp *HTTPProxy) forward(c *gin.Context, span trace.Span, rules config.RoutingRules) {
+	target, selectedEnv := hp.getSelectTarget(c, rules)
+	if target == "" {
+		handleNoTarget(c, span, rules, getEnvFromHeader(c))
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("proxy.target", target),
+		attribute.String("http.request_id", c.GetString("request_id")),
+	)
+	c.Set("proxy_target", target)   // 供访问日志记录匹配到的目标
+	c.Set("proxy_env", selectedEnv) // 供调试响应头展示上游选择信息
+	if hp.loadBalancer != nil {
+		c.Set("proxy_balancer", hp.loadBalancer.Type())
+	}
+	transform, _ := findRequestTransform(rules, target)
+	setRequestTransform(c, transform)
+	setResponseFilter(c, findResponseFilter(rules, target))
+	setDefaultHeaders(c, findDefaultHeaders(hp.proxySettings().defaultHeaders, rules, target))
+	GuardTarget(c, target, func() {
+		if mirror, ok := findMirror(rules, target); ok {
+			mirrorRequest(c, mirror)
+		}
+		if hp.httpPoolEnabled && !acceptsEventStream(c.Request) {
 			hp.getProxyWithPool(c, target, selectedEnv)
 		} else {
 			hp.proxyDirect(c, target, selectedEnv)
 		}
-	}
+	})
 }
 
 // proxyDirect 使用直接代理方式转发请求
@@ -136,9 +309,31 @@ func (hp *HTTPProxy) proxyDirect(c *gin.Context, target, env string) {
 		return
 	}
 
+	if requestID := c.GetString("request_id"); requestID != "" {
+		c.Request.Header.Set("X-Request-ID", requestID)
+	}
+	missingDefaultHeaders(c, c.Request.Header.Set)
+	// 过滤响应字段需要未压缩的响应体
+	if _, filtering := responseFilter(c); filtering {
+		c.Request.Header.Set("Accept-Encoding", "identity")
+	}
+
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-	proxy.Director = hp.createDirector(targetURL, env)
-	proxy.ErrorHandler = hp.createErrorHandler(target, span)
+	transform, _ := requestTransform(c)
+	proxy.Director = hp.createDirector(targetURL, env, transform)
+	// 错误处理函数已自行记录请求结果，此时不再按上游状态码重复记录
+	failed := false
+	handleError := hp.createErrorHandler(c, target, span)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		failed = true
+		handleError(w, r, err)
+	}
+	proxy.ModifyResponse = hp.modifyResponse(c, target)
+	proxy.Transport = &retryTransport{
+		base:   &upstreamTimingTransport{base: upstreamTransport, target: target, lb: hp.loadBalancer},
+		policy: hp.retryPolicy(),
+		target: target,
+	}
 
 	logger.Info("Routing HTTP request",
 		zap.String("path", c.Request.URL.Path),
@@ -149,8 +344,11 @@ func (hp *HTTPProxy) proxyDirect(c *gin.Context, target, env string) {
 	// 包装 c.Writer，使其满足 http.CloseNotifier 接口要求
 	wrappedWriter := &closeNotifyResponseWriter{c.Writer}
 	proxy.ServeHTTP(wrappedWriter, c.Request)
+	if failed {
+		return
+	}
 	span.SetStatus(codes.Ok, "HTTP proxy completed successfully")
-	health.GetGlobalHealthChecker().UpdateRequestCount(target, true)
+	recordUpstreamOutcome(c, target)
 }
 
 // proxyWithPool 使用连接池代理转发请求
@@ -170,17 +368,73 @@ func (hp *HTTPProxy) proxyWithPool(c *gin.Context, target, env string) {
 	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseRequest(req)
 	defer fasthttp.ReleaseResponse(resp)
+	// 未声明长度的响应（如分块传输）以流的形式读取，由 writeFastHTTPResponse 边读边写
+	resp.StreamBody = true
+	defer discardBodyStream(resp)
 
 	hp.prepareFastHTTPRequest(c, req, target, env)
 
-	if err := client.Do(req, resp); err != nil {
+	start := time.Now()
+	err = fastHTTPUpstreamError(client.Addr, doWithRetry(c.Request.Context(), client, req, resp, hp.retryPolicy(), target))
+	elapsed := time.Since(start)
+	observability.UpstreamDuration.WithLabelValues(target).Observe(elapsed.Seconds())
+	loadbalancer.ObserveLatency(hp.loadBalancer, target, elapsed, err != nil || resp.StatusCode() >= http.StatusInternalServerError)
+	if err != nil {
+		SetUpstreamStatus(c, 0)
+		if protocol := upstreamProtocol(err); protocol != "" {
+			handleProtocolMismatch(c.Writer, c.Request, span, target, protocol, err)
+			return
+		}
 		handleProxyError(c, span, target, "Backend service unavailable", err)
 		return
 	}
+	SetUpstreamStatus(c, resp.StatusCode())
+	if !hp.proxySettings().passthroughGRPC && isGRPCResponse(string(resp.Header.ContentType()), len(resp.Header.Peek(grpcStatusKey)) > 0) {
+		handleProtocolMismatch(c.Writer, c.Request, span, target, protocolGRPC, errGRPCUpstream)
+		return
+	}
 
-	hp.writeFastHTTPResponse(c, resp)
+	hp.writeFastHTTPResponse(c, resp, target)
 	span.SetStatus(codes.Ok, "HTTP proxy completed successfully")
-	health.GetGlobalHealthChecker().UpdateRequestCount(target, true)
+	recordUpstreamOutcome(c, target)
+}
+
+// modifyResponse 创建直接代理模式的 ModifyResponse：记录上游状态码，限制上游响应头大小，拒绝 HTTP 路由上的 gRPC 响应，并调用插件的响应拦截器
+func (hp *HTTPProxy) modifyResponse(c *gin.Context, target string) func(*http.Response) error {
+	settings := hp.proxySettings()
+	return func(resp *http.Response) error {
+		SetUpstreamStatus(c, resp.StatusCode)
+		if !settings.passthroughGRPC {
+			if err := rejectGRPCResponse(resp); err != nil {
+				return err
+			}
+		}
+		settings.headerLimit.apply(resp.Header, target)
+		if fields, ok := responseFilter(c); ok {
+			if err := filterHTTPResponse(resp, fields); err != nil {
+				return err
+			}
+		}
+		plugins.InterceptResponse(c, resp.StatusCode, resp.Header)
+		return nil
+	}
+}
+
+// upstreamTimingTransport 记录直接代理模式下后端往返耗时（至收到响应头为止），并交给负载均衡器用于按响应时间选择目标
+type upstreamTimingTransport struct {
+	base   http.RoundTripper
+	target string
+	lb     loadbalancer.LoadBalancer
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *upstreamTimingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+	observability.UpstreamDuration.WithLabelValues(t.target).Observe(elapsed.Seconds())
+	loadbalancer.ObserveLatency(t.lb, t.target, elapsed, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
 }
 
 // initializeLoadBalancer 初始化负载均衡器
@@ -189,9 +443,19 @@ func initializeLoadBalancer(cfg *config.Config) loadbalancer.LoadBalancer {
 	if err != nil {
 		logger.Error("Failed to initialize load balancer",
 			zap.Error(err))
-		return loadbalancer.NewRoundRobin()
+		lb = loadbalancer.NewRoundRobin()
 	}
-	return lb
+	return loadbalancer.NewDrainAware(lb, isTargetDrained).WithWarmup(targetWarmup)
+}
+
+// targetWarmup 返回目标当前可承接的流量比例，尚未通过就绪探测时为 0，慢启动期间逐步增加到 1
+func targetWarmup(target string) float64 {
+	return health.GetGlobalHealthChecker().WarmupFactor(target)
+}
+
+// isTargetDrained 判断目标是否被管理员下线
+func isTargetDrained(target string) bool {
+	return health.GetGlobalHealthChecker().IsDrained(target)
 }
 
 // logPoolStatus 记录连接池状态
@@ -212,6 +476,39 @@ func getEnvFromHeader(c *gin.Context) string {
 	return defaultEnv
 }
 
+// RequestEnv 返回请求的目标环境，供路由追踪等模块使用
+func RequestEnv(c *gin.Context) string {
+	return getEnvFromHeader(c)
+}
+
+// selectEnv 确定请求的目标环境：请求头指定时优先使用，否则按路由配置的灰度比例分流
+func selectEnv(c *gin.Context, rules config.RoutingRules, grayscale config.Grayscale) string {
+	if env := c.GetHeader("X-Env"); env != "" {
+		return env
+	}
+	if percentage := rules.CanaryPercentage(canaryEnv); percentage > 0 && canaryBucket(c, grayscale.SessionCookie) < percentage {
+		return canaryEnv
+	}
+	return defaultEnv
+}
+
+// canaryBucket 将请求映射到 [0, 100) 的分桶，同一会话或客户端 IP 始终落在同一分桶，两者都缺失时随机分桶
+func canaryBucket(c *gin.Context, sessionCookie string) int {
+	key := ""
+	if sessionCookie != "" {
+		key, _ = c.Cookie(sessionCookie)
+	}
+	if key == "" {
+		key = c.ClientIP()
+	}
+	if key == "" {
+		return rand.Intn(100)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
 // filterRules 根据环境过滤路由规则
 func (hp *HTTPProxy) filterRules(rules config.RoutingRules, env string) config.RoutingRules {
 	filtered := hp.objectPool.GetRules(len(rules))
@@ -228,6 +525,17 @@ func (hp *HTTPProxy) filterRules(rules config.RoutingRules, env string) config.R
 		}
 		return filtered
 	}
+	// 按比例灰度时，未分到灰度的流量不进入灰度目标，保证灰度目标只承接配置的比例
+	if rules.CanaryPercentage(canaryEnv) > 0 {
+		for _, rule := range rules {
+			if rule.Env != canaryEnv {
+				filtered = append(filtered, rule)
+			}
+		}
+		if len(filtered) > 0 {
+			return filtered
+		}
+	}
 	return append(filtered, rules...)
 }
 
@@ -250,6 +558,7 @@ func (hp *HTTPProxy) selectTarget(c *gin.Context, rules config.RoutingRules) (st
 	defer span.End()
 
 	c.Request = c.Request.WithContext(ctx)
+	rules = excludeEjectedRules(rules)
 	cfg := config.GetConfig()
 	grayscale := cfg.Routing.Grayscale
 	if !grayscale.Enabled {
@@ -257,7 +566,7 @@ func (hp *HTTPProxy) selectTarget(c *gin.Context, rules config.RoutingRules) (st
 	}
 
 	// 灰度发布启用时的逻辑
-	env := getEnvFromHeader(c)
+	env := selectEnv(c, rules, grayscale)
 	targetRules := hp.filterRulesWithFallback(rules, env, grayscale)
 	defer hp.objectPool.PutRules(targetRules)
 
@@ -270,8 +579,59 @@ func (hp *HTTPProxy) selectTarget(c *gin.Context, rules config.RoutingRules) (st
 	return hp.selectWithLoadBalancer(c, targetRules, targetRules[0].Env)
 }
 
+// excludeEjectedRules 排除被动健康检查剔除的目标，全部被剔除时保留原规则以避免整体不可用
+func excludeEjectedRules(rules config.RoutingRules) config.RoutingRules {
+	checker := health.GetGlobalHealthChecker()
+	var healthy config.RoutingRules
+	for i, rule := range rules {
+		if !checker.IsEjected(rule.Target) {
+			if healthy != nil {
+				healthy = append(healthy, rule)
+			}
+			continue
+		}
+		if healthy == nil {
+			healthy = append(make(config.RoutingRules, 0, len(rules)), rules[:i]...)
+		}
+	}
+	if healthy == nil {
+		return rules
+	}
+	if len(healthy) == 0 {
+		logger.Warn("All targets ejected by outlier detection, ignoring ejection",
+			zap.Int("targetCount", len(rules)))
+		return rules
+	}
+	return healthy
+}
+
+// excludeDrainedRules 排除被管理员下线或尚未通过就绪探测的目标，不经过负载均衡器的选择逻辑需自行过滤
+func excludeDrainedRules(rules config.RoutingRules) config.RoutingRules {
+	var available config.RoutingRules
+	for i, rule := range rules {
+		if !isTargetDrained(rule.Target) && targetWarmup(rule.Target) > 0 {
+			if available != nil {
+				available = append(available, rule)
+			}
+			continue
+		}
+		if available == nil {
+			available = append(make(config.RoutingRules, 0, len(rules)), rules[:i]...)
+		}
+	}
+	if available == nil {
+		return rules
+	}
+	return available
+}
+
 // selectWithWeightedRandom 使用权重随机选择目标
 func (hp *HTTPProxy) selectWithWeightedRandom(rules config.RoutingRules, path string) (string, string) {
+	rules = excludeDrainedRules(rules)
+	if len(rules) == 0 {
+		logger.Warn("All targets are drained", zap.String("path", path))
+		return "", ""
+	}
 	selectedRule := WeightedRandomSelect(rules)
 	if selectedRule == nil {
 		logger.Warn("Weighted random selection failed, using first rule",
@@ -337,83 +697,202 @@ func (hp *HTTPProxy) filterRulesWithFallback(rules config.RoutingRules, env stri
 	return filtered
 }
 
-// handleNoTarget 处理无可用目标的情况
-func handleNoTarget(c *gin.Context, span trace.Span, path, env string) {
+// handleNoTarget 处理无可用目标的情况，路由配置了降级响应时返回降级响应
+func handleNoTarget(c *gin.Context, span trace.Span, rules config.RoutingRules, env string) {
 	span.SetStatus(codes.Error, "No available target")
 	logger.Warn("No target available for request",
-		zap.String("path", path),
+		zap.String("path", c.Request.URL.Path),
 		zap.String("env", env))
-	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No available target"})
+	if WriteFallback(c, rules) {
+		return
+	}
+	WriteError(c, http.StatusServiceUnavailable, ErrCodeNoTarget, "No available target")
+}
+
+// handleHostNotFound 处理路由不服务该 Host 的请求，与未匹配到路由一样返回 404
+func handleHostNotFound(c *gin.Context, span trace.Span) {
+	span.SetStatus(codes.Error, "Host not routed")
+	logger.Warn("No routing rule for request host",
+		zap.String("path", c.Request.URL.Path),
+		zap.String("host", c.Request.Host))
+	WriteError(c, http.StatusNotFound, ErrCodeRouteNotFound, "Route not found")
+}
+
+// handleMethodNotAllowed 处理路由不允许的请求方法，返回 405 并在 Allow 头中列出允许的方法
+func handleMethodNotAllowed(c *gin.Context, span trace.Span, rules config.RoutingRules) {
+	span.SetStatus(codes.Error, "Method not allowed")
+	logger.Warn("Request method not allowed on route",
+		zap.String("path", c.Request.URL.Path),
+		zap.String("method", c.Request.Method))
+	c.Header("Allow", strings.Join(rules.AllowedMethods(), ", "))
+	WriteError(c, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
 }
 
 // handleProxyError 处理代理错误
 func handleProxyError(c *gin.Context, span trace.Span, target, msg string, err error) {
 	span.RecordError(err)
 	span.SetStatus(codes.Error, "Proxy error")
-	health.GetGlobalHealthChecker().UpdateRequestCount(target, false)
+	updateRequestCount(target, false)
 	logger.Error("HTTP proxy request failed",
 		zap.String("target", target),
 		zap.String("message", msg),
 		zap.Error(err))
-	c.JSON(http.StatusBadGateway, gin.H{"error": msg})
+	WriteError(c, http.StatusBadGateway, ErrCodeBadGateway, msg)
 }
 
-// createDirector 创建代理请求的 Director 函数
-func (hp *HTTPProxy) createDirector(targetURL *url.URL, env string) func(*http.Request) {
+// createDirector 创建代理请求的 Director 函数，配置了请求体改写时改写 JSON 请求体
+func (hp *HTTPProxy) createDirector(targetURL *url.URL, env string, transform config.RequestTransform) func(*http.Request) {
+	settings := hp.proxySettings()
+	director := defaultDirector(targetURL)
+	if settings.preserveRawPath {
+		director = rawPathDirector(targetURL)
+	}
 	return func(req *http.Request) {
-		defaultDirector(targetURL)(req)
+		forwarded := newForwardedHeaders(req, settings.trustForwarded)
+		director(req)
+		forwarded.applyToHTTP(req.Header)
 		if env == canaryEnv {
 			req.Header.Set("X-Env", canaryEnv)
 		}
+		if transform.Enabled() {
+			applyRequestTransform(req, transform)
+		}
+		// 签名使用改写后实际发往上游的路径
+		if settings.signer != nil {
+			settings.signer.Apply(req.Method, req.URL.Path, req.URL.RawQuery, time.Now(), req.Header.Set)
+		}
 	}
 }
 
 // createErrorHandler 创建代理错误处理函数
-func (hp *HTTPProxy) createErrorHandler(target string, span trace.Span) func(http.ResponseWriter, *http.Request, error) {
+func (hp *HTTPProxy) createErrorHandler(c *gin.Context, target string, span trace.Span) func(http.ResponseWriter, *http.Request, error) {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
+		// ModifyResponse 拒绝响应时已记录上游状态码，只有未收到上游响应时才记为 0
+		if _, forwarded := UpstreamStatus(c); !forwarded {
+			SetUpstreamStatus(c, 0)
+		}
+		if protocol := upstreamProtocol(err); protocol != "" {
+			handleProtocolMismatch(w, r, span, target, protocol, err)
+			return
+		}
+		if errors.Is(err, errResponseNotFiltered) {
+			span.RecordError(err)
+			writeResponseNotFiltered(w, r, target, err)
+			return
+		}
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Proxy error")
-		health.GetGlobalHealthChecker().UpdateRequestCount(target, false)
+		updateRequestCount(target, false)
 		logger.Error("HTTP proxy request failed",
 			zap.String("path", r.URL.Path),
 			zap.String("target", target),
 			zap.Error(err))
-		w.WriteHeader(http.StatusBadGateway)
-		w.Write([]byte("Bad Gateway"))
+		writeHTTPError(w, r, http.StatusBadGateway, ErrCodeBadGateway, "Backend service unavailable")
 	}
 }
 
 // prepareFastHTTPRequest 准备 FastHTTP 请求
 func (hp *HTTPProxy) prepareFastHTTPRequest(c *gin.Context, req *fasthttp.Request, target, env string) {
-	reqURI := "http://" + target + c.Request.URL.Path
+	settings := hp.proxySettings()
+	path := c.Request.URL.Path
+	if settings.preserveRawPath {
+		path = c.Request.URL.EscapedPath()
+	}
+	// 目标可以是 URL 或 host:port，请求 URI 只使用其中的主机部分
+	host, err := normalizeTarget(target)
+	if err != nil {
+		host = target
+	}
+	reqURI := "http://" + host + path
 	if c.Request.URL.RawQuery != "" {
 		reqURI += "?" + c.Request.URL.RawQuery
 	}
 	req.SetRequestURI(reqURI)
 	req.Header.SetMethod(c.Request.Method)
 
+	connection := strings.Join(c.Request.Header.Values("Connection"), ",")
 	for key, values := range c.Request.Header {
+		if isHopHeader(key, connection) {
+			continue
+		}
 		for _, value := range values {
 			req.Header.Add(key, value)
 		}
 	}
+	newForwardedHeaders(c.Request, settings.trustForwarded).applyToFastHTTP(&req.Header)
+	missingDefaultHeaders(c, req.Header.Set)
+	// 过滤响应字段需要未压缩的响应体
+	if _, filtering := responseFilter(c); filtering {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
 	if env == canaryEnv {
 		req.Header.Set("X-Env", canaryEnv)
 	}
+	if requestID := c.GetString("request_id"); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	if settings.signer != nil {
+		settings.signer.Apply(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, time.Now(), req.Header.Set)
+	}
 	if c.Request.Body != nil {
 		if body, err := c.GetRawData(); err == nil {
+			if transform, ok := requestTransform(c); ok {
+				body = transformRequestBody(transform, c.GetHeader("Content-Type"), body)
+			}
 			req.SetBody(body)
 		}
 	}
 }
 
-// writeFastHTTPResponse 写入 FastHTTP 响应
-func (hp *HTTPProxy) writeFastHTTPResponse(c *gin.Context, resp *fasthttp.Response) {
+// writeFastHTTPResponse 写入 FastHTTP 响应，超出大小上限的响应头按配置删除或截断，配置了响应字段过滤时过滤 JSON 响应体
+// 以流的形式读取的响应体边读边写，需要过滤的 JSON 响应体读取后再过滤，无法过滤时返回 502；写出响应体之前调用插件的响应拦截器
+func (hp *HTTPProxy) writeFastHTTPResponse(c *gin.Context, resp *fasthttp.Response, target string) {
+	headerLimit := hp.proxySettings().headerLimit
+	fields, filtering := responseFilter(c)
+	streaming := resp.IsBodyStream() && !(filtering && isJSONContentType(string(resp.Header.ContentType())))
+	var body []byte
+	filtered := false
+	if !streaming {
+		var err error
+		if filtering {
+			body, err = readFilterableBody(resp)
+			if err == nil {
+				body, filtered, err = filterResponseBody(fields, string(resp.Header.ContentType()), string(resp.Header.ContentEncoding()), body)
+			}
+		} else {
+			body = resp.Body()
+		}
+		if err != nil {
+			writeResponseNotFiltered(c.Writer, c.Request, target, err)
+			return
+		}
+	}
+
 	c.Status(resp.StatusCode())
+	connection := string(resp.Header.Peek("Connection"))
 	resp.Header.VisitAll(func(key, value []byte) {
-		c.Header(string(key), string(value))
+		if isHopHeader(string(key), connection) {
+			return
+		}
+		// 过滤后响应体长度改变，由 net/http 重新计算 Content-Length
+		if filtered && strings.EqualFold(string(key), "Content-Length") {
+			return
+		}
+		if value, ok := headerLimit.fit(target, string(key), string(value)); ok {
+			c.Header(string(key), value)
+		}
 	})
-	c.Writer.Write(resp.Body())
+	plugins.InterceptResponse(c, resp.StatusCode(), c.Writer.Header())
+	if streaming {
+		if err := streamFastHTTPBody(c, resp); err != nil {
+			logger.Warn("Failed to stream upstream response",
+				zap.String("path", c.Request.URL.Path),
+				zap.String("target", target),
+				zap.Error(err))
+		}
+		return
+	}
+	c.Writer.Write(body)
 }
 
 // defaultDirector 创建默认的代理请求 Director 函数，用于将请求转发到目标 URL
@@ -431,6 +910,28 @@ func defaultDirector(targetURL *url.URL) func(req *http.Request) {
 	}
 }
 
+// rawPathDirector 创建保留原始编码路径的 Director 函数，同时合并 Path 与 RawPath
+func rawPathDirector(targetURL *url.URL) func(req *http.Request) {
+	return func(req *http.Request) {
+		req.URL.Scheme = targetURL.Scheme
+		req.URL.Host = targetURL.Host
+		req.URL.Path, req.URL.RawPath = joinURLPath(targetURL, req.URL)
+		req.Host = targetURL.Host
+		logger.Debug("Forwarding proxy request with raw path",
+			zap.String("rawPath", req.URL.EscapedPath()),
+			zap.String("forwardedURL", req.URL.String()),
+		)
+	}
+}
+
+// joinURLPath 合并目标与请求的路径，任一方带有原始编码时同时返回合并后的 RawPath
+func joinURLPath(a, b *url.URL) (path, rawPath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return SingleJoiningSlash(a.Path, b.Path), ""
+	}
+	return SingleJoiningSlash(a.Path, b.Path), SingleJoiningSlash(a.EscapedPath(), b.EscapedPath())
+}
+
 // SingleJoiningSlash 合并两个路径段，确保它们之间恰好有一个斜杠
 func SingleJoiningSlash(a, b string) string {
 	aslash := strings.HasSuffix(a, "/") // 检查 a 是否以斜杠结尾
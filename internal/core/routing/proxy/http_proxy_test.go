@@ -2,14 +2,18 @@ package proxy
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/penwyp/mini-gateway/internal/core/health"
-	"github.com/penwyp/mini-gateway/pkg/util"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/valyala/fasthttp"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -18,19 +22,48 @@ import (
 	"github.com/penwyp/mini-gateway/config"
 )
 
+// testProxyOption 调整 newTestProxy 创建的 HTTPProxy
+type testProxyOption func(hp *HTTPProxy)
+
+// withPool 指定是否通过连接池转发
+func withPool(usePool bool) testProxyOption {
+	return func(hp *HTTPProxy) {
+		hp.httpPoolEnabled = usePool
+	}
+}
+
+// withSelectTarget 替换目标选择逻辑
+func withSelectTarget(selectTarget func(c *gin.Context, rules config.RoutingRules) (string, string)) testProxyOption {
+	return func(hp *HTTPProxy) {
+		hp.selectTargetFunc = selectTarget
+	}
+}
+
+// newTestProxy 初始化测试日志与全局配置，再像 NewHTTPProxy 一样按配置创建 HTTPProxy
+// cfg 为 nil 时使用 config.InitTestConfigManager 的默认配置；与配置无关的行为通过 opts 调整
+func newTestProxy(t *testing.T, cfg *config.Config, opts ...testProxyOption) *HTTPProxy {
+	t.Helper()
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	config.InitTestConfigManager()
+	if cfg != nil {
+		config.SetConfig(cfg)
+	}
+	hp := NewHTTPProxy(config.GetConfig())
+	// 扇出剩余结果的统计协程会调用 updateRequestCount，需在测试还原该钩子前退出
+	t.Cleanup(hp.drains.Wait)
+	for _, opt := range opts {
+		opt(hp)
+	}
+	return hp
+}
+
 // TestCreateHTTPHandler_NoTarget 模拟没有可用目标的情况，验证返回 503 和错误 JSON 信息。
 func TestCreateHTTPHandler_NoTarget(t *testing.T) {
-	config.InitTestConfigManager()
-	gin.SetMode(gin.TestMode)
-	// 构造一个 testHTTPProxy，使 selectTarget 返回空值
-	proxy := &HTTPProxy{
-		httpPool:        NewHTTPConnectionPool(config.GetConfig()),
-		loadBalancer:    initializeLoadBalancer(config.GetConfig()),
-		objectPool:      util.NewPoolManager(config.GetConfig()),
-		httpPoolEnabled: false,
-		selectTargetFunc: func(c *gin.Context, rules config.RoutingRules) (string, string) {
-			return "", ""
-		}}
+	// 使 selectTarget 返回空值
+	proxy := newTestProxy(t, nil, withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		return "", ""
+	}))
 	// 此处 rules 可传空（测试中并不使用）
 	dummyRules := config.RoutingRules{}
 	handler := proxy.CreateHTTPHandler(dummyRules)
@@ -51,16 +84,16 @@ func TestCreateHTTPHandler_NoTarget(t *testing.T) {
 	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("解析响应时出错: %v", err)
 	}
-	if resp["error"] != "No available target" {
-		t.Errorf("预期错误信息 'No available target'，实际得到 '%s'", resp["error"])
+	if resp["code"] != ErrCodeNoTarget {
+		t.Errorf("预期错误码 '%s'，实际得到 '%s'", ErrCodeNoTarget, resp["code"])
+	}
+	if resp["message"] != "No available target" {
+		t.Errorf("预期错误信息 'No available target'，实际得到 '%s'", resp["message"])
 	}
 }
 
 // TestCreateHTTPHandler_ProxyDirect 模拟直接代理模式（httpPoolEnabled = false），使用 httptest.NewServer 模拟目标服务。
 func TestCreateHTTPHandler_ProxyDirect(t *testing.T) {
-	config.InitTestConfigManager()
-	health.InitHealthChecker(config.GetConfig())
-	gin.SetMode(gin.TestMode)
 	// 启动一个模拟目标服务，返回固定响应
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -68,16 +101,11 @@ func TestCreateHTTPHandler_ProxyDirect(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	proxy := &HTTPProxy{
-		httpPool:        NewHTTPConnectionPool(config.GetConfig()),
-		loadBalancer:    initializeLoadBalancer(config.GetConfig()),
-		objectPool:      util.NewPoolManager(config.GetConfig()),
-		httpPoolEnabled: false,
-		// 注入自定义 selectTarget 逻辑，直接返回模拟目标服务的 URL 和默认环境
-		selectTargetFunc: func(c *gin.Context, rules config.RoutingRules) (string, string) {
-			return ts.URL, "stable"
-		},
-	}
+	// 注入自定义 selectTarget 逻辑，直接返回模拟目标服务的 URL 和默认环境
+	proxy := newTestProxy(t, nil, withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		return ts.URL, "stable"
+	}))
+	health.InitHealthChecker(config.GetConfig())
 	dummyRules := config.RoutingRules{}
 	handler := proxy.CreateHTTPHandler(dummyRules)
 
@@ -99,23 +127,15 @@ func TestCreateHTTPHandler_ProxyDirect(t *testing.T) {
 
 // TestCreateHTTPHandler_ProxyWithPool 模拟连接池模式（httpPoolEnabled = true），重写 proxyWithPool 方法，直接返回固定 JSON 响应。
 func TestCreateHTTPHandler_ProxyWithPool(t *testing.T) {
-	config.InitTestConfigManager()
-	gin.SetMode(gin.TestMode)
-	proxy := &HTTPProxy{
-		httpPool:        NewHTTPConnectionPool(config.GetConfig()),
-		loadBalancer:    initializeLoadBalancer(config.GetConfig()),
-		objectPool:      util.NewPoolManager(config.GetConfig()),
-		httpPoolEnabled: true,
-		selectTargetFunc: func(c *gin.Context, rules config.RoutingRules) (string, string) {
-			return "dummy-target", "stable"
-		},
-		proxyWithPoolFunc: func(c *gin.Context, target, env string) {
-			c.JSON(http.StatusOK, gin.H{
-				"message": "proxied with pool",
-				"target":  target,
-				"env":     env,
-			})
-		},
+	proxy := newTestProxy(t, nil, withPool(true), withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		return "dummy-target", "stable"
+	}))
+	proxy.proxyWithPoolFunc = func(c *gin.Context, target, env string) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "proxied with pool",
+			"target":  target,
+			"env":     env,
+		})
 	}
 	dummyRules := config.RoutingRules{}
 	handler := proxy.CreateHTTPHandler(dummyRules)
@@ -202,6 +222,7 @@ func (d dummyLB) SelectTarget(targets []string, req *http.Request) string {
 	}
 	return ""
 }
+func (d dummyLB) ActiveTargets() []string { return []string{"dummy"} }
 
 // 假设 config.RoutingRule 的定义如下（请根据实际情况调整）：
 //
@@ -296,7 +317,7 @@ func (ds dummySpan) SpanContext() trace.SpanContext                   { return t
 // TestCreateDirector 测试 createDirector 返回的 director 函数
 func TestCreateDirector(t *testing.T) {
 	targetURL, _ := url.Parse("http://example.com")
-	director := (&HTTPProxy{}).createDirector(targetURL, "canary")
+	director := (&HTTPProxy{}).createDirector(targetURL, "canary", config.RequestTransform{})
 	req, _ := http.NewRequest("GET", "/path", nil)
 	req.URL.Path = "/path"
 	director(req)
@@ -360,7 +381,7 @@ func TestWriteFastHTTPResponse(t *testing.T) {
 	fResp.SetBody([]byte(`{"result": "ok"}`))
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	(&HTTPProxy{}).writeFastHTTPResponse(c, fResp)
+	(&HTTPProxy{}).writeFastHTTPResponse(c, fResp, "http://backend")
 	if w.Code != 201 {
 		t.Errorf("expected status 201, got %d", w.Code)
 	}
@@ -418,3 +439,450 @@ func TestWeightedRandomSelect(t *testing.T) {
 		t.Errorf("expected at least 2 different targets, got %v", found)
 	}
 }
+
+// TestCreateDirector_PreserveRawPath 测试启用原始路径保留时，编码的 %2F 原样到达后端
+func TestCreateDirector_PreserveRawPath(t *testing.T) {
+	var gotURI string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	targetURL, _ := url.Parse(ts.URL + "/api")
+	tests := []struct {
+		name     string
+		preserve bool
+		want     string
+	}{
+		{"preserve enabled", true, "/api/files/a%2Fb?x=1"},
+		{"preserve disabled", false, "/api/files/a/b?x=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hp := &HTTPProxy{}
+			hp.RefreshSettings(&config.Config{Routing: config.Routing{PreserveRawPath: tt.preserve}})
+			rp := httputil.NewSingleHostReverseProxy(targetURL)
+			rp.Director = hp.createDirector(targetURL, "stable", config.RequestTransform{})
+
+			req := httptest.NewRequest("GET", "/files/a%2Fb?x=1", nil)
+			w := httptest.NewRecorder()
+			rp.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if gotURI != tt.want {
+				t.Errorf("expected backend request URI %q, got %q", tt.want, gotURI)
+			}
+		})
+	}
+}
+
+// TestPrepareFastHTTPRequest_PreserveRawPath 测试连接池模式下启用原始路径保留时，编码的 %2F 原样到达后端
+func TestPrepareFastHTTPRequest_PreserveRawPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var gotURI string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	target := strings.TrimPrefix(ts.URL, "http://")
+
+	tests := []struct {
+		name     string
+		preserve bool
+		want     string
+	}{
+		{"preserve enabled", true, "/files/a%2Fb?x=1"},
+		{"preserve disabled", false, "/files/a/b?x=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/files/a%2Fb?x=1", nil)
+
+			fReq := fasthttp.AcquireRequest()
+			fResp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(fReq)
+			defer fasthttp.ReleaseResponse(fResp)
+
+			cfg := &config.Config{Routing: config.Routing{PreserveRawPath: tt.preserve}}
+			client, err := NewHTTPConnectionPool(cfg).GetClient(ts.URL)
+			if err != nil {
+				t.Fatalf("failed to get client: %v", err)
+			}
+			hp := &HTTPProxy{}
+			hp.RefreshSettings(cfg)
+			hp.prepareFastHTTPRequest(c, fReq, target, "stable")
+			if err := client.Do(fReq, fResp); err != nil {
+				t.Fatalf("request to backend failed: %v", err)
+			}
+			if gotURI != tt.want {
+				t.Errorf("expected backend request URI %q, got %q", tt.want, gotURI)
+			}
+		})
+	}
+}
+
+// newCanaryTestContext 创建带客户端地址、可选会话 Cookie 和环境请求头的测试上下文
+func newCanaryTestContext(remoteAddr, session, envHeader string) *gin.Context {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remoteAddr
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session})
+	}
+	if envHeader != "" {
+		req.Header.Set("X-Env", envHeader)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	return c
+}
+
+// TestSelectEnv_CanaryPercentage 测试按比例灰度的分流比例与同一客户端的稳定性
+func TestSelectEnv_CanaryPercentage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rules := config.RoutingRules{
+		{Target: "stable", Env: "stable"},
+		{Target: "canary", Env: "canary", CanaryWeight: 20},
+	}
+	grayscale := config.Grayscale{Enabled: true, SessionCookie: "session_id"}
+
+	const clients = 2000
+	canary := 0
+	for i := 0; i < clients; i++ {
+		addr := fmt.Sprintf("10.0.%d.%d:12345", i/256, i%256)
+		env := selectEnv(newCanaryTestContext(addr, "", ""), rules, grayscale)
+		if env == canaryEnv {
+			canary++
+		}
+		if again := selectEnv(newCanaryTestContext(addr, "", ""), rules, grayscale); again != env {
+			t.Fatalf("client %s switched from %q to %q", addr, env, again)
+		}
+	}
+	if ratio := float64(canary) / clients; ratio < 0.15 || ratio > 0.25 {
+		t.Errorf("expected about 20%% canary traffic, got %.1f%%", ratio*100)
+	}
+}
+
+// TestSelectEnv_SessionCookieAndHeader 测试会话 Cookie 优先于客户端 IP，请求头强制指定环境
+func TestSelectEnv_SessionCookieAndHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rules := config.RoutingRules{
+		{Target: "stable", Env: "stable"},
+		{Target: "canary", Env: "canary", CanaryWeight: 50},
+	}
+	grayscale := config.Grayscale{Enabled: true, SessionCookie: "session_id"}
+
+	for i := 0; i < 20; i++ {
+		session := fmt.Sprintf("session-%d", i)
+		want := selectEnv(newCanaryTestContext("10.0.0.1:1", session, ""), rules, grayscale)
+		if got := selectEnv(newCanaryTestContext("10.0.0.2:1", session, ""), rules, grayscale); got != want {
+			t.Errorf("session %s routed to %q from one IP and %q from another", session, want, got)
+		}
+	}
+
+	noPercentage := config.RoutingRules{{Target: "stable", Env: "stable"}, {Target: "canary", Env: "canary"}}
+	if env := selectEnv(newCanaryTestContext("10.0.0.1:1", "", "canary"), noPercentage, grayscale); env != canaryEnv {
+		t.Errorf("expected header to force canary, got %q", env)
+	}
+	full := config.RoutingRules{{Target: "stable", Env: "stable"}, {Target: "canary", Env: "canary", CanaryWeight: 100}}
+	if env := selectEnv(newCanaryTestContext("10.0.0.1:1", "", "stable"), full, grayscale); env != defaultEnv {
+		t.Errorf("expected header to force stable, got %q", env)
+	}
+}
+
+// TestFilterRules_CanaryPercentageExcludesCanaryFromStable 测试按比例灰度时稳定流量不进入灰度目标
+func TestFilterRules_CanaryPercentageExcludesCanaryFromStable(t *testing.T) {
+	hp := newTestProxy(t, nil)
+	rules := config.RoutingRules{
+		{Target: "stable", Env: "stable"},
+		{Target: "canary", Env: "canary", CanaryWeight: 10},
+	}
+
+	stable := hp.filterRules(rules, defaultEnv)
+	if len(stable) != 1 || stable[0].Target != "stable" {
+		t.Errorf("expected only the stable target, got %v", stable)
+	}
+	canary := hp.filterRules(rules, canaryEnv)
+	if len(canary) != 1 || canary[0].Target != "canary" {
+		t.Errorf("expected only the canary target, got %v", canary)
+	}
+}
+
+// TestCreateHTTPHandler_MethodNotAllowed 验证路由限制请求方法时返回 405 和 Allow 头，允许的方法继续进入代理流程。
+func TestCreateHTTPHandler_MethodNotAllowed(t *testing.T) {
+	proxy := newTestProxy(t, nil, withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		return "", ""
+	}))
+	rules := config.RoutingRules{{Target: "http://localhost:8381", Methods: []string{"GET"}}}
+	router := gin.New()
+	router.Any("/test", proxy.CreateHTTPHandler(rules))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/test", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("预期状态码 %d，实际得到 %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD" {
+		t.Errorf("预期 Allow 头 'GET, HEAD'，实际得到 '%s'", allow)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应时出错: %v", err)
+	}
+	if resp["code"] != ErrCodeMethodNotAllowed {
+		t.Errorf("预期错误码 '%s'，实际得到 '%s'", ErrCodeMethodNotAllowed, resp["code"])
+	}
+
+	// 允许的方法越过方法检查，因无可用目标返回 503
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/test", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("预期状态码 %d，实际得到 %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+// TestCreateHTTPHandler_MethodFiltersRules 验证同一路径下只有允许该方法的规则参与目标选择。
+func TestCreateHTTPHandler_MethodFiltersRules(t *testing.T) {
+	var offered config.RoutingRules
+	proxy := newTestProxy(t, nil, withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		offered = rules
+		return "", ""
+	}))
+	rules := config.RoutingRules{
+		{Target: "http://localhost:8381", Methods: []string{"GET"}},
+		{Target: "http://localhost:8382", Methods: []string{"GET", "DELETE"}},
+	}
+	router := gin.New()
+	router.Any("/test", proxy.CreateHTTPHandler(rules))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/test", nil))
+	if len(offered) != 1 || offered[0].Target != "http://localhost:8382" {
+		t.Errorf("预期 DELETE 只能选择 http://localhost:8382，实际候选规则为 %v", offered)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	if len(offered) != 2 {
+		t.Errorf("预期 GET 可选择全部 2 条规则，实际为 %d 条", len(offered))
+	}
+}
+
+// TestCreateHTTPHandler_Host 验证按 Host 选择规则：精确匹配优先于通配匹配，未限定 Host 的规则兜底，没有规则处理该 Host 时返回 404。
+func TestCreateHTTPHandler_Host(t *testing.T) {
+	var selected string
+	proxy := newTestProxy(t, nil, withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+		selected = rules[0].Target
+		return "", ""
+	}))
+	tenantRules := config.RoutingRules{
+		{Target: "http://localhost:8381", Host: "api.example.com"},
+		{Target: "http://localhost:8382", Host: "*.example.com"},
+	}
+	router := gin.New()
+	router.Any("/tenant", proxy.CreateHTTPHandler(tenantRules))
+	router.Any("/shared", proxy.CreateHTTPHandler(append(tenantRules, config.RoutingRule{Target: "http://localhost:8383"})))
+
+	for _, tt := range []struct {
+		path, host, want string
+	}{
+		{"/tenant", "api.example.com", "http://localhost:8381"},
+		{"/tenant", "API.example.com:8380", "http://localhost:8381"},
+		{"/tenant", "shop.example.com", "http://localhost:8382"},
+		{"/shared", "other.org", "http://localhost:8383"},
+	} {
+		selected = ""
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if selected != tt.want {
+			t.Errorf("%s%s: 预期选择目标 '%s'，实际得到 '%s'", tt.host, tt.path, tt.want, selected)
+		}
+	}
+
+	for _, host := range []string{"example.com", "other.org"} {
+		selected = ""
+		req := httptest.NewRequest("GET", "/tenant", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: 预期状态码 %d，实际得到 %d", host, http.StatusNotFound, w.Code)
+		}
+		if selected != "" {
+			t.Errorf("%s: 不应选择目标，实际得到 '%s'", host, selected)
+		}
+	}
+}
+
+// TestRefreshLoadBalancer_KeepsUnchangedRouteState 测试热更新只修改其他路由时，未变化路由的加权轮询计数得以保留
+func TestRefreshLoadBalancer_KeepsUnchangedRouteState(t *testing.T) {
+	newCfg := func(bTargets ...string) *config.Config {
+		cfg := &config.Config{Routing: config.Routing{
+			LoadBalancer: "weighted_round_robin",
+			Rules: map[string]config.RoutingRules{
+				"/a": {{Target: "http://a1", Weight: 1}, {Target: "http://a2", Weight: 1}},
+			},
+		}}
+		for _, target := range bTargets {
+			cfg.Routing.Rules["/b"] = append(cfg.Routing.Rules["/b"], config.RoutingRule{Target: target, Weight: 1})
+		}
+		return cfg
+	}
+	cfg := newCfg("http://b1")
+	hp := newTestProxy(t, cfg)
+	targets := []string{"http://a1", "http://a2"}
+	selectA := func() string {
+		return hp.loadBalancer.SelectTarget(targets, httptest.NewRequest("GET", "/a", nil))
+	}
+
+	if got := selectA(); got != "http://a1" {
+		t.Fatalf("first selection = %s, want http://a1", got)
+	}
+	lb := hp.loadBalancer
+	hp.RefreshLoadBalancer(newCfg("http://b1", "http://b2"))
+	if hp.loadBalancer != lb {
+		t.Error("load balancer should be updated in place when the algorithm is unchanged")
+	}
+	if got := selectA(); got != "http://a2" {
+		t.Errorf("selection after reload = %s, want http://a2 (counter of unchanged route should survive)", got)
+	}
+
+	// 负载均衡算法变化时重新创建
+	changed := newCfg("http://b1")
+	changed.Routing.LoadBalancer = "round_robin"
+	hp.RefreshLoadBalancer(changed)
+	if hp.loadBalancer == lb || hp.GetLoadBalancerType() != "round-robin" {
+		t.Errorf("load balancer should be recreated when the algorithm changes, got %s", hp.GetLoadBalancerType())
+	}
+}
+
+// TestRefreshLoadBalancer_RebuildsKetamaOnVirtualNodesChange 测试虚拟节点数变化时重新创建 Ketama 以重建哈希环
+func TestRefreshLoadBalancer_RebuildsKetamaOnVirtualNodesChange(t *testing.T) {
+	newCfg := func(virtualNodes int) *config.Config {
+		return &config.Config{Routing: config.Routing{
+			LoadBalancer: "ketama",
+			Ketama:       config.Ketama{VirtualNodes: virtualNodes},
+			Rules:        map[string]config.RoutingRules{"/a": {{Target: "http://a1"}, {Target: "http://a2"}}},
+		}}
+	}
+	cfg := newCfg(160)
+	hp := newTestProxy(t, cfg)
+
+	lb := hp.loadBalancer
+	hp.RefreshLoadBalancer(newCfg(160))
+	if hp.loadBalancer != lb {
+		t.Error("ketama should be kept when virtual nodes are unchanged")
+	}
+	hp.RefreshLoadBalancer(newCfg(100))
+	if hp.loadBalancer == lb {
+		t.Error("ketama should be recreated when virtual nodes change")
+	}
+}
+
+// TestCreateHTTPHandler_EjectsFailingTarget 验证直接代理与连接池两种模式下，上游持续返回 5xx 或不可达时每个请求只计入一次失败，目标连续失败后被剔除
+func TestCreateHTTPHandler_EjectsFailingTarget(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name    string
+		usePool bool
+		target  string
+	}{
+		{"direct 5xx", false, failing.URL},
+		{"pool 5xx", true, failing.URL},
+		{"direct unreachable", false, unreachable.URL},
+		{"pool unreachable", true, unreachable.URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := newTestProxy(t, nil, withPool(tt.usePool), withSelectTarget(func(c *gin.Context, rules config.RoutingRules) (string, string) {
+				return tt.target, "stable"
+			}))
+			cache.InitTestStore()
+			cfg := *config.GetConfig()
+			cfg.Routing.OutlierDetection = config.OutlierDetection{Enabled: true, ConsecutiveFailures: 3, EjectionDuration: time.Minute}
+			checker := health.InitHealthChecker(&cfg)
+			original := updateRequestCount
+			var outcomes []bool
+			updateRequestCount = func(target string, success bool) {
+				outcomes = append(outcomes, success)
+				checker.UpdateRequestCount(target, success)
+			}
+			t.Cleanup(func() { updateRequestCount = original })
+
+			router := gin.New()
+			router.GET("/test", proxy.CreateHTTPHandler(config.RoutingRules{}))
+			for i := 0; i < 3; i++ {
+				if checker.IsEjected(tt.target) {
+					t.Fatalf("第 %d 个请求前目标已被剔除", i+1)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+				if w.Code < http.StatusInternalServerError {
+					t.Fatalf("预期 5xx 状态码，实际得到 %d", w.Code)
+				}
+			}
+
+			if len(outcomes) != 3 {
+				t.Fatalf("预期每个请求记录一次结果，实际记录 %v", outcomes)
+			}
+			for _, success := range outcomes {
+				if success {
+					t.Errorf("预期请求均计为失败，实际记录 %v", outcomes)
+				}
+			}
+			if !checker.IsEjected(tt.target) {
+				t.Errorf("预期目标 %s 连续失败后被剔除", tt.target)
+			}
+		})
+	}
+}
+
+// TestRefreshSettings_PreserveRawPath 测试热更新原始路径保留设置后，直接代理与连接池模式均按新设置转发
+func TestRefreshSettings_PreserveRawPath(t *testing.T) {
+	var gotURI string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	// 直接代理模式拼接目标路径，连接池模式只使用目标的主机部分
+	for _, tt := range []struct {
+		usePool bool
+		target  string
+		prefix  string
+	}{
+		{false, ts.URL + "/api", "/api"},
+		{true, ts.URL, ""},
+	} {
+		cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+		cfg.Performance.HttpPoolEnabled = tt.usePool
+		proxy := newTestProxy(t, cfg, withPool(tt.usePool))
+		router := gin.New()
+		router.GET("/files/*path", proxy.CreateHTTPHandler(config.RoutingRules{{Target: tt.target, Protocol: "http"}}))
+		serve := func() string {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/files/a%2Fb", nil))
+			return gotURI
+		}
+
+		if got := serve(); got != tt.prefix+"/files/a/b" {
+			t.Errorf("pool=%v: expected decoded path before reload, got %q", tt.usePool, got)
+		}
+		proxy.RefreshSettings(&config.Config{Routing: config.Routing{PreserveRawPath: true}})
+		if got := serve(); got != tt.prefix+"/files/a%2Fb" {
+			t.Errorf("pool=%v: expected raw path after reload, got %q", tt.usePool, got)
+		}
+		proxy.RefreshSettings(&config.Config{})
+		if got := serve(); got != tt.prefix+"/files/a/b" {
+			t.Errorf("pool=%v: expected decoded path after disabling, got %q", tt.usePool, got)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package proxy
+
+import "github.com/gin-gonic/gin"
+
+// matchedRouteKey 上下文中记录请求匹配到的路由模式的键
+const matchedRouteKey = "matched_route"
+
+// SetMatchedRoute 记录请求匹配到的路由模式（即 routing.rules 的键），由各路由引擎在匹配成功后、转发之前调用
+// trie、regexp 等引擎在中间件中完成匹配，c.FullPath() 为空，熔断、降级等按路由划分的逻辑据此找到所属路由
+func SetMatchedRoute(c *gin.Context, pattern string) {
+	c.Set(matchedRouteKey, pattern)
+}
+
+// MatchedRoute 返回请求匹配到的路由模式，尚未匹配时 ok 为 false
+func MatchedRoute(c *gin.Context) (pattern string, ok bool) {
+	value, exists := c.Get(matchedRouteKey)
+	if !exists {
+		return "", false
+	}
+	pattern, ok = value.(string)
+	return pattern, ok
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	maxMirrorBodySize    = 4 << 20            // 可镜像的最大请求体，超过时跳过镜像以免占用过多内存
+	maxInflightMirrors   = 256                // 同时进行的镜像请求上限，达到上限时丢弃新的镜像请求
+	mirrorRequestTimeout = 10 * time.Second   // 单个镜像请求的超时时间
+	mirrorHeader         = "X-Mirror-Request" // 镜像请求携带的请求头，便于镜像目标识别
+)
+
+var (
+	// mirrorClient 发送镜像请求的客户端，与主请求的连接互不影响
+	mirrorClient = &http.Client{Timeout: mirrorRequestTimeout}
+	// mirrorSlots 限制同时进行的镜像请求数
+	mirrorSlots = make(chan struct{}, maxInflightMirrors)
+)
+
+// findMirror 返回选中目标所在规则的镜像配置
+func findMirror(rules config.RoutingRules, target string) (config.Mirror, bool) {
+	for _, rule := range rules {
+		if rule.Target == target && rule.Mirror.Target != "" && rule.Mirror.Percentage > 0 {
+			return rule.Mirror, true
+		}
+	}
+	return config.Mirror{}, false
+}
+
+// mirrorRequest 按比例将请求异步复制到镜像目标，镜像响应被丢弃，失败只记录日志
+// 请求体被读入内存后同时供主请求和镜像请求使用，镜像请求在独立协程中发送，不增加客户端延迟
+func mirrorRequest(c *gin.Context, mirror config.Mirror) {
+	if rand.Intn(100) >= mirror.Percentage {
+		return
+	}
+	mirrorURL, err := url.Parse(mirror.Target)
+	if err != nil {
+		logger.Error("Invalid mirror target", zap.String("mirror", mirror.Target), zap.Error(err))
+		return
+	}
+
+	body, ok := bufferRequestBody(c.Request)
+	if !ok {
+		logger.Debug("Request body too large to mirror",
+			zap.String("path", c.Request.URL.Path),
+			zap.String("mirror", mirror.Target))
+		return
+	}
+
+	// 镜像请求不随客户端请求结束而取消
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), mirrorRequestTimeout)
+	req := c.Request.Clone(ctx)
+	req.RequestURI = ""
+	req.Body = http.NoBody
+	if len(body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set(mirrorHeader, "true")
+	if requestID := c.GetString("request_id"); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	defaultDirector(mirrorURL)(req)
+
+	select {
+	case mirrorSlots <- struct{}{}:
+	default:
+		cancel()
+		logger.Warn("Too many in-flight mirror requests, dropping mirror",
+			zap.String("mirror", mirror.Target))
+		return
+	}
+	go func() {
+		defer func() { <-mirrorSlots }()
+		defer cancel()
+		sendMirrorRequest(req, mirror.Target)
+	}()
+}
+
+// sendMirrorRequest 发送镜像请求并丢弃响应
+func sendMirrorRequest(req *http.Request, target string) {
+	resp, err := mirrorClient.Do(req)
+	if err != nil {
+		logger.Warn("Mirror request failed",
+			zap.String("path", req.URL.Path),
+			zap.String("mirror", target),
+			zap.Error(err))
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	logger.Debug("Mirror request completed",
+		zap.String("path", req.URL.Path),
+		zap.String("mirror", target),
+		zap.Int("status", resp.StatusCode))
+}
+
+// bufferRequestBody 读取请求体并放回请求，供主请求和镜像请求分别读取，超过上限时返回 false 且保持请求体可读
+func bufferRequestBody(req *http.Request) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxMirrorBodySize+1))
+	if err != nil || len(body) > maxMirrorBodySize {
+		// 已读取的部分与剩余部分拼接，主请求仍能读取完整请求体
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		return nil, false
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true
+}
+
+// readCloser 组合 Reader 与原始请求体的 Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
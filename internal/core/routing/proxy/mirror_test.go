@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mirroredRequest 镜像目标收到的请求
+type mirroredRequest struct {
+	path   string
+	body   string
+	header http.Header
+}
+
+// serveWithMirror 使用带镜像配置的规则处理一次 POST 请求
+func serveWithMirror(t *testing.T, rules config.RoutingRules, body string) *httptest.ResponseRecorder {
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+
+	hp := newTestProxy(t, cfg)
+	router := gin.New()
+	router.POST("/orders", hp.CreateHTTPHandler(rules))
+	t.Cleanup(waitMirrors)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/orders?id=1", strings.NewReader(body)))
+	return w
+}
+
+// waitMirrors 占满全部镜像槽位以等待进行中的镜像协程结束，避免其日志输出与后续测试重新初始化 logger 竞争
+func waitMirrors() {
+	for i := 0; i < maxInflightMirrors; i++ {
+		mirrorSlots <- struct{}{}
+	}
+	for i := 0; i < maxInflightMirrors; i++ {
+		<-mirrorSlots
+	}
+}
+
+func TestMirror_ReplaysRequestWithoutDelayingClient(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		w.Write([]byte("primary:" + string(data)))
+	}))
+	defer primary.Close()
+
+	mirrored := make(chan mirroredRequest, 1)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mirrored <- mirroredRequest{path: r.URL.RequestURI(), body: string(data), header: r.Header.Clone()}
+		time.Sleep(500 * time.Millisecond) // 慢速镜像目标不应拖慢客户端
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mirror.Close()
+
+	rules := config.RoutingRules{{Target: primary.URL, Mirror: config.Mirror{Target: mirror.URL, Percentage: 100}}}
+	start := time.Now()
+	w := serveWithMirror(t, rules, `{"item":"book"}`)
+	elapsed := time.Since(start)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `primary:{"item":"book"}`, w.Body.String())
+	assert.Less(t, elapsed, 400*time.Millisecond, "mirror must not add latency to the client path")
+
+	select {
+	case got := <-mirrored:
+		assert.Equal(t, "/orders?id=1", got.path)
+		assert.Equal(t, `{"item":"book"}`, got.body)
+		assert.Equal(t, "true", got.header.Get(mirrorHeader))
+	case <-time.After(2 * time.Second):
+		t.Fatal("mirror target did not receive the request")
+	}
+}
+
+func TestMirror_FailureDoesNotAffectResponse(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer primary.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	rules := config.RoutingRules{{Target: primary.URL, Mirror: config.Mirror{Target: unreachable.URL, Percentage: 100}}}
+	w := serveWithMirror(t, rules, "payload")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestBufferRequestBody_TooLarge(t *testing.T) {
+	payload := strings.Repeat("x", maxMirrorBodySize+10)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(payload))
+
+	_, ok := bufferRequestBody(req)
+	assert.False(t, ok)
+	data, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, len(payload), len(data), "primary request must still see the full body")
+}
+
+func TestFindMirror(t *testing.T) {
+	rules := config.RoutingRules{
+		{Target: "http://a"},
+		{Target: "http://b", Mirror: config.Mirror{Target: "http://shadow", Percentage: 10}},
+		{Target: "http://c", Mirror: config.Mirror{Target: "http://shadow"}},
+	}
+	_, ok := findMirror(rules, "http://a")
+	assert.False(t, ok)
+	mirror, ok := findMirror(rules, "http://b")
+	assert.True(t, ok)
+	assert.Equal(t, "http://shadow", mirror.Target)
+	_, ok = findMirror(rules, "http://c")
+	assert.False(t, ok, "zero percentage disables mirroring")
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+// hookPlugin 为请求添加请求头、为响应添加响应头的插件，请求携带 X-Deny 时中止请求
+type hookPlugin struct{}
+
+func (hookPlugin) PluginInfo() plugins.Info {
+	return plugins.Info{Name: "hook", Signature: plugins.SIGNATURE}
+}
+
+func (hookPlugin) Setup(r gin.IRouter) {}
+
+func (hookPlugin) Execute(ctx context.Context) error { return nil }
+
+func (hookPlugin) OnRequest(c *gin.Context) {
+	if c.GetHeader("X-Deny") != "" {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	c.Request.Header.Set("X-Plugin-Request", "1")
+}
+
+func (hookPlugin) OnResponse(c *gin.Context, status int, header http.Header) {
+	header.Set("X-Plugin-Status", http.StatusText(status))
+}
+
+func TestProxy_PluginHooks(t *testing.T) {
+	plugins.Register("hook", hookPlugin{})
+	t.Cleanup(func() { plugins.Unregister("hook") })
+
+	for _, tc := range []struct {
+		name    string
+		usePool bool
+	}{
+		{name: "direct"},
+		{name: "pool", usePool: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var hits atomic.Int32
+			received := make(chan http.Header, 1)
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				received <- r.Header.Clone()
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer backend.Close()
+
+			cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+			hp := newTestProxy(t, cfg, withPool(tc.usePool))
+			router := gin.New()
+			router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, "1", (<-received).Get("X-Plugin-Request"))
+			assert.Equal(t, "Created", w.Header().Get("X-Plugin-Status"))
+
+			req := httptest.NewRequest("GET", "/api/v1/user", nil)
+			req.Header.Set("X-Deny", "1")
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, int32(1), hits.Load(), "aborted requests are not forwarded")
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grpcContentType = "application/grpc" // gRPC 响应的 Content-Type 前缀
+	grpcStatusKey   = "Grpc-Status"      // gRPC 状态码响应头（或 trailer）
+
+	protocolGRPC  = "grpc"  // 上游返回 gRPC 响应
+	protocolHTTP2 = "http2" // 上游只接受 HTTP/2（如未开启 HTTP/1 兼容的 gRPC 服务）
+	protocolHTTP1 = "http1" // gRPC 路由的上游只接受 HTTP/1
+)
+
+// errGRPCUpstream 表示 HTTP 路由的上游返回了 gRPC 响应
+var errGRPCUpstream = errors.New("upstream returned a gRPC response on an HTTP route")
+
+// errHTTP2Upstream 表示上游在 HTTP/1 连接上发来了 HTTP/2 帧，即上游只会说 HTTP/2
+var errHTTP2Upstream = errors.New("upstream sent an HTTP/2 frame on an HTTP/1 connection")
+
+// http2FrameSettings HTTP/2 SETTINGS 帧的类型值
+const http2FrameSettings = 0x04
+
+// isGRPCResponse 判断上游响应是否为 gRPC 响应
+func isGRPCResponse(contentType string, hasGRPCStatus bool) bool {
+	return strings.HasPrefix(contentType, grpcContentType) || hasGRPCStatus
+}
+
+// isHTTP2Upstream 判断代理错误是否由上游只会说 HTTP/2 引起
+func isHTTP2Upstream(err error) bool {
+	return errors.Is(err, errHTTP2Upstream)
+}
+
+// isHTTP2Frame 判断连接上收到的首段数据是否为 HTTP/2 SETTINGS 帧
+// HTTP/2 服务建立连接后先发送 SETTINGS 帧：帧头首字节为长度高位 0，第四个字节为帧类型；HTTP/1 响应总以 "HTTP/" 开头
+func isHTTP2Frame(p []byte) bool {
+	return len(p) > 0 && p[0] == 0 && (len(p) < 4 || p[3] == http2FrameSettings)
+}
+
+// http2SniffConn 检查上游连接上收到的首段数据，发现 HTTP/2 帧时以 errHTTP2Upstream 结束读取，
+// 使标准库返回可用 errors.Is 判断的错误，而不是解析响应行失败的错误
+type http2SniffConn struct {
+	net.Conn
+	sniffed bool
+	onSniff func(http2 bool) // 首段数据检查完成后回调，可为空
+}
+
+func (c *http2SniffConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if !c.sniffed && n > 0 {
+		c.sniffed = true
+		http2 := isHTTP2Frame(p[:n])
+		if c.onSniff != nil {
+			c.onSniff(http2)
+		}
+		if http2 {
+			return 0, errHTTP2Upstream
+		}
+	}
+	return n, err
+}
+
+// upstreamTransport 直接代理模式使用的 Transport，在默认 Transport 的基础上检查上游是否只会说 HTTP/2
+var upstreamTransport = newUpstreamTransport()
+
+// newUpstreamTransport 复制默认 Transport 并包装其建立的连接
+func newUpstreamTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &http2SniffConn{Conn: conn}, nil
+	}
+	return transport
+}
+
+// fastHTTPUpstreams 记录连接池中各上游地址最近建立的连接是否收到了 HTTP/2 帧
+// fasthttp 把首字节之前的读取错误统一报告为连接被关闭，无法从返回的错误中区分，因此按地址记录检查结果
+var fastHTTPUpstreams sync.Map // addr -> bool
+
+// dialFastHTTPUpstream 连接池 HostClient 使用的拨号函数，检查方式与 upstreamTransport 相同
+func dialFastHTTPUpstream(addr string) (net.Conn, error) {
+	conn, err := fasthttp.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &http2SniffConn{Conn: conn, onSniff: func(http2 bool) {
+		fastHTTPUpstreams.Store(addr, http2)
+	}}, nil
+}
+
+// fastHTTPUpstreamError 连接池请求失败且该地址最近的连接收到了 HTTP/2 帧时，为错误附加 errHTTP2Upstream
+func fastHTTPUpstreamError(addr string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if http2, ok := fastHTTPUpstreams.Load(addr); ok && http2.(bool) {
+		return fmt.Errorf("%w: %w", errHTTP2Upstream, err)
+	}
+	return err
+}
+
+// isHTTP1Upstream 判断 gRPC 调用失败是否由上游为 HTTP/1 服务引起
+// gRPC 客户端把 HTTP/1 响应当作 HTTP/2 帧读取时会得到超长帧错误，上游返回非 gRPC 响应时会得到 content-type 错误
+func isHTTP1Upstream(st *status.Status) bool {
+	msg := st.Message()
+	return strings.Contains(msg, "frame too large") || strings.Contains(msg, "unexpected content-type")
+}
+
+// upstreamProtocol 返回导致错误的上游实际协议，协议匹配时返回空字符串
+func upstreamProtocol(err error) string {
+	switch {
+	case errors.Is(err, errGRPCUpstream):
+		return protocolGRPC
+	case isHTTP2Upstream(err):
+		return protocolHTTP2
+	}
+	return ""
+}
+
+// protocolMismatchMessage 返回协议不匹配时给客户端的说明
+func protocolMismatchMessage(protocol string) string {
+	switch protocol {
+	case protocolGRPC:
+		return "Upstream responded with gRPC, but the route is configured for HTTP; set protocol: grpc on the route"
+	case protocolHTTP1:
+		return "Upstream does not speak gRPC (likely an HTTP/1 server), but the route is configured for gRPC; set protocol: http on the route"
+	}
+	return "Upstream only speaks HTTP/2 (likely a gRPC server), but the route is configured for HTTP; set protocol: grpc on the route"
+}
+
+// handleProtocolMismatch 记录协议不匹配并返回带说明的 502
+func handleProtocolMismatch(w http.ResponseWriter, r *http.Request, span trace.Span, target, protocol string, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "Upstream protocol mismatch")
+	updateRequestCount(target, false)
+	observability.ProtocolMismatches.WithLabelValues(target, protocol).Inc()
+	logger.Error("Upstream protocol does not match route",
+		zap.String("path", r.URL.Path),
+		zap.String("target", target),
+		zap.String("upstreamProtocol", protocol),
+		zap.Error(err))
+	writeHTTPError(w, r, http.StatusBadGateway, ErrCodeProtocolMismatch, protocolMismatchMessage(protocol))
+}
+
+// rejectGRPCResponse 作为直接代理模式的 ModifyResponse，拒绝 HTTP 路由上的 gRPC 响应
+func rejectGRPCResponse(resp *http.Response) error {
+	_, hasStatus := resp.Header[grpcStatusKey]
+	if isGRPCResponse(resp.Header.Get("Content-Type"), hasStatus) {
+		return errGRPCUpstream
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newHTTP2OnlyBackend 启动只说 HTTP/2 的模拟后端，与 gRPC 服务一样在连接建立后立即发送 SETTINGS 帧并在收到 HTTP/1 请求后断开
+// 真实 gRPC 服务未读完请求就关闭连接会触发 RST，客户端可能来不及读到 SETTINGS 帧，因此模拟后端读完请求头后再正常关闭
+func newHTTP2OnlyBackend(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("\x00\x00\x00\x04\x00\x00\x00\x00\x00")) // 空的 SETTINGS 帧
+				http.ReadRequest(bufio.NewReader(conn))
+			}()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+// serveHTTPRoute 通过 HTTP 路由代理一次请求到指定目标
+func serveHTTPRoute(t *testing.T, target string, usePool, passthroughGRPC bool) *httptest.ResponseRecorder {
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+	if passthroughGRPC {
+		cfg.Routing.ProtocolMismatch = "passthrough"
+	}
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: target, Protocol: "http"}}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+	return w
+}
+
+func TestProtocolMismatch_HTTP2OnlyBackendBehindHTTPRoute(t *testing.T) {
+	addr := newHTTP2OnlyBackend(t)
+
+	for _, tc := range []struct {
+		name    string
+		target  string
+		usePool bool
+	}{
+		{name: "direct", target: "http://" + addr},
+		{name: "pool", target: "http://" + addr, usePool: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := testutil.ToFloat64(observability.ProtocolMismatches.WithLabelValues(tc.target, protocolHTTP2))
+			w := serveHTTPRoute(t, tc.target, tc.usePool, false)
+
+			assert.Equal(t, http.StatusBadGateway, w.Code)
+			assert.Contains(t, w.Body.String(), "set protocol: grpc on the route")
+			assert.Equal(t, before+1, testutil.ToFloat64(observability.ProtocolMismatches.WithLabelValues(tc.target, protocolHTTP2)))
+		})
+	}
+}
+
+func TestProtocolMismatch_GRPCResponseOverHTTP1(t *testing.T) {
+	// 模拟同时支持 HTTP/1 的 gRPC 服务（如经过 Envoy 等代理）
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "12")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	addr := backend.Listener.Addr().String()
+
+	for _, tc := range []struct {
+		name    string
+		target  string
+		usePool bool
+	}{
+		{name: "direct", target: backend.URL},
+		{name: "pool", target: "http://" + addr, usePool: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := testutil.ToFloat64(observability.ProtocolMismatches.WithLabelValues(tc.target, protocolGRPC))
+			w := serveHTTPRoute(t, tc.target, tc.usePool, false)
+
+			assert.Equal(t, http.StatusBadGateway, w.Code)
+			assert.Contains(t, w.Body.String(), "Upstream responded with gRPC")
+			assert.Equal(t, before+1, testutil.ToFloat64(observability.ProtocolMismatches.WithLabelValues(tc.target, protocolGRPC)))
+		})
+	}
+
+	t.Run("passthrough", func(t *testing.T) {
+		w := serveHTTPRoute(t, backend.URL, false, true)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/grpc", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestHTTPProxy_RefreshSettings_ProtocolMismatch(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "12")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	for _, usePool := range []bool{false, true} {
+		hp := newTestProxy(t, &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}, withPool(usePool))
+		router := gin.New()
+		router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+		serve := func() int {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusBadGateway, serve(), "rejected initially (pool=%v)", usePool)
+		hp.RefreshSettings(&config.Config{Routing: config.Routing{ProtocolMismatch: "passthrough"}})
+		assert.Equal(t, http.StatusOK, serve(), "passed through after reload (pool=%v)", usePool)
+	}
+}
+
+func TestIsHTTP2Upstream(t *testing.T) {
+	assert.True(t, isHTTP2Upstream(errHTTP2Upstream))
+	assert.True(t, isHTTP2Upstream(fmt.Errorf("error when reading response headers: %w", errHTTP2Upstream)))
+	assert.False(t, isHTTP2Upstream(errors.New(`malformed HTTP response "\x00\x00\x06\x04\x00"`)), "error text is not inspected")
+	assert.False(t, isHTTP2Upstream(errors.New("dial tcp 127.0.0.1:1: connect: connection refused")))
+	assert.False(t, isHTTP2Upstream(nil))
+}
+
+func TestIsHTTP2Frame(t *testing.T) {
+	assert.True(t, isHTTP2Frame([]byte("\x00\x00\x00\x04\x00\x00\x00\x00\x00")))
+	assert.True(t, isHTTP2Frame([]byte("\x00\x00\x12\x04\x00\x00\x00\x00\x00")))
+	assert.True(t, isHTTP2Frame([]byte("\x00\x00")), "short read that cannot be HTTP/1")
+	assert.False(t, isHTTP2Frame([]byte("HTTP/1.1 200 OK\r\n")))
+	assert.False(t, isHTTP2Frame([]byte("\x00\x00\x08\x07\x00")), "not a SETTINGS frame")
+	assert.False(t, isHTTP2Frame(nil))
+}
+
+func TestFastHTTPUpstreamError(t *testing.T) {
+	connErr := errors.New("the server closed connection before returning the first response byte")
+	fastHTTPUpstreams.Store("127.0.0.1:9001", true)
+	fastHTTPUpstreams.Store("127.0.0.1:9002", false)
+	t.Cleanup(func() {
+		fastHTTPUpstreams.Delete("127.0.0.1:9001")
+		fastHTTPUpstreams.Delete("127.0.0.1:9002")
+	})
+
+	assert.True(t, isHTTP2Upstream(fastHTTPUpstreamError("127.0.0.1:9001", connErr)))
+	assert.ErrorIs(t, fastHTTPUpstreamError("127.0.0.1:9001", connErr), connErr)
+	assert.Equal(t, connErr, fastHTTPUpstreamError("127.0.0.1:9002", connErr))
+	assert.Equal(t, connErr, fastHTTPUpstreamError("127.0.0.1:9003", connErr))
+	assert.NoError(t, fastHTTPUpstreamError("127.0.0.1:9001", nil))
+}
+
+func TestIsHTTP1Upstream(t *testing.T) {
+	assert.True(t, isHTTP1Upstream(status.New(codes.Unavailable, `connection error: desc = "error reading server preface: http2: frame too large"`)))
+	assert.True(t, isHTTP1Upstream(status.New(codes.Unknown, `unexpected HTTP status code received from server: 404 (Not Found); transport: received unexpected content-type "text/plain"`)))
+	assert.False(t, isHTTP1Upstream(status.New(codes.Unavailable, "connection refused")))
+}
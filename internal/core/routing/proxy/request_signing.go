@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/penwyp/mini-gateway/pkg/signing"
+	"go.uber.org/zap"
+)
+
+// newRequestSigner 根据配置创建转发请求的签名器，未启用时返回 nil
+func newRequestSigner(cfg config.RequestSigning) *signing.Signer {
+	if !cfg.Enabled {
+		return nil
+	}
+	signer, err := signing.New(cfg.Secret, cfg.Header, cfg.Fields, cfg.Tolerance)
+	if err != nil {
+		logger.Error("Invalid request signing configuration, upstream requests will not be signed",
+			zap.Error(err))
+		return nil
+	}
+	logger.Info("Upstream request signing enabled",
+		zap.String("header", signer.Header()),
+		zap.Strings("fields", cfg.Fields))
+	return signer
+}
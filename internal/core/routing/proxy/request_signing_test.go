@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSigning(t *testing.T) {
+	signingCfg := config.RequestSigning{
+		Enabled: true,
+		Secret:  "shared-secret",
+		Fields:  []string{signing.FieldMethod, signing.FieldPath, signing.FieldQuery, signing.FieldTimestamp},
+	}
+	verifier, err := signing.New(signingCfg.Secret, "", signingCfg.Fields, time.Minute)
+	require.NoError(t, err)
+
+	for _, mode := range []struct {
+		name    string
+		usePool bool
+	}{
+		{name: "direct"},
+		{name: "pool", usePool: true},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			verified := make(chan error, 1)
+			var path string
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				verified <- verifier.Verify(r, time.Now())
+			}))
+			defer backend.Close()
+
+			cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin", Signing: signingCfg}}
+			hp := newTestProxy(t, cfg, withPool(mode.usePool))
+			router := gin.New()
+			router.GET("/api/v1/users/:id", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+
+			// 客户端伪造的签名请求头被网关覆盖
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/a%20b?page=2", nil)
+			req.Header.Set(signing.DefaultHeader, "forged")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.NoError(t, <-verified)
+			assert.Equal(t, "/api/v1/users/a b", path)
+		})
+	}
+
+	assert.Nil(t, newRequestSigner(config.RequestSigning{Secret: "shared-secret"}), "signing is disabled")
+}
+
+func TestHTTPProxy_RefreshSettings_Signing(t *testing.T) {
+	signingCfg := config.RequestSigning{
+		Enabled: true,
+		Secret:  "shared-secret",
+		Fields:  []string{signing.FieldMethod, signing.FieldPath, signing.FieldTimestamp},
+	}
+	verifier, err := signing.New(signingCfg.Secret, "", signingCfg.Fields, time.Minute)
+	require.NoError(t, err)
+
+	verified := make(chan error, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified <- verifier.Verify(r, time.Now())
+	}))
+	defer backend.Close()
+
+	hp := newTestProxy(t, &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}})
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+	serve := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+	}
+
+	serve()
+	assert.Error(t, <-verified, "signing is disabled initially")
+
+	hp.RefreshSettings(&config.Config{Routing: config.Routing{Signing: signingCfg}})
+	serve()
+	assert.NoError(t, <-verified, "reloaded settings sign upstream requests")
+
+	hp.RefreshSettings(&config.Config{})
+	serve()
+	assert.Error(t, <-verified, "signing is disabled again")
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// maxTransformBodySize 可改写的请求体或响应体上限，超过时不改写，避免为改写缓存过大的消息体
+const maxTransformBodySize = 1 << 20
+
+// requestTransformKey 上下文中保存所选目标请求体改写规则的键
+const requestTransformKey = "request_transform"
+
+// transformTemplates 已解析的请求体模板，按模板内容缓存，避免每个请求重复解析
+var transformTemplates sync.Map // 模板内容 -> *template.Template
+
+// findRequestTransform 返回转发到 target 的规则配置的请求体改写规则
+func findRequestTransform(rules config.RoutingRules, target string) (config.RequestTransform, bool) {
+	for _, rule := range rules {
+		if rule.Target == target && rule.RequestTransform.Enabled() {
+			return rule.RequestTransform, true
+		}
+	}
+	return config.RequestTransform{}, false
+}
+
+// setRequestTransform 记录本次转发使用的请求体改写规则，未配置时清除之前的记录
+func setRequestTransform(c *gin.Context, transform config.RequestTransform) {
+	c.Set(requestTransformKey, transform)
+}
+
+// requestTransform 返回本次转发使用的请求体改写规则
+func requestTransform(c *gin.Context) (config.RequestTransform, bool) {
+	value, ok := c.Get(requestTransformKey)
+	if !ok {
+		return config.RequestTransform{}, false
+	}
+	transform, ok := value.(config.RequestTransform)
+	return transform, ok && transform.Enabled()
+}
+
+// isJSONContentType 判断 Content-Type 是否为 JSON，包括 application/*+json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// transformRequestBody 按规则改写 JSON 请求体，非 JSON 请求体与超过 maxTransformBodySize 的请求体原样返回；
+// 格式错误的 JSON 或改写失败时记录警告并原样返回，不中断转发
+func transformRequestBody(transform config.RequestTransform, contentType string, body []byte) []byte {
+	if !transform.Enabled() || len(body) == 0 || !isJSONContentType(contentType) {
+		return body
+	}
+	if len(body) > maxTransformBodySize {
+		logger.Warn("Request body too large to transform", zap.Int("size", len(body)))
+		return body
+	}
+
+	data, err := decodeJSON(body)
+	if err != nil {
+		logger.Warn("Skipping request transform for malformed JSON body", zap.Error(err))
+		return body
+	}
+	if object, ok := data.(map[string]any); ok {
+		for _, rename := range transform.Rename {
+			if value, exists := object[rename.From]; exists {
+				delete(object, rename.From)
+				object[rename.To] = value
+			}
+		}
+	}
+
+	if transform.Template == "" {
+		out, err := json.Marshal(data)
+		if err != nil {
+			logger.Warn("Failed to encode transformed request body", zap.Error(err))
+			return body
+		}
+		return out
+	}
+	tmpl, err := transformTemplate(transform)
+	if err != nil {
+		logger.Warn("Invalid request transform template", zap.Error(err))
+		return body
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		logger.Warn("Failed to execute request transform template", zap.Error(err))
+		return body
+	}
+	if !json.Valid(out.Bytes()) {
+		// 模板输出包含请求中的数据，不写入日志
+		logger.Warn("Request transform template produced invalid JSON, forwarding original body",
+			zap.Int("size", out.Len()))
+		return body
+	}
+	return out.Bytes()
+}
+
+// decodeJSON 解析 JSON，数字保留为 json.Number，避免大整数在改写后丢失精度
+func decodeJSON(body []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return data, nil
+}
+
+// transformTemplate 返回已解析的请求体模板
+func transformTemplate(transform config.RequestTransform) (*template.Template, error) {
+	if tmpl, ok := transformTemplates.Load(transform.Template); ok {
+		return tmpl.(*template.Template), nil
+	}
+	tmpl, err := transform.ParseTemplate()
+	if err != nil {
+		return nil, err
+	}
+	transformTemplates.Store(transform.Template, tmpl)
+	return tmpl, nil
+}
+
+// applyRequestTransform 改写直接代理模式下发往上游的请求体，并更新 Content-Length
+// 请求体超过 maxTransformBodySize 时不改写，已读取的部分与剩余部分拼接后原样转发
+func applyRequestTransform(req *http.Request, transform config.RequestTransform) {
+	if req.Body == nil || req.Body == http.NoBody || !isJSONContentType(req.Header.Get("Content-Type")) {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxTransformBodySize+1))
+	if err != nil {
+		logger.Warn("Failed to read request body for transform", zap.Error(err))
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+		return
+	}
+	if len(body) > maxTransformBodySize {
+		logger.Warn("Request body too large to transform", zap.String("path", req.URL.Path))
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+		return
+	}
+	req.Body.Close()
+
+	body = transformRequestBody(transform, req.Header.Get("Content-Type"), body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
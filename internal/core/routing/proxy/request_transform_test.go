@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestTransformRequestBody(t *testing.T) {
+	logger.InitTestLogger()
+	rename := config.RequestTransform{Rename: []config.FieldRename{{From: "userId", To: "user_id"}}}
+	quoted := config.RequestTransform{Template: `{"name": "{{.name}}", "age": {{.age}}, "admin": false}`}
+	oversized := `{"userId":"` + strings.Repeat("a", maxTransformBodySize) + `"}`
+	wrap := config.RequestTransform{
+		Rename:   []config.FieldRename{{From: "userId", To: "uid"}},
+		Template: `{"version": 1, "payload": {{json .}}, "name": {{json .name}}}`,
+	}
+	tests := []struct {
+		name        string
+		transform   config.RequestTransform
+		contentType string
+		body        string
+		want        string
+	}{
+		{"rename", rename, "application/json", `{"userId":7,"name":"alice"}`, `{"name":"alice","user_id":7}`},
+		{"template", wrap, "application/json; charset=utf-8", `{"userId":7,"name":"alice"}`,
+			`{"version": 1, "payload": {"name":"alice","uid":7}, "name": "alice"}`},
+		{"vendor json", rename, "application/vnd.api+json", `{"userId":7}`, `{"user_id":7}`},
+		{"non json untouched", rename, "text/plain", `{"userId":7}`, `{"userId":7}`},
+		{"malformed json untouched", rename, "application/json", `{"userId":`, `{"userId":`},
+		{"invalid template output untouched", config.RequestTransform{Template: `{{.name}}`}, "application/json",
+			`{"name":"alice"}`, `{"name":"alice"}`},
+		{"template values escaped", quoted, "application/json",
+			`{"name":"x\", \"admin\": true, \"y\": \"z","age":30}`,
+			`{"name": "x\", \"admin\": true, \"y\": \"z", "age": 30, "admin": false}`},
+		{"oversized body untouched", rename, "application/json", oversized, oversized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := transformRequestBody(tt.transform, tt.contentType, []byte(tt.body))
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestPrepareFastHTTPRequest_TransformsBody(t *testing.T) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/users", strings.NewReader(`{"userId":7}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	setRequestTransform(c, config.RequestTransform{Template: `{"data": {{json .}}}`})
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	(&HTTPProxy{}).prepareFastHTTPRequest(c, req, "example.com", "stable")
+
+	assert.Equal(t, `{"data": {"userId":7}}`, string(req.Body()))
+
+	// 连接池模式同样不改写超过上限的请求体
+	oversized := `{"userId":"` + strings.Repeat("a", maxTransformBodySize) + `"}`
+	c.Request = httptest.NewRequest("POST", "/users", strings.NewReader(oversized))
+	c.Request.Header.Set("Content-Type", "application/json")
+	req.Reset()
+	(&HTTPProxy{}).prepareFastHTTPRequest(c, req, "example.com", "stable")
+	assert.Equal(t, oversized, string(req.Body()))
+}
+
+func TestCreateDirector_TransformsBody(t *testing.T) {
+	logger.InitTestLogger()
+	var received string
+	var contentLength int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, contentLength = string(body), r.ContentLength
+	}))
+	defer backend.Close()
+
+	targetURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	transform := config.RequestTransform{Rename: []config.FieldRename{{From: "userId", To: "user_id"}}}
+	req, err := http.NewRequest("POST", backend.URL+"/users", strings.NewReader(`{"userId":7}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	(&HTTPProxy{}).createDirector(targetURL, "stable", transform)(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, `{"user_id":7}`, received)
+	assert.Equal(t, int64(len(received)), contentLength)
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+)
+
+// responseFilterKey 上下文中保存所选目标响应字段过滤规则的键
+const responseFilterKey = "response_filter"
+
+// findResponseFilter 返回转发到 target 的规则配置的响应字段过滤规则
+func findResponseFilter(rules config.RoutingRules, target string) []string {
+	for _, rule := range rules {
+		if rule.Target == target && len(rule.ResponseFilter) > 0 {
+			return rule.ResponseFilter
+		}
+	}
+	return nil
+}
+
+// setResponseFilter 记录本次转发使用的响应字段过滤规则
+func setResponseFilter(c *gin.Context, fields []string) {
+	c.Set(responseFilterKey, fields)
+}
+
+// responseFilter 返回本次转发使用的响应字段过滤规则
+func responseFilter(c *gin.Context) ([]string, bool) {
+	fields, _ := c.Value(responseFilterKey).([]string)
+	return fields, len(fields) > 0
+}
+
+// errResponseNotFiltered 配置了响应字段过滤但响应体无法过滤，为避免泄露应过滤的字段不返回该响应
+var errResponseNotFiltered = errors.New("response body cannot be filtered")
+
+// filterResponseBody 从 JSON 响应体中删除指定字段，返回过滤后的响应体及是否做了改写
+// 非 JSON 响应原样返回；JSON 响应经过压缩、超过 maxTransformBodySize 或格式错误时无法过滤，返回 errResponseNotFiltered
+func filterResponseBody(fields []string, contentType, contentEncoding string, body []byte) ([]byte, bool, error) {
+	if len(fields) == 0 || len(body) == 0 || !isJSONContentType(contentType) {
+		return body, false, nil
+	}
+	if !isIdentityEncoding(contentEncoding) {
+		return nil, false, fmt.Errorf("%w: content encoding %q", errResponseNotFiltered, contentEncoding)
+	}
+	if len(body) > maxTransformBodySize {
+		return nil, false, fmt.Errorf("%w: body exceeds %d bytes", errResponseNotFiltered, maxTransformBodySize)
+	}
+
+	data, err := decodeJSON(body)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", errResponseNotFiltered, err)
+	}
+	for _, field := range fields {
+		removeJSONField(data, strings.Split(field, "."))
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", errResponseNotFiltered, err)
+	}
+	return out, true, nil
+}
+
+// removeJSONField 按路径删除字段，路径经过数组时对每个元素继续匹配剩余路径
+func removeJSONField(node any, path []string) {
+	switch v := node.(type) {
+	case []any:
+		for _, item := range v {
+			removeJSONField(item, path)
+		}
+	case map[string]any:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		if child, ok := v[path[0]]; ok {
+			removeJSONField(child, path[1:])
+		}
+	}
+}
+
+// isIdentityEncoding 判断响应体是否未经压缩
+func isIdentityEncoding(contentEncoding string) bool {
+	return contentEncoding == "" || strings.EqualFold(contentEncoding, "identity")
+}
+
+// filterHTTPResponse 过滤直接代理模式下上游 JSON 响应中的字段，并更新 Content-Length
+// 响应体无法过滤时返回 errResponseNotFiltered，由 ReverseProxy 关闭响应体并交给错误处理函数返回 502
+func filterHTTPResponse(resp *http.Response, fields []string) error {
+	contentType, contentEncoding := resp.Header.Get("Content-Type"), resp.Header.Get("Content-Encoding")
+	if !isJSONContentType(contentType) {
+		return nil
+	}
+	if resp.ContentLength > maxTransformBodySize {
+		return fmt.Errorf("%w: body exceeds %d bytes", errResponseNotFiltered, maxTransformBodySize)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTransformBodySize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errResponseNotFiltered, err)
+	}
+	resp.Body.Close()
+
+	body, _, err = filterResponseBody(fields, contentType, contentEncoding, body)
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// readFilterableBody 读取需要过滤的 fasthttp 响应体，以流的形式读取时最多读取 maxTransformBodySize+1 字节，
+// 超出上限的部分不会被缓存，由 filterResponseBody 判定为无法过滤
+func readFilterableBody(resp *fasthttp.Response) ([]byte, error) {
+	if !resp.IsBodyStream() {
+		return resp.Body(), nil
+	}
+	if resp.Header.ContentLength() > maxTransformBodySize {
+		return nil, fmt.Errorf("%w: body exceeds %d bytes", errResponseNotFiltered, maxTransformBodySize)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.BodyStream(), maxTransformBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errResponseNotFiltered, err)
+	}
+	return body, nil
+}
+
+// writeResponseNotFiltered 记录无法过滤的响应并返回 502，不计为目标失败
+func writeResponseNotFiltered(w http.ResponseWriter, r *http.Request, target string, err error) {
+	logger.Warn("Rejected upstream response that cannot be filtered",
+		zap.String("path", r.URL.Path),
+		zap.String("target", target),
+		zap.Error(err))
+	writeHTTPError(w, r, http.StatusBadGateway, ErrCodeResponseNotFiltered, "Upstream response cannot be filtered")
+}
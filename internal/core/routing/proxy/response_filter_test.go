@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFilterResponseBody(t *testing.T) {
+	logger.InitTestLogger()
+	fields := []string{"password", "profile.ssn", "orders.card.number", "tags.secret"}
+	tests := []struct {
+		name            string
+		contentType     string
+		contentEncoding string
+		body            string
+		want            string
+		wantFiltered    bool
+	}{
+		{
+			name:        "nested fields and arrays",
+			contentType: "application/json",
+			body: `{"id":9007199254740993,"password":"x","profile":{"name":"alice","ssn":"123"},` +
+				`"orders":[{"id":1,"card":{"number":"4111","brand":"visa"}},{"id":2,"card":{"number":"5500"}}],"tags":["a","b"]}`,
+			want: `{"id":9007199254740993,"orders":[{"card":{"brand":"visa"},"id":1},{"card":{},"id":2}],` +
+				`"profile":{"name":"alice"},"tags":["a","b"]}`,
+			wantFiltered: true,
+		},
+		{
+			name:         "top-level array",
+			contentType:  "application/json; charset=utf-8",
+			body:         `[{"id":1,"password":"x"},{"id":2}]`,
+			want:         `[{"id":1},{"id":2}]`,
+			wantFiltered: true,
+		},
+		{name: "non json untouched", contentType: "text/plain", body: `{"password":"x"}`, want: `{"password":"x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, filtered, err := filterResponseBody(fields, tt.contentType, tt.contentEncoding, []byte(tt.body))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.wantFiltered, filtered)
+		})
+	}
+
+	// 无法过滤的 JSON 响应不原样返回
+	large := `{"password":"` + strings.Repeat("x", maxTransformBodySize) + `"}`
+	for name, tt := range map[string]struct{ encoding, body string }{
+		"compressed": {"gzip", `{"password":"x"}`},
+		"malformed":  {"", `{"password":`},
+		"oversize":   {"", large},
+	} {
+		got, _, err := filterResponseBody(fields, "application/json", tt.encoding, []byte(tt.body))
+		assert.ErrorIs(t, err, errResponseNotFiltered, name)
+		assert.Nil(t, got, name)
+	}
+}
+
+// newFilteringGateway 启动转发到 backend 的网关，/users 路由过滤响应中的 password 字段
+func newFilteringGateway(t *testing.T, backend http.HandlerFunc, usePool bool) string {
+	upstream := httptest.NewServer(backend)
+	t.Cleanup(upstream.Close)
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.GET("/users", hp.CreateHTTPHandler(config.RoutingRules{{Target: upstream.URL, Protocol: "http", ResponseFilter: []string{"password"}}}))
+	gateway := httptest.NewServer(router)
+	t.Cleanup(gateway.Close)
+	return gateway.URL
+}
+
+// getWithGzip 以浏览器的方式请求，声明接受 gzip 压缩，并关闭客户端的自动解压
+func getWithGzip(t *testing.T, url string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	resp, err := (&http.Client{Transport: &http.Transport{DisableCompression: true}}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+// gzipJSON 返回 gzip 压缩后的 JSON 响应
+func gzipJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	gz.Write([]byte(body))
+	gz.Close()
+}
+
+func TestProxy_ResponseFilterRequestsUncompressedBody(t *testing.T) {
+	for _, usePool := range []bool{false, true} {
+		// 后端按 Accept-Encoding 协商压缩：网关在过滤路由上只接受未压缩的响应体
+		gateway := newFilteringGateway(t, func(w http.ResponseWriter, r *http.Request) {
+			body := `{"name":"alice","password":"x"}`
+			if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				gzipJSON(w, body)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}, usePool)
+
+		resp, body := getWithGzip(t, gateway+"/users")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "pool=%v", usePool)
+		assert.Equal(t, `{"name":"alice"}`, body, "pool=%v", usePool)
+	}
+}
+
+func TestProxy_ResponseFilterFailsClosed(t *testing.T) {
+	large := `{"password":"` + strings.Repeat("x", maxTransformBodySize) + `"}`
+	for name, backend := range map[string]http.HandlerFunc{
+		// 后端忽略 Accept-Encoding 仍返回压缩的响应体
+		"gzip": func(w http.ResponseWriter, r *http.Request) { gzipJSON(w, `{"password":"x"}`) },
+		"oversize": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(large))
+		},
+		// 分块传输，未声明长度
+		"oversize chunked": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(large[:maxTransformBodySize/2]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(large[maxTransformBodySize/2:]))
+		},
+	} {
+		for _, usePool := range []bool{false, true} {
+			resp, body := getWithGzip(t, newFilteringGateway(t, backend, usePool)+"/users")
+			assert.Equal(t, http.StatusBadGateway, resp.StatusCode, "%s pool=%v", name, usePool)
+			assert.Contains(t, body, ErrCodeResponseNotFiltered, "%s pool=%v", name, usePool)
+			assert.NotContains(t, body, "password", "%s pool=%v", name, usePool)
+		}
+	}
+}
+
+func TestWriteFastHTTPResponse_FiltersFields(t *testing.T) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	resp.Header.SetContentType("application/json")
+	resp.SetBody([]byte(`{"user":{"name":"alice","password":"x"}}`))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setResponseFilter(c, []string{"user.password"})
+	(&HTTPProxy{}).writeFastHTTPResponse(c, resp, "http://backend")
+
+	assert.Equal(t, `{"user":{"name":"alice"}}`, w.Body.String())
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		assert.Equal(t, strconv.Itoa(w.Body.Len()), cl)
+	}
+}
+
+func TestFilterHTTPResponse(t *testing.T) {
+	logger.InitTestLogger()
+	body := `{"items":[{"id":1,"token":"a"},{"id":2,"token":"b"}]}`
+	resp := &http.Response{
+		Header:        http.Header{"Content-Type": {"application/json"}, "Content-Length": {strconv.Itoa(len(body))}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+
+	require.NoError(t, filterHTTPResponse(resp, []string{"items.token"}))
+
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"items":[{"id":1},{"id":2}]}`, string(got))
+	assert.Equal(t, int64(len(got)), resp.ContentLength)
+	assert.Equal(t, strconv.Itoa(len(got)), resp.Header.Get("Content-Length"))
+}
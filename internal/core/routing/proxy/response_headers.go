@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	oversizedHeaderStrip    = "strip"    // 删除超出上限的响应头
+	oversizedHeaderTruncate = "truncate" // 将超出上限的响应头值截断到上限
+)
+
+// headerLimit 上游响应头大小限制
+type headerLimit struct {
+	maxSize int
+	action  string
+}
+
+// newHeaderLimit 根据配置创建响应头大小限制
+func newHeaderLimit(cfg config.ResponseHeaderLimit) headerLimit {
+	action := cfg.Action
+	if action == "" {
+		action = oversizedHeaderStrip
+	}
+	return headerLimit{maxSize: cfg.MaxSize, action: action}
+}
+
+// enabled 是否启用了响应头大小限制
+func (l headerLimit) enabled() bool {
+	return l.maxSize > 0
+}
+
+// fit 检查单个响应头是否超出上限，返回应转发的值，ok 为 false 时应删除该响应头
+// 名称本身已超出上限、无法保留任何值时，truncate 也按 strip 处理
+func (l headerLimit) fit(target, name, value string) (string, bool) {
+	if !l.enabled() || len(name)+len(value) <= l.maxSize {
+		return value, true
+	}
+	keep := l.maxSize - len(name)
+	action := l.action
+	if keep <= 0 {
+		action = oversizedHeaderStrip
+	}
+
+	observability.OversizedResponseHeaders.WithLabelValues(target, name, action).Inc()
+	logger.Warn("Upstream response header exceeds size limit",
+		zap.String("target", target),
+		zap.String("header", name),
+		zap.Int("size", len(name)+len(value)),
+		zap.Int("maxSize", l.maxSize),
+		zap.String("action", action))
+
+	if action == oversizedHeaderStrip {
+		return "", false
+	}
+	return value[:keep], true
+}
+
+// apply 对直接代理模式下的上游响应头应用大小限制
+func (l headerLimit) apply(h http.Header, target string) {
+	if !l.enabled() {
+		return
+	}
+	for name, values := range h {
+		kept := values[:0]
+		for _, value := range values {
+			if value, ok := l.fit(target, name, value); ok {
+				kept = append(kept, value)
+			}
+		}
+		if len(kept) == 0 {
+			delete(h, name)
+		} else {
+			h[name] = kept
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// hugeCookie 超过 fasthttp 默认 4KB 读缓冲区的 Set-Cookie 值
+var hugeCookie = "session=" + strings.Repeat("a", 6000)
+
+// serveWithHeaderLimit 按给定响应头限制代理一次请求到返回超大 Set-Cookie 的后端
+func serveWithHeaderLimit(t *testing.T, usePool bool, limit config.ResponseHeaderLimit) (*httptest.ResponseRecorder, string) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", hugeCookie)
+		w.Header().Set("X-Small", "ok")
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(backend.Close)
+
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin", ResponseHeaders: limit}}
+
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+	return w, backend.URL
+}
+
+func TestResponseHeaderLimit_Strip(t *testing.T) {
+	for _, usePool := range []bool{false, true} {
+		w, target := serveWithHeaderLimit(t, usePool, config.ResponseHeaderLimit{MaxSize: 4096, Action: "strip"})
+
+		assert.Equal(t, http.StatusOK, w.Code, "pool=%v", usePool)
+		assert.Equal(t, "hello", w.Body.String(), "pool=%v", usePool)
+		assert.Empty(t, w.Header().Values("Set-Cookie"), "pool=%v", usePool)
+		assert.Equal(t, "ok", w.Header().Get("X-Small"), "pool=%v", usePool)
+		assert.Equal(t, 1.0, testutil.ToFloat64(observability.OversizedResponseHeaders.WithLabelValues(target, "Set-Cookie", "strip")), "pool=%v", usePool)
+	}
+}
+
+func TestResponseHeaderLimit_Truncate(t *testing.T) {
+	for _, usePool := range []bool{false, true} {
+		w, target := serveWithHeaderLimit(t, usePool, config.ResponseHeaderLimit{MaxSize: 4096, Action: "truncate"})
+
+		assert.Equal(t, http.StatusOK, w.Code, "pool=%v", usePool)
+		cookie := w.Header().Get("Set-Cookie")
+		assert.Len(t, cookie, 4096-len("Set-Cookie"), "pool=%v", usePool)
+		assert.True(t, strings.HasPrefix(hugeCookie, cookie), "pool=%v", usePool)
+		assert.Equal(t, 1.0, testutil.ToFloat64(observability.OversizedResponseHeaders.WithLabelValues(target, "Set-Cookie", "truncate")), "pool=%v", usePool)
+	}
+}
+
+func TestResponseHeaderLimit_Disabled(t *testing.T) {
+	for _, usePool := range []bool{false, true} {
+		w, _ := serveWithHeaderLimit(t, usePool, config.ResponseHeaderLimit{})
+
+		assert.Equal(t, http.StatusOK, w.Code, "pool=%v", usePool)
+		assert.Equal(t, hugeCookie, w.Header().Get("Set-Cookie"), "pool=%v", usePool)
+	}
+}
+
+func TestHeaderLimitFit_NameExceedsLimit(t *testing.T) {
+	logger.InitTestLogger()
+	limit := newHeaderLimit(config.ResponseHeaderLimit{MaxSize: 4, Action: "truncate"})
+
+	_, ok := limit.fit("http://backend", "X-Long-Name", "v")
+	assert.False(t, ok, "a header whose name alone exceeds the limit cannot be truncated and must be stripped")
+}
+
+func TestHTTPProxy_RefreshSettings_HeaderLimit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", hugeCookie)
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(backend.Close)
+
+	for _, usePool := range []bool{false, true} {
+		hp := newTestProxy(t, &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}, withPool(usePool))
+		router := gin.New()
+		router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: backend.URL, Protocol: "http"}}))
+		serve := func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+			return w
+		}
+
+		assert.Equal(t, hugeCookie, serve().Header().Get("Set-Cookie"), "no limit initially (pool=%v)", usePool)
+
+		hp.RefreshSettings(&config.Config{Routing: config.Routing{ResponseHeaders: config.ResponseHeaderLimit{MaxSize: 4096, Action: "strip"}}})
+		w := serve()
+		assert.Equal(t, http.StatusOK, w.Code, "pool=%v", usePool)
+		assert.Empty(t, w.Header().Values("Set-Cookie"), "reloaded limit strips the header (pool=%v)", usePool)
+	}
+}
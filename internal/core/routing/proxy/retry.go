@@ -0,0 +1,207 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+)
+
+const (
+	retryOnConnection = "connection" // 仅连接错误时重试
+	retryOnStatus     = "status"     // 连接错误及指定状态码时重试
+
+	maxRetryBodySize = 1 << 20 // 可重试请求体的上限，超过时不重试以免缓存过大的请求体
+)
+
+// retryPolicy 上游重试策略
+type retryPolicy struct {
+	attempts    int
+	on          string
+	statusCodes []int
+	backoff     time.Duration // 首次重试前的等待时间
+	maxBackoff  time.Duration // 等待时间上限
+}
+
+// newRetryPolicy 根据配置创建重试策略
+func newRetryPolicy(cfg config.Retry) retryPolicy {
+	on := cfg.On
+	if on == "" {
+		on = retryOnConnection
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff < cfg.Backoff {
+		maxBackoff = cfg.Backoff
+	}
+	return retryPolicy{attempts: cfg.Attempts, on: on, statusCodes: cfg.StatusCodes, backoff: cfg.Backoff, maxBackoff: maxBackoff}
+}
+
+// delay 返回第 attempt 次重试前的等待时间：按指数增长并受上限约束，再在其一半到全部之间随机取值
+func (p retryPolicy) delay(attempt int) time.Duration {
+	if p.backoff <= 0 {
+		return 0
+	}
+	d := p.backoff
+	for i := 1; i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(d-half)+1))
+}
+
+// wait 在第 attempt 次重试前等待，请求被取消时提前返回错误
+func (p retryPolicy) wait(ctx context.Context, attempt int) error {
+	d := p.delay(attempt)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// allows 检查该请求是否可以重试：只重试幂等方法，且请求体不超过上限
+func (p retryPolicy) allows(method string, contentLength int64) bool {
+	if p.attempts <= 0 || contentLength < 0 || contentLength > maxRetryBodySize {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryReason 返回本次结果需要重试的原因，不需要重试时返回空字符串
+func (p retryPolicy) retryReason(err error, status int) string {
+	if err != nil {
+		if isConnectionError(err) {
+			return retryOnConnection
+		}
+		return ""
+	}
+	if p.on == retryOnStatus && slices.Contains(p.statusCodes, status) {
+		return strconv.Itoa(status)
+	}
+	return ""
+}
+
+// isConnectionError 判断错误是否发生在连接建立阶段，此时请求尚未发送到后端，重试不会重复产生副作用
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, fasthttp.ErrDialTimeout)
+}
+
+// recordRetry 记录一次重试
+func recordRetry(target, reason string, attempt int) {
+	observability.UpstreamRetries.WithLabelValues(target, reason).Inc()
+	logger.Warn("Retrying upstream request",
+		zap.String("target", target),
+		zap.String("reason", reason),
+		zap.Int("attempt", attempt))
+}
+
+// retryTransport 在直接代理模式下按重试策略重新发送请求
+type retryTransport struct {
+	base   http.RoundTripper
+	policy retryPolicy
+	target string
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.policy.allows(req.Method, req.ContentLength) {
+		return t.base.RoundTrip(req)
+	}
+	if err := makeRequestReplayable(req); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		reason := t.policy.retryReason(err, status)
+		if reason == "" || attempt > t.policy.attempts || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		recordRetry(t.target, reason, attempt)
+		if err := t.policy.wait(req.Context(), attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// makeRequestReplayable 读取请求体到内存并设置 GetBody，使请求可以重复发送，请求体大小已由 allows 限制
+func makeRequestReplayable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// doWithRetry 在连接池模式下按重试策略发送请求，请求体已在 fasthttp 请求中缓存，可直接重发；ctx 被取消时停止重试
+func doWithRetry(ctx context.Context, client *fasthttp.HostClient, req *fasthttp.Request, resp *fasthttp.Response, policy retryPolicy, target string) error {
+	if !policy.allows(string(req.Header.Method()), int64(len(req.Body()))) {
+		return client.Do(req, resp)
+	}
+	for attempt := 1; ; attempt++ {
+		err := client.Do(req, resp)
+		reason := policy.retryReason(err, resp.StatusCode())
+		if reason == "" || attempt > policy.attempts {
+			return err
+		}
+		// Reset 会清除 StreamBody，重试前恢复；未读完的响应体流连同连接一起丢弃
+		discardBodyStream(resp)
+		stream := resp.StreamBody
+		resp.Reset()
+		resp.StreamBody = stream
+		recordRetry(target, reason, attempt)
+		if waitErr := policy.wait(ctx, attempt); waitErr != nil {
+			return waitErr
+		}
+	}
+}
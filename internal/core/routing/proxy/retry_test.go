@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveWithRetry 按给定重试策略代理一次请求到目标
+func serveWithRetry(t *testing.T, target string, usePool bool, retry config.Retry, method string) *httptest.ResponseRecorder {
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin", Retry: retry}}
+
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.Any("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: target, Protocol: "http"}}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/user", strings.NewReader("")))
+	return w
+}
+
+// newFailingBackend 启动始终返回 500 的后端，返回其地址和请求计数
+func newFailingBackend(t *testing.T) (string, *atomic.Int32) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(backend.Close)
+	return backend.URL, &hits
+}
+
+// closedAddr 返回一个没有进程监听的本地地址，连接会被拒绝
+func closedAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+func TestRetry_ConnectionOnlyDoesNotRetryStatus(t *testing.T) {
+	for _, usePool := range []bool{false, true} {
+		target, hits := newFailingBackend(t)
+		w := serveWithRetry(t, target, usePool, config.Retry{Attempts: 2}, http.MethodGet)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, "pool=%v", usePool)
+		assert.Equal(t, int32(1), hits.Load(), "a 500 must not be retried in connection-only mode (pool=%v)", usePool)
+	}
+}
+
+func TestRetry_ConnectionRefusedIsRetried(t *testing.T) {
+	for _, usePool := range []bool{false, true} {
+		target := "http://" + closedAddr(t)
+		before := testutil.ToFloat64(observability.UpstreamRetries.WithLabelValues(target, retryOnConnection))
+		w := serveWithRetry(t, target, usePool, config.Retry{Attempts: 2}, http.MethodGet)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code, "pool=%v", usePool)
+		assert.Equal(t, before+2, testutil.ToFloat64(observability.UpstreamRetries.WithLabelValues(target, retryOnConnection)), "pool=%v", usePool)
+	}
+}
+
+func TestRetry_StatusCodes(t *testing.T) {
+	retry := config.Retry{Attempts: 2, On: "status", StatusCodes: []int{http.StatusInternalServerError}}
+	for _, usePool := range []bool{false, true} {
+		target, hits := newFailingBackend(t)
+		w := serveWithRetry(t, target, usePool, retry, http.MethodGet)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, "pool=%v", usePool)
+		assert.Equal(t, int32(3), hits.Load(), "pool=%v", usePool)
+
+		// 非幂等方法不重试
+		target, hits = newFailingBackend(t)
+		serveWithRetry(t, target, usePool, retry, http.MethodPost)
+		assert.Equal(t, int32(1), hits.Load(), "POST must not be retried (pool=%v)", usePool)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := newRetryPolicy(config.Retry{Attempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond})
+	for i := 0; i < 100; i++ {
+		for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 10: 300 * time.Millisecond} {
+			d := policy.delay(attempt)
+			assert.GreaterOrEqual(t, d, want/2, "attempt %d", attempt)
+			assert.LessOrEqual(t, d, want, "attempt %d", attempt)
+		}
+	}
+
+	assert.Zero(t, newRetryPolicy(config.Retry{Attempts: 2}).delay(1), "no backoff configured")
+}
+
+func TestRetryPolicy_WaitStopsOnCancel(t *testing.T) {
+	policy := newRetryPolicy(config.Retry{Attempts: 1, Backoff: time.Minute, MaxBackoff: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	assert.ErrorIs(t, policy.wait(ctx, 1), context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetry_BacksOffBetweenAttempts(t *testing.T) {
+	retry := config.Retry{Attempts: 2, On: "status", StatusCodes: []int{http.StatusInternalServerError}, Backoff: 40 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+	for _, usePool := range []bool{false, true} {
+		target, hits := newFailingBackend(t)
+		start := time.Now()
+		serveWithRetry(t, target, usePool, retry, http.MethodGet)
+
+		assert.Equal(t, int32(3), hits.Load(), "pool=%v", usePool)
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond, "two retries wait at least half the backoff each (pool=%v)", usePool)
+	}
+}
+
+func TestHTTPProxy_RefreshRetryPolicy(t *testing.T) {
+	target, hits := newFailingBackend(t)
+	hp := newTestProxy(t, &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}})
+	router := gin.New()
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(config.RoutingRules{{Target: target, Protocol: "http"}}))
+	serve := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/user", nil))
+	}
+
+	serve()
+	assert.Equal(t, int32(1), hits.Load(), "retry is disabled initially")
+
+	hp.RefreshRetryPolicy(&config.Config{Routing: config.Routing{
+		Retry: config.Retry{Attempts: 1, On: "status", StatusCodes: []int{http.StatusInternalServerError}},
+	}})
+	serve()
+	assert.Equal(t, int32(3), hits.Load(), "reloaded policy retries once")
+}
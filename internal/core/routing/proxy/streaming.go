@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	eventStreamMediaType = "text/event-stream" // SSE 的媒体类型
+	streamChunkSize      = 32 << 10            // 流式转发时每次读取上游响应体的缓冲区大小
+)
+
+// acceptsEventStream 判断客户端是否请求 SSE
+// 连接池客户端的读取超时作用于整个响应，长时间推送的事件流会被截断，这类请求改走直接代理，
+// httputil 收到事件流后每读到一段数据就立即刷新
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == eventStreamMediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamFastHTTPBody 边读边写上游响应体，每读到一段数据就刷新给客户端，SSE 事件和分块响应无需等到上游结束即可到达
+// 读完后关闭响应体流，连接归还连接池；中途失败时由 discardBodyStream 关闭连接
+func streamFastHTTPBody(c *gin.Context, resp *fasthttp.Response) error {
+	body := resp.BodyStream()
+	buf := make([]byte, streamChunkSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			c.Writer.Flush()
+		}
+		if err == io.EOF {
+			return resp.CloseBodyStream()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// discardBodyStream 关闭未读完的上游响应体流，连接上仍有未读数据，不能放回连接池复用，因此同时关闭连接
+func discardBodyStream(resp *fasthttp.Response) {
+	if resp.IsBodyStream() {
+		resp.SetConnectionClose()
+		resp.CloseBodyStream()
+	}
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEventStreamBackend 启动先推送一个事件、收到 release 后再推送第二个事件的后端
+func newEventStreamBackend(t *testing.T, contentType string) (string, func()) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "data: second\n\n")
+	}))
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(backend.Close)
+	t.Cleanup(releaseOnce) // 先放行后端处理函数，backend.Close 才不会阻塞
+	return backend.URL, releaseOnce
+}
+
+// newStreamingGateway 启动将 /events 代理到 target 的网关
+func newStreamingGateway(t *testing.T, target string, usePool bool) string {
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.GET("/events", hp.CreateHTTPHandler(config.RoutingRules{{Target: target, Protocol: "http"}}))
+	gateway := httptest.NewServer(router)
+	t.Cleanup(gateway.Close)
+	return gateway.URL
+}
+
+// readLine 在超时前读取一行，超时返回空字符串
+func readLine(r *bufio.Reader, timeout time.Duration) string {
+	got := make(chan string, 1)
+	go func() {
+		line, _ := r.ReadString('\n')
+		got <- line
+	}()
+	select {
+	case line := <-got:
+		return line
+	case <-time.After(timeout):
+		return ""
+	}
+}
+
+func TestProxy_StreamsEventsBeforeUpstreamFinishes(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		accept      string
+		usePool     bool
+	}{
+		{name: "direct", contentType: "text/event-stream", accept: "text/event-stream"},
+		{name: "pool with event-stream accept", contentType: "text/event-stream", accept: "text/event-stream", usePool: true},
+		{name: "pool chunked response", contentType: "text/plain", usePool: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			target, release := newEventStreamBackend(t, tc.contentType)
+			gateway := newStreamingGateway(t, target, tc.usePool)
+
+			req, err := http.NewRequest("GET", gateway+"/events", nil)
+			require.NoError(t, err)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tc.contentType, resp.Header.Get("Content-Type"))
+
+			// 上游尚未结束时客户端就应收到第一个事件
+			body := bufio.NewReader(resp.Body)
+			assert.Equal(t, "data: first\n", readLine(body, 2*time.Second))
+
+			release()
+			rest, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Contains(t, string(rest), "data: second\n\n")
+		})
+	}
+}
+
+func TestAcceptsEventStream(t *testing.T) {
+	for _, tc := range []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "text/event-stream", want: true},
+		{accept: "application/json, text/event-stream;q=0.9", want: true},
+		{accept: "TEXT/EVENT-STREAM", want: true},
+		{accept: "application/json", want: false},
+		{accept: "", want: false},
+	} {
+		req := httptest.NewRequest("GET", "/events", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		assert.Equal(t, tc.want, acceptsEventStream(req), tc.accept)
+	}
+}
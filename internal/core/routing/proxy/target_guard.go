@@ -0,0 +1,50 @@
+package proxy
+
+import "github.com/gin-gonic/gin"
+
+// targetGuardKey 上下文中保存目标保护函数的键
+const targetGuardKey = "target_guard"
+
+// TargetGuard 包装对已选目标的转发，熔断器据此按目标隔离故障后端
+// 允许转发时调用 forward，拒绝时自行写出响应
+type TargetGuard func(target string, forward func())
+
+// SetTargetGuard 为请求设置目标保护函数，由代理之前的中间件（如熔断器）调用
+func SetTargetGuard(c *gin.Context, guard TargetGuard) {
+	c.Set(targetGuardKey, guard)
+}
+
+// GuardTarget 在选出目标后转发请求，请求设置了目标保护函数时经由它转发
+// 各协议的代理在负载均衡选出目标后调用
+func GuardTarget(c *gin.Context, target string, forward func()) {
+	if value, ok := c.Get(targetGuardKey); ok {
+		if guard, ok := value.(TargetGuard); ok && guard != nil {
+			guard(target, forward)
+			return
+		}
+	}
+	forward()
+}
+
+// targetAdmissionKey 上下文中保存目标准入函数的键
+const targetAdmissionKey = "target_admission"
+
+// TargetAdmission 为同时转发到多个目标的请求（如扇出）逐个判断目标是否允许转发，拒绝时 ok 为 false
+// 允许时须在收到该目标的结果后调用 done，status 为上游状态码，0 表示未收到上游响应
+type TargetAdmission func(target string) (done func(status int), ok bool)
+
+// SetTargetAdmission 为请求设置目标准入函数，由代理之前的中间件（如熔断器）调用
+func SetTargetAdmission(c *gin.Context, admission TargetAdmission) {
+	c.Set(targetAdmissionKey, admission)
+}
+
+// AdmitTarget 判断目标是否允许转发，请求未设置目标准入函数时总是允许
+// 响应由多个目标的结果合成、无法经由 GuardTarget 转发的代理（如扇出）在发出每个目标的请求前调用
+func AdmitTarget(c *gin.Context, target string) (done func(status int), ok bool) {
+	if value, exists := c.Get(targetAdmissionKey); exists {
+		if admission, valid := value.(TargetAdmission); valid && admission != nil {
+			return admission(target)
+		}
+	}
+	return func(int) {}, true
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/internal/core/health"
+)
+
+// upstreamStatusKey 上下文中记录上游结果的键，仅在请求实际转发到上游后设置
+const upstreamStatusKey = "upstream_status"
+
+// SetUpstreamStatus 记录上游响应的状态码，status 为 0 表示已转发但未收到上游响应（连接失败、超时等）
+// 各协议的代理在请求到达上游后调用
+func SetUpstreamStatus(c *gin.Context, status int) {
+	c.Set(upstreamStatusKey, status)
+	if value, ok := c.Get(upstreamResponseHookKey); ok {
+		if hook, ok := value.(func()); ok && hook != nil {
+			hook()
+		}
+	}
+}
+
+// upstreamResponseHookKey 上下文中保存收到上游响应时回调的键
+const upstreamResponseHookKey = "upstream_response_hook"
+
+// OnUpstreamResponse 注册记录上游结果时的回调，此时已收到上游响应头（或确认未能收到），响应体可能仍在传输
+// 熔断器据此只对等待上游响应的阶段计时；同一请求可能多次记录上游结果，回调需能重复调用
+func OnUpstreamResponse(c *gin.Context, hook func()) {
+	c.Set(upstreamResponseHookKey, hook)
+}
+
+// UpstreamStatus 返回请求的上游结果，forwarded 为 false 表示请求未到达上游，
+// 响应由网关自身产生（如限流、认证失败或没有可用目标），熔断等按上游健康度统计的逻辑应忽略这类请求
+func UpstreamStatus(c *gin.Context) (status int, forwarded bool) {
+	value, ok := c.Get(upstreamStatusKey)
+	if !ok {
+		return 0, false
+	}
+	status, ok = value.(int)
+	return status, ok
+}
+
+// updateRequestCount 计入单个目标的请求结果，测试中可替换
+var updateRequestCount = func(target string, success bool) {
+	health.GetGlobalHealthChecker().UpdateRequestCount(target, success)
+}
+
+// recordUpstreamOutcome 按记录的上游结果计入一次目标请求结果，未收到上游响应（状态码 0）或 5xx 计为失败，请求未到达上游时不计入
+func recordUpstreamOutcome(c *gin.Context, target string) {
+	status, forwarded := UpstreamStatus(c)
+	if !forwarded {
+		return
+	}
+	updateRequestCount(target, status != 0 && status < http.StatusInternalServerError)
+}
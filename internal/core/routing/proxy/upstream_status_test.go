@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// proxyUpstreamStatus 代理一次请求到给定规则，返回代理记录的上游结果
+func proxyUpstreamStatus(t *testing.T, rules config.RoutingRules, usePool bool) (status int, forwarded bool) {
+	cfg := &config.Config{Routing: config.Routing{LoadBalancer: "round_robin"}}
+
+	hp := newTestProxy(t, cfg, withPool(usePool))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		status, forwarded = UpstreamStatus(c)
+	})
+	router.GET("/api/v1/user", hp.CreateHTTPHandler(rules))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/user", nil))
+	return status, forwarded
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	backend, _ := newFailingBackend(t)
+	for _, usePool := range []bool{false, true} {
+		status, forwarded := proxyUpstreamStatus(t, config.RoutingRules{{Target: backend, Protocol: "http"}}, usePool)
+		assert.True(t, forwarded, "pool=%v", usePool)
+		assert.Equal(t, http.StatusInternalServerError, status, "pool=%v: upstream status is recorded", usePool)
+
+		status, forwarded = proxyUpstreamStatus(t, config.RoutingRules{{Target: "http://" + closedAddr(t), Protocol: "http"}}, usePool)
+		assert.True(t, forwarded, "pool=%v", usePool)
+		assert.Equal(t, 0, status, "pool=%v: unreachable upstream is recorded as 0", usePool)
+	}
+}
+
+func TestUpstreamStatus_GatewayResponsesAreNotForwarded(t *testing.T) {
+	// 没有可用目标时由网关直接返回错误，不应视为上游结果
+	_, forwarded := proxyUpstreamStatus(t, config.RoutingRules{}, false)
+	assert.False(t, forwarded)
+}
@@ -21,6 +21,7 @@ type WebSocketPool struct {
 	dialer    *websocket.Dialer          // WebSocket 拨号器
 	poolMgr   *util.ObjectPoolManager    // 可重用对象池管理器
 	cleanupCh chan struct{}              // 清理终止信号通道
+	done      chan struct{}              // 清理协程退出时关闭
 }
 
 // NewWebSocketPool 根据配置创建并初始化 WebSocket 连接池
@@ -31,6 +32,7 @@ func NewWebSocketPool(cfg *config.Config) *WebSocketPool {
 		idleTime:  cfg.WebSocket.IdleTimeout,  // 未指定时默认为 5 分钟
 		dialer:    websocket.DefaultDialer,
 		cleanupCh: make(chan struct{}),
+		done:      make(chan struct{}),
 		poolMgr:   util.NewPoolManager(cfg), // 初始化对象池管理器
 	}
 	go pool.startCleanup() // 启动后台清理协程
@@ -81,9 +83,10 @@ func (p *WebSocketPool) ReleaseConn(target string) {
 	// 不立即操作，依赖清理协程管理连接关闭
 }
 
-// Close 关闭连接池并清理所有活跃连接
+// Close 关闭连接池并清理所有活跃连接，返回前等待清理协程退出
 func (p *WebSocketPool) Close() {
 	close(p.cleanupCh) // 通知清理协程停止
+	<-p.done
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -101,6 +104,7 @@ func (p *WebSocketPool) Close() {
 
 // startCleanup 定期清理超出 maxIdle 限制的空闲连接
 func (p *WebSocketPool) startCleanup() {
+	defer close(p.done)
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
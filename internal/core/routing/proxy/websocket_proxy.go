@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/penwyp/mini-gateway/pkg/util"
 
@@ -29,8 +30,9 @@ var websocketTracer = otel.Tracer("proxy:websocket")
 
 // WebSocketProxy 管理 WebSocket 代理，包括连接池和负载均衡
 type WebSocketProxy struct {
-	pool *WebSocketPool            // WebSocket 连接池
-	lb   loadbalancer.LoadBalancer // 负载均衡器
+	pool   *WebSocketPool            // WebSocket 连接池
+	lb     loadbalancer.LoadBalancer // 负载均衡器
+	relays sync.WaitGroup            // 进行中的连接转发及其消息转发协程，关闭代理时等待其退出
 }
 
 // NewWebSocketProxy 根据配置创建并初始化 WebSocketProxy 实例
@@ -44,7 +46,7 @@ func NewWebSocketProxy(cfg *config.Config) *WebSocketProxy {
 	}
 	return &WebSocketProxy{
 		pool: NewWebSocketPool(cfg),
-		lb:   lb,
+		lb:   loadbalancer.NewDrainAware(lb, isTargetDrained),
 	}
 }
 
@@ -75,6 +77,8 @@ func (wp *WebSocketProxy) SetupWebSocketProxy(r gin.IRouter, cfg *config.Config)
 }
 
 // createWebSocketHandler 创建 WebSocket 连接的处理函数
+// 先选出目标并建立后端连接，成功后才升级客户端连接，目标不可用时客户端收到普通的 HTTP 错误响应；
+// 建立后端连接经由 GuardTarget，熔断器按目标统计握手失败
 func (wp *WebSocketProxy) createWebSocketHandler(rules config.RoutingRules, upgrader websocket.Upgrader, cfg *config.Config, poolMgr *util.ObjectPoolManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 HTTP 请求头中提取追踪上下文
@@ -83,23 +87,6 @@ func (wp *WebSocketProxy) createWebSocketHandler(rules config.RoutingRules, upgr
 			trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
 		defer connectSpan.End()
 
-		// 将客户端连接升级为 WebSocket
-		clientConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-		if err != nil {
-			connectSpan.RecordError(err)
-			connectSpan.SetStatus(codes.Error, "Failed to upgrade connection")
-			logger.Error("Failed to upgrade client connection to WebSocket",
-				zap.String("path", c.Request.URL.Path),
-				zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upgrade to WebSocket"})
-			return
-		}
-		defer clientConn.Close()
-
-		// 跟踪活跃的 WebSocket 连接数
-		observability.ActiveWebSocketConnections.Inc()
-		defer observability.ActiveWebSocketConnections.Dec()
-
 		// 使用对象池管理器获取目标切片
 		targets := poolMgr.GetTargets(len(rules))
 		defer poolMgr.PutTargets(targets)
@@ -112,11 +99,12 @@ func (wp *WebSocketProxy) createWebSocketHandler(rules config.RoutingRules, upgr
 			connectSpan.SetStatus(codes.Error, "No available target")
 			logger.Warn("No available WebSocket target found",
 				zap.String("path", c.Request.URL.Path))
-			clientConn.WriteMessage(websocket.TextMessage, []byte("No available target"))
+			WriteError(c, http.StatusServiceUnavailable, ErrCodeNoTarget, "No available target")
 			return
 		}
 		logger.Debug("Selected WebSocket target by load balancer",
 			zap.String("target", target))
+		c.Set("proxy_target", target)
 
 		// 验证目标是否为有效的 WebSocket URL
 		targetURL, err := url.Parse(target)
@@ -127,7 +115,7 @@ func (wp *WebSocketProxy) createWebSocketHandler(rules config.RoutingRules, upgr
 			logger.Error("Invalid WebSocket target URL detected",
 				zap.String("target", target),
 				zap.Error(err))
-			clientConn.WriteMessage(websocket.TextMessage, []byte("Invalid target address"))
+			WriteError(c, http.StatusBadGateway, ErrCodeBadGateway, "Invalid target address")
 			return
 		}
 
@@ -155,38 +143,68 @@ func (wp *WebSocketProxy) createWebSocketHandler(rules config.RoutingRules, upgr
 		logger.Debug("Determined final WebSocket forwarding target",
 			zap.String("fullTarget", fullTarget))
 
-		// 从连接池获取或创建后端 WebSocket 连接
-		backendConn, err := wp.pool.GetConn(fullTarget)
-		if err != nil {
-			health.GetGlobalHealthChecker().UpdateRequestCount(target, false)
-			connectSpan.RecordError(err)
-			connectSpan.SetStatus(codes.Error, "Failed to connect to backend")
-			logger.Error("Failed to establish backend WebSocket connection",
-				zap.String("fullTarget", fullTarget),
-				zap.Error(err))
-			clientConn.WriteMessage(websocket.TextMessage, []byte("Backend connection failed"))
-			return
-		}
+		GuardTarget(c, target, func() {
+			wp.relay(ctx, c, connectSpan, upgrader, target, fullTarget)
+		})
+	}
+}
 
-		// 双向转发客户端与后端之间的消息
-		errCh := make(chan error, 2)
-		go wp.forwardMessages(ctx, clientConn, backendConn, "client-to-backend", errCh)
-		go wp.forwardMessages(ctx, backendConn, clientConn, "backend-to-client", errCh)
+// relay 建立后端连接并升级客户端连接，随后双向转发消息直到任一方断开
+func (wp *WebSocketProxy) relay(ctx context.Context, c *gin.Context, connectSpan trace.Span, upgrader websocket.Upgrader, target, fullTarget string) {
+	// 从连接池获取或创建后端 WebSocket 连接
+	backendConn, err := wp.pool.GetConn(fullTarget)
+	if err != nil {
+		SetUpstreamStatus(c, 0)
+		health.GetGlobalHealthChecker().UpdateRequestCount(target, false)
+		connectSpan.RecordError(err)
+		connectSpan.SetStatus(codes.Error, "Failed to connect to backend")
+		logger.Error("Failed to establish backend WebSocket connection",
+			zap.String("fullTarget", fullTarget),
+			zap.Error(err))
+		WriteError(c, http.StatusBadGateway, ErrCodeBadGateway, "Backend connection failed")
+		return
+	}
+	// 后端握手成功即视为上游已响应，连接的持续时间不计入熔断超时
+	SetUpstreamStatus(c, http.StatusSwitchingProtocols)
 
-		if err := <-errCh; err != nil {
-			health.GetGlobalHealthChecker().UpdateRequestCount(target, false)
-			connectSpan.RecordError(err)
-			connectSpan.SetStatus(codes.Error, "Message forwarding failed")
-			logger.Error("WebSocket message forwarding failed",
-				zap.String("path", c.Request.URL.Path),
-				zap.String("fullTarget", fullTarget),
-				zap.Error(err))
-		}
+	// 将客户端连接升级为 WebSocket，升级失败时 Upgrader 已写出错误响应
+	clientConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		connectSpan.RecordError(err)
+		connectSpan.SetStatus(codes.Error, "Failed to upgrade connection")
+		logger.Error("Failed to upgrade client connection to WebSocket",
+			zap.String("path", c.Request.URL.Path),
+			zap.Error(err))
+		return
+	}
+	defer clientConn.Close()
+	wp.relays.Add(1)
+	defer wp.relays.Done()
+
+	// 跟踪活跃的 WebSocket 连接数
+	observability.ActiveWebSocketConnections.Inc()
+	defer observability.ActiveWebSocketConnections.Dec()
+
+	// 双向转发客户端与后端之间的消息
+	errCh := make(chan error, 2)
+	wp.relays.Add(2)
+	go wp.forwardMessages(ctx, clientConn, backendConn, "client-to-backend", errCh)
+	go wp.forwardMessages(ctx, backendConn, clientConn, "backend-to-client", errCh)
+
+	if err := <-errCh; err != nil {
+		health.GetGlobalHealthChecker().UpdateRequestCount(target, false)
+		connectSpan.RecordError(err)
+		connectSpan.SetStatus(codes.Error, "Message forwarding failed")
+		logger.Error("WebSocket message forwarding failed",
+			zap.String("path", c.Request.URL.Path),
+			zap.String("fullTarget", fullTarget),
+			zap.Error(err))
 	}
 }
 
 // forwardMessages 在两个 WebSocket 连接之间转发消息
 func (wp *WebSocketProxy) forwardMessages(ctx context.Context, from, to *websocket.Conn, direction string, errCh chan<- error) {
+	defer wp.relays.Done()
 	for {
 		_, span := websocketTracer.Start(ctx, "WebSocket.Message",
 			trace.WithAttributes(attribute.String("direction", direction)))
@@ -212,8 +230,9 @@ func (wp *WebSocketProxy) forwardMessages(ctx context.Context, from, to *websock
 	}
 }
 
-// Close 关闭 WebSocket 代理并释放资源
+// Close 关闭 WebSocket 代理并释放资源，关闭后端连接后等待进行中的转发协程退出
 func (wp *WebSocketProxy) Close() {
 	wp.pool.Close()
+	wp.relays.Wait()
 	logger.Info("WebSocket proxy closed successfully")
 }
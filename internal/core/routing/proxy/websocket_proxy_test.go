@@ -77,8 +77,10 @@ func TestWebSocketProxy_Forward(t *testing.T) {
 	// 可选：设置负载均衡器类型（此处使用默认轮询）
 	cfg.Routing.LoadBalancer = "round_robin"
 
-	// 创建 WebSocketProxy 实例
+	// 创建 WebSocketProxy 实例，测试结束时关闭并释放连接池中的所有连接，
+	// Close 等待转发协程退出，避免其日志与后续测试重新初始化日志器竞争
 	wp := NewWebSocketProxy(cfg)
+	t.Cleanup(wp.Close)
 
 	// 创建 gin 路由，并配置 WebSocket 代理路由
 	router := gin.New()
@@ -119,10 +121,55 @@ func TestWebSocketProxy_Forward(t *testing.T) {
 	if string(recvMsg) != testMsg {
 		t.Errorf("预期回显消息 %q，实际得到 %q", testMsg, string(recvMsg))
 	}
+}
+
+// TestWebSocketProxy_GuardsBackendDial 测试建立后端连接经由 GuardTarget，后端不可用时在升级前返回 HTTP 错误
+func TestWebSocketProxy_GuardsBackendDial(t *testing.T) {
+	config.InitTestConfigManager()
+	logger.InitTestLogger()
+	health.InitHealthChecker(config.GetConfig())
 
-	// 关闭 WebSocketProxy，释放连接池中的所有连接
-	wp.Close()
+	// 获取一个随即关闭的端口作为不可用的后端
+	closed := httptest.NewServer(http.NotFoundHandler())
+	backendURL := "ws" + strings.TrimPrefix(closed.URL, "http")
+	closed.Close()
 
-	// 模拟等待一小段时间，确保后端连接关闭
-	time.Sleep(100 * time.Millisecond)
+	cfg := config.GetConfig()
+	cfg.WebSocket.Prefix = "/ws"
+	cfg.Routing.Rules = map[string]config.RoutingRules{
+		"/ws/down": {{Target: backendURL, Weight: 1, Protocol: "websocket"}},
+	}
+	cfg.Routing.LoadBalancer = "round_robin"
+	wp := NewWebSocketProxy(cfg)
+	defer wp.Close()
+
+	var guarded string
+	var upstreamStatus int
+	var forwarded bool
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		SetTargetGuard(c, func(target string, forward func()) {
+			guarded = target
+			forward()
+			upstreamStatus, forwarded = UpstreamStatus(c)
+		})
+	})
+	wp.SetupWebSocketProxy(router, cfg)
+	proxyTS := httptest.NewServer(router)
+	defer proxyTS.Close()
+
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	_, resp, err := dialer.Dial("ws"+strings.TrimPrefix(proxyTS.URL, "http")+"/ws/down", nil)
+	if err == nil {
+		t.Fatal("后端不可用时握手应失败")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("预期 HTTP 502 响应，实际得到 %v", resp)
+	}
+	if guarded != backendURL {
+		t.Errorf("预期经由 GuardTarget 转发到 %q，实际得到 %q", backendURL, guarded)
+	}
+	if !forwarded || upstreamStatus != 0 {
+		t.Errorf("预期记录未收到上游响应，实际 status=%d forwarded=%v", upstreamStatus, forwarded)
+	}
 }
@@ -5,9 +5,16 @@ import (
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
 	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// ginTracer 为 Gin 路由模块初始化追踪器
+var ginTracer = otel.Tracer("router:gin")
+
 // GinRouter 管理 Gin 框架的 HTTP 路由设置
 type GinRouter struct {
 }
@@ -19,6 +26,8 @@ func NewGinRouter() *GinRouter {
 }
 
 // Setup 在提供的 Gin 路由器中配置 HTTP 路由规则
+// 路由之间的匹配顺序由 Gin 的路由树决定（静态段优先于参数段），不受规则的 priority 影响；
+// 同理路由按路径选定后才检查 Host，不处理该 Host 时返回 404，不会回退到其他路由
 func (gr *GinRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *config.Config) {
 	rules := cfg.Routing.GetHTTPRules()
 	if len(rules) == 0 {
@@ -32,6 +41,27 @@ func (gr *GinRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *confi
 			zap.String("path", path),
 			zap.Any("targets", targetRules))
 
-		r.Any(path, httpProxy.CreateHTTPHandler(targetRules))
+		handler := tracedGinHandler(path, targetRules, httpProxy)
+		for _, routePath := range RoutePaths(path, rules, cfg) {
+			r.Any(routePath, handler)
+		}
+	}
+}
+
+// tracedGinHandler 为 Gin 匹配到的路由记录追踪信息后转发请求
+func tracedGinHandler(path string, targetRules config.RoutingRules, httpProxy *proxy.HTTPProxy) gin.HandlerFunc {
+	handler := httpProxy.CreateHTTPHandler(targetRules)
+	return func(c *gin.Context) {
+		ctx, span := ginTracer.Start(c.Request.Context(), "Routing.Match",
+			trace.WithAttributes(attribute.String("type", "Gin")),
+			trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
+		defer span.End()
+
+		span.SetAttributes(routeMatchAttributes(c, httpProxy, engineGin, path, targetRules)...)
+		span.SetStatus(codes.Ok, "Route matched successfully")
+
+		proxy.SetMatchedRoute(c, path)
+		c.Request = c.Request.WithContext(ctx)
+		handler(c)
 	}
 }
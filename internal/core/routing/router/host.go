@@ -0,0 +1,19 @@
+package router
+
+import "github.com/penwyp/mini-gateway/config"
+
+// routeFilter 判断路由是否参与匹配，为空时所有路由都参与匹配
+type routeFilter func(rules config.RoutingRules) bool
+
+// accepts 检查路由是否参与匹配
+func (f routeFilter) accepts(rules config.RoutingRules) bool {
+	return f == nil || f(rules)
+}
+
+// servesHost 返回只接受处理该 Host 请求的路由的过滤函数
+// 路径匹配前先按 Host 过滤，使限定了其他 Host 的路由不会遮蔽同样能匹配该路径的路由
+func servesHost(host string) routeFilter {
+	return func(rules config.RoutingRules) bool {
+		return len(rules.ForHost(host)) > 0
+	}
+}
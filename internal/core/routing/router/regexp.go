@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"regexp"
+	"sort"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
@@ -22,9 +24,12 @@ var regexpTracer = otel.Tracer("router:regexp")
 
 // RegexpRouter 使用正则表达式和负载均衡处理路由逻辑
 type RegexpRouter struct {
-	rules map[string]*regexp.Regexp // 路径到正则表达式的映射
+	rules []RegexRule               // 预编译的路由规则，按具体程度从高到低排序
 	cfg   *config.Config            // 存储配置以访问路由规则
 	lb    loadbalancer.LoadBalancer // 负载均衡器实例
+
+	mu     sync.RWMutex                   // 保护 rules，配置热更新时替换
+	loaded map[string]config.RoutingRules // 上次加载的路由规则，用于计算热更新的差异
 }
 
 // NewRegexpRouter 根据配置创建并初始化 RegexpRouter 实例
@@ -37,51 +42,118 @@ func NewRegexpRouter(cfg *config.Config) *RegexpRouter {
 		lb = loadbalancer.NewRoundRobin() // 初始化失败时回退到轮询
 	}
 	router := &RegexpRouter{
-		rules: make(map[string]*regexp.Regexp),
-		cfg:   cfg,
-		lb:    lb,
+		cfg: cfg,
+		lb:  lb,
 	}
 	// 初始化时注册路由规则
-	rules := cfg.Routing.GetHTTPRules()
-	for path := range rules {
-		router.registerRule(path)
-	}
+	router.load(cfg.Routing.GetHTTPRules())
 	return router
 }
 
-// registerRule 编译并注册单个路由规则
-func (rr *RegexpRouter) registerRule(path string) {
+// load 按与上次加载的差异更新路由规则：只编译新增的路由，规则变化的路由复用编译结果，未变化的路由保持不变
+func (rr *RegexpRouter) load(routes map[string]config.RoutingRules) {
+	diff := config.DiffRoutingRules(rr.loaded, routes)
+	if diff.Empty() {
+		return
+	}
+
+	rr.mu.RLock()
+	rules := make([]RegexRule, 0, len(routes))
+	for _, rule := range rr.rules {
+		if targetRules, ok := routes[rule.Pattern]; ok {
+			rule.Rules = targetRules
+			rules = append(rules, rule)
+		}
+	}
+	rr.mu.RUnlock()
+	for _, path := range diff.Added {
+		if rule, ok := compileRule(path, routes[path]); ok {
+			rules = append(rules, rule)
+		}
+	}
+	sortRegexRules(rules)
+
+	rr.mu.Lock()
+	rr.rules, rr.loaded = rules, routes
+	rr.mu.Unlock()
+	logger.Info("RegexpRouter routes updated",
+		zap.Int("added", len(diff.Added)),
+		zap.Int("removed", len(diff.Removed)),
+		zap.Int("changed", len(diff.Changed)))
+}
+
+// compileRule 编译单个路由规则
+func compileRule(path string, targetRules config.RoutingRules) (RegexRule, bool) {
 	pattern := "^" + path + "$" // 为精确匹配添加锚点
 	re, err := regexp.Compile(pattern)
 	if err != nil {
 		logger.Error("Failed to compile regular expression for route",
 			zap.String("path", path),
 			zap.Error(err))
-		return
+		return RegexRule{}, false
 	}
-	rr.rules[path] = re
 	logger.Info("Successfully registered route in RegexpRouter",
 		zap.String("path", path),
-		zap.Any("targets", rr.cfg.Routing.Rules[path]))
+		zap.Any("targets", targetRules))
+	return RegexRule{Regex: re, Pattern: path, Rules: targetRules}, true
 }
 
 // Match 查找与给定路径匹配的路由规则
 func (rr *RegexpRouter) Match(ctx context.Context, path string) (config.RoutingRules, bool) {
-	ctx, span := trieRegexpTracer.Start(ctx, "RegexpRouter.Match",
+	_, rules, found := rr.MatchRule(ctx, path)
+	return rules, found
+}
+
+// MatchRule 查找与给定路径匹配的路由规则，同时返回匹配的规则模板
+// 多个规则都能匹配时选择最具体的规则，见 sortRegexRules
+func (rr *RegexpRouter) MatchRule(ctx context.Context, path string) (string, config.RoutingRules, bool) {
+	return rr.match(ctx, path, nil)
+}
+
+// MatchHost 与 MatchRule 相同，但只匹配处理该 Host 请求的规则，不处理的规则视为不存在
+func (rr *RegexpRouter) MatchHost(ctx context.Context, path, host string) (string, config.RoutingRules, bool) {
+	return rr.match(ctx, path, servesHost(host))
+}
+
+// match 查找与给定路径匹配的路由规则，accept 不为空时只匹配其接受的规则
+func (rr *RegexpRouter) match(ctx context.Context, path string, accept routeFilter) (string, config.RoutingRules, bool) {
+	_, span := regexpTracer.Start(ctx, "RegexpRouter.Match",
 		trace.WithAttributes(attribute.String("path", path)))
 	defer span.End()
 
-	for pattern, re := range rr.rules {
-		if re.MatchString(path) {
-			return rr.cfg.Routing.Rules[pattern], true
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	for _, rule := range rr.rules {
+		if accept.accepts(rule.Rules) && rule.Regex.MatchString(path) {
+			return rule.Pattern, rule.Rules, true
 		}
 	}
-	return nil, false
+	return "", nil, false
+}
+
+// sortRegexRules 按优先级和具体程度从高到低排序正则规则，使匹配结果不受配置的 map 遍历顺序影响
+// 配置了 priority 的规则优先；优先级相同时字面前缀更长的规则更具体（如 /api/v1/.* 优先于 /api/.*），其次是更长的规则，最后按字典序
+func sortRegexRules(rules []RegexRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if pi, pj := rules[i].Rules.Priority(), rules[j].Rules.Priority(); pi != pj {
+			return pi > pj
+		}
+		pi, _ := rules[i].Regex.LiteralPrefix()
+		pj, _ := rules[j].Regex.LiteralPrefix()
+		if len(pi) != len(pj) {
+			return len(pi) > len(pj)
+		}
+		if len(rules[i].Pattern) != len(rules[j].Pattern) {
+			return len(rules[i].Pattern) > len(rules[j].Pattern)
+		}
+		return rules[i].Pattern < rules[j].Pattern
+	})
 }
 
 // Setup 根据配置在 Gin 路由器中设置 HTTP 路由规则
 func (rr *RegexpRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *config.Config) {
 	rules := cfg.Routing.GetHTTPRules()
+	rr.load(rules)
 	if len(rules) == 0 {
 		logger.Warn("No HTTP routing rules found in configuration")
 		return
@@ -95,24 +167,33 @@ func (rr *RegexpRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *co
 		defer span.End()
 
 		path := c.Request.URL.Path
-		targetRules, found := rr.Match(ctx, path)
+		var (
+			pattern     string
+			targetRules config.RoutingRules
+		)
+		found := matchPath(cfg, path, func(p string) bool {
+			var ok bool
+			pattern, targetRules, ok = rr.MatchHost(ctx, p, c.Request.Host)
+			return ok
+		})
 
 		if !found {
 			logger.Warn("No matching route found",
 				zap.String("path", path),
 				zap.String("method", c.Request.Method))
-			c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			proxy.WriteError(c, http.StatusNotFound, proxy.ErrCodeRouteNotFound, "Route not found")
 			c.Abort()
 			span.SetStatus(codes.Error, "Route not found")
 			return
 		}
 
-		span.SetAttributes(attribute.String("matched_target", targetRules[0].Target))
+		span.SetAttributes(routeMatchAttributes(c, httpProxy, engineRegexp, pattern, targetRules)...)
 		span.SetStatus(codes.Ok, "Route matched successfully")
 		logger.Info("Successfully matched route",
 			zap.String("path", path),
 			zap.Any("rules", targetRules))
 
+		proxy.SetMatchedRoute(c, pattern)
 		c.Request = c.Request.WithContext(ctx)
 		httpProxy.CreateHTTPHandler(targetRules)(c)
 	})
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"context"
+	"regexp"
 	"testing"
 
 	"github.com/penwyp/mini-gateway/config"
@@ -55,9 +57,124 @@ func TestMatch(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rules, found := router.Match(tt.path)
+			rules, found := router.Match(context.Background(), tt.path)
 			assert.Equal(t, tt.wantFound, found, "Expected found to be %v for path %v", tt.wantFound, tt.path)
 			assert.Equal(t, tt.wantRules, rules, "Expected rules to match for path %v", tt.path)
 		})
 	}
 }
+
+// TestMatch_MostSpecificWins 测试多个正则规则重叠时总是选择最具体的规则
+func TestMatch_MostSpecificWins(t *testing.T) {
+	cfg := &config.Config{
+		Routing: config.Routing{
+			LoadBalancer: "round_robin",
+			Rules: map[string]config.RoutingRules{
+				"/api/.*":        {{Target: "http://localhost:8080"}},
+				"/api/v1/.*":     {{Target: "http://localhost:8081"}},
+				"/api/v1/user.*": {{Target: "http://localhost:8082"}},
+			},
+		},
+	}
+
+	// map 遍历顺序随机，多次创建路由器以确认结果稳定
+	for i := 0; i < 20; i++ {
+		router := NewRegexpRouter(cfg)
+
+		pattern, _, found := router.MatchRule(context.Background(), "/api/v1/users")
+		assert.True(t, found)
+		assert.Equal(t, "/api/v1/user.*", pattern)
+
+		pattern, _, found = router.MatchRule(context.Background(), "/api/v1/orders")
+		assert.True(t, found)
+		assert.Equal(t, "/api/v1/.*", pattern)
+
+		pattern, _, found = router.MatchRule(context.Background(), "/api/v2/orders")
+		assert.True(t, found)
+		assert.Equal(t, "/api/.*", pattern)
+	}
+}
+
+// TestMatch_PriorityOverridesSpecificity 测试配置了更高优先级的规则优先于更具体的规则
+func TestMatch_PriorityOverridesSpecificity(t *testing.T) {
+	cfg := &config.Config{
+		Routing: config.Routing{
+			LoadBalancer: "round_robin",
+			Rules: map[string]config.RoutingRules{
+				"/api/.*":    {{Target: "http://localhost:8080", Priority: 10}},
+				"/api/v1/.*": {{Target: "http://localhost:8081"}},
+				"/health.*":  {{Target: "http://localhost:8082"}},
+			},
+		},
+	}
+
+	for i := 0; i < 20; i++ {
+		router := NewRegexpRouter(cfg)
+
+		pattern, _, found := router.MatchRule(context.Background(), "/api/v1/users")
+		assert.True(t, found)
+		assert.Equal(t, "/api/.*", pattern)
+
+		pattern, _, found = router.MatchRule(context.Background(), "/healthz")
+		assert.True(t, found)
+		assert.Equal(t, "/health.*", pattern)
+	}
+}
+
+// TestMatchHost 测试限定了其他 Host 的规则不参与匹配，请求落到下一个能匹配的规则
+func TestMatchHost(t *testing.T) {
+	cfg := &config.Config{
+		Routing: config.Routing{
+			LoadBalancer: "round_robin",
+			Rules: map[string]config.RoutingRules{
+				"/api/v1/.*": {{Target: "http://localhost:8080", Host: "api.example.com"}},
+				"/api/.*":    {{Target: "http://localhost:8081"}},
+			},
+		},
+	}
+	router := NewRegexpRouter(cfg)
+
+	pattern, _, found := router.MatchHost(context.Background(), "/api/v1/users", "API.example.com")
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/.*", pattern)
+
+	pattern, _, found = router.MatchHost(context.Background(), "/api/v1/users", "web.example.com")
+	assert.True(t, found)
+	assert.Equal(t, "/api/.*", pattern)
+}
+
+// TestRegexpRouter_LoadOnlyChangedRoutes 测试热更新时只编译新增的规则，未变化的规则复用编译结果
+func TestRegexpRouter_LoadOnlyChangedRoutes(t *testing.T) {
+	cfg := &config.Config{
+		Routing: config.Routing{
+			LoadBalancer: "round_robin",
+			Rules: map[string]config.RoutingRules{
+				"/api/.*":   {{Target: "http://localhost:8080"}},
+				"/health.*": {{Target: "http://localhost:8082"}},
+			},
+		},
+	}
+	router := NewRegexpRouter(cfg)
+	compiled := make(map[string]*regexp.Regexp)
+	for _, rule := range router.rules {
+		compiled[rule.Pattern] = rule.Regex
+	}
+
+	added := config.RoutingRules{{Target: "http://localhost:8083"}}
+	router.load(map[string]config.RoutingRules{
+		"/api/.*":    {{Target: "http://localhost:8080"}},
+		"/orders/.*": added,
+	})
+
+	assert.Len(t, router.rules, 2)
+	for _, rule := range router.rules {
+		if rule.Pattern == "/api/.*" {
+			assert.Same(t, compiled[rule.Pattern], rule.Regex, "unchanged rule should not be recompiled")
+		}
+	}
+	_, rules, found := router.MatchRule(context.Background(), "/orders/1")
+	assert.True(t, found)
+	assert.Equal(t, added, rules)
+	_, _, found = router.MatchRule(context.Background(), "/healthz")
+	assert.False(t, found)
+}
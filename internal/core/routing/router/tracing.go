@@ -0,0 +1,38 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 路由匹配 span 的属性键
+const (
+	attrRouteEngine   = "route.engine"   // 匹配所用的路由引擎
+	attrRouteRule     = "route.rule"     // 匹配到的路由规则模板
+	attrRouteBalancer = "route.balancer" // 选择目标所用的负载均衡器
+	attrRouteEnv      = "route.env"      // 请求的目标环境
+)
+
+// 路由引擎名称，与配置项 routing.engine 一致
+const (
+	engineGin        = "gin"
+	engineTrie       = "trie"
+	engineTrieRegexp = "trie-regexp"
+	engineRegexp     = "regexp"
+)
+
+// routeMatchAttributes 返回路由匹配成功时记录到 span 的属性
+func routeMatchAttributes(c *gin.Context, httpProxy *proxy.HTTPProxy, engine, rule string, targetRules config.RoutingRules) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String(attrRouteEngine, engine),
+		attribute.String(attrRouteRule, rule),
+		attribute.String(attrRouteBalancer, httpProxy.GetLoadBalancerType()),
+		attribute.String(attrRouteEnv, proxy.RequestEnv(c)),
+	}
+	if len(targetRules) > 0 {
+		attrs = append(attrs, attribute.String("matched_target", targetRules[0].Target))
+	}
+	return attrs
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spanRecorderOnce sync.Once
+	spanRecorder     *tracetest.SpanRecorder
+)
+
+// testSpanRecorder 安装全局 TracerProvider，包级追踪器只能委托一次，因此所有测试共享同一个记录器
+func testSpanRecorder() *tracetest.SpanRecorder {
+	spanRecorderOnce.Do(func() {
+		spanRecorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	})
+	return spanRecorder
+}
+
+// routingSpanAttributes 返回指定追踪器最近一次结束的 Routing.Match span 的属性
+func routingSpanAttributes(t *testing.T, recorder *tracetest.SpanRecorder, tracerName string) map[attribute.Key]attribute.Value {
+	spans := recorder.Ended()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == "Routing.Match" && spans[i].InstrumentationScope().Name == tracerName {
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range spans[i].Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			return attrs
+		}
+	}
+	t.Fatalf("no Routing.Match span recorded by %s", tracerName)
+	return nil
+}
+
+func TestRoutingSpanAttributes(t *testing.T) {
+	recorder := testSpanRecorder()
+	gin.SetMode(gin.TestMode)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name       string
+		engine     string
+		tracerName string
+		rulePath   string
+		reqPath    string
+		newRouter  func(cfg *config.Config) Router
+	}{
+		{"Gin", engineGin, "router:gin", "/api/v1/users/:id", "/api/v1/users/42",
+			func(cfg *config.Config) Router { return NewGinRouter() }},
+		{"Trie", engineTrie, "router:trie", "/api/v1/users", "/api/v1/users",
+			func(cfg *config.Config) Router { return NewTrieRouter() }},
+		{"TrieRegexp", engineTrieRegexp, "router:trie-regexp", "/api/v2/.*", "/api/v2/orders",
+			func(cfg *config.Config) Router { return NewTrieRegexpRouter() }},
+		{"Regexp", engineRegexp, "router:regexp", "/api/v3/[0-9]+", "/api/v3/7",
+			func(cfg *config.Config) Router { return NewRegexpRouter(cfg) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Routing: config.Routing{
+					Engine:       tt.engine,
+					LoadBalancer: "round_robin",
+					Rules: map[string]config.RoutingRules{
+						tt.rulePath: {{Target: backend.URL, Weight: 1, Env: "canary"}},
+					},
+				},
+			}
+			config.InitTestConfigManager()
+			config.SetConfig(cfg)
+
+			r := gin.New()
+			tt.newRouter(cfg).Setup(r, proxy.NewHTTPProxy(cfg), cfg)
+
+			req := httptest.NewRequest(http.MethodGet, tt.reqPath, nil)
+			req.Header.Set("X-Env", "canary")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			attrs := routingSpanAttributes(t, recorder, tt.tracerName)
+			assert.Equal(t, tt.engine, attrs[attrRouteEngine].AsString())
+			assert.Equal(t, tt.rulePath, attrs[attrRouteRule].AsString())
+			assert.Equal(t, "round-robin", attrs[attrRouteBalancer].AsString())
+			assert.Equal(t, "canary", attrs[attrRouteEnv].AsString())
+			assert.Equal(t, backend.URL, attrs["matched_target"].AsString())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+)
+
+// 尾部斜杠策略，见 config.Routing.TrailingSlash
+const (
+	TrailingSlashStrict   = "strict"   // 严格匹配，/api/user/ 与 /api/user 是不同的路径
+	TrailingSlashRedirect = "redirect" // 重定向到已配置的路径（GET 为 301，其余方法为 307），未配置时的默认策略
+	TrailingSlashIgnore   = "ignore"   // 忽略尾部斜杠，两种写法都匹配同一路由
+)
+
+// trailingSlashPolicy 返回配置的尾部斜杠策略，未配置时为 redirect
+func trailingSlashPolicy(cfg *config.Config) string {
+	if cfg.Routing.TrailingSlash == "" {
+		return TrailingSlashRedirect
+	}
+	return cfg.Routing.TrailingSlash
+}
+
+// ConfigureTrailingSlash 按尾部斜杠策略设置 Gin 引擎
+// 各路由引擎都先由 Gin 匹配已注册的路径，因此重定向统一由 Gin 的 RedirectTrailingSlash 完成
+func ConfigureTrailingSlash(engine *gin.Engine, cfg *config.Config) {
+	engine.RedirectTrailingSlash = trailingSlashPolicy(cfg) == TrailingSlashRedirect
+}
+
+// RoutePaths 返回路由规则需要在 Gin 中注册的路径
+// ignore 策略下额外注册切换尾部斜杠后的路径；已单独配置该路径、根路径以及通配段或正则路径除外
+func RoutePaths(path string, rules map[string]config.RoutingRules, cfg *config.Config) []string {
+	paths := []string{path}
+	if trailingSlashPolicy(cfg) != TrailingSlashIgnore || path == "/" || config.IsRegexPattern(path) || strings.Contains(path, "*") {
+		return paths
+	}
+	alt := toggleTrailingSlash(path)
+	if _, ok := rules[alt]; !ok {
+		paths = append(paths, alt)
+	}
+	return paths
+}
+
+// matchPath 按尾部斜杠策略匹配请求路径，ignore 策略下原路径未命中时再尝试切换尾部斜杠后的路径
+func matchPath(cfg *config.Config, path string, match func(path string) bool) bool {
+	if match(path) {
+		return true
+	}
+	if trailingSlashPolicy(cfg) != TrailingSlashIgnore || path == "/" {
+		return false
+	}
+	return match(toggleTrailingSlash(path))
+}
+
+// toggleTrailingSlash 去掉路径的尾部斜杠，没有尾部斜杠时添加
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path + "/"
+}
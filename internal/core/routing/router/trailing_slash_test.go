@@ -0,0 +1,33 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRoutePaths 测试 ignore 策略下额外注册的路径及其例外情况
+func TestRoutePaths(t *testing.T) {
+	rules := map[string]config.RoutingRules{
+		"/api/v1/user":      {{Target: "http://localhost:8081"}},
+		"/api/v1/order":     {{Target: "http://localhost:8082"}},
+		"/api/v1/order/":    {{Target: "http://localhost:8083"}},
+		"/static/*path":     {{Target: "http://localhost:8084"}},
+		"/api/v2/.*":        {{Target: "http://localhost:8085"}},
+		"/api/v1/users/:id": {{Target: "http://localhost:8086"}},
+	}
+	ignore := &config.Config{Routing: config.Routing{TrailingSlash: TrailingSlashIgnore}}
+
+	assert.Equal(t, []string{"/api/v1/user", "/api/v1/user/"}, RoutePaths("/api/v1/user", rules, ignore))
+	assert.Equal(t, []string{"/api/v1/users/:id", "/api/v1/users/:id/"}, RoutePaths("/api/v1/users/:id", rules, ignore))
+	assert.Equal(t, []string{"/api/v1/order"}, RoutePaths("/api/v1/order", rules, ignore), "an explicitly configured path takes precedence")
+	assert.Equal(t, []string{"/static/*path"}, RoutePaths("/static/*path", rules, ignore))
+	assert.Equal(t, []string{"/api/v2/.*"}, RoutePaths("/api/v2/.*", rules, ignore))
+	assert.Equal(t, []string{"/"}, RoutePaths("/", rules, ignore))
+
+	for _, policy := range []string{"", TrailingSlashStrict, TrailingSlashRedirect} {
+		cfg := &config.Config{Routing: config.Routing{TrailingSlash: policy}}
+		assert.Equal(t, []string{"/api/v1/user"}, RoutePaths("/api/v1/user", rules, cfg), policy)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
 
@@ -23,6 +24,9 @@ var trieTracer = otel.Tracer("router:trie")
 // TrieRouter 使用 Trie 数据结构管理 HTTP 路由
 type TrieRouter struct {
 	Trie *Trie // Trie 数据结构实例
+
+	mu    sync.RWMutex                   // 保护 Trie，配置热更新时就地修改
+	rules map[string]config.RoutingRules // 上次加载的路由规则，用于计算热更新的差异
 }
 
 // Trie 表示用于高效前缀匹配路由的 Trie 数据结构
@@ -32,9 +36,13 @@ type Trie struct {
 
 // TrieNode 表示 Trie 中的一个节点，包含子节点和路由规则
 type TrieNode struct {
-	Children map[rune]*TrieNode  // 子节点映射
-	Rules    config.RoutingRules // 路由规则
-	IsEnd    bool                // 标记此节点是否为有效路由的终点
+	Children  map[rune]*TrieNode  // 子节点映射
+	Param     *TrieNode           // :name 参数段子节点，匹配一个路径段
+	Wildcard  *TrieNode           // *name 通配段子节点，匹配剩余的全部路径
+	ParamName string              // 参数段或通配段的参数名
+	Rules     config.RoutingRules // 路由规则
+	Pattern   string              // 插入时的路由规则模板
+	IsEnd     bool                // 标记此节点是否为有效路由的终点
 }
 
 // NewTrieRouter 创建并初始化 TrieRouter 实例
@@ -48,54 +56,191 @@ func NewTrieRouter() *TrieRouter {
 
 // Insert 将路径及其关联的路由规则插入 Trie
 func (t *Trie) Insert(path string, rules config.RoutingRules) {
-	node := t.Root
-	path = strings.TrimPrefix(path, "/") // 规范化路径，去除前导斜杠
-	for _, ch := range path {
+	t.Root.insert(path, rules)
+	logger.Info("Successfully inserted route into Trie",
+		zap.String("path", path),
+		zap.Any("rules", rules))
+}
+
+// Remove 从 Trie 中删除路由，并清理不再被其他路由使用的节点，路由不存在时返回 false
+func (t *Trie) Remove(path string) bool {
+	if !t.Root.remove(path) {
+		return false
+	}
+	logger.Info("Successfully removed route from Trie", zap.String("path", path))
+	return true
+}
+
+// insert 将路由插入以当前节点为根的 Trie，Trie 与 TrieRegexp 的静态路由共用
+func (n *TrieNode) insert(pattern string, rules config.RoutingRules) {
+	node := n
+	runes := []rune(strings.TrimPrefix(pattern, "/")) // 规范化路径，去除前导斜杠
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if kind, name, end := parseDynamicSegment(runes, i); kind != 0 {
+			node = node.dynamicChild(kind, name, pattern)
+			i = end - 1
+			continue
+		}
 		if node.Children[ch] == nil {
 			node.Children[ch] = &TrieNode{Children: make(map[rune]*TrieNode)}
 		}
 		node = node.Children[ch]
 	}
 	node.Rules = rules
+	node.Pattern = pattern
 	node.IsEnd = true
-	logger.Info("Successfully inserted route into Trie",
-		zap.String("path", "/"+path),
-		zap.Any("rules", rules))
+}
+
+// remove 从以当前节点为根的 Trie 中删除路由，并清理不再被其他路由使用的节点，路由不存在时返回 false
+func (n *TrieNode) remove(pattern string) bool {
+	runes := []rune(strings.TrimPrefix(pattern, "/"))
+	nodes := []*TrieNode{n}
+	node := n
+	for i := 0; i < len(runes); i++ {
+		if kind, _, end := parseDynamicSegment(runes, i); kind == paramSegment {
+			node, i = node.Param, end-1
+		} else if kind == wildcardSegment {
+			node, i = node.Wildcard, end-1
+		} else {
+			node = node.Children[runes[i]]
+		}
+		if node == nil {
+			return false
+		}
+		nodes = append(nodes, node)
+	}
+	// 参数名不同的路由落在同一节点上，只删除模板一致的路由
+	if !node.IsEnd || node.Pattern != pattern {
+		return false
+	}
+	node.Rules, node.Pattern, node.IsEnd = nil, "", false
+
+	for i := len(nodes) - 1; i > 0 && nodes[i].empty(); i-- {
+		nodes[i-1].removeChild(nodes[i])
+	}
+	return true
+}
+
+// empty 判断节点是否既不是路由终点也没有子节点
+func (n *TrieNode) empty() bool {
+	return !n.IsEnd && len(n.Children) == 0 && n.Param == nil && n.Wildcard == nil
+}
+
+// removeChild 删除指定的子节点
+func (n *TrieNode) removeChild(child *TrieNode) {
+	switch child {
+	case n.Param:
+		n.Param = nil
+	case n.Wildcard:
+		n.Wildcard = nil
+	default:
+		for ch, c := range n.Children {
+			if c == child {
+				delete(n.Children, ch)
+			}
+		}
+	}
+}
+
+// dynamicChild 返回参数段或通配段子节点，不存在时创建
+// 同一位置只能有一个参数名，后插入的路由沿用先插入的参数名
+func (n *TrieNode) dynamicChild(kind rune, name, pattern string) *TrieNode {
+	child := &n.Param
+	if kind == wildcardSegment {
+		child = &n.Wildcard
+	}
+	if *child == nil {
+		*child = &TrieNode{Children: make(map[rune]*TrieNode), ParamName: name}
+	} else if (*child).ParamName != name {
+		logger.Warn("Conflicting parameter name in Trie route, keeping the existing one",
+			zap.String("pattern", pattern),
+			zap.String("existing", (*child).ParamName),
+			zap.String("name", name))
+	}
+	return *child
 }
 
 // Search 在 Trie 中查找给定路径的路由规则
 func (t *Trie) Search(ctx context.Context, path string) (config.RoutingRules, bool) {
-	ctx, span := trieTracer.Start(ctx, "Trie.Search",
+	_, rules, found := t.SearchRule(ctx, path)
+	return rules, found
+}
+
+// SearchRule 在 Trie 中查找给定路径，同时返回匹配的路由规则模板
+func (t *Trie) SearchRule(ctx context.Context, path string) (string, config.RoutingRules, bool) {
+	pattern, rules, _, found := t.SearchParams(ctx, path)
+	return pattern, rules, found
+}
+
+// SearchParams 在 Trie 中查找给定路径，同时返回匹配的路由规则模板和路径参数
+// 匹配优先级为静态段、参数段、通配段，优先级高的分支匹配失败时回退尝试其他分支
+func (t *Trie) SearchParams(ctx context.Context, path string) (string, config.RoutingRules, gin.Params, bool) {
+	return t.search(ctx, path, nil)
+}
+
+// SearchHost 与 SearchParams 相同，但只匹配处理该 Host 请求的路由，不处理的路由视为不存在，继续尝试其他分支
+func (t *Trie) SearchHost(ctx context.Context, path, host string) (string, config.RoutingRules, gin.Params, bool) {
+	return t.search(ctx, path, servesHost(host))
+}
+
+// search 查找给定路径，accept 不为空时只匹配其接受的路由
+func (t *Trie) search(ctx context.Context, path string, accept routeFilter) (string, config.RoutingRules, gin.Params, bool) {
+	_, span := trieTracer.Start(ctx, "Trie.Search",
 		trace.WithAttributes(attribute.String("path", path)))
 	defer span.End()
 
-	node := t.Root
-	path = strings.TrimPrefix(path, "/") // 规范化路径，去除前导斜杠
-	path = strings.TrimSuffix(path, "/") // 去除尾部斜杠以保持一致性
-	for _, ch := range path {
-		if node.Children[ch] == nil {
-			return nil, false
+	// 仅去除前导斜杠，尾部斜杠按路由配置的策略处理，见 matchPath
+	path = strings.TrimPrefix(path, "/")
+	node, params := t.Root.match([]rune(path), nil, accept)
+	if node == nil {
+		return "", nil, nil, false
+	}
+	return node.Pattern, node.Rules, params, true
+}
+
+// match 从当前节点开始匹配剩余路径，返回路由终点节点和捕获的参数
+// 多个路由都能匹配时选择优先级最高的路由，优先级相同时按静态段、参数段、通配段的顺序选择
+func (n *TrieNode) match(path []rune, params gin.Params, accept routeFilter) (*TrieNode, gin.Params) {
+	if len(path) == 0 {
+		if n.IsEnd && accept.accepts(n.Rules) {
+			return n, params
+		}
+		return nil, nil
+	}
+
+	var best *TrieNode
+	var bestParams gin.Params
+	consider := func(node *TrieNode, p gin.Params) {
+		if node != nil && (best == nil || node.Rules.Priority() > best.Rules.Priority()) {
+			best, bestParams = node, p
 		}
-		node = node.Children[ch]
 	}
-	if node.IsEnd {
-		return node.Rules, true
+	// 追加参数时复制切片，避免各分支共享底层数组
+	params = params[:len(params):len(params)]
+	if child := n.Children[path[0]]; child != nil {
+		consider(child.match(path[1:], params, accept))
+	}
+	if n.Param != nil {
+		if end := segmentEnd(path); end > 0 {
+			param := gin.Param{Key: n.Param.ParamName, Value: string(path[:end])}
+			consider(n.Param.match(path[end:], append(params, param), accept))
+		}
 	}
-	return nil, false
+	if n.Wildcard != nil && n.Wildcard.IsEnd && accept.accepts(n.Wildcard.Rules) {
+		consider(n.Wildcard, append(params, wildcardParam(n.Wildcard.ParamName, path)))
+	}
+	return best, bestParams
 }
 
 // Setup 根据配置在 Gin 路由器中设置 TrieRouter 的 HTTP 路由规则
 func (tr *TrieRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *config.Config) {
 	rules := cfg.Routing.GetHTTPRules()
+	tr.load(rules)
 	if len(rules) == 0 {
 		logger.Warn("No HTTP routing rules found in configuration")
 		return
 	}
-
-	// 将所有路由规则插入 Trie
-	for path, targetRules := range rules {
-		tr.Trie.Insert(path, targetRules)
-	}
 	logger.Info("Trie routing setup completed",
 		zap.Int("ruleCount", len(rules)))
 
@@ -110,26 +255,61 @@ func (tr *TrieRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *conf
 		logger.Debug("Processing request in Trie routing middleware",
 			zap.String("path", c.Request.URL.Path))
 		path := c.Request.URL.Path
-		targetRules, found := tr.Trie.Search(ctx, path)
+		var (
+			pattern     string
+			targetRules config.RoutingRules
+			params      gin.Params
+		)
+		found := matchPath(cfg, path, func(p string) bool {
+			tr.mu.RLock()
+			defer tr.mu.RUnlock()
+			var ok bool
+			pattern, targetRules, params, ok = tr.Trie.SearchHost(ctx, p, c.Request.Host)
+			return ok
+		})
 		if !found {
 			span.SetStatus(codes.Error, "Route not found")
 			logger.Warn("No matching route found",
 				zap.String("path", path),
 				zap.String("method", c.Request.Method))
-			c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			proxy.WriteError(c, http.StatusNotFound, proxy.ErrCodeRouteNotFound, "Route not found")
 			c.Abort()
 			return
 		}
 
 		// 记录和追踪成功匹配的路由
-		span.SetAttributes(attribute.String("matched_target", targetRules[0].Target))
+		span.SetAttributes(routeMatchAttributes(c, httpProxy, engineTrie, pattern, targetRules)...)
 		span.SetStatus(codes.Ok, "Route matched successfully")
 		logger.Info("Successfully matched route in Trie",
 			zap.String("path", path),
 			zap.Any("rules", targetRules))
 
-		// 将追踪上下文传递下游并处理请求
+		// 将路径参数写入 gin 上下文，将追踪上下文传递下游并处理请求
+		c.Params = append(c.Params, params...)
+		proxy.SetMatchedRoute(c, pattern)
 		c.Request = c.Request.WithContext(ctx)
 		httpProxy.CreateHTTPHandler(targetRules)(c)
 	})
 }
+
+// load 按与上次加载的差异更新 Trie：删除已移除的路由，插入新增或变化的路由，未变化的路由保持不变
+func (tr *TrieRouter) load(rules map[string]config.RoutingRules) {
+	diff := config.DiffRoutingRules(tr.rules, rules)
+	if diff.Empty() {
+		return
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for _, path := range diff.Removed {
+		tr.Trie.Remove(path)
+	}
+	for _, path := range append(diff.Added, diff.Changed...) {
+		tr.Trie.Insert(path, rules[path])
+	}
+	tr.rules = rules
+	logger.Info("Trie routes updated",
+		zap.Int("added", len(diff.Added)),
+		zap.Int("removed", len(diff.Removed)),
+		zap.Int("changed", len(diff.Changed)))
+}
@@ -0,0 +1,41 @@
+package router
+
+import "github.com/gin-gonic/gin"
+
+// 动态路径段的类型，与 gin 路由的写法一致
+const (
+	paramSegment    = ':' // :name 匹配一个路径段
+	wildcardSegment = '*' // *name 匹配剩余的全部路径，只能位于路由末尾
+)
+
+// parseDynamicSegment 检查 runes[i] 是否为参数段或通配段的起始位置
+// 返回段类型（非动态段时为 0）、参数名和段结束位置，通配段总是延续到路径末尾
+func parseDynamicSegment(runes []rune, i int) (kind rune, name string, end int) {
+	if i > 0 && runes[i-1] != '/' {
+		return 0, "", i
+	}
+	switch runes[i] {
+	case paramSegment:
+		end = i + segmentEnd(runes[i:])
+	case wildcardSegment:
+		end = len(runes)
+	default:
+		return 0, "", i
+	}
+	return runes[i], string(runes[i+1 : end]), end
+}
+
+// segmentEnd 返回路径中第一个路径段的长度
+func segmentEnd(path []rune) int {
+	for i, ch := range path {
+		if ch == '/' {
+			return i
+		}
+	}
+	return len(path)
+}
+
+// wildcardParam 构造通配段参数，与 gin 一致，参数值以斜杠开头
+func wildcardParam(name string, rest []rune) gin.Param {
+	return gin.Param{Key: name, Value: "/" + string(rest)}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
@@ -21,16 +23,18 @@ var trieRegexpTracer = otel.Tracer("router:trie-regexp")
 
 type TrieRegexpRouter struct {
 	Trie *TrieRegexp
+
+	mu    sync.RWMutex                   // 保护 Trie，配置热更新时就地修改
+	rules map[string]config.RoutingRules // 上次加载的路由规则，用于计算热更新的差异
 }
 
 type TrieRegexp struct {
 	Root *TrieRegexpNode
 }
 
+// TrieRegexpNode TrieRegexp 的根节点，静态路由与参数路由复用 Trie 的节点与匹配逻辑
 type TrieRegexpNode struct {
-	Children   map[rune]*TrieRegexpNode
-	Rules      config.RoutingRules
-	IsEnd      bool
+	TrieNode
 	RegexRules []RegexRule // 存储多个正则规则
 }
 
@@ -41,10 +45,13 @@ type RegexRule struct {
 }
 
 func NewTrieRegexpRouter() *TrieRegexpRouter {
-	return &TrieRegexpRouter{
-		Trie: &TrieRegexp{
-			Root: &TrieRegexpNode{Children: make(map[rune]*TrieRegexpNode)},
-		},
+	return &TrieRegexpRouter{Trie: newTrieRegexp()}
+}
+
+// newTrieRegexp 创建空的 TrieRegexp
+func newTrieRegexp() *TrieRegexp {
+	return &TrieRegexp{
+		Root: &TrieRegexpNode{TrieNode: TrieNode{Children: make(map[rune]*TrieNode)}},
 	}
 }
 
@@ -52,7 +59,18 @@ func (t *TrieRegexp) Insert(path string, rules config.RoutingRules) {
 	node := t.Root
 	originalPath := path
 
-	if strings.ContainsAny(path, ".*+?()|[]^$\\") {
+	if config.IsRegexPattern(path) {
+		// 已存在的正则规则只替换路由规则，复用编译结果
+		for i := range node.RegexRules {
+			if node.RegexRules[i].Pattern == path {
+				node.RegexRules[i].Rules = rules
+				sortRegexRules(node.RegexRules)
+				logger.Info("Successfully updated regex route in Trie",
+					zap.String("pattern", originalPath),
+					zap.Any("rules", rules))
+				return
+			}
+		}
 		re, err := regexp.Compile("^" + path + "$")
 		if err != nil {
 			logger.Error("Failed to compile regular expression pattern",
@@ -65,65 +83,94 @@ func (t *TrieRegexp) Insert(path string, rules config.RoutingRules) {
 			Pattern: path,
 			Rules:   rules,
 		})
+		sortRegexRules(node.RegexRules)
 		logger.Info("Successfully inserted regex route into Trie",
 			zap.String("pattern", originalPath),
 			zap.Any("rules", rules))
 		return
 	}
 
-	cleanPath := strings.TrimPrefix(path, "/")
-	for _, ch := range cleanPath {
-		if node.Children[ch] == nil {
-			node.Children[ch] = &TrieRegexpNode{Children: make(map[rune]*TrieRegexpNode)}
-		}
-		node = node.Children[ch]
-	}
-	node.Rules = rules
-	node.IsEnd = true
+	node.insert(path, rules)
 	logger.Info("Successfully inserted static route into TrieRegexp",
 		zap.String("path", originalPath),
 		zap.Any("rules", rules))
 }
 
+// Remove 删除正则规则或静态路由，并清理不再被其他路由使用的节点，路由不存在时返回 false
+func (t *TrieRegexp) Remove(path string) bool {
+	if config.IsRegexPattern(path) {
+		root := t.Root
+		for i, rule := range root.RegexRules {
+			if rule.Pattern == path {
+				root.RegexRules = slices.Delete(root.RegexRules, i, i+1)
+				logger.Info("Successfully removed regex route from Trie", zap.String("pattern", path))
+				return true
+			}
+		}
+		return false
+	}
+
+	if !t.Root.remove(path) {
+		return false
+	}
+	logger.Info("Successfully removed static route from TrieRegexp", zap.String("path", path))
+	return true
+}
+
 func (t *TrieRegexp) Search(ctx context.Context, path string) (config.RoutingRules, bool) {
-	ctx, span := trieRegexpTracer.Start(ctx, "TrieRegexp.Search",
+	_, rules, found := t.SearchRule(ctx, path)
+	return rules, found
+}
+
+// SearchRule 查找给定路径，同时返回匹配的静态路由或正则规则模板
+func (t *TrieRegexp) SearchRule(ctx context.Context, path string) (string, config.RoutingRules, bool) {
+	pattern, rules, _, found := t.SearchParams(ctx, path)
+	return pattern, rules, found
+}
+
+// SearchParams 查找给定路径，同时返回匹配的规则模板和路径参数
+// 先按静态段、参数段、通配段的顺序匹配 Trie，正则规则的优先级高于 Trie 命中的路由时以正则规则为准
+func (t *TrieRegexp) SearchParams(ctx context.Context, path string) (string, config.RoutingRules, gin.Params, bool) {
+	return t.search(ctx, path, nil)
+}
+
+// SearchHost 与 SearchParams 相同，但只匹配处理该 Host 请求的路由，不处理的路由视为不存在
+func (t *TrieRegexp) SearchHost(ctx context.Context, path, host string) (string, config.RoutingRules, gin.Params, bool) {
+	return t.search(ctx, path, servesHost(host))
+}
+
+// search 查找给定路径，accept 不为空时只匹配其接受的路由
+func (t *TrieRegexp) search(ctx context.Context, path string, accept routeFilter) (string, config.RoutingRules, gin.Params, bool) {
+	_, span := trieRegexpTracer.Start(ctx, "TrieRegexp.Search",
 		trace.WithAttributes(attribute.String("path", path)))
 	defer span.End()
 
-	node := t.Root
-	cleanPath := strings.TrimPrefix(path, "/")
+	node, params := t.Root.match([]rune(strings.TrimPrefix(path, "/")), nil, accept)
 
-	for _, ch := range cleanPath {
-		if node.Children[ch] == nil {
+	// 正则规则已按优先级和具体程度从高到低排序
+	for _, regexRule := range t.Root.RegexRules {
+		if node != nil && regexRule.Rules.Priority() <= node.Rules.Priority() {
 			break
 		}
-		node = node.Children[ch]
-	}
-	if node != nil && node.IsEnd {
-		return node.Rules, true
-	}
-
-	// 检查所有正则规则
-	for _, regexRule := range t.Root.RegexRules {
-		if regexRule.Regex.MatchString(path) {
-			return regexRule.Rules, true
+		if accept.accepts(regexRule.Rules) && regexRule.Regex.MatchString(path) {
+			return regexRule.Pattern, regexRule.Rules, nil, true
 		}
 	}
 
-	return nil, false
+	if node != nil {
+		return node.Pattern, node.Rules, params, true
+	}
+	return "", nil, nil, false
 }
 
 func (tr *TrieRegexpRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *config.Config) {
 	rules := cfg.Routing.GetHTTPRules()
+	tr.load(rules)
 	if len(rules) == 0 {
 		logger.Warn("No HTTP routing rules found in configuration")
 		return
 	}
 
-	for path, targetRules := range rules {
-		tr.Trie.Insert(path, targetRules)
-	}
-
 	r.Use(func(c *gin.Context) {
 		ctx, span := trieRegexpTracer.Start(c.Request.Context(), "Routing.Match",
 			trace.WithAttributes(attribute.String("type", "TrieRegexp")),
@@ -131,24 +178,59 @@ func (tr *TrieRegexpRouter) Setup(r gin.IRouter, httpProxy *proxy.HTTPProxy, cfg
 		defer span.End()
 
 		path := c.Request.URL.Path
-		targetRules, found := tr.Trie.Search(ctx, path)
+		var (
+			pattern     string
+			targetRules config.RoutingRules
+			params      gin.Params
+		)
+		found := matchPath(cfg, path, func(p string) bool {
+			tr.mu.RLock()
+			defer tr.mu.RUnlock()
+			var ok bool
+			pattern, targetRules, params, ok = tr.Trie.SearchHost(ctx, p, c.Request.Host)
+			return ok
+		})
 		if !found {
 			logger.Warn("No matching route found",
 				zap.String("path", path),
 				zap.String("method", c.Request.Method))
-			c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+			proxy.WriteError(c, http.StatusNotFound, proxy.ErrCodeRouteNotFound, "Route not found")
 			c.Abort()
 			span.SetStatus(codes.Error, "Route not found")
 			return
 		}
 
-		span.SetAttributes(attribute.String("matched_target", targetRules[0].Target))
+		span.SetAttributes(routeMatchAttributes(c, httpProxy, engineTrieRegexp, pattern, targetRules)...)
 		span.SetStatus(codes.Ok, "Route matched successfully")
 		logger.Info("Successfully matched route in TrieRegexp",
 			zap.String("path", path),
 			zap.Any("rules", targetRules))
 
+		c.Params = append(c.Params, params...)
+		proxy.SetMatchedRoute(c, pattern)
 		c.Request = c.Request.WithContext(ctx)
 		httpProxy.CreateHTTPHandler(targetRules)(c)
 	})
 }
+
+// load 按与上次加载的差异更新 Trie：删除已移除的路由，插入新增或变化的路由，未变化的路由及其正则编译结果保持不变
+func (tr *TrieRegexpRouter) load(rules map[string]config.RoutingRules) {
+	diff := config.DiffRoutingRules(tr.rules, rules)
+	if diff.Empty() {
+		return
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for _, path := range diff.Removed {
+		tr.Trie.Remove(path)
+	}
+	for _, path := range append(diff.Added, diff.Changed...) {
+		tr.Trie.Insert(path, rules[path])
+	}
+	tr.rules = rules
+	logger.Info("TrieRegexp routes updated",
+		zap.Int("added", len(diff.Added)),
+		zap.Int("removed", len(diff.Removed)),
+		zap.Int("changed", len(diff.Changed)))
+}
@@ -1,9 +1,12 @@
 package router
 
 import (
+	"context"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -84,7 +87,7 @@ func TestTrieRegexpInsert(t *testing.T) {
 			// 设置日志捕获
 			_, recordedLogs := setupLogger()
 
-			trie := &TrieRegexp{Root: &TrieRegexpNode{Children: make(map[rune]*TrieRegexpNode)}}
+			trie := newTrieRegexp()
 			trie.Insert(tt.path, tt.rules)
 
 			// 在检查日志前同步，确保缓冲区被刷新
@@ -96,7 +99,7 @@ func TestTrieRegexpInsert(t *testing.T) {
 				assert.Equal(t, tt.rules, trie.Root.RegexRules[0].Rules, "Expected Rules to match for path %v", tt.path)
 				assert.False(t, trie.Root.IsEnd, "Expected IsEnd to be false for regex path %v", tt.path)
 			} else if !tt.wantError {
-				node := trie.Root
+				node := &trie.Root.TrieNode
 				cleanPath := strings.TrimPrefix(tt.path, "/")
 				for _, ch := range cleanPath {
 					node = node.Children[ch]
@@ -104,7 +107,7 @@ func TestTrieRegexpInsert(t *testing.T) {
 				}
 				assert.Equal(t, tt.wantIsEnd, node.IsEnd, "Expected IsEnd to be %v for path %v", tt.wantIsEnd, tt.path)
 				assert.Equal(t, tt.rules, node.Rules, "Expected Rules to match for path %v", tt.path)
-				assert.Empty(t, node.RegexRules, "Expected RegexRules to be empty for static path %v", tt.path)
+				assert.Empty(t, trie.Root.RegexRules, "Expected RegexRules to be empty for static path %v", tt.path)
 			} else {
 				assert.Empty(t, trie.Root.RegexRules, "Expected RegexRules to remain empty for invalid regex path %v", tt.path)
 				assert.Empty(t, trie.Root.Children, "Expected Children to remain empty for invalid regex path %v", tt.path)
@@ -126,7 +129,7 @@ func TestTrieRegexpInsert(t *testing.T) {
 }
 
 func TestTrieRegexpSearch(t *testing.T) {
-	trie := &TrieRegexp{Root: &TrieRegexpNode{Children: make(map[rune]*TrieRegexpNode)}}
+	trie := newTrieRegexp()
 	rulesStatic := config.RoutingRules{{Target: "http://localhost:8080"}}
 	rulesRegex := config.RoutingRules{{Target: "http://localhost:8081"}}
 	rulesRoot := config.RoutingRules{{Target: "http://localhost:8082"}}
@@ -187,9 +190,131 @@ func TestTrieRegexpSearch(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rules, found := trie.Search(tt.path)
+			rules, found := trie.Search(context.Background(), tt.path)
 			assert.Equal(t, tt.wantFound, found, "Expected found to be %v for path %v", tt.wantFound, tt.path)
 			assert.Equal(t, tt.wantRules, rules, "Expected rules to match for path %v", tt.path)
 		})
 	}
 }
+
+func TestTrieRegexpSearchParams(t *testing.T) {
+	trie := newTrieRegexp()
+	rulesMe := config.RoutingRules{{Target: "http://localhost:8080"}}
+	rulesUser := config.RoutingRules{{Target: "http://localhost:8081"}}
+	rulesFiles := config.RoutingRules{{Target: "http://localhost:8082"}}
+	rulesRegex := config.RoutingRules{{Target: "http://localhost:8083"}}
+
+	trie.Insert("/users/me", rulesMe)
+	trie.Insert("/users/:id", rulesUser)
+	trie.Insert("/files/*path", rulesFiles)
+	trie.Insert("/api/v[0-9]+/.*", rulesRegex)
+	assert.Len(t, trie.Root.RegexRules, 1, "Wildcard segments must not be treated as regular expressions")
+
+	pattern, rules, params, found := trie.SearchParams(context.Background(), "/users/me")
+	assert.True(t, found)
+	assert.Equal(t, "/users/me", pattern)
+	assert.Equal(t, rulesMe, rules)
+	assert.Empty(t, params)
+
+	pattern, rules, params, found = trie.SearchParams(context.Background(), "/users/42")
+	assert.True(t, found)
+	assert.Equal(t, "/users/:id", pattern)
+	assert.Equal(t, rulesUser, rules)
+	assert.Equal(t, gin.Params{{Key: "id", Value: "42"}}, params)
+
+	_, rules, params, found = trie.SearchParams(context.Background(), "/files/docs/readme.md")
+	assert.True(t, found)
+	assert.Equal(t, rulesFiles, rules)
+	assert.Equal(t, gin.Params{{Key: "path", Value: "/docs/readme.md"}}, params)
+
+	_, rules, params, found = trie.SearchParams(context.Background(), "/api/v3/orders")
+	assert.True(t, found)
+	assert.Equal(t, rulesRegex, rules)
+	assert.Empty(t, params)
+}
+
+func TestTrieRegexpSearch_MostSpecificRegexWins(t *testing.T) {
+	for _, order := range [][]string{{"/api/.*", "/api/v1/.*"}, {"/api/v1/.*", "/api/.*"}} {
+		trie := newTrieRegexp()
+		for _, path := range order {
+			trie.Insert(path, config.RoutingRules{{Target: "http://localhost:8080"}})
+		}
+
+		pattern, _, found := trie.SearchRule(context.Background(), "/api/v1/users")
+		assert.True(t, found)
+		assert.Equal(t, "/api/v1/.*", pattern, "insertion order %v", order)
+	}
+}
+
+func TestTrieRegexpSearch_PriorityRegexBeatsStatic(t *testing.T) {
+	trie := newTrieRegexp()
+	rulesStatic := config.RoutingRules{{Target: "http://localhost:8080"}}
+	rulesRegex := config.RoutingRules{{Target: "http://localhost:8081", Priority: 1}}
+	rulesLow := config.RoutingRules{{Target: "http://localhost:8082"}}
+
+	trie.Insert("/api/v1/users", rulesStatic)
+	trie.Insert("/api/v1/.*", rulesRegex)
+	trie.Insert("/orders/.*", rulesLow)
+	trie.Insert("/orders/list", rulesStatic)
+
+	pattern, rules, found := trie.SearchRule(context.Background(), "/api/v1/users")
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/.*", pattern)
+	assert.Equal(t, rulesRegex, rules)
+
+	// 优先级相同时静态路由仍优先于正则规则
+	pattern, _, found = trie.SearchRule(context.Background(), "/orders/list")
+	assert.True(t, found)
+	assert.Equal(t, "/orders/list", pattern)
+}
+
+// TestTrieRegexpSearchHost 测试静态路由和正则规则都只在处理该 Host 时参与匹配
+func TestTrieRegexpSearchHost(t *testing.T) {
+	trie := newTrieRegexp()
+	rulesStatic := config.RoutingRules{{Target: "http://localhost:8080", Host: "api.example.com"}}
+	rulesRegex := config.RoutingRules{{Target: "http://localhost:8081", Host: "*.example.com", Priority: 1}}
+	rulesAll := config.RoutingRules{{Target: "http://localhost:8082"}}
+
+	trie.Insert("/api/v1/users", rulesStatic)
+	trie.Insert("/api/v1/.*", rulesRegex)
+	trie.Insert("/api/*path", rulesAll)
+
+	pattern, _, _, found := trie.SearchHost(context.Background(), "/api/v1/users", "web.example.com")
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/.*", pattern)
+
+	pattern, _, _, found = trie.SearchHost(context.Background(), "/api/v1/users", "example.org")
+	assert.True(t, found)
+	assert.Equal(t, "/api/*path", pattern)
+}
+
+// TestTrieRegexpRouter_LoadOnlyChangedRoutes 测试热更新时未变化的正则规则复用编译结果，已删除的规则不再匹配
+func TestTrieRegexpRouter_LoadOnlyChangedRoutes(t *testing.T) {
+	tr := NewTrieRegexpRouter()
+	tr.load(map[string]config.RoutingRules{
+		"/api/v1/.*": {{Target: "http://localhost:8080"}},
+		"/api/v2/.*": {{Target: "http://localhost:8081"}},
+		"/static":    {{Target: "http://localhost:8082"}},
+	})
+	compiled := make(map[string]*regexp.Regexp)
+	for _, rule := range tr.Trie.Root.RegexRules {
+		compiled[rule.Pattern] = rule.Regex
+	}
+
+	changed := config.RoutingRules{{Target: "http://localhost:9081"}}
+	tr.load(map[string]config.RoutingRules{
+		"/api/v1/.*": {{Target: "http://localhost:8080"}},
+		"/api/v2/.*": changed,
+	})
+
+	assert.Len(t, tr.Trie.Root.RegexRules, 2)
+	for _, rule := range tr.Trie.Root.RegexRules {
+		assert.Same(t, compiled[rule.Pattern], rule.Regex, "regex %s should not be recompiled", rule.Pattern)
+	}
+	_, rules, found := tr.Trie.SearchRule(context.Background(), "/api/v2/users")
+	assert.True(t, found)
+	assert.Equal(t, changed, rules)
+	_, _, found = tr.Trie.SearchRule(context.Background(), "/static")
+	assert.False(t, found)
+	assert.Empty(t, tr.Trie.Root.Children, "nodes of the removed static route should be pruned")
+}
@@ -1,9 +1,11 @@
 package router
 
 import (
+	"context"
 	"strings"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -103,10 +105,11 @@ func TestTrieSearch(t *testing.T) {
 			wantFound: true,
 		},
 		{
+			// Trie 严格匹配，尾部斜杠由路由的 trailingSlash 策略处理
 			name:      "Search with trailing slash",
 			path:      "/api/v1/",
-			wantRules: rulesV1,
-			wantFound: true,
+			wantRules: nil,
+			wantFound: false,
 		},
 		{
 			name:      "Search non-existent path",
@@ -130,9 +133,197 @@ func TestTrieSearch(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rules, found := trie.Search(tt.path)
+			rules, found := trie.Search(context.Background(), tt.path)
 			assert.Equal(t, tt.wantFound, found, "Expected found to be %v for path %v", tt.wantFound, tt.path)
 			assert.Equal(t, tt.wantRules, rules, "Expected rules to match for path %v", tt.path)
 		})
 	}
 }
+
+// TestTrieSearchParams 测试参数段和通配段的匹配，以及静态段与参数段重叠时的优先级
+func TestTrieSearchParams(t *testing.T) {
+	trie := &Trie{Root: &TrieNode{Children: make(map[rune]*TrieNode)}}
+	rulesNew := config.RoutingRules{{Target: "http://localhost:8080"}}
+	rulesUser := config.RoutingRules{{Target: "http://localhost:8081"}}
+	rulesPosts := config.RoutingRules{{Target: "http://localhost:8082"}}
+	rulesStatic := config.RoutingRules{{Target: "http://localhost:8083"}}
+
+	trie.Insert("/users/new", rulesNew)
+	trie.Insert("/users/:id", rulesUser)
+	trie.Insert("/users/:id/posts", rulesPosts)
+	trie.Insert("/static/*filepath", rulesStatic)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantPattern string
+		wantRules   config.RoutingRules
+		wantParams  gin.Params
+		wantFound   bool
+	}{
+		{
+			name:        "Static segment wins over param",
+			path:        "/users/new",
+			wantPattern: "/users/new",
+			wantRules:   rulesNew,
+			wantFound:   true,
+		},
+		{
+			name:        "Param segment",
+			path:        "/users/123",
+			wantPattern: "/users/:id",
+			wantRules:   rulesUser,
+			wantParams:  gin.Params{{Key: "id", Value: "123"}},
+			wantFound:   true,
+		},
+		{
+			name:        "Param sharing a prefix with a static segment",
+			path:        "/users/newton",
+			wantPattern: "/users/:id",
+			wantRules:   rulesUser,
+			wantParams:  gin.Params{{Key: "id", Value: "newton"}},
+			wantFound:   true,
+		},
+		{
+			name:        "Backtrack from static to param",
+			path:        "/users/new/posts",
+			wantPattern: "/users/:id/posts",
+			wantRules:   rulesPosts,
+			wantParams:  gin.Params{{Key: "id", Value: "new"}},
+			wantFound:   true,
+		},
+		{
+			name:        "Wildcard captures the remaining path",
+			path:        "/static/css/app.css",
+			wantPattern: "/static/*filepath",
+			wantRules:   rulesStatic,
+			wantParams:  gin.Params{{Key: "filepath", Value: "/css/app.css"}},
+			wantFound:   true,
+		},
+		{
+			name:      "Param does not match an empty segment",
+			path:      "/users/",
+			wantFound: false,
+		},
+		{
+			name:      "Param does not span segments",
+			path:      "/users/123/comments",
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, rules, params, found := trie.SearchParams(context.Background(), tt.path)
+			assert.Equal(t, tt.wantFound, found, "Expected found to be %v for path %v", tt.wantFound, tt.path)
+			assert.Equal(t, tt.wantPattern, pattern, "Expected pattern to match for path %v", tt.path)
+			assert.Equal(t, tt.wantRules, rules, "Expected rules to match for path %v", tt.path)
+			assert.Equal(t, tt.wantParams, params, "Expected params to match for path %v", tt.path)
+		})
+	}
+}
+
+// TestTrieSearchParams_Priority 测试优先级更高的参数段路由优先于静态路由，未配置优先级时仍为静态段优先
+func TestTrieSearchParams_Priority(t *testing.T) {
+	trie := &Trie{Root: &TrieNode{Children: make(map[rune]*TrieNode)}}
+	rulesMe := config.RoutingRules{{Target: "http://localhost:8080"}}
+	rulesUser := config.RoutingRules{{Target: "http://localhost:8081", Priority: 5}}
+	rulesNew := config.RoutingRules{{Target: "http://localhost:8082"}}
+	rulesPost := config.RoutingRules{{Target: "http://localhost:8083"}}
+
+	trie.Insert("/users/me", rulesMe)
+	trie.Insert("/users/:id", rulesUser)
+	trie.Insert("/posts/new", rulesNew)
+	trie.Insert("/posts/:id", rulesPost)
+
+	pattern, rules, params, found := trie.SearchParams(context.Background(), "/users/me")
+	assert.True(t, found)
+	assert.Equal(t, "/users/:id", pattern)
+	assert.Equal(t, rulesUser, rules)
+	assert.Equal(t, gin.Params{{Key: "id", Value: "me"}}, params)
+
+	pattern, _, params, found = trie.SearchParams(context.Background(), "/posts/new")
+	assert.True(t, found)
+	assert.Equal(t, "/posts/new", pattern)
+	assert.Empty(t, params)
+}
+
+// TestTrieSearchHost 测试限定了其他 Host 的路由不会遮蔽同样能匹配该路径的路由
+func TestTrieSearchHost(t *testing.T) {
+	trie := &Trie{Root: &TrieNode{Children: make(map[rune]*TrieNode)}}
+	rulesTenant := config.RoutingRules{{Target: "http://localhost:8080", Host: "*.tenant.example.com"}}
+	rulesUser := config.RoutingRules{{Target: "http://localhost:8081", Host: "api.example.com"}}
+	rulesAll := config.RoutingRules{{Target: "http://localhost:8082"}}
+
+	trie.Insert("/api/v1/users", rulesTenant)
+	trie.Insert("/api/v1/:name", rulesUser)
+	trie.Insert("/api/*path", rulesAll)
+
+	tests := []struct {
+		host    string
+		pattern string
+	}{
+		{"a.tenant.example.com", "/api/v1/users"},
+		{"api.example.com:8380", "/api/v1/:name"},
+		{"other.example.com", "/api/*path"},
+	}
+	for _, tt := range tests {
+		pattern, _, _, found := trie.SearchHost(context.Background(), "/api/v1/users", tt.host)
+		assert.True(t, found, tt.host)
+		assert.Equal(t, tt.pattern, pattern, tt.host)
+	}
+
+	// 不区分 Host 查找时仍按路径匹配最具体的路由
+	pattern, _, _, found := trie.SearchParams(context.Background(), "/api/v1/users")
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/users", pattern)
+
+	trie.Remove("/api/*path")
+	_, _, _, found = trie.SearchHost(context.Background(), "/api/v1/users", "other.example.com")
+	assert.False(t, found)
+}
+
+// TestTrieRemove 测试删除路由后清理不再使用的节点，共享前缀的其他路由不受影响
+func TestTrieRemove(t *testing.T) {
+	trie := &Trie{Root: &TrieNode{Children: make(map[rune]*TrieNode)}}
+	rules := config.RoutingRules{{Target: "http://localhost:8080"}}
+	trie.Insert("/api", rules)
+	trie.Insert("/api/users/:id", rules)
+	trie.Insert("/files/*path", rules)
+
+	assert.True(t, trie.Remove("/api/users/:id"))
+	assert.False(t, trie.Remove("/api/users/:id"), "route already removed")
+	assert.False(t, trie.Remove("/ap"), "prefix of a route is not a route")
+	_, found := trie.Search(context.Background(), "/api/users/1")
+	assert.False(t, found)
+	_, found = trie.Search(context.Background(), "/api")
+	assert.True(t, found)
+	assert.Empty(t, trie.Root.Children['a'].Children['p'].Children['i'].Children, "unused nodes should be pruned")
+
+	assert.True(t, trie.Remove("/files/*path"))
+	assert.NotContains(t, trie.Root.Children, 'f')
+}
+
+// TestTrieRouter_LoadOnlyChangedRoutes 测试热更新只修改变化的路由，未变化路由的节点保持不变
+func TestTrieRouter_LoadOnlyChangedRoutes(t *testing.T) {
+	tr := NewTrieRouter()
+	tr.load(map[string]config.RoutingRules{
+		"/users":  {{Target: "http://localhost:8080"}},
+		"/orders": {{Target: "http://localhost:8081"}},
+		"/items":  {{Target: "http://localhost:8082"}},
+	})
+	users := tr.Trie.Root.Children['u']
+
+	changed := config.RoutingRules{{Target: "http://localhost:9081"}}
+	tr.load(map[string]config.RoutingRules{
+		"/users":  {{Target: "http://localhost:8080"}},
+		"/orders": changed,
+	})
+
+	assert.Same(t, users, tr.Trie.Root.Children['u'], "unchanged route should keep its nodes")
+	rules, found := tr.Trie.Search(context.Background(), "/orders")
+	assert.True(t, found)
+	assert.Equal(t, changed, rules)
+	_, found = tr.Trie.Search(context.Background(), "/items")
+	assert.False(t, found)
+}
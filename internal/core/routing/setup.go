@@ -1,9 +1,11 @@
 package routing
 
 import (
+	"net/http"
 	"os"
-	"strings"
+	"sync"
 
+	"github.com/penwyp/mini-gateway/internal/core/observability"
 	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
 	internalrouter "github.com/penwyp/mini-gateway/internal/core/routing/router"
 
@@ -13,68 +15,58 @@ import (
 	"go.uber.org/zap"
 )
 
-// isRegexPattern 检查路径是否包含正则表达式字符
-func isRegexPattern(path string) bool {
-	return strings.ContainsAny(path, ".*+?()|[]^$\\")
+// validateRules 验证路由规则与配置的引擎兼容性，不兼容时退出
+func validateRules(cfg *config.Config) {
+	if err := config.ValidateRoutingRules(cfg); err != nil {
+		logger.Error("Routing rules validation failed",
+			zap.String("engine", cfg.Routing.Engine),
+			zap.Error(err))
+		os.Exit(1)
+	}
 }
 
-// validateRules 验证路由规则与配置的引擎兼容性
-func validateRules(cfg *config.Config) {
-	engine := cfg.Routing.Engine
-	rules := cfg.Routing.Rules
-
-	for path, pathEndpoints := range rules {
-		for _, endpoint := range pathEndpoints {
-			// 跳过 gRPC 的进一步验证，因其有单独处理
-			if endpoint.Protocol == "grpc" {
-				continue
-			}
-			// Trie 引擎不支持正则表达式路径
-			if engine == "trie" && isRegexPattern(path) {
-				logger.Error("Trie routing engine does not support regular expression paths",
-					zap.String("path", path),
-					zap.String("hint", "Use 'trie-regexp' or 'regexp' engine for regex support"))
-				os.Exit(1)
-			}
-			// 除 trie-regexp 和 regexp 外的非正则引擎无法处理正则路径
-			if isRegexPattern(path) && engine != "trie-regexp" && engine != "regexp" {
-				logger.Error("Routing engine incompatible with regular expression path",
-					zap.String("engine", engine),
-					zap.String("path", path),
-					zap.String("hint", "Use 'trie-regexp' or 'regexp' engine for regex support"))
-				os.Exit(1)
-			}
-		}
+// healthCheckOnly 仅健康检查模式下拒绝所有代理请求
+func healthCheckOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		proxy.AbortWithError(c, http.StatusServiceUnavailable, proxy.ErrCodeUnavailable, "Gateway is running in health-check-only mode")
 	}
 }
 
-// Setup 初始化路由引擎并配置路由规则，包括 gRPC 和 WebSocket 代理
+// isNilRouter 判断路由器是否为空，包括包装了空指针的接口值
+func isNilRouter(r gin.IRouter) bool {
+	switch router := r.(type) {
+	case nil:
+		return true
+	case *gin.Engine:
+		return router == nil
+	case *gin.RouterGroup:
+		return router == nil
+	}
+	return false
+}
+
+// Setup 初始化路由引擎并配置路由规则，包括 gRPC 和 WebSocket 代理，路由器为空时记录错误并跳过，不建立任何连接
 func Setup(protected gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *config.Config) {
+	// 创建分组与注册路由都需要路由器，必须在此之前检查
+	if isNilRouter(protected) {
+		logger.Error("No router available to mount routes, routing rules are not loaded")
+		return
+	}
 	logger.Info("Loading routing rules from configuration",
 		zap.Any("rules", cfg.Routing.Rules))
 	validateRules(cfg)
+	rules, targets := cfg.Routing.Counts()
+	observability.RoutingRules.Set(float64(rules))
+	observability.RoutingTargets.Set(float64(targets))
 
-	// 根据配置选择并初始化适当的路由引擎
-	var router internalrouter.Router
-	switch cfg.Routing.Engine {
-	case "trie":
-		router = internalrouter.NewTrieRouter()
-		logger.Info("Initialized Trie routing engine")
-	case "trie-regexp", "trie_regexp": // 支持连字符和下划线两种变体
-		router = internalrouter.NewTrieRegexpRouter()
-		logger.Info("Initialized Trie-Regexp routing engine")
-	case "regexp":
-		router = internalrouter.NewRegexpRouter(cfg)
-		logger.Info("Initialized Regexp routing engine")
-	case "gin":
-		router = internalrouter.NewGinRouter()
-		logger.Info("Initialized Gin routing engine")
-	default:
-		logger.Warn("Unknown routing engine specified, defaulting to Gin",
-			zap.String("engine", cfg.Routing.Engine))
-		router = internalrouter.NewGinRouter()
+	// 仅健康检查模式：路由照常注册以便匹配，但在转发前统一返回 503
+	if cfg.Server.HealthCheckOnly {
+		protected.Use(healthCheckOnly())
+		logger.Warn("Health-check-only mode enabled, proxied routes will return 503")
 	}
 
+	router := routerFor(cfg)
+
 	// 为 gRPC 和 WebSocket 路由创建分组，使用配置中的前缀
 	grpcGroup := protected.Group(cfg.GRPC.Prefix)
 	wsGroup := protected.Group(cfg.WebSocket.Prefix)
@@ -96,10 +88,58 @@ func Setup(protected gin.IRouter, httpProxy *proxy.HTTPProxy, cfg *config.Config
 
 	// 为特定引擎中的动态路由注册空处理器
 	switch cfg.Routing.Engine {
-	case "trie", "trie_regexp", "regexp":
-		for p := range cfg.Routing.GetHTTPRules() {
+	case "trie", "trie-regexp", "trie_regexp", "regexp":
+		rules := cfg.Routing.GetHTTPRules()
+		for p := range rules {
 			// 空处理器依赖特定 Router 实现中的中间件
-			protected.Any(p, func(c *gin.Context) {})
+			for _, routePath := range internalrouter.RoutePaths(p, rules, cfg) {
+				protected.Any(routePath, func(c *gin.Context) {})
+			}
 		}
 	}
 }
+
+// 上次使用的路由引擎，配置热更新时引擎类型未变化则复用，只按差异更新变化的路由
+var (
+	currentMu     sync.Mutex
+	currentEngine string
+	currentRouter internalrouter.Router
+)
+
+// routerFor 返回配置的路由引擎，引擎类型与上次相同时复用已有实例，
+// 未变化的路由（前缀树节点、正则编译结果）保持不变，否则创建新的引擎
+func routerFor(cfg *config.Config) internalrouter.Router {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if currentRouter != nil && currentEngine == cfg.Routing.Engine {
+		logger.Info("Reusing routing engine for configuration reload",
+			zap.String("engine", cfg.Routing.Engine))
+		return currentRouter
+	}
+	currentEngine, currentRouter = cfg.Routing.Engine, newRouter(cfg)
+	return currentRouter
+}
+
+// newRouter 根据配置选择并初始化适当的路由引擎
+func newRouter(cfg *config.Config) internalrouter.Router {
+	var router internalrouter.Router
+	switch cfg.Routing.Engine {
+	case "trie":
+		router = internalrouter.NewTrieRouter()
+		logger.Info("Initialized Trie routing engine")
+	case "trie-regexp", "trie_regexp": // 支持连字符和下划线两种变体
+		router = internalrouter.NewTrieRegexpRouter()
+		logger.Info("Initialized Trie-Regexp routing engine")
+	case "regexp":
+		router = internalrouter.NewRegexpRouter(cfg)
+		logger.Info("Initialized Regexp routing engine")
+	case "gin":
+		router = internalrouter.NewGinRouter()
+		logger.Info("Initialized Gin routing engine")
+	default:
+		logger.Warn("Unknown routing engine specified, defaulting to Gin",
+			zap.String("engine", cfg.Routing.Engine))
+		router = internalrouter.NewGinRouter()
+	}
+	return router
+}
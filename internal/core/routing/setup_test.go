@@ -0,0 +1,185 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/health"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	internalrouter "github.com/penwyp/mini-gateway/internal/core/routing/router"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSetup_HealthCheckOnly 测试仅健康检查模式下代理路由返回 503，而状态路由照常报告各目标的探测结果
+func TestSetup_HealthCheckOnly(t *testing.T) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	for _, engine := range []string{"gin", "trie"} {
+		t.Run(engine, func(t *testing.T) {
+			var proxiedHits atomic.Int32
+			newBackend := func(healthStatus int) *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/health" {
+						w.WriteHeader(healthStatus)
+						return
+					}
+					proxiedHits.Add(1)
+					w.WriteHeader(http.StatusOK)
+				}))
+			}
+			healthy := newBackend(http.StatusOK)
+			defer healthy.Close()
+			unhealthy := newBackend(http.StatusInternalServerError)
+			defer unhealthy.Close()
+
+			cfg := &config.Config{
+				Server: config.Server{HealthCheckOnly: true},
+				Routing: config.Routing{
+					Engine:       engine,
+					LoadBalancer: "round_robin",
+					Rules: map[string]config.RoutingRules{
+						"/api/v1/user": {
+							{Target: healthy.URL, Weight: 50, Protocol: "http", HealthCheckPath: "/health"},
+							{Target: unhealthy.URL, Weight: 50, Protocol: "http", HealthCheckPath: "/health"},
+						},
+					},
+				},
+			}
+			config.InitTestConfigManager()
+			config.SetConfig(cfg)
+			cache.InitTestStore()
+			checker := health.InitHealthChecker(cfg)
+			defer checker.Close()
+
+			r := gin.New()
+			r.GET("/status", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"backendStats": checker.GetAllStats()})
+			})
+			Setup(r.Group("/"), proxy.NewHTTPProxy(cfg), cfg)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+			assert.Contains(t, w.Body.String(), "health-check-only")
+
+			// 等待两个目标都完成至少一次探测
+			probed := func() map[string]health.TargetStatus {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/status", nil))
+				if w.Code != http.StatusOK {
+					return nil
+				}
+				var body struct {
+					BackendStats []health.TargetStatus `json:"backendStats"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					return nil
+				}
+				stats := make(map[string]health.TargetStatus, len(body.BackendStats))
+				for _, stat := range body.BackendStats {
+					if stat.ProbeRequestCount > 0 {
+						stats[stat.URL] = stat
+					}
+				}
+				return stats
+			}
+			var stats map[string]health.TargetStatus
+			assert.Eventually(t, func() bool {
+				stats = probed()
+				return len(stats) == 2
+			}, 5*time.Second, 50*time.Millisecond, "status should report probe results for every target")
+
+			assert.Positive(t, stats[healthy.URL].ProbeSuccessCount, "healthy target should pass its probe")
+			assert.Zero(t, stats[healthy.URL].ProbeFailureCount)
+			assert.Positive(t, stats[unhealthy.URL].ProbeFailureCount, "unhealthy target should fail its probe")
+			assert.Zero(t, stats[unhealthy.URL].ProbeSuccessCount)
+			assert.Zero(t, proxiedHits.Load(), "backends should only receive health probes")
+		})
+	}
+}
+
+// TestSetup_TrailingSlash 测试尾部斜杠策略在所有路由引擎中表现一致
+func TestSetup_TrailingSlash(t *testing.T) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	for _, engine := range []string{"gin", "trie", "trie-regexp", "regexp"} {
+		for _, tc := range []struct {
+			policy       string
+			wantCode     int
+			wantLocation string
+		}{
+			{policy: "strict", wantCode: http.StatusNotFound},
+			{policy: "redirect", wantCode: http.StatusMovedPermanently, wantLocation: "/api/v1/user"},
+			{policy: "ignore", wantCode: http.StatusOK},
+		} {
+			t.Run(engine+"/"+tc.policy, func(t *testing.T) {
+				cfg := &config.Config{
+					Routing: config.Routing{
+						Engine:        engine,
+						LoadBalancer:  "round_robin",
+						TrailingSlash: tc.policy,
+						Rules: map[string]config.RoutingRules{
+							"/api/v1/user": {{Target: backend.URL, Weight: 100, Protocol: "http"}},
+						},
+					},
+				}
+				config.InitTestConfigManager()
+				config.SetConfig(cfg)
+
+				r := gin.New()
+				internalrouter.ConfigureTrailingSlash(r, cfg)
+				Setup(r.Group("/"), proxy.NewHTTPProxy(cfg), cfg)
+
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+				assert.Equal(t, http.StatusOK, w.Code)
+
+				w = httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user/", nil))
+				assert.Equal(t, tc.wantCode, w.Code)
+				assert.Equal(t, tc.wantLocation, w.Header().Get("Location"))
+			})
+		}
+	}
+}
+
+// TestSetup_NilRouter 测试没有可挂载的路由器时记录错误并跳过，而不是在创建 gRPC、WebSocket 分组时 panic
+func TestSetup_NilRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		GRPC:      config.GRPCConfig{Enabled: true, Prefix: "/grpc"},
+		WebSocket: config.WebSocket{Enabled: true, Prefix: "/ws"},
+		Routing: config.Routing{
+			LoadBalancer: "round_robin",
+			Rules: map[string]config.RoutingRules{
+				"/grpc/hello": {{Protocol: "grpc", Target: "dummy-target"}},
+			},
+		},
+	}
+	config.InitTestConfigManager()
+	config.SetConfig(cfg)
+
+	var nilEngine *gin.Engine
+	var nilGroup *gin.RouterGroup
+	for name, r := range map[string]gin.IRouter{"nil interface": nil, "nil engine": nilEngine, "nil group": nilGroup} {
+		t.Run(name, func(t *testing.T) {
+			_, logs := logger.InitTestLogger()
+			assert.NotPanics(t, func() { Setup(r, proxy.NewHTTPProxy(cfg), cfg) })
+			assert.Equal(t, 1, logs.FilterMessage("No router available to mount routes, routing rules are not loaded").Len())
+		})
+	}
+}
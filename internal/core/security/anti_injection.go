@@ -51,6 +51,7 @@ func AntiInjection() gin.HandlerFunc {
 					)
 					span.SetStatus(codes.Error, "Injection detected in query")
 					observability.AntiInjectionBlocks.WithLabelValues(c.Request.URL.Path).Inc()
+					RecordViolation(c.Request.Context(), c.ClientIP(), ViolationInjection)
 					c.JSON(http.StatusBadRequest, gin.H{"error": "Potential injection attack detected"})
 					c.Abort()
 					return
@@ -70,6 +71,7 @@ func AntiInjection() gin.HandlerFunc {
 						)
 						span.SetStatus(codes.Error, "Injection detected in form")
 						observability.AntiInjectionBlocks.WithLabelValues(c.Request.URL.Path).Inc()
+						RecordViolation(c.Request.Context(), c.ClientIP(), ViolationInjection)
 						c.JSON(http.StatusBadRequest, gin.H{"error": "Potential injection attack detected"})
 						c.Abort()
 						return
@@ -99,6 +101,7 @@ func AntiInjection() gin.HandlerFunc {
 						)
 						span.SetStatus(codes.Error, "Injection detected in JSON body")
 						observability.AntiInjectionBlocks.WithLabelValues(c.Request.URL.Path).Inc()
+						RecordViolation(c.Request.Context(), c.ClientIP(), ViolationInjection)
 						c.JSON(http.StatusBadRequest, gin.H{"error": "Potential injection attack detected"})
 						c.Abort()
 						return
@@ -119,6 +122,7 @@ func AntiInjection() gin.HandlerFunc {
 					)
 					span.SetStatus(codes.Error, "Injection detected in header")
 					observability.AntiInjectionBlocks.WithLabelValues(c.Request.URL.Path).Inc()
+					RecordViolation(c.Request.Context(), c.ClientIP(), ViolationInjection)
 					c.JSON(http.StatusBadRequest, gin.H{"error": "Potential injection attack detected"})
 					c.Abort()
 					return
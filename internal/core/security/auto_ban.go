@@ -0,0 +1,84 @@
+package security
+
+import (
+	"context"
+	"sync"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const violationKeyPrefix = "mg:ip_violations:" // Cache 中 IP 违规计数的键前缀
+
+// 违规原因
+const (
+	ViolationInjection = "injection"  // 触发防注入拦截
+	ViolationRateLimit = "rate_limit" // 触发限流拒绝
+)
+
+var (
+	autoBanMu  sync.RWMutex
+	autoBanCfg config.AutoBan
+)
+
+// InitAutoBan 根据配置初始化自动封禁
+func InitAutoBan(cfg *config.Config) {
+	autoBanMu.Lock()
+	defer autoBanMu.Unlock()
+	autoBanCfg = cfg.Security.AutoBan
+	if autoBanCfg.Enabled {
+		logger.Info("IP auto-ban enabled",
+			zap.Int("threshold", autoBanCfg.Threshold),
+			zap.Duration("window", autoBanCfg.Window),
+			zap.Duration("banDuration", autoBanCfg.BanDuration))
+	}
+}
+
+// RecordViolation 记录 IP 的一次违规，时间窗口内违规次数达到阈值时临时封禁该 IP
+// 计数保存在 Cache 中，多个网关实例共享
+func RecordViolation(ctx context.Context, ip, reason string) {
+	autoBanMu.RLock()
+	cfg := autoBanCfg
+	autoBanMu.RUnlock()
+	if !cfg.Enabled || cfg.Threshold <= 0 || ip == "" {
+		return
+	}
+
+	key := violationKeyPrefix + ip
+	count, err := cache.Default.Incr(ctx, key)
+	if err != nil {
+		logger.Error("Failed to record IP violation",
+			zap.String("ip", ip),
+			zap.String("reason", reason),
+			zap.Error(err))
+		return
+	}
+	// 首次违规时开启计数窗口
+	if count == 1 {
+		if err := cache.Default.Expire(ctx, key, cfg.Window); err != nil {
+			logger.Error("Failed to set IP violation window",
+				zap.String("ip", ip),
+				zap.Error(err))
+		}
+	}
+	if count < int64(cfg.Threshold) {
+		return
+	}
+
+	if err := BanIP(ctx, ip, cfg.BanDuration); err != nil {
+		logger.Error("Failed to auto-ban IP",
+			zap.String("ip", ip),
+			zap.String("reason", reason),
+			zap.Error(err))
+		return
+	}
+	cache.Default.Del(ctx, key)
+	observability.IPAutoBans.WithLabelValues(reason).Inc()
+	logger.Warn("IP auto-banned after repeated violations",
+		zap.String("ip", ip),
+		zap.String("reason", reason),
+		zap.Int64("violations", count))
+}
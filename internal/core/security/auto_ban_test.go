@@ -0,0 +1,68 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAutoBan_RepeatedInjection 测试同一 IP 多次触发防注入拦截后被自动封禁
+func TestAutoBan_RepeatedInjection(t *testing.T) {
+	store := cache.InitTestStore()
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	InitAutoBan(&config.Config{Security: config.Security{AutoBan: config.AutoBan{
+		Enabled:     true,
+		Threshold:   3,
+		Window:      time.Minute,
+		BanDuration: 10 * time.Minute,
+	}}})
+	defer InitAutoBan(&config.Config{})
+
+	ip := "192.0.2.1"
+	key := violationKeyPrefix + ip
+
+	router := gin.New()
+	router.Use(AntiInjection())
+	router.GET("/api", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	bansBefore := testutil.ToFloat64(observability.IPAutoBans.WithLabelValues(ViolationInjection))
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api?file=../../etc/passwd", nil)
+		req.RemoteAddr = ip + ":12345"
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	assert.Equal(t, bansBefore+1, testutil.ToFloat64(observability.IPAutoBans.WithLabelValues(ViolationInjection)))
+
+	// 封禁后违规计数被清除
+	_, err := store.Get(context.Background(), key)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	// 封禁生效后，IP 访问控制拒绝该 IP
+	allowed, err := CheckIPAccess(context.Background(), ip, &config.Config{})
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+// TestAutoBan_Disabled 测试未启用自动封禁时不记录违规
+func TestAutoBan_Disabled(t *testing.T) {
+	store := cache.InitTestStore()
+	InitAutoBan(&config.Config{})
+
+	RecordViolation(context.Background(), "192.0.2.2", ViolationRateLimit)
+	_, err := store.Get(context.Background(), violationKeyPrefix+"192.0.2.2")
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+}
@@ -2,20 +2,23 @@ package security
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/observability"
 	"github.com/penwyp/mini-gateway/pkg/cache"
 	"github.com/penwyp/mini-gateway/pkg/logger"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
 const (
-	blacklistKey = "mg:ip_blacklist" // Cache 中 IP 黑名单的键
-	whitelistKey = "mg:ip_whitelist" // Cache 中 IP 白名单的键
+	blacklistKey     = "mg:ip_blacklist" // Cache 中 IP 黑名单的键
+	whitelistKey     = "mg:ip_whitelist" // Cache 中 IP 白名单的键
+	tempBanKeyPrefix = "mg:ip_ban:"      // Cache 中临时封禁 IP 的键前缀，每个 IP 一个带过期时间的键
 )
 
 // IPAcl 中间件实现 IP 黑白名单检查
@@ -27,10 +30,17 @@ func IPAcl() gin.HandlerFunc {
 
 		allowed, err := CheckIPAccess(ctx, clientIP, cfg)
 		if err != nil {
+			policy := ipAclFailurePolicy(cfg)
+			observability.RedisFailures.WithLabelValues("ip_acl", policy).Inc()
 			logger.Error("Failed to check IP access",
 				zap.String("ip", clientIP),
+				zap.String("failurePolicy", policy),
 				zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			if policy == config.FailurePolicyOpen {
+				c.Next()
+				return
+			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable"})
 			c.Abort()
 			return
 		}
@@ -50,41 +60,69 @@ func IPAcl() gin.HandlerFunc {
 	}
 }
 
+// ipAclFailurePolicy 返回 IP 黑白名单检查时 Redis 失败的处理方式，未配置时拒绝请求
+func ipAclFailurePolicy(cfg *config.Config) string {
+	if cfg.Security.IPAclFailurePolicy == config.FailurePolicyOpen {
+		return config.FailurePolicyOpen
+	}
+	return config.FailurePolicyClosed
+}
+
 // CheckIPAccess 检查 IP 是否被允许访问
 func CheckIPAccess(ctx context.Context, ip string, cfg *config.Config) (bool, error) {
+	// Redis 不可用时直接按 ipAclFailurePolicy 处理，不等待请求超时
+	if cache.Degraded() {
+		return false, cache.ErrUnavailable
+	}
+
 	// 检查白名单（优先级最高）
 	if len(cfg.Security.IPWhitelist) > 0 {
-		isWhitelisted, err := cache.Client.HGet(ctx, whitelistKey, ip).Bool()
-		if err != nil && err != redis.Nil {
-			return false, err
+		_, err := cache.Default.HGet(ctx, whitelistKey, ip)
+		if errors.Is(err, cache.ErrNotFound) {
+			return false, nil // 白名单模式下，未列入白名单的 IP 被拒绝
 		}
-		if isWhitelisted {
-			return true, nil
+		if err != nil {
+			return false, err
 		}
-		return false, nil // 白名单模式下，未列入白名单的 IP 被拒绝
+		return true, nil
+	}
+
+	// 检查临时封禁，过期的键由 Cache 自动删除
+	_, err := cache.Default.Get(ctx, tempBanKey(ip))
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, cache.ErrNotFound) {
+		return false, err
 	}
 
 	// 检查黑名单
 	if len(cfg.Security.IPBlacklist) > 0 {
-		isBlacklisted, err := cache.Client.HGet(ctx, blacklistKey, ip).Bool()
-		if err != nil && err != redis.Nil {
-			return false, err
-		}
-		if isBlacklisted {
+		_, err := cache.Default.HGet(ctx, blacklistKey, ip)
+		if err == nil {
 			return false, nil
 		}
+		if !errors.Is(err, cache.ErrNotFound) {
+			return false, err
+		}
 	}
 
 	return true, nil // 无白名单且不在黑名单时允许
 }
 
-// InitIPRules 将 IP 黑白名单初始化到 Cache
+// InitIPRules 将 IP 黑白名单初始化到 Cache，Redis 不可用后恢复时重新写入
 func InitIPRules(cfg *config.Config) {
+	cache.OnReconnect("ip_acl", func() { loadIPRules(cfg) })
+	loadIPRules(cfg)
+}
+
+// loadIPRules 将 IP 黑白名单写入 Cache
+func loadIPRules(cfg *config.Config) {
 	ctx := context.Background()
 
 	// 根据 IPUpdateMode 决定覆盖还是追加
 	if cfg.Security.IPUpdateMode == "override" {
-		err := cache.Client.Del(ctx, blacklistKey, whitelistKey).Err()
+		err := cache.Default.Del(ctx, blacklistKey, whitelistKey)
 		if err != nil {
 			logger.Error("Failed to clear IP rules in Cache",
 				zap.Error(err))
@@ -96,7 +134,7 @@ func InitIPRules(cfg *config.Config) {
 	// 初始化白名单
 	if len(cfg.Security.IPWhitelist) > 0 {
 		for _, ip := range cfg.Security.IPWhitelist {
-			err := cache.Client.HSet(ctx, whitelistKey, ip, "true").Err()
+			err := cache.Default.HSet(ctx, whitelistKey, map[string]string{ip: "true"})
 			if err != nil {
 				logger.Error("Failed to initialize IP whitelist in Cache",
 					zap.String("ip", ip),
@@ -110,7 +148,7 @@ func InitIPRules(cfg *config.Config) {
 	// 初始化黑名单
 	if len(cfg.Security.IPBlacklist) > 0 {
 		for _, ip := range cfg.Security.IPBlacklist {
-			err := cache.Client.HSet(ctx, blacklistKey, ip, "true").Err()
+			err := cache.Default.HSet(ctx, blacklistKey, map[string]string{ip: "true"})
 			if err != nil {
 				logger.Error("Failed to initialize IP blacklist in Cache",
 					zap.String("ip", ip),
@@ -121,3 +159,53 @@ func InitIPRules(cfg *config.Config) {
 			zap.Strings("ips", cfg.Security.IPBlacklist))
 	}
 }
+
+// tempBanKey 返回临时封禁 IP 的键
+func tempBanKey(ip string) string {
+	return tempBanKeyPrefix + ip
+}
+
+// BanIP 临时封禁 IP，到期后自动解除
+func BanIP(ctx context.Context, ip string, duration time.Duration) error {
+	if err := cache.Default.Set(ctx, tempBanKey(ip), "true", duration); err != nil {
+		return err
+	}
+	logger.Warn("IP temporarily banned",
+		zap.String("ip", ip),
+		zap.Duration("duration", duration))
+	return nil
+}
+
+// BanIPHandler 处理临时封禁 IP 的请求，duration 为 Go 时间格式（如 "10m"）
+func BanIPHandler(c *gin.Context) {
+	var request struct {
+		IP       string `json:"ip" binding:"required"`
+		Duration string `json:"duration" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: ip and duration are required"})
+		return
+	}
+	if net.ParseIP(request.IP) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address: " + request.IP})
+		return
+	}
+	duration, err := time.ParseDuration(request.Duration)
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration: " + request.Duration})
+		return
+	}
+
+	if err := BanIP(c.Request.Context(), request.IP, duration); err != nil {
+		logger.Error("Failed to ban IP",
+			zap.String("ip", request.IP),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "IP banned temporarily: " + request.IP,
+		"expiresAt": time.Now().Add(duration).Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,141 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials 用户名或密码错误
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+var (
+	verifierMu sync.RWMutex
+	verifier   CredentialVerifier = NewStaticVerifier(nil)
+)
+
+// InitLogin 根据配置初始化登录凭证校验器，配置刷新时重新调用即可替换
+func InitLogin(cfg *config.Config) {
+	v := NewCredentialVerifier(cfg)
+	verifierMu.Lock()
+	defer verifierMu.Unlock()
+	verifier = v
+}
+
+// VerifyCredentials 使用当前的凭证校验器校验用户名和密码
+func VerifyCredentials(ctx context.Context, username, password string) error {
+	verifierMu.RLock()
+	v := verifier
+	verifierMu.RUnlock()
+	return v.Verify(ctx, username, password)
+}
+
+// CredentialVerifier 校验登录凭证，凭证错误时返回 ErrInvalidCredentials，校验过程本身失败时返回其他错误
+type CredentialVerifier interface {
+	Verify(ctx context.Context, username, password string) error
+}
+
+// NewCredentialVerifier 根据 security.login.verifier 创建凭证校验器
+func NewCredentialVerifier(cfg *config.Config) CredentialVerifier {
+	if cfg.Security.Login.Verifier == "http" {
+		logger.Info("Login credentials verified by external endpoint",
+			zap.String("url", cfg.Security.Login.HTTP.URL))
+		return NewHTTPVerifier(cfg.Security.Login.HTTP)
+	}
+	if len(cfg.Security.Users) == 0 {
+		logger.Warn("No login users configured, all login attempts will be rejected")
+	} else {
+		logger.Info("Login users loaded", zap.Int("count", len(cfg.Security.Users)))
+	}
+	return NewStaticVerifier(cfg.Security.Users)
+}
+
+// HashPassword 生成密码的 bcrypt 哈希，用于填写 security.users
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// StaticVerifier 使用配置中的用户列表校验凭证，密码以 bcrypt 哈希比对
+type StaticVerifier struct {
+	users map[string][]byte
+}
+
+// dummyHash 用户不存在时参与比对的哈希，使用户存在与否的响应耗时一致
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("mini-gateway"), bcrypt.DefaultCost)
+
+// NewStaticVerifier 创建基于用户列表的凭证校验器
+func NewStaticVerifier(users []config.User) *StaticVerifier {
+	v := &StaticVerifier{users: make(map[string][]byte, len(users))}
+	for _, user := range users {
+		v.users[user.Username] = []byte(user.PasswordHash)
+	}
+	return v
+}
+
+// Verify 校验用户名和密码
+func (v *StaticVerifier) Verify(_ context.Context, username, password string) error {
+	hash, ok := v.users[username]
+	if !ok {
+		hash = dummyHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// HTTPVerifier 调用外部接口校验凭证
+type HTTPVerifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPVerifier 创建调用外部接口的凭证校验器
+func NewHTTPVerifier(cfg config.LoginHTTP) *HTTPVerifier {
+	return &HTTPVerifier{url: cfg.URL, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Verify 以 JSON 形式将用户名和密码 POST 到外部接口，2xx 表示通过，401/403 表示凭证错误
+func (v *HTTPVerifier) Verify(ctx context.Context, username, password string) error {
+	body, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("credential verification request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return ErrInvalidCredentials
+	}
+	return fmt.Errorf("credential verification endpoint returned status %d", resp.StatusCode)
+}
@@ -0,0 +1,81 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestStaticVerifier 测试基于用户列表的 bcrypt 凭证校验
+func TestStaticVerifier(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	v := NewStaticVerifier([]config.User{{Username: "alice", PasswordHash: string(hash)}})
+
+	assert.NoError(t, v.Verify(context.Background(), "alice", "s3cret"))
+	assert.ErrorIs(t, v.Verify(context.Background(), "alice", "wrong"), ErrInvalidCredentials)
+	assert.ErrorIs(t, v.Verify(context.Background(), "bob", "s3cret"), ErrInvalidCredentials)
+	assert.ErrorIs(t, NewStaticVerifier(nil).Verify(context.Background(), "admin", "password"), ErrInvalidCredentials)
+}
+
+// TestHTTPVerifier 测试外部接口校验：2xx 通过，401/403 为凭证错误，其他状态视为校验失败
+func TestHTTPVerifier(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var creds map[string]string
+		json.NewDecoder(r.Body).Decode(&creds)
+		switch {
+		case creds["username"] == "down":
+			w.WriteHeader(http.StatusInternalServerError)
+		case creds["username"] == "alice" && creds["password"] == "s3cret":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer backend.Close()
+	v := NewHTTPVerifier(config.LoginHTTP{URL: backend.URL, Timeout: time.Second})
+
+	assert.NoError(t, v.Verify(context.Background(), "alice", "s3cret"))
+	assert.ErrorIs(t, v.Verify(context.Background(), "alice", "wrong"), ErrInvalidCredentials)
+
+	err := v.Verify(context.Background(), "down", "x")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestInitLogin 测试按配置切换凭证校验器
+func TestInitLogin(t *testing.T) {
+	logger.InitTestLogger()
+	defer InitLogin(&config.Config{})
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	InitLogin(&config.Config{Security: config.Security{Users: []config.User{{Username: "admin", PasswordHash: string(hash)}}}})
+	assert.NoError(t, VerifyCredentials(context.Background(), "admin", "password"))
+
+	InitLogin(&config.Config{Security: config.Security{Login: config.Login{
+		Verifier: "http",
+		HTTP:     config.LoginHTTP{URL: "http://127.0.0.1:1", Timeout: time.Second},
+	}}})
+	err := VerifyCredentials(context.Background(), "admin", "password")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestHashPassword 测试生成的哈希可被静态校验器校验
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	assert.NoError(t, err)
+	v := NewStaticVerifier([]config.User{{Username: "alice", PasswordHash: hash}})
+	assert.NoError(t, v.Verify(context.Background(), "alice", "s3cret"))
+
+	_, err = HashPassword("")
+	assert.Error(t, err)
+}
@@ -1,14 +1,18 @@
 package traffic
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/afex/hystrix-go/hystrix"
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel"
@@ -28,77 +32,92 @@ type RequestStat struct {
 	Timestamp time.Time     // 请求完成时间
 }
 
+// windowBuckets 滑动窗口划分的桶数，窗口按桶整体滑动，统计粒度为窗口长度的 1/windowBuckets
+const windowBuckets = 10
+
 // TimeSlidingWindow 实现基于时间的滑动窗口，用于请求统计
+// 窗口划分为固定数量的桶，每个桶只保存计数和延迟总和，Update 和统计计算都是 O(1)，内存占用与 QPS 无关
 type TimeSlidingWindow struct {
-	requests []RequestStat // 最近请求	// 最近请求统计列表
-	mutex    sync.RWMutex  // 互斥锁，确保线程安全
-	duration time.Duration // 窗口持续时间
+	buckets  [windowBuckets]windowBucket
+	width    int64            // 每个桶覆盖的时长（纳秒）
+	mutex    sync.RWMutex     // 互斥锁，确保线程安全
+	duration time.Duration    // 窗口持续时间
+	now      func() time.Time // 当前时间，测试时可替换
+}
+
+// windowBucket 窗口中一个时间片内的请求统计
+type windowBucket struct {
+	slot    int64         // 桶对应的时间片序号，与当前时间片相差 windowBuckets 个及以上时已过期
+	total   int           // 请求数
+	failed  int           // 失败请求数
+	latency time.Duration // 请求延迟总和
 }
 
 // NewTimeSlidingWindow 创建新的时间滑动窗口
 func NewTimeSlidingWindow(duration time.Duration) *TimeSlidingWindow {
-	sw := &TimeSlidingWindow{
-		requests: make([]RequestStat, 0),
+	return &TimeSlidingWindow{
+		width:    max(int64(duration)/windowBuckets, 1),
 		duration: duration,
+		now:      time.Now,
 	}
-	go sw.cleanup() // 启动后台清理协程
-	return sw
 }
 
-// Update 向滑动窗口添加新的请求统计
+// Update 向滑动窗口添加新的请求统计，已移出窗口的统计被忽略
 func (sw *TimeSlidingWindow) Update(stat RequestStat) {
 	sw.mutex.Lock()
 	defer sw.mutex.Unlock()
-	sw.requests = append(sw.requests, stat)
-}
-
-// cleanup 定期清理窗口中过期的请求统计
-func (sw *TimeSlidingWindow) cleanup() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-	for range ticker.C {
-		sw.mutex.Lock()
-		now := time.Now()
-		var validRequests []RequestStat
-		for _, stat := range sw.requests {
-			if now.Sub(stat.Timestamp) <= sw.duration {
-				validRequests = append(validRequests, stat)
-			}
+
+	slot := stat.Timestamp.UnixNano() / sw.width
+	if !sw.inWindow(slot) {
+		return
+	}
+	b := &sw.buckets[slot%windowBuckets]
+	if b.slot != slot {
+		*b = windowBucket{slot: slot}
+	}
+	b.total++
+	if !stat.Success {
+		b.failed++
+	}
+	b.latency += stat.Latency
+}
+
+// inWindow 判断时间片是否仍在窗口内
+func (sw *TimeSlidingWindow) inWindow(slot int64) bool {
+	return slot > sw.now().UnixNano()/sw.width-windowBuckets
+}
+
+// sum 汇总窗口内各桶的统计
+func (sw *TimeSlidingWindow) sum() (total, failed int, latency time.Duration) {
+	sw.mutex.RLock()
+	defer sw.mutex.RUnlock()
+	for _, b := range sw.buckets {
+		if b.total == 0 || !sw.inWindow(b.slot) {
+			continue
 		}
-		sw.requests = validRequests
-		sw.mutex.Unlock()
+		total += b.total
+		failed += b.failed
+		latency += b.latency
 	}
+	return total, failed, latency
 }
 
 // ErrorRate 计算窗口内的当前错误率
 func (sw *TimeSlidingWindow) ErrorRate() float64 {
-	sw.mutex.RLock()
-	defer sw.mutex.RUnlock()
-	if len(sw.requests) == 0 {
+	total, failed, _ := sw.sum()
+	if total == 0 {
 		return 0
 	}
-	var total, failed int
-	for _, stat := range sw.requests {
-		total++
-		if !stat.Success {
-			failed++
-		}
-	}
 	return float64(failed) / float64(total)
 }
 
 // AvgLatency 计算窗口内请求的平均延迟
 func (sw *TimeSlidingWindow) AvgLatency() time.Duration {
-	sw.mutex.RLock()
-	defer sw.mutex.RUnlock()
-	if len(sw.requests) == 0 {
+	total, _, latency := sw.sum()
+	if total == 0 {
 		return 0
 	}
-	var totalLatency time.Duration
-	for _, stat := range sw.requests {
-		totalLatency += stat.Latency
-	}
-	return totalLatency / time.Duration(len(sw.requests))
+	return latency / time.Duration(total)
 }
 
 // Prometheus 指标用于熔断器可观测性
@@ -127,7 +146,169 @@ func init() {
 	prometheus.MustRegister(errorRateGauge, latencyGauge)
 }
 
+// targetCircuits 按路由和目标划分的熔断器，同一路由的各目标独立熔断，一个后端故障不影响该路由的其他后端
+// 配置中的目标在创建时配置熔断参数，其他目标（如服务发现返回的目标）在首次被选中时配置
+type targetCircuits struct {
+	settings   config.TrafficBreaker
+	configured sync.Map // 熔断器名称 -> 所属路由
+	states     sync.Map // 熔断器名称 -> *atomic.Int32，最近一次观察到的 breakerState
+}
+
+// breakerState 熔断器状态，取值与 gateway_breaker_state 指标一致
+type breakerState int32
+
+const (
+	breakerClosed   breakerState = iota // 关闭，请求正常转发
+	breakerOpen                         // 打开，请求直接降级
+	breakerHalfOpen                     // 半开，休眠窗口结束后放行单个探测请求
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// activeCircuits 当前熔断中间件使用的熔断器，供手动关闭熔断器时查找路由下的各目标
+var activeCircuits atomic.Pointer[targetCircuits]
+
+// circuitName 返回路由与目标对应的熔断器名称
+func circuitName(route, target string) string {
+	return route + "|" + target
+}
+
+// commandConfig 根据熔断器配置生成 Hystrix 命令配置
+func commandConfig(settings config.TrafficBreaker) hystrix.CommandConfig {
+	return hystrix.CommandConfig{
+		Timeout:                settings.Timeout,
+		MaxConcurrentRequests:  settings.MaxConcurrent,
+		RequestVolumeThreshold: settings.MinRequests,
+		SleepWindow:            settings.SleepWindow,
+		ErrorPercentThreshold:  int(settings.ErrorRate * 100),
+	}
+}
+
+// circuit 返回路由与目标对应的熔断器名称，首次使用时按配置创建
+func (tc *targetCircuits) circuit(route, target string) string {
+	name := circuitName(route, target)
+	if _, loaded := tc.configured.LoadOrStore(name, route); !loaded {
+		hystrix.ConfigureCommand(name, commandConfig(tc.settings))
+		// 配置重新加载时熔断器沿用原有状态，已打开的熔断器仍记为打开
+		state := breakerClosed
+		if cb, _, err := hystrix.GetCircuit(name); err == nil && cb.IsOpen() {
+			state = breakerOpen
+		}
+		value, _ := tc.states.LoadOrStore(name, new(atomic.Int32))
+		value.(*atomic.Int32).Store(int32(state))
+		observability.BreakerState.WithLabelValues(route, target).Set(float64(state))
+	}
+	return name
+}
+
+// transition 记录观察到的熔断器状态，状态变化时更新指标并记录日志
+// Hystrix 不提供状态变化的回调，状态在请求经过熔断器时观察得到
+func (tc *targetCircuits) transition(route, target string, to breakerState) {
+	value, _ := tc.states.LoadOrStore(circuitName(route, target), new(atomic.Int32))
+	from := breakerState(value.(*atomic.Int32).Swap(int32(to)))
+	if from == to {
+		return
+	}
+	observability.BreakerState.WithLabelValues(route, target).Set(float64(to))
+	logger.Info("Circuit breaker state changed",
+		zap.String("route", route),
+		zap.String("target", target),
+		zap.Stringer("from", from),
+		zap.Stringer("to", to))
+}
+
+// breakerRoute 返回请求匹配的路由，优先使用路由引擎记录的路由模式，未匹配到配置的路由时使用请求路径
+// 熔断器在选出目标时才确定路由，此时 trie、regexp 等在中间件中匹配路由的引擎已完成匹配
+func breakerRoute(c *gin.Context, rules map[string]config.RoutingRules) string {
+	if pattern, ok := proxy.MatchedRoute(c); ok {
+		if _, exists := rules[pattern]; exists {
+			return pattern
+		}
+	}
+	if _, ok := rules[c.FullPath()]; ok {
+		return c.FullPath()
+	}
+	return c.Request.URL.Path
+}
+
+// admission 熔断器放行的一次转发，report 报告上游结果后由 wait 取得熔断器对本次转发的判定
+type admission struct {
+	probing  bool                 // 是否为熔断器半开时放行的探测请求
+	outcomes chan upstreamOutcome // 上游结果，只接收第一次报告
+	result   chan error           // Hystrix 命令的结果
+}
+
+// upstreamOutcome 一次转发的上游结果
+type upstreamOutcome struct {
+	status    int  // 上游状态码，0 表示未收到上游响应
+	forwarded bool // 请求是否到达上游
+}
+
+// report 报告上游结果，熔断超时只计算到此时为止；可重复调用，只有第一次生效
+func (a *admission) report(status int, forwarded bool) {
+	select {
+	case a.outcomes <- upstreamOutcome{status: status, forwarded: forwarded}:
+	default:
+	}
+}
+
+// wait 等待熔断器对本次转发的判定，上游失败或超时时返回错误，须在 report 之后调用
+func (a *admission) wait() error {
+	return <-a.result
+}
+
+// admit 在路由与目标对应的熔断器中申请转发，熔断器打开、并发超限或等待执行超时时返回错误
+// 放行后 Hystrix 命令一直等到上游结果报告为止，命令超时只作用于建立连接并等待上游响应头的阶段，
+// SSE、分块等流式响应体的传输不受熔断超时限制
+func (tc *targetCircuits) admit(route, target string) (*admission, error) {
+	name := tc.circuit(route, target)
+	cb, _, _ := hystrix.GetCircuit(name)
+	a := &admission{
+		outcomes: make(chan upstreamOutcome, 1),
+		result:   make(chan error, 1),
+	}
+	admitted := make(chan struct{})
+	go func() {
+		a.result <- hystrix.Do(name, func() error {
+			// 熔断器打开时仍被放行的请求是休眠窗口结束后的探测请求，此时熔断器处于半开状态
+			if cb != nil && cb.IsOpen() {
+				a.probing = true
+				tc.transition(route, target, breakerHalfOpen)
+			}
+			close(admitted)
+			// 只有上游失败才计为熔断器的错误
+			if outcome := <-a.outcomes; outcome.forwarded && !upstreamSucceeded(outcome.status) {
+				return fmt.Errorf("upstream failed with status %d", outcome.status)
+			}
+			return nil
+		}, nil)
+	}()
+
+	select {
+	case <-admitted:
+		return a, nil
+	case err := <-a.result:
+		// 未放行的请求不会转发，命令若在超时后才开始执行则立即结束
+		a.report(0, false)
+		if errors.Is(err, hystrix.ErrCircuitOpen) {
+			tc.transition(route, target, breakerOpen)
+		}
+		return nil, err
+	}
+}
+
 // Breaker 返回用于熔断和降级的 Gin 中间件
+// 熔断器按路由和负载均衡选出的目标划分，代理选出目标后经由中间件设置的目标保护函数转发，见 proxy.GuardTarget；
+// 扇出等同时转发到多个目标的请求经由目标准入函数逐个判断，见 proxy.AdmitTarget
 func Breaker() gin.HandlerFunc {
 	cfg := config.GetConfig()
 	if !cfg.Middleware.Breaker || !cfg.Traffic.Breaker.Enabled {
@@ -136,49 +317,41 @@ func Breaker() gin.HandlerFunc {
 		}
 	}
 
-	// 为每个路由配置 Hystrix
-	for path := range cfg.Routing.Rules {
-		hystrix.ConfigureCommand(path, hystrix.CommandConfig{
-			Timeout:                cfg.Traffic.Breaker.Timeout,
-			MaxConcurrentRequests:  cfg.Traffic.Breaker.MaxConcurrent,
-			RequestVolumeThreshold: cfg.Traffic.Breaker.MinRequests,
-			SleepWindow:            cfg.Traffic.Breaker.SleepWindow,
-			ErrorPercentThreshold:  int(cfg.Traffic.Breaker.ErrorRate * 100),
-		})
+	// 为每个路由的每个目标配置 Hystrix
+	circuits := &targetCircuits{settings: cfg.Traffic.Breaker}
+	for path, rules := range cfg.Routing.Rules {
+		for _, rule := range rules {
+			circuits.circuit(path, rule.Target)
+		}
 	}
+	activeCircuits.Store(circuits)
 
 	// 初始化时间滑动窗口用于请求统计
 	window := NewTimeSlidingWindow(time.Duration(cfg.Traffic.Breaker.WindowDuration) * time.Second)
 
-	return func(c *gin.Context) {
-		// 开始追踪熔断器检查
-		_, span := breakerTimeSlidingTracer.Start(c.Request.Context(), "Breaker.Check",
-			trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
-		defer span.End()
-
-		start := time.Now()
-		path := c.Request.URL.Path
+	// settle 记录一次转发的结果：更新熔断器状态、滑动窗口统计与指标
+	settle := func(path, route, target string, a *admission, start time.Time, outcome upstreamOutcome) {
+		success := outcome.forwarded && upstreamSucceeded(outcome.status)
+		switch {
+		case a.probing:
+			// 探测成功后 Hystrix 关闭熔断器，失败则保持打开并重新开始休眠窗口
+			result, next := "failure", breakerOpen
+			if success {
+				result, next = "success", breakerClosed
+			}
+			observability.BreakerProbes.WithLabelValues(route, result).Inc()
+			circuits.transition(route, target, next)
+		case outcome.forwarded:
+			circuits.transition(route, target, breakerClosed)
+		}
 
-		// 在 Hystrix 熔断器中执行请求
-		err := hystrix.Do(path, func() error {
-			c.Next() // 处理下游请求
-			return c.Err()
-		}, func(err error) error {
-			// 熔断打开时的回退逻辑
-			logger.Warn("Circuit breaker triggered for route",
-				zap.String("path", path),
-				zap.Error(err))
-			span.SetStatus(codes.Error, "Circuit breaker open")
-			span.SetAttributes(attribute.String("breakerState", "open"))
-			observability.BreakerTrips.WithLabelValues(path).Inc()
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable"})
-			c.Abort()
-			return nil // 表示回退已处理错误
-		})
+		// 熔断降级等由网关自身产生的响应不反映上游健康度，不计入统计
+		if !outcome.forwarded {
+			return
+		}
 
 		// 在滑动窗口中记录请求统计
 		latency := time.Since(start)
-		success := err == nil && c.Writer.Status() < http.StatusBadRequest
 		window.Update(RequestStat{
 			Success:   success,
 			Latency:   latency,
@@ -190,16 +363,100 @@ func Breaker() gin.HandlerFunc {
 		avgLatency := window.AvgLatency()
 		errorRateGauge.WithLabelValues(path).Set(errorRate)
 		latencyGauge.WithLabelValues(path).Set(float64(avgLatency) / float64(time.Second))
-		span.SetStatus(codes.Ok, "Request processed successfully")
 
 		// 记录请求统计用于调试
 		logger.Debug("Updated request statistics",
 			zap.String("path", path),
+			zap.String("target", target),
 			zap.Bool("success", success),
 			zap.Duration("latency", latency),
 			zap.Float64("errorRate", errorRate),
 			zap.Duration("avgLatency", avgLatency))
 	}
+
+	return func(c *gin.Context) {
+		// 开始追踪熔断器检查
+		_, span := breakerTimeSlidingTracer.Start(c.Request.Context(), "Breaker.Check",
+			trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
+		defer span.End()
+
+		path := c.Request.URL.Path
+		proxy.SetTargetGuard(c, func(target string, forward func()) {
+			start := time.Now()
+			route := breakerRoute(c, config.GetConfig().Routing.Rules)
+			span.SetAttributes(attribute.String("target", target))
+
+			a, err := circuits.admit(route, target)
+			if err == nil {
+				// 收到上游响应头时即报告结果，流式响应体在请求协程中继续转发
+				proxy.OnUpstreamResponse(c, func() {
+					a.report(proxy.UpstreamStatus(c))
+				})
+				forward()
+				a.report(proxy.UpstreamStatus(c))
+				err = a.wait()
+			}
+
+			// 转发与降级响应都在请求协程中写出；上游失败或超时时响应已经写出，只需计入熔断统计
+			if err != nil && !c.Writer.Written() {
+				logger.Warn("Circuit breaker triggered for target",
+					zap.String("path", path),
+					zap.String("route", route),
+					zap.String("target", target),
+					zap.Error(err))
+				span.SetStatus(codes.Error, "Circuit breaker open")
+				span.SetAttributes(attribute.String("breakerState", "open"))
+				observability.BreakerTrips.WithLabelValues(path).Inc()
+				// 按路由配置的降级策略返回缓存的最近一次成功响应或固定响应，策略为 error 或未配置时返回 503
+				rules, _ := proxy.FindRouteRules(c, config.GetConfig().Routing.Rules)
+				if !proxy.WriteFallback(c, rules) {
+					proxy.WriteError(c, http.StatusServiceUnavailable, proxy.ErrCodeCircuitOpen, "Service temporarily unavailable")
+				}
+				c.Abort()
+			}
+			if a == nil {
+				return
+			}
+
+			status, forwarded := proxy.UpstreamStatus(c)
+			settle(path, route, target, a, start, upstreamOutcome{status: status, forwarded: forwarded})
+			if !forwarded {
+				return
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "Upstream request failed")
+			} else {
+				span.SetStatus(codes.Ok, "Request processed successfully")
+			}
+		})
+		proxy.SetTargetAdmission(c, func(target string) (func(status int), bool) {
+			start := time.Now()
+			route := breakerRoute(c, config.GetConfig().Routing.Rules)
+			a, err := circuits.admit(route, target)
+			if err != nil {
+				logger.Warn("Circuit breaker rejected target",
+					zap.String("path", path),
+					zap.String("route", route),
+					zap.String("target", target),
+					zap.Error(err))
+				observability.BreakerTrips.WithLabelValues(path).Inc()
+				return nil, false
+			}
+			return func(status int) {
+				a.report(status, true)
+				a.wait()
+				settle(path, route, target, a, start, upstreamOutcome{status: status, forwarded: true})
+			}, true
+		})
+		// 限流、认证失败等在选出目标之前结束的请求不经过目标保护函数，不计入熔断统计
+		c.Next()
+	}
+}
+
+// upstreamSucceeded 判断上游结果是否成功，status 为 0 表示未收到上游响应
+func upstreamSucceeded(status int) bool {
+	return status != 0 && status < http.StatusBadRequest
 }
 
 // DisableBreakerHandler 处理关闭指定路径熔断器的请求
@@ -221,15 +478,23 @@ func DisableBreakerHandler(c *gin.Context) {
 		return
 	}
 
-	// 强制关闭熔断器
+	// 强制关闭该路由下各目标的熔断器
 	// Hystrix 不提供直接关闭熔断器的 API，可以通过重置统计数据来间接实现
-	hystrix.ConfigureCommand(request.Path, hystrix.CommandConfig{
+	disabled := hystrix.CommandConfig{
 		Timeout:                cfg.Traffic.Breaker.Timeout,
 		MaxConcurrentRequests:  cfg.Traffic.Breaker.MaxConcurrent,
 		RequestVolumeThreshold: cfg.Traffic.Breaker.MinRequests,
 		SleepWindow:            cfg.Traffic.Breaker.SleepWindow,
 		ErrorPercentThreshold:  0, // 将错误阈值设为 0，避免触发熔断
-	})
+	}
+	if circuits := activeCircuits.Load(); circuits != nil {
+		circuits.configured.Range(func(name, route any) bool {
+			if route == request.Path {
+				hystrix.ConfigureCommand(name.(string), disabled)
+			}
+			return true
+		})
+	}
 
 	// 记录操作
 	logger.Info("Circuit breaker disabled manually",
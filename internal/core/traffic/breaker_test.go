@@ -3,14 +3,21 @@ package traffic
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/afex/hystrix-go/hystrix"
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,15 +39,28 @@ func newBreakerTestConfig() *config.Config {
 		},
 		Routing: config.Routing{
 			Rules: map[string]config.RoutingRules{
-				"/test": nil,
+				"/test": {{Target: breakerTestTarget}},
 			},
 		},
 	}
 }
 
+// breakerTestTarget 测试路由的上游目标
+const breakerTestTarget = "http://127.0.0.1:8381"
+
+// forwardTo 模拟代理选出目标后经由熔断器转发，上游返回 status
+func forwardTo(c *gin.Context, target string, status int) {
+	proxy.GuardTarget(c, target, func() {
+		proxy.SetUpstreamStatus(c, status)
+		c.String(status, http.StatusText(status))
+	})
+}
+
 // initBreakerTestConfig 初始化测试配置
 func initBreakerTestConfig() {
 	// 如果 config 包没有 SetConfig 方法，请确保在测试中能够正确设置全局配置
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
 	config.SetConfig(newBreakerTestConfig())
 }
 
@@ -71,15 +91,84 @@ func TestTimeSlidingWindow_Cleanup(t *testing.T) {
 	// 使用 2 秒窗口，便于测试：过期记录为 3 秒前，近期记录为当前时间
 	window := NewTimeSlidingWindow(2 * time.Second)
 	now := time.Now()
+	window.now = func() time.Time { return now }
 	// 添加一个过期的请求统计（3 秒前）
 	window.Update(RequestStat{Success: true, Latency: 100 * time.Millisecond, Timestamp: now.Add(-3 * time.Second)})
 	// 添加一个近期的请求统计（当前）
 	window.Update(RequestStat{Success: false, Latency: 200 * time.Millisecond, Timestamp: now})
-	// 等待 2 秒让后台清理协程运行
-	time.Sleep(2 * time.Second)
 	// 此时窗口内仅应保留近期记录，错误率为 1（即失败率 100%）
 	errorRate := window.ErrorRate()
 	assert.Equal(t, 1.0, errorRate, "清理后错误率应反映仅近期失败请求")
+
+	// 2 秒后近期记录也移出窗口
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, 0.0, window.ErrorRate())
+	assert.Equal(t, time.Duration(0), window.AvgLatency())
+}
+
+// TestTimeSlidingWindow_SlidesByBucket 验证窗口按桶滑动，旧桶被新时间片复用时不残留统计
+func TestTimeSlidingWindow_SlidesByBucket(t *testing.T) {
+	window := NewTimeSlidingWindow(time.Second)
+	now := time.Unix(1000, 0)
+	window.now = func() time.Time { return now }
+
+	window.Update(RequestStat{Success: false, Timestamp: now})
+	now = now.Add(500 * time.Millisecond)
+	window.Update(RequestStat{Success: true, Timestamp: now})
+	assert.Equal(t, 0.5, window.ErrorRate())
+
+	// 第一个请求所在的桶移出窗口后被新请求复用
+	now = now.Add(500 * time.Millisecond)
+	window.Update(RequestStat{Success: true, Timestamp: now})
+	assert.Equal(t, 0.0, window.ErrorRate())
+}
+
+// sliceWindow 逐条保存请求统计的滑动窗口，每次计算遍历全部记录，作为基准测试的对照
+type sliceWindow struct {
+	requests []RequestStat
+	mutex    sync.RWMutex
+}
+
+func (sw *sliceWindow) Update(stat RequestStat) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	sw.requests = append(sw.requests, stat)
+}
+
+func (sw *sliceWindow) ErrorRate() float64 {
+	sw.mutex.RLock()
+	defer sw.mutex.RUnlock()
+	if len(sw.requests) == 0 {
+		return 0
+	}
+	var failed int
+	for _, stat := range sw.requests {
+		if !stat.Success {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(sw.requests))
+}
+
+// 模拟熔断中间件每个请求的操作：记录一次统计并计算错误率，b.N 相当于 1 秒内的请求数
+func BenchmarkTimeSlidingWindow(b *testing.B) {
+	window := NewTimeSlidingWindow(10 * time.Second)
+	now := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		window.Update(RequestStat{Success: i%10 != 0, Latency: time.Millisecond, Timestamp: now})
+		window.ErrorRate()
+	}
+}
+
+func BenchmarkSliceWindow(b *testing.B) {
+	window := &sliceWindow{}
+	now := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		window.Update(RequestStat{Success: i%10 != 0, Latency: time.Millisecond, Timestamp: now})
+		window.ErrorRate()
+	}
 }
 
 //
@@ -113,30 +202,454 @@ func TestBreakerMiddleware_Fallback(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	initBreakerTestConfig()
 
-	// 为测试目的，配置一个较短超时的 Hystrix 命令，便于触发回退
-	hystrix.ConfigureCommand("/test", hystrix.CommandConfig{
+	router := gin.New()
+	router.Use(Breaker())
+	t.Cleanup(hystrix.Flush) // 熔断器状态是全局的，避免影响其他测试
+
+	// 为测试目的，配置一个较短超时的 Hystrix 命令，便于触发回退（Breaker 会按配置重新设置命令，需在其后配置）
+	hystrix.ConfigureCommand(circuitName("/test", breakerTestTarget), hystrix.CommandConfig{
 		Timeout:                100, // 100ms 超时
 		MaxConcurrentRequests:  1,
 		RequestVolumeThreshold: 1,
 		SleepWindow:            500,
 		ErrorPercentThreshold:  1,
 	})
-
-	router := gin.New()
-	router.Use(Breaker())
-	// 模拟处理过程中返回错误
+	// 模拟上游返回错误
 	router.GET("/test", func(c *gin.Context) {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("test error"))
+		proxy.GuardTarget(c, breakerTestTarget, func() {
+			proxy.SetUpstreamStatus(c, http.StatusInternalServerError)
+			c.AbortWithError(http.StatusInternalServerError, errors.New("test error"))
+		})
 	})
 
+	// 上游失败的响应原样返回，并计入熔断统计使熔断器打开
 	req, _ := http.NewRequest("GET", "/test", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "上游失败的响应应原样返回")
+	circuit, _, err := hystrix.GetCircuit(circuitName("/test", breakerTestTarget))
+	assert.NoError(t, err)
+	assert.Eventually(t, circuit.IsOpen, time.Second, 10*time.Millisecond, "上游失败应使熔断器打开")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
 
 	// 当熔断触发时，回退逻辑应返回 503
 	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "熔断回退应返回 503")
 	var body map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &body)
+	err = json.Unmarshal(w.Body.Bytes(), &body)
 	assert.NoError(t, err, "返回的 JSON 应合法")
-	assert.Equal(t, "Service temporarily unavailable", body["error"], "回退返回的错误信息应正确")
+	assert.Equal(t, proxy.ErrCodeCircuitOpen, body["code"], "回退返回的错误码应正确")
+	assert.Equal(t, "Service temporarily unavailable", body["message"], "回退返回的错误信息应正确")
+}
+
+// TestBreakerMiddleware_RouteFallback 验证熔断打开时优先返回路由配置的降级响应
+func TestBreakerMiddleware_RouteFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
+	cfg := newBreakerTestConfig()
+	cfg.Routing.Rules["/maintenance"] = config.RoutingRules{{
+		Target:   "http://127.0.0.1:8381",
+		Fallback: config.Fallback{Body: `{"message":"under maintenance"}`},
+	}}
+	config.SetConfig(cfg)
+
+	router := gin.New()
+	router.Use(Breaker())
+	router.GET("/maintenance", func(c *gin.Context) {
+		forwardTo(c, "http://127.0.0.1:8381", http.StatusOK)
+	})
+	t.Cleanup(hystrix.Flush)
+
+	// 上报失败事件使熔断器打开
+	hystrix.ConfigureCommand(circuitName("/maintenance", "http://127.0.0.1:8381"), hystrix.CommandConfig{
+		Timeout:                1000,
+		RequestVolumeThreshold: 1,
+		SleepWindow:            5000,
+		ErrorPercentThreshold:  1,
+	})
+	circuit, _, err := hystrix.GetCircuit(circuitName("/maintenance", "http://127.0.0.1:8381"))
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		circuit.ReportEvent([]string{"failure"}, time.Now(), 0)
+	}
+	assert.Eventually(t, circuit.IsOpen, time.Second, 10*time.Millisecond, "熔断器应已打开")
+
+	req, _ := http.NewRequest("GET", "/maintenance", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "降级响应默认返回 503")
+	assert.Equal(t, `{"message":"under maintenance"}`, w.Body.String(), "应返回路由配置的降级响应体")
+}
+
+// TestBreakerMiddleware_IgnoresRateLimitRejections 验证网关限流返回的 429 不计入熔断统计
+func TestBreakerMiddleware_IgnoresRateLimitRejections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
+	cfg := newBreakerTestConfig()
+	cfg.Routing.Rules["/limited"] = nil
+	cfg.Traffic.RateLimit = config.TrafficRateLimit{Enabled: true, QPS: 1, Burst: 2, Algorithm: "leaky_bucket"}
+	config.SetConfig(cfg)
+
+	router := gin.New()
+	router.Use(Breaker(), LeakyBucketRateLimit())
+	router.GET("/limited", func(c *gin.Context) {
+		forwardTo(c, breakerTestTarget, http.StatusOK)
+	})
+	t.Cleanup(hystrix.Flush)
+	hystrix.ConfigureCommand(circuitName("/limited", breakerTestTarget), hystrix.CommandConfig{
+		Timeout:                1000,
+		RequestVolumeThreshold: 1,
+		SleepWindow:            5000,
+		ErrorPercentThreshold:  1,
+	})
+
+	rejected := 0
+	for i := 0; i < 20; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/limited", nil))
+		if w.Code == http.StatusTooManyRequests {
+			rejected++
+		}
+	}
+	assert.Greater(t, rejected, 10, "大部分请求应被限流")
+
+	circuit, _, err := hystrix.GetCircuit(circuitName("/limited", breakerTestTarget))
+	assert.NoError(t, err)
+	assert.Never(t, circuit.IsOpen, 200*time.Millisecond, 20*time.Millisecond, "限流拒绝不应使熔断器打开")
+	assert.Equal(t, 0.0, testutil.ToFloat64(errorRateGauge.WithLabelValues("/limited")), "限流拒绝不应计入错误率")
+}
+
+// TestBreakerMiddleware_IsolatesFailingTarget 验证熔断器按目标划分，同一路由的一个后端故障不影响其他后端
+func TestBreakerMiddleware_IsolatesFailingTarget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
+	cfg := newBreakerTestConfig()
+	cfg.Traffic.Breaker.MinRequests = 1
+	cfg.Traffic.Breaker.ErrorRate = 0.01
+	const good, bad = "http://127.0.0.1:8381", "http://127.0.0.1:8382"
+	cfg.Routing.Rules["/multi"] = config.RoutingRules{{Target: good}, {Target: bad}}
+	config.SetConfig(cfg)
+
+	router := gin.New()
+	router.Use(Breaker())
+	t.Cleanup(hystrix.Flush)
+	// 按查询参数模拟负载均衡选出的目标，bad 目标总是返回 502
+	router.GET("/multi", func(c *gin.Context) {
+		target := c.Query("target")
+		status := http.StatusOK
+		if target == bad {
+			status = http.StatusBadGateway
+		}
+		forwardTo(c, target, status)
+	})
+	request := func(target string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/multi?target="+target, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadGateway, request(bad))
+	badCircuit, _, err := hystrix.GetCircuit(circuitName("/multi", bad))
+	assert.NoError(t, err)
+	assert.Eventually(t, badCircuit.IsOpen, time.Second, 10*time.Millisecond, "故障目标的熔断器应打开")
+	assert.Equal(t, http.StatusServiceUnavailable, request(bad), "故障目标应被熔断")
+
+	goodCircuit, _, err := hystrix.GetCircuit(circuitName("/multi", good))
+	assert.NoError(t, err)
+	assert.False(t, goodCircuit.IsOpen(), "同一路由的其他目标不应被熔断")
+	assert.Equal(t, http.StatusOK, request(good))
+}
+
+// TestBreakerMiddleware_FallbackStrategy 验证熔断时按路由配置的降级策略返回响应
+func TestBreakerMiddleware_FallbackStrategy(t *testing.T) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		fallback   config.Fallback
+		cached     string // 缓存中保留的最近一次成功响应，为空表示没有
+		wantStatus int
+		wantBody   string
+	}{
+		{"error", config.Fallback{Strategy: config.FallbackStrategyError, Body: "ignored"},
+			"last good", http.StatusServiceUnavailable, `"code":"CIRCUIT_OPEN"`},
+		{"static", config.Fallback{Strategy: config.FallbackStrategyStatic, Body: "maintenance"},
+			"last good", http.StatusServiceUnavailable, "maintenance"},
+		{"cached", config.Fallback{Strategy: config.FallbackStrategyCached, Body: "maintenance"},
+			"last good", http.StatusOK, "last good"},
+		{"cached miss uses static", config.Fallback{Strategy: config.FallbackStrategyCached, Body: "maintenance"},
+			"", http.StatusServiceUnavailable, "maintenance"},
+		{"cached miss without static", config.Fallback{Strategy: config.FallbackStrategyCached},
+			"", http.StatusServiceUnavailable, `"code":"CIRCUIT_OPEN"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.InitTestConfigManager()
+			cfg := newBreakerTestConfig()
+			cfg.Routing.Rules["/test"][0].Fallback = tt.fallback
+			config.SetConfig(cfg)
+			t.Cleanup(hystrix.Flush)
+
+			router := gin.New()
+			// 模拟缓存中间件提供最近一次成功响应
+			router.Use(func(c *gin.Context) {
+				proxy.SetCachedFallback(c, func(c *gin.Context) bool {
+					if tt.cached == "" {
+						return false
+					}
+					c.String(http.StatusOK, tt.cached)
+					return true
+				})
+			})
+			router.Use(Breaker())
+			router.GET("/test", func(c *gin.Context) {
+				forwardTo(c, breakerTestTarget, http.StatusOK)
+			})
+
+			name := circuitName("/test", breakerTestTarget)
+			hystrix.ConfigureCommand(name, hystrix.CommandConfig{
+				Timeout:                1000,
+				RequestVolumeThreshold: 1,
+				SleepWindow:            5000,
+				ErrorPercentThreshold:  1,
+			})
+			circuit, _, err := hystrix.GetCircuit(name)
+			assert.NoError(t, err)
+			for i := 0; i < 3; i++ {
+				circuit.ReportEvent([]string{"failure"}, time.Now(), 0)
+			}
+			assert.Eventually(t, circuit.IsOpen, time.Second, 10*time.Millisecond, "熔断器应已打开")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+// TestBreakerMiddleware_StateTransitions 验证熔断器打开、半开探测和关闭时更新状态指标
+func TestBreakerMiddleware_StateTransitions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
+	cfg := newBreakerTestConfig()
+	cfg.Traffic.Breaker.MinRequests = 1
+	cfg.Traffic.Breaker.ErrorRate = 0.01
+	cfg.Traffic.Breaker.SleepWindow = 50 // 毫秒
+	const target = "http://127.0.0.1:8381"
+	cfg.Routing.Rules["/probe"] = config.RoutingRules{{Target: target}}
+	config.SetConfig(cfg)
+
+	router := gin.New()
+	router.Use(Breaker())
+	t.Cleanup(hystrix.Flush)
+	upstream := http.StatusBadGateway
+	router.GET("/probe", func(c *gin.Context) {
+		forwardTo(c, target, upstream)
+	})
+	request := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/probe", nil))
+		return w.Code
+	}
+	state := func() float64 {
+		return testutil.ToFloat64(observability.BreakerState.WithLabelValues("/probe", target))
+	}
+	probes := func(result string) float64 {
+		return testutil.ToFloat64(observability.BreakerProbes.WithLabelValues("/probe", result))
+	}
+	successes, failures := probes("success"), probes("failure")
+
+	assert.Equal(t, float64(breakerClosed), state())
+	assert.Equal(t, http.StatusBadGateway, request())
+	circuit, _, err := hystrix.GetCircuit(circuitName("/probe", target))
+	assert.NoError(t, err)
+	assert.Eventually(t, circuit.IsOpen, time.Second, 10*time.Millisecond, "熔断器应已打开")
+	assert.Equal(t, http.StatusServiceUnavailable, request())
+	assert.Equal(t, float64(breakerOpen), state())
+
+	// 休眠窗口结束后放行一个探测请求，探测失败时熔断器保持打开
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, http.StatusBadGateway, request())
+	assert.Equal(t, float64(breakerOpen), state())
+	assert.Equal(t, failures+1, probes("failure"))
+
+	// 探测成功后熔断器关闭
+	upstream = http.StatusOK
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, request())
+	assert.Equal(t, float64(breakerClosed), state())
+	assert.Equal(t, successes+1, probes("success"))
+}
+
+// TestBreakerMiddleware_StreamingOutlastsTimeout 验证流式响应体的传输时间不受熔断超时限制
+// 上游在超时内返回响应头，之后持续推送超过超时时间，客户端应收到完整的响应且不计为熔断失败
+func TestBreakerMiddleware_StreamingOutlastsTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer upstream.Close()
+
+	for _, usePool := range []bool{false, true} {
+		t.Run(fmt.Sprintf("pool=%v", usePool), func(t *testing.T) {
+			cfg := newBreakerTestConfig()
+			cfg.Traffic.Breaker.Timeout = 100 // 毫秒，短于整个响应的传输时间
+			cfg.Traffic.Breaker.MinRequests = 1
+			cfg.Traffic.Breaker.ErrorRate = 0.01
+			cfg.Routing.LoadBalancer = "round_robin"
+			cfg.Routing.Rules = map[string]config.RoutingRules{"/events": {{Target: upstream.URL, Protocol: "http"}}}
+			cfg.Performance.HttpPoolEnabled = usePool
+			config.InitTestConfigManager()
+			config.SetConfig(cfg)
+			t.Cleanup(hystrix.Flush)
+
+			router := gin.New()
+			router.Use(Breaker())
+			router.GET("/events", proxy.NewHTTPProxy(cfg).CreateHTTPHandler(cfg.Routing.Rules["/events"]))
+			gateway := httptest.NewServer(router)
+			defer gateway.Close()
+
+			for i := 0; i < 2; i++ {
+				resp, err := http.Get(gateway.URL + "/events")
+				assert.NoError(t, err)
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, "data: 0\n\ndata: 1\n\ndata: 2\n\ndata: 3\n\n", string(body), "流式响应应完整转发")
+			}
+			circuit, _, err := hystrix.GetCircuit(circuitName("/events", upstream.URL))
+			assert.NoError(t, err)
+			assert.False(t, circuit.IsOpen(), "超过熔断超时的流式响应不应计为失败")
+		})
+	}
+}
+
+// TestBreakerMiddleware_SlowHeadersCountAsTimeout 验证等待上游响应头超过熔断超时仍计为失败
+func TestBreakerMiddleware_SlowHeadersCountAsTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initBreakerTestConfig()
+
+	router := gin.New()
+	router.Use(Breaker())
+	t.Cleanup(hystrix.Flush)
+	hystrix.ConfigureCommand(circuitName("/test", breakerTestTarget), hystrix.CommandConfig{
+		Timeout:                50,
+		MaxConcurrentRequests:  10,
+		RequestVolumeThreshold: 1,
+		SleepWindow:            5000,
+		ErrorPercentThreshold:  1,
+	})
+	router.GET("/test", func(c *gin.Context) {
+		proxy.GuardTarget(c, breakerTestTarget, func() {
+			time.Sleep(150 * time.Millisecond)
+			proxy.SetUpstreamStatus(c, http.StatusOK)
+			c.String(http.StatusOK, "slow")
+		})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "超时前已转发的请求由请求协程写出上游响应")
+	circuit, _, err := hystrix.GetCircuit(circuitName("/test", breakerTestTarget))
+	assert.NoError(t, err)
+	assert.Eventually(t, circuit.IsOpen, time.Second, 10*time.Millisecond, "等待响应头超时应计为失败")
+}
+
+// TestBreakerMiddleware_KeysOnMatchedRoute 验证在中间件中匹配路由的引擎（trie、regexp 等）下熔断器按匹配到的路由模式划分，
+// 并返回该路由配置的降级响应
+func TestBreakerMiddleware_KeysOnMatchedRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
+	cfg := newBreakerTestConfig()
+	cfg.Traffic.Breaker.MinRequests = 1
+	cfg.Traffic.Breaker.ErrorRate = 0.01
+	cfg.Routing.Rules["/users/:id"] = config.RoutingRules{{
+		Target:   breakerTestTarget,
+		Fallback: config.Fallback{Strategy: config.FallbackStrategyStatic, Body: "user service down"},
+	}}
+	config.SetConfig(cfg)
+
+	router := gin.New()
+	// 模拟 trie 引擎：熔断中间件之后的中间件完成路由匹配并转发，c.FullPath() 为空
+	router.Use(Breaker(), func(c *gin.Context) {
+		proxy.SetMatchedRoute(c, "/users/:id")
+		forwardTo(c, breakerTestTarget, http.StatusBadGateway)
+	})
+	t.Cleanup(hystrix.Flush)
+
+	for _, path := range []string{"/users/1", "/users/2"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	}
+	circuit, _, err := hystrix.GetCircuit(circuitName("/users/:id", breakerTestTarget))
+	assert.NoError(t, err)
+	assert.Eventually(t, circuit.IsOpen, time.Second, 10*time.Millisecond, "同一路由模式下的不同路径应共用熔断器")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/3", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "路由模式的熔断器打开后其他路径也应被熔断")
+	assert.Equal(t, "user service down", w.Body.String(), "应返回匹配到的路由配置的降级响应")
+}
+
+// TestBreakerMiddleware_AdmitTarget 验证同时转发到多个目标的请求（如扇出）经由目标准入函数按目标熔断
+func TestBreakerMiddleware_AdmitTarget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	config.InitTestConfigManager()
+	cfg := newBreakerTestConfig()
+	cfg.Traffic.Breaker.MinRequests = 1
+	cfg.Traffic.Breaker.ErrorRate = 0.01
+	const good, bad = "http://127.0.0.1:8381", "http://127.0.0.1:8382"
+	cfg.Routing.Rules["/fanout"] = config.RoutingRules{{Target: good}, {Target: bad}}
+	config.SetConfig(cfg)
+
+	router := gin.New()
+	router.Use(Breaker())
+	t.Cleanup(hystrix.Flush)
+	// 每个请求同时转发到两个目标，bad 目标总是返回 502，响应体列出被放行的目标
+	router.GET("/fanout", func(c *gin.Context) {
+		var admitted []string
+		for _, target := range []string{good, bad} {
+			done, ok := proxy.AdmitTarget(c, target)
+			if !ok {
+				continue
+			}
+			admitted = append(admitted, target)
+			status := http.StatusOK
+			if target == bad {
+				status = http.StatusBadGateway
+			}
+			done(status)
+		}
+		c.JSON(http.StatusOK, admitted)
+	})
+	request := func() []string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/fanout", nil))
+		var admitted []string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &admitted))
+		return admitted
+	}
+
+	assert.Equal(t, []string{good, bad}, request())
+	badCircuit, _, err := hystrix.GetCircuit(circuitName("/fanout", bad))
+	assert.NoError(t, err)
+	assert.Eventually(t, badCircuit.IsOpen, time.Second, 10*time.Millisecond, "故障目标的熔断器应打开")
+	assert.Equal(t, []string{good}, request(), "熔断打开的目标不应被放行")
 }
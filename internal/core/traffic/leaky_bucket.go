@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/internal/core/security"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -34,6 +35,8 @@ type LeakyBucketLimiter struct {
 	queue    chan struct{} // 表示桶队列的通道
 	mutex    sync.Mutex    // 确保队列操作的线程安全
 	stopChan chan struct{} // 信号通道，用于停止漏出协程
+	done     chan struct{} // 漏出协程退出时关闭，不限流的限流器没有漏出协程，为 nil
+	stopOnce sync.Once     // 保证重复调用 Stop 时只关闭一次 stopChan
 }
 
 func NewMultiDimensionalLeakyBucket(cfg *config.Config) *MultiDimensionalLeakyBucket {
@@ -49,12 +52,20 @@ func NewMultiDimensionalLeakyBucket(cfg *config.Config) *MultiDimensionalLeakyBu
 	return mdl
 }
 
+// NewLeakyBucketLimiter 创建漏桶限流器，qps 或 burst 不为正数时无法限流，返回不限流的限流器
 func NewLeakyBucketLimiter(qps, burst int) *LeakyBucketLimiter {
+	if qps <= 0 || burst <= 0 {
+		logger.Warn("LeakyBucketLimiter requires positive qps and burst, rate limiting disabled for this limiter",
+			zap.Int("qps", qps),
+			zap.Int("burst", burst))
+		return &LeakyBucketLimiter{stopChan: make(chan struct{})}
+	}
 	l := &LeakyBucketLimiter{
 		capacity: burst,
 		rate:     float64(qps),
 		queue:    make(chan struct{}, burst),
 		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	go l.startLeak()
 	logger.Info("LeakyBucketLimiter initialized",
@@ -64,6 +75,7 @@ func NewLeakyBucketLimiter(qps, burst int) *LeakyBucketLimiter {
 }
 
 func (l *LeakyBucketLimiter) startLeak() {
+	defer close(l.done)
 	ticker := time.NewTicker(time.Second / time.Duration(l.rate))
 	defer ticker.Stop()
 
@@ -85,7 +97,15 @@ func (l *LeakyBucketLimiter) startLeak() {
 	}
 }
 
+// unlimited 判断限流器是否为不限流的限流器
+func (l *LeakyBucketLimiter) unlimited() bool {
+	return l.queue == nil
+}
+
 func (l *LeakyBucketLimiter) Allow() bool {
+	if l.unlimited() {
+		return true
+	}
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
@@ -97,8 +117,38 @@ func (l *LeakyBucketLimiter) Allow() bool {
 	}
 }
 
+// status 返回限流器当前状态：额度为桶容量，剩余额度为桶中空位，恢复时间为桶中请求全部漏出所需的时间
+// 不限流的限流器返回零值
+func (l *LeakyBucketLimiter) status() rateLimitStatus {
+	if l.unlimited() {
+		return rateLimitStatus{}
+	}
+	l.mutex.Lock()
+	queued := len(l.queue)
+	l.mutex.Unlock()
+	return rateLimitStatus{
+		limit:     l.capacity,
+		remaining: l.capacity - queued,
+		reset:     time.Duration(float64(queued) * float64(l.leakInterval())),
+	}
+}
+
+// leakInterval 返回漏出一个请求的间隔，即被拒绝的请求至少需要等待的时间
+func (l *LeakyBucketLimiter) leakInterval() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / l.rate)
+}
+
+// Stop 停止漏出协程并等待其退出，可重复调用
 func (l *LeakyBucketLimiter) Stop() {
-	close(l.stopChan)
+	l.stopOnce.Do(func() {
+		close(l.stopChan)
+	})
+	if l.done != nil {
+		<-l.done
+	}
 }
 
 // getOrCreateLimiter 获取或创建特定维度的限流器
@@ -128,9 +178,11 @@ func (mdl *MultiDimensionalLeakyBucket) getOrCreateLimiter(dimension, key string
 }
 
 func LeakyBucketRateLimit() gin.HandlerFunc {
-	cfg := config.GetConfig()
-	mdl := NewMultiDimensionalLeakyBucket(cfg)
+	return leakyBucketRateLimit(config.GetConfig(), NewMultiDimensionalLeakyBucket(config.GetConfig()))
+}
 
+// leakyBucketRateLimit 使用给定的多维度漏桶创建限流中间件，调用方负责通过 CleanupLeakyBucket 停止漏出协程
+func leakyBucketRateLimit(cfg *config.Config, mdl *MultiDimensionalLeakyBucket) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !cfg.Traffic.RateLimit.Enabled {
 			c.Next()
@@ -143,17 +195,17 @@ func LeakyBucketRateLimit() gin.HandlerFunc {
 
 		// 检查全局限流
 		if mdl.globalLimiter != nil && !mdl.globalLimiter.Allow() {
-			rejectRequest(c, span, "global", "", cfg.Traffic.RateLimit.QPS, cfg.Traffic.RateLimit.Burst)
+			rejectRequest(c, span, mdl.globalLimiter, "global", "", cfg.Traffic.RateLimit.QPS, cfg.Traffic.RateLimit.Burst)
 			return
 		}
 
 		// 检查IP限流（这里假设配置中增加了IP限流规则）
 		clientIP := c.ClientIP()
-		ipQPS := cfg.Traffic.RateLimit.QPS / 2 // 示例：IP限流为全局的一半
-		ipBurst := cfg.Traffic.RateLimit.Burst / 2
+		ipQPS := perIPLimit(cfg.Traffic.RateLimit.QPS)
+		ipBurst := perIPLimit(cfg.Traffic.RateLimit.Burst)
 		ipLimiter := mdl.getOrCreateLimiter("ip", clientIP, ipQPS, ipBurst)
 		if !ipLimiter.Allow() {
-			rejectRequest(c, span, "ip", clientIP, ipQPS, ipBurst)
+			rejectRequest(c, span, ipLimiter, "ip", clientIP, ipQPS, ipBurst)
 			return
 		}
 
@@ -163,16 +215,22 @@ func LeakyBucketRateLimit() gin.HandlerFunc {
 		routeBurst := cfg.Traffic.RateLimit.Burst
 		routeLimiter := mdl.getOrCreateLimiter("route", route, routeQPS, routeBurst)
 		if !routeLimiter.Allow() {
-			rejectRequest(c, span, "route", route, routeQPS, routeBurst)
+			rejectRequest(c, span, routeLimiter, "route", route, routeQPS, routeBurst)
 			return
 		}
 
+		// 响应头反映各维度中剩余额度最少的限流器
+		status := ipLimiter.status().tighter(routeLimiter.status())
+		if mdl.globalLimiter != nil {
+			status = status.tighter(mdl.globalLimiter.status())
+		}
+		setRateLimitHeaders(c, status)
 		span.SetStatus(codes.Ok, "Request allowed by leaky bucket")
 		c.Next()
 	}
 }
 
-func rejectRequest(c *gin.Context, span trace.Span, dimension, key string, qps, burst int) {
+func rejectRequest(c *gin.Context, span trace.Span, limiter *LeakyBucketLimiter, dimension, key string, qps, burst int) {
 	logger.Warn("Rate limit exceeded with leaky bucket",
 		zap.String("dimension", dimension),
 		zap.String("key", key),
@@ -182,7 +240,10 @@ func rejectRequest(c *gin.Context, span trace.Span, dimension, key string, qps,
 		zap.Int("burst", burst))
 	span.SetStatus(codes.Error, "Rate limit exceeded")
 	observability.RateLimitRejections.WithLabelValues(c.Request.URL.Path).Inc()
+	security.RecordViolation(c.Request.Context(), c.ClientIP(), security.ViolationRateLimit)
 
+	setRateLimitHeaders(c, limiter.status())
+	setRetryAfter(c, limiter.leakInterval())
 	c.JSON(http.StatusTooManyRequests, gin.H{
 		"error":     "Request rate limit exceeded",
 		"dimension": dimension,
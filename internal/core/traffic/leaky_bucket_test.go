@@ -1,6 +1,8 @@
 package traffic
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -54,6 +56,45 @@ func TestLeakyBucketLimiter(t *testing.T) {
 	assert.True(t, limiter.Allow(), "Should allow after leak")
 }
 
+func TestLeakyBucketLimiter_Stop(t *testing.T) {
+	initTest()
+	limiter := NewLeakyBucketLimiter(10, 20)
+	limiter.Stop()
+
+	select {
+	case <-limiter.done:
+	default:
+		t.Fatal("Stop should wait for the leak routine to exit")
+	}
+	assert.NotPanics(t, limiter.Stop, "Stop can be called more than once")
+	assert.NotPanics(t, NewLeakyBucketLimiter(0, 20).Stop, "unlimited limiter has no leak routine")
+}
+
+func TestLeakyBucketLimiter_ZeroQPS(t *testing.T) {
+	initTest()
+	// QPS 为 0 时曾在漏出协程中除零导致进程崩溃，现在返回不限流的限流器
+	for _, limiter := range []*LeakyBucketLimiter{NewLeakyBucketLimiter(0, 20), NewLeakyBucketLimiter(10, 0)} {
+		for i := 0; i < 50; i++ {
+			assert.True(t, limiter.Allow(), "Unlimited limiter should allow every request")
+		}
+		assert.Equal(t, rateLimitStatus{}, limiter.status())
+		limiter.Stop()
+	}
+}
+
+func TestLeakyBucketRateLimit_MinimalIPQPS(t *testing.T) {
+	initTest()
+	// QPS 为 1 时 IP 维度的 QPS 取半后保持为 1，IP 维度仍然限流：容量为全局的一半，是最严格的维度
+	cfg := newLeakyTestConfig()
+	cfg.Traffic.RateLimit.QPS = 1
+	config.SetConfig(cfg)
+	handler := LeakyBucketRateLimit()
+
+	w := serveRateLimited(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get(headerRateLimitLimit))
+}
+
 func TestMultiDimensionalLeakyBucket_Global(t *testing.T) {
 	initTest()
 
@@ -90,3 +131,53 @@ func TestMultiDimensionalLeakyBucket_Route(t *testing.T) {
 	}
 	assert.False(t, routeLimiter.Allow(), "Route limiter should reject when burst exceeded")
 }
+
+// serveRateLimited 通过限流中间件发送请求，返回响应
+func serveRateLimited(handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(handler)
+	router.GET("/api/v1/user", func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+	return w
+}
+
+func TestLeakyBucketRateLimit_Headers(t *testing.T) {
+	initTest()
+	// IP 维度为全局的一半：QPS 5、容量 2，是最严格的维度
+	config.SetConfig(&config.Config{Traffic: config.Traffic{RateLimit: config.TrafficRateLimit{
+		Enabled: true, QPS: 10, Burst: 4, Algorithm: "leaky_bucket",
+	}}})
+	handler := LeakyBucketRateLimit()
+
+	w := serveRateLimited(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = serveRateLimited(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = serveRateLimited(handler)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestLeakyBucketLimiter_Status(t *testing.T) {
+	initTest()
+	limiter := NewLeakyBucketLimiter(4, 10)
+	defer limiter.Stop()
+
+	for i := 0; i < 6; i++ {
+		limiter.Allow()
+	}
+	status := limiter.status()
+	assert.Equal(t, 10, status.limit)
+	assert.InDelta(t, 4, status.remaining, 1, "remaining should reflect free slots in the bucket")
+	assert.Equal(t, 250*time.Millisecond, limiter.leakInterval())
+}
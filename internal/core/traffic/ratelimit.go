@@ -0,0 +1,29 @@
+package traffic
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+)
+
+// RateLimit 按 traffic.rateLimit.algorithm 创建限流中间件，同时返回释放限流器资源的清理函数
+// 漏桶的每个限流器都有后台漏出协程，配置热更新重建中间件后需调用上一次返回的清理函数，否则协程会随每次重建泄漏
+func RateLimit(cfg *config.Config) (gin.HandlerFunc, func(), error) {
+	switch cfg.Traffic.RateLimit.Algorithm {
+	case "token_bucket":
+		return TokenBucketRateLimit(), func() {}, nil
+	case "leaky_bucket":
+		mdl := NewMultiDimensionalLeakyBucket(cfg)
+		return leakyBucketRateLimit(cfg, mdl), func() { CleanupLeakyBucket(mdl) }, nil
+	case "sliding_window":
+		return SlidingWindowRateLimit(), func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown rate limit algorithm: %q", cfg.Traffic.RateLimit.Algorithm)
+}
+
+// perIPLimit 返回单个客户端 IP 的限额：全局限额的一半，至少为 1
+// 全局限额为 1 时取半得 0，令牌桶会因速率为 0 除零 panic，漏桶和滑动窗口则会不再限制单个 IP
+func perIPLimit(n int) int {
+	return max(n/2, 1)
+}
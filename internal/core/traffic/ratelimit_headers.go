@@ -0,0 +1,59 @@
+package traffic
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 标准限流响应头
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// rateLimitStatus 一次限流检查后限流器的状态，用于写入限流响应头，零值表示不限流
+type rateLimitStatus struct {
+	limit     int           // 限流额度
+	remaining int           // 剩余额度
+	reset     time.Duration // 额度完全恢复所需的时间
+}
+
+// tighter 返回两个状态中剩余额度更少的一个，不限流的状态不参与比较
+func (s rateLimitStatus) tighter(o rateLimitStatus) rateLimitStatus {
+	if s.limit <= 0 {
+		return o
+	}
+	if o.limit > 0 && o.remaining < s.remaining {
+		return o
+	}
+	return s
+}
+
+// setRateLimitHeaders 写入 X-RateLimit-Limit、X-RateLimit-Remaining 和 X-RateLimit-Reset，Reset 为距离额度恢复的秒数
+// 不限流时不写入
+func setRateLimitHeaders(c *gin.Context, s rateLimitStatus) {
+	if s.limit <= 0 {
+		return
+	}
+	c.Header(headerRateLimitLimit, strconv.Itoa(s.limit))
+	c.Header(headerRateLimitRemaining, strconv.Itoa(max(s.remaining, 0)))
+	c.Header(headerRateLimitReset, strconv.Itoa(ceilSeconds(s.reset)))
+}
+
+// setRetryAfter 写入 Retry-After，至少为 1 秒，避免客户端立即重试
+func setRetryAfter(c *gin.Context, wait time.Duration) {
+	c.Header(headerRetryAfter, strconv.Itoa(max(ceilSeconds(wait), 1)))
+}
+
+// ceilSeconds 将时长向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
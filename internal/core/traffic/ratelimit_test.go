@@ -0,0 +1,71 @@
+package traffic
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_SelectsAlgorithm(t *testing.T) {
+	initTest()
+	for _, algorithm := range []string{"token_bucket", "leaky_bucket", "sliding_window"} {
+		cfg := newLeakyTestConfig()
+		cfg.Traffic.RateLimit.Algorithm = algorithm
+		cfg.Traffic.RateLimit.Window = time.Second
+		config.SetConfig(cfg)
+
+		handler, cleanup, err := RateLimit(cfg)
+		require.NoError(t, err, algorithm)
+		assert.Equal(t, http.StatusOK, serveRateLimited(handler).Code, algorithm)
+		cleanup()
+	}
+
+	cfg := newLeakyTestConfig()
+	cfg.Traffic.RateLimit.Algorithm = "fixed_window"
+	_, _, err := RateLimit(cfg)
+	assert.EqualError(t, err, `unknown rate limit algorithm: "fixed_window"`)
+}
+
+func TestRateLimit_MinimalQPS(t *testing.T) {
+	initTest()
+	// QPS 与容量为 1 时单个 IP 的限额取半后仍为 1：首个请求放行，紧接着的请求被限流，不会 panic
+	for _, algorithm := range []string{"token_bucket", "leaky_bucket", "sliding_window"} {
+		cfg := &config.Config{Traffic: config.Traffic{RateLimit: config.TrafficRateLimit{
+			Enabled: true, QPS: 1, Burst: 1, Algorithm: algorithm, Window: time.Second,
+		}}}
+		config.SetConfig(cfg)
+
+		handler, cleanup, err := RateLimit(cfg)
+		require.NoError(t, err, algorithm)
+		require.NotPanics(t, func() {
+			assert.Equal(t, http.StatusOK, serveRateLimited(handler).Code, algorithm)
+			assert.Equal(t, http.StatusTooManyRequests, serveRateLimited(handler).Code, algorithm)
+		}, algorithm)
+		cleanup()
+	}
+	assert.Equal(t, 1, perIPLimit(1))
+	assert.Equal(t, 5, perIPLimit(10))
+}
+
+func TestRateLimit_ReloadDoesNotLeakGoroutines(t *testing.T) {
+	initTest()
+	cfg := newLeakyTestConfig()
+	config.SetConfig(cfg)
+	before := runtime.NumGoroutine()
+
+	// 模拟多次配置热更新：每次重建限流中间件，处理请求创建各维度限流器，再停止上一次的限流器
+	for i := 0; i < 50; i++ {
+		handler, cleanup, err := RateLimit(cfg)
+		require.NoError(t, err)
+		serveRateLimited(handler)
+		cleanup()
+	}
+
+	// 漏桶的 Stop 等待漏出协程退出，cleanup 返回后不再残留后台协程
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before+5, "goroutines grew from %d across reloads", before)
+}
@@ -0,0 +1,307 @@
+package traffic
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/internal/core/security"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var slidingWindowTracer = otel.Tracer("ratelimit:sliding-window")
+
+// MultiDimensionalSlidingWindow 管理多维度滑动窗口限流
+// IP 与路由维度的键来自客户端，为避免限流器随不同的客户端 IP 和请求路径无限增长，
+// 每经过一个窗口长度，在创建新限流器时顺带清理窗口内已没有请求的限流器
+type MultiDimensionalSlidingWindow struct {
+	globalLimiter *SlidingWindowLimiter
+	ipLimiters    sync.Map // map[string]*SlidingWindowLimiter
+	routeLimiters sync.Map // map[string]*SlidingWindowLimiter
+	config        *config.Config
+	mutex         sync.Mutex
+	lastSweep     time.Time        // 上次清理空闲限流器的时间，由 mutex 保护
+	now           func() time.Time // 当前时间，测试时可替换
+}
+
+// SlidingWindowLimiter 实现滑动窗口日志限流器：记录窗口内每个请求的时间，
+// 任意长度为 window 的时间段内最多允许 limit 个请求，不像令牌桶和漏桶那样平滑突发
+type SlidingWindowLimiter struct {
+	limit  int           // 窗口内允许的请求数
+	window time.Duration // 窗口长度
+	log    []time.Time   // 环形缓冲区，保存窗口内已放行请求的时间，按需扩容，最多 limit 个
+	head   int           // 最早一个请求在 log 中的下标
+	count  int           // 窗口内的请求数
+	mutex  sync.Mutex
+	now    func() time.Time // 当前时间，测试时可替换
+
+	evicted bool // 已作为空闲限流器被清理，不再记录请求，由 mutex 保护
+}
+
+func NewMultiDimensionalSlidingWindow(cfg *config.Config) *MultiDimensionalSlidingWindow {
+	mds := &MultiDimensionalSlidingWindow{
+		config: cfg,
+		now:    time.Now,
+	}
+
+	if cfg.Traffic.RateLimit.Enabled {
+		mds.globalLimiter = NewSlidingWindowLimiter(cfg.Traffic.RateLimit.QPS, cfg.Traffic.RateLimit.Window)
+	}
+
+	return mds
+}
+
+// NewSlidingWindowLimiter 创建滑动窗口限流器，窗口内允许 qps×窗口秒数 个请求（至少 1 个）
+// qps 或 window 不为正数时无法限流，返回不限流的限流器
+func NewSlidingWindowLimiter(qps int, window time.Duration) *SlidingWindowLimiter {
+	if qps <= 0 || window <= 0 {
+		logger.Warn("SlidingWindowLimiter requires positive qps and window, rate limiting disabled for this limiter",
+			zap.Int("qps", qps),
+			zap.Duration("window", window))
+		return &SlidingWindowLimiter{now: time.Now}
+	}
+	limit := max(int(float64(qps)*window.Seconds()), 1)
+	logger.Info("SlidingWindowLimiter initialized",
+		zap.Int("qps", qps),
+		zap.Duration("window", window),
+		zap.Int("limit", limit))
+	return &SlidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// slidingWindowInitialLog 请求日志的初始容量，窗口额度很大时不必为每个限流器预先分配全部额度
+const slidingWindowInitialLog = 8
+
+// unlimited 判断限流器是否为不限流的限流器
+func (l *SlidingWindowLimiter) unlimited() bool {
+	return l.limit == 0
+}
+
+// Allow 判断请求能否放行，放行时记录请求时间
+// 被拒绝时同时返回需要等待的时间，即窗口内最早的请求移出窗口所需的时间
+func (l *SlidingWindowLimiter) Allow() (bool, time.Duration) {
+	allowed, wait, _ := l.allow()
+	return allowed, wait
+}
+
+// allow 与 Allow 相同，限流器已被清理时返回的 live 为 false，调用方需重新获取限流器
+func (l *SlidingWindowLimiter) allow() (allowed bool, wait time.Duration, live bool) {
+	if l.unlimited() {
+		return true, 0, true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.evicted {
+		return false, 0, false
+	}
+	now := l.now()
+	l.evict(now)
+	if l.count >= l.limit {
+		return false, l.log[l.head].Add(l.window).Sub(now), true
+	}
+	if l.count == len(l.log) {
+		l.grow()
+	}
+	l.log[(l.head+l.count)%len(l.log)] = now
+	l.count++
+	return true, 0, true
+}
+
+// grow 扩大请求日志的容量，按请求顺序从下标 0 开始重新排列，调用方需持有锁
+func (l *SlidingWindowLimiter) grow() {
+	log := make([]time.Time, min(max(2*len(l.log), slidingWindowInitialLog), l.limit))
+	for i := 0; i < l.count; i++ {
+		log[i] = l.log[(l.head+i)%len(l.log)]
+	}
+	l.log, l.head = log, 0
+}
+
+// evict 移除已不在窗口内的请求，调用方需持有锁
+func (l *SlidingWindowLimiter) evict(now time.Time) {
+	boundary := now.Add(-l.window)
+	for l.count > 0 && !l.log[l.head].After(boundary) {
+		l.head = (l.head + 1) % len(l.log)
+		l.count--
+	}
+}
+
+// markIdle 窗口内没有请求时将限流器标记为已清理并返回 true，此时它与新建的限流器等价，可以安全丢弃
+func (l *SlidingWindowLimiter) markIdle() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.evict(l.now())
+	if l.count == 0 {
+		l.evicted = true
+	}
+	return l.evicted
+}
+
+// status 返回限流器当前状态：额度为窗口内允许的请求数，恢复时间为窗口内最近一个请求移出窗口所需的时间
+// 不限流的限流器返回零值
+func (l *SlidingWindowLimiter) status() rateLimitStatus {
+	if l.unlimited() {
+		return rateLimitStatus{}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.evict(now)
+	var reset time.Duration
+	if l.count > 0 {
+		newest := l.log[(l.head+l.count-1)%len(l.log)]
+		reset = newest.Add(l.window).Sub(now)
+	}
+	return rateLimitStatus{
+		limit:     l.limit,
+		remaining: l.limit - l.count,
+		reset:     reset,
+	}
+}
+
+// getOrCreateLimiter 获取或创建特定维度的限流器
+func (mds *MultiDimensionalSlidingWindow) getOrCreateLimiter(dimension, key string, qps int) *SlidingWindowLimiter {
+	var limiterMap *sync.Map
+	if dimension == "ip" {
+		limiterMap = &mds.ipLimiters
+	} else if dimension == "route" {
+		limiterMap = &mds.routeLimiters
+	}
+
+	if limiter, ok := limiterMap.Load(key); ok {
+		return limiter.(*SlidingWindowLimiter)
+	}
+
+	mds.mutex.Lock()
+	defer mds.mutex.Unlock()
+
+	// 双重检查
+	if limiter, ok := limiterMap.Load(key); ok {
+		return limiter.(*SlidingWindowLimiter)
+	}
+
+	mds.sweepIdle()
+	limiter := NewSlidingWindowLimiter(qps, mds.config.Traffic.RateLimit.Window)
+	limiter.now = mds.now
+	limiterMap.Store(key, limiter)
+	return limiter
+}
+
+// allowDimension 检查特定维度的限流器，限流器恰好被清理时重新获取
+func (mds *MultiDimensionalSlidingWindow) allowDimension(dimension, key string, qps int) (*SlidingWindowLimiter, bool, time.Duration) {
+	for {
+		limiter := mds.getOrCreateLimiter(dimension, key, qps)
+		if allowed, wait, live := limiter.allow(); live {
+			return limiter, allowed, wait
+		}
+	}
+}
+
+// sweepIdle 距上次清理超过一个窗口长度时，删除 IP 与路由维度中窗口内已没有请求的限流器，调用方需持有 mutex
+func (mds *MultiDimensionalSlidingWindow) sweepIdle() {
+	now := mds.now()
+	if now.Sub(mds.lastSweep) < mds.config.Traffic.RateLimit.Window {
+		return
+	}
+	mds.lastSweep = now
+
+	removed := 0
+	for _, limiterMap := range []*sync.Map{&mds.ipLimiters, &mds.routeLimiters} {
+		limiterMap.Range(func(key, value any) bool {
+			if value.(*SlidingWindowLimiter).markIdle() {
+				limiterMap.Delete(key)
+				removed++
+			}
+			return true
+		})
+	}
+	if removed > 0 {
+		logger.Debug("Removed idle sliding window limiters", zap.Int("count", removed))
+	}
+}
+
+func SlidingWindowRateLimit() gin.HandlerFunc {
+	cfg := config.GetConfig()
+	mds := NewMultiDimensionalSlidingWindow(cfg)
+
+	return func(c *gin.Context) {
+		if !cfg.Traffic.RateLimit.Enabled {
+			c.Next()
+			return
+		}
+
+		_, span := slidingWindowTracer.Start(c.Request.Context(), "RateLimit.SlidingWindow",
+			trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
+		defer span.End()
+
+		// 检查全局限流
+		if mds.globalLimiter != nil {
+			if allowed, wait := mds.globalLimiter.Allow(); !allowed {
+				rejectSlidingWindow(c, span, mds.globalLimiter, "global", "", wait)
+				return
+			}
+		}
+
+		// 检查IP限流，与其他算法一致，IP 维度为全局的一半
+		clientIP := c.ClientIP()
+		ipLimiter, allowed, wait := mds.allowDimension("ip", clientIP, perIPLimit(cfg.Traffic.RateLimit.QPS))
+		if !allowed {
+			rejectSlidingWindow(c, span, ipLimiter, "ip", clientIP, wait)
+			return
+		}
+
+		// 检查路由限流
+		route := c.Request.URL.Path
+		routeLimiter, allowed, wait := mds.allowDimension("route", route, cfg.Traffic.RateLimit.QPS)
+		if !allowed {
+			rejectSlidingWindow(c, span, routeLimiter, "route", route, wait)
+			return
+		}
+
+		// 响应头反映各维度中剩余额度最少的限流器
+		status := ipLimiter.status().tighter(routeLimiter.status())
+		if mds.globalLimiter != nil {
+			status = status.tighter(mds.globalLimiter.status())
+		}
+		setRateLimitHeaders(c, status)
+		span.SetStatus(codes.Ok, "Request allowed by sliding window")
+		c.Next()
+	}
+}
+
+func rejectSlidingWindow(c *gin.Context, span trace.Span, limiter *SlidingWindowLimiter, dimension, key string, wait time.Duration) {
+	logger.Warn("Rate limit exceeded with sliding window",
+		zap.String("dimension", dimension),
+		zap.String("key", key),
+		zap.String("clientIP", c.ClientIP()),
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("limit", limiter.limit),
+		zap.Duration("window", limiter.window))
+	span.SetStatus(codes.Error, "Rate limit exceeded")
+	observability.RateLimitRejections.WithLabelValues(c.Request.URL.Path).Inc()
+	security.RecordViolation(c.Request.Context(), c.ClientIP(), security.ViolationRateLimit)
+
+	setRateLimitHeaders(c, limiter.status())
+	setRetryAfter(c, wait)
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":     "Request rate limit exceeded",
+		"dimension": dimension,
+		"key":       key,
+		"limit":     limiter.limit,
+		"window":    limiter.window.String(),
+	})
+	c.Abort()
+}
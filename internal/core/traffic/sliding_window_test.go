@@ -0,0 +1,185 @@
+package traffic
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSlidingWindow 创建使用可控时钟的滑动窗口限流器，返回推进时钟的函数
+func newTestSlidingWindow(qps int, window time.Duration) (*SlidingWindowLimiter, func(time.Duration)) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewSlidingWindowLimiter(qps, window)
+	limiter.now = func() time.Time { return now }
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSlidingWindowLimiter_ExactLimit(t *testing.T) {
+	initTest()
+	limiter, _ := newTestSlidingWindow(5, time.Second)
+
+	for i := 0; i < 5; i++ {
+		allowed, _ := limiter.Allow()
+		assert.True(t, allowed, "Should allow request %d within the window limit", i+1)
+	}
+	allowed, wait := limiter.Allow()
+	assert.False(t, allowed, "Should reject the request exceeding the window limit")
+	assert.Equal(t, time.Second, wait)
+}
+
+func TestSlidingWindowLimiter_WindowBoundary(t *testing.T) {
+	initTest()
+	limiter, advance := newTestSlidingWindow(2, time.Second)
+
+	// t=0 与 t=600ms 各放行一个请求
+	allowed, _ := limiter.Allow()
+	assert.True(t, allowed)
+	advance(600 * time.Millisecond)
+	allowed, _ = limiter.Allow()
+	assert.True(t, allowed)
+
+	// t=999ms 时两个请求都在窗口内
+	advance(399 * time.Millisecond)
+	allowed, wait := limiter.Allow()
+	assert.False(t, allowed, "Both requests are still inside the window just before the boundary")
+	assert.Equal(t, time.Millisecond, wait)
+
+	// t=1s 时 t=0 的请求恰好移出窗口，只空出一个名额
+	advance(time.Millisecond)
+	allowed, _ = limiter.Allow()
+	assert.True(t, allowed, "The first request leaves the window exactly at the boundary")
+	allowed, wait = limiter.Allow()
+	assert.False(t, allowed, "Only one slot frees up at the boundary")
+	assert.Equal(t, 600*time.Millisecond, wait)
+
+	// 固定窗口会在 t=1s 重置计数，滑动窗口在任意 1s 内都不超过 2 个请求
+	advance(600 * time.Millisecond)
+	allowed, _ = limiter.Allow()
+	assert.True(t, allowed)
+}
+
+func TestSlidingWindowLimiter_RejectedRequestsDoNotCount(t *testing.T) {
+	initTest()
+	limiter, advance := newTestSlidingWindow(1, time.Second)
+
+	allowed, _ := limiter.Allow()
+	assert.True(t, allowed)
+	for i := 0; i < 10; i++ {
+		advance(50 * time.Millisecond)
+		allowed, _ = limiter.Allow()
+		assert.False(t, allowed)
+	}
+	advance(500 * time.Millisecond)
+	allowed, _ = limiter.Allow()
+	assert.True(t, allowed, "Rejected requests must not extend the window")
+}
+
+func TestSlidingWindowLimiter_Status(t *testing.T) {
+	initTest()
+	limiter, advance := newTestSlidingWindow(4, 500*time.Millisecond)
+
+	assert.Equal(t, rateLimitStatus{limit: 2, remaining: 2}, limiter.status())
+	limiter.Allow()
+	advance(100 * time.Millisecond)
+	limiter.Allow()
+	assert.Equal(t, rateLimitStatus{limit: 2, remaining: 0, reset: 500 * time.Millisecond}, limiter.status())
+	advance(400 * time.Millisecond)
+	assert.Equal(t, rateLimitStatus{limit: 2, remaining: 1, reset: 100 * time.Millisecond}, limiter.status())
+}
+
+func TestSlidingWindowLimiter_GrowsLogOnDemand(t *testing.T) {
+	initTest()
+	limiter, advance := newTestSlidingWindow(20, time.Second)
+	assert.Empty(t, limiter.log, "The request log should not be preallocated")
+
+	// 前 6 个请求在 t=0，随后 4 个在 t=500ms，使环形缓冲区在扩容前发生回绕
+	for i := 0; i < 6; i++ {
+		allowed, _ := limiter.Allow()
+		assert.True(t, allowed)
+	}
+	advance(500 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		allowed, _ := limiter.Allow()
+		assert.True(t, allowed)
+	}
+	advance(500 * time.Millisecond)
+	// t=1s 时 t=0 的 6 个请求移出窗口，窗口内剩 4 个，还能放行 16 个
+	for i := 0; i < 16; i++ {
+		allowed, _ := limiter.Allow()
+		assert.True(t, allowed, "Should allow request %d after the oldest requests leave the window", i+1)
+	}
+	allowed, wait := limiter.Allow()
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait, "The oldest remaining request is the first one at t=500ms")
+	assert.Len(t, limiter.log, 20, "The log should never grow beyond the limit")
+}
+
+func TestMultiDimensionalSlidingWindow_SweepsIdleLimiters(t *testing.T) {
+	initTest()
+	now := time.Unix(1700000000, 0)
+	mds := NewMultiDimensionalSlidingWindow(&config.Config{Traffic: config.Traffic{RateLimit: config.TrafficRateLimit{
+		QPS: 2, Window: time.Second,
+	}}})
+	mds.now = func() time.Time { return now }
+
+	idle, allowed, _ := mds.allowDimension("ip", "10.0.0.1", 1)
+	assert.True(t, allowed)
+	now = now.Add(600 * time.Millisecond)
+	busy, _, _ := mds.allowDimension("ip", "10.0.0.2", 1)
+
+	// 距上次清理超过一个窗口后创建新限流器时，清理窗口内已没有请求的限流器
+	now = now.Add(600 * time.Millisecond)
+	mds.allowDimension("ip", "10.0.0.3", 1)
+	_, ok := mds.ipLimiters.Load("10.0.0.1")
+	assert.False(t, ok, "Idle limiter should be removed")
+	_, ok = mds.ipLimiters.Load("10.0.0.2")
+	assert.True(t, ok, "Limiter with requests inside the window should be kept")
+
+	// 已被清理的限流器不再记录请求，调用方获取新的限流器
+	_, _, live := idle.allow()
+	assert.False(t, live)
+	limiter, allowed, _ := mds.allowDimension("ip", "10.0.0.1", 1)
+	assert.True(t, allowed)
+	assert.NotSame(t, idle, limiter)
+
+	// 仍在窗口内的限流器继续限流
+	limiter, allowed, _ = mds.allowDimension("ip", "10.0.0.2", 1)
+	assert.Same(t, busy, limiter)
+	assert.False(t, allowed)
+}
+
+func TestSlidingWindowLimiter_ZeroQPS(t *testing.T) {
+	initTest()
+	limiter := NewSlidingWindowLimiter(0, time.Second)
+	for i := 0; i < 50; i++ {
+		allowed, _ := limiter.Allow()
+		assert.True(t, allowed, "Unlimited limiter should allow every request")
+	}
+	assert.Equal(t, rateLimitStatus{}, limiter.status())
+}
+
+func TestSlidingWindowRateLimit_Headers(t *testing.T) {
+	initTest()
+	config.SetConfig(&config.Config{Traffic: config.Traffic{RateLimit: config.TrafficRateLimit{
+		Enabled: true, QPS: 4, Window: time.Second, Algorithm: "sliding_window",
+	}}})
+	handler := SlidingWindowRateLimit()
+
+	// IP 维度的额度为全局的一半
+	w := serveRateLimited(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = serveRateLimited(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = serveRateLimited(handler)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+}
@@ -2,12 +2,14 @@ package traffic
 
 import (
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/internal/core/security"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -19,6 +21,9 @@ import (
 
 var tokenBucketTracer = otel.Tracer("ratelimit:token-bucket")
 
+// takeTolerance 未等待时 Take 返回其内部读取的当前时间，总是略晚于调用前记录的时间，低于该值的差距视为未等待
+const takeTolerance = time.Millisecond
+
 // MultiDimensionalTokenBucket 管理多维度限流
 type MultiDimensionalTokenBucket struct {
 	globalLimiter *TokenBucketLimiter
@@ -30,6 +35,7 @@ type MultiDimensionalTokenBucket struct {
 
 type TokenBucketLimiter struct {
 	limiter uberRatelimit.Limiter
+	qps     int // 每秒允许的请求数，作为 X-RateLimit-Limit 返回
 }
 
 func NewMultiDimensionalTokenBucket(cfg *config.Config) *MultiDimensionalTokenBucket {
@@ -47,6 +53,7 @@ func NewMultiDimensionalTokenBucket(cfg *config.Config) *MultiDimensionalTokenBu
 func NewTokenBucketLimiter(qps, burst int) *TokenBucketLimiter {
 	l := &TokenBucketLimiter{
 		limiter: uberRatelimit.New(qps, uberRatelimit.WithSlack(burst)),
+		qps:     qps,
 	}
 	logger.Info("TokenBucketLimiter initialized",
 		zap.Int("qps", qps),
@@ -105,8 +112,8 @@ func TokenBucketRateLimit() gin.HandlerFunc {
 
 		// 检查IP限流
 		clientIP := c.ClientIP()
-		ipQPS := cfg.Traffic.RateLimit.QPS / 2
-		ipBurst := cfg.Traffic.RateLimit.Burst / 2
+		ipQPS := perIPLimit(cfg.Traffic.RateLimit.QPS)
+		ipBurst := perIPLimit(cfg.Traffic.RateLimit.Burst)
 		ipLimiter := mdt.getOrCreateLimiter("ip", clientIP, ipQPS, ipBurst)
 		if !checkLimit(ipLimiter, c, span, "ip", clientIP) {
 			return
@@ -121,6 +128,12 @@ func TokenBucketRateLimit() gin.HandlerFunc {
 			return
 		}
 
+		// 令牌桶不暴露剩余令牌数，通过的请求只返回各维度中最严格的额度
+		limit := min(ipLimiter.qps, routeLimiter.qps)
+		if mdt.globalLimiter != nil {
+			limit = min(limit, mdt.globalLimiter.qps)
+		}
+		c.Header(headerRateLimitLimit, strconv.Itoa(limit))
 		span.SetStatus(codes.Ok, "Request allowed by token bucket")
 		c.Next()
 	}
@@ -131,7 +144,7 @@ func checkLimit(limiter *TokenBucketLimiter, c *gin.Context, span trace.Span, di
 	takeTime := limiter.Take()
 	waitDuration := takeTime.Sub(now)
 
-	if waitDuration > 0 {
+	if waitDuration > takeTolerance {
 		logger.Warn("Rate limit exceeded with token bucket",
 			zap.String("dimension", dimension),
 			zap.String("key", key),
@@ -140,7 +153,10 @@ func checkLimit(limiter *TokenBucketLimiter, c *gin.Context, span trace.Span, di
 			zap.Duration("waitDuration", waitDuration))
 		span.SetStatus(codes.Error, "Rate limit exceeded")
 		observability.RateLimitRejections.WithLabelValues(c.Request.URL.Path).Inc()
+		security.RecordViolation(c.Request.Context(), c.ClientIP(), security.ViolationRateLimit)
 
+		setRateLimitHeaders(c, rateLimitStatus{limit: limiter.qps, remaining: 0, reset: waitDuration})
+		setRetryAfter(c, waitDuration)
 		c.JSON(http.StatusTooManyRequests, gin.H{
 			"error":      "Request rate limit exceeded",
 			"dimension":  dimension,
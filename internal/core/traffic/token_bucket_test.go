@@ -1,17 +1,20 @@
 package traffic
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
 	"github.com/stretchr/testify/assert"
 )
 
 // 初始化测试环境，参考 leaky_bucket_test 的初始化
 func initTokenBucketTest() {
 	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
 	config.InitTestConfigManager()
 	newTokenBucketTestConfig()
 }
@@ -126,3 +129,24 @@ func TestMultiDimensionalTokenBucket_Route(t *testing.T) {
 	extraDelay := nextToken.Sub(tokenTimes[len(tokenTimes)-1])
 	assert.GreaterOrEqual(t, extraDelay, 125*time.Millisecond, "路由超出突发容量后，额外令牌应至少延时 125ms")
 }
+
+// 测试令牌桶限流的响应头：通过时返回最严格的额度，拒绝时返回等待时间
+func TestTokenBucketRateLimit_Headers(t *testing.T) {
+	initTokenBucketTest()
+	// IP 维度为全局的一半（QPS 10），通过时返回该额度；第二个请求先被全局维度（QPS 20）拒绝
+	config.SetConfig(&config.Config{Traffic: config.Traffic{RateLimit: config.TrafficRateLimit{
+		Enabled: true, QPS: 20, Burst: 20, Algorithm: "token_bucket",
+	}}})
+	handler := TokenBucketRateLimit()
+
+	w := serveRateLimited(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+
+	w = serveRateLimited(handler)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "20", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// AccessLog 返回访问日志中间件，每个请求输出一条结构化日志
+func AccessLog(cfg *config.Config) gin.HandlerFunc {
+	accessCfg := cfg.Logging.Access
+	excluded := make(map[string]struct{}, len(accessCfg.ExcludePaths))
+	for _, path := range accessCfg.ExcludePaths {
+		excluded[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if !accessCfg.Enabled {
+			c.Next()
+			return
+		}
+		if _, ok := excluded[c.Request.URL.Path]; ok {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		// 按采样率决定是否记录，采样率 >= 1 时记录全部请求
+		if accessCfg.SampleRate < 1 && rand.Float64() >= accessCfg.SampleRate {
+			return
+		}
+
+		requestSize := c.Request.ContentLength
+		if requestSize < 0 {
+			requestSize = 0
+		}
+		responseSize := c.Writer.Size()
+		if responseSize < 0 {
+			responseSize = 0
+		}
+
+		traceID := c.GetString("trace_id")
+		if traceID == "" {
+			if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+				traceID = spanCtx.TraceID().String()
+			}
+		}
+
+		logger.Info("Access log",
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("target", c.GetString("proxy_target")),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int64("requestSize", requestSize),
+			zap.Int("responseSize", responseSize),
+			zap.String("clientIP", c.ClientIP()),
+			zap.String("requestID", c.GetString("request_id")),
+			zap.String("traceID", traceID),
+		)
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAccessLogTestRouter 创建经过请求 ID 与访问日志中间件的路由
+func newAccessLogTestRouter(access config.AccessLog) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), AccessLog(&config.Config{Logging: config.Logging{Access: access}}))
+	r.POST("/api", func(c *gin.Context) {
+		c.Set("proxy_target", "http://backend:8080")
+		c.String(http.StatusCreated, "created")
+	})
+	r.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestAccessLog_Fields(t *testing.T) {
+	_, logs := logger.InitTestLogger()
+	r := newAccessLogTestRouter(config.AccessLog{Enabled: true, SampleRate: 1})
+
+	req := httptest.NewRequest("POST", "/api", strings.NewReader("hello"))
+	req.Header.Set(RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("Access log").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"clientIP", "latency", "method", "path", "requestID", "requestSize", "responseSize", "status", "target", "traceID"}, keys,
+		"access log fields use camelCase consistently")
+	assert.Equal(t, "POST", fields["method"])
+	assert.Equal(t, "/api", fields["path"])
+	assert.Equal(t, "http://backend:8080", fields["target"])
+	assert.EqualValues(t, http.StatusCreated, fields["status"])
+	assert.EqualValues(t, 5, fields["requestSize"])
+	assert.EqualValues(t, len("created"), fields["responseSize"])
+	assert.Equal(t, "req-1", fields["requestID"])
+}
+
+func TestAccessLog_ExcludedAndDisabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		access config.AccessLog
+		path   string
+	}{
+		{"disabled", config.AccessLog{Enabled: false, SampleRate: 1}, "/api"},
+		{"excluded path", config.AccessLog{Enabled: true, SampleRate: 1, ExcludePaths: []string{"/health"}}, "/health"},
+		{"sampled out", config.AccessLog{Enabled: true, SampleRate: 0}, "/api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, logs := logger.InitTestLogger()
+			r := newAccessLogTestRouter(tt.access)
+
+			method := "POST"
+			if tt.path == "/health" {
+				method = "GET"
+			}
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, tt.path, nil))
+
+			assert.Zero(t, logs.FilterMessage("Access log").Len())
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/penwyp/mini-gateway/config"
 )
 
+// Auth 创建认证中间件，按当前配置的 security.authMode 选择已注册的认证器
 func Auth() gin.HandlerFunc {
 	authenticator := NewAuthenticator(config.GetConfig())
 	return func(c *gin.Context) {
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var apiKeyTracer = otel.Tracer("auth:apikey")
+
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+	// apiKeysRedisKey Redis 中保存 API Key 的哈希表，字段为 Key 的 SHA-256 十六进制摘要，值为客户端标识
+	apiKeysRedisKey = "mg:api_keys"
+)
+
+// APIKeyAuthenticator 校验请求头中的 API Key，并将其对应的客户端标识写入上下文的 username
+type APIKeyAuthenticator struct {
+	header  string
+	clients map[string]string // Key 的 SHA-256 摘要到客户端标识，按摘要查找避免直接比较明文 Key
+	redis   bool
+}
+
+// NewAPIKeyAuthenticator 根据 security.apiKey 创建 API Key 认证器
+func NewAPIKeyAuthenticator(cfg *config.Config) *APIKeyAuthenticator {
+	apiKey := cfg.Security.APIKey
+	a := &APIKeyAuthenticator{
+		header:  apiKey.Header,
+		clients: make(map[string]string, len(apiKey.Keys)),
+		redis:   apiKey.Redis,
+	}
+	if a.header == "" {
+		a.header = defaultAPIKeyHeader
+	}
+	for _, entry := range apiKey.Keys {
+		a.clients[digestAPIKey(entry.Key)] = entry.Client
+	}
+	return a
+}
+
+// digestAPIKey 返回 API Key 的 SHA-256 十六进制摘要
+func digestAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *APIKeyAuthenticator) Authenticate(c *gin.Context) {
+	ctx, span := apiKeyTracer.Start(c.Request.Context(), "Auth.APIKey",
+		trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
+	defer span.End()
+
+	key := c.GetHeader(a.header)
+	if key == "" {
+		a.reject(c, span, http.StatusUnauthorized, "API key required")
+		return
+	}
+
+	client, err := a.lookup(ctx, digestAPIKey(key))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "API key lookup failed")
+		logger.Error("Failed to look up API key", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication unavailable"})
+		c.Abort()
+		return
+	}
+	if client == "" {
+		a.reject(c, span, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+
+	// API Key 是网关的凭证，不转发给上游
+	c.Request.Header.Del(a.header)
+	span.SetAttributes(attribute.String("client", client))
+	span.SetStatus(codes.Ok, "Authentication succeeded")
+	c.Set("username", client)
+	c.Next()
+}
+
+// lookup 先在配置中查找 Key，未找到且启用 Redis 时再查 Redis，Key 无效时返回空字符串
+func (a *APIKeyAuthenticator) lookup(ctx context.Context, digest string) (string, error) {
+	if client, ok := a.clients[digest]; ok {
+		return client, nil
+	}
+	if !a.redis || cache.Default == nil {
+		return "", nil
+	}
+	client, err := cache.Default.HGet(ctx, apiKeysRedisKey, digest)
+	if errors.Is(err, cache.ErrNotFound) {
+		return "", nil
+	}
+	return client, err
+}
+
+// reject 记录认证失败并返回错误响应
+func (a *APIKeyAuthenticator) reject(c *gin.Context, span trace.Span, status int, msg string) {
+	span.SetStatus(codes.Error, msg)
+	logger.Warn("API key authentication failed",
+		zap.String("path", c.Request.URL.Path),
+		zap.String("reason", msg))
+	observability.APIKeyAuthFailures.WithLabelValues(c.Request.URL.Path).Inc()
+	c.JSON(status, gin.H{"error": msg})
+	c.Abort()
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAPIKeyTestRouter 创建经过 API Key 认证的路由，上游返回客户端标识和是否收到了 API Key 头
+func newAPIKeyTestRouter(apiKey config.APIKey) *gin.Engine {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Security: config.Security{AuthMode: "apikey", APIKey: apiKey}}
+
+	r := gin.New()
+	r.Use(NewAuthenticator(cfg).Authenticate)
+	r.GET("/api/v1/user", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("username")+"|"+c.GetHeader("X-API-Key")+c.GetHeader("X-Client-Key"))
+	})
+	return r
+}
+
+// requestWithKey 携带指定请求头发送请求
+func requestWithKey(r *gin.Engine, header, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	if key != "" {
+		req.Header.Set(header, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyAuthenticator_ConfigKeys(t *testing.T) {
+	// 同一客户端的新旧 Key 在轮换期间同时有效
+	r := newAPIKeyTestRouter(config.APIKey{Keys: []config.APIKeyEntry{
+		{Key: "old-key", Client: "billing"},
+		{Key: "new-key", Client: "billing"},
+		{Key: "ops-key", Client: "ops"},
+	}})
+
+	for key, client := range map[string]string{"old-key": "billing", "new-key": "billing", "ops-key": "ops"} {
+		w := requestWithKey(r, "X-API-Key", key)
+		assert.Equal(t, http.StatusOK, w.Code, key)
+		assert.Equal(t, client+"|", w.Body.String(), "the API key must not be forwarded upstream")
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, requestWithKey(r, "X-API-Key", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, requestWithKey(r, "X-API-Key", "revoked-key").Code)
+}
+
+func TestAPIKeyAuthenticator_CustomHeader(t *testing.T) {
+	r := newAPIKeyTestRouter(config.APIKey{Header: "X-Client-Key", Keys: []config.APIKeyEntry{{Key: "k-1", Client: "ci"}}})
+
+	assert.Equal(t, http.StatusUnauthorized, requestWithKey(r, "X-API-Key", "k-1").Code)
+	w := requestWithKey(r, "X-Client-Key", "k-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ci|", w.Body.String())
+}
+
+func TestAPIKeyAuthenticator_Redis(t *testing.T) {
+	store := cache.InitTestStore()
+	defer func() { cache.Default = nil }()
+	r := newAPIKeyTestRouter(config.APIKey{Redis: true, Keys: []config.APIKeyEntry{{Key: "static-key", Client: "static"}}})
+
+	assert.NoError(t, store.HSet(context.Background(), apiKeysRedisKey, map[string]string{digestAPIKey("redis-key"): "partner"}))
+	w := requestWithKey(r, "X-API-Key", "redis-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partner|", w.Body.String())
+
+	// 配置中的 Key 不查 Redis
+	assert.Equal(t, http.StatusOK, requestWithKey(r, "X-API-Key", "static-key").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, requestWithKey(r, "X-API-Key", "unknown").Code)
+
+	cache.Default = cache.NewErrorStore(errors.New("connection refused"))
+	assert.Equal(t, http.StatusServiceUnavailable, requestWithKey(r, "X-API-Key", "any").Code)
+}
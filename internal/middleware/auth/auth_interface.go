@@ -1,25 +1,73 @@
 package auth
 
 import (
+	"sort"
+	"sync"
+
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.uber.org/zap"
 )
 
-// Authenticator 定义认证器接口
+// Authenticator 定义认证器接口，是添加新认证模式的扩展点
+// Authenticate 在认证通过时应将用户标识写入上下文的 username 并调用 c.Next()，
+// 认证失败时应写入错误响应并调用 c.Abort()，不得在未终止请求的情况下直接返回
 type Authenticator interface {
 	Authenticate(c *gin.Context)
 }
 
-// NewAuthenticator 创建认证器实例
+// Factory 根据配置创建认证器，在 Auth 中间件创建时调用一次，创建的认证器会被并发使用
+type Factory func(cfg *config.Config) Authenticator
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory) // 认证模式名称到认证器工厂的映射
+)
+
+func init() {
+	Register("jwt", func(cfg *config.Config) Authenticator { return &JWTAuthenticator{cfg: cfg} })
+	Register("rbac", func(cfg *config.Config) Authenticator { return &RBACAuthenticator{cfg: cfg} })
+	Register("apikey", func(cfg *config.Config) Authenticator { return NewAPIKeyAuthenticator(cfg) })
+	Register("oauth2-introspection", func(cfg *config.Config) Authenticator { return NewIntrospectionAuthenticator(cfg) })
+	Register("none", func(cfg *config.Config) Authenticator { return &NoopAuthenticator{} })
+}
+
+// Register 注册认证模式，配置 security.authMode 为该名称时使用此认证器，同名注册会覆盖已有的认证器
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Registered 返回已注册的认证模式名称，按字母排序
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAuthenticator 按 security.authMode 创建认证器实例，未设置时不认证
 func NewAuthenticator(cfg *config.Config) Authenticator {
-	switch cfg.Security.AuthMode {
-	case "jwt":
-		return &JWTAuthenticator{cfg: cfg}
-	case "rbac":
-		return &RBACAuthenticator{cfg: cfg}
-	default:
+	mode := cfg.Security.AuthMode
+	if mode == "" {
+		mode = "none"
+	}
+	registryMu.RLock()
+	factory, ok := registry[mode]
+	registryMu.RUnlock()
+	if !ok {
+		logger.Warn("Unknown auth mode, requests will not be authenticated",
+			zap.String("authMode", mode),
+			zap.Strings("registered", Registered()))
 		return &NoopAuthenticator{}
 	}
+	return factory(cfg)
 }
 
 // NoopAuthenticator 无认证实现
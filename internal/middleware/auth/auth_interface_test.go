@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// headerKeyAuthenticator 测试用的自定义认证器，要求请求携带配置的 Key
+type headerKeyAuthenticator struct {
+	key string
+}
+
+func (a *headerKeyAuthenticator) Authenticate(c *gin.Context) {
+	if c.GetHeader("X-API-Key") != a.key {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		return
+	}
+	c.Set("username", "api-client")
+	c.Next()
+}
+
+func TestRegister_CustomAuthenticatorSelectedByAuthMode(t *testing.T) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	Register("header-key", func(cfg *config.Config) Authenticator {
+		return &headerKeyAuthenticator{key: cfg.Security.JWT.Secret}
+	})
+	assert.Contains(t, Registered(), "header-key")
+
+	cfg := &config.Config{Security: config.Security{AuthMode: "header-key", JWT: config.JWT{Secret: "k-123"}}}
+	authenticator := NewAuthenticator(cfg)
+	assert.IsType(t, &headerKeyAuthenticator{}, authenticator)
+
+	r := gin.New()
+	r.Use(authenticator.Authenticate)
+	r.GET("/api/v1/user", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("username"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	req.Header.Set("X-API-Key", "k-123")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "api-client", w.Body.String())
+}
+
+func TestNewAuthenticator_BuiltIns(t *testing.T) {
+	logger.InitTestLogger()
+	assert.Equal(t, []string{"apikey", "jwt", "none", "rbac"}, filterBuiltIns(Registered()))
+
+	for mode, want := range map[string]Authenticator{
+		"apikey":  &APIKeyAuthenticator{},
+		"jwt":     &JWTAuthenticator{},
+		"rbac":    &RBACAuthenticator{},
+		"none":    &NoopAuthenticator{},
+		"":        &NoopAuthenticator{},
+		"unknown": &NoopAuthenticator{},
+	} {
+		cfg := &config.Config{Security: config.Security{AuthMode: mode}}
+		assert.IsType(t, want, NewAuthenticator(cfg), mode)
+	}
+}
+
+// filterBuiltIns 过滤出内置的认证模式，忽略其他测试注册的自定义模式
+func filterBuiltIns(names []string) []string {
+	var builtIns []string
+	for _, name := range names {
+		switch name {
+		case "apikey", "jwt", "rbac", "none":
+			builtIns = append(builtIns, name)
+		}
+	}
+	return builtIns
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/observability"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var oauth2Tracer = otel.Tracer("auth:oauth2")
+
+// introspectionCachePrefix 缓存自省结果的键前缀，键的其余部分为令牌的 SHA-256 十六进制摘要，不保存明文令牌
+const introspectionCachePrefix = "mg:introspection:"
+
+// introspectionResult 自省结果中网关关心的字段，同时作为缓存的值
+type introspectionResult struct {
+	Active   bool   `json:"active"`
+	Username string `json:"username,omitempty"`
+	Subject  string `json:"sub,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Expiry   int64  `json:"exp,omitempty"` // 令牌过期时间，Unix 秒
+}
+
+// identity 返回写入上下文 username 的标识，依次取 username、sub、client_id
+func (r introspectionResult) identity() string {
+	for _, id := range []string{r.Username, r.Subject, r.ClientID} {
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// IntrospectionAuthenticator 通过 RFC 7662 令牌自省校验 Bearer 令牌，自省结果按令牌摘要缓存一段时间
+type IntrospectionAuthenticator struct {
+	url          string
+	clientID     string
+	clientSecret string
+	cacheTTL     time.Duration
+	client       *http.Client
+	now          func() time.Time
+}
+
+// NewIntrospectionAuthenticator 根据 security.introspection 创建令牌自省认证器
+func NewIntrospectionAuthenticator(cfg *config.Config) *IntrospectionAuthenticator {
+	in := cfg.Security.Introspection
+	return &IntrospectionAuthenticator{
+		url:          in.URL,
+		clientID:     in.ClientID,
+		clientSecret: in.ClientSecret,
+		cacheTTL:     in.CacheTTL,
+		client:       &http.Client{Timeout: in.Timeout},
+		now:          time.Now,
+	}
+}
+
+func (a *IntrospectionAuthenticator) Authenticate(c *gin.Context) {
+	ctx, span := oauth2Tracer.Start(c.Request.Context(), "Auth.OAuth2Introspection",
+		trace.WithAttributes(attribute.String("path", c.Request.URL.Path)))
+	defer span.End()
+
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		a.reject(c, span, "Bearer token required")
+		return
+	}
+
+	result, err := a.lookup(ctx, token)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Token introspection failed")
+		logger.Error("Failed to introspect OAuth2 token", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication unavailable"})
+		c.Abort()
+		return
+	}
+	if !result.Active {
+		a.reject(c, span, "Invalid or expired token")
+		return
+	}
+
+	username := result.identity()
+	span.SetAttributes(attribute.String("username", username))
+	span.SetStatus(codes.Ok, "Authentication succeeded")
+	c.Set("username", username)
+	c.Next()
+}
+
+// lookup 先查缓存，未命中时调用自省端点并缓存结果；缓存读写失败不影响认证，直接以自省端点为准
+func (a *IntrospectionAuthenticator) lookup(ctx context.Context, token string) (introspectionResult, error) {
+	key := introspectionCachePrefix + digestAPIKey(token)
+	caching := a.cacheTTL > 0 && cache.Default != nil
+	if caching {
+		cached, err := cache.Default.Get(ctx, key)
+		if err == nil {
+			var result introspectionResult
+			if json.Unmarshal([]byte(cached), &result) == nil {
+				return result, nil
+			}
+		} else if !errors.Is(err, cache.ErrNotFound) {
+			logger.Warn("Failed to read cached introspection result", zap.Error(err))
+		}
+	}
+
+	result, err := a.introspect(ctx, token)
+	if err != nil {
+		return result, err
+	}
+	// 已过期的令牌即使授权服务器仍返回 active 也视为无效
+	if result.Active && result.Expiry > 0 && result.Expiry <= a.now().Unix() {
+		result.Active = false
+	}
+	if caching {
+		ttl := a.cacheTTL
+		if result.Active && result.Expiry > 0 {
+			if remaining := time.Unix(result.Expiry, 0).Sub(a.now()); remaining < ttl {
+				ttl = remaining
+			}
+		}
+		value, _ := json.Marshal(result)
+		if err := cache.Default.Set(ctx, key, string(value), ttl); err != nil {
+			logger.Warn("Failed to cache introspection result", zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+// introspect 以表单 POST 令牌到自省端点，客户端凭证通过 HTTP Basic 认证发送
+func (a *IntrospectionAuthenticator) introspect(ctx context.Context, token string) (introspectionResult, error) {
+	var result introspectionResult
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return result, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	return result, nil
+}
+
+// reject 记录认证失败并返回 401
+func (a *IntrospectionAuthenticator) reject(c *gin.Context, span trace.Span, msg string) {
+	span.SetStatus(codes.Error, msg)
+	logger.Warn("OAuth2 token authentication failed",
+		zap.String("path", c.Request.URL.Path),
+		zap.String("reason", msg))
+	observability.OAuth2AuthFailures.WithLabelValues(c.Request.URL.Path).Inc()
+	c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+	c.Abort()
+}
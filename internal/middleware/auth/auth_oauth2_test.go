@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startIntrospectionServer 启动自省端点：active-token 有效，expired-token 已过期，其余令牌无效；返回调用次数计数
+func startIntrospectionServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "gateway" || secret != "gateway-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.PostFormValue("token") {
+		case "active-token":
+			json.NewEncoder(w).Encode(map[string]any{"active": true, "sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+		case "expired-token":
+			json.NewEncoder(w).Encode(map[string]any{"active": true, "sub": "bob", "exp": time.Now().Add(-time.Minute).Unix()})
+		case "broken-token":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"active": false})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newIntrospectionTestRouter 创建经过令牌自省认证的路由，上游返回上下文中的 username
+func newIntrospectionTestRouter(in config.OAuth2Introspection) *gin.Engine {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Security: config.Security{AuthMode: "oauth2-introspection", Introspection: in}}
+
+	r := gin.New()
+	r.Use(NewAuthenticator(cfg).Authenticate)
+	r.GET("/api/v1/user", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("username"))
+	})
+	return r
+}
+
+func TestIntrospectionAuthenticator(t *testing.T) {
+	store := cache.InitTestStore()
+	server, calls := startIntrospectionServer(t)
+	r := newIntrospectionTestRouter(config.OAuth2Introspection{
+		URL: server.URL, ClientID: "gateway", ClientSecret: "gateway-secret", Timeout: time.Second, CacheTTL: time.Minute,
+	})
+
+	w := requestWithKey(r, "Authorization", "Bearer active-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+
+	// 第二次请求命中缓存，不再调用自省端点
+	w = requestWithKey(r, "Authorization", "Bearer active-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// 缓存键为令牌摘要，不包含明文令牌
+	cached, err := store.Get(context.Background(), introspectionCachePrefix+digestAPIKey("active-token"))
+	require.NoError(t, err)
+	assert.Contains(t, cached, `"active":true`)
+	assert.NotContains(t, cached, "active-token")
+
+	for _, header := range []string{"", "Basic abc", "Bearer revoked-token", "Bearer expired-token"} {
+		assert.Equal(t, http.StatusUnauthorized, requestWithKey(r, "Authorization", header).Code, header)
+	}
+	// 无效结果同样被缓存
+	before := calls.Load()
+	assert.Equal(t, http.StatusUnauthorized, requestWithKey(r, "Authorization", "Bearer revoked-token").Code)
+	assert.Equal(t, before, calls.Load())
+
+	assert.Equal(t, http.StatusServiceUnavailable, requestWithKey(r, "Authorization", "Bearer broken-token").Code)
+}
+
+func TestIntrospectionAuthenticator_NoCache(t *testing.T) {
+	cache.InitTestStore()
+	server, calls := startIntrospectionServer(t)
+	r := newIntrospectionTestRouter(config.OAuth2Introspection{URL: server.URL, ClientID: "gateway", ClientSecret: "gateway-secret"})
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, requestWithKey(r, "Authorization", "Bearer active-token").Code)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	// 客户端凭证错误时自省端点返回 401，网关无法完成认证
+	r = newIntrospectionTestRouter(config.OAuth2Introspection{URL: server.URL, ClientID: "gateway", ClientSecret: "wrong"})
+	w := requestWithKey(r, "Authorization", "Bearer active-token")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Authentication unavailable")
+}
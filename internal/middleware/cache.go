@@ -2,13 +2,22 @@ package middleware
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/penwyp/mini-gateway/internal/core/observability"
 
 	"github.com/gin-gonic/gin"
 	"github.com/penwyp/mini-gateway/config"
 	"github.com/penwyp/mini-gateway/internal/core/health" // 引入 health 包
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	"github.com/penwyp/mini-gateway/pkg/cache"
 	"github.com/penwyp/mini-gateway/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -28,6 +37,12 @@ func CacheMiddleware() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		cachePath, cacheable := cacheKeyPath(c.Request, rule)
+		if !cacheable {
+			logger.Debug("Skipping cache for authenticated request", zap.String("path", path))
+			c.Next()
+			return
+		}
 
 		// 获取目标主机（假设从路由规则中提取第一个目标）
 		target := ""
@@ -38,18 +53,52 @@ func CacheMiddleware() gin.HandlerFunc {
 			}
 		}
 
+		// Redis 不可用时直接按 failurePolicy 处理，不等待请求超时
+		if cache.Degraded() {
+			if !rejectOnRedisFailure(c, cache.ErrUnavailable) {
+				c.Next()
+			}
+			return
+		}
+
 		// 增加请求计数并检查阈值
-		count := health.GetGlobalHealthChecker().IncrementRequestCount(c.Request.Context(), path, rule.TTL)
+		count, err := health.GetGlobalHealthChecker().IncrementRequestCount(c.Request.Context(), path, rule.TTL)
+		if err != nil && rejectOnRedisFailure(c, err) {
+			return
+		}
 		logger.Debug("Request count", zap.String("path", path), zap.Int64("count", count))
 
 		// 检查缓存
-		if content, found := health.GetGlobalHealthChecker().CheckCache(c.Request.Context(), method, path, target); found {
+		content, found, err := health.GetGlobalHealthChecker().CheckCache(c.Request.Context(), method, cachePath, target)
+		if err != nil && rejectOnRedisFailure(c, err) {
+			return
+		}
+		if found {
 			observability.CacheHits.WithLabelValues(method, path, target).Inc()
-			c.String(http.StatusOK, content)
+			c.Set("cache_hit", true) // 供调试响应头标记缓存命中
+			writeCachedResponse(c, content)
 			c.Abort()
 			return
 		}
 
+		// 保留最近一次成功响应时，熔断或无可用目标时可按降级策略 cached 返回
+		servedStale := false
+		if rule.StaleTTL > 0 {
+			proxy.SetCachedFallback(c, func(c *gin.Context) bool {
+				content, found, err := health.GetGlobalHealthChecker().CheckCache(c.Request.Context(), method, staleCachePath(cachePath), target)
+				if err != nil {
+					logger.Error("Failed to read stale cache", zap.String("path", path), zap.Error(err))
+					return false
+				}
+				if !found {
+					return false
+				}
+				servedStale = true
+				writeCachedResponse(c, content)
+				return true
+			})
+		}
+
 		if count < int64(rule.Threshold) {
 			c.Next()
 			return
@@ -61,14 +110,140 @@ func CacheMiddleware() gin.HandlerFunc {
 		c.Next()
 
 		observability.CacheMisses.WithLabelValues(method, path, target).Inc()
-		if c.Writer.Status() == http.StatusOK {
+		if c.Writer.Status() == http.StatusOK && !servedStale {
 			content := writer.body.String()
-			err := health.GetGlobalHealthChecker().SetCache(c.Request.Context(), method, path, content, rule.TTL)
+			if rule.Compress {
+				compressed, err := compressForCache(writer.body.Bytes(), c.Writer.Header().Get("Content-Encoding"))
+				if err != nil {
+					logger.Warn("Skipping cache for response that cannot be stored compressed",
+						zap.String("path", path),
+						zap.Error(err))
+					return
+				}
+				content = string(compressed)
+			}
+			err := health.GetGlobalHealthChecker().SetCache(c.Request.Context(), method, cachePath, content, rule.TTL)
 			if err != nil {
 				logger.Error("Failed to cache response", zap.Error(err))
 			}
+			if rule.StaleTTL > 0 {
+				err := health.GetGlobalHealthChecker().SetCache(c.Request.Context(), method, staleCachePath(cachePath), content, rule.StaleTTL)
+				if err != nil {
+					logger.Error("Failed to keep stale response", zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// rejectOnRedisFailure 处理读取缓存时的 Redis 失败：failurePolicy 为 closed 时返回 503 并中止请求，
+// 否则按未命中继续转发，返回请求是否已被中止
+func rejectOnRedisFailure(c *gin.Context, err error) bool {
+	policy := config.FailurePolicyOpen
+	if config.GetConfig().Caching.FailurePolicy == config.FailurePolicyClosed {
+		policy = config.FailurePolicyClosed
+	}
+	observability.RedisFailures.WithLabelValues("cache", policy).Inc()
+	logger.Error("Failed to read cache",
+		zap.String("path", c.Request.URL.Path),
+		zap.String("failurePolicy", policy),
+		zap.Error(err))
+	if policy == config.FailurePolicyOpen {
+		return false
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable"})
+	c.Abort()
+	return true
+}
+
+// cacheKeyPath 返回请求的缓存路径，携带 Authorization 的请求仅在规则允许时缓存，并按凭证摘要隔离
+func cacheKeyPath(r *http.Request, rule *config.CachingRule) (string, bool) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return r.URL.Path, true
+	}
+	if !rule.Authenticated {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(authorization))
+	return r.URL.Path + "@" + hex.EncodeToString(sum[:8]), true
+}
+
+// staleCachePath 返回保留最近一次成功响应的缓存路径，其过期时间由 staleTTL 决定，与普通缓存互不影响
+func staleCachePath(cachePath string) string {
+	return cachePath + "#stale"
+}
+
+// compressForCache 返回响应体的 gzip 压缩形式，上游已返回 gzip 时直接使用，其他编码无法统一处理时返回错误
+func compressForCache(body []byte, contentEncoding string) ([]byte, error) {
+	switch strings.ToLower(contentEncoding) {
+	case "gzip":
+		return body, nil
+	case "", "identity":
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCachedResponse 写入缓存的响应，压缩形式的缓存对支持 gzip 的客户端原样返回，否则解压后返回
+func writeCachedResponse(c *gin.Context, content string) {
+	if !isGzipped(content) {
+		c.String(http.StatusOK, content)
+		return
+	}
+
+	c.Header("Vary", "Accept-Encoding")
+	if acceptsGzip(c.Request) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(content))
+		return
+	}
+
+	zr, err := gzip.NewReader(strings.NewReader(content))
+	if err == nil {
+		var body []byte
+		if body, err = io.ReadAll(zr); err == nil {
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", body)
+			return
+		}
+	}
+	logger.Error("Failed to decompress cached response",
+		zap.String("path", c.Request.URL.Path),
+		zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+}
+
+// isGzipped 根据 gzip 魔数判断缓存内容是否为压缩形式，兼容切换 compress 选项前写入的缓存
+func isGzipped(content string) bool {
+	return len(content) >= 2 && content[0] == 0x1f && content[1] == 0x8b
+}
+
+// acceptsGzip 检查客户端是否接受 gzip 编码
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.TrimSpace(coding) != "*" {
+			continue
+		}
+		// q=0 表示明确拒绝该编码
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
 		}
+		return true
 	}
+	return false
 }
 
 // responseWriter 用于捕获响应内容
@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/internal/core/health"
+	"github.com/penwyp/mini-gateway/internal/core/routing/proxy"
+	"github.com/penwyp/mini-gateway/pkg/cache"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveCached 使用给定的缓存内容和 Accept-Encoding 处理一次缓存命中的请求
+func serveCached(content, acceptEncoding string) *httptest.ResponseRecorder {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/user", func(c *gin.Context) {
+		writeCachedResponse(c, content)
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// gunzip 解压 gzip 数据
+func gunzip(t *testing.T, data []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCompressForCache(t *testing.T) {
+	compressed, err := compressForCache([]byte(`{"user":"alice"}`), "")
+	require.NoError(t, err)
+	assert.True(t, isGzipped(string(compressed)))
+	assert.Equal(t, `{"user":"alice"}`, gunzip(t, compressed))
+
+	// 上游已压缩的响应直接缓存，不再二次压缩
+	again, err := compressForCache(compressed, "gzip")
+	require.NoError(t, err)
+	assert.Equal(t, compressed, again)
+
+	_, err = compressForCache([]byte("data"), "br")
+	assert.Error(t, err)
+}
+
+func TestWriteCachedResponse_GzipClientGetsStoredBytes(t *testing.T) {
+	stored, err := compressForCache([]byte(`{"user":"alice"}`), "")
+	require.NoError(t, err)
+
+	w := serveCached(string(stored), "br, gzip;q=0.8")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, stored, w.Body.Bytes(), "cached gzip body must be served as stored, without re-compression")
+}
+
+func TestWriteCachedResponse_DecompressesForOtherClients(t *testing.T) {
+	stored, err := compressForCache([]byte(`{"user":"alice"}`), "")
+	require.NoError(t, err)
+
+	for _, acceptEncoding := range []string{"", "identity", "gzip;q=0"} {
+		w := serveCached(string(stored), acceptEncoding)
+
+		assert.Equal(t, http.StatusOK, w.Code, acceptEncoding)
+		assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+		assert.Equal(t, `{"user":"alice"}`, w.Body.String(), acceptEncoding)
+	}
+}
+
+func TestWriteCachedResponse_Uncompressed(t *testing.T) {
+	w := serveCached(`{"user":"alice"}`, "gzip")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"user":"alice"}`, w.Body.String())
+}
+
+func TestCacheKeyPath(t *testing.T) {
+	rule := &config.CachingRule{Path: "/api/v1/user", Method: "GET"}
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+
+	path, ok := cacheKeyPath(req, rule)
+	assert.True(t, ok)
+	assert.Equal(t, "/api/v1/user", path)
+
+	req.Header.Set("Authorization", "Bearer alice")
+	_, ok = cacheKeyPath(req, rule)
+	assert.False(t, ok, "authenticated requests must not be cached unless the rule opts in")
+
+	rule.Authenticated = true
+	alice, ok := cacheKeyPath(req, rule)
+	assert.True(t, ok)
+	assert.NotEqual(t, "/api/v1/user", alice)
+	assert.NotContains(t, alice, "alice", "the credential itself must not appear in the cache key")
+
+	req.Header.Set("Authorization", "Bearer bob")
+	bob, _ := cacheKeyPath(req, rule)
+	assert.NotEqual(t, alice, bob)
+}
+
+// newCacheTestRouter 使用内存缓存存储和给定缓存规则创建经过缓存中间件的路由，上游固定返回 upstream
+func newCacheTestRouter(t *testing.T, rule config.CachingRule) (*gin.Engine, cache.Store) {
+	store := cache.InitTestStore()
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	health.InitHealthChecker(&config.Config{})
+	config.InitTestConfigManager()
+	config.SetConfig(&config.Config{Caching: config.Caching{Enabled: true, Rules: []config.CachingRule{rule}}})
+	t.Cleanup(config.InitTestConfigManager)
+
+	r := gin.New()
+	r.Use(CacheMiddleware())
+	r.GET(rule.Path, func(c *gin.Context) {
+		c.String(http.StatusOK, "upstream")
+	})
+	return r, store
+}
+
+func TestCacheMiddleware_AuthenticatedBypassesSharedCache(t *testing.T) {
+	rule := config.CachingRule{Path: "/api/v1/user", Method: "GET", TTL: time.Minute}
+	r, store := newCacheTestRouter(t, rule)
+	// 共享缓存中已有匿名请求的响应
+	require.NoError(t, store.Set(context.Background(), health.GetCacheKey("GET", "/api/v1/user"), "shared", 0))
+
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	req.Header.Set("Authorization", "Bearer alice")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "upstream", w.Body.String(), "the shared cache must not be consulted for authenticated requests")
+	_, err := store.Get(context.Background(), health.GetPathReqCountKey("/api/v1/user"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+}
+
+func TestCacheMiddleware_AuthenticatedOptInUsesIdentityKey(t *testing.T) {
+	rule := config.CachingRule{Path: "/api/v1/user", Method: "GET", TTL: time.Minute, Authenticated: true}
+	r, store := newCacheTestRouter(t, rule)
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	req.Header.Set("Authorization", "Bearer alice")
+	path, _ := cacheKeyPath(req, &rule)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "upstream", w.Body.String())
+	content, err := store.Get(context.Background(), health.GetCacheKey("GET", path))
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream", content)
+	_, err = store.Get(context.Background(), health.GetCacheKey("GET", "/api/v1/user"))
+	assert.ErrorIs(t, err, cache.ErrNotFound, "authenticated responses must not be written to the shared key")
+}
+
+func TestCacheMiddleware_RedisFailurePolicy(t *testing.T) {
+	rule := config.CachingRule{Path: "/api/v1/user", Method: "GET", Threshold: 10, TTL: time.Minute}
+	tests := []struct {
+		policy   string
+		wantCode int
+		wantBody string
+	}{
+		{"", http.StatusOK, "upstream"},
+		{config.FailurePolicyOpen, http.StatusOK, "upstream"},
+		{config.FailurePolicyClosed, http.StatusServiceUnavailable, `{"error":"Service temporarily unavailable"}`},
+	}
+	for _, tt := range tests {
+		t.Run("policy="+tt.policy, func(t *testing.T) {
+			r, _ := newCacheTestRouter(t, rule)
+			config.GetConfig().Caching.FailurePolicy = tt.policy
+			cache.Default = cache.NewErrorStore(errors.New("connection refused"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestCacheMiddleware_KeepsStaleResponse(t *testing.T) {
+	rule := config.CachingRule{Path: "/api/v1/user", Method: "GET", TTL: time.Minute, StaleTTL: time.Hour}
+	r, store := newCacheTestRouter(t, rule)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+
+	assert.Equal(t, "upstream", w.Body.String())
+	for _, path := range []string{"/api/v1/user", staleCachePath("/api/v1/user")} {
+		content, err := store.Get(context.Background(), health.GetCacheKey("GET", path))
+		assert.NoError(t, err)
+		assert.Equal(t, "upstream", content)
+	}
+}
+
+func TestCacheMiddleware_ServesStaleResponseAsFallback(t *testing.T) {
+	rule := config.CachingRule{Path: "/api/v1/order", Method: "GET", TTL: time.Minute, StaleTTL: time.Hour}
+	store := cache.InitTestStore()
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	health.InitHealthChecker(&config.Config{})
+	config.InitTestConfigManager()
+	config.SetConfig(&config.Config{Caching: config.Caching{Enabled: true, Rules: []config.CachingRule{rule}}})
+	t.Cleanup(config.InitTestConfigManager)
+
+	r := gin.New()
+	r.Use(CacheMiddleware())
+	// 上游不可用，按降级策略 cached 返回
+	r.GET(rule.Path, func(c *gin.Context) {
+		fallback := config.Fallback{Strategy: config.FallbackStrategyCached}
+		if !proxy.WriteFallback(c, config.RoutingRules{{Target: "http://a", Fallback: fallback}}) {
+			c.Status(http.StatusServiceUnavailable)
+		}
+	})
+	require.NoError(t, store.Set(context.Background(), health.GetCacheKey("GET", staleCachePath("/api/v1/order")), "last good", time.Hour))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/order", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "last good", w.Body.String())
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// newConcurrencyTestRouter 创建经过并发限制中间件的路由，/slow 在 release 关闭前不返回
+func newConcurrencyTestRouter(mc config.TrafficConcurrency) (*gin.Engine, chan struct{}, chan struct{}) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(ConcurrencyLimit(&config.Config{Traffic: config.Traffic{MaxConcurrent: mc}}))
+	r.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/fast", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("handler failed")
+	})
+	return r, started, release
+}
+
+func serve(r *gin.Engine, path string) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w.Code
+}
+
+func TestConcurrencyLimit_RejectsOverGlobalLimit(t *testing.T) {
+	r, started, release := newConcurrencyTestRouter(config.TrafficConcurrency{Global: 1, OnLimit: config.ConcurrencyOnLimitReject})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.Equal(t, http.StatusOK, serve(r, "/slow"))
+	}()
+	<-started
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(r, "/fast"), "the only slot is held by the slow request")
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, serve(r, "/fast"), "the slot is released when the request finishes")
+}
+
+func TestConcurrencyLimit_RouteLimitOnlyAffectsRoute(t *testing.T) {
+	r, started, release := newConcurrencyTestRouter(config.TrafficConcurrency{Routes: map[string]int64{"/slow": 1}})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(r, "/slow")
+	}()
+	<-started
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(r, "/slow"))
+	assert.Equal(t, http.StatusOK, serve(r, "/fast"), "routes without a limit are not affected")
+	close(release)
+	wg.Wait()
+}
+
+func TestConcurrencyLimit_QueueWaitsForSlot(t *testing.T) {
+	r, started, release := newConcurrencyTestRouter(config.TrafficConcurrency{
+		Global:       1,
+		OnLimit:      config.ConcurrencyOnLimitQueue,
+		QueueTimeout: 100 * time.Millisecond,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(r, "/slow")
+	}()
+	<-started
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(r, "/fast"), "queued request times out while the slot is held")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	assert.Equal(t, http.StatusOK, serve(r, "/fast"), "queued request proceeds once the slot is released")
+	wg.Wait()
+}
+
+func TestConcurrencyLimit_PanicReleasesSlot(t *testing.T) {
+	r, _, _ := newConcurrencyTestRouter(config.TrafficConcurrency{Global: 1, Routes: map[string]int64{"/panic": 1}})
+
+	assert.Equal(t, http.StatusInternalServerError, serve(r, "/panic"))
+	assert.Equal(t, http.StatusInternalServerError, serve(r, "/panic"), "a panicking request must not leak its slot")
+	assert.Equal(t, http.StatusOK, serve(r, "/fast"))
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+)
+
+// 调试相关的 HTTP 头名称
+const (
+	DebugHeader         = "X-Gateway-Debug"    // 请求头，携带调试令牌
+	DebugTargetHeader   = "X-Gateway-Target"   // 选中的上游目标
+	DebugBalancerHeader = "X-Gateway-Balancer" // 负载均衡器类型
+	DebugEnvHeader      = "X-Gateway-Env"      // 选中目标所属环境
+	DebugCacheHeader    = "X-Gateway-Cache"    // 响应是否来自缓存：HIT 或 MISS
+)
+
+// DebugHeaders 返回调试响应头中间件，请求携带与配置一致的调试令牌时，在响应中附带上游选择信息
+// 调试请求头会在转发前移除，避免令牌泄露给后端
+func DebugHeaders(cfg *config.Config) gin.HandlerFunc {
+	token := []byte(cfg.Server.Debug.Token)
+	return func(c *gin.Context) {
+		provided := c.GetHeader(DebugHeader)
+		if provided == "" {
+			c.Next()
+			return
+		}
+		c.Request.Header.Del(DebugHeader)
+		if len(token) == 0 || subtle.ConstantTimeCompare([]byte(provided), token) != 1 {
+			c.Next()
+			return
+		}
+
+		c.Writer = &debugResponseWriter{ResponseWriter: c.Writer, ctx: c}
+		c.Next()
+	}
+}
+
+// debugResponseWriter 在响应头写出前注入调试响应头，此时目标选择与缓存检查均已完成
+type debugResponseWriter struct {
+	gin.ResponseWriter
+	ctx      *gin.Context
+	injected bool
+}
+
+// inject 根据 gin 上下文中代理与缓存记录的信息写入调试响应头，仅执行一次
+func (w *debugResponseWriter) inject() {
+	if w.injected {
+		return
+	}
+	w.injected = true
+	header := w.ResponseWriter.Header()
+	for name, key := range map[string]string{
+		DebugTargetHeader:   "proxy_target",
+		DebugBalancerHeader: "proxy_balancer",
+		DebugEnvHeader:      "proxy_env",
+	} {
+		if value := w.ctx.GetString(key); value != "" {
+			header.Set(name, value)
+		}
+	}
+	if w.ctx.GetBool("cache_hit") {
+		header.Set(DebugCacheHeader, "HIT")
+	} else {
+		header.Set(DebugCacheHeader, "MISS")
+	}
+}
+
+func (w *debugResponseWriter) WriteHeader(code int) {
+	w.inject()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *debugResponseWriter) WriteHeaderNow() {
+	w.inject()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *debugResponseWriter) Write(b []byte) (int, error) {
+	w.inject()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *debugResponseWriter) WriteString(s string) (int, error) {
+	w.inject()
+	return w.ResponseWriter.WriteString(s)
+}
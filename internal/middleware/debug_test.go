@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveDebug 以模拟代理的处理函数处理一次请求，debugToken 非空时携带调试请求头
+func serveDebug(cfg *config.Config, debugToken string, cacheHit bool) (*httptest.ResponseRecorder, http.Header) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(DebugHeaders(cfg))
+	var upstreamHeader http.Header
+	r.GET("/api/v1/user", func(c *gin.Context) {
+		upstreamHeader = c.Request.Header.Clone()
+		if cacheHit {
+			c.Set("cache_hit", true)
+			c.String(http.StatusOK, "cached")
+			return
+		}
+		c.Set("proxy_target", "http://127.0.0.1:8381")
+		c.Set("proxy_env", "canary")
+		c.Set("proxy_balancer", "round_robin")
+		// 与 ReverseProxy 一致，先写状态码再写响应体
+		c.Writer.WriteHeader(http.StatusOK)
+		c.Writer.Write([]byte("proxied"))
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	if debugToken != "" {
+		req.Header.Set(DebugHeader, debugToken)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, upstreamHeader
+}
+
+func TestDebugHeaders_Authorized(t *testing.T) {
+	cfg := &config.Config{Server: config.Server{Debug: config.Debug{Enabled: true, Token: "s3cret"}}}
+
+	w, upstream := serveDebug(cfg, "s3cret", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://127.0.0.1:8381", w.Header().Get(DebugTargetHeader))
+	assert.Equal(t, "round_robin", w.Header().Get(DebugBalancerHeader))
+	assert.Equal(t, "canary", w.Header().Get(DebugEnvHeader))
+	assert.Equal(t, "MISS", w.Header().Get(DebugCacheHeader))
+	assert.Empty(t, upstream.Get(DebugHeader), "debug token must not be forwarded upstream")
+
+	w, _ = serveDebug(cfg, "s3cret", true)
+	assert.Equal(t, "HIT", w.Header().Get(DebugCacheHeader))
+	assert.Empty(t, w.Header().Get(DebugTargetHeader))
+}
+
+func TestDebugHeaders_Unauthorized(t *testing.T) {
+	cfg := &config.Config{Server: config.Server{Debug: config.Debug{Enabled: true, Token: "s3cret"}}}
+
+	for name, token := range map[string]string{"missing": "", "wrong": "guess"} {
+		w, upstream := serveDebug(cfg, token, false)
+		assert.Equal(t, http.StatusOK, w.Code, name)
+		for _, header := range []string{DebugTargetHeader, DebugBalancerHeader, DebugEnvHeader, DebugCacheHeader} {
+			assert.Empty(t, w.Header().Get(header), name)
+		}
+		assert.Empty(t, upstream.Get(DebugHeader), name)
+	}
+
+	// 未配置令牌时任何请求都不返回调试响应头
+	w, _ := serveDebug(&config.Config{}, "anything", false)
+	assert.Empty(t, w.Header().Get(DebugTargetHeader))
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求 ID 的 HTTP 头名称
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 客户端传入的请求 ID 的最大长度
+const maxRequestIDLength = 128
+
+// RequestID 返回请求 ID 中间件，沿用客户端传入的合法 X-Request-ID 或生成新的 UUID，
+// 并写入请求头（随代理请求转发到后端）、gin 上下文与响应头
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+			c.Request.Header.Set(RequestIDHeader, requestID)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// validRequestID 判断客户端传入的请求 ID 是否可以沿用：非空、不超过 maxRequestIDLength 且只含字母、数字与 . _ -，
+// 避免超长或带控制字符的值被写入日志和后端请求
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch ch := id[i]; {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '.', ch == '_', ch == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"missing", "", false},
+		{"valid", "req-1.2_ABC", true},
+		{"max length", strings.Repeat("a", maxRequestIDLength), true},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"space", "req 1", false},
+		{"log injection", "req\r\nlevel=error", false},
+		{"non-ascii", "请求", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded, stored string
+			r := gin.New()
+			r.Use(RequestID())
+			r.GET("/", func(c *gin.Context) {
+				forwarded = c.Request.Header.Get(RequestIDHeader)
+				stored = c.GetString("request_id")
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tt.keep {
+				assert.Equal(t, tt.incoming, got)
+			} else {
+				_, err := uuid.Parse(got)
+				assert.NoError(t, err, "invalid request ID should be replaced by a UUID")
+			}
+			assert.Equal(t, got, forwarded, "request header forwarded to the backend")
+			assert.Equal(t, got, stored, "request ID stored in the gin context")
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	"go.uber.org/zap"
+)
+
+// defaultScriptTimeout 脚本默认执行超时时间
+const defaultScriptTimeout = 50 * time.Millisecond
+
+// 脚本沙箱的资源上限，执行时间由超时控制，以下限制约束单次执行可占用的内存
+const (
+	scriptCallStackSize   = 64      // 调用栈深度上限，防止无限递归
+	scriptRegistrySize    = 256     // 数据栈初始大小
+	scriptRegistryMaxSize = 4096    // 数据栈最大大小，超出时脚本报错
+	scriptMaxStringSize   = 1 << 20 // string.rep 单次生成字符串的最大字节数
+)
+
+// routeScript 预编译的路由脚本
+type routeScript struct {
+	proto   *lua.FunctionProto
+	timeout time.Duration
+}
+
+// scriptResponse 脚本通过 respond 返回的响应
+type scriptResponse struct {
+	status int
+	body   string
+}
+
+// Script 返回路由脚本中间件，按路由路径执行 Lua 脚本
+// 脚本可通过 request 表读取请求信息，通过 set_header/del_header 修改请求头，通过 respond 直接返回响应
+func Script(cfg *config.Config) gin.HandlerFunc {
+	scripts := make(map[string]*routeScript, len(cfg.Routing.Scripts))
+	for path, scriptCfg := range cfg.Routing.Scripts {
+		script, err := compileRouteScript(path, scriptCfg)
+		if err != nil {
+			logger.Error("Failed to load route script",
+				zap.String("path", path),
+				zap.Error(err))
+			continue
+		}
+		scripts[path] = script
+		logger.Info("Route script loaded", zap.String("path", path))
+	}
+
+	return func(c *gin.Context) {
+		script, ok := scripts[c.FullPath()]
+		if !ok {
+			script, ok = scripts[c.Request.URL.Path]
+		}
+		if !ok {
+			c.Next()
+			return
+		}
+
+		resp, err := script.run(c)
+		if resp != nil {
+			c.String(resp.status, resp.body)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logger.Error("Route script execution failed",
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Script execution failed"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// compileRouteScript 读取并编译脚本，避免每个请求重复解析
+func compileRouteScript(path string, scriptCfg config.RouteScript) (*routeScript, error) {
+	source := scriptCfg.Source
+	if source == "" && scriptCfg.File != "" {
+		data, err := os.ReadFile(scriptCfg.File)
+		if err != nil {
+			return nil, err
+		}
+		source = string(data)
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(source), path)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := scriptCfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultScriptTimeout
+	}
+	return &routeScript{proto: proto, timeout: timeout}, nil
+}
+
+// run 在独立的沙箱中执行脚本，超时后中断执行
+func (s *routeScript) run(c *gin.Context) (*scriptResponse, error) {
+	L := newSandboxState()
+	defer L.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	var resp *scriptResponse
+	L.SetGlobal("request", newRequestTable(L, c))
+	L.SetGlobal("set_header", L.NewFunction(func(L *lua.LState) int {
+		c.Request.Header.Set(L.CheckString(1), L.CheckString(2))
+		return 0
+	}))
+	L.SetGlobal("del_header", L.NewFunction(func(L *lua.LState) int {
+		c.Request.Header.Del(L.CheckString(1))
+		return 0
+	}))
+	L.SetGlobal("respond", L.NewFunction(func(L *lua.LState) int {
+		resp = &scriptResponse{status: L.CheckInt(1), body: L.OptString(2, "")}
+		// 抛出错误以立即终止脚本，调用方根据 resp 判断是否为主动返回
+		L.RaiseError("respond")
+		return 0
+	}))
+
+	L.Push(L.NewFunctionFromProto(s.proto))
+	err := L.PCall(0, lua.MultRet, nil)
+	return resp, err
+}
+
+// newSandboxState 创建仅包含基础库、字符串、表和数学库的 Lua 状态，禁止访问文件系统和操作系统，
+// 并限制调用栈、数据栈和 string.rep 的大小
+func newSandboxState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       scriptCallStackSize,
+		RegistrySize:        scriptRegistrySize,
+		RegistryMaxSize:     scriptRegistryMaxSize,
+		MinimizeStackMemory: true,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	if str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", L.NewFunction(boundedStringRep))
+	}
+	return L
+}
+
+// boundedStringRep 替换 string.rep，生成的字符串超过 scriptMaxStringSize 时报错，
+// 避免单次调用在超时生效前分配大量内存
+func boundedStringRep(L *lua.LState) int {
+	s := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 || len(s) == 0 {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if n > scriptMaxStringSize/len(s) {
+		L.RaiseError("string.rep result exceeds %d bytes", scriptMaxStringSize)
+		return 0
+	}
+	L.Push(lua.LString(strings.Repeat(s, n)))
+	return 1
+}
+
+// newRequestTable 构建脚本可读取的 request 表
+func newRequestTable(L *lua.LState, c *gin.Context) *lua.LTable {
+	headers := L.NewTable()
+	for name := range c.Request.Header {
+		headers.RawSetString(name, lua.LString(c.Request.Header.Get(name)))
+	}
+
+	req := L.NewTable()
+	req.RawSetString("method", lua.LString(c.Request.Method))
+	req.RawSetString("path", lua.LString(c.Request.URL.Path))
+	req.RawSetString("query", lua.LString(c.Request.URL.RawQuery))
+	req.RawSetString("client_ip", lua.LString(c.ClientIP()))
+	req.RawSetString("headers", headers)
+	return req
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// newScriptTestRouter 创建挂载脚本中间件的测试路由，返回上游收到的请求头
+func newScriptTestRouter(scripts map[string]config.RouteScript) (*gin.Engine, *http.Header) {
+	logger.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Routing: config.Routing{Scripts: scripts}}
+	received := &http.Header{}
+	r := gin.New()
+	r.Use(Script(cfg))
+	r.GET("/api/v1/user", func(c *gin.Context) {
+		*received = c.Request.Header.Clone()
+		c.String(http.StatusOK, "upstream")
+	})
+	return r, received
+}
+
+// TestScript_AddHeader 测试脚本添加请求头后继续转发
+func TestScript_AddHeader(t *testing.T) {
+	r, received := newScriptTestRouter(map[string]config.RouteScript{
+		"/api/v1/user": {Source: `set_header("X-Script", "added-" .. request.method)`},
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "upstream", w.Body.String())
+	assert.Equal(t, "added-GET", received.Get("X-Script"))
+}
+
+// TestScript_Reject 测试脚本直接返回响应，请求不再转发
+func TestScript_Reject(t *testing.T) {
+	r, received := newScriptTestRouter(map[string]config.RouteScript{
+		"/api/v1/user": {Source: `
+if request.headers["X-Block"] == "yes" then
+  respond(403, "blocked by script")
+end
+set_header("X-Reached", "1")`},
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/user", nil)
+	req.Header.Set("X-Block", "yes")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "blocked by script", w.Body.String())
+	assert.Empty(t, *received, "upstream should not be called")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", received.Get("X-Reached"))
+}
+
+// TestScript_Timeout 测试超时的脚本被中断并返回错误
+func TestScript_Timeout(t *testing.T) {
+	r, received := newScriptTestRouter(map[string]config.RouteScript{
+		"/api/v1/user": {Source: `while true do end`, Timeout: 20 * time.Millisecond},
+	})
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Empty(t, *received)
+}
+
+// TestScript_Sandbox 测试脚本无法访问文件系统和操作系统库
+func TestScript_Sandbox(t *testing.T) {
+	r, _ := newScriptTestRouter(map[string]config.RouteScript{
+		"/api/v1/user": {Source: `if os ~= nil or io ~= nil or dofile ~= nil then respond(500, "unsafe") end`},
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestScript_ResourceLimits 测试超出调用栈、数据栈或字符串大小上限的脚本被中断并返回错误
+func TestScript_ResourceLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"unbounded recursion", `local function f(n) return f(n + 1) + 1 end f(1)`},
+		{"huge unpack", `local t = {} for i = 1, 100000 do t[i] = i end unpack(t)`},
+		{"huge string", `local s = string.rep("x", 1000000000)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, received := newScriptTestRouter(map[string]config.RouteScript{
+				"/api/v1/user": {Source: tt.source, Timeout: time.Second},
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Empty(t, *received)
+		})
+	}
+
+	r, received := newScriptTestRouter(map[string]config.RouteScript{
+		"/api/v1/user": {Source: `set_header("X-Rep", string.rep("ab", 3))`},
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ababab", received.Get("X-Rep"))
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryMaxEntries 未配置时内存缓存最多保存的键数量
+const DefaultMemoryMaxEntries = 10000
+
+// memoryEntry 内存缓存中的一个键，字符串值与哈希值二选一
+type memoryEntry struct {
+	key       string
+	value     string
+	hash      map[string]string
+	expiresAt time.Time // 零值表示不过期
+}
+
+// memoryStore 进程内的缓存存储，适合单实例部署；键数量达到上限时淘汰最久未访问的键
+type memoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List // 队首为最近访问的键
+	now        func() time.Time
+}
+
+// NewMemoryStore 创建最多保存 maxEntries 个键的内存缓存存储，maxEntries 不大于 0 时使用 DefaultMemoryMaxEntries
+func NewMemoryStore(maxEntries int) Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMemoryMaxEntries
+	}
+	return &memoryStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// lookup 返回未过期的键并标记为最近访问，过期的键在这里删除，调用方需持有锁
+func (s *memoryStore) lookup(key string) *memoryEntry {
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !s.now().Before(entry.expiresAt) {
+		s.remove(elem)
+		return nil
+	}
+	s.lru.MoveToFront(elem)
+	return entry
+}
+
+// insert 新建键，超过上限时淘汰最久未访问的键，调用方需持有锁
+func (s *memoryStore) insert(entry *memoryEntry) {
+	s.entries[entry.key] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *memoryStore) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*memoryEntry).key)
+}
+
+// expiry 将 ttl 转为过期时间，ttl 为 0 时不过期
+func (s *memoryStore) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return s.now().Add(ttl)
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.lookup(key)
+	if entry == nil {
+		return "", ErrNotFound
+	}
+	if entry.hash != nil {
+		return "", fmt.Errorf("cache: key %s holds a hash", key)
+	}
+	return entry.value, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.lookup(key); entry != nil {
+		entry.value, entry.hash, entry.expiresAt = value, nil, s.expiry(ttl)
+		return nil
+	}
+	s.insert(&memoryEntry{key: key, value: value, expiresAt: s.expiry(ttl)})
+	return nil
+}
+
+func (s *memoryStore) Incr(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, _, err := s.incr(key)
+	return count, err
+}
+
+func (s *memoryStore) IncrExpire(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, entry, err := s.incr(key)
+	if err != nil {
+		return 0, err
+	}
+	entry.expiresAt = s.expiry(ttl)
+	return count, nil
+}
+
+// incr 增加计数，同时返回计数所在的键，调用方需持有锁
+func (s *memoryStore) incr(key string) (int64, *memoryEntry, error) {
+	entry := s.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{key: key, value: "1"}
+		s.insert(entry)
+		return 1, entry, nil
+	}
+	if entry.hash != nil {
+		return 0, nil, fmt.Errorf("cache: key %s holds a hash", key)
+	}
+	count, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cache: key %s is not an integer", key)
+	}
+	count++
+	entry.value = strconv.FormatInt(count, 10)
+	return count, entry, nil
+}
+
+func (s *memoryStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.lookup(key); entry != nil {
+		entry.expiresAt = s.expiry(ttl)
+	}
+	return nil
+}
+
+func (s *memoryStore) Del(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := s.entries[key]; ok {
+			s.remove(elem)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DelPrefix(ctx context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, elem := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(elem)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) HGet(ctx context.Context, key, field string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.lookup(key)
+	if entry == nil {
+		return "", ErrNotFound
+	}
+	value, ok := entry.hash[field]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *memoryStore) HSet(ctx context.Context, key string, values map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{key: key, hash: make(map[string]string, len(values))}
+		s.insert(entry)
+	} else if entry.hash == nil {
+		return fmt.Errorf("cache: key %s does not hold a hash", key)
+	}
+	for field, value := range values {
+		entry.hash[field] = value
+	}
+	return nil
+}
+
+func (s *memoryStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]string)
+	if entry := s.lookup(key); entry != nil {
+		for field, value := range entry.hash {
+			values[field] = value
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMemoryStore 创建使用可控时钟的内存缓存存储
+func newTestMemoryStore(maxEntries int) (*memoryStore, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore(maxEntries).(*memoryStore)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestMemoryStore_GetSetWithTTL(t *testing.T) {
+	ctx := context.Background()
+	s, now := newTestMemoryStore(10)
+
+	_, err := s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, s.Set(ctx, "k", "v", time.Minute))
+	require.NoError(t, s.Set(ctx, "forever", "v", 0))
+	value, err := s.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, "v", value)
+
+	*now = now.Add(time.Minute)
+	_, err = s.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrNotFound, "keys expire after their ttl")
+	_, err = s.Get(ctx, "forever")
+	assert.NoError(t, err)
+}
+
+func TestMemoryStore_IncrAndExpire(t *testing.T) {
+	ctx := context.Background()
+	s, now := newTestMemoryStore(10)
+
+	for want := int64(1); want <= 3; want++ {
+		count, err := s.Incr(ctx, "count")
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+	}
+	require.NoError(t, s.Expire(ctx, "count", time.Second))
+	*now = now.Add(time.Second)
+	count, err := s.Incr(ctx, "count")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "an expired counter starts over")
+
+	require.NoError(t, s.Set(ctx, "text", "abc", 0))
+	_, err = s.Incr(ctx, "text")
+	assert.EqualError(t, err, "cache: key text is not an integer")
+}
+
+func TestMemoryStore_IncrExpire(t *testing.T) {
+	ctx := context.Background()
+	s, now := newTestMemoryStore(10)
+
+	// 每次增加计数都重置过期时间
+	for want := int64(1); want <= 3; want++ {
+		count, err := s.IncrExpire(ctx, "count", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+		*now = now.Add(900 * time.Millisecond)
+	}
+	*now = now.Add(100 * time.Millisecond)
+	count, err := s.IncrExpire(ctx, "count", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "an expired counter starts over")
+
+	// ttl 为 0 时不过期
+	_, err = s.IncrExpire(ctx, "count", 0)
+	require.NoError(t, err)
+	*now = now.Add(time.Hour)
+	value, err := s.Get(ctx, "count")
+	require.NoError(t, err)
+	assert.Equal(t, "2", value)
+
+	require.NoError(t, s.HSet(ctx, "hash", map[string]string{"a": "1"}))
+	_, err = s.IncrExpire(ctx, "hash", time.Second)
+	assert.EqualError(t, err, "cache: key hash holds a hash")
+}
+
+func TestMemoryStore_Hash(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestMemoryStore(10)
+
+	require.NoError(t, s.HSet(ctx, "h", map[string]string{"a": "1", "b": "2"}))
+	require.NoError(t, s.HSet(ctx, "h", map[string]string{"b": "3"}))
+	value, err := s.HGet(ctx, "h", "b")
+	require.NoError(t, err)
+	assert.Equal(t, "3", value)
+	_, err = s.HGet(ctx, "h", "c")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	all, err := s.HGetAll(ctx, "h")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "3"}, all)
+	all, err = s.HGetAll(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	_, err = s.Get(ctx, "h")
+	assert.EqualError(t, err, "cache: key h holds a hash")
+}
+
+func TestMemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestMemoryStore(2)
+
+	require.NoError(t, s.Set(ctx, "a", "1", 0))
+	require.NoError(t, s.Set(ctx, "b", "2", 0))
+	_, err := s.Get(ctx, "a") // a 成为最近访问的键
+	require.NoError(t, err)
+	require.NoError(t, s.Set(ctx, "c", "3", 0))
+
+	_, err = s.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotFound, "the least recently used key is evicted")
+	_, err = s.Get(ctx, "a")
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.lru.Len())
+}
+
+func TestMemoryStore_Del(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestMemoryStore(10)
+	for _, key := range []string{"mg:cache:a", "mg:cache:b", "mg:health:a"} {
+		require.NoError(t, s.Set(ctx, key, "v", 0))
+	}
+
+	require.NoError(t, s.DelPrefix(ctx, "mg:cache:"))
+	require.NoError(t, s.Del(ctx, "missing"))
+	_, err := s.Get(ctx, "mg:cache:a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Get(ctx, "mg:health:a")
+	assert.NoError(t, err)
+
+	require.NoError(t, s.Del(ctx, "mg:health:a"))
+	assert.Zero(t, s.lru.Len())
+}
+
+func TestInit_MemoryBackend(t *testing.T) {
+	logger.InitTestLogger()
+	t.Cleanup(func() { Default = nil })
+
+	Init(&config.Config{Cache: config.Cache{Backend: config.CacheBackendMemory, MaxEntries: 5}})
+	assert.Nil(t, client)
+	assert.False(t, Degraded())
+	if assert.IsType(t, &memoryStore{}, Default) {
+		assert.Equal(t, 5, Default.(*memoryStore).maxEntries)
+	}
+}
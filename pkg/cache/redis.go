@@ -2,8 +2,11 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/penwyp/mini-gateway/config"
@@ -12,25 +15,147 @@ import (
 	"go.uber.org/zap"
 )
 
-// Client 是全局的 Redis 客户端实例
-var Client *redis.Client
+// client 是 redis 后端使用的 Redis 客户端，按 cache.mode 连接单机、集群或哨兵模式的 Redis
+// 其他包通过 Default 访问缓存，不直接依赖 Redis 客户端
+var client redis.UniversalClient
 
-// Init 初始化 Redis 客户端
+// errNotInitialized 缓存存储尚未初始化时辅助函数返回的错误
+var errNotInitialized = errors.New("cache store not initialized")
+
+// ErrUnavailable Redis 处于降级状态时依赖 Redis 的功能返回的错误
+var ErrUnavailable = errors.New("redis is unavailable")
+
+// defaultReconnectInterval 未配置时后台检查 Redis 连接的间隔
+const defaultReconnectInterval = 5 * time.Second
+
+// degraded Redis 不可用时为 true，依赖 Redis 的中间件据此直接按失败处理方式处理请求，不再等待 Redis 超时
+var degraded atomic.Bool
+
+var (
+	reconnectHooks   = make(map[string]func()) // Redis 恢复可用时调用的函数，按名称注册
+	reconnectHooksMu sync.Mutex
+)
+
+// Init 按 cache.backend 初始化缓存存储，redis 后端同时初始化 Redis 客户端
+// Redis 连接失败时不中止启动，进入降级状态，并在后台定期重连
 func Init(cfg *config.Config) {
-	Client = redis.NewClient(&redis.Options{
-		Addr:     cfg.Cache.Addr,     // Redis 地址
-		Password: cfg.Cache.Password, // Redis 密码
-		DB:       cfg.Cache.DB,       // Redis 数据库编号
-	})
+	if cfg.Cache.Backend == config.CacheBackendMemory {
+		client = nil
+		Default = NewMemoryStore(cfg.Cache.MaxEntries)
+		logger.Info("Using in-memory cache backend", zap.Int("maxEntries", cfg.Cache.MaxEntries))
+		return
+	}
+
+	client = newClient(cfg.Cache)
+	Default = NewRedisStore(client)
+	endpoint := cfg.Cache.Endpoint()
 
 	// 测试连接
 	ctx := context.Background()
-	_, err := Client.Ping(ctx).Result()
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.Error("Failed to connect to Redis, running degraded until it becomes available",
+			zap.Error(err),
+			zap.String("mode", cfg.Cache.Mode),
+			zap.String("addr", endpoint))
+		degraded.Store(true)
+	} else {
+		logger.Info("Redis connected successfully",
+			zap.String("mode", cfg.Cache.Mode),
+			zap.String("addr", endpoint))
+	}
+
+	interval := cfg.Cache.ReconnectInterval
+	if interval <= 0 {
+		interval = defaultReconnectInterval
+	}
+	go monitor(client, endpoint, interval)
+}
+
+// newClient 按部署模式创建 Redis 客户端，连接池和超时设置为 0 时使用客户端默认值
+func newClient(c config.Cache) redis.UniversalClient {
+	switch c.Mode {
+	case config.CacheModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        c.Addrs,
+			Password:     c.Password,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+		})
+	case config.CacheModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    c.MasterName,
+			SentinelAddrs: c.Addrs,
+			Password:      c.Password,
+			DB:            c.DB,
+			PoolSize:      c.PoolSize,
+			MinIdleConns:  c.MinIdleConns,
+			DialTimeout:   c.DialTimeout,
+			ReadTimeout:   c.ReadTimeout,
+			WriteTimeout:  c.WriteTimeout,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:         c.Addr,     // Redis 地址
+			Password:     c.Password, // Redis 密码
+			DB:           c.DB,       // Redis 数据库编号
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+		})
+	}
+}
+
+// Degraded 返回 Redis 当前是否不可用
+func Degraded() bool {
+	return degraded.Load()
+}
+
+// OnReconnect 注册 Redis 从不可用恢复时调用的函数，用于重新写入启动时未能写入的数据，同名函数重复注册时替换
+func OnReconnect(name string, fn func()) {
+	reconnectHooksMu.Lock()
+	defer reconnectHooksMu.Unlock()
+	reconnectHooks[name] = fn
+}
+
+// monitor 定期检查 Redis 连接并更新降级状态，客户端在下一次命令时自动重建连接
+func monitor(client redis.UniversalClient, addr string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := client.Ping(ctx).Err()
+		cancel()
+		updateDegraded(err, addr)
+	}
+}
+
+// updateDegraded 根据连接检查结果更新降级状态，只在状态变化时记录日志
+func updateDegraded(err error, addr string) {
 	if err != nil {
-		logger.Error("Failed to connect to Redis", zap.Error(err), zap.String("addr", cfg.Cache.Addr))
-		panic(err)
+		if !degraded.Swap(true) {
+			logger.Error("Redis became unavailable, running degraded",
+				zap.Error(err),
+				zap.String("addr", addr))
+		}
+		return
+	}
+	if degraded.Swap(false) {
+		logger.Info("Redis reconnected", zap.String("addr", addr))
+		reconnectHooksMu.Lock()
+		hooks := make([]func(), 0, len(reconnectHooks))
+		for _, fn := range reconnectHooks {
+			hooks = append(hooks, fn)
+		}
+		reconnectHooksMu.Unlock()
+		for _, fn := range hooks {
+			fn()
+		}
 	}
-	logger.Info("Redis connected successfully", zap.String("addr", cfg.Cache.Addr))
 }
 
 // GetCacheKey 生成缓存键，基于 HTTP 方法和路径
@@ -40,14 +165,14 @@ func GetCacheKey(method, path string) string {
 
 // CheckCache 检查缓存是否存在并返回内容
 func CheckCache(ctx context.Context, method, path string) (string, bool) {
-	if Client == nil {
-		logger.Warn("Redis client not initialized, skipping cache check")
+	if Default == nil {
+		logger.Warn("Cache store not initialized, skipping cache check")
 		return "", false
 	}
 
 	key := GetCacheKey(method, path)
-	content, err := Client.Get(ctx, key).Result()
-	if err == redis.Nil {
+	content, err := Default.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
 		logger.Debug("Cache miss", zap.String("key", key))
 		return "", false
 	} else if err != nil {
@@ -61,13 +186,13 @@ func CheckCache(ctx context.Context, method, path string) (string, bool) {
 
 // SetCache 设置缓存内容并指定过期时间
 func SetCache(ctx context.Context, method, path, content string, ttl time.Duration) error {
-	if Client == nil {
-		logger.Warn("Redis client not initialized, skipping cache set")
-		return fmt.Errorf("redis client not initialized")
+	if Default == nil {
+		logger.Warn("Cache store not initialized, skipping cache set")
+		return errNotInitialized
 	}
 
 	key := GetCacheKey(method, path)
-	err := Client.Set(ctx, key, content, ttl).Err()
+	err := Default.Set(ctx, key, content, ttl)
 	if err != nil {
 		logger.Error("Failed to set cache", zap.Error(err), zap.String("key", key), zap.Duration("ttl", ttl))
 		return err
@@ -80,13 +205,13 @@ func SetCache(ctx context.Context, method, path, content string, ttl time.Durati
 // IncrementRequestCount 增加指定路径的请求计数，返回当前计数。
 // 当计数器为新建时，设置过期时间为当前TTL窗口长度。
 func IncrementRequestCount(ctx context.Context, path string, ttl time.Duration) int64 {
-	if Client == nil {
-		logger.Warn("Redis client not initialized, skipping request count increment")
+	if Default == nil {
+		logger.Warn("Cache store not initialized, skipping request count increment")
 		return 0
 	}
 
 	key := GetPathReqCountKey(path)
-	count, err := Client.Incr(ctx, key).Result()
+	count, err := Default.Incr(ctx, key)
 	if err != nil {
 		logger.Error("Failed to increment request count", zap.Error(err), zap.String("key", key))
 		return 0
@@ -94,7 +219,7 @@ func IncrementRequestCount(ctx context.Context, path string, ttl time.Duration)
 
 	// 如果是新的计数，设置过期时间
 	if count == 1 {
-		err := Client.Expire(ctx, key, ttl).Err()
+		err := Default.Expire(ctx, key, ttl)
 		if err != nil {
 			logger.Error("Failed to set TTL for request count", zap.Error(err), zap.String("key", key), zap.Duration("ttl", ttl))
 		}
@@ -110,13 +235,13 @@ func GetPathReqCountKey(path string) string {
 
 // ClearRequestCount 清除指定路径的请求计数（可选，用于测试或重置）
 func ClearRequestCount(ctx context.Context, path string) error {
-	if Client == nil {
-		logger.Warn("Redis client not initialized, skipping request count clear")
-		return fmt.Errorf("redis client not initialized")
+	if Default == nil {
+		logger.Warn("Cache store not initialized, skipping request count clear")
+		return errNotInitialized
 	}
 
 	key := GetPathReqCountKey(path)
-	err := Client.Del(ctx, key).Err()
+	err := Default.Del(ctx, key)
 	if err != nil {
 		logger.Error("Failed to clear request count", zap.Error(err), zap.String("key", key))
 		return err
@@ -131,52 +256,30 @@ type PathCount struct {
 	Count int64  `json:"count"`
 }
 
-// BatchGetPathReqCount 批量获取多个路径的请求计数
+// BatchGetPathReqCount 批量获取多个路径的请求计数，计数不存在或无法解析的路径计为 0
 func BatchGetPathReqCount(ctx context.Context, paths []string) ([]PathCount, error) {
-	if Client == nil {
-		logger.Warn("Redis client not initialized, skipping batch request count retrieval")
-		return nil, fmt.Errorf("redis client not initialized")
-	}
-
-	keys := make([]string, len(paths))
-	for i, path := range paths {
-		keys[i] = GetPathReqCountKey(path)
-	}
-
-	counts, err := Client.MGet(ctx, keys...).Result()
-	if err != nil {
-		logger.Error("Failed to batch get request counts", zap.Error(err))
-		return nil, err
+	if Default == nil {
+		logger.Warn("Cache store not initialized, skipping batch request count retrieval")
+		return nil, errNotInitialized
 	}
 
 	results := make([]PathCount, len(paths))
-	for i, count := range counts {
-		// 初始化 PathCount 结构体
-		results[i] = PathCount{
-			Path: paths[i], // 假设 PathCount 结构体有 Path 字段
+	for i, path := range paths {
+		results[i] = PathCount{Path: path}
+		countStr, err := Default.Get(ctx, GetPathReqCountKey(path))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			logger.Error("Failed to batch get request counts", zap.Error(err))
+			return nil, err
 		}
-
-		// 处理 Redis 返回的计数结果
-		if count == nil { // key 不存在时返回 0
-			results[i].Count = 0
+		if val, err := strconv.ParseInt(countStr, 10, 64); err == nil {
+			results[i].Count = val
 		} else {
-			// 将 interface{} 类型转换为字符串，然后转换为整数
-			if countStr, ok := count.(string); ok {
-				if val, err := strconv.ParseInt(countStr, 10, 64); err == nil {
-					results[i].Count = val // 假设 PathCount 结构体有 Count 字段
-				} else {
-					logger.Warn("Failed to parse count value",
-						zap.String("path", paths[i]),
-						zap.String("value", countStr),
-						zap.Error(err))
-					results[i].Count = 0
-				}
-			} else {
-				logger.Warn("Unexpected count type",
-					zap.String("path", paths[i]),
-					zap.Any("value", count))
-				results[i].Count = 0
-			}
+			logger.Warn("Failed to parse count value",
+				zap.String("path", path),
+				zap.String("value", countStr),
+				zap.Error(err))
 		}
 	}
 	return results, nil
@@ -184,13 +287,13 @@ func BatchGetPathReqCount(ctx context.Context, paths []string) ([]PathCount, err
 
 // ClearMethodCount 清除指定方法和路径的请求计数（可选，用于测试或重置）
 func ClearMethodCount(ctx context.Context, method, path string) error {
-	if Client == nil {
-		logger.Warn("Redis client not initialized, skipping request count clear")
-		return fmt.Errorf("redis client not initialized")
+	if Default == nil {
+		logger.Warn("Cache store not initialized, skipping request count clear")
+		return errNotInitialized
 	}
 
-	key := fmt.Sprintf("mg:cache:%s:%s", method, path)
-	err := Client.Del(ctx, key).Err()
+	key := GetCacheKey(method, path)
+	err := Default.Del(ctx, key)
 	if err != nil {
 		logger.Error("Failed to clear method count", zap.Error(err), zap.String("key", key))
 		return err
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrExpireScript 增加计数并重置过期时间，ARGV[1] 为毫秒数，不大于 0 时移除过期时间
+// 两条命令在脚本中原子执行，避免 INCR 成功而设置过期时间失败时计数永不过期
+var incrExpireScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if tonumber(ARGV[1]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+else
+	redis.call('PERSIST', KEYS[1])
+end
+return count
+`)
+
+// redisStore 基于 Redis 的缓存存储，多个网关实例共享数据
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore 基于 Redis 客户端创建缓存存储
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client}
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (s *redisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *redisStore) Incr(ctx context.Context, key string) (int64, error) {
+	return s.client.Incr(ctx, key).Result()
+}
+
+func (s *redisStore) IncrExpire(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrExpireScript.Run(ctx, s.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+func (s *redisStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Expire(ctx, key, ttl).Err()
+}
+
+func (s *redisStore) Del(ctx context.Context, keys ...string) error {
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// DelPrefix 通过 SCAN 查找匹配的键后删除，避免 KEYS 阻塞 Redis；集群模式下逐个主节点处理
+func (s *redisStore) DelPrefix(ctx context.Context, prefix string) error {
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return delPrefix(ctx, node, prefix)
+		})
+	}
+	return delPrefix(ctx, s.client, prefix)
+}
+
+// delPrefix 删除单个节点上所有以 prefix 开头的键
+func delPrefix(ctx context.Context, client redis.UniversalClient, prefix string) error {
+	var keys []string
+	iter := client.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	pipe := client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisStore) HGet(ctx context.Context, key, field string) (string, error) {
+	value, err := s.client.HGet(ctx, key, field).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (s *redisStore) HSet(ctx context.Context, key string, values map[string]string) error {
+	return s.client.HSet(ctx, key, values).Err()
+}
+
+func (s *redisStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return s.client.HGetAll(ctx, key).Result()
+}
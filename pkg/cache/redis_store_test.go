@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStore_NotFound(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	s := NewRedisStore(db)
+	ctx := context.Background()
+
+	mock.ExpectGet("mg:cache:GET:/a").RedisNil()
+	_, err := s.Get(ctx, "mg:cache:GET:/a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mock.ExpectHGet("mg:ip:blacklist", "10.0.0.1").RedisNil()
+	_, err = s.HGet(ctx, "mg:ip:blacklist", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisStore_Commands(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	s := NewRedisStore(db)
+	ctx := context.Background()
+
+	mock.ExpectSet("mg:cache:GET:/a", "body", time.Minute).SetVal("OK")
+	require.NoError(t, s.Set(ctx, "mg:cache:GET:/a", "body", time.Minute))
+	mock.ExpectIncr("mg:counter").SetVal(3)
+	count, err := s.Incr(ctx, "mg:counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	mock.ExpectEvalSha(incrExpireScript.Hash(), []string{"mg:counter"}, int64(60000)).SetVal(int64(4))
+	count, err = s.IncrExpire(ctx, "mg:counter", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	mock.ExpectHSet("mg:ip:whitelist", map[string]string{"10.0.0.1": "true"}).SetVal(1)
+	require.NoError(t, s.HSet(ctx, "mg:ip:whitelist", map[string]string{"10.0.0.1": "true"}))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisStore_DelPrefix(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	s := NewRedisStore(db)
+
+	mock.ExpectScan(0, "mg:health:*", 0).SetVal([]string{"mg:health:a", "mg:health:b"}, 0)
+	mock.ExpectDel("mg:health:a").SetVal(1)
+	mock.ExpectDel("mg:health:b").SetVal(1)
+	require.NoError(t, s.DelPrefix(context.Background(), "mg:health:"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
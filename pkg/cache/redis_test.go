@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit_UnavailableRedisIsNotFatal(t *testing.T) {
+	logger.InitTestLogger()
+	// 监听后立即关闭，得到一个拒绝连接的地址
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	t.Cleanup(func() {
+		client.Close()
+		client = nil
+		degraded.Store(false)
+	})
+	assert.NotPanics(t, func() {
+		Init(&config.Config{Cache: config.Cache{Addr: addr, ReconnectInterval: time.Hour}})
+	})
+	assert.True(t, Degraded())
+	assert.NotNil(t, client)
+}
+
+func TestUpdateDegraded_RunsReconnectHooksOnRecovery(t *testing.T) {
+	logger.InitTestLogger()
+	calls := 0
+	OnReconnect("test", func() { calls++ })
+	t.Cleanup(func() {
+		reconnectHooksMu.Lock()
+		delete(reconnectHooks, "test")
+		reconnectHooksMu.Unlock()
+		degraded.Store(false)
+	})
+
+	updateDegraded(nil, "redis:6379")
+	assert.Equal(t, 0, calls, "hooks only run when recovering from a degraded state")
+
+	updateDegraded(errors.New("connection refused"), "redis:6379")
+	assert.True(t, Degraded())
+	updateDegraded(nil, "redis:6379")
+	assert.False(t, Degraded())
+	assert.Equal(t, 1, calls)
+
+	updateDegraded(nil, "redis:6379")
+	assert.Equal(t, 1, calls)
+}
+
+func TestNewClient_Modes(t *testing.T) {
+	standalone := newClient(config.Cache{Addr: "127.0.0.1:6379", PoolSize: 20, MinIdleConns: 5, ReadTimeout: time.Second})
+	defer standalone.Close()
+	if assert.IsType(t, &redis.Client{}, standalone) {
+		opts := standalone.(*redis.Client).Options()
+		assert.Equal(t, 20, opts.PoolSize)
+		assert.Equal(t, 5, opts.MinIdleConns)
+		assert.Equal(t, time.Second, opts.ReadTimeout)
+	}
+
+	cluster := newClient(config.Cache{Mode: config.CacheModeCluster, Addrs: []string{"10.0.0.1:6379"}, PoolSize: 8})
+	defer cluster.Close()
+	if assert.IsType(t, &redis.ClusterClient{}, cluster) {
+		assert.Equal(t, 8, cluster.(*redis.ClusterClient).Options().PoolSize)
+	}
+
+	// 哨兵模式返回连接当前主节点的普通客户端
+	sentinel := newClient(config.Cache{Mode: config.CacheModeSentinel, Addrs: []string{"10.0.0.1:26379"}, MasterName: "mymaster", DB: 1})
+	defer sentinel.Close()
+	if assert.IsType(t, &redis.Client{}, sentinel) {
+		assert.Equal(t, 1, sentinel.(*redis.Client).Options().DB)
+	}
+}
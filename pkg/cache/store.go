@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound 键或哈希字段不存在时返回的错误
+var ErrNotFound = errors.New("cache: key not found")
+
+// Store 缓存存储接口，IP 访问控制、响应缓存、健康检查统计、共享负载均衡计数和 API Key 查询通过它读写数据
+// 新的缓存后端实现该接口后在 Init 中按 cache.backend 创建即可
+// ttl 为 0 表示不过期
+type Store interface {
+	Get(ctx context.Context, key string) (string, error) // 键不存在时返回 ErrNotFound
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Incr(ctx context.Context, key string) (int64, error)                          // 键不存在时从 0 开始计数
+	IncrExpire(ctx context.Context, key string, ttl time.Duration) (int64, error) // 原子地增加计数并将过期时间重置为 ttl
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	DelPrefix(ctx context.Context, prefix string) error          // 删除所有以 prefix 开头的键
+	HGet(ctx context.Context, key, field string) (string, error) // 键或字段不存在时返回 ErrNotFound
+	HSet(ctx context.Context, key string, values map[string]string) error
+	HGetAll(ctx context.Context, key string) (map[string]string, error) // 键不存在时返回空 map
+}
+
+// Default 是全局的缓存存储，由 Init 按 cache.backend 创建
+var Default Store
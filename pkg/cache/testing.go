@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// InitTestStore 将全局缓存存储替换为新的内存存储并返回，供其他包的测试使用
+func InitTestStore() Store {
+	Default = NewMemoryStore(0)
+	return Default
+}
+
+// errorStore 所有操作都返回同一个错误的存储，用于测试缓存不可用时的处理
+type errorStore struct {
+	err error
+}
+
+// NewErrorStore 创建所有操作都返回 err 的缓存存储
+func NewErrorStore(err error) Store {
+	return errorStore{err: err}
+}
+
+func (s errorStore) Get(ctx context.Context, key string) (string, error) { return "", s.err }
+
+func (s errorStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.err
+}
+
+func (s errorStore) Incr(ctx context.Context, key string) (int64, error) { return 0, s.err }
+
+func (s errorStore) IncrExpire(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, s.err
+}
+
+func (s errorStore) Expire(ctx context.Context, key string, ttl time.Duration) error { return s.err }
+
+func (s errorStore) Del(ctx context.Context, keys ...string) error { return s.err }
+
+func (s errorStore) DelPrefix(ctx context.Context, prefix string) error { return s.err }
+
+func (s errorStore) HGet(ctx context.Context, key, field string) (string, error) { return "", s.err }
+
+func (s errorStore) HSet(ctx context.Context, key string, values map[string]string) error {
+	return s.err
+}
+
+func (s errorStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return nil, s.err
+}
@@ -166,5 +166,5 @@ func Error(msg string, fields ...zap.Field) {
 
 // WithTrace 添加分布式追踪字段（例如 Trace ID）
 func WithTrace(traceID string) *Logger {
-	return &Logger{GetLogger().With(zap.String("trace_id", traceID))}
+	return &Logger{GetLogger().With(zap.String("traceID", traceID))}
 }
@@ -0,0 +1,63 @@
+package signing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner_ApplyAndVerify(t *testing.T) {
+	s, err := New("secret", "", nil, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultHeader, s.Header())
+
+	now := time.Unix(1700000000, 0)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/a%20b?id=1", nil)
+	s.Apply(req.Method, req.URL.Path, req.URL.RawQuery, now, req.Header.Set)
+	assert.Equal(t, "1700000000", req.Header.Get(TimestampHeader))
+	assert.Len(t, req.Header.Get(DefaultHeader), 64)
+
+	assert.NoError(t, s.Verify(req, now.Add(30*time.Second)))
+	assert.ErrorIs(t, s.Verify(req, now.Add(2*time.Minute)), ErrExpired)
+	assert.ErrorIs(t, s.Verify(req, now.Add(-2*time.Minute)), ErrExpired)
+
+	// 默认字段不包含查询字符串，修改方法或路径会使签名失效
+	tampered := req.Clone(req.Context())
+	tampered.URL.RawQuery = "id=2"
+	assert.NoError(t, s.Verify(tampered, now))
+	tampered.Method = http.MethodPost
+	assert.ErrorIs(t, s.Verify(tampered, now), ErrInvalidSignature)
+	tampered = req.Clone(req.Context())
+	tampered.URL.Path = "/api/v1/users/admin"
+	assert.ErrorIs(t, s.Verify(tampered, now), ErrInvalidSignature)
+
+	other, err := New("other", "", nil, time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, other.Verify(req, now), ErrInvalidSignature)
+
+	req.Header.Del(TimestampHeader)
+	assert.ErrorIs(t, s.Verify(req, now), ErrMissingSignature)
+}
+
+func TestSigner_Fields(t *testing.T) {
+	s, err := New("secret", "x-signature", []string{FieldTimestamp, FieldMethod, FieldPath, FieldQuery}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "X-Signature", s.Header())
+
+	now := time.Unix(1700000000, 0)
+	req := httptest.NewRequest(http.MethodGet, "/orders?page=1", nil)
+	s.Apply(req.Method, req.URL.Path, req.URL.RawQuery, now, req.Header.Set)
+	assert.NoError(t, s.Verify(req, now.Add(4*time.Minute)), "default tolerance is 5 minutes")
+	req.URL.RawQuery = "page=2"
+	assert.ErrorIs(t, s.Verify(req, now), ErrInvalidSignature)
+
+	_, err = New("", "", nil, 0)
+	assert.Error(t, err)
+	assert.EqualError(t, ValidateFields([]string{FieldMethod, FieldPath}), `signing fields must include "timestamp"`)
+	assert.EqualError(t, ValidateFields([]string{FieldTimestamp, "body"}), `unknown signing field "body"`)
+	assert.EqualError(t, ValidateFields([]string{FieldTimestamp, FieldTimestamp}), `signing field "timestamp" is listed more than once`)
+}
@@ -24,12 +24,12 @@ func NewPoolManager(cfg *config.Config) *ObjectPoolManager {
 	if cfg.Performance.MemoryPool.Enabled {
 		pm.targetsPool = sync.Pool{
 			New: func() interface{} {
-				return make([]string, 0, cfg.Performance.MemoryPool.RulesCapacity)
+				return make([]string, 0, cfg.Performance.MemoryPool.TargetsCapacity)
 			},
 		}
 		pm.rulesPool = sync.Pool{
 			New: func() interface{} {
-				return make(config.RoutingRules, 0, cfg.Performance.MemoryPool.TargetsCapacity)
+				return make(config.RoutingRules, 0, cfg.Performance.MemoryPool.RulesCapacity)
 			},
 		}
 	} else {
@@ -197,7 +197,7 @@ func TestGetTargets(t *testing.T) {
 			assert.NotNil(t, targets, "Targets slice should not be nil")
 			assert.Equal(t, 0, len(targets), "Targets slice should be empty initially")
 			if tt.poolEnabled {
-				assert.Equal(t, cfg.Performance.MemoryPool.RulesCapacity, cap(targets), "Capacity should match configured RulesCapacity")
+				assert.Equal(t, cfg.Performance.MemoryPool.TargetsCapacity, cap(targets), "Capacity should match configured TargetsCapacity")
 			} else {
 				assert.Equal(t, tt.capacity, cap(targets), "Capacity should match input capacity when pool disabled")
 			}
@@ -221,7 +221,7 @@ func TestPutTargets(t *testing.T) {
 	// 获取并检查是否复用
 	reusedTargets := pm.GetTargets(10)
 	assert.Equal(t, 0, len(reusedTargets), "Reused targets should be reset to length 0")
-	assert.Equal(t, cfg.Performance.MemoryPool.RulesCapacity, cap(reusedTargets), "Capacity should remain unchanged")
+	assert.Equal(t, cfg.Performance.MemoryPool.TargetsCapacity, cap(reusedTargets), "Capacity should remain unchanged")
 }
 
 // TestGetRules 测试 GetRules 方法
@@ -253,7 +253,7 @@ func TestGetRules(t *testing.T) {
 			assert.NotNil(t, rules, "Rules slice should not be nil")
 			assert.Equal(t, 0, len(rules), "Rules slice should be empty initially")
 			if tt.poolEnabled {
-				assert.Equal(t, cfg.Performance.MemoryPool.TargetsCapacity, cap(rules), "Capacity should match configured TargetsCapacity")
+				assert.Equal(t, cfg.Performance.MemoryPool.RulesCapacity, cap(rules), "Capacity should match configured RulesCapacity")
 			} else {
 				assert.Equal(t, tt.capacity, cap(rules), "Capacity should match input capacity when pool disabled")
 			}
@@ -277,7 +277,7 @@ func TestPutRules(t *testing.T) {
 	// 获取并检查是否复用
 	reusedRules := pm.GetRules(10)
 	assert.Equal(t, 0, len(reusedRules), "Reused rules should be reset to length 0")
-	assert.Equal(t, cfg.Performance.MemoryPool.TargetsCapacity, cap(reusedRules), "Capacity should remain unchanged")
+	assert.Equal(t, cfg.Performance.MemoryPool.RulesCapacity, cap(reusedRules), "Capacity should remain unchanged")
 }
 
 // TestPoolReuse 测试对象池的复用功能
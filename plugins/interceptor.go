@@ -0,0 +1,148 @@
+package plugins
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestInterceptor 插件可选实现的接口，在每个 HTTP 代理请求转发到上游之前调用
+// 可读取或修改 c.Request；调用 c.Abort 系列方法写出响应后请求不再转发，后续插件也不再调用
+type RequestInterceptor interface {
+	OnRequest(c *gin.Context)
+}
+
+// ResponseInterceptor 插件可选实现的接口，在上游响应头写给客户端之前调用
+// status 为上游状态码，header 为即将写给客户端的响应头，可直接修改；此时响应体尚未写出
+type ResponseInterceptor interface {
+	OnResponse(c *gin.Context, status int, header http.Header)
+}
+
+var (
+	registry             = make(map[string]PluginInterface) // 已注册的插件
+	registryOrder        []string                           // 插件的注册顺序，拦截器按该顺序调用
+	requestInterceptors  []RequestInterceptor               // 按注册顺序排列的请求拦截器，注册变化时整体替换
+	responseInterceptors []ResponseInterceptor              // 按注册顺序排列的响应拦截器，注册变化时整体替换
+	registryMu           sync.RWMutex
+)
+
+// Register 注册插件，实现了 RequestInterceptor 或 ResponseInterceptor 的插件加入代理请求处理流程
+// 同名插件已注册时替换原插件并保留其顺序
+func Register(name string, p PluginInterface) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; !ok {
+		registryOrder = append(registryOrder, name)
+	}
+	registry[name] = p
+	rebuildInterceptors()
+}
+
+// Unregister 移除插件，插件通过 Setup 注册的路由和中间件不受影响
+func Unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; !ok {
+		return
+	}
+	delete(registry, name)
+	order := make([]string, 0, len(registryOrder)-1)
+	for _, n := range registryOrder {
+		if n != name {
+			order = append(order, n)
+		}
+	}
+	registryOrder = order
+	rebuildInterceptors()
+}
+
+// namedPlugin 带注册名称的插件
+type namedPlugin struct {
+	name   string
+	plugin PluginInterface
+}
+
+// replacePlugins 在同一次加锁中注销 previous 中的插件并按顺序注册 next 中的插件，first 为 true 时 next 排在其他已注册插件之前，否则排在之后
+// 用于配置重新加载时整体替换一组插件，请求不会看到部分插件已注销、新插件尚未注册的中间状态
+func replacePlugins(previous []string, next []namedPlugin, first bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	replaced := make(map[string]struct{}, len(previous)+len(next))
+	for _, name := range previous {
+		replaced[name] = struct{}{}
+		delete(registry, name)
+	}
+	var names, others []string
+	for _, p := range next {
+		if !slices.Contains(names, p.name) {
+			names = append(names, p.name)
+		}
+		replaced[p.name] = struct{}{}
+		registry[p.name] = p.plugin
+	}
+	for _, name := range registryOrder {
+		if _, ok := replaced[name]; !ok {
+			others = append(others, name)
+		}
+	}
+	if first {
+		registryOrder = append(names, others...)
+	} else {
+		registryOrder = append(others, names...)
+	}
+	rebuildInterceptors()
+}
+
+// rebuildInterceptors 按注册顺序重建拦截器列表，调用方需持有写锁
+func rebuildInterceptors() {
+	var reqs []RequestInterceptor
+	var resps []ResponseInterceptor
+	for _, name := range registryOrder {
+		p := registry[name]
+		if i, ok := p.(RequestInterceptor); ok {
+			reqs = append(reqs, i)
+		}
+		if i, ok := p.(ResponseInterceptor); ok {
+			resps = append(resps, i)
+		}
+	}
+	requestInterceptors, responseInterceptors = reqs, resps
+}
+
+// InterceptRequest 依次调用插件的 OnRequest，某个插件中止请求后不再调用后续插件
+func InterceptRequest(c *gin.Context) {
+	registryMu.RLock()
+	interceptors := requestInterceptors
+	registryMu.RUnlock()
+
+	for _, i := range interceptors {
+		i.OnRequest(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+}
+
+// InterceptResponse 依次调用插件的 OnResponse
+func InterceptResponse(c *gin.Context, status int, header http.Header) {
+	registryMu.RLock()
+	interceptors := responseInterceptors
+	registryMu.RUnlock()
+
+	for _, i := range interceptors {
+		i.OnResponse(c, status, header)
+	}
+}
+
+// GetLoadedPlugins 按注册顺序返回已加载的插件
+func GetLoadedPlugins() []PluginInterface {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	pluginsList := make([]PluginInterface, 0, len(registryOrder))
+	for _, name := range registryOrder {
+		pluginsList = append(pluginsList, registry[name])
+	}
+	return pluginsList
+}
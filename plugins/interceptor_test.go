@@ -0,0 +1,84 @@
+package plugins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPlugin 记录拦截器调用顺序的插件，abort 为 true 时中止请求
+type recordingPlugin struct {
+	fakePlugin
+	name  string
+	calls *[]string
+	abort bool
+}
+
+func (p recordingPlugin) OnRequest(c *gin.Context) {
+	*p.calls = append(*p.calls, "request:"+p.name)
+	if p.abort {
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+func (p recordingPlugin) OnResponse(c *gin.Context, status int, header http.Header) {
+	*p.calls = append(*p.calls, "response:"+p.name)
+	header.Set("X-Plugin-"+p.name, "1")
+}
+
+// register 注册插件，测试结束后移除
+func register(t *testing.T, name string, p PluginInterface) {
+	Register(name, p)
+	t.Cleanup(func() { Unregister(name) })
+}
+
+func TestInterceptors_CalledInRegistrationOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	var calls []string
+	register(t, "b", recordingPlugin{name: "b", calls: &calls})
+	register(t, "a", recordingPlugin{name: "a", calls: &calls})
+	register(t, "setup-only", fakePlugin{signature: SIGNATURE})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	InterceptRequest(c)
+	header := http.Header{}
+	InterceptResponse(c, http.StatusOK, header)
+
+	assert.Equal(t, []string{"request:b", "request:a", "response:b", "response:a"}, calls)
+	assert.Equal(t, "1", header.Get("X-Plugin-a"))
+	assert.Len(t, GetLoadedPlugins(), 3)
+
+	// 同名插件替换后保留原顺序
+	calls = nil
+	register(t, "b", recordingPlugin{name: "b2", calls: &calls})
+	InterceptRequest(c)
+	assert.Equal(t, []string{"request:b2", "request:a"}, calls)
+
+	Unregister("a")
+	calls = nil
+	InterceptRequest(c)
+	assert.Equal(t, []string{"request:b2"}, calls)
+}
+
+func TestInterceptRequest_AbortStopsLaterPlugins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	var calls []string
+	register(t, "deny", recordingPlugin{name: "deny", calls: &calls, abort: true})
+	register(t, "later", recordingPlugin{name: "later", calls: &calls})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	InterceptRequest(c)
+
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, []string{"request:deny"}, calls)
+}
@@ -1,7 +1,7 @@
 package plugins
 
 import (
-	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"plugin"
@@ -13,94 +13,135 @@ import (
 	"go.uber.org/zap"
 )
 
-var loadedPlugins = make(map[string]PluginInterface)
+// loadedPlugins 上一次 LoadPlugins 注册的 .so 插件名称，配置重新加载时据此注销不再启用的插件
+var loadedPlugins []string
 
-// LoadPlugins 扫描插件目录并动态加载 .so 文件中的插件
+// openPlugin 从 .so 文件加载插件实例，测试时可替换
+var openPlugin = loadPlugin
+
+// LoadPlugins 按配置的顺序动态加载插件目录中的 .so 插件，未配置插件列表时按文件名顺序加载目录中的全部插件
+// 配置重新加载时再次调用：上次加载而本次未启用（或加载失败）的插件被注销，其拦截器不再执行，插件整体替换，请求不会看到中间状态
 func LoadPlugins(r gin.IRouter, cfg *config.Config) {
+	var loaded []namedPlugin
+	defer func() {
+		replacePlugins(loadedPlugins, loaded, true)
+		loadedPlugins = loadedPlugins[:0]
+		for _, p := range loaded {
+			loadedPlugins = append(loadedPlugins, p.name)
+		}
+	}()
+
 	pluginDir := cfg.Plugin.Dir
 	if pluginDir == "" {
 		logger.Warn("Plugin directory not specified in config, skipping plugin loading")
 		return
 	}
 
-	// 如果指定了插件名称列表，则只加载匹配的插件
-	pluginNames := make(map[string]bool)
-	for _, name := range cfg.Plugin.Plugins {
-		pluginNames[name] = true
+	if len(cfg.Plugin.Plugins) == 0 {
+		loaded = loadAllPlugins(r, pluginDir)
+		return
 	}
-	loadAll := len(cfg.Plugin.Plugins) == 0
 
-	// 读取插件目录
+	for _, entry := range cfg.Plugin.Ordered() {
+		if !entry.Enabled {
+			logger.Info("Skipping disabled plugin", zap.String("plugin", entry.Name))
+			continue
+		}
+		pluginPath := filepath.Join(pluginDir, entry.Name+".so")
+		if _, err := os.Stat(pluginPath); err != nil {
+			// 配置中列出但目录中没有对应 .so 文件的插件
+			logger.Warn("Configured plugin not found in plugin directory",
+				zap.String("plugin", entry.Name),
+				zap.String("dir", pluginDir),
+				zap.Error(err))
+			continue
+		}
+		if p, ok := setupPlugin(r, pluginPath); ok {
+			loaded = append(loaded, namedPlugin{name: entry.Name, plugin: p})
+		}
+	}
+}
+
+// loadAllPlugins 按文件名顺序加载插件目录中的全部插件
+func loadAllPlugins(r gin.IRouter, pluginDir string) []namedPlugin {
 	files, err := os.ReadDir(pluginDir)
 	if err != nil {
 		logger.Error("Failed to read plugin directory",
 			zap.String("dir", pluginDir),
 			zap.Error(err))
-		return
+		return nil
 	}
 
+	var loaded []namedPlugin
 	for _, file := range files {
 		if file.IsDir() || !strings.HasSuffix(file.Name(), ".so") {
 			continue
 		}
-
-		pluginPath := filepath.Join(pluginDir, file.Name())
-		pluginName := strings.TrimSuffix(file.Name(), ".so")
-
-		// 如果指定了插件名称列表且当前插件不在列表中，跳过
-		if !loadAll && !pluginNames[pluginName] {
-			logger.Debug("Skipping plugin not in config list",
-				zap.String("plugin", pluginName))
-			continue
-		}
-
-		// 加载插件
-		p, err := loadPlugin(pluginPath)
-		if err != nil {
-			logger.Error("Failed to load plugin",
-				zap.String("path", pluginPath),
-				zap.Error(err))
-			continue
+		if p, ok := setupPlugin(r, filepath.Join(pluginDir, file.Name())); ok {
+			loaded = append(loaded, namedPlugin{name: strings.TrimSuffix(file.Name(), ".so"), plugin: p})
 		}
+	}
+	return loaded
+}
 
-		loadedPlugins[pluginName] = p
-		// 注册插件
-		p.Setup(r)
-		logger.Info("Plugin loaded successfully",
-			zap.String("name", p.PluginInfo().Name),
-			zap.String("description", p.PluginInfo().Description),
-			zap.Any("version", p.PluginInfo().Version),
-			zap.String("path", pluginPath))
+// setupPlugin 加载插件并注册其路由和中间件，加载失败时记录错误日志并跳过
+// 插件由调用方统一注册，实现了拦截器接口的插件随之加入代理请求处理流程
+func setupPlugin(r gin.IRouter, pluginPath string) (PluginInterface, bool) {
+	p, err := openPlugin(pluginPath)
+	if err != nil {
+		logger.Error("Failed to load plugin",
+			zap.String("path", pluginPath),
+			zap.Error(err))
+		return nil, false
 	}
+
+	p.Setup(r)
+	info := p.PluginInfo()
+	logger.Info("Plugin loaded successfully",
+		zap.String("name", info.Name),
+		zap.String("description", info.Description),
+		zap.Any("version", info.Version),
+		zap.String("signature", info.Signature),
+		zap.String("path", pluginPath))
+	return p, true
 }
 
+// pluginConstructor 插件须导出的构造函数名，签名为 func() plugins.PluginInterface
+const pluginConstructor = "NewPlugin"
+
 // loadPlugin 从 .so 文件加载插件实例
 func loadPlugin(path string) (PluginInterface, error) {
 	p, err := plugin.Open(path)
 	if err != nil {
-		return nil, err
+		// 插件与网关的 Go 版本或依赖版本不一致时在这里失败
+		return nil, fmt.Errorf("open plugin (it must be built with the same Go toolchain and dependency versions as the gateway): %w", err)
 	}
 
-	// 查找插件中导出的 "NewPlugin" 符号
-	pluginSymbol, err := p.Lookup("NewPlugin")
+	symbol, err := p.Lookup(pluginConstructor)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("plugin does not export %s: %w", pluginConstructor, err)
 	}
+	return instantiatePlugin(symbol)
+}
 
-	// 确保符号是函数类型并返回 Plugin 接口
-
-	pluginInfoSymbolFunc, ok := pluginSymbol.(func() PluginInterface)
+// instantiatePlugin 调用插件导出的构造函数并校验插件签名，构造过程中的 panic 转为错误，不影响网关启动
+func instantiatePlugin(symbol plugin.Symbol) (p PluginInterface, err error) {
+	newPlugin, ok := symbol.(func() PluginInterface)
 	if !ok {
-		return nil, errors.New("invalid Plugin")
+		return nil, fmt.Errorf("%s has type %T, want func() plugins.PluginInterface", pluginConstructor, symbol)
 	}
 
-	return pluginInfoSymbolFunc(), nil
-}
-
-func GetLoadedPlugins() []PluginInterface {
-	var pluginsList []PluginInterface
-	for _, p := range loadedPlugins {
-		pluginsList = append(pluginsList, p)
+	defer func() {
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("plugin panicked during initialization: %v", r)
+		}
+	}()
+	p = newPlugin()
+	if p == nil {
+		return nil, fmt.Errorf("%s returned nil", pluginConstructor)
+	}
+	if info := p.PluginInfo(); info.Signature != SIGNATURE {
+		return nil, fmt.Errorf("plugin %q signature %q does not match the gateway plugin API signature %q", info.Name, info.Signature, SIGNATURE)
 	}
-	return pluginsList
+	return p, nil
 }
@@ -0,0 +1,122 @@
+package plugins
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePlugin struct {
+	signature string
+}
+
+func (p fakePlugin) PluginInfo() Info {
+	return Info{Name: "fake", Signature: p.signature}
+}
+
+func (p fakePlugin) Setup(r gin.IRouter) {}
+
+func (p fakePlugin) Execute(ctx context.Context) error { return nil }
+
+func TestInstantiatePlugin(t *testing.T) {
+	logger.InitTestLogger()
+	p, err := instantiatePlugin(func() PluginInterface { return fakePlugin{signature: SIGNATURE} })
+	require.NoError(t, err)
+	assert.Equal(t, "fake", p.PluginInfo().Name)
+}
+
+func TestInstantiatePlugin_Errors(t *testing.T) {
+	logger.InitTestLogger()
+	tests := []struct {
+		name   string
+		symbol any
+		err    string
+	}{
+		{
+			name:   "wrong constructor type",
+			symbol: func() *fakePlugin { return &fakePlugin{} },
+			err:    "NewPlugin has type func() *plugins.fakePlugin, want func() plugins.PluginInterface",
+		},
+		{
+			name:   "nil plugin",
+			symbol: func() PluginInterface { return nil },
+			err:    "NewPlugin returned nil",
+		},
+		{
+			name:   "panic during initialization",
+			symbol: func() PluginInterface { panic("boom") },
+			err:    "plugin panicked during initialization: boom",
+		},
+		{
+			name:   "signature mismatch",
+			symbol: func() PluginInterface { return fakePlugin{signature: "old-api"} },
+			err:    `plugin "fake" signature "old-api" does not match the gateway plugin API signature "` + SIGNATURE + `"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := instantiatePlugin(tt.symbol)
+			assert.Nil(t, p)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+// TestLoadPlugins_ReloadUnregistersDisabledPlugins 验证重新加载时注销不再启用的插件，并按新的顺序注册插件
+func TestLoadPlugins_ReloadUnregistersDisabledPlugins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitTestLogger()
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".so"), nil, 0o644))
+	}
+	var calls []string
+	openPlugin = func(path string) (PluginInterface, error) {
+		name := strings.TrimSuffix(filepath.Base(path), ".so")
+		return recordingPlugin{fakePlugin: fakePlugin{signature: SIGNATURE}, name: name, calls: &calls}, nil
+	}
+	// 其他方式注册的插件（如 WASM 插件）不受 .so 插件重新加载的影响
+	register(t, "other", recordingPlugin{name: "other", calls: &calls})
+	t.Cleanup(func() {
+		openPlugin = loadPlugin
+		LoadPlugins(gin.New(), &config.Config{})
+	})
+
+	intercepted := func() []string {
+		calls = nil
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		InterceptRequest(c)
+		return calls
+	}
+
+	LoadPlugins(gin.New(), &config.Config{Plugin: config.Plugin{Dir: dir, Plugins: []config.PluginEntry{
+		{Name: "a", Enabled: true, Order: 1},
+		{Name: "b", Enabled: true, Order: 2},
+	}}})
+	assert.Equal(t, []string{"request:a", "request:b", "request:other"}, intercepted())
+
+	LoadPlugins(gin.New(), &config.Config{Plugin: config.Plugin{Dir: dir, Plugins: []config.PluginEntry{
+		{Name: "a", Enabled: false},
+		{Name: "b", Enabled: true},
+	}}})
+	assert.Equal(t, []string{"request:b", "request:other"}, intercepted(), "禁用的插件应被注销")
+
+	LoadPlugins(gin.New(), &config.Config{Plugin: config.Plugin{Dir: dir, Plugins: []config.PluginEntry{
+		{Name: "a", Enabled: true, Order: 2},
+		{Name: "b", Enabled: true, Order: 1},
+	}}})
+	assert.Equal(t, []string{"request:b", "request:a", "request:other"}, intercepted(), "插件应按新的加载顺序执行")
+
+	LoadPlugins(gin.New(), &config.Config{})
+	assert.Equal(t, []string{"request:other"}, intercepted(), "移除插件目录后应注销全部 .so 插件")
+}
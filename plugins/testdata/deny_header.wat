@@ -0,0 +1,10 @@
+;; 请求携带 X-Deny 请求头时以 403 拒绝，否则放行
+(module
+  (import "gateway" "request_header" (func $request_header (param i32 i32 i32 i32) (result i32)))
+  (memory (export "memory") 1)
+  (data (i32.const 0) "x-deny")
+  (func (export "filter_request") (result i32)
+    (if (result i32)
+      (i32.ge_s (call $request_header (i32.const 0) (i32.const 6) (i32.const 16) (i32.const 0)) (i32.const 0))
+      (then (i32.const 403))
+      (else (i32.const 0)))))
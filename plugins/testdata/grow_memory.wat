@@ -0,0 +1,8 @@
+;; 尝试将内存增长 16 页（1MiB），增长失败时以 507 拒绝请求，否则放行
+(module
+  (memory (export "memory") 1)
+  (func (export "filter_request") (result i32)
+    (if (result i32)
+      (i32.eq (memory.grow (i32.const 16)) (i32.const -1))
+      (then (i32.const 507))
+      (else (i32.const 0)))))
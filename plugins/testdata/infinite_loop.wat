@@ -0,0 +1,5 @@
+;; 永不返回，用于测试执行超时
+(module
+  (func (export "filter_request") (result i32)
+    (loop (br 0))
+    unreachable))
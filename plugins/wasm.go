@@ -0,0 +1,262 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"go.uber.org/zap"
+)
+
+// WASM 请求过滤 ABI：
+//
+// 模块导出 filter_request() i32，返回 0 表示放行，返回 100-599 表示以该状态码拒绝请求。
+// 网关以 gateway 模块名提供以下只读函数，均将值写入 buf 并返回值的完整长度，buf 不足时只写入前 buf_len 字节：
+//
+//	request_header(name_ptr, name_len, buf_ptr, buf_len i32) i32 // 请求头不存在时返回 -1，名称不区分大小写
+//	request_method(buf_ptr, buf_len i32) i32
+//	request_path(buf_ptr, buf_len i32) i32
+const (
+	wasmHostModule   = "gateway"
+	wasmFilterExport = "filter_request"
+)
+
+// defaultWasmTimeout WASM 模块默认执行超时时间
+const defaultWasmTimeout = 50 * time.Millisecond
+
+// defaultWasmMemoryLimitPages 单个模块实例默认可用的最大内存页数（16MiB）
+const defaultWasmMemoryLimitPages = 256
+
+// errWasmRuntimeClosed 模块所属的运行时已在配置重新加载后关闭
+var errWasmRuntimeClosed = errors.New("WASM runtime closed after reload")
+
+// wasmRequestKey 上下文中保存当前请求的键，供宿主函数读取
+type wasmRequestKey struct{}
+
+// wasmPlugin 预编译的 WASM 请求过滤模块，每个请求使用独立的模块实例
+type wasmPlugin struct {
+	name     string
+	runtime  *wasmRuntime
+	compiled wazero.CompiledModule
+	timeout  time.Duration
+}
+
+// wasmRuntime 一次加载的全部 WASM 模块共用的运行时，配置重新加载时整体替换
+// 执行中的模块持有读锁，关闭运行时等待它们结束后进行
+type wasmRuntime struct {
+	wazero.Runtime
+	mu     sync.RWMutex
+	closed bool
+}
+
+// close 等待执行中的模块结束后关闭运行时，释放编译结果占用的内存
+func (rt *wasmRuntime) close(ctx context.Context) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.closed = true
+	if err := rt.Close(ctx); err != nil {
+		logger.Warn("Failed to close WASM runtime", zap.Error(err))
+	}
+}
+
+var (
+	activeWasmRuntime *wasmRuntime // 当前已注册的 WASM 模块所用的运行时
+	loadedWasmPlugins []string     // 当前已注册的 WASM 模块的插件名称
+)
+
+// LoadWasmPlugins 加载配置中的 WASM 模块并注册为请求拦截器，加载失败的模块记录错误日志并跳过
+// 配置重新加载时再次调用：新模块在新的运行时中编译后整体替换上次加载的模块，已移除的模块随之注销，
+// 上次的运行时在执行中的请求结束后关闭
+func LoadWasmPlugins(cfg *config.Config) {
+	var (
+		runtime *wasmRuntime
+		loaded  []namedPlugin
+	)
+	defer func() {
+		replacePlugins(loadedWasmPlugins, loaded, false)
+		loadedWasmPlugins = loadedWasmPlugins[:0]
+		for _, p := range loaded {
+			loadedWasmPlugins = append(loadedWasmPlugins, p.name)
+		}
+		if previous := activeWasmRuntime; previous != nil {
+			go previous.close(context.Background())
+		}
+		activeWasmRuntime = runtime
+	}()
+	if len(cfg.Plugin.Wasm.Modules) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	memoryLimit := cfg.Plugin.Wasm.MemoryLimitPages
+	if memoryLimit == 0 {
+		memoryLimit = defaultWasmMemoryLimitPages
+	}
+	runtime, err := newWasmRuntime(ctx, memoryLimit)
+	if err != nil {
+		logger.Error("Failed to create WASM runtime", zap.Error(err))
+		return
+	}
+
+	timeout := cfg.Plugin.Wasm.Timeout
+	if timeout <= 0 {
+		timeout = defaultWasmTimeout
+	}
+	for _, module := range cfg.Plugin.Wasm.Modules {
+		p, err := compileWasmPlugin(ctx, runtime, module, timeout)
+		if err != nil {
+			logger.Error("Failed to load WASM plugin",
+				zap.String("name", module.Name),
+				zap.String("path", module.Path),
+				zap.Error(err))
+			continue
+		}
+		loaded = append(loaded, namedPlugin{name: "wasm:" + module.Name, plugin: p})
+		logger.Info("WASM plugin loaded successfully",
+			zap.String("name", module.Name),
+			zap.String("path", module.Path))
+	}
+}
+
+// newWasmRuntime 创建 WASM 运行时并注册宿主函数，上下文取消时中断正在执行的模块
+// 模块实例的内存不超过 memoryLimitPages 页，超出时内存增长失败
+func newWasmRuntime(ctx context.Context, memoryLimitPages uint32) (*wasmRuntime, error) {
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(memoryLimitPages))
+	// 以 wasm32-wasi 为目标编译的模块需要 WASI 导入，模块本身不会被授予文件系统或网络访问
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	_, err := runtime.NewHostModuleBuilder(wasmHostModule).
+		NewFunctionBuilder().WithFunc(wasmRequestHeader).Export("request_header").
+		NewFunctionBuilder().WithFunc(wasmRequestMethod).Export("request_method").
+		NewFunctionBuilder().WithFunc(wasmRequestPath).Export("request_path").
+		Instantiate(ctx)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	return &wasmRuntime{Runtime: runtime}, nil
+}
+
+// compileWasmPlugin 读取并编译模块，避免每个请求重复编译
+func compileWasmPlugin(ctx context.Context, runtime *wasmRuntime, module config.WasmModule, timeout time.Duration) (*wasmPlugin, error) {
+	data, err := os.ReadFile(module.Path)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("compile module: %w", err)
+	}
+	filter, ok := compiled.ExportedFunctions()[wasmFilterExport]
+	if !ok {
+		return nil, fmt.Errorf("module does not export %s", wasmFilterExport)
+	}
+	if len(filter.ParamTypes()) != 0 || len(filter.ResultTypes()) != 1 || filter.ResultTypes()[0] != api.ValueTypeI32 {
+		return nil, fmt.Errorf("%s must have type () -> i32", wasmFilterExport)
+	}
+	return &wasmPlugin{name: module.Name, runtime: runtime, compiled: compiled, timeout: timeout}, nil
+}
+
+func (p *wasmPlugin) PluginInfo() Info {
+	return Info{Name: p.name, Description: "WASM request filter", Signature: SIGNATURE}
+}
+
+func (p *wasmPlugin) Setup(r gin.IRouter) {}
+
+func (p *wasmPlugin) Execute(ctx context.Context) error { return nil }
+
+// OnRequest 执行模块的 filter_request，模块拒绝请求时返回其状态码，执行失败时返回 500
+func (p *wasmPlugin) OnRequest(c *gin.Context) {
+	status, err := p.filter(c.Request)
+	if err != nil {
+		logger.Error("WASM plugin execution failed",
+			zap.String("name", p.name),
+			zap.String("path", c.Request.URL.Path),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WASM plugin execution failed"})
+		c.Abort()
+		return
+	}
+	if status != 0 {
+		c.JSON(status, gin.H{"error": "Request denied by plugin"})
+		c.Abort()
+	}
+}
+
+// filter 在独立的模块实例中执行 filter_request，超时后中断执行
+func (p *wasmPlugin) filter(req *http.Request) (int, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), p.timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, wasmRequestKey{}, req)
+
+	// 配置重新加载后旧的运行时可能已关闭，此时请求仍按执行失败处理
+	p.runtime.mu.RLock()
+	defer p.runtime.mu.RUnlock()
+	if p.runtime.closed {
+		return 0, errWasmRuntimeClosed
+	}
+	// 模块名为空时可以同时存在多个实例；反应器模块的 _initialize 在实例化时执行，不存在时跳过
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return 0, fmt.Errorf("instantiate module: %w", err)
+	}
+	defer mod.Close(context.Background())
+
+	results, err := mod.ExportedFunction(wasmFilterExport).Call(ctx)
+	if err != nil {
+		return 0, err
+	}
+	status := int(api.DecodeI32(results[0]))
+	if status != 0 && (status < 100 || status > 599) {
+		return 0, fmt.Errorf("%s returned invalid status %d", wasmFilterExport, status)
+	}
+	return status, nil
+}
+
+// wasmRequest 返回宿主函数调用所属的请求
+func wasmRequest(ctx context.Context) *http.Request {
+	return ctx.Value(wasmRequestKey{}).(*http.Request)
+}
+
+// writeWasmString 将 value 写入模块内存的 buf，返回 value 的完整长度
+func writeWasmString(m api.Module, value string, bufPtr, bufLen uint32) int32 {
+	n := min(uint32(len(value)), bufLen)
+	if !m.Memory().WriteString(bufPtr, value[:n]) {
+		panic(fmt.Errorf("buffer [%d, %d) is out of memory range", bufPtr, bufPtr+n))
+	}
+	return int32(len(value))
+}
+
+func wasmRequestHeader(ctx context.Context, m api.Module, namePtr, nameLen, bufPtr, bufLen uint32) int32 {
+	name, ok := m.Memory().Read(namePtr, nameLen)
+	if !ok {
+		panic(fmt.Errorf("header name [%d, %d) is out of memory range", namePtr, namePtr+nameLen))
+	}
+	values := wasmRequest(ctx).Header.Values(string(name))
+	if len(values) == 0 {
+		return -1
+	}
+	return writeWasmString(m, values[0], bufPtr, bufLen)
+}
+
+func wasmRequestMethod(ctx context.Context, m api.Module, bufPtr, bufLen uint32) int32 {
+	return writeWasmString(m, wasmRequest(ctx).Method, bufPtr, bufLen)
+}
+
+func wasmRequestPath(ctx context.Context, m api.Module, bufPtr, bufLen uint32) int32 {
+	return writeWasmString(m, wasmRequest(ctx).URL.Path, bufPtr, bufLen)
+}
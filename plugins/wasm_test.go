@@ -0,0 +1,146 @@
+package plugins
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/penwyp/mini-gateway/config"
+	"github.com/penwyp/mini-gateway/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWasmPlugin 编译 testdata 中的模块，模块的 WAT 源码与 .wasm 文件放在一起
+func newTestWasmPlugin(t *testing.T, path string, timeout time.Duration) *wasmPlugin {
+	logger.InitTestLogger()
+	ctx := context.Background()
+	runtime, err := newWasmRuntime(ctx, defaultWasmMemoryLimitPages)
+	require.NoError(t, err)
+	t.Cleanup(func() { runtime.Close(ctx) })
+
+	p, err := compileWasmPlugin(ctx, runtime, config.WasmModule{Name: "test", Path: path}, timeout)
+	require.NoError(t, err)
+	return p
+}
+
+// runWasmPlugin 以给定请求头执行插件，返回响应
+func runWasmPlugin(p *wasmPlugin, header http.Header) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/v1/user", nil)
+	c.Request.Header = header
+	p.OnRequest(c)
+	return c, w
+}
+
+func TestWasmPlugin_DenyByHeader(t *testing.T) {
+	p := newTestWasmPlugin(t, "testdata/deny_header.wasm", time.Second)
+
+	c, _ := runWasmPlugin(p, http.Header{})
+	assert.False(t, c.IsAborted())
+
+	c, w := runWasmPlugin(p, http.Header{"X-Deny": {"1"}})
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestWasmPlugin_Timeout(t *testing.T) {
+	p := newTestWasmPlugin(t, "testdata/infinite_loop.wasm", 20*time.Millisecond)
+
+	c, w := runWasmPlugin(p, http.Header{})
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCompileWasmPlugin_Errors(t *testing.T) {
+	logger.InitTestLogger()
+	ctx := context.Background()
+	runtime, err := newWasmRuntime(ctx, defaultWasmMemoryLimitPages)
+	require.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.wasm")
+	require.NoError(t, os.WriteFile(empty, []byte("\x00asm\x01\x00\x00\x00"), 0644))
+	invalid := filepath.Join(dir, "invalid.wasm")
+	require.NoError(t, os.WriteFile(invalid, []byte("not wasm"), 0644))
+
+	_, err = compileWasmPlugin(ctx, runtime, config.WasmModule{Name: "empty", Path: empty}, time.Second)
+	assert.EqualError(t, err, "module does not export filter_request")
+	_, err = compileWasmPlugin(ctx, runtime, config.WasmModule{Name: "invalid", Path: invalid}, time.Second)
+	assert.ErrorContains(t, err, "compile module")
+	_, err = compileWasmPlugin(ctx, runtime, config.WasmModule{Name: "missing", Path: filepath.Join(dir, "missing.wasm")}, time.Second)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWasmPlugin_MemoryLimit(t *testing.T) {
+	logger.InitTestLogger()
+	ctx := context.Background()
+	for _, tt := range []struct {
+		limit  uint32
+		status int
+	}{
+		{limit: defaultWasmMemoryLimitPages, status: http.StatusOK},
+		{limit: 8, status: http.StatusInsufficientStorage}, // 模块无法增长到 17 页
+	} {
+		runtime, err := newWasmRuntime(ctx, tt.limit)
+		require.NoError(t, err)
+		p, err := compileWasmPlugin(ctx, runtime, config.WasmModule{Name: "grow", Path: "testdata/grow_memory.wasm"}, time.Second)
+		require.NoError(t, err)
+
+		_, w := runWasmPlugin(p, http.Header{})
+		assert.Equal(t, tt.status, w.Code, "limit=%d", tt.limit)
+		runtime.close(ctx)
+	}
+}
+
+// TestLoadWasmPlugins_Reload 验证重新加载时整体替换模块：移除的模块被注销，上次的运行时被关闭
+func TestLoadWasmPlugins_Reload(t *testing.T) {
+	logger.InitTestLogger()
+	t.Cleanup(func() { LoadWasmPlugins(&config.Config{}) })
+	loaded := func() []string {
+		var names []string
+		for _, p := range GetLoadedPlugins() {
+			if wp, ok := p.(*wasmPlugin); ok {
+				names = append(names, wp.name)
+			}
+		}
+		return names
+	}
+
+	LoadWasmPlugins(&config.Config{Plugin: config.Plugin{Wasm: config.WasmPlugins{Modules: []config.WasmModule{
+		{Name: "deny", Path: "testdata/deny_header.wasm"},
+		{Name: "grow", Path: "testdata/grow_memory.wasm"},
+	}}}})
+	assert.Equal(t, []string{"deny", "grow"}, loaded())
+	first := activeWasmRuntime
+	require.NotNil(t, first)
+
+	LoadWasmPlugins(&config.Config{Plugin: config.Plugin{Wasm: config.WasmPlugins{Modules: []config.WasmModule{
+		{Name: "grow", Path: "testdata/grow_memory.wasm"},
+	}}}})
+	assert.Equal(t, []string{"grow"}, loaded(), "移除的模块应被注销")
+	assert.NotSame(t, first, activeWasmRuntime)
+	assert.Eventually(t, func() bool {
+		first.mu.RLock()
+		defer first.mu.RUnlock()
+		return first.closed
+	}, time.Second, 10*time.Millisecond, "上次的运行时应被关闭")
+
+	// 关闭后仍持有旧模块的请求按执行失败处理
+	stale, err := compileWasmPlugin(context.Background(), activeWasmRuntime, config.WasmModule{Name: "deny", Path: "testdata/deny_header.wasm"}, time.Second)
+	require.NoError(t, err)
+	stale.runtime = first
+	_, w := runWasmPlugin(stale, http.Header{})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	LoadWasmPlugins(&config.Config{})
+	assert.Empty(t, loaded())
+}